@@ -100,4 +100,367 @@ If the output is a shell, introduce ANSI colors around the provided text
 {{- else -}}
 {{ printf "%s" $message -}}
 {{- end }}
-{{- end }}
\ No newline at end of file
+{{- end }}
+
+{{/*
+Environment variables tuning the processors of the telemetry-proxy
+*/}}
+{{- define "helm.telemetryProxyTuningEnv" -}}
+{{- with .Values.controllerManager.telemetryProxy.memoryLimiter }}
+{{- if .checkInterval }}
+        - name: LUMIGO_MEMORY_LIMITER_CHECK_INTERVAL
+          value: "{{ .checkInterval }}"
+{{- end }}
+{{- if .limitPercentage }}
+        - name: LUMIGO_MEMORY_LIMITER_LIMIT_PERCENTAGE
+          value: "{{ .limitPercentage }}"
+{{- end }}
+{{- if .spikeLimitPercentage }}
+        - name: LUMIGO_MEMORY_LIMITER_SPIKE_LIMIT_PERCENTAGE
+          value: "{{ .spikeLimitPercentage }}"
+{{- end }}
+{{- end }}
+{{- with .Values.controllerManager.telemetryProxy.batch }}
+{{- if .sendBatchSize }}
+        - name: LUMIGO_BATCH_SEND_BATCH_SIZE
+          value: "{{ .sendBatchSize }}"
+{{- end }}
+{{- if .sendBatchMaxSize }}
+        - name: LUMIGO_BATCH_SEND_BATCH_MAX_SIZE
+          value: "{{ .sendBatchMaxSize }}"
+{{- end }}
+{{- if .timeout }}
+        - name: LUMIGO_BATCH_TIMEOUT
+          value: "{{ .timeout }}"
+{{- end }}
+{{- end }}
+{{- with .Values.controllerManager.telemetryProxy.export }}
+{{- if .compression }}
+{{- if not (has .compression (list "none" "gzip" "zstd")) }}
+{{- fail (printf "controllerManager.telemetryProxy.export.compression must be one of 'none', 'gzip' or 'zstd', found '%s'" .compression) }}
+{{- end }}
+        - name: LUMIGO_EXPORT_COMPRESSION
+          value: "{{ .compression }}"
+{{- end }}
+{{- if .writeBufferSize }}
+        - name: LUMIGO_EXPORT_WRITE_BUFFER_SIZE
+          value: "{{ .writeBufferSize }}"
+{{- end }}
+{{- with .retryOnFailure }}
+{{- if .initialInterval }}
+        - name: LUMIGO_EXPORT_RETRY_INITIAL_INTERVAL
+          value: "{{ .initialInterval }}"
+{{- end }}
+{{- if .maxInterval }}
+        - name: LUMIGO_EXPORT_RETRY_MAX_INTERVAL
+          value: "{{ .maxInterval }}"
+{{- end }}
+{{- if .maxElapsedTime }}
+        - name: LUMIGO_EXPORT_RETRY_MAX_ELAPSED_TIME
+          value: "{{ .maxElapsedTime }}"
+{{- end }}
+{{- end }}
+{{- with .sendingQueue }}
+{{- if .numConsumers }}
+        - name: LUMIGO_EXPORT_QUEUE_NUM_CONSUMERS
+          value: "{{ .numConsumers }}"
+{{- end }}
+{{- if .queueSize }}
+        - name: LUMIGO_EXPORT_QUEUE_SIZE
+          value: "{{ .queueSize }}"
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{/*
+Environment variables of the OTLP receiver of the telemetry-proxy next to the controller
+*/}}
+{{- define "helm.telemetryProxyReceiverEnv" -}}
+{{- with .Values.controllerManager.telemetryProxy.receiver.grpc }}
+{{- if .enabled }}
+        - name: LUMIGO_RECEIVER_GRPC_ENABLED
+          value: "true"
+{{- if .maxConcurrentStreams }}
+        - name: LUMIGO_RECEIVER_GRPC_MAX_CONCURRENT_STREAMS
+          value: "{{ .maxConcurrentStreams }}"
+{{- end }}
+{{- with .keepalive }}
+{{- if .maxConnectionIdle }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_IDLE
+          value: "{{ .maxConnectionIdle }}"
+{{- end }}
+{{- if .maxConnectionAge }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_AGE
+          value: "{{ .maxConnectionAge }}"
+{{- end }}
+{{- if .maxConnectionAgeGrace }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_AGE_GRACE
+          value: "{{ .maxConnectionAgeGrace }}"
+{{- end }}
+{{- if .time }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_TIME
+          value: "{{ .time }}"
+{{- end }}
+{{- if .timeout }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_TIMEOUT
+          value: "{{ .timeout }}"
+{{- end }}
+{{- if .minTime }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_MIN_TIME
+          value: "{{ .minTime }}"
+{{- end }}
+{{- if not (kindIs "invalid" .permitWithoutStream) }}
+        - name: LUMIGO_RECEIVER_KEEPALIVE_PERMIT_WITHOUT_STREAM
+          value: "{{ .permitWithoutStream }}"
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{/*
+The minimum TLS version, which defaults to 1.2 when `tls.fipsApprovedOnly` is set
+*/}}
+{{- define "helm.tlsMinVersion" -}}
+{{- .Values.tls.minVersion | default (ternary "1.2" "" .Values.tls.fipsApprovedOnly) -}}
+{{- end }}
+
+{{- define "helm.telemetryProxyTlsEnv" -}}
+{{- if include "helm.tlsMinVersion" . }}
+        - name: LUMIGO_TLS_MIN_VERSION
+          value: "{{ include "helm.tlsMinVersion" . }}"
+{{- end }}
+{{- end }}
+
+{{/*
+The namespaces in which the manager has permissions in the namespace-scoped mode: the watched ones and its own
+*/}}
+{{- define "helm.managerNamespaces" -}}
+{{- append .Values.watchNamespaces .Release.Namespace | uniq | toJson -}}
+{{- end }}
+
+{{/*
+The rules of the manager for the resources it changes, granted in the watched namespaces or, by default, in the whole cluster
+*/}}
+{{- define "helm.managerNamespacedRules" }}
+- apiGroups:
+  - operator.lumigo.io
+  resources:
+  - lumigoes
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - patch
+  - update
+  - watch
+- apiGroups:
+  - operator.lumigo.io
+  resources:
+  - lumigoes/finalizers
+  verbs:
+  - update
+- apiGroups:
+  - operator.lumigo.io
+  resources:
+  - lumigoes/status
+  verbs:
+  - get
+  - patch
+  - update
+- apiGroups:
+  - ""
+  resources:
+  - events
+  verbs:
+  - create
+  - get
+  - list
+  - update
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  # The manager creates the `lumigo-tracer-token` secrets of the `ProjectedSecret` token injection mode
+  - secrets
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - update
+  - watch
+- apiGroups:
+  - apps
+  resources:
+  - daemonsets
+  - deployments
+  - replicasets
+  - statefulsets
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+- apiGroups:
+  - batch
+  resources:
+  - cronjobs
+  - jobs
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+- apiGroups:
+  # The manager runs the `lumigo-telemetry-verification` Jobs that send a synthetic trace of their namespace
+  - batch
+  resources:
+  - jobs
+  verbs:
+  - create
+  - delete
+- apiGroups:
+  # The manager injects the pod templates of the ScaledJobs of Keda, if installed
+  - keda.sh
+  resources:
+  - scaledjobs
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+- apiGroups:
+  # The manager imports the settings of the Instrumentations of the OpenTelemetry operator referenced by Lumigo instances
+  - opentelemetry.io
+  resources:
+  - instrumentations
+  verbs:
+  - get
+- apiGroups:
+  - ""
+  resources:
+  # The manager writes the compatibility reports of the namespaces and, if enabled, the audit entries in ConfigMaps
+  - configmaps
+  verbs:
+  - create
+  - delete
+  - get
+  - update
+{{- if .Values.controllerManager.telemetryProxy.dedicatedProxies.enabled }}
+- apiGroups:
+  # The manager deploys the dedicated telemetry-proxies in the namespaces whose Lumigo instances request them
+  - apps
+  resources:
+  - deployments
+  verbs:
+  - create
+  - delete
+- apiGroups:
+  - ""
+  resources:
+  - serviceaccounts
+  - services
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - update
+  - watch
+{{- end }}
+{{- if eq .Values.controllerManager.telemetryProxy.mode "sharded" }}
+- apiGroups:
+  # The manager protects the telemetry-proxy shards with more than one replica with PodDisruptionBudgets
+  - policy
+  resources:
+  - poddisruptionbudgets
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - update
+  - watch
+{{- end }}
+{{- if .Values.networkPolicy.enabled }}
+- apiGroups:
+  - networking.k8s.io
+  resources:
+  - networkpolicies
+  verbs:
+  - create
+  - delete
+  - get
+  - list
+  - update
+  - watch
+{{- end }}
+{{- end }}
+
+{{/*
+The read-only rules that the telemetry-proxy needs to enrich the telemetry with the metadata of the cluster
+*/}}
+{{- define "helm.managerClusterReadRules" }}
+- apiGroups:
+  - ""
+  resources:
+  - namespaces
+  - namespaces/status
+  - nodes
+  - nodes/spec
+  - pods
+  - pods/status
+  - replicationcontrollers
+  - replicationcontrollers/status
+  - resourcequotas
+  - services
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - apps
+  resources:
+  - daemonsets
+  - deployments
+  - replicasets
+  - statefulsets
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - batch
+  resources:
+  - cronjobs
+  - jobs
+  verbs:
+  - get
+  - list
+  - watch
+{{- end }}
+
+{{/*
+The failure policy of a webhook: in maintenance mode, the webhooks are installed and upgraded with the `Ignore` one,
+and the operator sets the given one once the rollout of the controller manager has completed
+*/}}
+{{- define "helm.webhookFailurePolicy" -}}
+{{- if not (has .failurePolicy (list "Fail" "Ignore")) }}
+{{- fail (printf "the failure policy of the webhooks must be either 'Fail' or 'Ignore', got '%s'" .failurePolicy) }}
+{{- end }}
+{{- if .root.Values.webhookMaintenance.enabled }}Ignore{{ else }}{{ .failurePolicy }}{{ end }}
+{{- end }}
+
+{{/*
+How the replicas of the controller manager and of the telemetry-proxy shards are spread across nodes
+*/}}
+{{- define "helm.podAntiAffinity" -}}
+{{- $podAntiAffinity := .Values.controllerManager.podAntiAffinity | default "none" }}
+{{- if not (has $podAntiAffinity (list "none" "preferred" "required")) }}
+{{- fail (printf "the pod anti-affinity of the controller manager must be either 'none', 'preferred' or 'required', got '%s'" $podAntiAffinity) }}
+{{- end }}
+{{- $podAntiAffinity }}
+{{- end }}
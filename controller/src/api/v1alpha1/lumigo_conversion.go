@@ -0,0 +1,5 @@
+package v1alpha1
+
+// Hub marks v1alpha1, the storage version of the Lumigo CRD, as the version that
+// the other versions are converted to and from
+func (*Lumigo) Hub() {}
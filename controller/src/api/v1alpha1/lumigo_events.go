@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"errors"
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
@@ -61,3 +62,116 @@ func RecordCannotUpdateInstrumentationEvent(eventRecorder record.EventRecorder,
 		fmt.Sprintf("Cannot update Lumigo instrumentation (trigger: %s): %s", trigger, err.Error()),
 	)
 }
+
+// The annotation of the LumigoSkippedInstrumentation events with why the resource has been skipped,
+// among a fixed set of values, e.g., `OptedOut`
+const SkipReasonEventAnnotationKey = "lumigo.io/skip-reason"
+
+// The errors that say why a resource is skipped among a fixed set of values, like the SkipInjectionError
+// of the mutation package, which imports this one
+type skipReasonError interface {
+	error
+	SkipReasonCode() string
+}
+
+func RecordSkippedInstrumentationEvent(eventRecorder record.EventRecorder, resource runtime.Object, trigger string, err error) {
+	var skipReasonErr skipReasonError
+	if !errors.As(err, &skipReasonErr) || skipReasonErr.SkipReasonCode() == "" {
+		eventRecorder.Event(
+			resource,
+			corev1.EventTypeNormal,
+			string(LumigoEventReasonSkippedInstrumentation),
+			fmt.Sprintf("Skipping Lumigo instrumentation (trigger: %s): %s", trigger, err.Error()),
+		)
+		return
+	}
+
+	eventRecorder.AnnotatedEventf(
+		resource,
+		map[string]string{SkipReasonEventAnnotationKey: skipReasonErr.SkipReasonCode()},
+		corev1.EventTypeNormal,
+		string(LumigoEventReasonSkippedInstrumentation),
+		"Skipping Lumigo instrumentation (trigger: %s, reason: %s): %s", trigger, skipReasonErr.SkipReasonCode(), err.Error(),
+	)
+}
+
+func RecordBackendUnreachableEvent(eventRecorder record.EventRecorder, resource runtime.Object, message string) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeWarning,
+		string(LumigoEventReasonBackendUnreachable),
+		fmt.Sprintf("The Lumigo backend is unreachable: %s", message),
+	)
+}
+
+func RecordBackendReachableEvent(eventRecorder record.EventRecorder, resource runtime.Object) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeNormal,
+		string(LumigoEventReasonBackendReachable),
+		"The Lumigo backend is reachable again",
+	)
+}
+
+func RecordTokenMissingEvent(eventRecorder record.EventRecorder, resource runtime.Object, message string) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeWarning,
+		string(LumigoEventReasonTokenMissing),
+		fmt.Sprintf("The Lumigo token is missing: %s", message),
+	)
+}
+
+func RecordTokenFoundEvent(eventRecorder record.EventRecorder, resource runtime.Object) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeNormal,
+		string(LumigoEventReasonTokenFound),
+		"The Lumigo token is available again",
+	)
+}
+
+func RecordTelemetryProxyUnreachableEvent(eventRecorder record.EventRecorder, resource runtime.Object, message string) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeWarning,
+		string(LumigoEventReasonTelemetryProxyUnreachable),
+		fmt.Sprintf("The telemetry-proxy is unreachable: %s", message),
+	)
+}
+
+func RecordTelemetryProxyReachableEvent(eventRecorder record.EventRecorder, resource runtime.Object) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeNormal,
+		string(LumigoEventReasonTelemetryProxyReachable),
+		"The telemetry-proxy is reachable again",
+	)
+}
+
+func RecordInjectionRuntimeFailuresEvent(eventRecorder record.EventRecorder, resource runtime.Object, message string) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeWarning,
+		string(LumigoEventReasonInjectionRuntimeFailures),
+		fmt.Sprintf("The Lumigo injector fails in pods of the namespace: %s", message),
+	)
+}
+
+func RecordAdmissionRejectedEvent(eventRecorder record.EventRecorder, resource runtime.Object, outcome string, message string) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeWarning,
+		string(LumigoEventReasonAdmissionRejected),
+		fmt.Sprintf("The Lumigo injector webhook %s the admission: %s", outcome, message),
+	)
+}
+
+func RecordInjectionRetriesStoppedEvent(eventRecorder record.EventRecorder, resource runtime.Object, trigger string, resetAnnotationKey string, err error) {
+	eventRecorder.Event(
+		resource,
+		corev1.EventTypeWarning,
+		string(LumigoEventReasonInjectionRetriesStopped),
+		fmt.Sprintf("Stopped retrying to add Lumigo instrumentation after too many failed attempts in a row, until reset with the '%s' annotation of the Lumigo resource (trigger: %s): %s", resetAnnotationKey, trigger, err.Error()),
+	)
+}
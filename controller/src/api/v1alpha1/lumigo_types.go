@@ -28,9 +28,13 @@ const (
 )
 
 // Lumigo is the Schema for the lumigoes API
+// +genclient
 // +kubebuilder:resource:scope=Namespaced
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:storageversion
 type Lumigo struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -56,6 +60,145 @@ type LumigoSpec struct {
 	Tracing        TracingSpec        `json:"tracing,omitempty"`
 	Logging				 LoggingSpec        `json:"logging,omitempty"`
 	Infrastructure InfrastructureSpec `json:"infrastructure,omitempty"`
+	// +kubebuilder:validation:Optional
+	Pipelines PipelinesSpec `json:"pipelines,omitempty"`
+	// +kubebuilder:validation:Optional
+	Endpoints EndpointsSpec `json:"endpoints,omitempty"`
+	// +kubebuilder:validation:Optional
+	Debug DebugSpec `json:"debug,omitempty"`
+	// +kubebuilder:validation:Optional
+	Archival ArchivalSpec `json:"archival,omitempty"`
+	// +kubebuilder:validation:Optional
+	Quota QuotaSpec `json:"quota,omitempty"`
+	// Whether the operator is paused in the namespace: while paused, neither the webhook nor the
+	// reconciler inject or remove the instrumentation of resources, and the resources injected
+	// beforehand are left as they are. Meant as a brake during incidents.
+	// If unspecified, defaults to `false`
+	// +kubebuilder:validation:Optional
+	Paused *bool `json:"paused,omitempty"`
+}
+
+// ArchivalSpec specifies whether the telemetry-proxy archives the raw telemetry of the
+// namespace in object storage, independently of what is sent to Lumigo
+type ArchivalSpec struct {
+	// Whether the telemetry-proxy archives the spans and logs of the namespace.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled"` // Using a pointer to support cases where the value is not set (and it counts as disabled)
+
+	// +kubebuilder:validation:Optional
+	S3 S3ArchivalSpec `json:"s3,omitempty"`
+}
+
+// S3ArchivalSpec specifies the S3 (or S3-compatible) bucket in which telemetry is archived
+type S3ArchivalSpec struct {
+	// The name of the bucket; required if archival is enabled
+	// +kubebuilder:validation:Optional
+	Bucket string `json:"bucket,omitempty"`
+	// The region of the bucket. If unspecified, defaults to `us-east-1`.
+	// +kubebuilder:validation:Optional
+	Region string `json:"region,omitempty"`
+	// The prefix of the archived objects, in which `{namespace}` is replaced with
+	// the name of the namespace. The objects are further partitioned by date under
+	// the prefix. If unspecified, defaults to `{namespace}`.
+	// +kubebuilder:validation:Optional
+	Prefix string `json:"prefix,omitempty"`
+	// How the archived objects are partitioned by date, either `hour` or `minute`.
+	// If unspecified, defaults to `hour`.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=hour;minute
+	Partition string `json:"partition,omitempty"`
+	// The endpoint of an S3-compatible object storage, e.g., `https://storage.googleapis.com`
+	// for Google Cloud Storage. If unspecified, AWS S3 is used.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// PipelinesSpec specifies which telemetry of the namespace the telemetry-proxy sends to Lumigo,
+// e.g., to send the traces of a namespace but not its logs and metrics
+type PipelinesSpec struct {
+	// Whether the spans of the namespace are sent to Lumigo and to the additional exporters.
+	// The span metrics are derived only from the spans that are sent.
+	// +kubebuilder:validation:Optional
+	Traces PipelineSpec `json:"traces,omitempty"`
+	// Whether the logs of the containers of the namespace are sent to Lumigo; the Kubernetes
+	// events and objects are governed by `.spec.infrastructure.kubeEvents` instead
+	// +kubebuilder:validation:Optional
+	Logs PipelineSpec `json:"logs,omitempty"`
+	// Whether the metrics of the namespace, i.e., the Prometheus metrics, the span metrics and
+	// the counts of the node lifecycle events, are sent to Lumigo
+	// +kubebuilder:validation:Optional
+	Metrics PipelineSpec `json:"metrics,omitempty"`
+}
+
+type PipelineSpec struct {
+	// Whether the telemetry-proxy sends this telemetry of the namespace.
+	// If unspecified, defaults to `true`
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// EndpointsSpec specifies the OTLP endpoints to which the telemetry-proxy sends each signal of the
+// namespace instead of the Lumigo endpoints of the operator, e.g., because the Lumigo region or an
+// intermediate gateway differs by signal
+type EndpointsSpec struct {
+	// The endpoint to which the spans of the namespace are sent
+	// +kubebuilder:validation:Optional
+	Traces *OtlpEndpointSpec `json:"traces,omitempty"`
+	// The endpoint to which the logs of the containers of the namespace are sent
+	// +kubebuilder:validation:Optional
+	Logs *OtlpEndpointSpec `json:"logs,omitempty"`
+	// The endpoint to which the metrics of the namespace, i.e., the Prometheus metrics, the span
+	// metrics and the counts of the node lifecycle events, are sent
+	// +kubebuilder:validation:Optional
+	Metrics *OtlpEndpointSpec `json:"metrics,omitempty"`
+}
+
+// OtlpEndpointSpec specifies an OTLP/HTTP endpoint to which the telemetry-proxy sends one signal
+// of the namespace, still authenticated with the Lumigo token
+type OtlpEndpointSpec struct {
+	// The base URL of the OTLP/HTTP endpoint, e.g., `https://otlp-gateway.example.com`; the path of
+	// the signal, e.g., `/v1/traces`, is appended to it
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Url string `json:"url"`
+	// Reference to a Kubernetes secret in the same namespace as the Lumigo resource;
+	// each key of the secret is sent as an HTTP header, with the key's value as value
+	// +kubebuilder:validation:Optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+}
+
+// QuotaSpec specifies how much telemetry of the namespace the telemetry-proxy sends to Lumigo
+// per day (UTC), e.g., to attribute the costs of monitoring to the teams owning the namespaces
+type QuotaSpec struct {
+	// The maximum amount of spans per day that the telemetry-proxy sends to Lumigo
+	// for this namespace. If unspecified, the amount of spans is not limited.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxSpansPerDay *int64 `json:"maxSpansPerDay,omitempty"`
+	// The maximum amount of gigabytes of spans per day that the telemetry-proxy sends
+	// to Lumigo for this namespace. If unspecified, the size of spans is not limited.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxGigabytesPerDay *int32 `json:"maxGigabytesPerDay,omitempty"`
+	// The percentage of traces that the telemetry-proxy keeps sending, for the rest
+	// of the day, once the namespace has exceeded its quota; the spans of the other
+	// traces are dropped, and the `QuotaExceeded` condition of this Lumigo instance is set.
+	// If unspecified, defaults to `0`, i.e., all spans are dropped.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	SamplingPercentageWhenExceeded *int32 `json:"samplingPercentageWhenExceeded,omitempty"`
+}
+
+// DebugSpec specifies settings to troubleshoot the telemetry of the namespace
+type DebugSpec struct {
+	// Whether the telemetry-proxy logs, in its own output, the telemetry of the namespace
+	// that it sends to Lumigo. This is meant to troubleshoot missing data in Lumigo, and it
+	// is very verbose, so it should be enabled only as long as needed.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	LogTelemetry *bool `json:"logTelemetry"` // Using a pointer to support cases where the value is not set (and it counts as disabled)
 }
 
 type Credentials struct {
@@ -63,6 +206,23 @@ type Credentials struct {
 	// for Lumigo. The secret must be in the same namespace as the
 	// LumigoSpec referencing it.
 	SecretRef KubernetesSecretRef `json:"secretRef,omitempty"`
+	// The Lumigo token itself, in place of `secretRef`. Discouraged: the token is stored in clear
+	// in the Lumigo resource, readable by whoever can read it; it is meant for short-lived environments,
+	// like previews, set up by scripts. The operator copies the token into the `lumigo-tracer-token`
+	// secret of the namespace, which the injected containers reference, and never writes it in the
+	// status, the conditions, the events or the logs.
+	// +kubebuilder:validation:Optional
+	Value string `json:"value,omitempty"`
+}
+
+// MarshalLog masks the Lumigo token of `value` in the logs
+func (c Credentials) MarshalLog() interface{} {
+	masked := c
+	if len(masked.Value) > 0 {
+		masked.Value = "***"
+	}
+
+	return masked
 }
 
 type KubernetesSecretRef struct {
@@ -76,6 +236,161 @@ type KubernetesSecretRef struct {
 // should be set up by the operator
 type TracingSpec struct {
 	Injection InjectionSpec `json:"injection"`
+	// +kubebuilder:validation:Optional
+	GoInstrumentation GoInstrumentationSpec `json:"goInstrumentation,omitempty"`
+	// +kubebuilder:validation:Optional
+	SpanMetrics SpanMetricsSpec `json:"spanMetrics,omitempty"`
+	// The maximum amount of spans per second that the telemetry-proxy accepts from
+	// this namespace; spans in excess are dropped, and the drops are reported in the
+	// `RateLimited` condition of this Lumigo instance. Span metrics are derived only
+	// from the spans that are not dropped. If unspecified, spans are not rate-limited.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxSpansPerSecond *int32 `json:"maxSpansPerSecond,omitempty"`
+	// Additional OTLP backends to which the telemetry-proxy sends the traces of the
+	// namespace, besides Lumigo
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	AdditionalExporters []OtlpExporterSpec `json:"additionalExporters,omitempty"`
+	// Routes of the traces and logs of some workloads of the namespace to other Lumigo
+	// projects than the one of `.spec.lumigoToken`. The workloads are matched by the
+	// labels of their top-level resource (e.g., the Deployment), and the first matching
+	// route applies; workloads not matched by any route use `.spec.lumigoToken`.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	Routes []TracingRoute `json:"routes,omitempty"`
+	// The context propagators of the injected containers, set as their `OTEL_PROPAGATORS` environment
+	// variable, e.g., `[tracecontext, baggage, xray]` to continue the traces of the AWS load balancers.
+	// If unspecified, the Lumigo distros use their default propagators.
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	Propagators []Propagator `json:"propagators,omitempty"`
+	// The domains whose HTTP calls are left out of the traces, e.g., `[vault.internal.example.com, "*.okta.com"]`
+	// to keep the calls to secret stores and identity providers out of the span data. The injected
+	// containers do not trace the calls, and the telemetry-proxy drops the spans of the calls anyhow,
+	// e.g., the ones of containers injected earlier. A leading `*.` matches all the subdomains.
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	SkipDomains []Domain `json:"skipDomains,omitempty"`
+	// The sampling of the traces of the injected containers. The fields that are set override the
+	// cluster-wide defaults of the operator, and the effective sampling is reported in
+	// `.status.effectiveSampling`. If unspecified, the cluster-wide defaults apply, if any.
+	// +kubebuilder:validation:Optional
+	Sampling SamplingSpec `json:"sampling,omitempty"`
+	// Whether the workloads of the namespace send their traces and logs to a telemetry-proxy of
+	// their own, which the operator deploys in the namespace with the Lumigo token of the namespace,
+	// rather than to the telemetry-proxy shared by all the namespaces; its traffic to Lumigo leaves
+	// from the namespace, subject to its NetworkPolicies. Requires the dedicated telemetry-proxies to
+	// be enabled in the operator. If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	DedicatedProxy *bool `json:"dedicatedProxy,omitempty"`
+	// The continuation of the traces of the AWS load balancers, e.g., the ALBs on EKS, which start
+	// them with the `X-Amzn-Trace-Id` header
+	// +kubebuilder:validation:Optional
+	AwsXRay AwsXRaySpec `json:"awsXRay,omitempty"`
+	// Extra HTTP headers that the telemetry-proxy sends with the telemetry of the namespace, e.g., a
+	// tenant ID or the credentials of an intermediate gateway. The headers are sent to Lumigo and to
+	// the endpoints of `.spec.endpoints`, but not to the additional exporters; the values are read
+	// from secrets, and are redacted in the status and in the logs of the operator.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	OtlpHeaders []OtlpHeaderSpec `json:"otlpHeaders,omitempty"`
+}
+
+// OtlpHeaderSpec specifies an HTTP header whose value is read from the key of a Kubernetes secret
+type OtlpHeaderSpec struct {
+	// The name of the HTTP header, e.g., `X-Scope-OrgID`; the `Authorization` header, which carries
+	// the Lumigo token, cannot be overridden
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9][-A-Za-z0-9_.]*$`
+	// +kubebuilder:validation:MaxLength=256
+	Name string `json:"name"`
+	// Reference to the key of a Kubernetes secret in the same namespace as the Lumigo resource
+	// whose value is the value of the header
+	SecretRef KubernetesSecretRef `json:"secretRef"`
+}
+
+// AwsXRaySpec specifies whether the injected containers continue the traces of the `X-Amzn-Trace-Id`
+// headers, so that the traces are not fragmented at the AWS load balancers
+type AwsXRaySpec struct {
+	// Whether the injected containers extract and propagate the `X-Amzn-Trace-Id` headers, by adding
+	// `xray` to their propagators, i.e., to `.spec.tracing.propagators` or, if unspecified, to
+	// `tracecontext` and `baggage`; and whether the telemetry-proxy adds the trace IDs in the X-Ray
+	// format, e.g., `1-5759e988-bd862e3fe1be46a994272793`, to the spans as the `aws.xray.trace_id`
+	// attribute, to look the traces up by the IDs in the access logs of the load balancers.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// SamplingSpec specifies the sampling of the traces of the injected containers, set with their
+// `OTEL_TRACES_SAMPLER` and `OTEL_TRACES_SAMPLER_ARG` env vars
+type SamplingSpec struct {
+	// The percentage of the traces that the injected containers record, from 0 to 100.
+	// If unspecified, all the traces are recorded.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage *int32 `json:"percentage,omitempty"`
+	// Whether the injected containers follow the sampling decisions of the callers that propagate one,
+	// so that the traces spanning multiple services are not broken up, and apply the percentage only
+	// to the traces they start. If unspecified, defaults to `true`.
+	// +kubebuilder:validation:Optional
+	ParentBased *bool `json:"parentBased,omitempty"`
+}
+
+// TracingRoute specifies the Lumigo token injected into the workloads matched by a selector
+type TracingRoute struct {
+	// The name of the route, unique within the Lumigo resource
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=50
+	Name string `json:"name"`
+	// The selector of the labels of the workloads that the route applies to
+	Selector metav1.LabelSelector `json:"selector"`
+	// The Lumigo token of the project that the matched workloads report to
+	LumigoToken Credentials `json:"lumigoToken"`
+}
+
+// OtlpExporterSpec specifies an OTLP/HTTP backend to send telemetry to
+type OtlpExporterSpec struct {
+	// The name of the exporter, unique within the Lumigo resource
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// The base URL of the OTLP/HTTP endpoint, e.g., `https://tempo.example.com:4318`
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+	// Reference to a Kubernetes secret in the same namespace as the Lumigo resource;
+	// each key of the secret is sent as an HTTP header, with the key's value as value
+	// +kubebuilder:validation:Optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+}
+
+// SpanMetricsSpec specifies whether the telemetry-proxy derives RED (rate, errors,
+// duration) metrics from the spans of the namespace
+type SpanMetricsSpec struct {
+	// Whether the telemetry-proxy generates metrics from the spans of the namespace
+	// and sends them to Lumigo. The metrics are derived before any sampling occurs.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled"` // Using a pointer to support cases where the value is not set (and it counts as disabled)
+
+	// Additional span attributes to use as dimensions of the generated metrics,
+	// besides the service name, span name, span kind and status code.
+	// +kubebuilder:validation:Optional
+	Dimensions []string `json:"dimensions,omitempty"`
+}
+
+// GoInstrumentationSpec specifies whether Go processes in the namespace are
+// instrumented by the node-level eBPF agent, as Go binaries cannot be instrumented
+// by the Lumigo injector
+type GoInstrumentationSpec struct {
+	// Whether Go processes running in the namespace are instrumented by the Lumigo
+	// Go instrumentation agent, which is deployed by the operator as a DaemonSet.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled"` // Using a pointer to support cases where the value is not set (and it counts as disabled)
 }
 
 type LoggingSpec struct {
@@ -100,14 +415,307 @@ type InjectionSpec struct {
 	// +kubebuilder:validation:Optional
 	InjectLumigoIntoExistingResourcesOnCreation *bool `json:"injectLumigoIntoExistingResourcesOnCreation,omitempty"`
 
+	// The maximum number of existing workloads that the operator updates to add the injection in
+	// one reconciliation of the Lumigo resource; the updates of the other workloads are queued in
+	// `.status.pendingInjections` and carried out in the next reconciliations, so that a mass
+	// injection restarts the pods of the namespace gradually. Pausing the Lumigo resource halts
+	// the queued updates. The operator may be configured with a lower, cluster-wide limit.
+	// If unspecified, only the cluster-wide limit, if any, applies.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentWorkloadUpdates *int32 `json:"maxConcurrentWorkloadUpdates,omitempty"`
+
 	// Whether Daemonsets, Deployments, ReplicaSets, StatefulSets, CronJobs and Jobs
 	// that are injected with Lumigo will be updated to remove the injection when the
 	// Lumigo resource is deleted.
 	// If unspecified, defaults to `true`. It requires `Enabled` to be set to `true`.
 	// +kubebuilder:validation:Optional
 	RemoveLumigoFromResourcesOnDeletion *bool `json:"removeLumigoFromResourcesOnDeletion,omitempty"`
+
+	// How the injection is removed from the resources when the Lumigo resource is deleted. With
+	// `Blocking`, the deletion of the Lumigo resource, and so of its namespace, waits until the
+	// injection has been removed from all the resources. With `Background`, the Lumigo resource is
+	// deleted right away, and the injection is removed in the background, tracked by the
+	// `lumigo-cleanup` ConfigMap of the namespace, which is deleted once done.
+	// If unspecified, defaults to `Blocking`.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Blocking;Background
+	RemovalMode RemovalMode `json:"removalMode,omitempty"`
+
+	// The report of which existing workloads of the namespace can be injected with Lumigo, which
+	// the operator produces without changing the workloads, also while the injection is disabled,
+	// so that the expected coverage is known before turning the injection on.
+	// +kubebuilder:validation:Optional
+	CompatibilityReport CompatibilityReportSpec `json:"compatibilityReport,omitempty"`
+
+	// The verification, with a synthetic trace, that the telemetry of the namespace reaches Lumigo,
+	// reported in the `TelemetryVerified` condition, so that a misconfigured endpoint or token is
+	// caught before the traffic of the instrumented workloads relies on it.
+	// +kubebuilder:validation:Optional
+	TelemetryVerification TelemetryVerificationSpec `json:"telemetryVerification,omitempty"`
+
+	// The pull policy of the Lumigo injector image used by the init container added
+	// to injected pods. If unspecified, the Kubernetes defaults apply.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	InjectorImagePullPolicy corev1.PullPolicy `json:"injectorImagePullPolicy,omitempty"`
+
+	// Additional image pull secrets to be added to the pods of injected resources,
+	// e.g., when the Lumigo injector image is pulled from a private registry mirror.
+	// The secrets must be in the same namespace as the Lumigo resource.
+	// +kubebuilder:validation:Optional
+	InjectorImagePullSecrets []corev1.LocalObjectReference `json:"injectorImagePullSecrets,omitempty"`
+
+	// How to treat pods that may be scheduled on nodes with a CPU architecture that the
+	// Lumigo injector does not support (the injector image supports `amd64` and `arm64`).
+	// With `Skip`, pods whose node selector or required node affinity only allow
+	// unsupported architectures are not injected; with `NodeAffinity`, a required node
+	// affinity on the supported architectures is added to injected pods; with `Ignore`,
+	// pods are injected regardless of their architecture.
+	// If unspecified, defaults to `Skip`.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Skip;NodeAffinity;Ignore
+	UnsupportedArchitecturePolicy UnsupportedArchitecturePolicy `json:"unsupportedArchitecturePolicy,omitempty"`
+
+	// Additional env vars to be added to every container injected with Lumigo, e.g.,
+	// `LUMIGO_DEBUG` or `OTEL_SERVICE_NAME`; they can reference other env vars of the
+	// container as `$(VAR_NAME)` and take their value from `valueFrom` sources. They
+	// replace the env vars of the container with the same name, and are removed with
+	// the rest of the injection. The env vars that the operator manages, like
+	// `LUMIGO_TRACER_TOKEN`, cannot be overridden.
+	// +kubebuilder:validation:Optional
+	ExtraEnv []corev1.EnvVar `json:"extraEnv,omitempty"`
+
+	// Reference to an `Instrumentation` resource of the OpenTelemetry operator, whose sampler,
+	// propagators and env vars are applied to the injected containers like the ones of
+	// `extraEnv`, so that the settings are not maintained twice while migrating. The env vars
+	// of `extraEnv` take precedence over the imported ones.
+	// +kubebuilder:validation:Optional
+	OpenTelemetryInstrumentationRef *OpenTelemetryInstrumentationRef `json:"openTelemetryInstrumentationRef,omitempty"`
+
+	// How much of the payloads of the requests and responses the Lumigo distros capture in the
+	// injected containers, e.g., to keep bodies and headers out of the traces of regulated
+	// namespaces. If unspecified, the Lumigo distros capture payloads with their defaults.
+	// +kubebuilder:validation:Optional
+	PayloadCapture PayloadCaptureSpec `json:"payloadCapture,omitempty"`
+
+	// How the injection composes with the Istio sidecars of the workloads of the namespace, e.g.,
+	// so that their telemetry reaches the telemetry-proxy under STRICT mTLS. If unspecified, the
+	// injection leaves the settings of the Istio sidecars alone.
+	// +kubebuilder:validation:Optional
+	Istio IstioSpec `json:"istio,omitempty"`
+
+	// A Go template for the `OTEL_SERVICE_NAME` env var of the injected containers, e.g.,
+	// `{{ .Namespace }}-{{ .WorkloadName }}` or `{{ index .Labels "app.kubernetes.io/name" }}`.
+	// The template can use `.Namespace`, `.WorkloadKind`, `.WorkloadName`, `.ContainerName`
+	// and the `.Labels` of the pod template. Containers that set `OTEL_SERVICE_NAME`
+	// themselves, or in `extraEnv`, are not affected. If unspecified, the service name
+	// is left to the defaults of the Lumigo distros.
+	// +kubebuilder:validation:Optional
+	ServiceNameTemplate string `json:"serviceNameTemplate,omitempty"`
+
+	// How the Lumigo token is made available to the injected containers: `EnvVar` sets the
+	// `LUMIGO_TRACER_TOKEN` env var from the secret of the Lumigo resource; `ProjectedSecret`
+	// mounts the token as a file from a `lumigo-tracer-token` secret that the operator manages
+	// in the namespace, and sets `LUMIGO_TRACER_TOKEN_FILE` to its path, so that the token
+	// does not show up in the environment of the processes.
+	// If unspecified, defaults to `EnvVar`.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=EnvVar;ProjectedSecret
+	TokenInjectionMode TokenInjectionMode `json:"tokenInjectionMode,omitempty"`
+
+	// What the operator does when the secret with the Lumigo token, or its key, is deleted after this
+	// Lumigo instance has become active; either way, the `TokenMissing` condition is set. With
+	// `StopInjecting`, this Lumigo instance is no longer active, and new resources are not injected.
+	// With `KeepInjecting`, the operator keeps injecting resources with the last valid Lumigo token,
+	// which it keeps in the `lumigo-tracer-token` secret of the namespace.
+	// If unspecified, defaults to `StopInjecting`.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=StopInjecting;KeepInjecting
+	TokenMissingPolicy TokenMissingPolicy `json:"tokenMissingPolicy,omitempty"`
+
+	// The types of the workloads to inject with Lumigo, e.g., `[Deployment, StatefulSet]` to leave
+	// DaemonSets and Jobs alone; workloads of other types are skipped. Supported types are `DaemonSet`,
+	// `Deployment`, `ReplicaSet`, `StatefulSet`, `CronJob`, `Job` and the Keda `ScaledJob`.
+	// If unspecified, workloads of all the supported types are injected.
+	// +kubebuilder:validation:Optional
+	WorkloadTypes []WorkloadType `json:"workloadTypes,omitempty"`
+
+	// Only the workloads created after this time are injected, e.g., to turn on Lumigo in a namespace
+	// with many existing workloads and instrument only the newly deployed ones, while the existing
+	// ones are migrated deliberately, by recreating them or by removing the setting. The workloads
+	// that are already injected keep being so. If unspecified, workloads are injected regardless of
+	// when they were created.
+	// +kubebuilder:validation:Optional
+	OnlyWorkloadsCreatedAfter *metav1.Time `json:"onlyWorkloadsCreatedAfter,omitempty"`
+
+	// Which containers of the injected workloads are instrumented, by their image, e.g., only the images
+	// of `internal-registry/payments/*`, leaving third-party images like `nginx` or `redis` alone.
+	// Containers that are not instrumented keep running as they are, and pods without any container
+	// to instrument are not injected at all. If unspecified, all the containers are instrumented.
+	// +kubebuilder:validation:Optional
+	ImagePatterns ImagePatternsSpec `json:"imagePatterns,omitempty"`
+
+	// Whether the injector webhook denies the creation and update of the workloads of the namespace
+	// that it cannot inject, e.g., because the Lumigo token is invalid or the telemetry-proxy is
+	// unreachable, rather than admitting them without the injection, so that no workload runs
+	// untraced. The workloads opted out with the `lumigo.auto-trace` label, or skipped by the
+	// settings of this Lumigo resource, like `imagePatterns`, are still admitted.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Strict *bool `json:"strict,omitempty"`
+}
+
+// ImagePatternsSpec selects the containers to instrument by their image, as written in the pod template,
+// e.g., `nginx:1.25` or `internal-registry/payments/api:1.2.3`. Patterns are globs, in which `*` matches
+// any sequence of characters, `/` included, and `?` any single character, or, if prefixed with `regex:`,
+// regular expressions; either way, they must match the whole image.
+type ImagePatternsSpec struct {
+	// The patterns of the images of the containers to instrument, e.g., `[internal-registry/payments/*]`.
+	// If unspecified, the containers of all the images not denied are instrumented.
+	// +kubebuilder:validation:Optional
+	Allow []string `json:"allow,omitempty"`
+	// The patterns of the images of the containers not to instrument, e.g., `[*nginx*, *redis*, *postgres*]`;
+	// they take precedence over the allowed ones.
+	// +kubebuilder:validation:Optional
+	Deny []string `json:"deny,omitempty"`
 }
 
+// CompatibilityReportSpec specifies the report of which existing workloads of the namespace can be injected
+type CompatibilityReportSpec struct {
+	// Whether the operator analyzes the workloads of the namespace every few minutes, and reports
+	// how many of them can be injected in `.status.compatibilityReport` and, workload by workload,
+	// in the `lumigo-compatibility-report` ConfigMap of the namespace.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// TelemetryVerificationSpec specifies the verification of the telemetry of the namespace with a synthetic trace
+type TelemetryVerificationSpec struct {
+	// Whether the operator runs, once the namespace is instrumented and whenever this spec changes,
+	// the `lumigo-telemetry-verification` Job in the namespace, which sends a synthetic trace to
+	// the telemetry-proxy, and checks that the telemetry-proxy sends it on to Lumigo.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// PayloadCaptureSpec specifies the payload capture of the Lumigo distros, set with the
+// `LUMIGO_MAX_ENTRY_SIZE` and `LUMIGO_SECRET_MASKING_REGEX_HTTP_*` env vars of the injected containers
+type PayloadCaptureSpec struct {
+	// The maximum size, in bytes, of each captured payload, e.g., of a request body; larger
+	// payloads are truncated. If unspecified, the default of the Lumigo distros applies.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxEntrySize *int32 `json:"maxEntrySize,omitempty"`
+	// Whether the bodies of HTTP requests are captured; if `false`, they are masked entirely.
+	// If unspecified, defaults to `true`.
+	// +kubebuilder:validation:Optional
+	CaptureRequestBodies *bool `json:"captureRequestBodies,omitempty"`
+	// Whether the bodies of HTTP responses are captured; if `false`, they are masked entirely.
+	// If unspecified, defaults to `true`.
+	// +kubebuilder:validation:Optional
+	CaptureResponseBodies *bool `json:"captureResponseBodies,omitempty"`
+	// The names of the HTTP request and response headers whose values are captured, e.g.,
+	// `[content-type, x-request-id]`; the values of all the other headers are masked.
+	// If unspecified, the values of all the headers are captured, except the secrets.
+	// +kubebuilder:validation:Optional
+	// +listType=set
+	AllowedHeaders []HeaderName `json:"allowedHeaders,omitempty"`
+}
+
+// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.-]+$`
+// +kubebuilder:validation:MaxLength=256
+type HeaderName string
+
+// IstioSpec specifies the annotations of the Istio sidecars that the injection sets on the pod
+// templates of the workloads, unless they opt out of the mesh with `sidecar.istio.io/inject: "false"`
+type IstioSpec struct {
+	// Whether the outbound traffic to the ports of the telemetry-proxy bypasses the Istio sidecars,
+	// with the `traffic.sidecar.istio.io/excludeOutboundPorts` annotation, so that the telemetry is
+	// sent even if the sidecar is not ready yet, and is not subject to the mTLS of the mesh.
+	// If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	ExcludeTelemetryProxyPorts *bool `json:"excludeTelemetryProxyPorts,omitempty"`
+	// Whether the containers start only once the Istio sidecar is ready, with the
+	// `holdApplicationUntilProxyStarts` setting of the `proxy.istio.io/config` annotation, so that
+	// the telemetry of their startup is not lost. Workloads that set the `proxy.istio.io/config`
+	// annotation themselves are left untouched. If unspecified, defaults to `false`.
+	// +kubebuilder:validation:Optional
+	HoldApplicationUntilProxyStarts *bool `json:"holdApplicationUntilProxyStarts,omitempty"`
+}
+
+// OpenTelemetryInstrumentationRef references an `Instrumentation` resource of the OpenTelemetry operator
+type OpenTelemetryInstrumentationRef struct {
+	// The name of the `Instrumentation` resource
+	Name string `json:"name"`
+	// The namespace of the `Instrumentation` resource; if unspecified, the namespace of the Lumigo resource
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type UnsupportedArchitecturePolicy string
+
+const (
+	UnsupportedArchitecturePolicySkip         UnsupportedArchitecturePolicy = "Skip"
+	UnsupportedArchitecturePolicyNodeAffinity UnsupportedArchitecturePolicy = "NodeAffinity"
+	UnsupportedArchitecturePolicyIgnore       UnsupportedArchitecturePolicy = "Ignore"
+)
+
+type TokenInjectionMode string
+
+const (
+	TokenInjectionModeEnvVar          TokenInjectionMode = "EnvVar"
+	TokenInjectionModeProjectedSecret TokenInjectionMode = "ProjectedSecret"
+)
+
+type RemovalMode string
+
+const (
+	RemovalModeBlocking   RemovalMode = "Blocking"
+	RemovalModeBackground RemovalMode = "Background"
+)
+
+type TokenMissingPolicy string
+
+const (
+	TokenMissingPolicyStopInjecting TokenMissingPolicy = "StopInjecting"
+	TokenMissingPolicyKeepInjecting TokenMissingPolicy = "KeepInjecting"
+)
+
+// +kubebuilder:validation:Pattern=`^(\*\.)?[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+// +kubebuilder:validation:MaxLength=253
+type Domain string
+
+// +kubebuilder:validation:Enum=tracecontext;baggage;b3;b3multi;jaeger;xray;ottrace
+type Propagator string
+
+const (
+	PropagatorTraceContext Propagator = "tracecontext"
+	PropagatorBaggage      Propagator = "baggage"
+	PropagatorB3           Propagator = "b3"
+	PropagatorB3Multi      Propagator = "b3multi"
+	PropagatorJaeger       Propagator = "jaeger"
+	// The `X-Amzn-Trace-Id` header of AWS X-Ray, e.g., set by the AWS load balancers
+	PropagatorXRay    Propagator = "xray"
+	PropagatorOtTrace Propagator = "ottrace"
+)
+
+// +kubebuilder:validation:Enum=DaemonSet;Deployment;ReplicaSet;StatefulSet;CronJob;Job;ScaledJob
+type WorkloadType string
+
+const (
+	WorkloadTypeDaemonSet   WorkloadType = "DaemonSet"
+	WorkloadTypeDeployment  WorkloadType = "Deployment"
+	WorkloadTypeReplicaSet  WorkloadType = "ReplicaSet"
+	WorkloadTypeStatefulSet WorkloadType = "StatefulSet"
+	WorkloadTypeCronJob     WorkloadType = "CronJob"
+	WorkloadTypeJob         WorkloadType = "Job"
+	// The ScaledJob of Keda (https://keda.sh), which creates jobs out of its pod template
+	WorkloadTypeScaledJob WorkloadType = "ScaledJob"
+)
+
 type InfrastructureSpec struct {
 	// Whether Kubernetes infrastructrure collection should be active.
 	// If unspecified, defaults to `true`
@@ -117,6 +725,16 @@ type InfrastructureSpec struct {
 	// How to collect Kubernetes events and send them to Lumigo.
 	// +kubebuilder:validation:Optional
 	KubeEvents KubeEventsSpec `json:"kubeEvents,omitempty"`
+
+	// How to scrape Prometheus metrics in the namespace and send them to Lumigo.
+	// +kubebuilder:validation:Optional
+	Prometheus PrometheusSpec `json:"prometheus,omitempty"`
+
+	// How to collect the lifecycle signals of the nodes of the cluster, e.g., scale-ups and
+	// scale-downs by Karpenter or the cluster-autoscaler and spot interruptions, and send
+	// them to Lumigo.
+	// +kubebuilder:validation:Optional
+	NodeLifecycle NodeLifecycleSpec `json:"nodeLifecycle,omitempty"`
 }
 
 type KubeEventsSpec struct {
@@ -126,13 +744,212 @@ type KubeEventsSpec struct {
 	Enabled *bool `json:"enabled"` // Using a pointer to support cases where the value is not set (and it counts as enabled)
 }
 
+type NodeLifecycleSpec struct {
+	// Whether the events of the nodes of the cluster should be collected and sent to Lumigo
+	// as logs, and counted in metrics, to correlate latency spikes with node churn.
+	// If unspecified, defaults to `false`
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled"` // Using a pointer to support cases where the value is not set (and it counts as disabled)
+}
+
+type PrometheusSpec struct {
+	// Whether the telemetry-proxy should scrape Prometheus metrics in the namespace
+	// and send them to Lumigo.
+	// If unspecified, defaults to `false`
+	// +kubebuilder:validation:Optional
+	Enabled *bool `json:"enabled"` // Using a pointer to support cases where the value is not set (and it counts as disabled)
+
+	// Whether pods in the namespace with the `prometheus.io/scrape: "true"` annotation
+	// are scraped, honoring the `prometheus.io/path` and `prometheus.io/port` annotations.
+	// If unspecified, defaults to `true`
+	// +kubebuilder:validation:Optional
+	ScrapeAnnotatedPods *bool `json:"scrapeAnnotatedPods,omitempty"`
+
+	// Additional targets to scrape, e.g., services exposing Prometheus metrics
+	// +kubebuilder:validation:Optional
+	ScrapeTargets []PrometheusScrapeTarget `json:"scrapeTargets,omitempty"`
+}
+
+type PrometheusScrapeTarget struct {
+	// The name of the Prometheus job, added as the `job` label to the scraped metrics
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_-]+$`
+	JobName string `json:"jobName"`
+
+	// The `host:port` addresses to scrape, e.g., `my-service:9090`
+	// +kubebuilder:validation:MinItems=1
+	Targets []string `json:"targets"`
+
+	// The HTTP path to scrape the metrics from.
+	// If unspecified, defaults to `/metrics`
+	// +kubebuilder:validation:Optional
+	MetricsPath string `json:"metricsPath,omitempty"`
+
+	// How often to scrape the targets, e.g., `30s`.
+	// If unspecified, defaults to `1m`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9]+(ms|s|m|h)$`
+	ScrapeInterval string `json:"scrapeInterval,omitempty"`
+}
+
 // LumigoStatus defines the observed state of Lumigo
 type LumigoStatus struct {
 	// The status of single Lumigo resources
 	Conditions []LumigoCondition `json:"conditions"`
 
+	// A summary of the conditions, for tools like the health assessments of ArgoCD and Flux
+	// that need a single value: `Pending` until the Lumigo instance is active, `Error` if it
+	// has an error, `Degraded` if it is active but some of its telemetry or injection fails,
+	// `Active` otherwise
+	// +optional
+	Phase LumigoPhase `json:"phase,omitempty"`
+
 	// List of resources instrumented by this Lumigo instance
 	InstrumentedResources []corev1.ObjectReference `json:"instrumentedResources"`
+
+	// The resources that the operator could not inject with Lumigo, e.g., because an admission
+	// policy rejected their update, and when the injection of each is going to be retried.
+	// Resources are removed from the list once their injection succeeds.
+	// +optional
+	FailedInjections []InjectionFailure `json:"failedInjections,omitempty"`
+
+	// The resources whose injection is queued because the limit of workloads updated in one
+	// reconciliation has been reached, in the order in which they are going to be injected.
+	// +optional
+	PendingInjections []corev1.ObjectReference `json:"pendingInjections,omitempty"`
+
+	// The paused Deployments and suspended CronJobs whose injection is deferred until they are
+	// resumed, so that the injection does not roll out as a surprise when they are resumed. They
+	// are usually injected by the injector webhook when they are resumed, or else by the operator.
+	// +optional
+	DeferredInjections []corev1.ObjectReference `json:"deferredInjections,omitempty"`
+
+	// Where the injection of the existing resources of the namespace, which is carried out over
+	// multiple reconciliations in namespaces with many resources, is going to resume from; unset
+	// once all the existing resources have been processed.
+	// +optional
+	InjectionProgress *InjectionProgress `json:"injectionProgress,omitempty"`
+
+	// How many existing workloads of the namespace can be injected with Lumigo, if
+	// `.spec.tracing.injection.compatibilityReport.enabled` is `true`
+	// +optional
+	CompatibilityReport *CompatibilityReport `json:"compatibilityReport,omitempty"`
+
+	// How much telemetry of the namespace the telemetry-proxy sent to Lumigo in each of the
+	// latest days, most recent first.
+	// +optional
+	DailyUsage []DailyUsage `json:"dailyUsage,omitempty"`
+
+	// The env vars imported from the `Instrumentation` resource referenced by
+	// `.spec.tracing.injection.openTelemetryInstrumentationRef`, which are added to the
+	// injected containers
+	// +optional
+	ImportedEnv []corev1.EnvVar `json:"importedEnv,omitempty"`
+
+	// The tags set with the `lumigo.io/tag.<key>` annotations of the namespace, which are added as
+	// resource attributes to the telemetry of the namespace
+	// +optional
+	NamespaceTags map[string]string `json:"namespaceTags,omitempty"`
+
+	// The sampling of the traces of the injected containers, that is, the fields set in
+	// `.spec.tracing.sampling` layered over the cluster-wide defaults of the operator; unset
+	// if neither sets the sampling
+	// +optional
+	EffectiveSampling *SamplingSpec `json:"effectiveSampling,omitempty"`
+
+	// The HTTP headers of `.spec.tracing.otlpHeaders` that the telemetry-proxy sends with the
+	// telemetry of the namespace, with their values redacted
+	// +optional
+	OtlpHeaders map[string]string `json:"otlpHeaders,omitempty"`
+
+	// The `.metadata.generation` of the Lumigo resource that the operator last reconciled: while it
+	// is lower than the current one, the conditions and the rest of the status may not reflect the
+	// latest changes of the spec yet
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The generation of this Lumigo instance whose telemetry was last verified with a synthetic
+	// trace, with the outcome in the `TelemetryVerified` condition, if
+	// `.spec.tracing.injection.telemetryVerification.enabled` is `true`
+	// +optional
+	TelemetryVerifiedGeneration int64 `json:"telemetryVerifiedGeneration,omitempty"`
+}
+
+// DailyUsage describes how much telemetry of the namespace the telemetry-proxy sent to Lumigo in one day
+type DailyUsage struct {
+	// The day, in UTC, formatted as `YYYY-MM-DD`
+	Day string `json:"day"`
+	// How many spans were sent
+	Spans int64 `json:"spans"`
+	// How many log records were sent
+	LogRecords int64 `json:"logRecords"`
+	// How many metric data points were sent
+	MetricPoints int64 `json:"metricPoints"`
+	// The size of the telemetry sent, in bytes of the OTLP protobuf encoding
+	Bytes int64 `json:"bytes"`
+}
+
+// CompatibilityReport summarizes which existing workloads of the namespace can be injected with Lumigo;
+// the ReplicaSets owned by Deployments and the Jobs, whose pod templates cannot be updated, are not counted
+type CompatibilityReport struct {
+	// When the workloads were last analyzed
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+	// How many workloads are already injected
+	Instrumented int32 `json:"instrumented"`
+	// How many workloads can be injected
+	Instrumentable int32 `json:"instrumentable"`
+	// How many workloads opt out of the injection with the `lumigo.auto-trace` label set to `false`
+	OptedOut int32 `json:"optedOut"`
+	// How many workloads are not injected because of their workload type, or because their pods
+	// run on CPU architectures or operating systems that the Lumigo injector does not support
+	UnsupportedRuntime int32 `json:"unsupportedRuntime"`
+	// How many workloads have containers instrumented otherwise, e.g., by the OpenTelemetry
+	// operator or with `LD_PRELOAD`, which conflicts with the injection
+	ConflictingSidecars int32 `json:"conflictingSidecars"`
+	// How many workloads cannot be updated to add the injection, e.g., because an admission
+	// policy rejects the update
+	PolicyBlocked int32 `json:"policyBlocked"`
+	// How many workloads are not injected, by why the injection skips them, e.g., `OptedOut`
+	// or `UnsupportedArchitecture`
+	// +optional
+	SkipReasons map[string]int32 `json:"skipReasons,omitempty"`
+}
+
+// InjectionFailure describes a resource that could not be injected with Lumigo
+type InjectionFailure struct {
+	// The resource that could not be injected
+	Resource corev1.ObjectReference `json:"resource"`
+	// The error of the latest attempt to inject the resource
+	Reason string `json:"reason"`
+	// How many attempts to inject the resource have failed in a row
+	Attempts int32 `json:"attempts"`
+	// When the latest attempt to inject the resource failed
+	LastAttemptTime metav1.Time `json:"lastAttemptTime"`
+	// When the injection of the resource is going to be retried, unless its circuit breaker is open
+	NextRetryTime metav1.Time `json:"nextRetryTime"`
+	// `Closed` while the injection of the resource is retried with a backoff, `Open` once it has failed
+	// too many times in a row, after which it is no longer retried until the circuit breaker is reset
+	// with the `operator.lumigo.io/reset-injection-backoff` annotation of the Lumigo resource
+	// +optional
+	CircuitBreaker CircuitBreakerState `json:"circuitBreaker,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Closed;Open
+type CircuitBreakerState string
+
+const (
+	CircuitBreakerStateClosed CircuitBreakerState = "Closed"
+	CircuitBreakerStateOpen   CircuitBreakerState = "Open"
+)
+
+// InjectionProgress is the checkpoint of the injection of the existing resources of the namespace,
+// which are listed one page at a time
+type InjectionProgress struct {
+	// The kind of the resources being injected, e.g., `Deployment`
+	Kind string `json:"kind"`
+	// The continue token of the next page of resources of that kind to be listed; if unset,
+	// the resources are listed from the first page
+	// +optional
+	Continue string `json:"continue,omitempty"`
 }
 
 type LumigoCondition struct {
@@ -148,6 +965,54 @@ type LumigoConditionType string
 const (
 	LumigoConditionTypeActive LumigoConditionType = "Active"
 	LumigoConditionTypeError  LumigoConditionType = "Error"
+	// Set when the telemetry-proxy drops spans of the namespace because they exceed
+	// the `spec.tracing.maxSpansPerSecond` limit
+	LumigoConditionTypeRateLimited LumigoConditionType = "RateLimited"
+	// Set when the telemetry-proxy has been failing for a while to send to Lumigo the
+	// telemetry of the namespace
+	LumigoConditionTypeTelemetryExportDegraded LumigoConditionType = "TelemetryExportDegraded"
+	// Set when the Lumigo backend cannot be reached from the cluster, or does not accept
+	// the telemetry the telemetry-proxy sends to it
+	LumigoConditionTypeBackendUnreachable LumigoConditionType = "BackendUnreachable"
+	// Set when the operator runs on a platform, like GKE Autopilot or EKS on Fargate,
+	// that does not support some of the features that are enabled
+	LumigoConditionTypeUnsupportedFeatures LumigoConditionType = "UnsupportedFeatures"
+	// Set when the Lumigo instance is paused, and the operator does not change the
+	// instrumentation of the resources in the namespace
+	LumigoConditionTypePaused LumigoConditionType = "Paused"
+	// Set when the namespace has exceeded the daily quota of telemetry set in `spec.quota`,
+	// and the telemetry-proxy samples or drops its spans until the end of the day
+	LumigoConditionTypeQuotaExceeded LumigoConditionType = "QuotaExceeded"
+	// Set when settings of the spec contradict each other, or have no effect because
+	// of other settings, e.g., `injectLumigoIntoExistingResourcesOnCreation` with the
+	// injection disabled
+	LumigoConditionTypeInconsistentSpec LumigoConditionType = "InconsistentSpec"
+	// Set when the secret with the Lumigo token, or its key, has been deleted after the Lumigo
+	// instance has become active; see `spec.tracing.injection.tokenMissingPolicy`
+	LumigoConditionTypeTokenMissing LumigoConditionType = "TokenMissing"
+	// Set when the OTLP endpoint of the telemetry-proxy that the injection advertises to the workloads
+	// of the namespace does not resolve, or does not accept connections; the injector webhook does not
+	// inject the workloads created or updated while it is set
+	LumigoConditionTypeTelemetryProxyUnreachable LumigoConditionType = "TelemetryProxyUnreachable"
+	// Set when the `lumigo-injector` init container of pods of the namespace fails, e.g., because its image
+	// cannot be pulled, or it cannot write to its volume; the message has how many pods fail, and why some do
+	LumigoConditionTypeInjectionRuntimeFailures LumigoConditionType = "InjectionRuntimeFailures"
+	// Set when the synthetic trace of the telemetry verification has been sent to Lumigo by the
+	// telemetry-proxy, or not, if `.spec.tracing.injection.telemetryVerification.enabled` is `true`
+	LumigoConditionTypeTelemetryVerified LumigoConditionType = "TelemetryVerified"
+	// Set when settings of the spec rely on API resources that the cluster does not serve, e.g., the
+	// batch/v1 CronJobs on clusters older than Kubernetes 1.21, or the ScaledJobs without Keda
+	LumigoConditionTypeUnsupportedOnThisCluster LumigoConditionType = "UnsupportedOnThisCluster"
+)
+
+// +kubebuilder:validation:Enum=Pending;Active;Degraded;Error
+type LumigoPhase string
+
+const (
+	LumigoPhasePending  LumigoPhase = "Pending"
+	LumigoPhaseActive   LumigoPhase = "Active"
+	LumigoPhaseDegraded LumigoPhase = "Degraded"
+	LumigoPhaseError    LumigoPhase = "Error"
 )
 
 type LumigoEventReason string
@@ -159,6 +1024,16 @@ const (
 	LumigoEventReasonCannotAddInstrumentation    LumigoEventReason = "LumigoCannotAddInstrumentation"
 	LumigoEventReasonCannotRemoveInstrumentation LumigoEventReason = "LumigoCannotRemoveInstrumentation"
 	LumigoEventReasonCannotUpdateInstrumentation LumigoEventReason = "LumigoCannotUpdateInstrumentation"
+	LumigoEventReasonSkippedInstrumentation      LumigoEventReason = "LumigoSkippedInstrumentation"
+	LumigoEventReasonBackendUnreachable          LumigoEventReason = "LumigoBackendUnreachable"
+	LumigoEventReasonBackendReachable            LumigoEventReason = "LumigoBackendReachable"
+	LumigoEventReasonTokenMissing                LumigoEventReason = "LumigoTokenMissing"
+	LumigoEventReasonTokenFound                  LumigoEventReason = "LumigoTokenFound"
+	LumigoEventReasonTelemetryProxyUnreachable   LumigoEventReason = "LumigoTelemetryProxyUnreachable"
+	LumigoEventReasonTelemetryProxyReachable     LumigoEventReason = "LumigoTelemetryProxyReachable"
+	LumigoEventReasonInjectionRuntimeFailures    LumigoEventReason = "LumigoInjectionRuntimeFailures"
+	LumigoEventReasonAdmissionRejected           LumigoEventReason = "LumigoAdmissionRejected"
+	LumigoEventReasonInjectionRetriesStopped     LumigoEventReason = "LumigoInjectionRetriesStopped"
 )
 
 func init() {
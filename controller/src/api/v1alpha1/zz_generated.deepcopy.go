@@ -26,6 +26,90 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchivalSpec) DeepCopyInto(out *ArchivalSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	out.S3 = in.S3
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchivalSpec.
+func (in *ArchivalSpec) DeepCopy() *ArchivalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchivalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AwsXRaySpec) DeepCopyInto(out *AwsXRaySpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AwsXRaySpec.
+func (in *AwsXRaySpec) DeepCopy() *AwsXRaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AwsXRaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompatibilityReport) DeepCopyInto(out *CompatibilityReport) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.SkipReasons != nil {
+		in, out := &in.SkipReasons, &out.SkipReasons
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompatibilityReport.
+func (in *CompatibilityReport) DeepCopy() *CompatibilityReport {
+	if in == nil {
+		return nil
+	}
+	out := new(CompatibilityReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompatibilityReportSpec) DeepCopyInto(out *CompatibilityReportSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompatibilityReportSpec.
+func (in *CompatibilityReportSpec) DeepCopy() *CompatibilityReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CompatibilityReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Credentials) DeepCopyInto(out *Credentials) {
 	*out = *in
@@ -42,6 +126,116 @@ func (in *Credentials) DeepCopy() *Credentials {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DailyUsage) DeepCopyInto(out *DailyUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DailyUsage.
+func (in *DailyUsage) DeepCopy() *DailyUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(DailyUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugSpec) DeepCopyInto(out *DebugSpec) {
+	*out = *in
+	if in.LogTelemetry != nil {
+		in, out := &in.LogTelemetry, &out.LogTelemetry
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugSpec.
+func (in *DebugSpec) DeepCopy() *DebugSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointsSpec) DeepCopyInto(out *EndpointsSpec) {
+	*out = *in
+	if in.Traces != nil {
+		in, out := &in.Traces, &out.Traces
+		*out = new(OtlpEndpointSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Logs != nil {
+		in, out := &in.Logs, &out.Logs
+		*out = new(OtlpEndpointSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(OtlpEndpointSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointsSpec.
+func (in *EndpointsSpec) DeepCopy() *EndpointsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GoInstrumentationSpec) DeepCopyInto(out *GoInstrumentationSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoInstrumentationSpec.
+func (in *GoInstrumentationSpec) DeepCopy() *GoInstrumentationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GoInstrumentationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePatternsSpec) DeepCopyInto(out *ImagePatternsSpec) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePatternsSpec.
+func (in *ImagePatternsSpec) DeepCopy() *ImagePatternsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePatternsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InfrastructureSpec) DeepCopyInto(out *InfrastructureSpec) {
 	*out = *in
@@ -51,6 +245,8 @@ func (in *InfrastructureSpec) DeepCopyInto(out *InfrastructureSpec) {
 		**out = **in
 	}
 	in.KubeEvents.DeepCopyInto(&out.KubeEvents)
+	in.Prometheus.DeepCopyInto(&out.Prometheus)
+	in.NodeLifecycle.DeepCopyInto(&out.NodeLifecycle)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfrastructureSpec.
@@ -63,6 +259,39 @@ func (in *InfrastructureSpec) DeepCopy() *InfrastructureSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionFailure) DeepCopyInto(out *InjectionFailure) {
+	*out = *in
+	out.Resource = in.Resource
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+	in.NextRetryTime.DeepCopyInto(&out.NextRetryTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionFailure.
+func (in *InjectionFailure) DeepCopy() *InjectionFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionProgress) DeepCopyInto(out *InjectionProgress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionProgress.
+func (in *InjectionProgress) DeepCopy() *InjectionProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InjectionSpec) DeepCopyInto(out *InjectionSpec) {
 	*out = *in
@@ -76,11 +305,52 @@ func (in *InjectionSpec) DeepCopyInto(out *InjectionSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.MaxConcurrentWorkloadUpdates != nil {
+		in, out := &in.MaxConcurrentWorkloadUpdates, &out.MaxConcurrentWorkloadUpdates
+		*out = new(int32)
+		**out = **in
+	}
 	if in.RemoveLumigoFromResourcesOnDeletion != nil {
 		in, out := &in.RemoveLumigoFromResourcesOnDeletion, &out.RemoveLumigoFromResourcesOnDeletion
 		*out = new(bool)
 		**out = **in
 	}
+	in.CompatibilityReport.DeepCopyInto(&out.CompatibilityReport)
+	in.TelemetryVerification.DeepCopyInto(&out.TelemetryVerification)
+	if in.InjectorImagePullSecrets != nil {
+		in, out := &in.InjectorImagePullSecrets, &out.InjectorImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraEnv != nil {
+		in, out := &in.ExtraEnv, &out.ExtraEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OpenTelemetryInstrumentationRef != nil {
+		in, out := &in.OpenTelemetryInstrumentationRef, &out.OpenTelemetryInstrumentationRef
+		*out = new(OpenTelemetryInstrumentationRef)
+		**out = **in
+	}
+	in.PayloadCapture.DeepCopyInto(&out.PayloadCapture)
+	in.Istio.DeepCopyInto(&out.Istio)
+	if in.WorkloadTypes != nil {
+		in, out := &in.WorkloadTypes, &out.WorkloadTypes
+		*out = make([]WorkloadType, len(*in))
+		copy(*out, *in)
+	}
+	if in.OnlyWorkloadsCreatedAfter != nil {
+		in, out := &in.OnlyWorkloadsCreatedAfter, &out.OnlyWorkloadsCreatedAfter
+		*out = (*in).DeepCopy()
+	}
+	in.ImagePatterns.DeepCopyInto(&out.ImagePatterns)
+	if in.Strict != nil {
+		in, out := &in.Strict, &out.Strict
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionSpec.
@@ -93,6 +363,31 @@ func (in *InjectionSpec) DeepCopy() *InjectionSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioSpec) DeepCopyInto(out *IstioSpec) {
+	*out = *in
+	if in.ExcludeTelemetryProxyPorts != nil {
+		in, out := &in.ExcludeTelemetryProxyPorts, &out.ExcludeTelemetryProxyPorts
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HoldApplicationUntilProxyStarts != nil {
+		in, out := &in.HoldApplicationUntilProxyStarts, &out.HoldApplicationUntilProxyStarts
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioSpec.
+func (in *IstioSpec) DeepCopy() *IstioSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeEventsSpec) DeepCopyInto(out *KubeEventsSpec) {
 	*out = *in
@@ -128,6 +423,26 @@ func (in *KubernetesSecretRef) DeepCopy() *KubernetesSecretRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingSpec) DeepCopyInto(out *LoggingSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingSpec.
+func (in *LoggingSpec) DeepCopy() *LoggingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Lumigo) DeepCopyInto(out *Lumigo) {
 	*out = *in
@@ -209,7 +524,18 @@ func (in *LumigoSpec) DeepCopyInto(out *LumigoSpec) {
 	*out = *in
 	out.LumigoToken = in.LumigoToken
 	in.Tracing.DeepCopyInto(&out.Tracing)
+	in.Logging.DeepCopyInto(&out.Logging)
 	in.Infrastructure.DeepCopyInto(&out.Infrastructure)
+	in.Pipelines.DeepCopyInto(&out.Pipelines)
+	in.Endpoints.DeepCopyInto(&out.Endpoints)
+	in.Debug.DeepCopyInto(&out.Debug)
+	in.Archival.DeepCopyInto(&out.Archival)
+	in.Quota.DeepCopyInto(&out.Quota)
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LumigoSpec.
@@ -237,6 +563,64 @@ func (in *LumigoStatus) DeepCopyInto(out *LumigoStatus) {
 		*out = make([]v1.ObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.FailedInjections != nil {
+		in, out := &in.FailedInjections, &out.FailedInjections
+		*out = make([]InjectionFailure, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingInjections != nil {
+		in, out := &in.PendingInjections, &out.PendingInjections
+		*out = make([]v1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeferredInjections != nil {
+		in, out := &in.DeferredInjections, &out.DeferredInjections
+		*out = make([]v1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.InjectionProgress != nil {
+		in, out := &in.InjectionProgress, &out.InjectionProgress
+		*out = new(InjectionProgress)
+		**out = **in
+	}
+	if in.CompatibilityReport != nil {
+		in, out := &in.CompatibilityReport, &out.CompatibilityReport
+		*out = new(CompatibilityReport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DailyUsage != nil {
+		in, out := &in.DailyUsage, &out.DailyUsage
+		*out = make([]DailyUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImportedEnv != nil {
+		in, out := &in.ImportedEnv, &out.ImportedEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NamespaceTags != nil {
+		in, out := &in.NamespaceTags, &out.NamespaceTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EffectiveSampling != nil {
+		in, out := &in.EffectiveSampling, &out.EffectiveSampling
+		*out = new(SamplingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OtlpHeaders != nil {
+		in, out := &in.OtlpHeaders, &out.OtlpHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LumigoStatus.
@@ -249,10 +633,401 @@ func (in *LumigoStatus) DeepCopy() *LumigoStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeLifecycleSpec) DeepCopyInto(out *NodeLifecycleSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeLifecycleSpec.
+func (in *NodeLifecycleSpec) DeepCopy() *NodeLifecycleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeLifecycleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenTelemetryInstrumentationRef) DeepCopyInto(out *OpenTelemetryInstrumentationRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenTelemetryInstrumentationRef.
+func (in *OpenTelemetryInstrumentationRef) DeepCopy() *OpenTelemetryInstrumentationRef {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenTelemetryInstrumentationRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OtlpEndpointSpec) DeepCopyInto(out *OtlpEndpointSpec) {
+	*out = *in
+	if in.HeadersSecretRef != nil {
+		in, out := &in.HeadersSecretRef, &out.HeadersSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OtlpEndpointSpec.
+func (in *OtlpEndpointSpec) DeepCopy() *OtlpEndpointSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OtlpEndpointSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OtlpExporterSpec) DeepCopyInto(out *OtlpExporterSpec) {
+	*out = *in
+	if in.HeadersSecretRef != nil {
+		in, out := &in.HeadersSecretRef, &out.HeadersSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OtlpExporterSpec.
+func (in *OtlpExporterSpec) DeepCopy() *OtlpExporterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OtlpExporterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OtlpHeaderSpec) DeepCopyInto(out *OtlpHeaderSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OtlpHeaderSpec.
+func (in *OtlpHeaderSpec) DeepCopy() *OtlpHeaderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OtlpHeaderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayloadCaptureSpec) DeepCopyInto(out *PayloadCaptureSpec) {
+	*out = *in
+	if in.MaxEntrySize != nil {
+		in, out := &in.MaxEntrySize, &out.MaxEntrySize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CaptureRequestBodies != nil {
+		in, out := &in.CaptureRequestBodies, &out.CaptureRequestBodies
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CaptureResponseBodies != nil {
+		in, out := &in.CaptureResponseBodies, &out.CaptureResponseBodies
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AllowedHeaders != nil {
+		in, out := &in.AllowedHeaders, &out.AllowedHeaders
+		*out = make([]HeaderName, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayloadCaptureSpec.
+func (in *PayloadCaptureSpec) DeepCopy() *PayloadCaptureSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PayloadCaptureSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSpec) DeepCopyInto(out *PipelineSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSpec.
+func (in *PipelineSpec) DeepCopy() *PipelineSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelinesSpec) DeepCopyInto(out *PipelinesSpec) {
+	*out = *in
+	in.Traces.DeepCopyInto(&out.Traces)
+	in.Logs.DeepCopyInto(&out.Logs)
+	in.Metrics.DeepCopyInto(&out.Metrics)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelinesSpec.
+func (in *PipelinesSpec) DeepCopy() *PipelinesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelinesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusScrapeTarget) DeepCopyInto(out *PrometheusScrapeTarget) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusScrapeTarget.
+func (in *PrometheusScrapeTarget) DeepCopy() *PrometheusScrapeTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusScrapeTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSpec) DeepCopyInto(out *PrometheusSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ScrapeAnnotatedPods != nil {
+		in, out := &in.ScrapeAnnotatedPods, &out.ScrapeAnnotatedPods
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ScrapeTargets != nil {
+		in, out := &in.ScrapeTargets, &out.ScrapeTargets
+		*out = make([]PrometheusScrapeTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSpec.
+func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaSpec) DeepCopyInto(out *QuotaSpec) {
+	*out = *in
+	if in.MaxSpansPerDay != nil {
+		in, out := &in.MaxSpansPerDay, &out.MaxSpansPerDay
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxGigabytesPerDay != nil {
+		in, out := &in.MaxGigabytesPerDay, &out.MaxGigabytesPerDay
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SamplingPercentageWhenExceeded != nil {
+		in, out := &in.SamplingPercentageWhenExceeded, &out.SamplingPercentageWhenExceeded
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaSpec.
+func (in *QuotaSpec) DeepCopy() *QuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3ArchivalSpec) DeepCopyInto(out *S3ArchivalSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3ArchivalSpec.
+func (in *S3ArchivalSpec) DeepCopy() *S3ArchivalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(S3ArchivalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SamplingSpec) DeepCopyInto(out *SamplingSpec) {
+	*out = *in
+	if in.Percentage != nil {
+		in, out := &in.Percentage, &out.Percentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ParentBased != nil {
+		in, out := &in.ParentBased, &out.ParentBased
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SamplingSpec.
+func (in *SamplingSpec) DeepCopy() *SamplingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SamplingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpanMetricsSpec) DeepCopyInto(out *SpanMetricsSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Dimensions != nil {
+		in, out := &in.Dimensions, &out.Dimensions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpanMetricsSpec.
+func (in *SpanMetricsSpec) DeepCopy() *SpanMetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SpanMetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TelemetryVerificationSpec) DeepCopyInto(out *TelemetryVerificationSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TelemetryVerificationSpec.
+func (in *TelemetryVerificationSpec) DeepCopy() *TelemetryVerificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TelemetryVerificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingRoute) DeepCopyInto(out *TracingRoute) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	out.LumigoToken = in.LumigoToken
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingRoute.
+func (in *TracingRoute) DeepCopy() *TracingRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TracingSpec) DeepCopyInto(out *TracingSpec) {
 	*out = *in
 	in.Injection.DeepCopyInto(&out.Injection)
+	in.GoInstrumentation.DeepCopyInto(&out.GoInstrumentation)
+	in.SpanMetrics.DeepCopyInto(&out.SpanMetrics)
+	if in.MaxSpansPerSecond != nil {
+		in, out := &in.MaxSpansPerSecond, &out.MaxSpansPerSecond
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AdditionalExporters != nil {
+		in, out := &in.AdditionalExporters, &out.AdditionalExporters
+		*out = make([]OtlpExporterSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]TracingRoute, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Propagators != nil {
+		in, out := &in.Propagators, &out.Propagators
+		*out = make([]Propagator, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkipDomains != nil {
+		in, out := &in.SkipDomains, &out.SkipDomains
+		*out = make([]Domain, len(*in))
+		copy(*out, *in)
+	}
+	in.Sampling.DeepCopyInto(&out.Sampling)
+	if in.DedicatedProxy != nil {
+		in, out := &in.DedicatedProxy, &out.DedicatedProxy
+		*out = new(bool)
+		**out = **in
+	}
+	in.AwsXRay.DeepCopyInto(&out.AwsXRay)
+	if in.OtlpHeaders != nil {
+		in, out := &in.OtlpHeaders, &out.OtlpHeaders
+		*out = make([]OtlpHeaderSpec, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingSpec.
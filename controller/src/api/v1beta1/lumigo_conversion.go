@@ -0,0 +1,383 @@
+package v1beta1
+
+import (
+	"github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// The v1beta1 spec only renames and regroups the fields of v1alpha1, so the
+// conversion is lossless in both directions.
+
+// ConvertTo converts this Lumigo to the hub version (v1alpha1)
+func (src *Lumigo) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.Lumigo)
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.LumigoToken = v1alpha1.Credentials{
+		SecretRef: v1alpha1.KubernetesSecretRef(src.Spec.LumigoToken.SecretRef),
+		Value:     src.Spec.LumigoToken.Value,
+	}
+
+	injection := src.Spec.Tracing.Injection
+	dst.Spec.Tracing = v1alpha1.TracingSpec{
+		Injection: v1alpha1.InjectionSpec{
+			Enabled: injection.Enabled,
+			InjectLumigoIntoExistingResourcesOnCreation: injection.InjectExistingResources,
+			MaxConcurrentWorkloadUpdates:                injection.MaxConcurrentWorkloadUpdates,
+			RemoveLumigoFromResourcesOnDeletion:         injection.RemoveInjectionOnDeletion,
+			RemovalMode:                                 v1alpha1.RemovalMode(injection.RemovalMode),
+			CompatibilityReport:                         v1alpha1.CompatibilityReportSpec(injection.CompatibilityReport),
+			TelemetryVerification:                       v1alpha1.TelemetryVerificationSpec(injection.TelemetryVerification),
+			InjectorImagePullPolicy:                     injection.InjectorImage.PullPolicy,
+			InjectorImagePullSecrets:                    injection.InjectorImage.PullSecrets,
+			UnsupportedArchitecturePolicy:               v1alpha1.UnsupportedArchitecturePolicy(injection.UnsupportedArchitecturePolicy),
+			ExtraEnv:                                    injection.ExtraEnv,
+			OpenTelemetryInstrumentationRef:             (*v1alpha1.OpenTelemetryInstrumentationRef)(injection.OpenTelemetryInstrumentationRef),
+			ServiceNameTemplate:                         injection.ServiceNameTemplate,
+			TokenInjectionMode:                          v1alpha1.TokenInjectionMode(injection.TokenInjectionMode),
+			TokenMissingPolicy:                          v1alpha1.TokenMissingPolicy(injection.TokenMissingPolicy),
+			Strict:                                      injection.Strict,
+			Istio:                                       v1alpha1.IstioSpec(injection.Istio),
+			OnlyWorkloadsCreatedAfter:                   injection.OnlyWorkloadsCreatedAfter,
+		},
+		GoInstrumentation: v1alpha1.GoInstrumentationSpec(src.Spec.Tracing.GoInstrumentation),
+		SpanMetrics:       v1alpha1.SpanMetricsSpec(src.Spec.Tracing.SpanMetrics),
+		MaxSpansPerSecond: src.Spec.Tracing.RateLimiting.MaxSpansPerSecond,
+		Sampling:          v1alpha1.SamplingSpec(src.Spec.Tracing.Sampling),
+		DedicatedProxy:    src.Spec.Tracing.DedicatedProxy,
+		AwsXRay:           v1alpha1.AwsXRaySpec(src.Spec.Tracing.AwsXRay),
+	}
+	if injection.WorkloadTypes != nil {
+		dst.Spec.Tracing.Injection.WorkloadTypes = make([]v1alpha1.WorkloadType, len(injection.WorkloadTypes))
+		for i, workloadType := range injection.WorkloadTypes {
+			dst.Spec.Tracing.Injection.WorkloadTypes[i] = v1alpha1.WorkloadType(workloadType)
+		}
+	}
+	dst.Spec.Tracing.Injection.PayloadCapture = v1alpha1.PayloadCaptureSpec{
+		MaxEntrySize:          injection.PayloadCapture.MaxEntrySize,
+		CaptureRequestBodies:  injection.PayloadCapture.CaptureRequestBodies,
+		CaptureResponseBodies: injection.PayloadCapture.CaptureResponseBodies,
+	}
+	if injection.PayloadCapture.AllowedHeaders != nil {
+		dst.Spec.Tracing.Injection.PayloadCapture.AllowedHeaders = make([]v1alpha1.HeaderName, len(injection.PayloadCapture.AllowedHeaders))
+		for i, headerName := range injection.PayloadCapture.AllowedHeaders {
+			dst.Spec.Tracing.Injection.PayloadCapture.AllowedHeaders[i] = v1alpha1.HeaderName(headerName)
+		}
+	}
+	dst.Spec.Tracing.Injection.ImagePatterns = v1alpha1.ImagePatternsSpec{
+		Allow: injection.ImagePatterns.Allow,
+		Deny:  injection.ImagePatterns.Deny,
+	}
+	if src.Spec.Tracing.AdditionalExporters != nil {
+		dst.Spec.Tracing.AdditionalExporters = make([]v1alpha1.OtlpExporterSpec, len(src.Spec.Tracing.AdditionalExporters))
+		for i, exporter := range src.Spec.Tracing.AdditionalExporters {
+			dst.Spec.Tracing.AdditionalExporters[i] = v1alpha1.OtlpExporterSpec(exporter)
+		}
+	}
+	if src.Spec.Tracing.Routes != nil {
+		dst.Spec.Tracing.Routes = make([]v1alpha1.TracingRoute, len(src.Spec.Tracing.Routes))
+		for i, route := range src.Spec.Tracing.Routes {
+			dst.Spec.Tracing.Routes[i] = v1alpha1.TracingRoute{
+				Name:     route.Name,
+				Selector: route.Selector,
+				LumigoToken: v1alpha1.Credentials{
+					SecretRef: v1alpha1.KubernetesSecretRef(route.LumigoToken.SecretRef),
+					Value:     route.LumigoToken.Value,
+				},
+			}
+		}
+	}
+	if src.Spec.Tracing.Propagators != nil {
+		dst.Spec.Tracing.Propagators = make([]v1alpha1.Propagator, len(src.Spec.Tracing.Propagators))
+		for i, propagator := range src.Spec.Tracing.Propagators {
+			dst.Spec.Tracing.Propagators[i] = v1alpha1.Propagator(propagator)
+		}
+	}
+	if src.Spec.Tracing.SkipDomains != nil {
+		dst.Spec.Tracing.SkipDomains = make([]v1alpha1.Domain, len(src.Spec.Tracing.SkipDomains))
+		for i, domain := range src.Spec.Tracing.SkipDomains {
+			dst.Spec.Tracing.SkipDomains[i] = v1alpha1.Domain(domain)
+		}
+	}
+	if src.Spec.Tracing.OtlpHeaders != nil {
+		dst.Spec.Tracing.OtlpHeaders = make([]v1alpha1.OtlpHeaderSpec, len(src.Spec.Tracing.OtlpHeaders))
+		for i, header := range src.Spec.Tracing.OtlpHeaders {
+			dst.Spec.Tracing.OtlpHeaders[i] = v1alpha1.OtlpHeaderSpec{
+				Name:      header.Name,
+				SecretRef: v1alpha1.KubernetesSecretRef(header.SecretRef),
+			}
+		}
+	}
+
+	dst.Spec.Logging = v1alpha1.LoggingSpec(src.Spec.Logging)
+	dst.Spec.Pipelines = v1alpha1.PipelinesSpec{
+		Traces:  v1alpha1.PipelineSpec(src.Spec.Pipelines.Traces),
+		Logs:    v1alpha1.PipelineSpec(src.Spec.Pipelines.Logs),
+		Metrics: v1alpha1.PipelineSpec(src.Spec.Pipelines.Metrics),
+	}
+	dst.Spec.Endpoints = v1alpha1.EndpointsSpec{
+		Traces:  (*v1alpha1.OtlpEndpointSpec)(src.Spec.Endpoints.Traces),
+		Logs:    (*v1alpha1.OtlpEndpointSpec)(src.Spec.Endpoints.Logs),
+		Metrics: (*v1alpha1.OtlpEndpointSpec)(src.Spec.Endpoints.Metrics),
+	}
+
+	dst.Spec.Infrastructure = v1alpha1.InfrastructureSpec{
+		Enabled:    src.Spec.Infrastructure.Enabled,
+		KubeEvents: v1alpha1.KubeEventsSpec(src.Spec.Infrastructure.KubeEvents),
+		Prometheus: v1alpha1.PrometheusSpec{
+			Enabled:             src.Spec.Infrastructure.Prometheus.Enabled,
+			ScrapeAnnotatedPods: src.Spec.Infrastructure.Prometheus.ScrapeAnnotatedPods,
+		},
+		NodeLifecycle: v1alpha1.NodeLifecycleSpec(src.Spec.Infrastructure.NodeLifecycle),
+	}
+	if src.Spec.Infrastructure.Prometheus.ScrapeTargets != nil {
+		dst.Spec.Infrastructure.Prometheus.ScrapeTargets = make([]v1alpha1.PrometheusScrapeTarget, len(src.Spec.Infrastructure.Prometheus.ScrapeTargets))
+		for i, target := range src.Spec.Infrastructure.Prometheus.ScrapeTargets {
+			dst.Spec.Infrastructure.Prometheus.ScrapeTargets[i] = v1alpha1.PrometheusScrapeTarget(target)
+		}
+	}
+
+	dst.Spec.Archival = v1alpha1.ArchivalSpec{
+		Enabled: src.Spec.Archival.Enabled,
+		S3:      v1alpha1.S3ArchivalSpec(src.Spec.Archival.S3),
+	}
+	dst.Spec.Debug = v1alpha1.DebugSpec(src.Spec.Debug)
+	dst.Spec.Quota = v1alpha1.QuotaSpec(src.Spec.Quota)
+	dst.Spec.Paused = src.Spec.Paused
+
+	dst.Status.InstrumentedResources = src.Status.InstrumentedResources
+	if src.Status.Conditions != nil {
+		dst.Status.Conditions = make([]v1alpha1.LumigoCondition, len(src.Status.Conditions))
+		for i, condition := range src.Status.Conditions {
+			dst.Status.Conditions[i] = v1alpha1.LumigoCondition{
+				Type:               v1alpha1.LumigoConditionType(condition.Type),
+				Status:             condition.Status,
+				LastUpdateTime:     condition.LastUpdateTime,
+				LastTransitionTime: condition.LastTransitionTime,
+				Message:            condition.Message,
+			}
+		}
+	}
+	if src.Status.FailedInjections != nil {
+		dst.Status.FailedInjections = make([]v1alpha1.InjectionFailure, len(src.Status.FailedInjections))
+		for i, failure := range src.Status.FailedInjections {
+			dst.Status.FailedInjections[i] = v1alpha1.InjectionFailure{
+				Resource:        failure.Resource,
+				Reason:          failure.Reason,
+				Attempts:        failure.Attempts,
+				LastAttemptTime: failure.LastAttemptTime,
+				NextRetryTime:   failure.NextRetryTime,
+				CircuitBreaker:  v1alpha1.CircuitBreakerState(failure.CircuitBreaker),
+			}
+		}
+	}
+	if src.Status.DailyUsage != nil {
+		dst.Status.DailyUsage = make([]v1alpha1.DailyUsage, len(src.Status.DailyUsage))
+		for i, usage := range src.Status.DailyUsage {
+			dst.Status.DailyUsage[i] = v1alpha1.DailyUsage(usage)
+		}
+	}
+	dst.Status.PendingInjections = src.Status.PendingInjections
+	dst.Status.DeferredInjections = src.Status.DeferredInjections
+	dst.Status.InjectionProgress = (*v1alpha1.InjectionProgress)(src.Status.InjectionProgress)
+	dst.Status.CompatibilityReport = (*v1alpha1.CompatibilityReport)(src.Status.CompatibilityReport)
+	dst.Status.ImportedEnv = src.Status.ImportedEnv
+	dst.Status.NamespaceTags = src.Status.NamespaceTags
+	dst.Status.EffectiveSampling = (*v1alpha1.SamplingSpec)(src.Status.EffectiveSampling)
+	dst.Status.OtlpHeaders = src.Status.OtlpHeaders
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.TelemetryVerifiedGeneration = src.Status.TelemetryVerifiedGeneration
+	dst.Status.Phase = v1alpha1.LumigoPhase(src.Status.Phase)
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1alpha1) to this version
+func (dst *Lumigo) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.Lumigo)
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.LumigoToken = Credentials{
+		SecretRef: KubernetesSecretRef(src.Spec.LumigoToken.SecretRef),
+		Value:     src.Spec.LumigoToken.Value,
+	}
+
+	injection := src.Spec.Tracing.Injection
+	dst.Spec.Tracing = TracingSpec{
+		Injection: InjectionSpec{
+			Enabled:                      injection.Enabled,
+			InjectExistingResources:      injection.InjectLumigoIntoExistingResourcesOnCreation,
+			MaxConcurrentWorkloadUpdates: injection.MaxConcurrentWorkloadUpdates,
+			RemoveInjectionOnDeletion:    injection.RemoveLumigoFromResourcesOnDeletion,
+			RemovalMode:                  RemovalMode(injection.RemovalMode),
+			CompatibilityReport:          CompatibilityReportSpec(injection.CompatibilityReport),
+			TelemetryVerification:        TelemetryVerificationSpec(injection.TelemetryVerification),
+			InjectorImage: InjectorImageSpec{
+				PullPolicy:  injection.InjectorImagePullPolicy,
+				PullSecrets: injection.InjectorImagePullSecrets,
+			},
+			UnsupportedArchitecturePolicy:   UnsupportedArchitecturePolicy(injection.UnsupportedArchitecturePolicy),
+			ExtraEnv:                        injection.ExtraEnv,
+			OpenTelemetryInstrumentationRef: (*OpenTelemetryInstrumentationRef)(injection.OpenTelemetryInstrumentationRef),
+			ServiceNameTemplate:             injection.ServiceNameTemplate,
+			TokenInjectionMode:              TokenInjectionMode(injection.TokenInjectionMode),
+			TokenMissingPolicy:              TokenMissingPolicy(injection.TokenMissingPolicy),
+			OnlyWorkloadsCreatedAfter:       injection.OnlyWorkloadsCreatedAfter,
+			Strict:                          injection.Strict,
+			Istio:                           IstioSpec(injection.Istio),
+		},
+		GoInstrumentation: GoInstrumentationSpec(src.Spec.Tracing.GoInstrumentation),
+		SpanMetrics:       SpanMetricsSpec(src.Spec.Tracing.SpanMetrics),
+		RateLimiting: RateLimitingSpec{
+			MaxSpansPerSecond: src.Spec.Tracing.MaxSpansPerSecond,
+		},
+		Sampling:       SamplingSpec(src.Spec.Tracing.Sampling),
+		DedicatedProxy: src.Spec.Tracing.DedicatedProxy,
+		AwsXRay:        AwsXRaySpec(src.Spec.Tracing.AwsXRay),
+	}
+	if injection.WorkloadTypes != nil {
+		dst.Spec.Tracing.Injection.WorkloadTypes = make([]WorkloadType, len(injection.WorkloadTypes))
+		for i, workloadType := range injection.WorkloadTypes {
+			dst.Spec.Tracing.Injection.WorkloadTypes[i] = WorkloadType(workloadType)
+		}
+	}
+	dst.Spec.Tracing.Injection.PayloadCapture = PayloadCaptureSpec{
+		MaxEntrySize:          injection.PayloadCapture.MaxEntrySize,
+		CaptureRequestBodies:  injection.PayloadCapture.CaptureRequestBodies,
+		CaptureResponseBodies: injection.PayloadCapture.CaptureResponseBodies,
+	}
+	if injection.PayloadCapture.AllowedHeaders != nil {
+		dst.Spec.Tracing.Injection.PayloadCapture.AllowedHeaders = make([]HeaderName, len(injection.PayloadCapture.AllowedHeaders))
+		for i, headerName := range injection.PayloadCapture.AllowedHeaders {
+			dst.Spec.Tracing.Injection.PayloadCapture.AllowedHeaders[i] = HeaderName(headerName)
+		}
+	}
+	dst.Spec.Tracing.Injection.ImagePatterns = ImagePatternsSpec{
+		Allow: injection.ImagePatterns.Allow,
+		Deny:  injection.ImagePatterns.Deny,
+	}
+	if src.Spec.Tracing.AdditionalExporters != nil {
+		dst.Spec.Tracing.AdditionalExporters = make([]OtlpExporterSpec, len(src.Spec.Tracing.AdditionalExporters))
+		for i, exporter := range src.Spec.Tracing.AdditionalExporters {
+			dst.Spec.Tracing.AdditionalExporters[i] = OtlpExporterSpec(exporter)
+		}
+	}
+	if src.Spec.Tracing.Routes != nil {
+		dst.Spec.Tracing.Routes = make([]TracingRoute, len(src.Spec.Tracing.Routes))
+		for i, route := range src.Spec.Tracing.Routes {
+			dst.Spec.Tracing.Routes[i] = TracingRoute{
+				Name:     route.Name,
+				Selector: route.Selector,
+				LumigoToken: Credentials{
+					SecretRef: KubernetesSecretRef(route.LumigoToken.SecretRef),
+					Value:     route.LumigoToken.Value,
+				},
+			}
+		}
+	}
+	if src.Spec.Tracing.Propagators != nil {
+		dst.Spec.Tracing.Propagators = make([]Propagator, len(src.Spec.Tracing.Propagators))
+		for i, propagator := range src.Spec.Tracing.Propagators {
+			dst.Spec.Tracing.Propagators[i] = Propagator(propagator)
+		}
+	}
+	if src.Spec.Tracing.SkipDomains != nil {
+		dst.Spec.Tracing.SkipDomains = make([]Domain, len(src.Spec.Tracing.SkipDomains))
+		for i, domain := range src.Spec.Tracing.SkipDomains {
+			dst.Spec.Tracing.SkipDomains[i] = Domain(domain)
+		}
+	}
+	if src.Spec.Tracing.OtlpHeaders != nil {
+		dst.Spec.Tracing.OtlpHeaders = make([]OtlpHeaderSpec, len(src.Spec.Tracing.OtlpHeaders))
+		for i, header := range src.Spec.Tracing.OtlpHeaders {
+			dst.Spec.Tracing.OtlpHeaders[i] = OtlpHeaderSpec{
+				Name:      header.Name,
+				SecretRef: KubernetesSecretRef(header.SecretRef),
+			}
+		}
+	}
+
+	dst.Spec.Logging = LoggingSpec(src.Spec.Logging)
+	dst.Spec.Pipelines = PipelinesSpec{
+		Traces:  PipelineSpec(src.Spec.Pipelines.Traces),
+		Logs:    PipelineSpec(src.Spec.Pipelines.Logs),
+		Metrics: PipelineSpec(src.Spec.Pipelines.Metrics),
+	}
+	dst.Spec.Endpoints = EndpointsSpec{
+		Traces:  (*OtlpEndpointSpec)(src.Spec.Endpoints.Traces),
+		Logs:    (*OtlpEndpointSpec)(src.Spec.Endpoints.Logs),
+		Metrics: (*OtlpEndpointSpec)(src.Spec.Endpoints.Metrics),
+	}
+
+	dst.Spec.Infrastructure = InfrastructureSpec{
+		Enabled:    src.Spec.Infrastructure.Enabled,
+		KubeEvents: KubeEventsSpec(src.Spec.Infrastructure.KubeEvents),
+		Prometheus: PrometheusSpec{
+			Enabled:             src.Spec.Infrastructure.Prometheus.Enabled,
+			ScrapeAnnotatedPods: src.Spec.Infrastructure.Prometheus.ScrapeAnnotatedPods,
+		},
+		NodeLifecycle: NodeLifecycleSpec(src.Spec.Infrastructure.NodeLifecycle),
+	}
+	if src.Spec.Infrastructure.Prometheus.ScrapeTargets != nil {
+		dst.Spec.Infrastructure.Prometheus.ScrapeTargets = make([]PrometheusScrapeTarget, len(src.Spec.Infrastructure.Prometheus.ScrapeTargets))
+		for i, target := range src.Spec.Infrastructure.Prometheus.ScrapeTargets {
+			dst.Spec.Infrastructure.Prometheus.ScrapeTargets[i] = PrometheusScrapeTarget(target)
+		}
+	}
+
+	dst.Spec.Archival = ArchivalSpec{
+		Enabled: src.Spec.Archival.Enabled,
+		S3:      S3ArchivalSpec(src.Spec.Archival.S3),
+	}
+	dst.Spec.Debug = DebugSpec(src.Spec.Debug)
+	dst.Spec.Quota = QuotaSpec(src.Spec.Quota)
+	dst.Spec.Paused = src.Spec.Paused
+
+	dst.Status.InstrumentedResources = src.Status.InstrumentedResources
+	if src.Status.Conditions != nil {
+		dst.Status.Conditions = make([]LumigoCondition, len(src.Status.Conditions))
+		for i, condition := range src.Status.Conditions {
+			dst.Status.Conditions[i] = LumigoCondition{
+				Type:               LumigoConditionType(condition.Type),
+				Status:             condition.Status,
+				LastUpdateTime:     condition.LastUpdateTime,
+				LastTransitionTime: condition.LastTransitionTime,
+				Message:            condition.Message,
+			}
+		}
+	}
+	if src.Status.FailedInjections != nil {
+		dst.Status.FailedInjections = make([]InjectionFailure, len(src.Status.FailedInjections))
+		for i, failure := range src.Status.FailedInjections {
+			dst.Status.FailedInjections[i] = InjectionFailure{
+				Resource:        failure.Resource,
+				Reason:          failure.Reason,
+				Attempts:        failure.Attempts,
+				LastAttemptTime: failure.LastAttemptTime,
+				NextRetryTime:   failure.NextRetryTime,
+				CircuitBreaker:  CircuitBreakerState(failure.CircuitBreaker),
+			}
+		}
+	}
+	if src.Status.DailyUsage != nil {
+		dst.Status.DailyUsage = make([]DailyUsage, len(src.Status.DailyUsage))
+		for i, usage := range src.Status.DailyUsage {
+			dst.Status.DailyUsage[i] = DailyUsage(usage)
+		}
+	}
+	dst.Status.PendingInjections = src.Status.PendingInjections
+	dst.Status.DeferredInjections = src.Status.DeferredInjections
+	dst.Status.InjectionProgress = (*InjectionProgress)(src.Status.InjectionProgress)
+	dst.Status.CompatibilityReport = (*CompatibilityReport)(src.Status.CompatibilityReport)
+	dst.Status.ImportedEnv = src.Status.ImportedEnv
+	dst.Status.NamespaceTags = src.Status.NamespaceTags
+	dst.Status.EffectiveSampling = (*SamplingSpec)(src.Status.EffectiveSampling)
+	dst.Status.OtlpHeaders = src.Status.OtlpHeaders
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.TelemetryVerifiedGeneration = src.Status.TelemetryVerifiedGeneration
+	dst.Status.Phase = LumigoPhase(src.Status.Phase)
+
+	return nil
+}
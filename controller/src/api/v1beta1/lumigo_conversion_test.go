@@ -0,0 +1,352 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestConversion(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Conversion Suite")
+}
+
+var _ = Describe("Lumigo conversion", func() {
+
+	newBool := func(b bool) *bool {
+		return &b
+	}
+
+	newInt32 := func(i int32) *int32 {
+		return &i
+	}
+
+	newInt64 := func(i int64) *int64 {
+		return &i
+	}
+
+	newV1alpha1Lumigo := func() *v1alpha1.Lumigo {
+		return &v1alpha1.Lumigo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-namespace",
+				Name:      "lumigo",
+			},
+			Spec: v1alpha1.LumigoSpec{
+				LumigoToken: v1alpha1.Credentials{
+					SecretRef: v1alpha1.KubernetesSecretRef{
+						Name: "lumigo-credentials",
+						Key:  "token",
+					},
+				},
+				Tracing: v1alpha1.TracingSpec{
+					Injection: v1alpha1.InjectionSpec{
+						Enabled: newBool(true),
+						InjectLumigoIntoExistingResourcesOnCreation: newBool(false),
+						MaxConcurrentWorkloadUpdates:                newInt32(20),
+						RemoveLumigoFromResourcesOnDeletion:         newBool(true),
+						RemovalMode:                                 v1alpha1.RemovalModeBackground,
+						CompatibilityReport: v1alpha1.CompatibilityReportSpec{
+							Enabled: newBool(true),
+						},
+						TelemetryVerification: v1alpha1.TelemetryVerificationSpec{
+							Enabled: newBool(true),
+						},
+						InjectorImagePullPolicy: corev1.PullIfNotPresent,
+						InjectorImagePullSecrets: []corev1.LocalObjectReference{
+							{Name: "mirror-credentials"},
+						},
+						UnsupportedArchitecturePolicy: v1alpha1.UnsupportedArchitecturePolicyNodeAffinity,
+						ExtraEnv: []corev1.EnvVar{
+							{Name: "LUMIGO_DEBUG", Value: "true"},
+						},
+						OpenTelemetryInstrumentationRef: &v1alpha1.OpenTelemetryInstrumentationRef{
+							Name:      "my-instrumentation",
+							Namespace: "otel",
+						},
+						PayloadCapture: v1alpha1.PayloadCaptureSpec{
+							MaxEntrySize:         newInt32(4096),
+							CaptureRequestBodies: newBool(false),
+							AllowedHeaders:       []v1alpha1.HeaderName{"content-type"},
+						},
+						ServiceNameTemplate: "{{ .Namespace }}-{{ .WorkloadName }}",
+						TokenInjectionMode:  v1alpha1.TokenInjectionModeProjectedSecret,
+						TokenMissingPolicy:  v1alpha1.TokenMissingPolicyKeepInjecting,
+						WorkloadTypes: []v1alpha1.WorkloadType{
+							v1alpha1.WorkloadTypeDeployment,
+							v1alpha1.WorkloadTypeStatefulSet,
+						},
+						ImagePatterns: v1alpha1.ImagePatternsSpec{
+							Allow: []string{"internal-registry/payments/*"},
+							Deny:  []string{"regex:.*(nginx|redis).*"},
+						},
+						Strict: newBool(true),
+						Istio: v1alpha1.IstioSpec{
+							ExcludeTelemetryProxyPorts: newBool(true),
+						},
+					},
+					GoInstrumentation: v1alpha1.GoInstrumentationSpec{
+						Enabled: newBool(true),
+					},
+					SpanMetrics: v1alpha1.SpanMetricsSpec{
+						Enabled:    newBool(true),
+						Dimensions: []string{"http.route"},
+					},
+					MaxSpansPerSecond: newInt32(100),
+					AdditionalExporters: []v1alpha1.OtlpExporterSpec{
+						{
+							Name:     "tempo",
+							Endpoint: "https://tempo.example.com:4318",
+						},
+					},
+					Routes: []v1alpha1.TracingRoute{
+						{
+							Name: "payments",
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{"team": "payments"},
+							},
+							LumigoToken: v1alpha1.Credentials{
+								SecretRef: v1alpha1.KubernetesSecretRef{
+									Name: "lumigo-payments-credentials",
+									Key:  "token",
+								},
+							},
+						},
+						{
+							Name: "previews",
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{"team": "previews"},
+							},
+							LumigoToken: v1alpha1.Credentials{
+								Value: "t_123456789012345678901",
+							},
+						},
+					},
+					Propagators: []v1alpha1.Propagator{v1alpha1.PropagatorTraceContext, v1alpha1.PropagatorXRay},
+					SkipDomains: []v1alpha1.Domain{"vault.internal.example.com", "*.okta.com"},
+					Sampling: v1alpha1.SamplingSpec{
+						Percentage: newInt32(25),
+					},
+					DedicatedProxy: newBool(true),
+					AwsXRay: v1alpha1.AwsXRaySpec{
+						Enabled: newBool(true),
+					},
+					OtlpHeaders: []v1alpha1.OtlpHeaderSpec{
+						{
+							Name: "X-Scope-OrgID",
+							SecretRef: v1alpha1.KubernetesSecretRef{
+								Name: "gateway-credentials",
+								Key:  "tenant",
+							},
+						},
+					},
+				},
+				Logging: v1alpha1.LoggingSpec{
+					Enabled: newBool(true),
+				},
+				Pipelines: v1alpha1.PipelinesSpec{
+					Logs: v1alpha1.PipelineSpec{
+						Enabled: newBool(false),
+					},
+				},
+				Endpoints: v1alpha1.EndpointsSpec{
+					Metrics: &v1alpha1.OtlpEndpointSpec{
+						Url: "https://metrics.example.com",
+					},
+				},
+				Infrastructure: v1alpha1.InfrastructureSpec{
+					Enabled: newBool(true),
+					KubeEvents: v1alpha1.KubeEventsSpec{
+						Enabled: newBool(false),
+					},
+					Prometheus: v1alpha1.PrometheusSpec{
+						Enabled: newBool(true),
+						ScrapeTargets: []v1alpha1.PrometheusScrapeTarget{
+							{
+								JobName: "my-service",
+								Targets: []string{"my-service:9090"},
+							},
+						},
+					},
+					NodeLifecycle: v1alpha1.NodeLifecycleSpec{
+						Enabled: newBool(true),
+					},
+				},
+				Archival: v1alpha1.ArchivalSpec{
+					Enabled: newBool(true),
+					S3: v1alpha1.S3ArchivalSpec{
+						Bucket: "my-bucket",
+					},
+				},
+				Debug: v1alpha1.DebugSpec{
+					LogTelemetry: newBool(true),
+				},
+				Quota: v1alpha1.QuotaSpec{
+					MaxSpansPerDay:                 newInt64(1000000),
+					MaxGigabytesPerDay:             newInt32(10),
+					SamplingPercentageWhenExceeded: newInt32(5),
+				},
+				Paused: newBool(true),
+			},
+			Status: v1alpha1.LumigoStatus{
+				Conditions: []v1alpha1.LumigoCondition{
+					{
+						Type:    v1alpha1.LumigoConditionTypeActive,
+						Status:  corev1.ConditionTrue,
+						Message: "Lumigo is ready",
+					},
+				},
+				InstrumentedResources: []corev1.ObjectReference{
+					{Kind: "Deployment", Namespace: "my-namespace", Name: "my-app"},
+				},
+				DailyUsage: []v1alpha1.DailyUsage{
+					{Day: "2023-06-02", Spans: 1000, LogRecords: 200, MetricPoints: 30, Bytes: 123456},
+				},
+				FailedInjections: []v1alpha1.InjectionFailure{
+					{
+						Resource:       corev1.ObjectReference{Kind: "Deployment", Namespace: "my-namespace", Name: "my-blocked-app"},
+						Reason:         "admission webhook denied the request",
+						Attempts:       10,
+						CircuitBreaker: v1alpha1.CircuitBreakerStateOpen,
+					},
+				},
+				PendingInjections: []corev1.ObjectReference{
+					{Kind: "StatefulSet", Namespace: "my-namespace", Name: "my-db"},
+				},
+				DeferredInjections: []corev1.ObjectReference{
+					{Kind: "CronJob", Namespace: "my-namespace", Name: "my-report"},
+				},
+				InjectionProgress: &v1alpha1.InjectionProgress{
+					Kind:     "ReplicaSet",
+					Continue: "eyJ2IjoibWV0YS5rOHMuaW8vdjEifQ",
+				},
+				CompatibilityReport: &v1alpha1.CompatibilityReport{
+					Instrumentable: 12,
+					PolicyBlocked:  1,
+					SkipReasons:    map[string]int32{"OptedOut": 2},
+				},
+				ImportedEnv: []corev1.EnvVar{
+					{Name: "OTEL_TRACES_SAMPLER", Value: "parentbased_traceidratio"},
+				},
+				NamespaceTags: map[string]string{"team": "payments"},
+				OtlpHeaders:   map[string]string{"X-Scope-OrgID": "***"},
+				EffectiveSampling: &v1alpha1.SamplingSpec{
+					Percentage:  newInt32(25),
+					ParentBased: newBool(false),
+				},
+				ObservedGeneration:          3,
+				TelemetryVerifiedGeneration: 2,
+				Phase:                       v1alpha1.LumigoPhaseDegraded,
+			},
+		}
+	}
+
+	It("moves the renamed fields when converting from v1alpha1", func() {
+		lumigo := &Lumigo{}
+		Expect(lumigo.ConvertFrom(newV1alpha1Lumigo())).To(Succeed())
+
+		Expect(lumigo.Name).To(Equal("lumigo"))
+		Expect(lumigo.Spec.LumigoToken.SecretRef.Key).To(Equal("token"))
+
+		injection := lumigo.Spec.Tracing.Injection
+		Expect(*injection.InjectExistingResources).To(BeFalse())
+		Expect(*injection.MaxConcurrentWorkloadUpdates).To(Equal(int32(20)))
+		Expect(*injection.RemoveInjectionOnDeletion).To(BeTrue())
+		Expect(*injection.CompatibilityReport.Enabled).To(BeTrue())
+		Expect(*injection.TelemetryVerification.Enabled).To(BeTrue())
+		Expect(injection.InjectorImage.PullPolicy).To(Equal(corev1.PullIfNotPresent))
+		Expect(injection.InjectorImage.PullSecrets).To(ConsistOf(corev1.LocalObjectReference{Name: "mirror-credentials"}))
+		Expect(injection.UnsupportedArchitecturePolicy).To(Equal(UnsupportedArchitecturePolicyNodeAffinity))
+		Expect(injection.ExtraEnv).To(ConsistOf(corev1.EnvVar{Name: "LUMIGO_DEBUG", Value: "true"}))
+		Expect(injection.TokenInjectionMode).To(Equal(TokenInjectionModeProjectedSecret))
+		Expect(injection.RemovalMode).To(Equal(RemovalModeBackground))
+		Expect(injection.TokenMissingPolicy).To(Equal(TokenMissingPolicyKeepInjecting))
+		Expect(*injection.Strict).To(BeTrue())
+		Expect(*injection.Istio.ExcludeTelemetryProxyPorts).To(BeTrue())
+		Expect(injection.WorkloadTypes).To(Equal([]WorkloadType{WorkloadTypeDeployment, WorkloadTypeStatefulSet}))
+		Expect(injection.ImagePatterns.Allow).To(Equal([]string{"internal-registry/payments/*"}))
+		Expect(injection.ImagePatterns.Deny).To(Equal([]string{"regex:.*(nginx|redis).*"}))
+
+		Expect(*lumigo.Spec.Tracing.RateLimiting.MaxSpansPerSecond).To(Equal(int32(100)))
+		Expect(lumigo.Spec.Tracing.AdditionalExporters).To(HaveLen(1))
+		Expect(lumigo.Spec.Tracing.Routes[0].LumigoToken.SecretRef.Name).To(Equal("lumigo-payments-credentials"))
+		Expect(lumigo.Spec.Tracing.Routes[1].LumigoToken.Value).To(Equal("t_123456789012345678901"))
+		Expect(lumigo.Spec.Tracing.Propagators).To(Equal([]Propagator{PropagatorTraceContext, PropagatorXRay}))
+		Expect(lumigo.Spec.Tracing.SkipDomains).To(Equal([]Domain{"vault.internal.example.com", "*.okta.com"}))
+		Expect(*lumigo.Spec.Tracing.DedicatedProxy).To(BeTrue())
+		Expect(*lumigo.Spec.Tracing.AwsXRay.Enabled).To(BeTrue())
+		Expect(lumigo.Spec.Tracing.OtlpHeaders).To(Equal([]OtlpHeaderSpec{{Name: "X-Scope-OrgID", SecretRef: KubernetesSecretRef{Name: "gateway-credentials", Key: "tenant"}}}))
+		Expect(lumigo.Status.OtlpHeaders).To(HaveKeyWithValue("X-Scope-OrgID", "***"))
+		Expect(*lumigo.Spec.Tracing.Sampling.Percentage).To(Equal(int32(25)))
+		Expect(*lumigo.Status.EffectiveSampling.ParentBased).To(BeFalse())
+		Expect(lumigo.Spec.Infrastructure.Prometheus.ScrapeTargets[0].JobName).To(Equal("my-service"))
+		Expect(*lumigo.Spec.Infrastructure.NodeLifecycle.Enabled).To(BeTrue())
+		Expect(*lumigo.Spec.Pipelines.Logs.Enabled).To(BeFalse())
+		Expect(lumigo.Spec.Endpoints.Metrics.Url).To(Equal("https://metrics.example.com"))
+		Expect(lumigo.Spec.Endpoints.Traces).To(BeNil())
+		Expect(*lumigo.Spec.Quota.MaxSpansPerDay).To(Equal(int64(1000000)))
+		Expect(*lumigo.Spec.Quota.SamplingPercentageWhenExceeded).To(Equal(int32(5)))
+		Expect(*lumigo.Spec.Paused).To(BeTrue())
+		Expect(lumigo.Status.Conditions[0].Type).To(Equal(LumigoConditionTypeActive))
+		Expect(lumigo.Status.DailyUsage).To(ConsistOf(DailyUsage{Day: "2023-06-02", Spans: 1000, LogRecords: 200, MetricPoints: 30, Bytes: 123456}))
+		Expect(lumigo.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef.Namespace).To(Equal("otel"))
+		Expect(*lumigo.Spec.Tracing.Injection.PayloadCapture.MaxEntrySize).To(Equal(int32(4096)))
+		Expect(lumigo.Spec.Tracing.Injection.PayloadCapture.AllowedHeaders).To(Equal([]HeaderName{"content-type"}))
+		Expect(lumigo.Status.FailedInjections[0].CircuitBreaker).To(Equal(CircuitBreakerStateOpen))
+		Expect(lumigo.Status.PendingInjections).To(HaveLen(1))
+		Expect(lumigo.Status.DeferredInjections).To(ConsistOf(corev1.ObjectReference{Kind: "CronJob", Namespace: "my-namespace", Name: "my-report"}))
+		Expect(lumigo.Status.InjectionProgress).To(Equal(&InjectionProgress{Kind: "ReplicaSet", Continue: "eyJ2IjoibWV0YS5rOHMuaW8vdjEifQ"}))
+		Expect(lumigo.Status.CompatibilityReport.Instrumentable).To(Equal(int32(12)))
+		Expect(lumigo.Status.CompatibilityReport.SkipReasons).To(HaveKeyWithValue("OptedOut", int32(2)))
+		Expect(lumigo.Status.ImportedEnv).To(HaveLen(1))
+		Expect(lumigo.Status.NamespaceTags).To(HaveKeyWithValue("team", "payments"))
+		Expect(lumigo.Status.ObservedGeneration).To(Equal(int64(3)))
+		Expect(lumigo.Status.TelemetryVerifiedGeneration).To(Equal(int64(2)))
+		Expect(lumigo.Status.Phase).To(Equal(LumigoPhaseDegraded))
+	})
+
+	It("converts back to the same v1alpha1 resource", func() {
+		original := newV1alpha1Lumigo()
+
+		lumigo := &Lumigo{}
+		Expect(lumigo.ConvertFrom(original.DeepCopy())).To(Succeed())
+
+		roundTripped := &v1alpha1.Lumigo{}
+		Expect(lumigo.ConvertTo(roundTripped)).To(Succeed())
+
+		Expect(roundTripped).To(Equal(original))
+	})
+
+	It("converts an empty resource without setting any field", func() {
+		lumigo := &Lumigo{}
+		Expect(lumigo.ConvertFrom(&v1alpha1.Lumigo{})).To(Succeed())
+		Expect(lumigo).To(Equal(&Lumigo{}))
+
+		roundTripped := &v1alpha1.Lumigo{}
+		Expect(lumigo.ConvertTo(roundTripped)).To(Succeed())
+		Expect(roundTripped).To(Equal(&v1alpha1.Lumigo{}))
+	})
+
+})
@@ -0,0 +1,10 @@
+package v1beta1
+
+import ctrl "sigs.k8s.io/controller-runtime"
+
+// SetupWebhookWithManager registers the conversion webhook of the Lumigo CRD, served at `/convert`
+func (r *Lumigo) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
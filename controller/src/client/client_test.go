@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/client/clientset/versioned/fake"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/client/informers/externalversions"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Client Suite")
+}
+
+var _ = Context("Typed client", func() {
+
+	const namespace = "my-namespace"
+
+	newLumigo := func(name string) *operatorv1alpha1.Lumigo {
+		return &operatorv1alpha1.Lumigo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+		}
+	}
+
+	var clientset *fake.Clientset
+	var stopCh chan struct{}
+
+	BeforeEach(func() {
+		// NewSimpleClientset would track the objects it is given as "lumigos", so they are created through the typed client
+		clientset = fake.NewSimpleClientset()
+		_, err := clientset.OperatorV1alpha1().Lumigoes(namespace).Create(context.TODO(), newLumigo("lumigo"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		stopCh = make(chan struct{})
+	})
+
+	AfterEach(func() {
+		close(stopCh)
+	})
+
+	It("reads and updates the status of Lumigo resources", func() {
+		lumigoes := clientset.OperatorV1alpha1().Lumigoes(namespace)
+
+		lumigo, err := lumigoes.Get(context.TODO(), "lumigo", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		lumigo.Status.InstrumentedResources = []corev1.ObjectReference{{Kind: "Deployment", Namespace: namespace, Name: "my-deployment"}}
+		_, err = lumigoes.UpdateStatus(context.TODO(), lumigo, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(clientset.Actions()).To(ContainElement(SatisfyAll(
+			HaveField("GetVerb()", "update"),
+			HaveField("GetSubresource()", "status"),
+			HaveField("GetResource().Resource", "lumigoes"),
+		)))
+	})
+
+	It("lists and watches Lumigo resources through the shared informers", func() {
+		factory := externalversions.NewSharedInformerFactoryWithOptions(clientset, time.Minute, externalversions.WithNamespace(namespace))
+		lister := factory.Operator().V1alpha1().Lumigoes().Lister()
+
+		factory.Start(stopCh)
+		for informerType, synced := range factory.WaitForCacheSync(stopCh) {
+			Expect(synced).To(BeTrue(), "informer %v has not synced", informerType)
+		}
+
+		Expect(lister.Lumigoes(namespace).Get("lumigo")).To(HaveField("Name", "lumigo"))
+
+		_, err := clientset.OperatorV1alpha1().Lumigoes(namespace).Create(context.TODO(), newLumigo("another-lumigo"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() ([]*operatorv1alpha1.Lumigo, error) {
+			return lister.List(labels.Everything())
+		}).Should(HaveLen(2))
+	})
+
+	It("gives generic access to the informers of Lumigo resources", func() {
+		factory := externalversions.NewSharedInformerFactory(clientset, time.Minute)
+
+		_, err := factory.ForResource(operatorv1alpha1.SchemeGroupVersion.WithResource("lumigoes"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+})
@@ -0,0 +1,244 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap, in each namespace, with the latest audit entries of that namespace
+	ConfigMapName = "lumigo-audit"
+	// ConfigMapEntriesKey is the key of the ConfigMap data with the audit entries, one JSON object per line, oldest first
+	ConfigMapEntriesKey = "entries.jsonl"
+
+	DefaultMaxConfigMapEntries = 50
+	// ConfigMaps cannot be larger than 1MiB; the oldest entries are dropped to leave plenty of room
+	maxConfigMapEntriesSize = 512 * 1024
+)
+
+// Action is the kind of mutation the operator performed on a resource
+type Action string
+
+const (
+	ActionInject            Action = "inject"
+	ActionUninject          Action = "uninject"
+	ActionRepairAnnotations Action = "repair-annotations"
+	ActionCollectArtifacts  Action = "collect-artifacts"
+)
+
+const (
+	ActorController      = "controller"
+	ActorInjectorWebhook = "injector-webhook"
+)
+
+// AuditEntry records one mutation performed by the operator
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Action    Action    `json:"action"`
+	// The component of the operator that performed the mutation
+	Actor string `json:"actor"`
+	// For mutations performed by the injector webhook, the user whose request has been mutated
+	RequestedBy     string          `json:"requestedBy,omitempty"`
+	OperatorVersion string          `json:"operatorVersion"`
+	Target          Target          `json:"target"`
+	Lumigo          LumigoReference `json:"lumigo"`
+	Changes         []Change        `json:"changes"`
+}
+
+// Target identifies the mutated resource
+type Target struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+}
+
+// LumigoReference identifies the Lumigo resource on behalf of which the mutation has been performed,
+// and the generation of its spec that the mutation is based on
+type LumigoReference struct {
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
+}
+
+// Auditor records the mutations performed by the operator as JSON lines on a stream and, optionally,
+// in the `lumigo-audit` ConfigMap of the namespace of the mutated resources.
+type Auditor struct {
+	operatorVersion string
+	// Optional: if nil, the entries are not written to a stream
+	writer io.Writer
+	// Optional: if nil, the entries are not recorded in ConfigMaps
+	configMaps          corev1client.ConfigMapsGetter
+	maxConfigMapEntries int
+
+	mutex sync.Mutex
+}
+
+// NewAuditor creates an Auditor that writes the audit entries to the given writer and, if configMaps
+// is not nil, records the latest maxConfigMapEntries entries of each namespace in its ConfigMap.
+func NewAuditor(operatorVersion string, writer io.Writer, configMaps corev1client.ConfigMapsGetter, maxConfigMapEntries int) *Auditor {
+	if maxConfigMapEntries < 1 {
+		maxConfigMapEntries = DefaultMaxConfigMapEntries
+	}
+
+	return &Auditor{
+		operatorVersion:     operatorVersion,
+		writer:              writer,
+		configMaps:          configMaps,
+		maxConfigMapEntries: maxConfigMapEntries,
+	}
+}
+
+// NewEntry creates the audit entry of the mutation of the original resource into the mutated one.
+func NewEntry(action Action, actor string, lumigo *operatorv1alpha1.Lumigo, original runtime.Object, mutated runtime.Object) (*AuditEntry, error) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(mutated)
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine the kind of the mutated resource: %w", err)
+	}
+
+	originalJson, err := json.Marshal(original)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the original resource: %w", err)
+	}
+
+	mutatedJson, err := json.Marshal(mutated)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the mutated resource: %w", err)
+	}
+
+	objectMeta, ok := mutated.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("the mutated resource has no object metadata")
+	}
+
+	return NewEntryFromJson(action, actor, lumigo, Target{
+		APIVersion: gvks[0].GroupVersion().String(),
+		Kind:       gvks[0].Kind,
+		Namespace:  objectMeta.GetNamespace(),
+		Name:       objectMeta.GetName(),
+	}, originalJson, mutatedJson)
+}
+
+// NewEntryFromJson creates the audit entry of the mutation of the target from the original JSON
+// representation into the mutated one.
+func NewEntryFromJson(action Action, actor string, lumigo *operatorv1alpha1.Lumigo, target Target, originalJson []byte, mutatedJson []byte) (*AuditEntry, error) {
+	changes, err := Diff(originalJson, mutatedJson)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditEntry{
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Actor:     actor,
+		Target:    target,
+		Lumigo: LumigoReference{
+			Name:       lumigo.Name,
+			Generation: lumigo.Generation,
+		},
+		Changes: changes,
+	}, nil
+}
+
+// Record writes the audit entry to the stream and the ConfigMap of the namespace of its target, if enabled.
+func (a *Auditor) Record(ctx context.Context, entry *AuditEntry) error {
+	entry.OperatorVersion = a.operatorVersion
+
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cannot marshal the audit entry: %w", err)
+	}
+
+	if a.writer != nil {
+		a.mutex.Lock()
+		_, err := a.writer.Write(append(entryJson, '\n'))
+		a.mutex.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("cannot write the audit entry: %w", err)
+		}
+	}
+
+	if a.configMaps != nil {
+		if err := a.appendToConfigMap(ctx, entry.Target.Namespace, string(entryJson)); err != nil {
+			return fmt.Errorf("cannot record the audit entry in the '%s/%s' ConfigMap: %w", entry.Target.Namespace, ConfigMapName, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *Auditor) appendToConfigMap(ctx context.Context, namespace string, entryJson string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := a.configMaps.ConfigMaps(namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      ConfigMapName,
+					Labels: map[string]string{
+						"app.kubernetes.io/part-of":    "lumigo",
+						"app.kubernetes.io/managed-by": "lumigo-operator",
+					},
+				},
+				Data: map[string]string{
+					ConfigMapEntriesKey: entryJson + "\n",
+				},
+			}
+
+			_, err = a.configMaps.ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Created meanwhile, e.g., by the other component of the operator: retry as a conflict
+				return apierrors.NewConflict(corev1.Resource("configmaps"), ConfigMapName, err)
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		entries := []string{}
+		if existingEntries := strings.TrimSpace(configMap.Data[ConfigMapEntriesKey]); len(existingEntries) > 0 {
+			entries = strings.Split(existingEntries, "\n")
+		}
+		entries = trimEntries(append(entries, entryJson), a.maxConfigMapEntries)
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[ConfigMapEntriesKey] = strings.Join(entries, "\n") + "\n"
+
+		_, err = a.configMaps.ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// trimEntries drops the oldest entries beyond the maximum count or size, but always keeps the latest one
+func trimEntries(entries []string, maxEntries int) []string {
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+
+	size := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		size += len(entries[i]) + 1
+		if size > maxConfigMapEntriesSize && i < len(entries)-1 {
+			return entries[i+1:]
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Audit Suite")
+}
+
+func newDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "my-namespace",
+			Name:            "my-deployment",
+			ResourceVersion: "1",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "my-app:1.0.0",
+							Env: []corev1.EnvVar{
+								{Name: "FOO", Value: "bar"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Context("Audit", func() {
+
+	lumigo := &operatorv1alpha1.Lumigo{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "my-namespace",
+			Name:       "lumigo",
+			Generation: 3,
+		},
+	}
+
+	It("lists the fields changed by a mutation", func() {
+		original := newDeployment()
+
+		mutated := original.DeepCopy()
+		mutated.ResourceVersion = "2"
+		mutated.Labels = map[string]string{"lumigo.auto-trace": "1.2.3"}
+		mutated.Spec.Template.Spec.Containers[0].Env = append(mutated.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: "LUMIGO_TRACER_TOKEN", Value: "t_123"})
+		mutated.Spec.Template.Spec.Containers[0].Env[0].Value = "baz"
+
+		entry, err := NewEntry(ActionInject, ActorController, lumigo, original, mutated)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(entry.Target).To(Equal(Target{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Namespace:  "my-namespace",
+			Name:       "my-deployment",
+		}))
+		Expect(entry.Lumigo).To(Equal(LumigoReference{Name: "lumigo", Generation: 3}))
+		Expect(entry.Changes).To(Equal([]Change{
+			{
+				Path: ".metadata.labels",
+				New:  map[string]interface{}{"lumigo.auto-trace": "1.2.3"},
+			},
+			{
+				Path: ".spec.template.spec.containers[name=app].env[name=FOO].value",
+				Old:  "bar",
+				New:  "baz",
+			},
+			{
+				Path: ".spec.template.spec.containers[name=app].env[name=LUMIGO_TRACER_TOKEN]",
+				New:  map[string]interface{}{"name": "LUMIGO_TRACER_TOKEN", "value": "t_123"},
+			},
+		}))
+	})
+
+	It("quotes the keys that are not plain identifiers", func() {
+		changes, err := Diff(
+			[]byte(`{"metadata":{"annotations":{"other":"annotation"}}}`),
+			[]byte(`{"metadata":{"annotations":{"other":"annotation","lumigo.io/injected-version":"1.2.3"}}}`),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(changes).To(Equal([]Change{
+			{
+				Path: `.metadata.annotations["lumigo.io/injected-version"]`,
+				New:  "1.2.3",
+			},
+		}))
+	})
+
+	It("writes the entries as JSON lines and records them in the ConfigMap of the namespace", func() {
+		clientset := fake.NewSimpleClientset()
+		stream := &bytes.Buffer{}
+		auditor := NewAuditor("1.2.3", stream, clientset.CoreV1(), 2)
+
+		for i := 0; i < 3; i++ {
+			original := newDeployment()
+			mutated := original.DeepCopy()
+			mutated.Spec.Template.Spec.Containers[0].Image = strings.Repeat("x", i+1)
+
+			entry, err := NewEntry(ActionUninject, ActorController, lumigo, original, mutated)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(auditor.Record(context.TODO(), entry)).To(Succeed())
+		}
+
+		lines := strings.Split(strings.TrimSpace(stream.String()), "\n")
+		Expect(lines).To(HaveLen(3))
+
+		entry := &AuditEntry{}
+		Expect(json.Unmarshal([]byte(lines[2]), entry)).To(Succeed())
+		Expect(entry.Action).To(Equal(ActionUninject))
+		Expect(entry.OperatorVersion).To(Equal("1.2.3"))
+
+		configMap, err := clientset.CoreV1().ConfigMaps("my-namespace").Get(context.TODO(), ConfigMapName, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configMap.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "lumigo-operator"))
+
+		// Only the latest two entries are kept
+		Expect(configMap.Data[ConfigMapEntriesKey]).To(Equal(lines[1] + "\n" + lines[2] + "\n"))
+	})
+
+})
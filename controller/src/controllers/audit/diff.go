@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+var (
+	// Fields that the API server, rather than the operator, changes
+	ignoredPaths = map[string]bool{
+		".metadata.resourceVersion": true,
+		".metadata.generation":      true,
+		".metadata.managedFields":   true,
+		".status":                   true,
+	}
+
+	plainKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Change is a field that the operator added, modified or removed
+type Change struct {
+	// The path of the field, e.g., `.spec.template.spec.containers[name=app].env[name=LUMIGO_TRACER_TOKEN]`;
+	// the elements of lists of named objects, like containers, volumes and environment variables, are
+	// identified by name, those of other lists by index
+	Path string `json:"path"`
+	// The value before the mutation, absent if the field has been added
+	Old interface{} `json:"old,omitempty"`
+	// The value after the mutation, absent if the field has been removed
+	New interface{} `json:"new,omitempty"`
+}
+
+// Diff returns the changes between the two JSON documents, in the order of their paths
+func Diff(originalJson []byte, mutatedJson []byte) ([]Change, error) {
+	var original, mutated interface{}
+	if err := json.Unmarshal(originalJson, &original); err != nil {
+		return nil, fmt.Errorf("cannot parse the original resource: %w", err)
+	}
+
+	if err := json.Unmarshal(mutatedJson, &mutated); err != nil {
+		return nil, fmt.Errorf("cannot parse the mutated resource: %w", err)
+	}
+
+	changes := []Change{}
+	diff("", original, mutated, &changes)
+
+	return changes, nil
+}
+
+func diff(path string, original interface{}, mutated interface{}, changes *[]Change) {
+	if ignoredPaths[path] || reflect.DeepEqual(original, mutated) {
+		return
+	}
+
+	switch originalValue := original.(type) {
+	case map[string]interface{}:
+		if mutatedValue, ok := mutated.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(originalValue, mutatedValue) {
+				diff(path+keyPath(key), originalValue[key], mutatedValue[key], changes)
+			}
+			return
+		}
+	case []interface{}:
+		if mutatedValue, ok := mutated.([]interface{}); ok {
+			if isNamedList(originalValue) && isNamedList(mutatedValue) {
+				diffNamedLists(path, originalValue, mutatedValue, changes)
+			} else {
+				for i := 0; i < len(originalValue) || i < len(mutatedValue); i++ {
+					diff(fmt.Sprintf("%s[%d]", path, i), elementAt(originalValue, i), elementAt(mutatedValue, i), changes)
+				}
+			}
+			return
+		}
+	}
+
+	*changes = append(*changes, Change{
+		Path: path,
+		Old:  original,
+		New:  mutated,
+	})
+}
+
+func diffNamedLists(path string, original []interface{}, mutated []interface{}, changes *[]Change) {
+	originalByName := map[string]interface{}{}
+	names := []string{}
+	for _, element := range original {
+		name := element.(map[string]interface{})["name"].(string)
+		originalByName[name] = element
+		names = append(names, name)
+	}
+
+	mutatedByName := map[string]interface{}{}
+	for _, element := range mutated {
+		name := element.(map[string]interface{})["name"].(string)
+		mutatedByName[name] = element
+		if _, isOriginal := originalByName[name]; !isOriginal {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		diff(fmt.Sprintf("%s[name=%s]", path, name), originalByName[name], mutatedByName[name], changes)
+	}
+}
+
+// isNamedList returns whether all the elements of the list are objects with a unique name
+func isNamedList(list []interface{}) bool {
+	names := map[string]bool{}
+	for _, element := range list {
+		object, isObject := element.(map[string]interface{})
+		if !isObject {
+			return false
+		}
+
+		name, isString := object["name"].(string)
+		if !isString || names[name] {
+			return false
+		}
+		names[name] = true
+	}
+
+	return true
+}
+
+func keyPath(key string) string {
+	if plainKeyRegexp.MatchString(key) {
+		return "." + key
+	}
+
+	// Keys like `lumigo.io/injected-at` would be ambiguous in dotted paths
+	return fmt.Sprintf("[%q]", key)
+}
+
+func sortedKeys(a map[string]interface{}, b map[string]interface{}) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+
+	for key := range b {
+		if _, isInA := a[key]; !isInA {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func elementAt(list []interface{}, i int) interface{} {
+	if i < len(list) {
+		return list[i]
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package backendprobe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How often the Lumigo backend is probed at most
+	defaultMinProbeInterval = 30 * time.Second
+	// How long a probe waits for the Lumigo backend to respond
+	defaultProbeTimeout = 10 * time.Second
+)
+
+// BackendProbe periodically checks whether the OTLP endpoints of the Lumigo backend, to which
+// the telemetry-proxy exports telemetry, can be reached from within the cluster.
+type BackendProbe struct {
+	endpoints        []string
+	httpClient       *http.Client
+	minProbeInterval time.Duration
+
+	mutex         sync.Mutex
+	lastProbeTime time.Time
+	lastError     error
+	failingSince  time.Time
+}
+
+// NewBackendProbe creates a BackendProbe for the given OTLP endpoints, e.g.,
+// `https://ga-otlp.lumigo-tracer-edge.golumigo.com`; duplicated endpoints are probed once.
+func NewBackendProbe(endpoints []string) *BackendProbe {
+	uniqueEndpoints := []string{}
+	seen := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+		if len(endpoint) < 1 || seen[endpoint] {
+			continue
+		}
+
+		seen[endpoint] = true
+		uniqueEndpoints = append(uniqueEndpoints, endpoint)
+	}
+
+	return &BackendProbe{
+		endpoints:        uniqueEndpoints,
+		httpClient:       &http.Client{Timeout: defaultProbeTimeout},
+		minProbeInterval: defaultMinProbeInterval,
+	}
+}
+
+// Probe sends a request to each OTLP endpoint, unless they have been probed recently; it is meant
+// to be called at every reconciliation of every Lumigo instance.
+func (p *BackendProbe) Probe(ctx context.Context) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	if !p.lastProbeTime.IsZero() && now.Sub(p.lastProbeTime) < p.minProbeInterval {
+		return
+	}
+
+	var err error
+	for _, endpoint := range p.endpoints {
+		if err = p.probeEndpoint(ctx, endpoint); err != nil {
+			break
+		}
+	}
+
+	if err != nil && p.lastError == nil {
+		p.failingSince = now
+	}
+
+	p.lastProbeTime = now
+	p.lastError = err
+}
+
+// IsUnreachable returns whether the probes have been failing for at least the given duration,
+// and the error of the latest probe.
+func (p *BackendProbe) IsUnreachable(minDuration time.Duration) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.lastError == nil {
+		return false, nil
+	}
+
+	return p.lastProbeTime.Sub(p.failingSince) >= minDuration, p.lastError
+}
+
+func (p *BackendProbe) probeEndpoint(ctx context.Context, endpoint string) error {
+	// An empty OTLP export request: the Lumigo backend rejects it for the lack of a token, but
+	// any response proves that the network path from the cluster to the backend works
+	url := endpoint + "/v1/traces"
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader([]byte{}))
+	if err != nil {
+		return fmt.Errorf("cannot create the request to probe '%s': %w", url, err)
+	}
+	request.Header.Set("Content-Type", "application/x-protobuf")
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("cannot reach '%s': %w", url, err)
+	}
+	defer response.Body.Close()
+
+	// Gateways and load balancers in front of the backend answer with these when it is not available
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("'%s' is not available: unexpected status code %d", url, response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backendprobe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Backend Probe Suite")
+}
+
+var _ = Context("Backend probe", func() {
+
+	var statusCode atomic.Int32
+	var server *httptest.Server
+	var probe *BackendProbe
+
+	BeforeEach(func() {
+		// The Lumigo backend rejects unauthenticated requests
+		statusCode.Store(http.StatusUnauthorized)
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/traces" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.WriteHeader(int(statusCode.Load()))
+		}))
+
+		// The same endpoint is often used for both traces and logs
+		probe = NewBackendProbe([]string{server.URL, server.URL + "/"})
+		// Probe at every call, rather than waiting between probes
+		probe.minProbeInterval = 0
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("probes duplicated endpoints only once", func() {
+		Expect(probe.endpoints).To(Equal([]string{server.URL}))
+	})
+
+	It("considers the backend reachable when it responds to the probes", func() {
+		probe.Probe(context.TODO())
+
+		isUnreachable, err := probe.IsUnreachable(0)
+		Expect(isUnreachable).To(BeFalse())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("considers the backend unreachable only after the probes have been failing long enough", func() {
+		statusCode.Store(http.StatusServiceUnavailable)
+
+		probe.Probe(context.TODO())
+
+		isUnreachable, err := probe.IsUnreachable(10 * time.Millisecond)
+		Expect(isUnreachable).To(BeFalse())
+		Expect(err).To(HaveOccurred())
+
+		time.Sleep(50 * time.Millisecond)
+		probe.Probe(context.TODO())
+
+		isUnreachable, err = probe.IsUnreachable(10 * time.Millisecond)
+		Expect(isUnreachable).To(BeTrue())
+		Expect(err).To(HaveOccurred())
+
+		// The backend recovers
+		statusCode.Store(http.StatusUnauthorized)
+		probe.Probe(context.TODO())
+
+		isUnreachable, _ = probe.IsUnreachable(10 * time.Millisecond)
+		Expect(isUnreachable).To(BeFalse())
+	})
+
+	It("considers the backend unreachable when the connection fails", func() {
+		server.Close()
+
+		probe.Probe(context.TODO())
+
+		isUnreachable, err := probe.IsUnreachable(0)
+		Expect(isUnreachable).To(BeTrue())
+		Expect(err).To(HaveOccurred())
+	})
+
+})
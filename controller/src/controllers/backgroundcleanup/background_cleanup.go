@@ -0,0 +1,81 @@
+package backgroundcleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap that tracks the removal of the injection from the resources
+	// of the namespace, which is carried out in the background after the deletion of its Lumigo instance
+	ConfigMapName = "lumigo-cleanup"
+	// ConfigMapLumigoNameKey is the key of the ConfigMap data with the name of the deleted Lumigo instance
+	ConfigMapLumigoNameKey = "lumigo"
+	// ConfigMapRequestTimeKey is the key of the ConfigMap data with when the Lumigo instance was deleted
+	ConfigMapRequestTimeKey = "requestTime"
+)
+
+// IsBackgroundRemoval returns whether the injection is removed in the background on deletion of the Lumigo instance
+func IsBackgroundRemoval(lumigo *operatorv1alpha1.Lumigo) bool {
+	return lumigo.Spec.Tracing.Injection.RemovalMode == operatorv1alpha1.RemovalModeBackground
+}
+
+// RequestCleanup creates the ConfigMap that tracks the removal of the injection from the resources of the
+// namespace of the Lumigo instance, unless it exists already.
+func RequestCleanup(ctx context.Context, configMaps corev1client.ConfigMapsGetter, lumigo *operatorv1alpha1.Lumigo, now metav1.Time) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: lumigo.Namespace,
+			Name:      ConfigMapName,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":    "lumigo",
+				"app.kubernetes.io/managed-by": "lumigo-operator",
+			},
+		},
+		Data: map[string]string{
+			ConfigMapLumigoNameKey:  lumigo.Name,
+			ConfigMapRequestTimeKey: now.UTC().Format(time.RFC3339),
+		},
+	}
+
+	if _, err := configMaps.ConfigMaps(lumigo.Namespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("cannot create the '%s/%s' ConfigMap: %w", lumigo.Namespace, ConfigMapName, err)
+	}
+
+	return nil
+}
+
+// GetPendingCleanup returns the deleted Lumigo instance, with only its namespace and name set, whose removal of
+// the injection from the resources of the namespace is pending, or nil if there is none.
+func GetPendingCleanup(ctx context.Context, configMaps corev1client.ConfigMapsGetter, namespace string) (*operatorv1alpha1.Lumigo, error) {
+	configMap, err := configMaps.ConfigMaps(namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot retrieve the '%s/%s' ConfigMap: %w", namespace, ConfigMapName, err)
+	}
+
+	return &operatorv1alpha1.Lumigo{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      configMap.Data[ConfigMapLumigoNameKey],
+		},
+	}, nil
+}
+
+// CompleteCleanup deletes the ConfigMap that tracks the removal of the injection from the resources of the namespace
+func CompleteCleanup(ctx context.Context, configMaps corev1client.ConfigMapsGetter, namespace string) error {
+	if err := configMaps.ConfigMaps(namespace).Delete(ctx, ConfigMapName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete the '%s/%s' ConfigMap: %w", namespace, ConfigMapName, err)
+	}
+
+	return nil
+}
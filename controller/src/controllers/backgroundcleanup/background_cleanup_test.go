@@ -0,0 +1,85 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backgroundcleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Background Cleanup Suite")
+}
+
+var _ = Context("Background cleanup", func() {
+
+	now := metav1.NewTime(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	newLumigo := func(removalMode operatorv1alpha1.RemovalMode) *operatorv1alpha1.Lumigo {
+		lumigo := &operatorv1alpha1.Lumigo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-namespace",
+				Name:      "lumigo",
+			},
+		}
+		lumigo.Spec.Tracing.Injection.RemovalMode = removalMode
+		return lumigo
+	}
+
+	It("removes the injection in the background only if requested", func() {
+		Expect(IsBackgroundRemoval(newLumigo(""))).To(BeFalse())
+		Expect(IsBackgroundRemoval(newLumigo(operatorv1alpha1.RemovalModeBlocking))).To(BeFalse())
+		Expect(IsBackgroundRemoval(newLumigo(operatorv1alpha1.RemovalModeBackground))).To(BeTrue())
+	})
+
+	It("tracks the pending cleanup of the namespace until it is completed", func() {
+		clientset := fake.NewSimpleClientset()
+		lumigo := newLumigo(operatorv1alpha1.RemovalModeBackground)
+
+		Expect(GetPendingCleanup(context.TODO(), clientset.CoreV1(), "my-namespace")).To(BeNil())
+
+		Expect(RequestCleanup(context.TODO(), clientset.CoreV1(), lumigo, now)).To(Succeed())
+		Expect(RequestCleanup(context.TODO(), clientset.CoreV1(), lumigo, now)).To(Succeed())
+
+		configMap, err := clientset.CoreV1().ConfigMaps("my-namespace").Get(context.TODO(), ConfigMapName, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configMap.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "lumigo-operator"))
+		Expect(configMap.Data).To(Equal(map[string]string{
+			ConfigMapLumigoNameKey:  "lumigo",
+			ConfigMapRequestTimeKey: "2023-06-01T12:00:00Z",
+		}))
+
+		pending, err := GetPendingCleanup(context.TODO(), clientset.CoreV1(), "my-namespace")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending.Namespace).To(Equal("my-namespace"))
+		Expect(pending.Name).To(Equal("lumigo"))
+
+		Expect(CompleteCleanup(context.TODO(), clientset.CoreV1(), "my-namespace")).To(Succeed())
+		Expect(CompleteCleanup(context.TODO(), clientset.CoreV1(), "my-namespace")).To(Succeed())
+		Expect(GetPendingCleanup(context.TODO(), clientset.CoreV1(), "my-namespace")).To(BeNil())
+	})
+})
@@ -0,0 +1,147 @@
+package capabilities
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/exp/slices"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/otelinstrumentation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/servicemonitors"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// Capability is an API resource that features of the operator rely on, and that not all clusters serve,
+// e.g., the batch/v1 CronJobs are served from Kubernetes 1.21 on, and the ScaledJobs only with Keda installed
+type Capability string
+
+const (
+	CronJobs                      Capability = "batch/v1 CronJobs"
+	KedaScaledJobs                Capability = "keda.sh/v1alpha1 ScaledJobs"
+	OpenTelemetryInstrumentations Capability = "opentelemetry.io/v1alpha1 Instrumentations"
+	PrometheusServiceMonitors     Capability = "monitoring.coreos.com/v1 ServiceMonitors"
+
+	// How long the capabilities discovered are trusted, so that the CRDs installed after the operator,
+	// e.g., the ones of Keda, are picked up
+	DefaultRefreshPeriod = 5 * time.Minute
+)
+
+var capabilityResources = map[Capability]schema.GroupVersionResource{
+	CronJobs: {
+		Group:    "batch",
+		Version:  "v1",
+		Resource: "cronjobs",
+	},
+	KedaScaledJobs:                mutation.KedaScaledJobGroupVersionResource,
+	OpenTelemetryInstrumentations: otelinstrumentation.InstrumentationGroupVersionResource,
+	PrometheusServiceMonitors:     servicemonitors.ServiceMonitorGroupVersionResource,
+}
+
+// Capabilities are whether the cluster serves each Capability; the capabilities that could not be
+// discovered are assumed to be served, so that a failed discovery does not turn features off
+type Capabilities map[Capability]bool
+
+// Has returns whether the cluster serves the capability; nil Capabilities have all of them
+func (c Capabilities) Has(capability Capability) bool {
+	isServed, isKnown := c[capability]
+	return !isKnown || isServed
+}
+
+// Discoverer looks up the capabilities of the cluster with the discovery API, and caches them
+// for the refresh period; a nil Discoverer reports all capabilities as served
+type Discoverer struct {
+	discovery     discovery.DiscoveryInterface
+	refreshPeriod time.Duration
+	log           logr.Logger
+
+	mutex            sync.Mutex
+	capabilities     Capabilities
+	lastDiscoveredAt time.Time
+}
+
+func NewDiscoverer(discovery discovery.DiscoveryInterface, refreshPeriod time.Duration, log logr.Logger) *Discoverer {
+	if refreshPeriod <= 0 {
+		refreshPeriod = DefaultRefreshPeriod
+	}
+
+	return &Discoverer{
+		discovery:     discovery,
+		refreshPeriod: refreshPeriod,
+		log:           log,
+	}
+}
+
+// Get returns the capabilities of the cluster, discovering them again if those cached are older than
+// the refresh period
+func (d *Discoverer) Get(now time.Time) Capabilities {
+	if d == nil {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.capabilities != nil && now.Sub(d.lastDiscoveredAt) < d.refreshPeriod {
+		return d.capabilities
+	}
+
+	capabilities := Capabilities{}
+	for capability, resource := range capabilityResources {
+		isServed, err := isResourceServed(d.discovery, resource)
+		if err != nil {
+			d.log.Error(err, "Cannot discover whether the cluster serves the resource, assuming it does", "capability", capability)
+			continue
+		}
+		capabilities[capability] = isServed
+	}
+
+	d.capabilities = capabilities
+	d.lastDiscoveredAt = now
+	return capabilities
+}
+
+func isResourceServed(discoveryClient discovery.DiscoveryInterface, resource schema.GroupVersionResource) (bool, error) {
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("cannot list the resources of %s: %w", resource.GroupVersion(), err)
+	}
+
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == resource.Resource {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetUnsupportedSettings returns the settings of the spec that rely on capabilities the cluster does not
+// have, each described with an explicit message. The workload types are reported only if listed
+// explicitly: by default, the workloads of the types the cluster does not serve are simply not there.
+func GetUnsupportedSettings(capabilities Capabilities, spec *operatorv1alpha1.LumigoSpec) []string {
+	unsupportedSettings := []string{}
+
+	injection := &spec.Tracing.Injection
+	if injection.Enabled == nil || *injection.Enabled {
+		if slices.Contains(injection.WorkloadTypes, operatorv1alpha1.WorkloadTypeCronJob) && !capabilities.Has(CronJobs) {
+			unsupportedSettings = append(unsupportedSettings, fmt.Sprintf("'.Spec.Tracing.Injection.WorkloadTypes' includes '%s', but the cluster does not serve %s", operatorv1alpha1.WorkloadTypeCronJob, CronJobs))
+		}
+		if slices.Contains(injection.WorkloadTypes, operatorv1alpha1.WorkloadTypeScaledJob) && !capabilities.Has(KedaScaledJobs) {
+			unsupportedSettings = append(unsupportedSettings, fmt.Sprintf("'.Spec.Tracing.Injection.WorkloadTypes' includes '%s', but the cluster does not serve %s; is Keda installed?", operatorv1alpha1.WorkloadTypeScaledJob, KedaScaledJobs))
+		}
+	}
+
+	if injection.OpenTelemetryInstrumentationRef != nil && !capabilities.Has(OpenTelemetryInstrumentations) {
+		unsupportedSettings = append(unsupportedSettings, fmt.Sprintf("'.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef' is set, but the cluster does not serve %s; is the OpenTelemetry operator installed?", OpenTelemetryInstrumentations))
+	}
+
+	return unsupportedSettings
+}
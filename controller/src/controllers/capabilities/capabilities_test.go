@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capabilities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Capabilities Suite")
+}
+
+var _ = Context("Capabilities", func() {
+
+	var discovery *fakediscovery.FakeDiscovery
+	var now time.Time
+
+	BeforeEach(func() {
+		discovery = &fakediscovery.FakeDiscovery{
+			Fake: &clienttesting.Fake{
+				Resources: []*metav1.APIResourceList{
+					{
+						GroupVersion: "batch/v1",
+						APIResources: []metav1.APIResource{{Name: "jobs"}},
+					},
+					{
+						GroupVersion: "keda.sh/v1alpha1",
+						APIResources: []metav1.APIResource{{Name: "scaledobjects"}, {Name: "scaledjobs"}},
+					},
+				},
+			},
+		}
+		now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("discovers the resources the cluster serves", func() {
+		capabilities := NewDiscoverer(discovery, time.Minute, logr.Discard()).Get(now)
+
+		Expect(capabilities.Has(CronJobs)).To(BeFalse())
+		Expect(capabilities.Has(KedaScaledJobs)).To(BeTrue())
+		Expect(capabilities.Has(OpenTelemetryInstrumentations)).To(BeFalse())
+		Expect(capabilities.Has(PrometheusServiceMonitors)).To(BeFalse())
+	})
+
+	It("discovers the capabilities again once the refresh period is over", func() {
+		discoverer := NewDiscoverer(discovery, time.Minute, logr.Discard())
+		Expect(discoverer.Get(now).Has(CronJobs)).To(BeFalse())
+
+		discovery.Resources[0].APIResources = append(discovery.Resources[0].APIResources, metav1.APIResource{Name: "cronjobs"})
+		Expect(discoverer.Get(now.Add(30 * time.Second)).Has(CronJobs)).To(BeFalse())
+		Expect(discoverer.Get(now.Add(time.Minute)).Has(CronJobs)).To(BeTrue())
+	})
+
+	It("reports all capabilities as served without a discoverer", func() {
+		var discoverer *Discoverer
+		capabilities := discoverer.Get(now)
+
+		Expect(capabilities.Has(CronJobs)).To(BeTrue())
+		Expect(capabilities.Has(KedaScaledJobs)).To(BeTrue())
+	})
+
+	It("reports the settings that rely on capabilities the cluster does not have", func() {
+		capabilities := NewDiscoverer(discovery, time.Minute, logr.Discard()).Get(now)
+
+		spec := &operatorv1alpha1.LumigoSpec{}
+		spec.Tracing.Injection.WorkloadTypes = []operatorv1alpha1.WorkloadType{operatorv1alpha1.WorkloadTypeCronJob, operatorv1alpha1.WorkloadTypeScaledJob}
+		spec.Tracing.Injection.OpenTelemetryInstrumentationRef = &operatorv1alpha1.OpenTelemetryInstrumentationRef{Name: "my-instrumentation"}
+
+		Expect(GetUnsupportedSettings(capabilities, spec)).To(ConsistOf(
+			"'.Spec.Tracing.Injection.WorkloadTypes' includes 'CronJob', but the cluster does not serve batch/v1 CronJobs",
+			"'.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef' is set, but the cluster does not serve opentelemetry.io/v1alpha1 Instrumentations; is the OpenTelemetry operator installed?",
+		))
+
+		spec.Tracing.Injection.WorkloadTypes = nil
+		spec.Tracing.Injection.OpenTelemetryInstrumentationRef = nil
+		Expect(GetUnsupportedSettings(capabilities, spec)).To(BeEmpty())
+	})
+})
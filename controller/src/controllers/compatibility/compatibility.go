@@ -0,0 +1,308 @@
+package compatibility
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	// ReportConfigMapName is the name of the ConfigMap, in the namespace of the Lumigo instance, with the
+	// compatibility of each workload
+	ReportConfigMapName = "lumigo-compatibility-report"
+	// ReportConfigMapKey is the key of the ConfigMap data with the report, as a JSON object
+	ReportConfigMapKey = "report.json"
+
+	// How often the workloads of a namespace are analyzed again
+	DefaultRefreshPeriod = 5 * time.Minute
+
+	// The prefix of the annotations with which the OpenTelemetry operator injects its own instrumentation
+	otelOperatorInjectAnnotationPrefix = "instrumentation.opentelemetry.io/inject-"
+)
+
+// Class is the outcome of the analysis of a workload
+type Class string
+
+const (
+	ClassInstrumented        Class = "Instrumented"
+	ClassInstrumentable      Class = "Instrumentable"
+	ClassOptedOut            Class = "OptedOut"
+	ClassUnsupportedRuntime  Class = "UnsupportedRuntime"
+	ClassConflictingSidecars Class = "ConflictingSidecars"
+	ClassPolicyBlocked       Class = "PolicyBlocked"
+)
+
+// WorkloadCompatibility is the outcome of the analysis of one workload
+type WorkloadCompatibility struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Class Class  `json:"class"`
+	// Why the workload cannot be injected; empty for the instrumented and instrumentable ones
+	Reason string `json:"reason,omitempty"`
+	// Why the mutator skips the workload, among a fixed set of values, e.g., `UnsupportedArchitecture`; empty
+	// for the workloads that the mutator does not skip
+	SkipReason mutation.SkipReason `json:"skipReason,omitempty"`
+}
+
+// CompatibilityReport is the content of the `lumigo-compatibility-report` ConfigMap
+type CompatibilityReport struct {
+	LastUpdateTime metav1.Time             `json:"lastUpdateTime"`
+	Workloads      []WorkloadCompatibility `json:"workloads"`
+}
+
+// Analyze classifies the workloads of the namespace by whether the mutator can inject them, without changing
+// them: the updates that would add the injection are submitted as dry-runs, so that the workloads whose updates
+// admission policies reject are told apart. The ReplicaSets owned by Deployments and the Jobs are left out, as
+// their pod templates are not updated by the operator.
+func Analyze(ctx context.Context, c client.Client, mutator mutation.Mutator, namespaceName string, now metav1.Time) (*CompatibilityReport, error) {
+	workloads, err := listWorkloads(ctx, c, namespaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CompatibilityReport{
+		LastUpdateTime: now,
+		Workloads:      make([]WorkloadCompatibility, 0, len(workloads)),
+	}
+	for _, workload := range workloads {
+		class, skipReason, reason := Classify(ctx, c, mutator, workload)
+		report.Workloads = append(report.Workloads, WorkloadCompatibility{
+			Kind:       workload.GetObjectKind().GroupVersionKind().Kind,
+			Name:       workload.GetName(),
+			Class:      class,
+			Reason:     reason,
+			SkipReason: skipReason,
+		})
+	}
+
+	return report, nil
+}
+
+// Classify returns the class of the workload and, if it cannot be injected, why, with the reason why the
+// mutator skips it, if it does.
+func Classify(ctx context.Context, c client.Client, mutator mutation.Mutator, workload client.Object) (Class, mutation.SkipReason, string) {
+	if strings.ToLower(workload.GetLabels()[mutation.LumigoAutoTraceLabelKey]) == "false" {
+		return ClassOptedOut, mutation.SkipReasonOptedOut, fmt.Sprintf("the workload has the '%s' label set to 'false'", mutation.LumigoAutoTraceLabelKey)
+	}
+
+	if podTemplate := getPodTemplate(workload); podTemplate != nil {
+		if reason := getConflictingSidecarsReason(podTemplate); reason != "" {
+			return ClassConflictingSidecars, "", reason
+		}
+	}
+
+	mutated := workload.DeepCopyObject().(client.Object)
+	result, err := mutator.InjectLumigoIntoWithResult(mutated)
+	if err != nil {
+		return ClassUnsupportedRuntime, "", fmt.Sprintf("the workload cannot be injected: %s", err.Error())
+	} else if result.IsSkipped() {
+		return ClassUnsupportedRuntime, result.SkipReason, result.SkipMessage
+	} else if !result.Mutated {
+		return ClassInstrumented, "", ""
+	}
+
+	if err := c.Update(ctx, mutated, client.DryRunAll); err != nil && (apierrors.IsForbidden(err) || apierrors.IsInvalid(err) || apierrors.IsBadRequest(err)) {
+		return ClassPolicyBlocked, "", err.Error()
+	}
+
+	return ClassInstrumentable, "", ""
+}
+
+// NewReportStatus returns the summary of the report for the status of the Lumigo instance
+func NewReportStatus(report *CompatibilityReport) *operatorv1alpha1.CompatibilityReport {
+	status := &operatorv1alpha1.CompatibilityReport{
+		LastUpdateTime: report.LastUpdateTime,
+	}
+
+	for _, workload := range report.Workloads {
+		switch workload.Class {
+		case ClassInstrumented:
+			status.Instrumented++
+		case ClassInstrumentable:
+			status.Instrumentable++
+		case ClassOptedOut:
+			status.OptedOut++
+		case ClassUnsupportedRuntime:
+			status.UnsupportedRuntime++
+		case ClassConflictingSidecars:
+			status.ConflictingSidecars++
+		case ClassPolicyBlocked:
+			status.PolicyBlocked++
+		}
+
+		if workload.SkipReason != "" {
+			if status.SkipReasons == nil {
+				status.SkipReasons = map[string]int32{}
+			}
+			status.SkipReasons[string(workload.SkipReason)]++
+		}
+	}
+
+	return status
+}
+
+// IsDueForRefresh returns whether the workloads of the namespace of the Lumigo instance are due to be analyzed
+func IsDueForRefresh(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) bool {
+	report := lumigo.Status.CompatibilityReport
+	return report == nil || now.Sub(report.LastUpdateTime.Time) >= DefaultRefreshPeriod
+}
+
+// UpsertReportConfigMap writes the report in the `lumigo-compatibility-report` ConfigMap of the namespace.
+func UpsertReportConfigMap(ctx context.Context, c client.Client, namespaceName string, report *CompatibilityReport) error {
+	reportJson, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("cannot marshal the compatibility report: %w", err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      ReportConfigMapName,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels["app.kubernetes.io/part-of"] = "lumigo"
+		configMap.Labels["app.kubernetes.io/managed-by"] = "lumigo-operator"
+		configMap.Data = map[string]string{
+			ReportConfigMapKey: string(reportJson),
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("cannot write the '%s/%s' ConfigMap: %w", namespaceName, ReportConfigMapName, err)
+	}
+
+	return nil
+}
+
+// RemoveReportConfigMap deletes the `lumigo-compatibility-report` ConfigMap of the namespace, if it exists.
+func RemoveReportConfigMap(ctx context.Context, c client.Client, namespaceName string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      ReportConfigMapName,
+		},
+	}
+	if err := c.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete the '%s/%s' ConfigMap: %w", namespaceName, ReportConfigMapName, err)
+	}
+
+	return nil
+}
+
+// The containers instrumented otherwise, whose instrumentation would be replaced by or clash with the Lumigo
+// one: the injection overrides LD_PRELOAD, and the OpenTelemetry operator injects its own agents
+func getConflictingSidecarsReason(podTemplate *corev1.PodTemplateSpec) string {
+	for key, value := range podTemplate.Annotations {
+		if strings.HasPrefix(key, otelOperatorInjectAnnotationPrefix) && strings.ToLower(value) != "false" {
+			return fmt.Sprintf("the pods are instrumented by the OpenTelemetry operator with the '%s' annotation", key)
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, podTemplate.Spec.InitContainers...), podTemplate.Spec.Containers...)
+	for _, container := range containers {
+		if container.Name == mutation.LumigoInjectorContainerName {
+			continue
+		}
+
+		for _, envVar := range container.Env {
+			if envVar.Name == mutation.LdPreloadEnvVarName && envVar.Value != mutation.LdPreloadEnvVarValue {
+				return fmt.Sprintf("the '%s' container sets its own '%s'", container.Name, mutation.LdPreloadEnvVarName)
+			}
+		}
+	}
+
+	return ""
+}
+
+func getPodTemplate(workload client.Object) *corev1.PodTemplateSpec {
+	switch w := workload.(type) {
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.ReplicaSet:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *batchv1.CronJob:
+		return &w.Spec.JobTemplate.Spec.Template
+	default:
+		return nil
+	}
+}
+
+// The workloads of the namespace whose pod templates the operator updates, with their kinds set, as the
+// items of typed lists have none
+func listWorkloads(ctx context.Context, c client.Client, namespaceName string) ([]client.Object, error) {
+	workloads := []client.Object{}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := c.List(ctx, daemonSets, client.InNamespace(namespaceName)); err != nil {
+		return nil, fmt.Errorf("cannot list daemonsets: %w", err)
+	}
+	for i := range daemonSets.Items {
+		daemonSets.Items[i].SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("DaemonSet"))
+		workloads = append(workloads, &daemonSets.Items[i])
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(namespaceName)); err != nil {
+		return nil, fmt.Errorf("cannot list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		deployments.Items[i].SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+		workloads = append(workloads, &deployments.Items[i])
+	}
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := c.List(ctx, replicaSets, client.InNamespace(namespaceName)); err != nil {
+		return nil, fmt.Errorf("cannot list replicasets: %w", err)
+	}
+	for i := range replicaSets.Items {
+		if metav1.GetControllerOf(&replicaSets.Items[i]) != nil {
+			// The pod templates of the ReplicaSets of Deployments are updated through the Deployments
+			continue
+		}
+		replicaSets.Items[i].SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))
+		workloads = append(workloads, &replicaSets.Items[i])
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, statefulSets, client.InNamespace(namespaceName)); err != nil {
+		return nil, fmt.Errorf("cannot list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		statefulSets.Items[i].SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("StatefulSet"))
+		workloads = append(workloads, &statefulSets.Items[i])
+	}
+
+	// Older clusters do not serve the batch/v1 CronJobs, and have none to analyze
+	cronJobs := &batchv1.CronJobList{}
+	if err := c.List(ctx, cronJobs, client.InNamespace(namespaceName)); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("cannot list cronjobs: %w", err)
+	}
+	for i := range cronJobs.Items {
+		cronJobs.Items[i].SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("CronJob"))
+		workloads = append(workloads, &cronJobs.Items[i])
+	}
+
+	return workloads, nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compatibility
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const namespaceName = "my-namespace"
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Compatibility Suite")
+}
+
+// Injects the deployments by adding an annotation, unless they already have it, and skips the "arm-app" one
+type fakeMutator struct {
+	mutation.Mutator
+}
+
+func (m *fakeMutator) InjectLumigoInto(resource interface{}) (bool, error) {
+	deployment, ok := resource.(*appsv1.Deployment)
+	if !ok {
+		return false, nil
+	}
+
+	if deployment.Name == "arm-app" {
+		return false, &mutation.SkipInjectionError{Code: mutation.SkipReasonUnsupportedArchitecture, Reason: "the 'arm64' architecture is not supported"}
+	}
+
+	if _, ok := deployment.Spec.Template.Annotations["injected"]; ok {
+		return false, nil
+	}
+
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	deployment.Spec.Template.Annotations["injected"] = "true"
+	return true, nil
+}
+
+func (m *fakeMutator) InjectLumigoIntoWithResult(resource interface{}) (*mutation.InjectionResult, error) {
+	mutated, err := m.InjectLumigoInto(resource)
+	if mutation.IsSkipInjectionError(err) {
+		return &mutation.InjectionResult{SkipReason: mutation.GetSkipReason(err), SkipMessage: err.Error()}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &mutation.InjectionResult{Mutated: mutated}, nil
+}
+
+func newDeployment(name string, labels map[string]string, annotations map[string]string, env []corev1.EnvVar) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      name,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: annotations,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "my-app:latest", Env: env},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Context("Compatibility report", func() {
+
+	var c client.Client
+	now := metav1.NewTime(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	BeforeEach(func() {
+		isController := true
+		c = fake.NewClientBuilder().WithObjects(
+			newDeployment("my-app", nil, nil, nil),
+			newDeployment("injected-app", nil, map[string]string{"injected": "true"}, nil),
+			newDeployment("opted-out-app", map[string]string{mutation.LumigoAutoTraceLabelKey: "false"}, nil, nil),
+			newDeployment("arm-app", nil, nil, nil),
+			newDeployment("otel-app", nil, map[string]string{"instrumentation.opentelemetry.io/inject-java": "true"}, nil),
+			newDeployment("preload-app", nil, nil, []corev1.EnvVar{{Name: mutation.LdPreloadEnvVarName, Value: "/opt/agent.so"}}),
+			&appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "my-app-12345",
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-app", UID: "1234", Controller: &isController},
+					},
+				},
+			},
+		).Build()
+	})
+
+	It("classifies the workloads of the namespace without changing them", func() {
+		report, err := Analyze(context.TODO(), c, &fakeMutator{}, namespaceName, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.LastUpdateTime).To(Equal(now))
+
+		classes := map[string]Class{}
+		skipReasons := map[string]mutation.SkipReason{}
+		for _, workload := range report.Workloads {
+			Expect(workload.Kind).To(Equal("Deployment"))
+			classes[workload.Name] = workload.Class
+			if workload.SkipReason != "" {
+				skipReasons[workload.Name] = workload.SkipReason
+			}
+		}
+		Expect(classes).To(Equal(map[string]Class{
+			"my-app":        ClassInstrumentable,
+			"injected-app":  ClassInstrumented,
+			"opted-out-app": ClassOptedOut,
+			"arm-app":       ClassUnsupportedRuntime,
+			"otel-app":      ClassConflictingSidecars,
+			"preload-app":   ClassConflictingSidecars,
+		}))
+		Expect(skipReasons).To(Equal(map[string]mutation.SkipReason{
+			"opted-out-app": mutation.SkipReasonOptedOut,
+			"arm-app":       mutation.SkipReasonUnsupportedArchitecture,
+		}))
+
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: "my-app"}, deployment)).To(Succeed())
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey("injected"))
+	})
+
+	It("summarizes the report for the status", func() {
+		report, err := Analyze(context.TODO(), c, &fakeMutator{}, namespaceName, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(NewReportStatus(report)).To(Equal(&operatorv1alpha1.CompatibilityReport{
+			LastUpdateTime:      now,
+			Instrumented:        1,
+			Instrumentable:      1,
+			OptedOut:            1,
+			UnsupportedRuntime:  1,
+			ConflictingSidecars: 2,
+			SkipReasons: map[string]int32{
+				string(mutation.SkipReasonOptedOut):                1,
+				string(mutation.SkipReasonUnsupportedArchitecture): 1,
+			},
+		}))
+	})
+
+	It("refreshes the report periodically", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		Expect(IsDueForRefresh(lumigo, now)).To(BeTrue())
+
+		lumigo.Status.CompatibilityReport = &operatorv1alpha1.CompatibilityReport{LastUpdateTime: now}
+		Expect(IsDueForRefresh(lumigo, metav1.NewTime(now.Add(time.Minute)))).To(BeFalse())
+		Expect(IsDueForRefresh(lumigo, metav1.NewTime(now.Add(DefaultRefreshPeriod)))).To(BeTrue())
+	})
+
+	It("writes and removes the report ConfigMap", func() {
+		report, err := Analyze(context.TODO(), c, &fakeMutator{}, namespaceName, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(UpsertReportConfigMap(context.TODO(), c, namespaceName, report)).To(Succeed())
+		Expect(UpsertReportConfigMap(context.TODO(), c, namespaceName, report)).To(Succeed())
+
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: ReportConfigMapName}, configMap)).To(Succeed())
+		Expect(configMap.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "lumigo-operator"))
+
+		written := &CompatibilityReport{}
+		Expect(json.Unmarshal([]byte(configMap.Data[ReportConfigMapKey]), written)).To(Succeed())
+		Expect(written.Workloads).To(HaveLen(6))
+
+		Expect(RemoveReportConfigMap(context.TODO(), c, namespaceName)).To(Succeed())
+		Expect(RemoveReportConfigMap(context.TODO(), c, namespaceName)).To(Succeed())
+
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: ReportConfigMapName}, configMap)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
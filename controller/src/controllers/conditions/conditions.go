@@ -23,6 +23,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/redaction"
 )
 
 func GetLumigoConditionByType(lumigo *operatorv1alpha1.Lumigo, t operatorv1alpha1.LumigoConditionType) *operatorv1alpha1.LumigoCondition {
@@ -73,6 +74,158 @@ func ClearErrorCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
 	updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeError, now, corev1.ConditionFalse, "")
 }
 
+func SetRateLimitedCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isRateLimited bool, message string) {
+	if isRateLimited {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeRateLimited, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeRateLimited, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetQuotaExceededCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isQuotaExceeded bool, message string) {
+	if isQuotaExceeded {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeQuotaExceeded, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeQuotaExceeded, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetTelemetryExportDegradedCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isDegraded bool, message string) {
+	if isDegraded {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryExportDegraded, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryExportDegraded, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetBackendUnreachableCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isUnreachable bool, message string) {
+	if isUnreachable {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeBackendUnreachable, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeBackendUnreachable, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetUnsupportedFeaturesCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, hasUnsupportedFeatures bool, message string) {
+	if hasUnsupportedFeatures {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeUnsupportedFeatures, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeUnsupportedFeatures, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetUnsupportedOnThisClusterCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isUnsupported bool, message string) {
+	if isUnsupported {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeUnsupportedOnThisCluster, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeUnsupportedOnThisCluster, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetPausedCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isPaused bool, message string) {
+	if isPaused {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypePaused, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypePaused, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetInconsistentSpecCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isInconsistent bool, message string) {
+	if isInconsistent {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeInconsistentSpec, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeInconsistentSpec, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetTokenMissingCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isTokenMissing bool, message string) {
+	if isTokenMissing {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeTokenMissing, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeTokenMissing, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetTelemetryProxyUnreachableCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isUnreachable bool, message string) {
+	if isUnreachable {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable, now, corev1.ConditionFalse, message)
+	}
+}
+
+func SetInjectionRuntimeFailuresCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, hasFailures bool, message string) {
+	if hasFailures {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeInjectionRuntimeFailures, now, corev1.ConditionTrue, message)
+	} else {
+		updateLumigoConditions(lumigo, operatorv1alpha1.LumigoConditionTypeInjectionRuntimeFailures, now, corev1.ConditionFalse, message)
+	}
+}
+
+// SetTelemetryVerifiedCondition sets the outcome of the telemetry verification; unlike the other
+// conditions, it is added also when False, as a failed verification is what it is there to report
+func SetTelemetryVerifiedCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isVerified bool, message string) {
+	conditionStatus := corev1.ConditionFalse
+	if isVerified {
+		conditionStatus = corev1.ConditionTrue
+	}
+
+	status := &lumigo.Status
+	if conditionIndex := getConditionIndexByType(status, operatorv1alpha1.LumigoConditionTypeTelemetryVerified); conditionIndex > -1 {
+		setLumigoCondition(&status.Conditions[conditionIndex], now, conditionStatus, message)
+	} else {
+		status.Conditions = append(status.Conditions, newLumigoCondition(operatorv1alpha1.LumigoConditionTypeTelemetryVerified, conditionStatus, now, "", message))
+	}
+}
+
+// RemoveCondition drops the condition of the given type, if any
+func RemoveCondition(lumigo *operatorv1alpha1.Lumigo, t operatorv1alpha1.LumigoConditionType) {
+	status := &lumigo.Status
+	if conditionIndex := getConditionIndexByType(status, t); conditionIndex > -1 {
+		status.Conditions = append(status.Conditions[:conditionIndex], status.Conditions[conditionIndex+1:]...)
+	}
+}
+
+func IsPaused(lumigo *operatorv1alpha1.Lumigo) bool {
+	if pausedCondition := GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypePaused); pausedCondition != nil {
+		return pausedCondition.Status == corev1.ConditionTrue
+	}
+
+	return false
+}
+
+func IsBackendUnreachable(lumigo *operatorv1alpha1.Lumigo) bool {
+	if condition := GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeBackendUnreachable); condition != nil {
+		return condition.Status == corev1.ConditionTrue
+	}
+
+	return false
+}
+
+func IsTelemetryProxyUnreachable(lumigo *operatorv1alpha1.Lumigo) bool {
+	if condition := GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable); condition != nil {
+		return condition.Status == corev1.ConditionTrue
+	}
+
+	return false
+}
+
+func HasInjectionRuntimeFailures(lumigo *operatorv1alpha1.Lumigo) bool {
+	if condition := GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeInjectionRuntimeFailures); condition != nil {
+		return condition.Status == corev1.ConditionTrue
+	}
+
+	return false
+}
+
+func IsTokenMissing(lumigo *operatorv1alpha1.Lumigo) bool {
+	if condition := GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeTokenMissing); condition != nil {
+		return condition.Status == corev1.ConditionTrue
+	}
+
+	return false
+}
+
 func IsActive(lumigo *operatorv1alpha1.Lumigo) bool {
 	if activeCondition := GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeActive); activeCondition != nil {
 		return activeCondition.Status == corev1.ConditionTrue
@@ -89,9 +242,44 @@ func HasError(lumigo *operatorv1alpha1.Lumigo) (bool, string) {
 	return false, ""
 }
 
+// degradingConditionTypes are the conditions that, while true, make an active Lumigo instance Degraded
+var degradingConditionTypes = []operatorv1alpha1.LumigoConditionType{
+	operatorv1alpha1.LumigoConditionTypeTelemetryExportDegraded,
+	operatorv1alpha1.LumigoConditionTypeBackendUnreachable,
+	operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable,
+	operatorv1alpha1.LumigoConditionTypeInjectionRuntimeFailures,
+	operatorv1alpha1.LumigoConditionTypeTokenMissing,
+}
+
+// GetPhase summarizes the conditions of the Lumigo instance in the value of `.status.phase`
+func GetPhase(lumigo *operatorv1alpha1.Lumigo) operatorv1alpha1.LumigoPhase {
+	if hasError, _ := HasError(lumigo); hasError {
+		return operatorv1alpha1.LumigoPhaseError
+	}
+
+	if !IsActive(lumigo) {
+		return operatorv1alpha1.LumigoPhasePending
+	}
+
+	for _, t := range degradingConditionTypes {
+		if condition := GetLumigoConditionByType(lumigo, t); condition != nil && condition.Status == corev1.ConditionTrue {
+			return operatorv1alpha1.LumigoPhaseDegraded
+		}
+	}
+
+	return operatorv1alpha1.LumigoPhaseActive
+}
+
+// SetPhase updates `.status.phase` to reflect the current conditions of the Lumigo instance
+func SetPhase(lumigo *operatorv1alpha1.Lumigo) {
+	lumigo.Status.Phase = GetPhase(lumigo)
+}
+
 func updateLumigoConditions(lumigo *operatorv1alpha1.Lumigo, t operatorv1alpha1.LumigoConditionType, now metav1.Time, conditionStatus corev1.ConditionStatus, desc string) {
 	status := &lumigo.Status
 	conditionIndex := getConditionIndexByType(status, t)
+	// The messages of the conditions, e.g., those of errors, are visible to whoever can read the Lumigo instance
+	desc = redaction.String(desc)
 
 	if conditionIndex > -1 {
 		setLumigoCondition(&status.Conditions[conditionIndex], now, conditionStatus, desc)
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Conditions Suite")
+}
+
+var _ = Context("Phase", func() {
+
+	now := metav1.Now()
+
+	It("is Pending before the Lumigo instance has conditions", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+
+		SetPhase(lumigo)
+
+		Expect(lumigo.Status.Phase).To(Equal(operatorv1alpha1.LumigoPhasePending))
+	})
+
+	It("is Active when the Lumigo instance is active", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		SetActiveAndErrorConditions(lumigo, now, nil)
+		SetBackendUnreachableCondition(lumigo, now, false, "")
+
+		SetPhase(lumigo)
+
+		Expect(lumigo.Status.Phase).To(Equal(operatorv1alpha1.LumigoPhaseActive))
+	})
+
+	It("is Error when the Lumigo instance has an error", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		SetActiveAndErrorConditions(lumigo, now, fmt.Errorf("the Lumigo token is not valid"))
+		SetBackendUnreachableCondition(lumigo, now, true, "")
+
+		SetPhase(lumigo)
+
+		Expect(lumigo.Status.Phase).To(Equal(operatorv1alpha1.LumigoPhaseError))
+	})
+
+	It("is Degraded when the Lumigo instance is active but its telemetry fails", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		SetActiveAndErrorConditions(lumigo, now, nil)
+		SetTelemetryProxyUnreachableCondition(lumigo, now, true, "the telemetry-proxy does not accept connections")
+
+		SetPhase(lumigo)
+
+		Expect(lumigo.Status.Phase).To(Equal(operatorv1alpha1.LumigoPhaseDegraded))
+
+		SetTelemetryProxyUnreachableCondition(lumigo, now, false, "")
+		SetPhase(lumigo)
+
+		Expect(lumigo.Status.Phase).To(Equal(operatorv1alpha1.LumigoPhaseActive))
+	})
+
+	It("is not Degraded by informational conditions", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		SetActiveAndErrorConditions(lumigo, now, nil)
+		SetRateLimitedCondition(lumigo, now, true, "spans are dropped")
+		SetInconsistentSpecCondition(lumigo, now, true, "some settings have no effect")
+
+		SetPhase(lumigo)
+
+		Expect(lumigo.Status.Phase).To(Equal(operatorv1alpha1.LumigoPhaseActive))
+	})
+})
+
+var _ = Context("Conditions", func() {
+
+	now := metav1.Now()
+
+	It("never disclose the Lumigo tokens", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		SetActiveAndErrorConditions(lumigo, now, fmt.Errorf("the Lumigo token 't_0123456789abcdef01234' is not valid"))
+		SetTelemetryProxyUnreachableCondition(lumigo, now, true, "cannot authenticate with t_0123456789abcdef01234")
+
+		Expect(GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeError).Message).To(Equal("the Lumigo token 't_***' is not valid"))
+		Expect(GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable).Message).To(Equal("cannot authenticate with t_***"))
+	})
+
+})
@@ -0,0 +1,72 @@
+package deferredinjections
+
+import (
+	"errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// ErrInjectionDeferred is returned in place of updating a workload that is paused or suspended, whose
+// injection is deferred until it is resumed
+var ErrInjectionDeferred = errors.New("the injection is deferred until the workload is resumed")
+
+// IsSuspended returns whether the workload is a paused Deployment or a suspended CronJob, whose injection
+// is deferred so that it does not roll out as a surprise when the workload is resumed; the other workloads,
+// including the suspended Jobs, whose pod templates cannot be changed later on, are never suspended.
+func IsSuspended(obj runtime.Object) bool {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return workload.Spec.Paused
+	case *batchv1.CronJob:
+		return workload.Spec.Suspend != nil && *workload.Spec.Suspend
+	default:
+		return false
+	}
+}
+
+// DeferInjection adds the workload to the deferred injections of the Lumigo instance, unless it is already there.
+func DeferInjection(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference) {
+	if getDeferredInjectionIndex(&lumigo.Status, &resource) > -1 {
+		return
+	}
+
+	lumigo.Status.DeferredInjections = append(lumigo.Status.DeferredInjections, resource)
+}
+
+// RemoveDeferredInjection removes the workload from the deferred injections of the Lumigo instance,
+// returning whether it was deferred.
+func RemoveDeferredInjection(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference) bool {
+	status := &lumigo.Status
+	index := getDeferredInjectionIndex(status, &resource)
+	if index < 0 {
+		return false
+	}
+
+	status.DeferredInjections = append(status.DeferredInjections[:index], status.DeferredInjections[index+1:]...)
+	if len(status.DeferredInjections) < 1 {
+		status.DeferredInjections = nil
+	}
+
+	return true
+}
+
+// ClearAllDeferredInjections removes all the deferred injections of the Lumigo instance.
+func ClearAllDeferredInjections(lumigo *operatorv1alpha1.Lumigo) {
+	lumigo.Status.DeferredInjections = nil
+}
+
+func getDeferredInjectionIndex(status *operatorv1alpha1.LumigoStatus, resource *corev1.ObjectReference) int {
+	for i, deferred := range status.DeferredInjections {
+		// The UIDs and resource versions are not compared, as the references are created from different copies of the resources
+		if deferred.Kind == resource.Kind && deferred.Namespace == resource.Namespace && deferred.Name == resource.Name {
+			return i
+		}
+	}
+
+	return -1
+}
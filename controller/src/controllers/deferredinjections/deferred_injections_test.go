@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deferredinjections
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Deferred Injections Suite")
+}
+
+func newBool(value bool) *bool {
+	return &value
+}
+
+var _ = Context("Deferred injections", func() {
+
+	It("defers only the paused deployments and the suspended cronjobs", func() {
+		Expect(IsSuspended(&appsv1.Deployment{Spec: appsv1.DeploymentSpec{Paused: true}})).To(BeTrue())
+		Expect(IsSuspended(&appsv1.Deployment{})).To(BeFalse())
+
+		Expect(IsSuspended(&batchv1.CronJob{Spec: batchv1.CronJobSpec{Suspend: newBool(true)}})).To(BeTrue())
+		Expect(IsSuspended(&batchv1.CronJob{Spec: batchv1.CronJobSpec{Suspend: newBool(false)}})).To(BeFalse())
+		Expect(IsSuspended(&batchv1.CronJob{})).To(BeFalse())
+
+		// The pod templates of jobs cannot be changed once created
+		Expect(IsSuspended(&batchv1.Job{Spec: batchv1.JobSpec{Suspend: newBool(true)}})).To(BeFalse())
+		Expect(IsSuspended(&appsv1.StatefulSet{})).To(BeFalse())
+	})
+
+	It("tracks each deferred workload once", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		deployment := corev1.ObjectReference{Kind: "Deployment", Namespace: "my-namespace", Name: "my-app"}
+		cronjob := corev1.ObjectReference{Kind: "CronJob", Namespace: "my-namespace", Name: "my-report"}
+
+		DeferInjection(lumigo, deployment)
+		DeferInjection(lumigo, cronjob)
+		DeferInjection(lumigo, corev1.ObjectReference{Kind: "Deployment", Namespace: "my-namespace", Name: "my-app", ResourceVersion: "42"})
+		Expect(lumigo.Status.DeferredInjections).To(Equal([]corev1.ObjectReference{deployment, cronjob}))
+
+		Expect(RemoveDeferredInjection(lumigo, deployment)).To(BeTrue())
+		Expect(RemoveDeferredInjection(lumigo, deployment)).To(BeFalse())
+		Expect(lumigo.Status.DeferredInjections).To(Equal([]corev1.ObjectReference{cronjob}))
+
+		Expect(RemoveDeferredInjection(lumigo, cronjob)).To(BeTrue())
+		Expect(lumigo.Status.DeferredInjections).To(BeNil())
+	})
+
+	It("clears all the deferred injections", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		DeferInjection(lumigo, corev1.ObjectReference{Kind: "Deployment", Namespace: "my-namespace", Name: "my-app"})
+
+		ClearAllDeferredInjections(lumigo)
+		Expect(lumigo.Status.DeferredInjections).To(BeNil())
+	})
+
+})
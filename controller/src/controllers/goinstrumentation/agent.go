@@ -0,0 +1,361 @@
+package goinstrumentation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	AgentName                          = "lumigo-go-instrumentation-agent"
+	agentNamespacesSecretKey           = "namespaces_to_instrument.json"
+	agentNamespacesMountPath           = "/lumigo/etc/namespaces/"
+	agentNamespacesVolumeName          = "namespace-configurations"
+	agentNamespacesChecksumAnnotation  = "lumigo.io/namespaces-checksum"
+	kubernetesAppNameLabelKey          = "app.kubernetes.io/name"
+	kubernetesAppComponentLabelKey     = "app.kubernetes.io/component"
+	kubernetesAppComponentLabelValue   = "go-instrumentation-agent"
+	kubernetesAppPartOfLabelKey        = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue      = "lumigo"
+	kubernetesAppManagedByLabelKey     = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue   = "lumigo-operator"
+	agentNamespacesConfigurationEnvVar = "LUMIGO_NAMESPACE_CONFIGURATIONS"
+)
+
+// AgentConfig contains the settings of the operator that apply to the
+// Go instrumentation agent DaemonSet
+type AgentConfig struct {
+	// The namespace the operator runs in, in which the agent DaemonSet is deployed
+	Namespace string
+	// The service account of the operator, which the agent uses to look up the pods of the processes it instruments
+	ServiceAccountName string
+	// The image of the Go instrumentation agent; if empty, the agent cannot be deployed
+	Image string
+	// The URL of the OTLP traces endpoint of the telemetry-proxy
+	TelemetryProxyOtlpServiceUrl string
+	LumigoOperatorVersion        string
+}
+
+type NamespaceInstrumentationConfig struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+func RemoveGoInstrumentationOfNamespace(ctx context.Context, c client.Client, agentConfig *AgentConfig, namespaceName string, log *logr.Logger) (bool, error) {
+	return updateGoInstrumentationOfNamespace(ctx, c, agentConfig, &NamespaceInstrumentationConfig{
+		Name: namespaceName,
+	}, false, log)
+}
+
+func UpsertGoInstrumentationOfNamespace(ctx context.Context, c client.Client, agentConfig *AgentConfig, namespaceName string, token string, log *logr.Logger) (bool, error) {
+	if agentConfig.Image == "" {
+		return false, fmt.Errorf("the Go instrumentation agent image is not configured")
+	}
+
+	return updateGoInstrumentationOfNamespace(ctx, c, agentConfig, &NamespaceInstrumentationConfig{
+		Name:  namespaceName,
+		Token: token,
+	}, true, log)
+}
+
+func updateGoInstrumentationOfNamespace(ctx context.Context, c client.Client, agentConfig *AgentConfig, namespaceConfig *NamespaceInstrumentationConfig, upsert bool, log *logr.Logger) (bool, error) {
+	secret := &corev1.Secret{}
+	secretExists := true
+	if err := c.Get(ctx, types.NamespacedName{Namespace: agentConfig.Namespace, Name: AgentName}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the Go instrumentation agent configuration secret: %w", err)
+		}
+		secretExists = false
+	}
+
+	if !secretExists && !upsert {
+		// No namespace is instrumented, so the agent is not deployed
+		return false, nil
+	}
+
+	var namespaces []NamespaceInstrumentationConfig
+	namespacesBytes := secret.Data[agentNamespacesSecretKey]
+	if len(namespacesBytes) > 0 {
+		if err := json.Unmarshal(namespacesBytes, &namespaces); err != nil {
+			return false, fmt.Errorf("cannot unmarshal the Go instrumentation agent namespace configurations: %w", err)
+		}
+	}
+
+	var newNamespaces []NamespaceInstrumentationConfig
+	// Keep all other namespaces in the new configuration
+	for _, namespace := range namespaces {
+		if namespace.Name != namespaceConfig.Name {
+			newNamespaces = append(newNamespaces, namespace)
+		}
+	}
+
+	if upsert {
+		newNamespaces = append(newNamespaces, *namespaceConfig)
+	}
+
+	// Sort namespace structs by namespace name
+	sort.Slice(newNamespaces, func(i, j int) bool {
+		return newNamespaces[i].Name < newNamespaces[j].Name
+	})
+
+	if len(newNamespaces) == 0 {
+		// No namespace is left to instrument, so there is no need for the agent anymore
+		return removeAgent(ctx, c, agentConfig, log)
+	}
+
+	updatedNamespacesBytes, err := json.Marshal(newNamespaces)
+	if err != nil {
+		return false, fmt.Errorf("cannot marshal the updated Go instrumentation agent namespace configurations: %w", err)
+	}
+
+	isChanged := false
+	if !bytes.Equal(namespacesBytes, updatedNamespacesBytes) {
+		secret.ObjectMeta.Namespace = agentConfig.Namespace
+		secret.ObjectMeta.Name = AgentName
+		secret.ObjectMeta.Labels = agentLabels()
+		secret.Data = map[string][]byte{
+			agentNamespacesSecretKey: updatedNamespacesBytes,
+		}
+
+		if secretExists {
+			err = c.Update(ctx, secret)
+		} else {
+			err = c.Create(ctx, secret)
+		}
+		if err != nil {
+			return false, fmt.Errorf("cannot write the Go instrumentation agent configuration secret: %w", err)
+		}
+
+		isChanged = true
+		log.Info("Updated Go instrumentation agent namespace configurations", "namespaces", namespaceNames(newNamespaces))
+	}
+
+	isDaemonSetChanged, err := upsertAgentDaemonSet(ctx, c, agentConfig, updatedNamespacesBytes, log)
+	if err != nil {
+		return isChanged, err
+	}
+
+	return isChanged || isDaemonSetChanged, nil
+}
+
+func upsertAgentDaemonSet(ctx context.Context, c client.Client, agentConfig *AgentConfig, namespacesBytes []byte, log *logr.Logger) (bool, error) {
+	checksum := sha256.Sum256(namespacesBytes)
+	desiredDaemonSet := newAgentDaemonSet(agentConfig, hex.EncodeToString(checksum[:]))
+
+	daemonSet := &appsv1.DaemonSet{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: agentConfig.Namespace, Name: AgentName}, daemonSet); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the Go instrumentation agent DaemonSet: %w", err)
+		}
+
+		if err := c.Create(ctx, desiredDaemonSet); err != nil {
+			return false, fmt.Errorf("cannot create the Go instrumentation agent DaemonSet: %w", err)
+		}
+
+		log.Info("Created the Go instrumentation agent DaemonSet", "namespace", agentConfig.Namespace, "name", AgentName)
+		return true, nil
+	}
+
+	// The checksum annotation rolls out the agent pods when the namespace configurations change
+	if daemonSet.Spec.Template.Annotations[agentNamespacesChecksumAnnotation] == desiredDaemonSet.Spec.Template.Annotations[agentNamespacesChecksumAnnotation] &&
+		daemonSet.Spec.Template.Spec.Containers[0].Image == agentConfig.Image {
+		return false, nil
+	}
+
+	daemonSet.ObjectMeta.Labels = desiredDaemonSet.ObjectMeta.Labels
+	daemonSet.Spec.Template = desiredDaemonSet.Spec.Template
+	if err := c.Update(ctx, daemonSet); err != nil {
+		return false, fmt.Errorf("cannot update the Go instrumentation agent DaemonSet: %w", err)
+	}
+
+	log.Info("Updated the Go instrumentation agent DaemonSet", "namespace", agentConfig.Namespace, "name", AgentName)
+	return true, nil
+}
+
+func removeAgent(ctx context.Context, c client.Client, agentConfig *AgentConfig, log *logr.Logger) (bool, error) {
+	isChanged := false
+
+	if err := c.Delete(ctx, &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: agentConfig.Namespace,
+			Name:      AgentName,
+		},
+	}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot delete the Go instrumentation agent DaemonSet: %w", err)
+		}
+	} else {
+		isChanged = true
+		log.Info("Deleted the Go instrumentation agent DaemonSet, as no namespace is left to instrument", "namespace", agentConfig.Namespace, "name", AgentName)
+	}
+
+	if err := c.Delete(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: agentConfig.Namespace,
+			Name:      AgentName,
+		},
+	}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return isChanged, fmt.Errorf("cannot delete the Go instrumentation agent configuration secret: %w", err)
+		}
+	} else {
+		isChanged = true
+	}
+
+	return isChanged, nil
+}
+
+func newAgentDaemonSet(agentConfig *AgentConfig, namespacesChecksum string) *appsv1.DaemonSet {
+	privileged := true
+	automountServiceAccountToken := true
+	labels := agentLabels()
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: agentConfig.Namespace,
+			Name:      AgentName,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					kubernetesAppNameLabelKey:      AgentName,
+					kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						agentNamespacesChecksumAnnotation: namespacesChecksum,
+					},
+				},
+				Spec: corev1.PodSpec{
+					// The agent needs to see the processes of the other pods on the node to instrument them
+					HostPID:                      true,
+					ServiceAccountName:           agentConfig.ServiceAccountName,
+					AutomountServiceAccountToken: &automountServiceAccountToken,
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{
+									{
+										MatchExpressions: []corev1.NodeSelectorRequirement{
+											{
+												Key:      "kubernetes.io/arch",
+												Operator: corev1.NodeSelectorOpIn,
+												Values:   []string{"amd64", "arm64"},
+											},
+											{
+												Key:      "kubernetes.io/os",
+												Operator: corev1.NodeSelectorOpIn,
+												Values:   []string{"linux"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					Tolerations: []corev1.Toleration{
+						{
+							// Go processes must be instrumented on every node they may run on
+							Operator: corev1.TolerationOpExists,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "agent",
+							Image: agentConfig.Image,
+							Env: []corev1.EnvVar{
+								{
+									Name:  agentNamespacesConfigurationEnvVar,
+									Value: agentNamespacesMountPath + agentNamespacesSecretKey,
+								},
+								{
+									// Referenced by the telemetry-proxy endpoint when the telemetry-proxy runs as a DaemonSet
+									Name: mutation.TelemetryProxyHostIpEnvVarName,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "status.hostIP",
+										},
+									},
+								},
+								{
+									Name:  "LUMIGO_ENDPOINT",
+									Value: agentConfig.TelemetryProxyOtlpServiceUrl,
+								},
+								{
+									Name:  "LUMIGO_OPERATOR_VERSION",
+									Value: agentConfig.LumigoOperatorVersion,
+								},
+								{
+									Name: "LUMIGO_NODE_NAME",
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "spec.nodeName",
+										},
+									},
+								},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								// Loading eBPF programs requires privileges on the node
+								Privileged: &privileged,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      agentNamespacesVolumeName,
+									MountPath: agentNamespacesMountPath,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: agentNamespacesVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: AgentName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func agentLabels() map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      AgentName,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+		kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+		// We do not need the operator to inject the agent
+		mutation.LumigoAutoTraceLabelKey: "false",
+	}
+}
+
+func namespaceNames(namespaces []NamespaceInstrumentationConfig) []string {
+	names := make([]string, len(namespaces))
+	for i, namespace := range namespaces {
+		names[i] = namespace.Name
+	}
+	return names
+}
@@ -0,0 +1,89 @@
+package imageverification
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+)
+
+// RegistryCredentials are the username and password to authenticate with a container registry
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// ParseDockerConfigJson parses the credentials in a `.dockerconfigjson` file, like those of
+// `kubernetes.io/dockerconfigjson` secrets, indexed by registry.
+func ParseDockerConfigJson(dockerConfigJson []byte) (map[string]RegistryCredentials, error) {
+	dockerConfig := struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(dockerConfigJson, &dockerConfig); err != nil {
+		return nil, fmt.Errorf("cannot parse the docker config: %w", err)
+	}
+
+	credentials := make(map[string]RegistryCredentials)
+	for registry, auth := range dockerConfig.Auths {
+		username, password := auth.Username, auth.Password
+		if len(auth.Auth) > 0 {
+			decodedAuth, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode the credentials of registry '%s': %w", registry, err)
+			}
+
+			var isFound bool
+			if username, password, isFound = strings.Cut(string(decodedAuth), ":"); !isFound {
+				return nil, fmt.Errorf("the credentials of registry '%s' are not in the 'username:password' format", registry)
+			}
+		}
+
+		// Registries may be listed as URLs, e.g., `https://index.docker.io/v1/`
+		registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+		registry, _, _ = strings.Cut(registry, "/")
+		if registry == "index.docker.io" {
+			registry = dockerHubRegistry
+		}
+
+		credentials[registry] = RegistryCredentials{
+			Username: username,
+			Password: password,
+		}
+	}
+
+	return credentials, nil
+}
+
+// registryKeychain authenticates with the registries that have configured credentials, and
+// anonymously with all the others
+type registryKeychain struct {
+	credentials map[string]RegistryCredentials
+}
+
+func (k *registryKeychain) Resolve(resource authn.Resource) (authn.Authenticator, error) {
+	registry := resource.RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = dockerHubRegistry
+	}
+
+	credentials, isFound := k.credentials[registry]
+	if !isFound {
+		return authn.Anonymous, nil
+	}
+
+	return &authn.Basic{
+		Username: credentials.Username,
+		Password: credentials.Password,
+	}, nil
+}
@@ -0,0 +1,262 @@
+package imageverification
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/tuf"
+)
+
+const (
+	// How long a successful verification is trusted before the image is verified again
+	defaultVerifiedTtl = 10 * time.Minute
+	// How long a failed verification is reported before the image is verified again
+	defaultFailedTtl = time.Minute
+	// How long the requests to the registry can take
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// Policy describes which cosign signatures the injector image must have; exactly one of
+// PublicKey and Keyless must be set.
+type Policy struct {
+	// The public key the image is signed with, as in `cosign sign --key`
+	PublicKey crypto.PublicKey
+	// The identity that signed the image with a short-lived Fulcio certificate, as in `cosign sign` without keys
+	Keyless *KeylessPolicy
+}
+
+// KeylessPolicy describes the identity expected to have signed an image with cosign's keyless flow
+type KeylessPolicy struct {
+	// The email address or URI in the certificate, e.g., the workflow that built the image
+	Identity string
+	// The OIDC issuer that authenticated the identity, e.g., `https://token.actions.githubusercontent.com`
+	Issuer string
+	// The root certificates of the Fulcio instance that issued the certificate
+	FulcioRoots *x509.CertPool
+	// The public key of the Rekor transparency log that recorded the signature
+	RekorPublicKey crypto.PublicKey
+}
+
+// Verifier verifies the cosign signatures of images in their registries, and caches the outcome
+// for a while, so that it can be used in admission webhooks
+type Verifier struct {
+	policy        *Policy
+	remoteOptions []remote.Option
+	verifiedTtl   time.Duration
+	failedTtl     time.Duration
+
+	mutex   sync.Mutex
+	results map[string]*verificationResult
+}
+
+type verificationResult struct {
+	pinnedImage string
+	err         error
+	expiresAt   time.Time
+}
+
+// NewVerifier creates a Verifier that enforces the given policy, and uses the given credentials,
+// indexed by registry, to pull signatures from private registries.
+func NewVerifier(policy *Policy, registryCredentials map[string]RegistryCredentials) (*Verifier, error) {
+	if (policy.PublicKey == nil) == (policy.Keyless == nil) {
+		return nil, fmt.Errorf("exactly one of a public key and a keyless identity must be configured")
+	}
+
+	if policy.Keyless != nil {
+		if len(policy.Keyless.Identity) < 1 || len(policy.Keyless.Issuer) < 1 {
+			return nil, fmt.Errorf("keyless verification requires both an identity and an issuer")
+		}
+
+		if policy.Keyless.FulcioRoots == nil || policy.Keyless.RekorPublicKey == nil {
+			return nil, fmt.Errorf("keyless verification requires both the Fulcio root certificates and the Rekor public key")
+		}
+	}
+
+	return &Verifier{
+		policy: policy,
+		remoteOptions: []remote.Option{
+			remote.WithAuthFromKeychain(&registryKeychain{credentials: registryCredentials}),
+		},
+		verifiedTtl: defaultVerifiedTtl,
+		failedTtl:   defaultFailedTtl,
+		results:     make(map[string]*verificationResult),
+	}, nil
+}
+
+// ParsePublicKey parses a PEM-encoded public key, like those created by `cosign generate-key-pair`
+func ParsePublicKey(publicKeyPem []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(publicKeyPem)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM-encoded public key found")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the public key: %w", err)
+	}
+
+	return publicKey, nil
+}
+
+// ParseCertificatePool parses one or more PEM-encoded certificates
+func ParseCertificatePool(certificatesPem []byte) (*x509.CertPool, error) {
+	certificates, err := parseCertificates(certificatesPem)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certificates) < 1 {
+		return nil, fmt.Errorf("no PEM-encoded certificate found")
+	}
+
+	pool := x509.NewCertPool()
+	for _, certificate := range certificates {
+		pool.AddCert(certificate)
+	}
+
+	return pool, nil
+}
+
+// Verify checks that the given image has a valid cosign signature according to the policy, and
+// returns the image reference pinned to the digest that has been verified.
+func (v *Verifier) Verify(ctx context.Context, image string) (string, error) {
+	v.mutex.Lock()
+	result, isCached := v.results[image]
+	v.mutex.Unlock()
+
+	now := time.Now()
+	if isCached && now.Before(result.expiresAt) {
+		return result.pinnedImage, result.err
+	}
+
+	pinnedImage, err := v.verify(ctx, image)
+
+	result = &verificationResult{
+		pinnedImage: pinnedImage,
+		err:         err,
+		expiresAt:   now.Add(v.verifiedTtl),
+	}
+	if err != nil {
+		result.expiresAt = now.Add(v.failedTtl)
+	}
+
+	v.mutex.Lock()
+	v.results[image] = result
+	v.mutex.Unlock()
+
+	return pinnedImage, err
+}
+
+func (v *Verifier) verify(ctx context.Context, image string) (string, error) {
+	reference, err := name.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse the image reference '%s': %w", image, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+
+	registryOptions := []ociremote.Option{
+		ociremote.WithRemoteOptions(append([]remote.Option{remote.WithContext(ctx)}, v.remoteOptions...)...),
+	}
+
+	digest, err := ociremote.ResolveDigest(reference, registryOptions...)
+	if err != nil {
+		return "", fmt.Errorf("cannot retrieve the manifest of '%s': %w", image, err)
+	}
+
+	checkOptions, err := v.checkOptions(registryOptions)
+	if err != nil {
+		return "", err
+	}
+
+	if _, _, err := cosign.VerifyImageSignatures(ctx, digest, checkOptions); err != nil {
+		return "", fmt.Errorf("the image '%s' with digest '%s' has no valid cosign signature: %w", image, digest.DigestStr(), err)
+	}
+
+	// Pin the image to the verified digest, so that it is the one pulled even if its tag is later moved
+	if _, isPinned := reference.(name.Digest); isPinned {
+		return image, nil
+	}
+
+	return image + "@" + digest.DigestStr(), nil
+}
+
+// checkOptions returns what cosign has to check about the signatures according to the policy;
+// cosign modifies the options while verifying, so they are created anew for each verification.
+func (v *Verifier) checkOptions(registryOptions []ociremote.Option) (*cosign.CheckOpts, error) {
+	checkOptions := &cosign.CheckOpts{
+		RegistryClientOpts: registryOptions,
+		ClaimVerifier:      cosign.SimpleClaimVerifier,
+	}
+
+	if v.policy.PublicKey != nil {
+		signatureVerifier, err := signature.LoadVerifier(v.policy.PublicKey, crypto.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load the public key: %w", err)
+		}
+
+		checkOptions.SigVerifier = signatureVerifier
+		// Like `cosign verify --key --insecure-ignore-tlog`: the key is trusted regardless of the transparency log
+		checkOptions.IgnoreTlog = true
+		return checkOptions, nil
+	}
+
+	rekorLogId, err := cosign.GetTransparencyLogID(v.policy.Keyless.RekorPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute the log ID of the Rekor public key: %w", err)
+	}
+
+	checkOptions.RootCerts = v.policy.Keyless.FulcioRoots
+	checkOptions.Identities = []cosign.Identity{{
+		Issuer:  v.policy.Keyless.Issuer,
+		Subject: v.policy.Keyless.Identity,
+	}}
+	// Fulcio certificates expire minutes after they are issued: the Rekor bundle attached to the
+	// signature attests when the signature was made, and that it was made with the certificate
+	checkOptions.RekorPubKeys = &cosign.TrustedTransparencyLogPubKeys{
+		Keys: map[string]cosign.TransparencyLogPubKey{
+			rekorLogId: {
+				PubKey: v.policy.Keyless.RekorPublicKey,
+				Status: tuf.Active,
+			},
+		},
+	}
+	checkOptions.Offline = true
+	// The policy has no certificate transparency log key to check the embedded SCTs with
+	checkOptions.IgnoreSCT = true
+
+	return checkOptions, nil
+}
+
+func parseCertificates(certificatesPem []byte) ([]*x509.Certificate, error) {
+	certificates := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		if block, certificatesPem = pem.Decode(certificatesPem); block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse certificate: %w", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	return certificates, nil
+}
@@ -0,0 +1,462 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageverification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	testRepository = "lumigo/lumigo-autotrace"
+	testIdentity   = "https://github.com/lumigo-io/lumigo-autotrace/.github/workflows/release.yml@refs/heads/main"
+	testIssuer     = "https://token.actions.githubusercontent.com"
+)
+
+var (
+	ctx = context.Background()
+
+	// The OIDC issuer in Fulcio certificates, as a raw string
+	fulcioIssuerOid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Image Verification Suite")
+}
+
+// testRegistry is an in-memory registry served over plain HTTP on the loopback interface
+type testRegistry struct {
+	server   *httptest.Server
+	requests atomic.Int64
+}
+
+func newTestRegistry() *testRegistry {
+	registry := &testRegistry{}
+
+	handler := ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))
+	registry.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry.requests.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+
+	return registry
+}
+
+func (r *testRegistry) image(tag string) string {
+	serverUrl, _ := url.Parse(r.server.URL)
+	return fmt.Sprintf("%s/%s:%s", serverUrl.Host, testRepository, tag)
+}
+
+// pushImage adds a random image with the given tag and returns its digest
+func (r *testRegistry) pushImage(tag string) name.Digest {
+	image, err := random.Image(256, 1)
+	Expect(err).NotTo(HaveOccurred())
+
+	reference, err := name.ParseReference(r.image(tag))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(remote.Write(reference, image)).To(Succeed())
+
+	digest, err := image.Digest()
+	Expect(err).NotTo(HaveOccurred())
+
+	return reference.Context().Digest(digest.String())
+}
+
+// pushSignature attaches a cosign signature of the given payload to the image with the given digest
+func (r *testRegistry) pushSignature(digest name.Digest, signedPayload []byte, signature []byte, options ...static.Option) {
+	cosignSignature, err := static.NewSignature(signedPayload, base64.StdEncoding.EncodeToString(signature), options...)
+	Expect(err).NotTo(HaveOccurred())
+
+	signedEntity, err := ociremote.SignedEntity(digest)
+	Expect(err).NotTo(HaveOccurred())
+
+	signedEntity, err = mutate.AttachSignatureToEntity(signedEntity, cosignSignature)
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(ociremote.WriteSignatures(digest.Repository, signedEntity)).To(Succeed())
+}
+
+func newSignedPayload(digest name.Digest) []byte {
+	signedPayload, err := payload.Cosign{Image: digest}.MarshalJSON()
+	Expect(err).NotTo(HaveOccurred())
+
+	return signedPayload
+}
+
+func sign(privateKey *ecdsa.PrivateKey, content []byte) []byte {
+	hash := sha256.Sum256(content)
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, hash[:])
+	Expect(err).NotTo(HaveOccurred())
+
+	return signature
+}
+
+func newPrivateKey() *ecdsa.PrivateKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	return privateKey
+}
+
+func newCertificate(template *x509.Certificate, publicKey crypto.PublicKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, []byte) {
+	if parent == nil {
+		parent = template
+	}
+
+	certificateDer, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, parentKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	certificate, err := x509.ParseCertificate(certificateDer)
+	Expect(err).NotTo(HaveOccurred())
+
+	return certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDer})
+}
+
+func newRootCertificate(rootKey *ecdsa.PrivateKey) (*x509.Certificate, []byte) {
+	return newCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio-test-root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, &rootKey.PublicKey, nil, rootKey)
+}
+
+var _ = Context("Image verification", func() {
+
+	var registry *testRegistry
+
+	BeforeEach(func() {
+		registry = newTestRegistry()
+	})
+
+	AfterEach(func() {
+		registry.server.Close()
+	})
+
+	Context("with a public key", func() {
+
+		var signingKey *ecdsa.PrivateKey
+		var verifier *Verifier
+
+		BeforeEach(func() {
+			signingKey = newPrivateKey()
+
+			publicKeyDer, err := x509.MarshalPKIXPublicKey(&signingKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			publicKey, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer}))
+			Expect(err).NotTo(HaveOccurred())
+
+			verifier, err = NewVerifier(&Policy{PublicKey: publicKey}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts a signed image and pins it to the verified digest", func() {
+			digest := registry.pushImage("1.0.0")
+			signedPayload := newSignedPayload(digest)
+			registry.pushSignature(digest, signedPayload, sign(signingKey, signedPayload))
+
+			pinnedImage, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pinnedImage).To(Equal(registry.image("1.0.0") + "@" + digest.DigestStr()))
+		})
+
+		It("does not pin again an image already pinned to a digest", func() {
+			digest := registry.pushImage("1.0.0")
+			signedPayload := newSignedPayload(digest)
+			registry.pushSignature(digest, signedPayload, sign(signingKey, signedPayload))
+
+			pinnedImage, err := verifier.Verify(ctx, digest.String())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pinnedImage).To(Equal(digest.String()))
+		})
+
+		It("rejects an unsigned image", func() {
+			registry.pushImage("1.0.0")
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("no signatures found")))
+		})
+
+		It("rejects an image signed with another key", func() {
+			digest := registry.pushImage("1.0.0")
+			signedPayload := newSignedPayload(digest)
+			registry.pushSignature(digest, signedPayload, sign(newPrivateKey(), signedPayload))
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("invalid signature")))
+		})
+
+		It("rejects the signature of another image", func() {
+			digest := registry.pushImage("1.0.0")
+			otherDigest := registry.pushImage("2.0.0")
+			signedPayload := newSignedPayload(otherDigest)
+			registry.pushSignature(digest, signedPayload, sign(signingKey, signedPayload))
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("invalid or missing digest in claim")))
+		})
+
+		It("caches the outcome of the verification", func() {
+			digest := registry.pushImage("1.0.0")
+			signedPayload := newSignedPayload(digest)
+			registry.pushSignature(digest, signedPayload, sign(signingKey, signedPayload))
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			requests := registry.requests.Load()
+
+			_, err = verifier.Verify(ctx, registry.image("1.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(registry.requests.Load()).To(Equal(requests))
+		})
+
+	})
+
+	Context("with a keyless identity", func() {
+
+		var rootKey, rekorKey, ephemeralKey *ecdsa.PrivateKey
+		var root *x509.Certificate
+		var verifier *Verifier
+
+		BeforeEach(func() {
+			rootKey, rekorKey, ephemeralKey = newPrivateKey(), newPrivateKey(), newPrivateKey()
+
+			var rootPem []byte
+			root, rootPem = newRootCertificate(rootKey)
+
+			fulcioRoots, err := ParseCertificatePool(rootPem)
+			Expect(err).NotTo(HaveOccurred())
+
+			verifier, err = NewVerifier(&Policy{
+				Keyless: &KeylessPolicy{
+					Identity:       testIdentity,
+					Issuer:         testIssuer,
+					FulcioRoots:    fulcioRoots,
+					RekorPublicKey: &rekorKey.PublicKey,
+				},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		// newSigningCertificate returns a certificate like those of Fulcio, valid only for a few minutes around the signature
+		newSigningCertificate := func(identity string, issuer string, signedAt time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) []byte {
+			identityUri, err := url.Parse(identity)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, certificatePem := newCertificate(&x509.Certificate{
+				SerialNumber:    big.NewInt(2),
+				NotBefore:       signedAt.Add(-time.Minute),
+				NotAfter:        signedAt.Add(9 * time.Minute),
+				KeyUsage:        x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+				URIs:            []*url.URL{identityUri},
+				ExtraExtensions: []pkix.Extension{{Id: fulcioIssuerOid, Value: []byte(issuer)}},
+			}, &ephemeralKey.PublicKey, parent, parentKey)
+
+			return certificatePem
+		}
+
+		// newRekorBundle returns the proof, signed by Rekor, that the signature made with the given
+		// certificate or public key was recorded in the transparency log at the given time
+		newRekorBundle := func(signedPayload []byte, signature []byte, recordedPem []byte, signedAt time.Time) *bundle.RekorBundle {
+			payloadHash := sha256.Sum256(signedPayload)
+			body, err := json.Marshal(map[string]interface{}{
+				"apiVersion": "0.0.1",
+				"kind":       "hashedrekord",
+				"spec": map[string]interface{}{
+					"data": map[string]interface{}{
+						"hash": map[string]string{
+							"algorithm": "sha256",
+							"value":     hex.EncodeToString(payloadHash[:]),
+						},
+					},
+					"signature": map[string]interface{}{
+						"content": base64.StdEncoding.EncodeToString(signature),
+						"publicKey": map[string]string{
+							"content": base64.StdEncoding.EncodeToString(recordedPem),
+						},
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			logId, err := cosign.GetTransparencyLogID(&rekorKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			rekorPayload := bundle.RekorPayload{
+				Body:           base64.StdEncoding.EncodeToString(body),
+				IntegratedTime: signedAt.Unix(),
+				LogIndex:       42,
+				LogID:          logId,
+			}
+
+			// Rekor signs the payload in its canonical form, which has the keys sorted like the maps marshalled by Go
+			canonicalPayload, err := json.Marshal(map[string]interface{}{
+				"body":           rekorPayload.Body,
+				"integratedTime": rekorPayload.IntegratedTime,
+				"logIndex":       rekorPayload.LogIndex,
+				"logID":          rekorPayload.LogID,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			return &bundle.RekorBundle{
+				SignedEntryTimestamp: sign(rekorKey, canonicalPayload),
+				Payload:              rekorPayload,
+			}
+		}
+
+		pushKeylessSignature := func(digest name.Digest, certificatePem []byte, recordedPem []byte, signedAt time.Time) {
+			signedPayload := newSignedPayload(digest)
+			signature := sign(ephemeralKey, signedPayload)
+
+			registry.pushSignature(digest, signedPayload, signature,
+				static.WithCertChain(certificatePem, nil),
+				static.WithBundle(newRekorBundle(signedPayload, signature, recordedPem, signedAt)),
+			)
+		}
+
+		It("accepts an image signed by the identity with a certificate that has since expired", func() {
+			digest := registry.pushImage("1.0.0")
+			signedAt := time.Now().Add(-30 * time.Minute)
+			certificatePem := newSigningCertificate(testIdentity, testIssuer, signedAt, root, rootKey)
+			pushKeylessSignature(digest, certificatePem, certificatePem, signedAt)
+
+			pinnedImage, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pinnedImage).To(Equal(registry.image("1.0.0") + "@" + digest.DigestStr()))
+		})
+
+		It("rejects an image signed by another identity", func() {
+			digest := registry.pushImage("1.0.0")
+			certificatePem := newSigningCertificate("https://github.com/someone/else/.github/workflows/release.yml@refs/heads/main", testIssuer, time.Now(), root, rootKey)
+			pushKeylessSignature(digest, certificatePem, certificatePem, time.Now())
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("none of the expected identities matched")))
+		})
+
+		It("rejects an identity authenticated by another issuer", func() {
+			digest := registry.pushImage("1.0.0")
+			certificatePem := newSigningCertificate(testIdentity, "https://accounts.google.com", time.Now(), root, rootKey)
+			pushKeylessSignature(digest, certificatePem, certificatePem, time.Now())
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("none of the expected identities matched")))
+		})
+
+		It("rejects a certificate not issued by the Fulcio roots", func() {
+			otherRootKey := newPrivateKey()
+			otherRoot, _ := newRootCertificate(otherRootKey)
+
+			digest := registry.pushImage("1.0.0")
+			certificatePem := newSigningCertificate(testIdentity, testIssuer, time.Now(), otherRoot, otherRootKey)
+			pushKeylessSignature(digest, certificatePem, certificatePem, time.Now())
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("certificate signed by unknown authority")))
+		})
+
+		It("rejects a Rekor entry recorded for another certificate", func() {
+			digest := registry.pushImage("1.0.0")
+			certificatePem := newSigningCertificate(testIdentity, testIssuer, time.Now(), root, rootKey)
+			otherCertificatePem := newSigningCertificate(testIdentity, testIssuer, time.Now(), root, rootKey)
+			pushKeylessSignature(digest, certificatePem, otherCertificatePem, time.Now())
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("comparing public key PEMs")))
+		})
+
+		It("rejects a Rekor entry recorded for another public key", func() {
+			otherKey := newPrivateKey()
+			otherPublicKeyDer, err := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			digest := registry.pushImage("1.0.0")
+			certificatePem := newSigningCertificate(testIdentity, testIssuer, time.Now(), root, rootKey)
+			pushKeylessSignature(digest, certificatePem, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherPublicKeyDer}), time.Now())
+
+			_, err = verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("comparing public key PEMs")))
+		})
+
+		It("rejects a Rekor bundle not signed by the Rekor public key", func() {
+			rekorKey = newPrivateKey()
+
+			digest := registry.pushImage("1.0.0")
+			certificatePem := newSigningCertificate(testIdentity, testIssuer, time.Now(), root, rootKey)
+			pushKeylessSignature(digest, certificatePem, certificatePem, time.Now())
+
+			_, err := verifier.Verify(ctx, registry.image("1.0.0"))
+
+			Expect(err).To(MatchError(ContainSubstring("rekor log public key not found")))
+		})
+
+	})
+
+})
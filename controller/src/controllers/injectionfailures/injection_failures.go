@@ -0,0 +1,178 @@
+package injectionfailures
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	// The delay before the first retry of a failed injection, doubled at every further failure
+	initialRetryDelay = 30 * time.Second
+	maxRetryDelay     = time.Hour
+	// How many attempts to inject a resource fail in a row before its circuit breaker opens, i.e., a few hours
+	// after the first failure with the backoff above
+	circuitBreakerThreshold = 10
+
+	// ResetAnnotationKey is the annotation of the Lumigo instance that resets the backoff and the circuit breaker of
+	// the failed injections, e.g., once the admission policy that blocked them has been fixed: either `*` for all
+	// of them, or a comma-separated list of `<kind>/<name>` resources, like `Deployment/my-app`
+	ResetAnnotationKey = "operator.lumigo.io/reset-injection-backoff"
+	resetAllValue      = "*"
+)
+
+// RecordInjectionFailure adds the resource to the failed injections of the Lumigo instance, or updates
+// its failure if the resource is already listed, and schedules the next retry of its injection. It returns
+// whether this failure has opened the circuit breaker of the resource, which is then no longer retried.
+func RecordInjectionFailure(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference, err error, now metav1.Time) bool {
+	status := &lumigo.Status
+	index := getInjectionFailureIndex(status, &resource)
+	if index < 0 {
+		status.FailedInjections = append(status.FailedInjections, operatorv1alpha1.InjectionFailure{
+			Resource: resource,
+		})
+		index = len(status.FailedInjections) - 1
+	}
+
+	failure := &status.FailedInjections[index]
+	failure.Resource = resource
+	failure.Reason = err.Error()
+	failure.Attempts++
+	failure.LastAttemptTime = now
+	failure.NextRetryTime = metav1.NewTime(now.Add(RetryDelay(failure.Attempts)))
+
+	if failure.CircuitBreaker == operatorv1alpha1.CircuitBreakerStateOpen {
+		return false
+	}
+
+	if failure.Attempts < circuitBreakerThreshold {
+		failure.CircuitBreaker = operatorv1alpha1.CircuitBreakerStateClosed
+		return false
+	}
+
+	failure.CircuitBreaker = operatorv1alpha1.CircuitBreakerStateOpen
+	return true
+}
+
+// ClearInjectionFailure removes the resource from the failed injections of the Lumigo instance,
+// returning whether it was listed.
+func ClearInjectionFailure(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference) bool {
+	status := &lumigo.Status
+	index := getInjectionFailureIndex(status, &resource)
+	if index < 0 {
+		return false
+	}
+
+	status.FailedInjections = append(status.FailedInjections[:index], status.FailedInjections[index+1:]...)
+	if len(status.FailedInjections) < 1 {
+		status.FailedInjections = nil
+	}
+
+	return true
+}
+
+// ClearAllInjectionFailures removes all the failed injections of the Lumigo instance.
+func ClearAllInjectionFailures(lumigo *operatorv1alpha1.Lumigo) {
+	lumigo.Status.FailedInjections = nil
+}
+
+// GetInjectionFailuresDueForRetry returns the resources whose injection is due to be retried.
+func GetInjectionFailuresDueForRetry(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) []corev1.ObjectReference {
+	resources := []corev1.ObjectReference{}
+	for _, failure := range lumigo.Status.FailedInjections {
+		if failure.CircuitBreaker != operatorv1alpha1.CircuitBreakerStateOpen && !now.Before(&failure.NextRetryTime) {
+			resources = append(resources, failure.Resource)
+		}
+	}
+
+	return resources
+}
+
+// IsCircuitBreakerOpen returns whether the injection of the resource has failed too many times in a row to be
+// attempted again until its circuit breaker is reset.
+func IsCircuitBreakerOpen(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference) bool {
+	index := getInjectionFailureIndex(&lumigo.Status, &resource)
+	return index >= 0 && lumigo.Status.FailedInjections[index].CircuitBreaker == operatorv1alpha1.CircuitBreakerStateOpen
+}
+
+// ResetInjectionFailures closes the circuit breaker and resets the backoff of the failed injections selected by
+// the value of the ResetAnnotationKey annotation, so that they are retried right away, and returns their resources.
+func ResetInjectionFailures(lumigo *operatorv1alpha1.Lumigo, value string, now metav1.Time) []corev1.ObjectReference {
+	isSelected := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		isSelected[strings.ToLower(strings.TrimSpace(entry))] = true
+	}
+
+	resources := []corev1.ObjectReference{}
+	for i := range lumigo.Status.FailedInjections {
+		failure := &lumigo.Status.FailedInjections[i]
+		if !isSelected[resetAllValue] && !isSelected[strings.ToLower(failure.Resource.Kind+"/"+failure.Resource.Name)] {
+			continue
+		}
+
+		failure.Attempts = 0
+		failure.NextRetryTime = now
+		failure.CircuitBreaker = operatorv1alpha1.CircuitBreakerStateClosed
+		resources = append(resources, failure.Resource)
+	}
+
+	return resources
+}
+
+// ResetRequestedInjectionFailures resets the failed injections selected by the ResetAnnotationKey annotation of the
+// Lumigo instance, if any, and returns their resources. The reset status is saved before the annotation is removed,
+// so that the reset is requested again by the next reconciliation if saving the status fails.
+func ResetRequestedInjectionFailures(ctx context.Context, c client.Client, lumigo *operatorv1alpha1.Lumigo, now metav1.Time) ([]corev1.ObjectReference, error) {
+	value, isSet := lumigo.Annotations[ResetAnnotationKey]
+	if !isSet {
+		return nil, nil
+	}
+
+	resources := ResetInjectionFailures(lumigo, value, now)
+	if err := c.Status().Update(ctx, lumigo); err != nil {
+		return nil, fmt.Errorf("cannot save the reset of the failed injections: %w", err)
+	}
+
+	delete(lumigo.Annotations, ResetAnnotationKey)
+	if err := c.Update(ctx, lumigo); err != nil {
+		return nil, fmt.Errorf("cannot remove the '%s' annotation: %w", ResetAnnotationKey, err)
+	}
+
+	return resources, nil
+}
+
+// RetryDelay returns how long to wait before retrying an injection that has failed the given amount of times in a row.
+func RetryDelay(attempts int32) time.Duration {
+	delay := initialRetryDelay
+	for i := int32(1); i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+
+	return delay
+}
+
+func getInjectionFailureIndex(status *operatorv1alpha1.LumigoStatus, resource *corev1.ObjectReference) int {
+	for i, failure := range status.FailedInjections {
+		if isSameResource(&failure.Resource, resource) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// The UIDs and resource versions are not compared, as the references are created from different copies of the resources
+func isSameResource(a *corev1.ObjectReference, b *corev1.ObjectReference) bool {
+	return a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
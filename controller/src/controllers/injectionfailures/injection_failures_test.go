@@ -0,0 +1,215 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injectionfailures
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// failingStatusClient fails all the updates of the status of the objects
+type failingStatusClient struct {
+	client.Client
+}
+
+func (c *failingStatusClient) Status() client.StatusWriter {
+	return &failingStatusWriter{c.Client.Status()}
+}
+
+type failingStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *failingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	return fmt.Errorf("the server is currently unable to handle the request")
+}
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injection Failures Suite")
+}
+
+var _ = Context("Injection failures", func() {
+
+	var lumigo *operatorv1alpha1.Lumigo
+	var now metav1.Time
+
+	deployment := corev1.ObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Namespace:  "my-namespace",
+		Name:       "my-deployment",
+	}
+
+	BeforeEach(func() {
+		lumigo = &operatorv1alpha1.Lumigo{}
+		now = metav1.NewTime(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	})
+
+	It("records the failures with an exponential backoff", func() {
+		RecordInjectionFailure(lumigo, deployment, fmt.Errorf("admission webhook denied the request"), now)
+
+		Expect(lumigo.Status.FailedInjections).To(HaveLen(1))
+		failure := lumigo.Status.FailedInjections[0]
+		Expect(failure.Resource).To(Equal(deployment))
+		Expect(failure.Reason).To(Equal("admission webhook denied the request"))
+		Expect(failure.Attempts).To(Equal(int32(1)))
+		Expect(failure.LastAttemptTime).To(Equal(now))
+		Expect(failure.NextRetryTime.Time).To(Equal(now.Add(30 * time.Second)))
+
+		later := metav1.NewTime(now.Add(time.Minute))
+		RecordInjectionFailure(lumigo, deployment, fmt.Errorf("quota exceeded"), later)
+
+		Expect(lumigo.Status.FailedInjections).To(HaveLen(1))
+		failure = lumigo.Status.FailedInjections[0]
+		Expect(failure.Reason).To(Equal("quota exceeded"))
+		Expect(failure.Attempts).To(Equal(int32(2)))
+		Expect(failure.NextRetryTime.Time).To(Equal(later.Add(time.Minute)))
+	})
+
+	It("caps the delay between retries", func() {
+		Expect(RetryDelay(1)).To(Equal(30 * time.Second))
+		Expect(RetryDelay(3)).To(Equal(2 * time.Minute))
+		Expect(RetryDelay(8)).To(Equal(time.Hour))
+		Expect(RetryDelay(1000)).To(Equal(time.Hour))
+	})
+
+	It("returns the failures that are due for retry", func() {
+		RecordInjectionFailure(lumigo, deployment, fmt.Errorf("boom"), now)
+
+		Expect(GetInjectionFailuresDueForRetry(lumigo, now)).To(BeEmpty())
+		Expect(GetInjectionFailuresDueForRetry(lumigo, metav1.NewTime(now.Add(30*time.Second)))).To(ConsistOf(deployment))
+	})
+
+	It("opens the circuit breaker of the resources that keep failing", func() {
+		for i := 1; i < circuitBreakerThreshold; i++ {
+			Expect(RecordInjectionFailure(lumigo, deployment, fmt.Errorf("admission webhook denied the request"), now)).To(BeFalse())
+		}
+		Expect(lumigo.Status.FailedInjections[0].CircuitBreaker).To(Equal(operatorv1alpha1.CircuitBreakerStateClosed))
+		Expect(IsCircuitBreakerOpen(lumigo, deployment)).To(BeFalse())
+
+		Expect(RecordInjectionFailure(lumigo, deployment, fmt.Errorf("admission webhook denied the request"), now)).To(BeTrue())
+		Expect(lumigo.Status.FailedInjections[0].CircuitBreaker).To(Equal(operatorv1alpha1.CircuitBreakerStateOpen))
+		Expect(IsCircuitBreakerOpen(lumigo, deployment)).To(BeTrue())
+		Expect(GetInjectionFailuresDueForRetry(lumigo, metav1.NewTime(now.Add(24*time.Hour)))).To(BeEmpty())
+
+		// The opening is reported only once
+		Expect(RecordInjectionFailure(lumigo, deployment, fmt.Errorf("admission webhook denied the request"), now)).To(BeFalse())
+		Expect(IsCircuitBreakerOpen(lumigo, deployment)).To(BeTrue())
+	})
+
+	It("resets the circuit breakers on request", func() {
+		statefulSet := corev1.ObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "StatefulSet",
+			Namespace:  "my-namespace",
+			Name:       "my-db",
+		}
+		for i := 0; i < circuitBreakerThreshold; i++ {
+			RecordInjectionFailure(lumigo, deployment, fmt.Errorf("boom"), now)
+			RecordInjectionFailure(lumigo, statefulSet, fmt.Errorf("boom"), now)
+		}
+
+		later := metav1.NewTime(now.Add(time.Minute))
+		Expect(ResetInjectionFailures(lumigo, "deployment/my-deployment, StatefulSet/other-db", later)).To(ConsistOf(deployment))
+		Expect(IsCircuitBreakerOpen(lumigo, deployment)).To(BeFalse())
+		Expect(IsCircuitBreakerOpen(lumigo, statefulSet)).To(BeTrue())
+		Expect(GetInjectionFailuresDueForRetry(lumigo, later)).To(ConsistOf(deployment))
+
+		// The backoff starts over
+		RecordInjectionFailure(lumigo, deployment, fmt.Errorf("boom"), later)
+		Expect(lumigo.Status.FailedInjections[0].Attempts).To(Equal(int32(1)))
+		Expect(lumigo.Status.FailedInjections[0].NextRetryTime.Time).To(Equal(later.Add(30 * time.Second)))
+
+		Expect(ResetInjectionFailures(lumigo, "*", later)).To(ConsistOf(deployment, statefulSet))
+		Expect(IsCircuitBreakerOpen(lumigo, statefulSet)).To(BeFalse())
+	})
+
+	Context("on request of the reset annotation", func() {
+
+		var c client.Client
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			lumigo = &operatorv1alpha1.Lumigo{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "my-namespace",
+					Name:        "lumigo",
+					Annotations: map[string]string{ResetAnnotationKey: "*"},
+				},
+			}
+			for i := 0; i < circuitBreakerThreshold; i++ {
+				RecordInjectionFailure(lumigo, deployment, fmt.Errorf("boom"), now)
+			}
+			c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(lumigo).Build()
+		})
+
+		It("saves the reset, then removes the annotation", func() {
+			Expect(ResetRequestedInjectionFailures(context.TODO(), c, lumigo, now)).To(ConsistOf(deployment))
+
+			saved := &operatorv1alpha1.Lumigo{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(lumigo), saved)).To(Succeed())
+			Expect(saved.Annotations).NotTo(HaveKey(ResetAnnotationKey))
+			Expect(IsCircuitBreakerOpen(saved, deployment)).To(BeFalse())
+			Expect(saved.Status.FailedInjections[0].Attempts).To(BeZero())
+		})
+
+		It("keeps the annotation if the reset cannot be saved", func() {
+			_, err := ResetRequestedInjectionFailures(context.TODO(), &failingStatusClient{c}, lumigo, now)
+			Expect(err).To(HaveOccurred())
+
+			saved := &operatorv1alpha1.Lumigo{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(lumigo), saved)).To(Succeed())
+			Expect(saved.Annotations).To(HaveKeyWithValue(ResetAnnotationKey, "*"))
+			Expect(IsCircuitBreakerOpen(saved, deployment)).To(BeTrue())
+		})
+
+		It("does nothing without the annotation", func() {
+			delete(lumigo.Annotations, ResetAnnotationKey)
+
+			Expect(ResetRequestedInjectionFailures(context.TODO(), &failingStatusClient{c}, lumigo, now)).To(BeEmpty())
+			Expect(IsCircuitBreakerOpen(lumigo, deployment)).To(BeTrue())
+		})
+
+	})
+
+	It("clears the failures", func() {
+		RecordInjectionFailure(lumigo, deployment, fmt.Errorf("boom"), now)
+
+		resource := deployment
+		resource.ResourceVersion = "42"
+		Expect(ClearInjectionFailure(lumigo, resource)).To(BeTrue())
+		Expect(lumigo.Status.FailedInjections).To(BeNil())
+
+		Expect(ClearInjectionFailure(lumigo, deployment)).To(BeFalse())
+	})
+})
@@ -0,0 +1,125 @@
+package injectiongc
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// Action is how the garbage collection fixes the injection artifacts of a resource
+type Action string
+
+const (
+	// ActionNone leaves the resource as it is
+	ActionNone Action = ""
+	// ActionCompleted injects the resource again, which adds the artifacts that are missing
+	ActionCompleted Action = "completed"
+	// ActionRemoved removes all the artifacts from the resource
+	ActionRemoved Action = "removed"
+
+	DefaultInterval = 30 * time.Minute
+)
+
+var (
+	// The resources whose partial or orphaned injection artifacts have been fixed, by namespace, kind and action
+	injectionArtifactsCollectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "lumigo_operator_injection_artifacts_collected_total",
+			Help: "Resources with partial or orphaned injection artifacts that the Lumigo operator has completed or removed",
+		},
+		[]string{"namespace", "kind", "action"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(injectionArtifactsCollectedTotal)
+}
+
+// Record counts the resource as fixed with the given action
+func Record(namespace string, kind string, action Action) {
+	if action == ActionNone {
+		return
+	}
+
+	injectionArtifactsCollectedTotal.WithLabelValues(namespace, kind, string(action)).Inc()
+}
+
+// Decide returns how to fix the injection artifacts of a resource, given the Lumigo instance of its namespace, if any,
+// and whether the removal of the injection from the resources of the namespace is pending in the background:
+//   - the partial artifacts in namespaces without Lumigo instance are orphaned, and removed; the complete injections
+//     are left alone, as they are kept on purpose when the Lumigo instance is deleted with
+//     `removeLumigoFromResourcesOnDeletion: false`, or while it is inactive or paused;
+//   - the partial artifacts in namespaces whose Lumigo instance injects the resources are completed;
+//   - the partial artifacts in namespaces whose Lumigo instance does not inject the resources are removed.
+//
+// The resources of paused Lumigo instances, and of the ones being deleted, are left alone.
+func Decide(artifacts *mutation.InjectionArtifacts, lumigo *operatorv1alpha1.Lumigo, hasPendingCleanup bool) Action {
+	if artifacts == nil {
+		return ActionNone
+	}
+
+	if lumigo == nil {
+		if hasPendingCleanup || !artifacts.IsPartial() {
+			return ActionNone
+		}
+		return ActionRemoved
+	}
+
+	if !lumigo.DeletionTimestamp.IsZero() || (lumigo.Spec.Paused != nil && *lumigo.Spec.Paused) || !artifacts.IsPartial() {
+		return ActionNone
+	}
+
+	if injectionEnabled := lumigo.Spec.Tracing.Injection.Enabled; injectionEnabled != nil && !*injectionEnabled {
+		return ActionRemoved
+	}
+
+	return ActionCompleted
+}
+
+// Collector runs the garbage collection of the injection artifacts periodically
+type Collector struct {
+	collect  func(context.Context) error
+	interval time.Duration
+	log      logr.Logger
+}
+
+// NewCollector creates a Collector that runs the given garbage collection pass at every interval
+func NewCollector(collect func(context.Context) error, interval time.Duration, log logr.Logger) *Collector {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Collector{
+		collect:  collect,
+		interval: interval,
+		log:      log,
+	}
+}
+
+// Start runs the garbage collection periodically until the context is done, which makes the collector a manager.Runnable.
+func (c *Collector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.collect(ctx); err != nil {
+				c.log.Error(err, "Cannot collect the partial and orphaned injection artifacts")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns true, as only one replica of the operator mutates the resources.
+func (c *Collector) NeedLeaderElection() bool {
+	return true
+}
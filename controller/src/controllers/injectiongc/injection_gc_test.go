@@ -0,0 +1,136 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injectiongc
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injection GC Suite")
+}
+
+var _ = Context("Injection GC", func() {
+
+	newDeployment := func(hasLabel bool, hasInitContainer bool, hasVolume bool, hasMount bool, hasPreload bool) *appsv1.Deployment {
+		deployment := &appsv1.Deployment{}
+		podTemplateSpec := &deployment.Spec.Template
+		if hasLabel {
+			podTemplateSpec.Labels = map[string]string{mutation.LumigoAutoTraceLabelKey: mutation.LumigoAutoTraceLabelVersionPrefixValue + "1.0.0"}
+		}
+		if hasInitContainer {
+			podTemplateSpec.Spec.InitContainers = []corev1.Container{{Name: mutation.LumigoInjectorContainerName}}
+		}
+		if hasVolume {
+			podTemplateSpec.Spec.Volumes = []corev1.Volume{{Name: mutation.LumigoInjectorVolumeName}}
+		}
+		container := corev1.Container{Name: "app"}
+		if hasMount {
+			container.VolumeMounts = []corev1.VolumeMount{{Name: mutation.LumigoInjectorVolumeName, MountPath: mutation.LumigoInjectorVolumeMountPoint}}
+		}
+		if hasPreload {
+			container.Env = []corev1.EnvVar{{Name: mutation.LdPreloadEnvVarName, Value: mutation.LdPreloadEnvVarValue}}
+		}
+		podTemplateSpec.Spec.Containers = []corev1.Container{container}
+		return deployment
+	}
+
+	getArtifacts := func(resource interface{}) *mutation.InjectionArtifacts {
+		artifacts, err := mutation.GetInjectionArtifacts(resource)
+		Expect(err).NotTo(HaveOccurred())
+		return artifacts
+	}
+
+	newFalse := func() *bool {
+		b := false
+		return &b
+	}
+
+	newTrue := func() *bool {
+		b := true
+		return &b
+	}
+
+	It("tells the complete injections from the partial ones", func() {
+		Expect(getArtifacts(newDeployment(false, false, false, false, false)).IsPresent()).To(BeFalse())
+		Expect(getArtifacts(newDeployment(false, false, false, false, false)).IsPartial()).To(BeFalse())
+		Expect(getArtifacts(newDeployment(true, true, true, true, true)).IsPartial()).To(BeFalse())
+
+		// The volume without the env vars, and vice versa
+		Expect(getArtifacts(newDeployment(true, true, true, true, false)).IsPartial()).To(BeTrue())
+		Expect(getArtifacts(newDeployment(true, false, false, false, true)).IsPartial()).To(BeTrue())
+		// The changes of the pod template without the label, and vice versa
+		Expect(getArtifacts(newDeployment(false, true, true, true, true)).IsPartial()).To(BeTrue())
+		Expect(getArtifacts(newDeployment(true, false, false, false, false)).IsPartial()).To(BeTrue())
+	})
+
+	It("does not collect the resources whose pod template it cannot change", func() {
+		Expect(getArtifacts(&batchv1.Job{})).To(BeNil())
+		Expect(getArtifacts(&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-deployment"}},
+			},
+		})).To(BeNil())
+	})
+
+	It("removes the orphaned partial artifacts of namespaces without Lumigo instance", func() {
+		Expect(Decide(getArtifacts(newDeployment(false, true, true, false, false)), nil, false)).To(Equal(ActionRemoved))
+		// The background removal of the injection takes care of them
+		Expect(Decide(getArtifacts(newDeployment(false, true, true, false, false)), nil, true)).To(Equal(ActionNone))
+		Expect(Decide(getArtifacts(newDeployment(false, false, false, false, false)), nil, false)).To(Equal(ActionNone))
+	})
+
+	It("keeps the complete injections of namespaces without Lumigo instance", func() {
+		// E.g., kept with `removeLumigoFromResourcesOnDeletion: false`, or by an inactive or paused Lumigo instance that was deleted
+		Expect(Decide(getArtifacts(newDeployment(true, true, true, true, true)), nil, false)).To(Equal(ActionNone))
+	})
+
+	It("completes the partial artifacts of namespaces whose Lumigo instance injects the resources", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+
+		Expect(Decide(getArtifacts(newDeployment(true, true, true, true, false)), lumigo, false)).To(Equal(ActionCompleted))
+		Expect(Decide(getArtifacts(newDeployment(true, true, true, true, true)), lumigo, false)).To(Equal(ActionNone))
+
+		lumigo.Spec.Tracing.Injection.Enabled = newFalse()
+		Expect(Decide(getArtifacts(newDeployment(true, true, true, true, false)), lumigo, false)).To(Equal(ActionRemoved))
+
+		lumigo.Spec.Paused = newTrue()
+		Expect(Decide(getArtifacts(newDeployment(true, true, true, true, false)), lumigo, false)).To(Equal(ActionNone))
+	})
+
+	It("counts the fixed resources by action", func() {
+		Record("my-namespace", "Deployment", ActionCompleted)
+		Record("my-namespace", "Deployment", ActionNone)
+
+		Expect(testutil.ToFloat64(injectionArtifactsCollectedTotal.WithLabelValues("my-namespace", "Deployment", string(ActionCompleted)))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(injectionArtifactsCollectedTotal.WithLabelValues("my-namespace", "Deployment", string(ActionRemoved)))).To(Equal(0.0))
+	})
+
+})
@@ -0,0 +1,43 @@
+package injectionskips
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// Source is the component of the operator that skipped the injection of a resource
+type Source string
+
+const (
+	SourceController Source = "controller"
+	SourceWebhook    Source = "webhook"
+)
+
+var (
+	// The resources that have not been injected, by namespace, kind, why they have been skipped, and
+	// which component of the operator skipped them
+	injectionSkipsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "lumigo_operator_injection_skips_total",
+			Help: "Resources that the Lumigo operator has not injected, by reason",
+		},
+		[]string{"namespace", "kind", "reason", "source"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(injectionSkipsTotal)
+}
+
+// Record counts the resource as skipped if the error is a mutation.SkipInjectionError, and returns why
+// it has been skipped, or an empty SkipReason otherwise
+func Record(namespace string, kind string, source Source, err error) mutation.SkipReason {
+	skipReason := mutation.GetSkipReason(err)
+	if skipReason != "" {
+		injectionSkipsTotal.WithLabelValues(namespace, kind, string(skipReason), string(source)).Inc()
+	}
+
+	return skipReason
+}
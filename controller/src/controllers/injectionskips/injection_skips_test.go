@@ -0,0 +1,56 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injectionskips
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injection Skips Suite")
+}
+
+var _ = Context("Injection skips", func() {
+
+	It("counts the skipped resources by reason", func() {
+		err := fmt.Errorf("cannot inject: %w", &mutation.SkipInjectionError{Code: mutation.SkipReasonOptedOut, Reason: "opted out"})
+
+		Expect(Record("my-namespace", "Deployment", SourceWebhook, err)).To(Equal(mutation.SkipReasonOptedOut))
+		Expect(testutil.ToFloat64(injectionSkipsTotal.WithLabelValues("my-namespace", "Deployment", string(mutation.SkipReasonOptedOut), string(SourceWebhook)))).To(Equal(1.0))
+	})
+
+	It("counts the skips without a code as unspecified", func() {
+		err := &mutation.SkipInjectionError{Reason: "skipped"}
+
+		Expect(Record("my-namespace", "StatefulSet", SourceController, err)).To(Equal(mutation.SkipReasonUnspecified))
+		Expect(testutil.ToFloat64(injectionSkipsTotal.WithLabelValues("my-namespace", "StatefulSet", string(mutation.SkipReasonUnspecified), string(SourceController)))).To(Equal(1.0))
+	})
+
+	It("does not count the other errors", func() {
+		Expect(Record("my-namespace", "CronJob", SourceController, fmt.Errorf("boom"))).To(BeEmpty())
+		Expect(testutil.ToFloat64(injectionSkipsTotal.WithLabelValues("my-namespace", "CronJob", "", string(SourceController)))).To(Equal(0.0))
+	})
+})
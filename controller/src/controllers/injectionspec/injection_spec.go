@@ -0,0 +1,20 @@
+package injectionspec
+
+import (
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/namespacetags"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/otelinstrumentation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokensecrets"
+)
+
+// EffectiveSpec returns the spec the workloads of the namespace of the Lumigo instance are injected with, that is,
+// its spec with the env vars imported from the OpenTelemetry Instrumentation and the tags of the namespace added to
+// the extra env vars, and the Lumigo tokens referencing the token secret of the namespace; both the controller and
+// the injector webhook build their mutators out of it, so that they inject the workloads alike. The spec of the
+// Lumigo instance is not changed.
+func EffectiveSpec(lumigo *operatorv1alpha1.Lumigo) *operatorv1alpha1.LumigoSpec {
+	spec := otelinstrumentation.SpecWithImportedEnv(lumigo)
+	spec = namespacetags.SpecWithNamespaceTags(lumigo, spec)
+	spec = tokensecrets.SpecWithTokenValuesInSecret(spec)
+	return tokensecrets.SpecWithCachedToken(lumigo, spec)
+}
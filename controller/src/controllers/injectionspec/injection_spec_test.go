@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injectionspec
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/namespacetags"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injection Spec Suite")
+}
+
+var _ = Context("Injection spec", func() {
+
+	It("returns the spec of the Lumigo instance if there is nothing to add to it", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				LumigoToken: operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{Name: "lumigosecret", Key: "token"},
+				},
+			},
+		}
+
+		Expect(EffectiveSpec(lumigo)).To(BeIdenticalTo(&lumigo.Spec))
+	})
+
+	It("adds the namespace tags and references the token values in the token secret without changing the Lumigo instance", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				LumigoToken: operatorv1alpha1.Credentials{
+					Value: "t_123456789012345678901",
+				},
+			},
+			Status: operatorv1alpha1.LumigoStatus{
+				NamespaceTags: map[string]string{"team": "payments"},
+			},
+		}
+		original := lumigo.DeepCopy()
+
+		spec := EffectiveSpec(lumigo)
+
+		Expect(spec.LumigoToken).To(Equal(operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: mutation.LumigoTracerTokenSecretName,
+				Key:  mutation.LumigoTracerTokenSecretKey,
+			},
+		}))
+		Expect(spec.Tracing.Injection.ExtraEnv).To(ContainElement(corev1.EnvVar{
+			Name:  namespacetags.OtelResourceAttributesEnvVarName,
+			Value: "team=payments",
+		}))
+		Expect(lumigo).To(Equal(original))
+	})
+
+})
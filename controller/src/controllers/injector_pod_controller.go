@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectorruntimefailures"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// InjectorPodReconciler follows the pods injected with Lumigo, which carry the autotrace label of their pod template,
+// and records in the monitor the ones whose `lumigo-injector` init container fails; the LumigoReconciler reports
+// them in the InjectionRuntimeFailures condition of the Lumigo instance of their namespace.
+type InjectorPodReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Monitor *injectorruntimefailures.Monitor
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *InjectorPodReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("injector-pod").
+		For(&corev1.Pod{}, builder.WithPredicates(predicate.NewPredicateFuncs(hasLumigoAutotraceLabel))).
+		Complete(r)
+}
+
+// Reconcile records whether the `lumigo-injector` init container of the pod is failing.
+//
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+func (r *InjectorPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pod := &corev1.Pod{}
+	if err := r.Client.Get(ctx, req.NamespacedName, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Monitor.Forget(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		// Error reading the pod - requeue the request.
+		return ctrl.Result{
+			RequeueAfter: defaultErrRequeuePeriod,
+		}, nil
+	}
+
+	if !pod.DeletionTimestamp.IsZero() {
+		r.Monitor.Forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	if message, isFailing := injectorruntimefailures.GetInjectorFailure(pod); isFailing {
+		r.Log.V(1).Info("The Lumigo injector init container is failing", "namespace", pod.Namespace, "name", pod.Name, "failure", message)
+	}
+	r.Monitor.Update(pod)
+
+	return ctrl.Result{}, nil
+}
+
+func hasLumigoAutotraceLabel(obj client.Object) bool {
+	_, ok := obj.GetLabels()[mutation.LumigoAutoTraceLabelKey]
+	return ok
+}
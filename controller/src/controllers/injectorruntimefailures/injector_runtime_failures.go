@@ -0,0 +1,150 @@
+package injectorruntimefailures
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// How many failure messages are reported for each namespace, besides the count of the failing pods
+const MaxSamples = 3
+
+// The reasons for which the kubelet keeps a container waiting that mean it cannot start without a change
+// of the pod or of the node, e.g., `ImagePullBackOff` when the injector image cannot be pulled
+var failedWaitingReasons = []string{
+	"CrashLoopBackOff",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+	"ErrImagePull",
+	"ImagePullBackOff",
+	"InvalidImageName",
+	"RunContainerError",
+}
+
+// Monitor keeps track of the pods whose `lumigo-injector` init container fails, e.g., because its image cannot be
+// pulled or it cannot write to its volume, so that the failures are reported on the Lumigo instances of their namespace
+// rather than only in the events of the pods.
+type Monitor struct {
+	mutex sync.Mutex
+	// The failure message of each failing pod, by namespace and pod name
+	failures map[string]map[string]string
+}
+
+// NewMonitor creates a Monitor with no failing pods.
+func NewMonitor() *Monitor {
+	return &Monitor{
+		failures: make(map[string]map[string]string),
+	}
+}
+
+// Update records whether the `lumigo-injector` init container of the pod is failing; it is
+// meant to be called at every change of the injected pods.
+func (m *Monitor) Update(pod *corev1.Pod) {
+	message, isFailing := GetInjectorFailure(pod)
+	if !isFailing {
+		m.Forget(types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name})
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	namespaceFailures, isFound := m.failures[pod.Namespace]
+	if !isFound {
+		namespaceFailures = make(map[string]string)
+		m.failures[pod.Namespace] = namespaceFailures
+	}
+	namespaceFailures[pod.Name] = message
+}
+
+// Forget removes the pod from the failing ones, e.g., after it has been deleted.
+func (m *Monitor) Forget(pod types.NamespacedName) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	namespaceFailures, isFound := m.failures[pod.Namespace]
+	if !isFound {
+		return
+	}
+
+	delete(namespaceFailures, pod.Name)
+	if len(namespaceFailures) < 1 {
+		delete(m.failures, pod.Namespace)
+	}
+}
+
+// GetFailures returns how many pods of the namespace have a failing `lumigo-injector` init container, and
+// the failure messages of up to MaxSamples of them, sorted by pod name so that they are stable across calls.
+func (m *Monitor) GetFailures(namespaceName string) (int, []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	namespaceFailures := m.failures[namespaceName]
+	podNames := make([]string, 0, len(namespaceFailures))
+	for podName := range namespaceFailures {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+
+	samples := []string{}
+	for _, podName := range podNames {
+		if len(samples) >= MaxSamples {
+			break
+		}
+		samples = append(samples, namespaceFailures[podName])
+	}
+
+	return len(podNames), samples
+}
+
+// GetInjectorFailure returns whether the `lumigo-injector` init container of the pod is failing, with a message
+// describing the failure; the init containers that are still starting, or that have completed, are not failing.
+func GetInjectorFailure(pod *corev1.Pod) (string, bool) {
+	index := slices.IndexFunc(pod.Status.InitContainerStatuses, func(s corev1.ContainerStatus) bool { return s.Name == mutation.LumigoInjectorContainerName })
+	if index < 0 {
+		return "", false
+	}
+
+	status := &pod.Status.InitContainerStatuses[index]
+	if waiting := status.State.Waiting; waiting != nil && slices.Contains(failedWaitingReasons, waiting.Reason) {
+		// The restarts of a crashing container are only waiting, and the reason of the crash is in its last termination
+		if terminated := status.LastTerminationState.Terminated; waiting.Reason == "CrashLoopBackOff" && terminated != nil {
+			return newFailureMessage(pod, describeExit(terminated)), true
+		}
+
+		return newFailureMessage(pod, withDetails(fmt.Sprintf("cannot start: %s", waiting.Reason), waiting.Message)), true
+	}
+
+	if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+		return newFailureMessage(pod, describeExit(terminated)), true
+	}
+
+	return "", false
+}
+
+func describeExit(terminated *corev1.ContainerStateTerminated) string {
+	details := terminated.Message
+	if len(details) < 1 {
+		details = terminated.Reason
+	}
+
+	return withDetails(fmt.Sprintf("exited with code %d", terminated.ExitCode), details)
+}
+
+func withDetails(failure string, details string) string {
+	if len(details) < 1 {
+		return failure
+	}
+
+	return fmt.Sprintf("%s (%s)", failure, details)
+}
+
+func newFailureMessage(pod *corev1.Pod, failure string) string {
+	return fmt.Sprintf("the '%s' init container of pod '%s' %s", mutation.LumigoInjectorContainerName, pod.Name, failure)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injectorruntimefailures
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injector Runtime Failures Suite")
+}
+
+func newPod(namespaceName string, name string, status corev1.ContainerStatus) *corev1.Pod {
+	status.Name = mutation.LumigoInjectorContainerName
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      name,
+		},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{status},
+		},
+	}
+}
+
+func newImagePullBackOffPod(namespaceName string, name string) *corev1.Pod {
+	return newPod(namespaceName, name, corev1.ContainerStatus{
+		State: corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{
+				Reason:  "ImagePullBackOff",
+				Message: "Back-off pulling image",
+			},
+		},
+	})
+}
+
+var _ = Context("Injector runtime failures", func() {
+
+	It("reports the injector images that cannot be pulled", func() {
+		message, isFailing := GetInjectorFailure(newImagePullBackOffPod("my-namespace", "my-pod"))
+
+		Expect(isFailing).To(BeTrue())
+		Expect(message).To(Equal("the 'lumigo-injector' init container of pod 'my-pod' cannot start: ImagePullBackOff (Back-off pulling image)"))
+	})
+
+	It("reports the exit code of the crashing injectors", func() {
+		pod := newPod("my-namespace", "my-pod", corev1.ContainerStatus{
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason: "CrashLoopBackOff",
+				},
+			},
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Reason:   "Error",
+				},
+			},
+		})
+
+		message, isFailing := GetInjectorFailure(pod)
+
+		Expect(isFailing).To(BeTrue())
+		Expect(message).To(Equal("the 'lumigo-injector' init container of pod 'my-pod' exited with code 1 (Error)"))
+	})
+
+	It("does not report the injectors that are starting or have completed", func() {
+		_, isFailing := GetInjectorFailure(newPod("my-namespace", "my-pod", corev1.ContainerStatus{
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason: "PodInitializing",
+				},
+			},
+		}))
+		Expect(isFailing).To(BeFalse())
+
+		_, isFailing = GetInjectorFailure(newPod("my-namespace", "my-pod", corev1.ContainerStatus{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 0,
+					Reason:   "Completed",
+				},
+			},
+		}))
+		Expect(isFailing).To(BeFalse())
+
+		_, isFailing = GetInjectorFailure(&corev1.Pod{})
+		Expect(isFailing).To(BeFalse())
+	})
+
+	It("counts the failing pods by namespace with a limited number of samples", func() {
+		monitor := NewMonitor()
+		for i := 4; i >= 0; i-- {
+			monitor.Update(newImagePullBackOffPod("my-namespace", fmt.Sprintf("my-pod-%d", i)))
+		}
+		monitor.Update(newImagePullBackOffPod("other-namespace", "other-pod"))
+
+		count, samples := monitor.GetFailures("my-namespace")
+		Expect(count).To(Equal(5))
+		Expect(samples).To(HaveLen(MaxSamples))
+		Expect(samples[0]).To(ContainSubstring("'my-pod-0'"))
+		Expect(samples[2]).To(ContainSubstring("'my-pod-2'"))
+
+		count, samples = monitor.GetFailures("empty-namespace")
+		Expect(count).To(Equal(0))
+		Expect(samples).To(BeEmpty())
+	})
+
+	It("forgets the pods that are deleted or no longer failing", func() {
+		monitor := NewMonitor()
+		monitor.Update(newImagePullBackOffPod("my-namespace", "my-pod-1"))
+		monitor.Update(newImagePullBackOffPod("my-namespace", "my-pod-2"))
+
+		monitor.Forget(types.NamespacedName{Namespace: "my-namespace", Name: "my-pod-1"})
+		count, _ := monitor.GetFailures("my-namespace")
+		Expect(count).To(Equal(1))
+
+		monitor.Update(newPod("my-namespace", "my-pod-2", corev1.ContainerStatus{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 0,
+				},
+			},
+		}))
+		count, _ = monitor.GetFailures("my-namespace")
+		Expect(count).To(Equal(0))
+	})
+
+})
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/instrumentednamespaces"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokendistribution"
+)
+
+// InstrumentedNamespacesReconciler creates, updates and deletes the Lumigo instances of the `instrumentedNamespaces`
+// of the Helm values, so that the namespaces to instrument are managed from the values file, while each of them still
+// has its own Lumigo instance, with its own status. As the operator reconciles every namespace on startup, removing a
+// namespace from the values deletes its Lumigo instance once the controller manager is rolled out with the new values.
+type InstrumentedNamespacesReconciler struct {
+	client.Client
+	Log                    logr.Logger
+	InstrumentedNamespaces []instrumentednamespaces.InstrumentedNamespace
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *InstrumentedNamespacesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("instrumentednamespaces").
+		For(&corev1.Namespace{}).
+		// Restore the Lumigo instances of the values if they are deleted or changed
+		Watches(&source.Kind{Type: &operatorv1alpha1.Lumigo{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfInstrumented)).
+		Complete(r)
+}
+
+// Reconcile ensures that the namespace has the Lumigo instance of its entry in the `instrumentedNamespaces` of the
+// Helm values, if any, and that it has none created from the values otherwise.
+//
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.lumigo.io,resources=lumigoes,verbs=get;list;watch;create;update;delete
+func (r *InstrumentedNamespacesReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespace", req.NamespacedName.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, req.NamespacedName, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The Lumigo instances are deleted together with their namespace
+			return ctrl.Result{}, nil
+		}
+		// Error reading the namespace - requeue the request.
+		return ctrl.Result{
+			RequeueAfter: defaultErrRequeuePeriod,
+		}, nil
+	}
+
+	if !namespace.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	lumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(ctx, lumigoes, client.InNamespace(namespace.Name)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("cannot list the Lumigo instances in namespace '%s': %w", namespace.Name, err)
+	}
+
+	instrumentedNamespace := r.getInstrumentedNamespace(namespace.Name)
+	if instrumentedNamespace == nil {
+		return ctrl.Result{}, r.removeInstrumentedNamespaceLumigoes(ctx, lumigoes, &log)
+	}
+
+	desiredLumigo := instrumentedNamespace.NewLumigo()
+
+	for i := range lumigoes.Items {
+		lumigo := &lumigoes.Items[i]
+		if !lumigo.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if !isInstrumentedNamespaceLumigo(lumigo) {
+			// The namespace already has a Lumigo instance created by users or by the auto-instrumentation of
+			// namespaces, which we leave alone
+			log.Info("Namespace listed in the instrumented namespaces already has a Lumigo instance not created from them", "name", lumigo.Name)
+			return ctrl.Result{}, nil
+		}
+
+		if lumigo.Annotations[instrumentednamespaces.SpecHashAnnotationKey] == desiredLumigo.Annotations[instrumentednamespaces.SpecHashAnnotationKey] {
+			return ctrl.Result{}, nil
+		}
+
+		lumigo.Spec = desiredLumigo.Spec
+		if lumigo.Annotations == nil {
+			lumigo.Annotations = map[string]string{}
+		}
+		lumigo.Annotations[instrumentednamespaces.SpecHashAnnotationKey] = desiredLumigo.Annotations[instrumentednamespaces.SpecHashAnnotationKey]
+
+		if err := r.Client.Update(ctx, lumigo); err != nil {
+			if apierrors.IsConflict(err) {
+				return ctrl.Result{Requeue: true}, nil
+			}
+
+			return ctrl.Result{}, fmt.Errorf("cannot update the Lumigo instance '%s/%s': %w", lumigo.Namespace, lumigo.Name, err)
+		}
+
+		log.Info("Updated Lumigo instance of the instrumented namespace", "name", lumigo.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if len(lumigoes.Items) > 0 {
+		// Wait for the Lumigo instances being deleted to be gone
+		return ctrl.Result{
+			RequeueAfter: defaultRequeuePeriod,
+		}, nil
+	}
+
+	if err := r.Client.Create(ctx, desiredLumigo); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("cannot create the Lumigo instance in namespace '%s': %w", namespace.Name, err)
+	}
+
+	log.Info("Created Lumigo instance in the instrumented namespace", "name", desiredLumigo.Name)
+	return ctrl.Result{}, nil
+}
+
+func (r *InstrumentedNamespacesReconciler) getInstrumentedNamespace(namespaceName string) *instrumentednamespaces.InstrumentedNamespace {
+	for i := range r.InstrumentedNamespaces {
+		if r.InstrumentedNamespaces[i].Name == namespaceName {
+			return &r.InstrumentedNamespaces[i]
+		}
+	}
+
+	return nil
+}
+
+// Deletes the Lumigo instances created from the values, after their namespace has been removed from them
+func (r *InstrumentedNamespacesReconciler) removeInstrumentedNamespaceLumigoes(ctx context.Context, lumigoes *operatorv1alpha1.LumigoList, log *logr.Logger) error {
+	for i := range lumigoes.Items {
+		lumigo := &lumigoes.Items[i]
+		if !isInstrumentedNamespaceLumigo(lumigo) || !lumigo.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, lumigo); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return fmt.Errorf("cannot delete the Lumigo instance '%s/%s': %w", lumigo.Namespace, lumigo.Name, err)
+		}
+
+		log.Info("Deleted Lumigo instance, as the namespace is no longer an instrumented namespace", "name", lumigo.Name)
+	}
+
+	return nil
+}
+
+// Like for the auto-instrumentation of namespaces, the copies that HNC propagates into the descendant namespaces
+// belong to HNC
+func isInstrumentedNamespaceLumigo(lumigo *operatorv1alpha1.Lumigo) bool {
+	return instrumentednamespaces.IsInstrumentedNamespaceLumigo(lumigo) && len(tokendistribution.GetInheritedFrom(lumigo)) < 1
+}
+
+func enqueueNamespaceIfInstrumented(obj client.Object) []reconcile.Request {
+	if obj.GetLabels()[instrumentednamespaces.InstrumentedNamespaceLabelKey] != instrumentednamespaces.InstrumentedNamespaceLabelValue {
+		return []reconcile.Request{}
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instrumentednamespaces
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	// InstrumentedNamespaceLabelKey labels the Lumigo instances created from the `instrumentedNamespaces` of the
+	// Helm values, which tells them apart from the ones created by users or by the auto-instrumentation of namespaces
+	InstrumentedNamespaceLabelKey   = "lumigo.io/instrumented-namespace"
+	InstrumentedNamespaceLabelValue = "true"
+
+	// SpecHashAnnotationKey holds the hash of the spec that the Lumigo instance has been created or last updated
+	// with, so that the spec is updated only when the Helm values change, rather than every time the defaulting
+	// webhook fills in the settings that the values leave out
+	SpecHashAnnotationKey = "lumigo.io/instrumented-namespace-spec-hash"
+
+	lumigoName            = "lumigo"
+	lumigoTokenSecretName = "lumigo-credentials"
+	lumigoTokenSecretKey  = "token"
+)
+
+// InstrumentedNamespace is an entry of the `instrumentedNamespaces` of the Helm values
+type InstrumentedNamespace struct {
+	// The name of the namespace to instrument
+	Name string `json:"name"`
+	// The spec of the Lumigo instance of the namespace; the Lumigo token defaults to the copy of the central token secret
+	Spec operatorv1alpha1.LumigoSpec `json:"spec,omitempty"`
+}
+
+// Parse reads the JSON list of the `instrumentedNamespaces` of the Helm values
+func Parse(value string) ([]InstrumentedNamespace, error) {
+	if len(strings.TrimSpace(value)) < 1 {
+		return []InstrumentedNamespace{}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(value))
+	decoder.DisallowUnknownFields()
+
+	instrumentedNamespaces := []InstrumentedNamespace{}
+	if err := decoder.Decode(&instrumentedNamespaces); err != nil {
+		return nil, fmt.Errorf("cannot parse the instrumented namespaces: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, instrumentedNamespace := range instrumentedNamespaces {
+		if errs := validation.IsDNS1123Label(instrumentedNamespace.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid instrumented namespace name '%s': %s", instrumentedNamespace.Name, strings.Join(errs, ", "))
+		}
+
+		if names[instrumentedNamespace.Name] {
+			return nil, fmt.Errorf("the namespace '%s' is listed more than once", instrumentedNamespace.Name)
+		}
+		names[instrumentedNamespace.Name] = true
+	}
+
+	return instrumentedNamespaces, nil
+}
+
+// HasToken returns whether the entry references its own Lumigo token secret, rather than the copy of the central one
+func (n *InstrumentedNamespace) HasToken() bool {
+	return len(n.Spec.LumigoToken.SecretRef.Name) > 0
+}
+
+// NewLumigo returns the Lumigo instance of the instrumented namespace
+func (n *InstrumentedNamespace) NewLumigo() *operatorv1alpha1.Lumigo {
+	spec := *n.Spec.DeepCopy()
+	if !n.HasToken() {
+		// The LumigoReconciler copies the central token secret into the namespace
+		spec.LumigoToken = operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: lumigoTokenSecretName,
+				Key:  lumigoTokenSecretKey,
+			},
+		}
+	}
+
+	return &operatorv1alpha1.Lumigo{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: n.Name,
+			Name:      lumigoName,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":    "lumigo",
+				"app.kubernetes.io/managed-by": "lumigo-operator",
+				InstrumentedNamespaceLabelKey:  InstrumentedNamespaceLabelValue,
+			},
+			Annotations: map[string]string{
+				SpecHashAnnotationKey: hashSpec(&spec),
+			},
+		},
+		Spec: spec,
+	}
+}
+
+// IsInstrumentedNamespaceLumigo returns whether the Lumigo instance has been created from the `instrumentedNamespaces`
+// of the Helm values
+func IsInstrumentedNamespaceLumigo(lumigo *operatorv1alpha1.Lumigo) bool {
+	return lumigo.Labels[InstrumentedNamespaceLabelKey] == InstrumentedNamespaceLabelValue
+}
+
+func hashSpec(spec *operatorv1alpha1.LumigoSpec) string {
+	// The spec is a plain struct, which always marshals
+	specBytes, _ := json.Marshal(spec)
+	checksum := sha256.Sum256(specBytes)
+	return hex.EncodeToString(checksum[:])
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instrumentednamespaces
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Instrumented Namespaces Suite")
+}
+
+var _ = Context("Instrumented namespaces", func() {
+
+	It("parses an empty list", func() {
+		instrumentedNamespaces, err := Parse("")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instrumentedNamespaces).To(BeEmpty())
+	})
+
+	It("parses the namespaces with their specs", func() {
+		instrumentedNamespaces, err := Parse(`[{"name":"payments","spec":{"logging":{"enabled":true}}},{"name":"orders"}]`)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instrumentedNamespaces).To(HaveLen(2))
+		Expect(instrumentedNamespaces[0].Name).To(Equal("payments"))
+		Expect(*instrumentedNamespaces[0].Spec.Logging.Enabled).To(BeTrue())
+		Expect(instrumentedNamespaces[1].Name).To(Equal("orders"))
+	})
+
+	It("rejects invalid namespace names", func() {
+		_, err := Parse(`[{"name":"Payments"}]`)
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects namespaces listed more than once", func() {
+		_, err := Parse(`[{"name":"payments"},{"name":"payments"}]`)
+
+		Expect(err).To(MatchError(ContainSubstring("listed more than once")))
+	})
+
+	It("rejects unknown fields", func() {
+		_, err := Parse(`[{"name":"payments","spec":{"tracin":{}}}]`)
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("defaults the Lumigo token to the copy of the central token secret", func() {
+		lumigo := (&InstrumentedNamespace{Name: "payments"}).NewLumigo()
+
+		Expect(lumigo.Namespace).To(Equal("payments"))
+		Expect(lumigo.Spec.LumigoToken.SecretRef.Name).To(Equal("lumigo-credentials"))
+		Expect(lumigo.Spec.LumigoToken.SecretRef.Key).To(Equal("token"))
+		Expect(IsInstrumentedNamespaceLumigo(lumigo)).To(BeTrue())
+	})
+
+	It("changes the spec hash only with the spec", func() {
+		instrumentedNamespaces, err := Parse(`[{"name":"payments"},{"name":"orders"},{"name":"billing","spec":{"logging":{"enabled":true}}}]`)
+		Expect(err).NotTo(HaveOccurred())
+
+		payments := instrumentedNamespaces[0].NewLumigo()
+		orders := instrumentedNamespaces[1].NewLumigo()
+		billing := instrumentedNamespaces[2].NewLumigo()
+
+		Expect(payments.Annotations[SpecHashAnnotationKey]).To(Equal(orders.Annotations[SpecHashAnnotationKey]))
+		Expect(payments.Annotations[SpecHashAnnotationKey]).NotTo(Equal(billing.Annotations[SpecHashAnnotationKey]))
+	})
+
+})
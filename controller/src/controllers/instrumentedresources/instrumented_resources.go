@@ -0,0 +1,113 @@
+package instrumentedresources
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// The kinds of the instrumented resources, in the order they are reported in the status of the Lumigo instances
+var kindOrder = []string{"DaemonSet", "Deployment", "ReplicaSet", "StatefulSet", "CronJob", "Job"}
+
+// Index keeps track of the instrumented resources of each namespace, i.e., the ones with the `lumigo.auto-trace`
+// label not set to `false`, from the changes of the watched resources, so that the Lumigo instances report their
+// instrumented resources without listing all the resources of their namespace at every reconciliation.
+type Index struct {
+	mutex sync.Mutex
+	// The references of the instrumented resources, by namespace and UID
+	references map[string]map[types.UID]corev1.ObjectReference
+}
+
+// NewIndex creates an Index with no instrumented resources.
+func NewIndex() *Index {
+	return &Index{
+		references: make(map[string]map[types.UID]corev1.ObjectReference),
+	}
+}
+
+// IsInstrumented returns whether the resource has the `lumigo.auto-trace` label not set to `false`.
+func IsInstrumented(obj client.Object) bool {
+	value, ok := obj.GetLabels()[mutation.LumigoAutoTraceLabelKey]
+	return ok && value != "false"
+}
+
+// Update records whether the resource is instrumented, and returns whether the instrumented resources of its
+// namespace changed; it is meant to be called at every change of the resources. The references do not have the
+// resource versions, so that the changes of the instrumented resources that do not add or remove any leave them
+// unchanged.
+func (i *Index) Update(obj client.Object) (bool, error) {
+	if !IsInstrumented(obj) {
+		return i.Forget(obj), nil
+	}
+
+	objectReference, err := reference.GetReference(scheme.Scheme, obj)
+	if err != nil {
+		return false, err
+	}
+	objectReference.ResourceVersion = ""
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	namespaceReferences, isFound := i.references[obj.GetNamespace()]
+	if !isFound {
+		namespaceReferences = make(map[types.UID]corev1.ObjectReference)
+		i.references[obj.GetNamespace()] = namespaceReferences
+	}
+
+	if existingReference, isFound := namespaceReferences[obj.GetUID()]; isFound && existingReference == *objectReference {
+		return false, nil
+	}
+
+	namespaceReferences[obj.GetUID()] = *objectReference
+	return true, nil
+}
+
+// Forget removes the resource from the instrumented ones, e.g., after it has been deleted, and returns whether the
+// instrumented resources of its namespace changed.
+func (i *Index) Forget(obj client.Object) bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	namespaceReferences := i.references[obj.GetNamespace()]
+	if _, isFound := namespaceReferences[obj.GetUID()]; !isFound {
+		return false
+	}
+
+	delete(namespaceReferences, obj.GetUID())
+	if len(namespaceReferences) < 1 {
+		delete(i.references, obj.GetNamespace())
+	}
+
+	return true
+}
+
+// Get returns the references of the instrumented resources of the namespace, sorted by kind and name like the
+// status of the Lumigo instances.
+func (i *Index) Get(namespaceName string) []corev1.ObjectReference {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	objectReferences := make([]corev1.ObjectReference, 0, len(i.references[namespaceName]))
+	for _, objectReference := range i.references[namespaceName] {
+		objectReferences = append(objectReferences, objectReference)
+	}
+
+	sort.Slice(objectReferences, func(a, b int) bool {
+		kindA, kindB := slices.Index(kindOrder, objectReferences[a].Kind), slices.Index(kindOrder, objectReferences[b].Kind)
+		if kindA != kindB {
+			return kindA < kindB
+		}
+		return objectReferences[a].Name < objectReferences[b].Name
+	})
+
+	return objectReferences
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instrumentedresources
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Instrumented Resources Suite")
+}
+
+func newObjectMeta(namespaceName string, name string, autoTrace string) metav1.ObjectMeta {
+	objectMeta := metav1.ObjectMeta{
+		Namespace:       namespaceName,
+		Name:            name,
+		UID:             types.UID(namespaceName + "/" + name),
+		ResourceVersion: "1",
+	}
+	if len(autoTrace) > 0 {
+		objectMeta.Labels = map[string]string{mutation.LumigoAutoTraceLabelKey: autoTrace}
+	}
+
+	return objectMeta
+}
+
+var _ = Context("Instrumented resources", func() {
+
+	It("reports the instrumented resources of the namespace sorted by kind and name", func() {
+		index := NewIndex()
+
+		for _, obj := range []client.Object{
+			&batchv1.Job{ObjectMeta: newObjectMeta("my-namespace", "a-job", "1.2.3")},
+			&appsv1.Deployment{ObjectMeta: newObjectMeta("my-namespace", "z-deployment", "1.2.3")},
+			&appsv1.Deployment{ObjectMeta: newObjectMeta("my-namespace", "a-deployment", "1.2.3")},
+			&appsv1.Deployment{ObjectMeta: newObjectMeta("other-namespace", "b-deployment", "1.2.3")},
+		} {
+			Expect(index.Update(obj)).To(BeTrue())
+		}
+
+		references := index.Get("my-namespace")
+		Expect(references).To(HaveLen(3))
+		Expect([]string{references[0].Name, references[1].Name, references[2].Name}).To(Equal([]string{"a-deployment", "z-deployment", "a-job"}))
+		Expect(references[0].Kind).To(Equal("Deployment"))
+		Expect(references[0].APIVersion).To(Equal("apps/v1"))
+		Expect(references[0].ResourceVersion).To(BeEmpty())
+	})
+
+	It("ignores the changes of instrumented resources that do not change the references", func() {
+		index := NewIndex()
+		deployment := &appsv1.Deployment{ObjectMeta: newObjectMeta("my-namespace", "my-deployment", "1.2.3")}
+		Expect(index.Update(deployment)).To(BeTrue())
+
+		deployment.ResourceVersion = "2"
+		Expect(index.Update(deployment)).To(BeFalse())
+	})
+
+	It("forgets the resources that are no longer instrumented", func() {
+		index := NewIndex()
+		deployment := &appsv1.Deployment{ObjectMeta: newObjectMeta("my-namespace", "my-deployment", "1.2.3")}
+		Expect(index.Update(deployment)).To(BeTrue())
+
+		deployment.Labels[mutation.LumigoAutoTraceLabelKey] = "false"
+		Expect(index.Update(deployment)).To(BeTrue())
+		Expect(index.Get("my-namespace")).To(BeEmpty())
+
+		Expect(index.Update(&appsv1.Deployment{ObjectMeta: newObjectMeta("my-namespace", "other-deployment", "")})).To(BeFalse())
+	})
+
+	It("forgets the deleted resources", func() {
+		index := NewIndex()
+		deployment := &appsv1.Deployment{ObjectMeta: newObjectMeta("my-namespace", "my-deployment", "1.2.3")}
+		Expect(index.Update(deployment)).To(BeTrue())
+
+		Expect(index.Forget(deployment)).To(BeTrue())
+		Expect(index.Get("my-namespace")).To(BeEmpty())
+		Expect(index.Forget(deployment)).To(BeFalse())
+	})
+})
@@ -0,0 +1,231 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/legacyannotations"
+)
+
+// LegacyAnnotationsReconciler migrates the namespaces and workloads configured with the annotations of the
+// annotation-driven Lumigo setup that predates the operator: it creates or updates the Lumigo instance of the
+// namespace with the equivalent settings, replaces the opt-outs of workloads with the `lumigo.auto-trace` label,
+// and strips the legacy annotations.
+type LegacyAnnotationsReconciler struct {
+	client.Client
+	Log logr.Logger
+	// Whether the central token secret is configured, in which case the Lumigo instances of the namespaces whose
+	// annotations specify no token secret reference a copy of the central one; otherwise, those namespaces are
+	// not migrated
+	CentralTokenSecretEnabled bool
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LegacyAnnotationsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("legacyannotations").
+		For(&corev1.Namespace{}, builder.WithPredicates(predicate.NewPredicateFuncs(legacyannotations.HasLegacyAnnotations))).
+		Watches(&source.Kind{Type: &appsv1.DaemonSet{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfLegacyAnnotated)).
+		Watches(&source.Kind{Type: &appsv1.Deployment{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfLegacyAnnotated)).
+		Watches(&source.Kind{Type: &appsv1.ReplicaSet{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfLegacyAnnotated)).
+		Watches(&source.Kind{Type: &appsv1.StatefulSet{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfLegacyAnnotated)).
+		Watches(&source.Kind{Type: &batchv1.CronJob{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfLegacyAnnotated)).
+		Watches(&source.Kind{Type: &batchv1.Job{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfLegacyAnnotated)).
+		Complete(r)
+}
+
+// Reconcile migrates the legacy annotations of the namespace and of its workloads.
+//
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=operator.lumigo.io,resources=lumigoes,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=apps,resources=daemonsets;deployments;replicasets;statefulsets,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;watch;update
+func (r *LegacyAnnotationsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespace", req.NamespacedName.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, req.NamespacedName, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		// Error reading the namespace - requeue the request.
+		return ctrl.Result{
+			RequeueAfter: defaultErrRequeuePeriod,
+		}, nil
+	}
+
+	if !namespace.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	workloads, err := r.listLegacyAnnotatedWorkloads(ctx, namespace.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !legacyannotations.HasLegacyAnnotations(namespace) && len(workloads) < 1 {
+		return ctrl.Result{}, nil
+	}
+
+	settings, errs := legacyannotations.GetSettings(namespace, workloads)
+	for _, err := range errs {
+		log.Error(err, "Ignoring invalid legacy annotation")
+	}
+
+	if isMigrated, err := r.migrateLumigo(ctx, namespace.Name, settings, &log); err != nil {
+		return ctrl.Result{}, err
+	} else if !isMigrated {
+		// The legacy annotations are left in place, so that the migration is retried once fixed
+		return ctrl.Result{}, nil
+	}
+
+	// The annotations of the namespace are stripped last, so that the migration is retried if stripping the ones
+	// of the workloads fails
+	for _, workload := range workloads {
+		if !legacyannotations.Strip(workload) {
+			continue
+		}
+
+		if err := r.Client.Update(ctx, workload); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return ctrl.Result{}, fmt.Errorf("cannot strip the legacy annotations of %s '%s/%s': %w", workload.GetObjectKind().GroupVersionKind().Kind, workload.GetNamespace(), workload.GetName(), err)
+		}
+	}
+
+	if legacyannotations.Strip(namespace) {
+		if err := r.Client.Update(ctx, namespace); err != nil {
+			return ctrl.Result{}, fmt.Errorf("cannot strip the legacy annotations of namespace '%s': %w", namespace.Name, err)
+		}
+	}
+
+	log.Info("Migrated the legacy annotations of the namespace", "workloads", len(workloads))
+	return ctrl.Result{}, nil
+}
+
+// Creates the Lumigo instance of the namespace if the legacy annotations opt it in, or updates the existing one with
+// their settings; it returns false if the namespace cannot be migrated yet
+func (r *LegacyAnnotationsReconciler) migrateLumigo(ctx context.Context, namespaceName string, settings legacyannotations.Settings, log *logr.Logger) (bool, error) {
+	lumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(ctx, lumigoes, client.InNamespace(namespaceName)); err != nil {
+		return false, fmt.Errorf("cannot list the Lumigo instances in namespace '%s': %w", namespaceName, err)
+	}
+
+	for i := range lumigoes.Items {
+		lumigo := &lumigoes.Items[i]
+		if !lumigo.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if !legacyannotations.ApplyTo(lumigo, settings) {
+			return true, nil
+		}
+
+		if err := r.Client.Update(ctx, lumigo); err != nil {
+			return false, fmt.Errorf("cannot update the Lumigo instance '%s/%s' with the legacy annotations: %w", lumigo.Namespace, lumigo.Name, err)
+		}
+
+		log.Info("Updated the Lumigo instance with the legacy annotations", "name", lumigo.Name)
+		return true, nil
+	}
+
+	if settings.Enabled == nil || !*settings.Enabled {
+		return true, nil
+	}
+
+	if settings.TokenSecretRef == nil && !r.CentralTokenSecretEnabled {
+		log.Info("Cannot migrate the legacy annotations: the namespace specifies no Lumigo token secret, and the central token secret is not configured")
+		return false, nil
+	}
+
+	lumigo := legacyannotations.NewLumigo(namespaceName, autoInstrumentedLumigoName, settings, operatorv1alpha1.KubernetesSecretRef{
+		Name: autoInstrumentedLumigoTokenSecretName,
+		Key:  autoInstrumentedLumigoTokenSecretKey,
+	})
+	if err := r.Client.Create(ctx, lumigo); err != nil && !apierrors.IsAlreadyExists(err) {
+		return false, fmt.Errorf("cannot create the Lumigo instance in namespace '%s': %w", namespaceName, err)
+	}
+
+	log.Info("Created Lumigo instance from the legacy annotations", "name", lumigo.Name)
+	return true, nil
+}
+
+// The workloads of the namespace with legacy annotations, with their kinds set, as the items of typed lists have none
+func (r *LegacyAnnotationsReconciler) listLegacyAnnotatedWorkloads(ctx context.Context, namespaceName string) ([]client.Object, error) {
+	workloads := []client.Object{}
+
+	for _, list := range []client.ObjectList{
+		&appsv1.DaemonSetList{},
+		&appsv1.DeploymentList{},
+		&appsv1.ReplicaSetList{},
+		&appsv1.StatefulSetList{},
+		&batchv1.CronJobList{},
+		&batchv1.JobList{},
+	} {
+		if err := r.Client.List(ctx, list, client.InNamespace(namespaceName)); err != nil {
+			return nil, fmt.Errorf("cannot list the workloads in namespace '%s': %w", namespaceName, err)
+		}
+
+		gvk, err := apiutil.GVKForObject(list, r.Client.Scheme())
+		if err != nil {
+			return nil, err
+		}
+		gvk.Kind = gvk.Kind[:len(gvk.Kind)-len("List")]
+
+		if err := meta.EachListItem(list, func(item runtime.Object) error {
+			if workload := item.(client.Object); legacyannotations.HasLegacyAnnotations(workload) {
+				workload.GetObjectKind().SetGroupVersionKind(gvk)
+				workloads = append(workloads, workload)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return workloads, nil
+}
+
+func enqueueNamespaceIfLegacyAnnotated(obj client.Object) []reconcile.Request {
+	if !legacyannotations.HasLegacyAnnotations(obj) {
+		return []reconcile.Request{}
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+}
@@ -0,0 +1,262 @@
+package legacyannotations
+
+import (
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	// KeyPrefix is the prefix of the annotations of the annotation-driven Lumigo setup that predates the operator
+	KeyPrefix = "autotrace.lumigo.io/"
+
+	// EnabledAnnotationKey opts namespaces and workloads in (`"true"`) or out (`"false"`) of tracing
+	EnabledAnnotationKey = KeyPrefix + "enabled"
+	// TokenSecretNameAnnotationKey is the name of the secret, in the same namespace, with the Lumigo token of the namespace
+	TokenSecretNameAnnotationKey = KeyPrefix + "token-secret-name"
+	// TokenSecretKeyAnnotationKey is the key of the Lumigo token in the secret; if unset, defaults to `token`
+	TokenSecretKeyAnnotationKey = KeyPrefix + "token-secret-key"
+	// LogsEnabledAnnotationKey opts namespaces in (`"true"`) or out (`"false"`) of sending their logs to Lumigo
+	LogsEnabledAnnotationKey = KeyPrefix + "logs-enabled"
+
+	// The label of the Lumigo instances created from the legacy annotations of their namespace
+	MigratedLabelKey   = "lumigo.io/migrated-from-legacy-annotations"
+	MigratedLabelValue = "true"
+
+	defaultTokenSecretKey = "token"
+)
+
+var annotationKeys = []string{EnabledAnnotationKey, TokenSecretNameAnnotationKey, TokenSecretKeyAnnotationKey, LogsEnabledAnnotationKey}
+
+// Settings is the configuration of a namespace expressed by the legacy annotations of the namespace and of its workloads
+type Settings struct {
+	// Whether the namespace is traced, which is the case if either the namespace or any of its workloads opts in;
+	// nil if no annotation says either way
+	Enabled *bool
+	// The secret with the Lumigo token of the namespace; nil if the namespace does not specify one
+	TokenSecretRef *operatorv1alpha1.KubernetesSecretRef
+	// Whether the logs of the namespace are sent to Lumigo; nil if the namespace does not specify it
+	LogsEnabled *bool
+}
+
+// HasLegacyAnnotations returns whether the namespace or workload has any of the legacy annotations, either in its
+// metadata or in the metadata of its pod template
+func HasLegacyAnnotations(obj client.Object) bool {
+	if hasAnyAnnotation(obj.GetAnnotations()) {
+		return true
+	}
+
+	if podTemplate := getPodTemplate(obj); podTemplate != nil {
+		return hasAnyAnnotation(podTemplate.Annotations)
+	}
+
+	return false
+}
+
+// GetSettings returns the settings of the legacy annotations of the namespace and of its workloads, and the errors
+// of the annotations whose values cannot be migrated, which are ignored
+func GetSettings(namespace *corev1.Namespace, workloads []client.Object) (Settings, []error) {
+	settings := Settings{}
+	errs := []error{}
+
+	if enabled, err := parseBoolAnnotation(namespace.Annotations, EnabledAnnotationKey); err != nil {
+		errs = append(errs, err)
+	} else {
+		settings.Enabled = enabled
+	}
+
+	if logsEnabled, err := parseBoolAnnotation(namespace.Annotations, LogsEnabledAnnotationKey); err != nil {
+		errs = append(errs, err)
+	} else {
+		settings.LogsEnabled = logsEnabled
+	}
+
+	if secretName := namespace.Annotations[TokenSecretNameAnnotationKey]; len(secretName) > 0 {
+		secretKey := namespace.Annotations[TokenSecretKeyAnnotationKey]
+		if len(secretKey) < 1 {
+			secretKey = defaultTokenSecretKey
+		}
+
+		settings.TokenSecretRef = &operatorv1alpha1.KubernetesSecretRef{
+			Name: secretName,
+			Key:  secretKey,
+		}
+	}
+
+	if settings.Enabled != nil {
+		// The annotation of the namespace prevails over the ones of its workloads
+		return settings, errs
+	}
+
+	for _, workload := range workloads {
+		if isWorkloadEnabled(workload) {
+			enabled := true
+			settings.Enabled = &enabled
+			break
+		}
+	}
+
+	return settings, errs
+}
+
+// NewLumigo returns the Lumigo instance with the settings of the legacy annotations; if the settings have no token
+// secret, the Lumigo instance references the default one
+func NewLumigo(namespaceName string, name string, settings Settings, defaultTokenSecretRef operatorv1alpha1.KubernetesSecretRef) *operatorv1alpha1.Lumigo {
+	lumigo := &operatorv1alpha1.Lumigo{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      name,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":    "lumigo",
+				"app.kubernetes.io/managed-by": "lumigo-operator",
+				MigratedLabelKey:               MigratedLabelValue,
+			},
+		},
+		Spec: operatorv1alpha1.LumigoSpec{
+			LumigoToken: operatorv1alpha1.Credentials{
+				SecretRef: defaultTokenSecretRef,
+			},
+		},
+	}
+
+	ApplyTo(lumigo, settings)
+	return lumigo
+}
+
+// ApplyTo sets the settings of the legacy annotations on the existing Lumigo instance, and returns whether it changed;
+// the settings that the annotations do not specify are left as they are
+func ApplyTo(lumigo *operatorv1alpha1.Lumigo, settings Settings) bool {
+	changed := false
+
+	if settings.TokenSecretRef != nil && lumigo.Spec.LumigoToken.SecretRef != *settings.TokenSecretRef {
+		lumigo.Spec.LumigoToken.SecretRef = *settings.TokenSecretRef
+		changed = true
+	}
+
+	if settings.LogsEnabled != nil && (lumigo.Spec.Logging.Enabled == nil || *lumigo.Spec.Logging.Enabled != *settings.LogsEnabled) {
+		logsEnabled := *settings.LogsEnabled
+		lumigo.Spec.Logging.Enabled = &logsEnabled
+		changed = true
+	}
+
+	return changed
+}
+
+// Strip removes the legacy annotations from the metadata of the namespace or workload and of its pod template, and
+// returns whether it changed. The workloads opted out of tracing get the `lumigo.auto-trace: "false"` label instead.
+func Strip(obj client.Object) bool {
+	changed := false
+
+	if _, isNamespace := obj.(*corev1.Namespace); !isNamespace && isWorkloadOptedOut(obj) {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if labels[mutation.LumigoAutoTraceLabelKey] != "false" {
+			labels[mutation.LumigoAutoTraceLabelKey] = "false"
+			obj.SetLabels(labels)
+			changed = true
+		}
+	}
+
+	annotations := obj.GetAnnotations()
+	changed = deleteAnnotations(annotations) || changed
+	obj.SetAnnotations(annotations)
+
+	if podTemplate := getPodTemplate(obj); podTemplate != nil {
+		changed = deleteAnnotations(podTemplate.Annotations) || changed
+	}
+
+	return changed
+}
+
+func isWorkloadEnabled(workload client.Object) bool {
+	for _, annotations := range workloadAnnotations(workload) {
+		if annotations[EnabledAnnotationKey] == "true" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isWorkloadOptedOut(workload client.Object) bool {
+	for _, annotations := range workloadAnnotations(workload) {
+		if annotations[EnabledAnnotationKey] == "false" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func workloadAnnotations(workload client.Object) []map[string]string {
+	annotations := []map[string]string{workload.GetAnnotations()}
+	if podTemplate := getPodTemplate(workload); podTemplate != nil {
+		annotations = append(annotations, podTemplate.Annotations)
+	}
+
+	return annotations
+}
+
+func hasAnyAnnotation(annotations map[string]string) bool {
+	for _, key := range annotationKeys {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func deleteAnnotations(annotations map[string]string) bool {
+	changed := false
+	for _, key := range annotationKeys {
+		if _, ok := annotations[key]; ok {
+			delete(annotations, key)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func parseBoolAnnotation(annotations map[string]string, key string) (*bool, error) {
+	value, ok := annotations[key]
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s' of the '%s' annotation: %w", value, key, err)
+	}
+
+	return &b, nil
+}
+
+// The pod templates of Jobs are immutable, so only the metadata of Jobs is migrated
+func getPodTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch w := obj.(type) {
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.ReplicaSet:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *batchv1.CronJob:
+		return &w.Spec.JobTemplate.Spec.Template
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package legacyannotations
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Legacy Annotations Suite")
+}
+
+func newNamespace(annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-namespace",
+			Annotations: annotations,
+		},
+	}
+}
+
+func newDeployment(annotations map[string]string, podTemplateAnnotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "my-namespace",
+			Name:        "my-deployment",
+			Annotations: annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: podTemplateAnnotations,
+				},
+			},
+		},
+	}
+}
+
+var enabled, disabled = true, false
+
+var _ = Context("Legacy annotations", func() {
+
+	It("finds the legacy annotations in the metadata and in the pod templates", func() {
+		Expect(HasLegacyAnnotations(newNamespace(map[string]string{EnabledAnnotationKey: "true"}))).To(BeTrue())
+		Expect(HasLegacyAnnotations(newDeployment(nil, map[string]string{EnabledAnnotationKey: "true"}))).To(BeTrue())
+		Expect(HasLegacyAnnotations(newDeployment(map[string]string{LogsEnabledAnnotationKey: "true"}, nil))).To(BeTrue())
+		Expect(HasLegacyAnnotations(newDeployment(map[string]string{"lumigo.io/tag.team": "payments"}, nil))).To(BeFalse())
+		Expect(HasLegacyAnnotations(newNamespace(nil))).To(BeFalse())
+	})
+
+	It("reads the settings of the namespace", func() {
+		settings, errs := GetSettings(newNamespace(map[string]string{
+			EnabledAnnotationKey:         "true",
+			LogsEnabledAnnotationKey:     "true",
+			TokenSecretNameAnnotationKey: "my-lumigo-token",
+		}), nil)
+
+		Expect(errs).To(BeEmpty())
+		Expect(settings.Enabled).To(Equal(&enabled))
+		Expect(settings.LogsEnabled).To(Equal(&enabled))
+		Expect(settings.TokenSecretRef).To(Equal(&operatorv1alpha1.KubernetesSecretRef{
+			Name: "my-lumigo-token",
+			Key:  "token",
+		}))
+	})
+
+	It("opts the namespace in if any of its workloads opts in", func() {
+		settings, errs := GetSettings(newNamespace(nil), []client.Object{
+			newDeployment(map[string]string{EnabledAnnotationKey: "false"}, nil),
+			newDeployment(nil, map[string]string{EnabledAnnotationKey: "true"}),
+		})
+
+		Expect(errs).To(BeEmpty())
+		Expect(settings.Enabled).To(Equal(&enabled))
+		Expect(settings.TokenSecretRef).To(BeNil())
+		Expect(settings.LogsEnabled).To(BeNil())
+	})
+
+	It("prefers the opt-out of the namespace over the opt-ins of its workloads", func() {
+		settings, _ := GetSettings(newNamespace(map[string]string{EnabledAnnotationKey: "false"}), []client.Object{
+			newDeployment(map[string]string{EnabledAnnotationKey: "true"}, nil),
+		})
+
+		Expect(settings.Enabled).To(Equal(&disabled))
+	})
+
+	It("ignores the annotations with invalid values", func() {
+		settings, errs := GetSettings(newNamespace(map[string]string{
+			EnabledAnnotationKey:     "yes please",
+			LogsEnabledAnnotationKey: "true",
+		}), nil)
+
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(ContainSubstring(EnabledAnnotationKey))
+		Expect(settings.Enabled).To(BeNil())
+		Expect(settings.LogsEnabled).To(Equal(&enabled))
+	})
+
+	It("creates the Lumigo instance with the default token secret", func() {
+		lumigo := NewLumigo("my-namespace", "lumigo", Settings{}, operatorv1alpha1.KubernetesSecretRef{Name: "lumigo-credentials", Key: "token"})
+
+		Expect(lumigo.Labels).To(HaveKeyWithValue(MigratedLabelKey, MigratedLabelValue))
+		Expect(lumigo.Spec.LumigoToken.SecretRef).To(Equal(operatorv1alpha1.KubernetesSecretRef{Name: "lumigo-credentials", Key: "token"}))
+		Expect(lumigo.Spec.Logging.Enabled).To(BeNil())
+	})
+
+	It("updates only the settings of the existing Lumigo instance specified by the annotations", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				LumigoToken: operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{Name: "my-token", Key: "token"},
+				},
+			},
+		}
+
+		Expect(ApplyTo(lumigo, Settings{LogsEnabled: &enabled})).To(BeTrue())
+		Expect(lumigo.Spec.LumigoToken.SecretRef.Name).To(Equal("my-token"))
+		Expect(lumigo.Spec.Logging.Enabled).To(Equal(&enabled))
+
+		Expect(ApplyTo(lumigo, Settings{LogsEnabled: &enabled})).To(BeFalse())
+	})
+
+	It("strips the annotations and carries over the opt-outs of workloads", func() {
+		deployment := newDeployment(map[string]string{
+			EnabledAnnotationKey: "false",
+			"my-annotation":      "value",
+		}, map[string]string{
+			EnabledAnnotationKey: "false",
+		})
+
+		Expect(Strip(deployment)).To(BeTrue())
+		Expect(deployment.Annotations).To(Equal(map[string]string{"my-annotation": "value"}))
+		Expect(deployment.Spec.Template.Annotations).To(BeEmpty())
+		Expect(deployment.Labels).To(HaveKeyWithValue(mutation.LumigoAutoTraceLabelKey, "false"))
+		Expect(HasLegacyAnnotations(deployment)).To(BeFalse())
+
+		Expect(Strip(deployment)).To(BeFalse())
+	})
+
+	It("does not label the workloads that opt in", func() {
+		deployment := newDeployment(map[string]string{EnabledAnnotationKey: "true"}, nil)
+
+		Expect(Strip(deployment)).To(BeTrue())
+		Expect(deployment.Labels).NotTo(HaveKey(mutation.LumigoAutoTraceLabelKey))
+	})
+
+	It("leaves the immutable pod templates of jobs alone", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{EnabledAnnotationKey: "false"},
+			},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{EnabledAnnotationKey: "false"},
+					},
+				},
+			},
+		}
+
+		Expect(Strip(job)).To(BeTrue())
+		Expect(job.Annotations).To(BeEmpty())
+		Expect(job.Spec.Template.Annotations).To(HaveKey(EnabledAnnotationKey))
+		Expect(job.Labels).To(HaveKeyWithValue(mutation.LumigoAutoTraceLabelKey, "false"))
+		Expect(HasLegacyAnnotations(job)).To(BeFalse())
+	})
+
+	It("does not label namespaces that opt out", func() {
+		namespace := newNamespace(map[string]string{EnabledAnnotationKey: "false"})
+
+		Expect(Strip(namespace)).To(BeTrue())
+		Expect(namespace.Annotations).To(BeEmpty())
+		Expect(namespace.Labels).To(BeEmpty())
+	})
+})
@@ -0,0 +1,74 @@
+package listpaging
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultPageSize is how many resources are listed per request, so that listing the resources of
+// namespaces with thousands of them does not time out
+const DefaultPageSize = 500
+
+// PageFunc lists and processes the page of resources selected by the options, which carry the limit
+// and the continue token of the page, and returns the continue token of the next page, which is empty
+// after the last page.
+type PageFunc func(ctx context.Context, options metav1.ListOptions) (string, error)
+
+// ListAll lists all the pages of the resources selected by the options.
+func ListAll(ctx context.Context, options metav1.ListOptions, listPage PageFunc) error {
+	if options.Limit < 1 {
+		options.Limit = DefaultPageSize
+	}
+	options.Continue = ""
+
+	for {
+		next, err := listPage(ctx, options)
+		if err != nil {
+			return err
+		}
+
+		if len(next) < 1 {
+			return nil
+		}
+		options.Continue = next
+	}
+}
+
+// ListPages lists at most maxPages pages of the resources selected by the options, starting from the page
+// of the continue token or, if it is empty, from the first page. It returns the continue token of the page
+// to resume from, which is empty once all the pages have been listed, and how many pages have been listed.
+// Continue tokens expire after a few minutes, in which case the listing restarts from the first page, so the
+// processing of the pages must be idempotent.
+func ListPages(ctx context.Context, options metav1.ListOptions, continueToken string, maxPages int, listPage PageFunc) (string, int, error) {
+	if options.Limit < 1 {
+		options.Limit = DefaultPageSize
+	}
+	options.Continue = continueToken
+
+	pages := 0
+	restarted := false
+	for pages < maxPages {
+		next, err := listPage(ctx, options)
+		if isExpired(err) && len(options.Continue) > 0 && !restarted {
+			options.Continue = ""
+			restarted = true
+			continue
+		} else if err != nil {
+			return options.Continue, pages, err
+		}
+
+		pages++
+		if len(next) < 1 {
+			return "", pages, nil
+		}
+		options.Continue = next
+	}
+
+	return options.Continue, pages, nil
+}
+
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listpaging
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "List Paging Suite")
+}
+
+// Lists the given number of pages, whose continue tokens are their indexes, recording the pages listed;
+// the continue tokens in expiredTokens are rejected as expired
+func newPager(pageCount int, expiredTokens ...string) (PageFunc, *[]string) {
+	listed := []string{}
+	return func(ctx context.Context, options metav1.ListOptions) (string, error) {
+		Expect(options.Limit).To(Equal(int64(DefaultPageSize)))
+		Expect(options.LabelSelector).To(Equal("app=my-app"))
+
+		for _, expiredToken := range expiredTokens {
+			if options.Continue == expiredToken {
+				return "", fmt.Errorf("cannot list pods: %w", apierrors.NewResourceExpired("the continue token has expired"))
+			}
+		}
+
+		page := 0
+		if len(options.Continue) > 0 {
+			page, _ = strconv.Atoi(options.Continue)
+		}
+		listed = append(listed, strconv.Itoa(page))
+
+		if page+1 >= pageCount {
+			return "", nil
+		}
+		return strconv.Itoa(page + 1), nil
+	}, &listed
+}
+
+var _ = Context("List paging", func() {
+
+	options := metav1.ListOptions{LabelSelector: "app=my-app"}
+
+	It("lists all the pages", func() {
+		listPage, listed := newPager(3)
+		Expect(ListAll(context.TODO(), options, listPage)).To(Succeed())
+		Expect(*listed).To(Equal([]string{"0", "1", "2"}))
+	})
+
+	It("stops listing at the first error", func() {
+		Expect(ListAll(context.TODO(), options, func(ctx context.Context, options metav1.ListOptions) (string, error) {
+			return "", fmt.Errorf("boom")
+		})).To(MatchError("boom"))
+	})
+
+	It("lists at most the given number of pages and returns where to resume from", func() {
+		listPage, listed := newPager(5)
+
+		next, pages, err := ListPages(context.TODO(), options, "", 2, listPage)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).To(Equal("2"))
+		Expect(pages).To(Equal(2))
+
+		next, pages, err = ListPages(context.TODO(), options, next, 10, listPage)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).To(BeEmpty())
+		Expect(pages).To(Equal(3))
+
+		Expect(*listed).To(Equal([]string{"0", "1", "2", "3", "4"}))
+	})
+
+	It("restarts from the first page if the continue token has expired", func() {
+		listPage, listed := newPager(3, "stale")
+
+		next, pages, err := ListPages(context.TODO(), options, "stale", 10, listPage)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).To(BeEmpty())
+		Expect(pages).To(Equal(3))
+		Expect(*listed).To(Equal([]string{"0", "1", "2"}))
+	})
+
+	It("returns the continue token of the page that cannot be listed", func() {
+		next, pages, err := ListPages(context.TODO(), options, "3", 10, func(ctx context.Context, options metav1.ListOptions) (string, error) {
+			return "", fmt.Errorf("timeout")
+		})
+		Expect(err).To(MatchError("timeout"))
+		Expect(next).To(Equal("3"))
+		Expect(pages).To(BeZero())
+	})
+})
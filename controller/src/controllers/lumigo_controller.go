@@ -18,8 +18,9 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
+	"reflect"
 	"sort"
 	"strings"
 	"time"
@@ -28,7 +29,9 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -40,18 +43,51 @@ import (
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/reference"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/audit"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/backendprobe"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/backgroundcleanup"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/capabilities"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/compatibility"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/deferredinjections"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/goinstrumentation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/imageverification"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectionfailures"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectiongc"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectionskips"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectionspec"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectorruntimefailures"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/instrumentedresources"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/internal/sorting"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/listpaging"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/namespacetags"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/networkpolicies"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/optimisticupdate"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/otelinstrumentation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/platform"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/selftelemetry"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/specvalidation"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyconfigs"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxydaemonset"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxydedicated"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxymetrics"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyprobe"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyshards"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryverification"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokendistribution"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokensecrets"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/workloadpacing"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
 	try "gopkg.in/matryer/try.v1"
 )
@@ -61,6 +97,33 @@ const (
 	defaultErrRequeuePeriod  = 1 * time.Second
 	maxTriggeredStateGroups  = 10
 	maxMutationRetryAttempts = 5
+	// How many pages of resources are listed in one reconciliation to inject the existing resources
+	// of the namespace, after which the injection is resumed in the next reconciliation
+	maxInjectionListPagesPerReconciliation = 10
+	// How recently the telemetry-proxy must have dropped spans of a namespace for its
+	// Lumigo instance to be considered rate-limited
+	rateLimitingWindow = 5 * time.Minute
+	// The quotas are set in gigabytes, and measured by the telemetry-proxy in bytes
+	bytesPerGigabyte = 1000 * 1000 * 1000
+	// The format of the days (UTC) the telemetry-proxy reports the usage of the quotas for
+	quotaDayFormat = "2006-01-02"
+
+	archivalPrefixNamespacePlaceholder = "{namespace}"
+	defaultArchivalPrefix              = archivalPrefixNamespacePlaceholder
+	defaultArchivalRegion              = "us-east-1"
+	defaultArchivalPartition           = "hour"
+
+	// How long the telemetry-proxy must have been failing to export telemetry without any
+	// success for the Lumigo instances to have the TelemetryExportDegraded condition
+	telemetryExportDegradedMinDuration = 2 * time.Minute
+
+	// How long the Lumigo backend must have been failing the probes for the Lumigo instances
+	// to have the BackendUnreachable condition
+	backendUnreachableMinDuration = time.Minute
+
+	// How long the OTLP endpoint of the telemetry-proxy must have been failing the probes for the Lumigo
+	// instances to have the TelemetryProxyUnreachable condition; it covers the rollout of new telemetry-proxies
+	telemetryProxyUnreachableMinDuration = time.Minute
 )
 
 // LumigoReconciler reconciles a Lumigo object
@@ -70,6 +133,8 @@ type LumigoReconciler struct {
 	client.Client                           // Deal with typed resources that you know the type for
 	*kubernetes.Clientset                   // Deal with events
 	DynamicClient         dynamic.Interface // Look up object references of resources we don't want to treat in a typed fashion
+	// Optional: if nil, the resources to be mutated are read through the cache of the client, which may lag behind
+	APIReader client.Reader
 	// End of clients (?)
 	record.EventRecorder
 	Scheme                                    *runtime.Scheme
@@ -79,20 +144,81 @@ type LumigoReconciler struct {
 	TelemetryProxyOtlpServiceUrl              string
 	TelemetryProxyOtlpLogsServiceUrl          string
 	TelemetryProxyNamespaceConfigurationsPath string
+	LumigoOperatorNamespace                   string
+	LumigoOperatorServiceAccountName          string
+	GoInstrumentationAgentImage               string
+	// Optional: if nil, the TelemetryExportDegraded condition is never set
+	TelemetryProxyExportMonitor *telemetryproxymetrics.ExportMonitor
+	// Optional: if nil, the BackendUnreachable condition relies only on the telemetry-proxy metrics
+	LumigoBackendProbe *backendprobe.BackendProbe
+	// Optional: if nil, the TelemetryProxyUnreachable condition is never set
+	TelemetryProxyProbe *telemetryproxyprobe.TelemetryProxyProbe
+	// Optional: if nil, the telemetry-proxy runs only next to the controller, rather than also as a DaemonSet
+	TelemetryProxyDaemonSetConfig *telemetryproxydaemonset.DaemonSetConfig
+	// Optional: if nil, the telemetry-proxy is not sharded, and all the instrumented workloads send telemetry to the same one
+	TelemetryProxyShardsConfig *telemetryproxyshards.ShardsConfig
+	// Optional: if nil, the Lumigo instances cannot request a dedicated telemetry-proxy in their namespace
+	DedicatedTelemetryProxyConfig *telemetryproxydedicated.DedicatedProxyConfig
+	// Optional: if nil, the operator does not manage NetworkPolicies
+	NetworkPoliciesConfig *networkpolicies.NetworkPoliciesConfig
+	// Optional: if nil, the injector image is injected without verifying its signature
+	InjectorImageVerifier *imageverification.Verifier
+	// Optional: if nil, the settings of the Lumigo distros not set by the Lumigo instances keep the defaults of the distros
+	InjectorDefaults *mutation.InjectorDefaults
+	// Optional: if nil, only the workloads that opt out are not injected; otherwise, the workloads of the operator
+	// itself are never injected, whatever the Lumigo instance of their namespace
+	ProtectedWorkloads *mutation.ProtectedWorkloads
+	// Optional: if nil, the mutations of resources are not audited
+	Auditor *audit.Auditor
+	// Optional: if nil, the failures of the `lumigo-injector` init container in the injected pods are not reported
+	InjectorRuntimeFailureMonitor *injectorruntimefailures.Monitor
+	// Optional: if nil, the instrumented resources reported in the status of the Lumigo instances are listed from the
+	// API server at every reconciliation, rather than kept track of from the changes of the watched resources
+	InstrumentedResources *instrumentedresources.Index
+	// Optional: if nil, the token secrets referenced by Lumigo instances are not copied from a central one
+	CentralTokenSecretConfig *tokendistribution.CentralTokenSecretConfig
+	// Optional: if nil, the reconciliations are not traced
+	SelfTelemetry *selftelemetry.Tracer
+	// Optional: if nil, the telemetry of the Lumigo instances that request it cannot be verified with a synthetic trace
+	TelemetryVerificationConfig *telemetryverification.Config
+	// Optional: if nil, only the limits of the Lumigo instances apply to the workloads updated to add the injection
+	WorkloadUpdatePacer *workloadpacing.Pacer
+	// The platform the operator runs on; on restricted ones, the injection is adjusted and DaemonSet-based features are disabled
+	Platform platform.Platform
+	// Features the operator has been configured with that the platform does not support, reported in the UnsupportedFeatures condition
+	UnsupportedPlatformFeatures []platform.Feature
+	// Optional: if nil, the cluster is assumed to serve all the API resources the features of the operator rely on
+	CapabilitiesDiscoverer *capabilities.Discoverer
+	// Optional: if zero, the partial and orphaned injection artifacts of the resources are not garbage-collected
+	InjectionGCInterval time.Duration
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *LumigoReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The changes of the instrumented resources are mapped to the Lumigo instances of their namespace; with the index
+	// of the instrumented resources, only the changes that add or remove instrumented resources are
+	var workloadEventHandler handler.EventHandler = handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)
+	if r.InstrumentedResources != nil {
+		workloadEventHandler = r.instrumentedResourcesEventHandler()
+	}
+
+	// The garbage collection of the injection artifacts goes across the namespaces, rather than by Lumigo instance
+	if r.InjectionGCInterval > 0 {
+		if err := mgr.Add(injectiongc.NewCollector(r.collectInjectionArtifacts, r.InjectionGCInterval, r.Log.WithName("injection-gc"))); err != nil {
+			return err
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.Lumigo{}).
 		// Watch for changes in secrets that are referenced in Lumigo instances as containing the Lumigo token
 		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfSecretReferencedByLumigo)).
-		Watches(&source.Kind{Type: &appsv1.DaemonSet{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)).
-		Watches(&source.Kind{Type: &appsv1.Deployment{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)).
-		Watches(&source.Kind{Type: &appsv1.ReplicaSet{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)).
-		Watches(&source.Kind{Type: &appsv1.StatefulSet{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)).
-		Watches(&source.Kind{Type: &batchv1.CronJob{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)).
-		Watches(&source.Kind{Type: &batchv1.Job{}}, handler.EnqueueRequestsFromMapFunc(r.enqueueIfHasLumigoAutotraceLabel)).
+		Watches(&source.Kind{Type: &appsv1.DaemonSet{}}, workloadEventHandler).
+		Watches(&source.Kind{Type: &appsv1.Deployment{}}, workloadEventHandler).
+		Watches(&source.Kind{Type: &appsv1.ReplicaSet{}}, workloadEventHandler).
+		Watches(&source.Kind{Type: &appsv1.StatefulSet{}}, workloadEventHandler).
+		Watches(&source.Kind{Type: &batchv1.CronJob{}}, workloadEventHandler).
+		Watches(&source.Kind{Type: &batchv1.Job{}}, workloadEventHandler).
 		Complete(r)
 }
 
@@ -108,11 +234,27 @@ func (r *LumigoReconciler) SetupWithManager(mgr ctrl.Manager) error {
 // +kubebuilder:rbac:groups=operator.lumigo.io,resources=lumigoes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.lumigo.io,resources=lumigoes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.lumigo.io,resources=lumigoes/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update;delete
+// +kubebuilder:rbac:groups=keda.sh,resources=scaledjobs,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=opentelemetry.io,resources=instrumentations,verbs=get
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;create;update;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=services;serviceaccounts,verbs=get;create;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=get;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;create;delete
 func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("name", req.NamespacedName.Name, "namespace", req.NamespacedName.Namespace)
 	now := metav1.NewTime(time.Now())
 
+	ctx, span := r.SelfTelemetry.StartSpan(ctx, "Reconcile Lumigo",
+		selftelemetry.String("k8s.namespace.name", req.NamespacedName.Namespace),
+		selftelemetry.String("lumigo.name", req.NamespacedName.Name),
+	)
+	defer span.End()
+
 	namespace, err := r.Clientset.CoreV1().Namespaces().Get(ctx, req.NamespacedName.Namespace, metav1.GetOptions{})
 	namespaceUid := ""
 	if err != nil {
@@ -132,6 +274,16 @@ func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	lumigo := &operatorv1alpha1.Lumigo{}
 	if err := r.Client.Get(ctx, req.NamespacedName, lumigo); err != nil {
 		if apierrors.IsNotFound(err) {
+			// The injection is removed in the background from the resources of the namespace of a deleted Lumigo instance
+			if pendingCleanup, err := backgroundcleanup.GetPendingCleanup(ctx, r.Clientset.CoreV1(), req.Namespace); err != nil {
+				log.Error(err, "Cannot look up the background removal of instrumentation from resources in namespace")
+				return ctrl.Result{
+					RequeueAfter: defaultErrRequeuePeriod,
+				}, nil
+			} else if pendingCleanup != nil {
+				return r.removeLumigoFromResourcesInBackground(ctx, pendingCleanup, &log)
+			}
+
 			// Request object may have been deleted after the reconcile request has been issued,
 			// e.g., due to garbage collection.
 			log.Info("Discarding reconciliation event, Lumigo instance no longer exists")
@@ -149,6 +301,9 @@ func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		log = log.WithValues("new-lumigo", true)
 	}
 
+	// The changes of the telemetry-proxy configurations are logged by their own component, whose log level can be set separately
+	proxyConfigLog := log.WithName("proxy-config")
+
 	if lumigo.ObjectMeta.DeletionTimestamp.IsZero() {
 		// The Lumigo instance is not being deleted, so ensure it has our finalizer
 		if !controllerutil.ContainsFinalizer(lumigo, operatorv1alpha1.LumigoResourceFinalizer) {
@@ -158,44 +313,12 @@ func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			}
 		}
 	} else if controllerutil.ContainsFinalizer(lumigo, operatorv1alpha1.LumigoResourceFinalizer) {
-		injectionSpec := lumigo.Spec.Tracing.Injection
-
-		if conditions.IsActive(lumigo) {
-			log.Info("Lumigo instance is being deleted, removing instrumentation from resources in namespace")
-			if isTruthy(injectionSpec.Enabled, true) && isTruthy(injectionSpec.RemoveLumigoFromResourcesOnDeletion, true) {
-				if err := r.removeLumigoFromResources(ctx, lumigo, &log); err != nil {
-					log.Error(err, "cannot remove instrumentation from resources", "namespace", req.Namespace)
-					return ctrl.Result{}, err
-				}
-			} else {
-				log.Info(
-					"Lumigo instance is being deleted, but instrumentation from resources in namespace will not be removed",
-					"Injection.Enabled", injectionSpec.Enabled,
-					"Injection.RemoveLumigoFromResourcesOnDeletion", injectionSpec.RemoveLumigoFromResourcesOnDeletion,
-				)
-			}
-		} else {
-			log.Info("Lumigo instance is being deleted, but its status is not active so the instrumentation will not be removed from resources in namespace")
-		}
-
-		// remove our finalizer from the list and update it.
-		controllerutil.RemoveFinalizer(lumigo, operatorv1alpha1.LumigoResourceFinalizer)
-		if err := r.Update(ctx, lumigo); err != nil {
-			return ctrl.Result{}, err
-		}
-
-		// Update telemetry-proxy not to collect Kube Events for this namespace
-		isChanged, err := telemetryproxyconfigs.RemoveTelemetryProxyMonitoringOfNamespace(ctx, r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace, &log)
-		if err != nil {
-			log.Error(err, "Cannot update the telemetry-proxy configurations to remove the monitoring of the namespace")
-		} else if isChanged {
-			log.Info("Updated the telemetry-proxy configurations to remove the monitoring of the namespace")
-		}
+		return r.finalizeLumigo(ctx, lumigo, now, &log, &proxyConfigLog)
+	}
 
-		// Set the lumigo instance as inactive
-		conditions.SetActiveConditionWithMessage(lumigo, now, false, "This Lumigo instance is being deleted")
-		conditions.ClearErrorCondition(lumigo, now)
-		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	// Before the status changes, as the reset saves the status and then updates the Lumigo instance
+	if err := r.resetInjectionBackoff(ctx, lumigo, now, &log); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// Validate there is only one Lumigo instance in any one namespace
@@ -226,27 +349,349 @@ func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
-	if lumigo.Spec == (operatorv1alpha1.LumigoSpec{}) {
+	if reflect.DeepEqual(lumigo.Spec, operatorv1alpha1.LumigoSpec{}) {
 		// This could happen if somehow the defaulter webhook is malfunctioning or turned off
 		return ctrl.Result{}, fmt.Errorf("the Lumigo spec is empty")
 	}
 
+	// While paused, the operator does not change the instrumentation of the resources in the namespace
+	isPaused, isResumed := r.updatePausedCondition(lumigo, now)
+
+	r.syncTokenSecretCopy(ctx, lumigo, &log)
+
 	token, err := r.validateCredentials(ctx, req.Namespace, &lumigo.Spec.LumigoToken)
+	// The token secret, or its key, deleted after the Lumigo instance became active is reported as such, and
+	// the last valid token is used in its stead with the `KeepInjecting` token missing policy
+	isTokenMissing := err != nil && (conditions.IsActive(lumigo) || conditions.IsTokenMissing(lumigo)) && r.isTokenSecretMissing(ctx, req.Namespace, &lumigo.Spec.LumigoToken)
+	r.updateTokenMissingCondition(lumigo, now, isTokenMissing, err)
+	if isTokenMissing && lumigo.Spec.Tracing.Injection.TokenMissingPolicy == operatorv1alpha1.TokenMissingPolicyKeepInjecting {
+		if cachedToken, cacheErr := tokensecrets.GetCachedToken(ctx, r.Client, lumigo.Namespace); cacheErr != nil {
+			log.Info("Cannot use the cached Lumigo token in place of the missing one", "error", cacheErr.Error())
+		} else {
+			log.Info("Using the cached Lumigo token in place of the missing one", "error", err.Error())
+			token, err = cachedToken, nil
+		}
+	}
 	if err != nil {
 		conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("invalid Lumigo token secret reference: %w", err))
 		log.Info("Invalid Lumigo token secret reference", "error", err.Error(), "status", &lumigo.Status)
 		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
 	}
 
+	routeTokens, err := r.resolveRouteTokens(ctx, req.Namespace, lumigo.Spec.Tracing.Routes)
+	if err != nil {
+		conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("invalid tracing routes: %w", err))
+		log.Info("Invalid tracing routes", "error", err.Error(), "status", &lumigo.Status)
+		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	}
+
+	// The telemetry of a namespace that requests a dedicated telemetry-proxy is not sent to the shared one instead
+	if telemetryproxydedicated.IsRequested(lumigo) && r.DedicatedTelemetryProxyConfig == nil {
+		conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("'.Spec.Tracing.DedicatedProxy' is 'true', but the dedicated telemetry-proxies are not enabled in the Lumigo operator"))
+		log.Info("Dedicated telemetry-proxy requested, but not enabled in the operator", "status", &lumigo.Status)
+		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	}
+
+	r.syncTokenSecretOfNamespace(ctx, lumigo, token, routeTokens, &log)
+
+	additionalExporters, err := r.resolveAdditionalExporters(ctx, req.Namespace, lumigo.Spec.Tracing.AdditionalExporters)
+	if err != nil {
+		conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("invalid additional exporters: %w", err))
+		log.Info("Invalid additional exporters", "error", err.Error(), "status", &lumigo.Status)
+		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	}
+
+	endpoints, err := r.resolveEndpoints(ctx, req.Namespace, &lumigo.Spec.Endpoints)
+	if err != nil {
+		conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("invalid endpoints: %w", err))
+		log.Info("Invalid endpoints", "error", err.Error(), "status", &lumigo.Status)
+		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	}
+
+	otlpHeaders, err := r.resolveOtlpHeaders(ctx, req.Namespace, lumigo.Spec.Tracing.OtlpHeaders)
+	if err != nil {
+		conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("invalid OTLP headers: %w", err))
+		log.Info("Invalid OTLP headers", "error", err.Error(), "status", &lumigo.Status)
+		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	}
+	endpoints = telemetryproxyconfigs.MergeOtlpHeaders(endpoints, otlpHeaders)
+	// The values of the headers are secrets, which the status does not disclose
+	lumigo.Status.OtlpHeaders = telemetryproxyconfigs.RedactHeaders(otlpHeaders)
+
+	if err := r.updateInjectionStatus(ctx, lumigo, namespace, &log); err != nil {
+		conditions.SetErrorAndActiveConditions(lumigo, now, err)
+		log.Info("Invalid injection settings", "error", err.Error(), "status", &lumigo.Status)
+		return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	}
+
+	var archivalConfig *telemetryproxyconfigs.ArchivalConfig
+	if isTruthy(lumigo.Spec.Archival.Enabled, false) {
+		if archivalConfig, err = newArchivalConfig(lumigo.Namespace, &lumigo.Spec.Archival.S3); err != nil {
+			conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("invalid archival settings: %w", err))
+			log.Info("Invalid archival settings", "error", err.Error(), "status", &lumigo.Status)
+			return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+		}
+	}
+
+	// The injector image is pinned to the digest whose signature has been verified
+	lumigoInjectorImage := r.LumigoInjectorImage
+	if r.InjectorImageVerifier != nil && isTruthy(lumigo.Spec.Tracing.Injection.Enabled, true) {
+		if lumigoInjectorImage, err = r.InjectorImageVerifier.Verify(ctx, r.LumigoInjectorImage); err != nil {
+			conditions.SetErrorAndActiveConditions(lumigo, now, fmt.Errorf("the signature of the Lumigo injector image cannot be verified: %w", err))
+			log.Info("Cannot verify the signature of the Lumigo injector image", "error", err.Error(), "status", &lumigo.Status)
+			return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+		}
+	}
+
 	if isLumigoJustCreated {
 		log.Info("New Lumigo instance found")
+	}
+
+	if isPaused {
+		if isLumigoJustCreated {
+			log.Info("Lumigo instance is paused, skipping instrumentation from resources in namespace")
+		}
+	} else {
+		r.injectLumigoIntoNamespace(ctx, lumigo, lumigoInjectorImage, isLumigoJustCreated, isResumed, now, &log)
+		conditions.SetPausedCondition(lumigo, now, false, "")
+	}
+
+	r.syncTelemetryProxyMonitoring(ctx, lumigo, namespaceUid, token, lumigo.Status.NamespaceTags, additionalExporters, endpoints, otlpHeaders, archivalConfig, &log, &proxyConfigLog)
+
+	// Allow the traffic of this namespace to and from the telemetry-proxy, if the operator manages NetworkPolicies
+	r.syncNetworkPolicies(ctx, lumigo, true, &log)
+
+	r.syncGoInstrumentation(ctx, lumigo, token, &log)
+
+	r.updateStatusReports(ctx, lumigo, lumigoInjectorImage, now, &log)
+
+	if !isPaused && isTruthy(lumigo.Spec.Tracing.Injection.Enabled, true) {
+		r.verifyTelemetry(ctx, lumigo, now, &log)
+	}
+
+	r.rebindLumigoEvents(ctx, lumigo, &log)
+
+	// Clear errors if any, mark instance as active, all is fine
+	conditions.SetActiveCondition(lumigo, now, true)
+	conditions.ClearErrorCondition(lumigo, now)
+
+	// Update autotraced resource references
+	if r.InstrumentedResources != nil {
+		lumigo.Status.InstrumentedResources = r.InstrumentedResources.Get(lumigo.Namespace)
+	} else {
+		instrumentedResources, err := r.getInstrumentedObjectReferences(ctx, lumigo.Namespace)
+		if err != nil {
+			log.Error(err, "Cannot put together the instrumented resource references")
+			return ctrl.Result{
+				RequeueAfter: defaultErrRequeuePeriod,
+			}, nil
+		}
+
+		lumigo.Status.InstrumentedResources = *instrumentedResources
+	}
+
+	return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+}
+
+// finalizeLumigo removes the instrumentation, and what else the Lumigo instance being deleted has set up in its
+// namespace, as well as its finalizer
+func (r *LumigoReconciler) finalizeLumigo(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, now metav1.Time, log *logr.Logger, proxyConfigLog *logr.Logger) (ctrl.Result, error) {
+	injectionSpec := lumigo.Spec.Tracing.Injection
+
+	isInstrumentationRemoved := false
+	if conditions.IsActive(lumigo) {
+		log.Info("Lumigo instance is being deleted, removing instrumentation from resources in namespace")
+		// A paused Lumigo instance leaves the instrumentation of the resources as it is, even on deletion
+		if isTruthy(injectionSpec.Enabled, true) && isTruthy(injectionSpec.RemoveLumigoFromResourcesOnDeletion, true) && !isTruthy(lumigo.Spec.Paused, false) {
+			if backgroundcleanup.IsBackgroundRemoval(lumigo) {
+				// The deletion of the Lumigo instance, and of the namespace, does not wait for the removal, which is
+				// carried out by the reconciliations of the Lumigo instance once it is gone; so is the one of the token secret
+				if err := backgroundcleanup.RequestCleanup(ctx, r.Clientset.CoreV1(), lumigo, now); err != nil {
+					log.Error(err, "cannot request the background removal of instrumentation from resources", "namespace", lumigo.Namespace)
+					return ctrl.Result{}, err
+				}
+				log.Info("Instrumentation will be removed from resources in namespace in the background")
+			} else if err := r.removeLumigoFromResources(ctx, lumigo, log); err != nil {
+				log.Error(err, "cannot remove instrumentation from resources", "namespace", lumigo.Namespace)
+				return ctrl.Result{}, err
+			} else {
+				isInstrumentationRemoved = true
+			}
+		} else {
+			log.Info(
+				"Lumigo instance is being deleted, but instrumentation from resources in namespace will not be removed",
+				"Injection.Enabled", injectionSpec.Enabled,
+				"Injection.RemoveLumigoFromResourcesOnDeletion", injectionSpec.RemoveLumigoFromResourcesOnDeletion,
+				"Paused", lumigo.Spec.Paused,
+			)
+		}
+	} else {
+		log.Info("Lumigo instance is being deleted, but its status is not active so the instrumentation will not be removed from resources in namespace")
+	}
+
+	// The token secret, and the dedicated telemetry-proxy, are still needed by the resources the instrumentation
+	// has not been removed from
+	if isInstrumentationRemoved {
+		if isChanged, err := tokensecrets.RemoveTokenSecretOfNamespace(ctx, r.Client, lumigo.Namespace, log); err != nil {
+			log.Error(err, "Cannot remove the Lumigo token secret of the namespace")
+		} else if isChanged {
+			log.Info("Removed the Lumigo token secret of the namespace")
+		}
+
+		r.removeDedicatedTelemetryProxy(ctx, lumigo.Namespace, log)
+	}
+
+	// Garbage-collect the copies of the central token secret, or of the token secret of the ancestor namespace
+	if tokendistribution.GetTokenSecretSource(lumigo, r.CentralTokenSecretConfig) != nil {
+		if isChanged, err := tokendistribution.RemoveTokenSecretCopiesOfNamespace(ctx, r.Client, lumigo.Namespace, log); err != nil {
+			log.Error(err, "Cannot remove the copies of the central token secret in the namespace")
+		} else if isChanged {
+			log.Info("Removed the copies of the central token secret in the namespace")
+		}
+	}
+
+	// remove our finalizer from the list and update it.
+	controllerutil.RemoveFinalizer(lumigo, operatorv1alpha1.LumigoResourceFinalizer)
+	if err := r.Update(ctx, lumigo); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update telemetry-proxy not to collect Kube Events for this namespace
+	isChanged, err := telemetryproxyconfigs.RemoveTelemetryProxyMonitoringOfNamespace(ctx, r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace, proxyConfigLog)
+	if err != nil {
+		log.Error(err, "Cannot update the telemetry-proxy configurations to remove the monitoring of the namespace")
+	} else if isChanged {
+		log.Info("Updated the telemetry-proxy configurations to remove the monitoring of the namespace")
+	}
+	r.syncTelemetryProxyDaemonSet(ctx, log)
+	r.syncTelemetryProxyShards(ctx, log)
+
+	// Stop allowing the traffic of this namespace to and from the telemetry-proxy
+	r.syncNetworkPolicies(ctx, lumigo, false, log)
+
+	// Update the Go instrumentation agent not to instrument this namespace
+	if isChanged, err := goinstrumentation.RemoveGoInstrumentationOfNamespace(ctx, r.Client, r.goInstrumentationAgentConfig(), lumigo.Namespace, log); err != nil {
+		log.Error(err, "Cannot update the Go instrumentation agent to remove the instrumentation of the namespace")
+	} else if isChanged {
+		log.Info("Updated the Go instrumentation agent to remove the instrumentation of the namespace")
+	}
+
+	if lumigo.Status.CompatibilityReport != nil {
+		if err := compatibility.RemoveReportConfigMap(ctx, r.Client, lumigo.Namespace); err != nil {
+			log.Error(err, "Cannot remove the compatibility report of the namespace")
+		}
+	}
+
+	// Set the lumigo instance as inactive
+	conditions.SetActiveConditionWithMessage(lumigo, now, false, "This Lumigo instance is being deleted")
+	conditions.ClearErrorCondition(lumigo, now)
+	return r.updateStatusIfNeeded(ctx, *log, lumigo, ctrl.Result{})
+}
+
+// updatePausedCondition tells whether the Lumigo instance is paused, during which the operator does not change the
+// instrumentation of the resources in the namespace, and whether it has just been resumed
+func (r *LumigoReconciler) updatePausedCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) (bool, bool) {
+	isPaused := isTruthy(lumigo.Spec.Paused, false)
+	// The resources created or updated while the Lumigo instance was paused have not been injected by the webhook
+	isResumed := !isPaused && conditions.IsPaused(lumigo)
+	if isPaused {
+		conditions.SetPausedCondition(lumigo, now, true, "This Lumigo instance is paused: the instrumentation of the resources in the namespace is left as it is")
+	}
+
+	return isPaused, isResumed
+}
+
+// syncTokenSecretCopy copies the central token secret into the namespace, or the token secret of the namespace the
+// Lumigo instance is inherited from if propagated by HNC, unless the namespace has its own token secret
+func (r *LumigoReconciler) syncTokenSecretCopy(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) {
+	if tokenSecretSource := tokendistribution.GetTokenSecretSource(lumigo, r.CentralTokenSecretConfig); tokenSecretSource != nil && len(lumigo.Spec.LumigoToken.Value) < 1 {
+		secretRef := lumigo.Spec.LumigoToken.SecretRef
+		if isChanged, err := tokendistribution.SyncTokenSecretCopyOfNamespace(ctx, r.Client, tokenSecretSource, lumigo.Namespace, secretRef.Name, secretRef.Key, log); err != nil {
+			log.Error(err, "Cannot copy the central token secret into the namespace")
+		} else if isChanged {
+			log.Info("Updated the copy of the central token secret in the namespace")
+		}
+	}
+}
+
+// syncTokenSecretOfNamespace projects the tokens into the injected containers, if the token injection mode requires
+// it, keeps the last valid token for when its secret is missing, if the token missing policy requires it, and keeps
+// the tokens set as values in a secret, so that the injected containers do not get them in clear
+func (r *LumigoReconciler) syncTokenSecretOfNamespace(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, token string, routeTokens map[string]string, log *logr.Logger) {
+	if lumigo.Spec.Tracing.Injection.TokenInjectionMode == operatorv1alpha1.TokenInjectionModeProjectedSecret || lumigo.Spec.Tracing.Injection.TokenMissingPolicy == operatorv1alpha1.TokenMissingPolicyKeepInjecting || tokensecrets.HasTokenValues(&lumigo.Spec) {
+		if isChanged, err := tokensecrets.UpsertTokenSecretOfNamespace(ctx, r.Client, lumigo.Namespace, token, routeTokens, log); err != nil {
+			log.Error(err, "Cannot update the Lumigo token secret of the namespace")
+		} else if isChanged {
+			log.Info("Updated the Lumigo token secret of the namespace")
+		}
+	} else if isChanged, err := tokensecrets.RemoveTokenSecretOfNamespace(ctx, r.Client, lumigo.Namespace, log); err != nil {
+		log.Error(err, "Cannot remove the Lumigo token secret of the namespace")
+	} else if isChanged {
+		log.Info(
+			"Removed the Lumigo token secret of the namespace",
+			"Tracing.Injection.TokenInjectionMode", lumigo.Spec.Tracing.Injection.TokenInjectionMode,
+		)
+	}
+}
+
+// updateInjectionStatus keeps in the status what the injector webhook layers over the spec of the Lumigo instance
+// when injecting the workloads of the namespace
+func (r *LumigoReconciler) updateInjectionStatus(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, namespace *corev1.Namespace, log *logr.Logger) error {
+	// The env imported from the Instrumentation of the OpenTelemetry operator is kept in the status, so that the
+	// injector webhook, whose mutator is cached by resource version, picks up the changes of the Instrumentation
+	// Without the CRDs of the OpenTelemetry operator, the reference is reported in the UnsupportedOnThisCluster condition
+	if ref := lumigo.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef; ref != nil && r.hasCapability(capabilities.OpenTelemetryInstrumentations) {
+		importedEnv, err := otelinstrumentation.GetImportedEnv(ctx, r.DynamicClient, ref, lumigo.Namespace)
+		if err != nil {
+			return fmt.Errorf("invalid OpenTelemetry Instrumentation reference: %w", err)
+		}
+		lumigo.Status.ImportedEnv = importedEnv
+	} else {
+		lumigo.Status.ImportedEnv = nil
+	}
+
+	// Likewise, the tags of the namespace are kept in the status for the injector webhook
+	namespaceTags, invalidTagAnnotations := namespacetags.GetNamespaceTags(namespace)
+	if len(invalidTagAnnotations) > 0 {
+		log.Info("Ignoring the tag annotations of the namespace with invalid keys or values", "annotations", invalidTagAnnotations)
+	}
+	lumigo.Status.NamespaceTags = namespaceTags
+
+	// The sampling of the namespace is layered over the cluster-wide one of the injector defaults, and the
+	// result is published so that the teams can tell which sampling their workloads are injected with
+	lumigo.Status.EffectiveSampling = mutation.EffectiveSampling(&lumigo.Spec, r.InjectorDefaults)
+
+	return nil
+}
+
+// injectLumigoIntoNamespace injects the resources of the namespace that are queued, deferred, not yet injected since
+// the Lumigo instance has been created or resumed, or whose injection failed earlier on
+func (r *LumigoReconciler) injectLumigoIntoNamespace(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, isLumigoJustCreated bool, isResumed bool, now metav1.Time, log *logr.Logger) {
+	// The workloads updated in this reconciliation are limited, and the queued ones go first
+	budget := workloadpacing.NewBudget(r.WorkloadUpdatePacer, lumigo.Spec.Tracing.Injection.MaxConcurrentWorkloadUpdates)
+	r.injectPendingInjections(ctx, lumigo, lumigoInjectorImage, budget, now, log)
+
+	// Inject the paused and suspended workloads whose injection has been deferred, once they are resumed
+	r.injectDeferredInjections(ctx, lumigo, lumigoInjectorImage, budget, now, log)
+
+	// The injection of the existing resources of large namespaces is carried out over multiple reconciliations
+	if isLumigoJustCreated || isResumed || lumigo.Status.InjectionProgress != nil {
+		if isResumed {
+			log.Info("Lumigo instance has been resumed")
+		}
+
+		if isLumigoJustCreated || isResumed {
+			lumigo.Status.InjectionProgress = nil
+		}
+
 		injectionSpec := lumigo.Spec.Tracing.Injection
 		if isTruthy(injectionSpec.Enabled, true) && isTruthy(injectionSpec.InjectLumigoIntoExistingResourcesOnCreation, true) {
-			log.Info("Injecting instrumentation into resources in namespace")
-			if err := r.injectLumigoIntoResources(ctx, lumigo, &log); err != nil {
+			log.Info("Injecting instrumentation into resources in namespace", "progress", lumigo.Status.InjectionProgress)
+			if err := r.injectLumigoIntoResources(ctx, lumigo, lumigoInjectorImage, budget, now, log); err != nil {
 				log.Error(err, "cannot inject resources")
 			}
 		} else {
+			lumigo.Status.InjectionProgress = nil
 			log.Info(
 				"Skipping instrumentation from resources in namespace",
 				"Injection.Enabled", injectionSpec.Enabled,
@@ -255,29 +700,238 @@ func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
-	// Update telemetry-proxy to ensure that Kube Events are collected correctly for this namespace
-	if isTruthy(lumigo.Spec.Infrastructure.Enabled, true) && isTruthy(lumigo.Spec.Infrastructure.KubeEvents.Enabled, true) {
-		isChanged, err := telemetryproxyconfigs.UpsertTelemetryProxyMonitoringOfNamespace(ctx, r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace, namespaceUid, token, &log)
+	// Retry the injection of the resources that could not be injected earlier on, once their backoff has elapsed
+	r.retryFailedInjections(ctx, lumigo, lumigoInjectorImage, budget, now, log)
+
+	// Add the injection annotations to resources injected by earlier versions of the operator
+	if err := r.repairInjectionAnnotations(ctx, lumigo, log); err != nil {
+		log.Error(err, "Cannot repair the injection annotations of resources in namespace")
+	}
+}
+
+// syncTelemetryProxyMonitoring updates the shared telemetry-proxy, and the dedicated one if requested, to ensure that
+// Kube Events, node lifecycle events, Prometheus metrics and span metrics are collected correctly for the namespace
+func (r *LumigoReconciler) syncTelemetryProxyMonitoring(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, namespaceUid string, token string, namespaceTags map[string]string, additionalExporters []telemetryproxyconfigs.OtlpExporterConfig, endpoints *telemetryproxyconfigs.EndpointsConfig, otlpHeaders map[string]string, archivalConfig *telemetryproxyconfigs.ArchivalConfig, log *logr.Logger, proxyConfigLog *logr.Logger) {
+	pipelinesSpec := lumigo.Spec.Pipelines
+	tracesEnabled := isTruthy(pipelinesSpec.Traces.Enabled, true)
+	logsEnabled := isTruthy(pipelinesSpec.Logs.Enabled, true)
+	metricsEnabled := isTruthy(pipelinesSpec.Metrics.Enabled, true)
+	infrastructureSpec := lumigo.Spec.Infrastructure
+	infrastructureEnabled := isTruthy(infrastructureSpec.Enabled, true)
+	kubeEventsEnabled := infrastructureEnabled && isTruthy(infrastructureSpec.KubeEvents.Enabled, true)
+	prometheusEnabled := metricsEnabled && infrastructureEnabled && isTruthy(infrastructureSpec.Prometheus.Enabled, false)
+	nodeLifecycleEnabled := infrastructureEnabled && isTruthy(infrastructureSpec.NodeLifecycle.Enabled, false)
+	spanMetricsEnabled := tracesEnabled && metricsEnabled && isTruthy(lumigo.Spec.Tracing.SpanMetrics.Enabled, false)
+	rateLimitingEnabled := lumigo.Spec.Tracing.MaxSpansPerSecond != nil
+	quotaConfig := newQuotaConfig(&lumigo.Spec.Quota)
+	debugEnabled := isTruthy(lumigo.Spec.Debug.LogTelemetry, false)
+	awsXRayEnabled := tracesEnabled && isTruthy(lumigo.Spec.Tracing.AwsXRay.Enabled, false)
+	namespaceMonitoringConfig := &telemetryproxyconfigs.NamespaceMonitoringConfig{
+		Name:                lumigo.Namespace,
+		Uid:                 namespaceUid,
+		Token:               token,
+		KubeEventsDisabled:  !kubeEventsEnabled,
+		TracesDisabled:      !tracesEnabled,
+		LogsDisabled:        !logsEnabled,
+		MetricsDisabled:     !metricsEnabled,
+		NodeLifecycle:       nodeLifecycleEnabled,
+		Quota:               quotaConfig,
+		Debug:               debugEnabled,
+		AdditionalExporters: additionalExporters,
+		Archival:            archivalConfig,
+		Endpoints:           endpoints,
+		AwsXRay:             awsXRayEnabled,
+		SkipHostsRegex:      mutation.SkipDomainsHostsRegex(lumigo.Spec.Tracing.SkipDomains),
+		Tags:                namespaceTags,
+		OtlpHeaders:         otlpHeaders,
+	}
+	if prometheusEnabled {
+		namespaceMonitoringConfig.Prometheus = newPrometheusScrapeConfig(&infrastructureSpec.Prometheus)
+	}
+	if spanMetricsEnabled {
+		namespaceMonitoringConfig.SpanMetrics = &telemetryproxyconfigs.SpanMetricsConfig{
+			Dimensions: lumigo.Spec.Tracing.SpanMetrics.Dimensions,
+		}
+	}
+	if rateLimitingEnabled {
+		namespaceMonitoringConfig.MaxSpansPerSecond = *lumigo.Spec.Tracing.MaxSpansPerSecond
+	}
+
+	if kubeEventsEnabled || nodeLifecycleEnabled || prometheusEnabled || spanMetricsEnabled || rateLimitingEnabled || quotaConfig != nil || debugEnabled || awsXRayEnabled || len(additionalExporters) > 0 || endpoints != nil || archivalConfig != nil || len(namespaceTags) > 0 || !tracesEnabled || !logsEnabled || !metricsEnabled {
+		_, proxyConfigSpan := r.SelfTelemetry.StartSpan(ctx, "Upsert telemetry-proxy configuration")
+		isChanged, err := telemetryproxyconfigs.UpsertTelemetryProxyMonitoringConfigOfNamespace(ctx, r.TelemetryProxyNamespaceConfigurationsPath, namespaceMonitoringConfig, proxyConfigLog)
+		proxyConfigSpan.RecordError(err)
+		proxyConfigSpan.End()
 		if err != nil {
 			log.Error(err, "Cannot update the telemetry-proxy configurations to monitor the namespace")
 		} else if isChanged {
 			log.Info("Updated the telemetry-proxy configurations to monitor the namespace")
 		}
 	} else {
-		if _, err := telemetryproxyconfigs.RemoveTelemetryProxyMonitoringOfNamespace(ctx, r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace, &log); err != nil {
+		_, proxyConfigSpan := r.SelfTelemetry.StartSpan(ctx, "Remove telemetry-proxy configuration")
+		_, err := telemetryproxyconfigs.RemoveTelemetryProxyMonitoringOfNamespace(ctx, r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace, proxyConfigLog)
+		proxyConfigSpan.RecordError(err)
+		proxyConfigSpan.End()
+		if err != nil {
 			log.Error(err, "Cannot update the telemetry-proxy configurations to remove the monitoring of the namespace")
 		} else {
 			log.Info(
 				"Removing infrastructure monitoring of the namespace",
 				"Infrastructure.Enabled", lumigo.Spec.Infrastructure.Enabled,
 				"Infrastructure.KubeEvents.Enabled", lumigo.Spec.Infrastructure.KubeEvents.Enabled,
+				"Infrastructure.NodeLifecycle.Enabled", lumigo.Spec.Infrastructure.NodeLifecycle.Enabled,
+				"Infrastructure.Prometheus.Enabled", lumigo.Spec.Infrastructure.Prometheus.Enabled,
+				"Tracing.SpanMetrics.Enabled", lumigo.Spec.Tracing.SpanMetrics.Enabled,
+				"Tracing.MaxSpansPerSecond", lumigo.Spec.Tracing.MaxSpansPerSecond,
+				"Quota", lumigo.Spec.Quota,
+				"Debug.LogTelemetry", lumigo.Spec.Debug.LogTelemetry,
+				"Tracing.AdditionalExporters", lumigo.Spec.Tracing.AdditionalExporters,
+				"Endpoints", lumigo.Spec.Endpoints,
+				"Tracing.AwsXRay.Enabled", lumigo.Spec.Tracing.AwsXRay.Enabled,
+				"Archival.Enabled", lumigo.Spec.Archival.Enabled,
 			)
 		}
 	}
 
-	// Validate that Lumigo events are all correctly associated with objects,
-	// as the webhook cannot correctly associate events with objects that do
-	// not yet exist.
+	// Propagate the namespace configurations to the telemetry-proxy DaemonSet or shards, if any
+	r.syncTelemetryProxyDaemonSet(ctx, log)
+	r.syncTelemetryProxyShards(ctx, log)
+
+	// Deploy the dedicated telemetry-proxy of the namespace, if requested; it receives the traces and logs of the
+	// namespace, while the cluster-wide telemetry of the namespace is still collected by the shared telemetry-proxy
+	if telemetryproxydedicated.IsRequested(lumigo) {
+		if isChanged, err := telemetryproxydedicated.UpsertDedicatedProxyOfNamespace(ctx, r.Client, r.DedicatedTelemetryProxyConfig, namespaceMonitoringConfig, log); err != nil {
+			log.Error(err, "Cannot update the dedicated telemetry-proxy of the namespace")
+		} else if isChanged {
+			log.Info("Updated the dedicated telemetry-proxy of the namespace")
+		}
+	} else {
+		r.removeDedicatedTelemetryProxy(ctx, lumigo.Namespace, log)
+	}
+}
+
+// syncGoInstrumentation updates the Go instrumentation agent to instrument the Go processes of the namespace; the agent is a
+// privileged DaemonSet, which restricted platforms do not run, in which case the namespace is not instrumented
+func (r *LumigoReconciler) syncGoInstrumentation(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, token string, log *logr.Logger) {
+	if isTruthy(lumigo.Spec.Tracing.GoInstrumentation.Enabled, false) && r.Platform.Supports(platform.FeatureGoInstrumentation) {
+		isChanged, err := goinstrumentation.UpsertGoInstrumentationOfNamespace(ctx, r.Client, r.goInstrumentationAgentConfig(), lumigo.Namespace, token, log)
+		if err != nil {
+			log.Error(err, "Cannot update the Go instrumentation agent to instrument the namespace")
+		} else if isChanged {
+			log.Info("Updated the Go instrumentation agent to instrument the namespace")
+		}
+	} else if isChanged, err := goinstrumentation.RemoveGoInstrumentationOfNamespace(ctx, r.Client, r.goInstrumentationAgentConfig(), lumigo.Namespace, log); err != nil {
+		log.Error(err, "Cannot update the Go instrumentation agent to remove the instrumentation of the namespace")
+	} else if isChanged {
+		log.Info(
+			"Removed Go instrumentation of the namespace",
+			"Tracing.GoInstrumentation.Enabled", lumigo.Spec.Tracing.GoInstrumentation.Enabled,
+		)
+	}
+}
+
+// verifyTelemetry runs, when due, the Job that sends a synthetic trace of the namespace to its telemetry-proxy,
+// and reports in the TelemetryVerified condition whether the telemetry-proxy has sent it on to Lumigo. The
+// metrics of only the telemetry-proxy next to the controller are scraped, so the telemetry sent to the other
+// ones, i.e., the dedicated, sharded and node-local telemetry-proxies, cannot be verified.
+func (r *LumigoReconciler) verifyTelemetry(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, now metav1.Time, log *logr.Logger) {
+	if !telemetryverification.IsEnabled(lumigo) {
+		if isDeleted, err := telemetryverification.DeleteJob(ctx, r.Client, lumigo.Namespace); err != nil {
+			log.Error(err, "Cannot delete the telemetry verification Job")
+		} else if isDeleted {
+			log.Info("Deleted the telemetry verification Job, as the telemetry verification is disabled")
+		}
+		conditions.RemoveCondition(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryVerified)
+		lumigo.Status.TelemetryVerifiedGeneration = 0
+		return
+	}
+
+	tracesUrl, _ := r.telemetryProxyOtlpServiceUrls(lumigo)
+	if r.TelemetryVerificationConfig == nil || r.TelemetryProxyExportMonitor == nil {
+		conditions.SetTelemetryVerifiedCondition(lumigo, now, false, "The telemetry cannot be verified, as the operator has not been installed with the telemetry verification")
+		lumigo.Status.TelemetryVerifiedGeneration = lumigo.Generation
+		return
+	} else if r.TelemetryProxyDaemonSetConfig != nil || tracesUrl != r.TelemetryProxyOtlpServiceUrl {
+		conditions.SetTelemetryVerifiedCondition(lumigo, now, false, "The telemetry cannot be verified, as the namespace sends it to a dedicated, sharded or node-local telemetry-proxy")
+		lumigo.Status.TelemetryVerifiedGeneration = lumigo.Generation
+		return
+	}
+
+	job, err := telemetryverification.GetJob(ctx, r.Client, lumigo.Namespace)
+	if err != nil {
+		log.Error(err, "Cannot look up the telemetry verification Job")
+		return
+	}
+
+	sentSpans := r.TelemetryProxyExportMonitor.GetNamespaceSentSpans(lumigo.Namespace)
+	if job == nil {
+		if !telemetryverification.IsDue(lumigo, now.Time) {
+			return
+		}
+
+		tokenSecretRef := injectionspec.EffectiveSpec(lumigo).LumigoToken.SecretRef
+		job = telemetryverification.NewJob(r.TelemetryVerificationConfig, lumigo, tracesUrl, &tokenSecretRef, sentSpans)
+		if err := r.Client.Create(ctx, job); err != nil {
+			log.Error(err, "Cannot create the telemetry verification Job")
+		} else {
+			log.Info("Created the telemetry verification Job", "generation", lumigo.Generation)
+		}
+		return
+	}
+
+	isDone, isVerified, message := telemetryverification.Evaluate(job, sentSpans, now.Time)
+	if !isDone {
+		return
+	}
+
+	conditions.SetTelemetryVerifiedCondition(lumigo, now, isVerified, message)
+	lumigo.Status.TelemetryVerifiedGeneration = telemetryverification.GetVerifiedGeneration(job)
+	log.Info("Verified the telemetry of the namespace", "verified", isVerified, "message", message)
+
+	if _, err := telemetryverification.DeleteJob(ctx, r.Client, lumigo.Namespace); err != nil {
+		log.Error(err, "Cannot delete the telemetry verification Job")
+	}
+}
+
+// updateStatusReports updates the conditions and reports that tell how the telemetry of the namespace fares
+func (r *LumigoReconciler) updateStatusReports(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, now metav1.Time, log *logr.Logger) {
+	// Report whether the telemetry-proxy has recently dropped spans of this namespace
+	r.updateRateLimitedCondition(lumigo, now, log)
+
+	// Report whether the namespace has exceeded its daily quota
+	r.updateQuotaExceededCondition(lumigo, now, log)
+
+	// Report whether the telemetry-proxy is failing to send the telemetry of this namespace to Lumigo
+	r.updateTelemetryExportDegradedCondition(ctx, lumigo, now, log)
+
+	// Report how much telemetry of this namespace the telemetry-proxy has sent to Lumigo in the latest days
+	r.updateDailyUsage(lumigo)
+
+	// Report whether the Lumigo backend is reachable and accepting the telemetry of the cluster
+	r.updateBackendUnreachableCondition(ctx, lumigo, now)
+
+	// Report whether the workloads of the namespace can reach the telemetry-proxy the injection sends them to
+	r.updateTelemetryProxyUnreachableCondition(ctx, lumigo, now)
+
+	// Report the injected pods of the namespace whose `lumigo-injector` init container fails
+	r.updateInjectionRuntimeFailuresCondition(lumigo, now)
+
+	// Report the enabled features that the platform the operator runs on does not support
+	r.updateUnsupportedFeaturesCondition(lumigo, now)
+
+	// Report the settings that rely on API resources the cluster does not serve
+	r.updateUnsupportedOnThisClusterCondition(lumigo, now)
+
+	// Report the settings of the spec that contradict each other or have no effect
+	r.updateInconsistentSpecCondition(lumigo, now)
+
+	// Report which workloads of the namespace can be injected, whether or not the injection is enabled
+	r.updateCompatibilityReport(ctx, lumigo, lumigoInjectorImage, now, log)
+}
+
+// rebindLumigoEvents associates the Lumigo events with their objects, as the webhook cannot correctly
+// associate events with objects that do not yet exist
+func (r *LumigoReconciler) rebindLumigoEvents(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) {
 	namespaceEvents := r.Clientset.CoreV1().Events(lumigo.Namespace)
 	lumigoEvents, err := namespaceEvents.List(ctx, metav1.ListOptions{
 		// Get all LumigoAddedInstrumentation events without the UID of the involved object
@@ -300,65 +954,183 @@ func (r *LumigoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			}
 		}
 	}
+}
 
-	// Clear errors if any, mark instance as active, all is fine
-	conditions.SetActiveCondition(lumigo, now, true)
-	conditions.ClearErrorCondition(lumigo, now)
+func (r *LumigoReconciler) goInstrumentationAgentConfig() *goinstrumentation.AgentConfig {
+	return &goinstrumentation.AgentConfig{
+		Namespace:                    r.LumigoOperatorNamespace,
+		ServiceAccountName:           r.LumigoOperatorServiceAccountName,
+		Image:                        r.GoInstrumentationAgentImage,
+		TelemetryProxyOtlpServiceUrl: r.TelemetryProxyOtlpServiceUrl,
+		LumigoOperatorVersion:        r.LumigoOperatorVersion,
+	}
+}
 
-	var instrumentedResources *[]corev1.ObjectReference
-	// Update autotraced resource references
-	if instrumentedResources, err = r.getInstrumentedObjectReferences(ctx, lumigo.Namespace); err != nil {
-		log.Error(err, "Cannot put together the instrumented resource references")
-		return ctrl.Result{
-			RequeueAfter: defaultErrRequeuePeriod,
-		}, nil
+func (r *LumigoReconciler) syncTelemetryProxyDaemonSet(ctx context.Context, log *logr.Logger) {
+	if r.TelemetryProxyDaemonSetConfig == nil {
+		return
 	}
 
-	lumigo.Status.InstrumentedResources = *instrumentedResources
-	return r.updateStatusIfNeeded(ctx, log, lumigo, result)
+	if isChanged, err := telemetryproxydaemonset.SyncTelemetryProxyDaemonSet(ctx, r.Client, r.TelemetryProxyDaemonSetConfig, r.TelemetryProxyNamespaceConfigurationsPath, log); err != nil {
+		log.Error(err, "Cannot update the telemetry-proxy DaemonSet")
+	} else if isChanged {
+		log.Info("Updated the telemetry-proxy DaemonSet")
+	}
 }
 
-func (r *LumigoReconciler) rebindLumigoEvent(ctx context.Context, eventInterface v1.EventInterface, event *corev1.Event) error {
-	if err := r.fillOutReference(ctx, &event.InvolvedObject); err != nil {
-		return fmt.Errorf("cannot fill out the 'InvolvedObject' reference: %w", err)
+func (r *LumigoReconciler) syncTelemetryProxyShards(ctx context.Context, log *logr.Logger) {
+	if r.TelemetryProxyShardsConfig == nil {
+		return
 	}
 
-	if _, err := eventInterface.Update(ctx, event, metav1.UpdateOptions{}); err != nil {
-		return fmt.Errorf("cannot update the dangling event %v: %w", event.UID, err)
+	if isChanged, err := telemetryproxyshards.SyncTelemetryProxyShards(ctx, r.Client, r.TelemetryProxyShardsConfig, r.TelemetryProxyNamespaceConfigurationsPath, log); err != nil {
+		log.Error(err, "Cannot update the telemetry-proxy shards")
+	} else if isChanged {
+		log.Info("Updated the telemetry-proxy shards")
 	}
-
-	return nil
 }
 
-func (r *LumigoReconciler) fillOutReference(ctx context.Context, reference *corev1.ObjectReference) error {
-	var schema schema.GroupVersion
-	switch reference.APIVersion {
-	case "apps/v1":
-		schema = appsv1.SchemeGroupVersion
-	case "batch/v1":
-		schema = batchv1.SchemeGroupVersion
-	default:
-		return fmt.Errorf("unexpected APIVersion for referenced object: '%s'", reference.APIVersion)
+func (r *LumigoReconciler) removeDedicatedTelemetryProxy(ctx context.Context, namespace string, log *logr.Logger) {
+	if r.DedicatedTelemetryProxyConfig == nil {
+		return
 	}
 
-	obj, err := r.DynamicClient.Resource(schema.WithResource(strings.ToLower(reference.Kind)+"s")).Namespace(reference.Namespace).Get(ctx, reference.Name, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("cannot retrieve '%s/%s' %s: %w", reference.Namespace, reference.Name, reference.Kind, err)
+	if isChanged, err := telemetryproxydedicated.RemoveDedicatedProxyOfNamespace(ctx, r.Client, r.DedicatedTelemetryProxyConfig, namespace, log); err != nil {
+		log.Error(err, "Cannot remove the dedicated telemetry-proxy of the namespace")
+	} else if isChanged {
+		log.Info("Removed the dedicated telemetry-proxy of the namespace")
 	}
+}
 
-	reference.UID = obj.GetUID()
-	reference.ResourceVersion = obj.GetResourceVersion()
+// The URLs to which the workloads of the namespace send traces and logs, which depend on the namespace if it has
+// a dedicated telemetry-proxy, or if the telemetry-proxy is sharded
+func (r *LumigoReconciler) telemetryProxyOtlpServiceUrls(lumigo *operatorv1alpha1.Lumigo) (string, string) {
+	if r.DedicatedTelemetryProxyConfig != nil && telemetryproxydedicated.IsRequested(lumigo) {
+		return telemetryproxydedicated.OtlpServiceUrls(lumigo.Namespace)
+	}
 
-	return nil
+	return telemetryproxyshards.OtlpServiceUrls(r.TelemetryProxyShardsConfig, lumigo.Namespace, r.TelemetryProxyOtlpServiceUrl, r.TelemetryProxyOtlpLogsServiceUrl)
 }
 
-// Check credentials existence
-func (r *LumigoReconciler) validateCredentials(ctx context.Context, namespaceName string, credentials *operatorv1alpha1.Credentials) (string, error) {
-	if credentials.SecretRef == (operatorv1alpha1.KubernetesSecretRef{}) {
-		return "", fmt.Errorf("no Kubernetes secret reference provided")
+func (r *LumigoReconciler) syncNetworkPolicies(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, isNamespaceMonitored bool, log *logr.Logger) {
+	if r.NetworkPoliciesConfig == nil {
+		return
 	}
 
-	if credentials.SecretRef.Name == "" {
+	if isNamespaceMonitored {
+		if isChanged, err := networkpolicies.UpsertNetworkPolicyOfNamespace(ctx, r.Client, r.NetworkPoliciesConfig, lumigo.Namespace, log); err != nil {
+			log.Error(err, "Cannot update the NetworkPolicy of the namespace")
+		} else if isChanged {
+			log.Info("Updated the NetworkPolicy of the namespace")
+		}
+	} else if isChanged, err := networkpolicies.RemoveNetworkPolicyOfNamespace(ctx, r.Client, lumigo.Namespace, log); err != nil {
+		log.Error(err, "Cannot remove the NetworkPolicy of the namespace")
+	} else if isChanged {
+		log.Info("Removed the NetworkPolicy of the namespace")
+	}
+
+	if isNamespaceMonitored && r.DedicatedTelemetryProxyConfig != nil && telemetryproxydedicated.IsRequested(lumigo) {
+		if isChanged, err := networkpolicies.UpsertDedicatedTelemetryProxyNetworkPolicy(ctx, r.Client, r.NetworkPoliciesConfig, lumigo.Namespace, log); err != nil {
+			log.Error(err, "Cannot update the NetworkPolicy of the dedicated telemetry-proxy of the namespace")
+		} else if isChanged {
+			log.Info("Updated the NetworkPolicy of the dedicated telemetry-proxy of the namespace")
+		}
+	} else if isChanged, err := networkpolicies.RemoveDedicatedTelemetryProxyNetworkPolicy(ctx, r.Client, lumigo.Namespace, log); err != nil {
+		log.Error(err, "Cannot remove the NetworkPolicy of the dedicated telemetry-proxy of the namespace")
+	} else if isChanged {
+		log.Info("Removed the NetworkPolicy of the dedicated telemetry-proxy of the namespace")
+	}
+
+	// The namespaces file of the telemetry-proxy does not list the namespaces that are only traced,
+	// so we look up all the Lumigo instances in the cluster instead
+	lumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(ctx, lumigoes); err != nil {
+		log.Error(err, "Cannot list the Lumigo instances to update the NetworkPolicies of the operator")
+		return
+	}
+
+	monitoredNamespaces := &networkpolicies.MonitoredNamespaces{
+		Names:                  []string{},
+		PrometheusScrapedNames: []string{},
+	}
+	for _, l := range lumigoes.Items {
+		if !l.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		monitoredNamespaces.Names = append(monitoredNamespaces.Names, l.Namespace)
+		if isTruthy(l.Spec.Infrastructure.Enabled, true) && isTruthy(l.Spec.Infrastructure.Prometheus.Enabled, false) {
+			monitoredNamespaces.PrometheusScrapedNames = append(monitoredNamespaces.PrometheusScrapedNames, l.Namespace)
+		}
+	}
+	// Keep the NetworkPolicies stable regardless of the order in which the Lumigo instances are listed
+	sort.Strings(monitoredNamespaces.Names)
+	sort.Strings(monitoredNamespaces.PrometheusScrapedNames)
+
+	if isChanged, err := networkpolicies.SyncOperatorNetworkPolicies(ctx, r.Client, r.NetworkPoliciesConfig, monitoredNamespaces, log); err != nil {
+		log.Error(err, "Cannot update the NetworkPolicies of the operator")
+	} else if isChanged {
+		log.Info("Updated the NetworkPolicies of the operator")
+	}
+}
+
+func (r *LumigoReconciler) rebindLumigoEvent(ctx context.Context, eventInterface v1.EventInterface, event *corev1.Event) error {
+	if err := r.fillOutReference(ctx, &event.InvolvedObject); err != nil {
+		return fmt.Errorf("cannot fill out the 'InvolvedObject' reference: %w", err)
+	}
+
+	if _, err := eventInterface.Update(ctx, event, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("cannot update the dangling event %v: %w", event.UID, err)
+	}
+
+	return nil
+}
+
+func (r *LumigoReconciler) fillOutReference(ctx context.Context, reference *corev1.ObjectReference) error {
+	var schema schema.GroupVersion
+	switch reference.APIVersion {
+	case "apps/v1":
+		schema = appsv1.SchemeGroupVersion
+	case "batch/v1":
+		schema = batchv1.SchemeGroupVersion
+	default:
+		return fmt.Errorf("unexpected APIVersion for referenced object: '%s'", reference.APIVersion)
+	}
+
+	obj, err := r.DynamicClient.Resource(schema.WithResource(strings.ToLower(reference.Kind)+"s")).Namespace(reference.Namespace).Get(ctx, reference.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("cannot retrieve '%s/%s' %s: %w", reference.Namespace, reference.Name, reference.Kind, err)
+	}
+
+	reference.UID = obj.GetUID()
+	reference.ResourceVersion = obj.GetResourceVersion()
+
+	return nil
+}
+
+// Check credentials existence
+func (r *LumigoReconciler) validateCredentials(ctx context.Context, namespaceName string, credentials *operatorv1alpha1.Credentials) (string, error) {
+	// The token set as value is never part of the errors, which end up in the conditions and the logs
+	if len(credentials.Value) > 0 {
+		if credentials.SecretRef != (operatorv1alpha1.KubernetesSecretRef{}) {
+			return "", fmt.Errorf("both a Kubernetes secret reference and a value are provided, but only one of them is allowed")
+		}
+
+		if !tokensecrets.IsWellFormedToken(credentials.Value) {
+			return "", fmt.Errorf(
+				"the value does not match the expected structure of Lumigo tokens: it should be `t_` followed by 21 " +
+					"alphanumeric characters; see https://docs.lumigo.io/docs/lumigo-tokens for instructions on how to " +
+					"retrieve your Lumigo token")
+		}
+
+		return credentials.Value, nil
+	}
+
+	if credentials.SecretRef == (operatorv1alpha1.KubernetesSecretRef{}) {
+		return "", fmt.Errorf("no Kubernetes secret reference provided")
+	}
+
+	if credentials.SecretRef.Name == "" {
 		return "", fmt.Errorf("cannot the secret name is not specified")
 	}
 
@@ -379,14 +1151,7 @@ func (r *LumigoReconciler) validateCredentials(ctx context.Context, namespaceNam
 
 	lumigoToken := string(lumigoTokenEnc)
 
-	matched, err := regexp.MatchString(`t_[[:xdigit:]]{21}`, lumigoToken)
-	if err != nil {
-		return "", fmt.Errorf(
-			"cannot match the value the field '%s' of the secret '%s/%s' against "+
-				"the expected structure of Lumigo tokens", credentials.SecretRef.Key, namespaceName, credentials.SecretRef.Name)
-	}
-
-	if !matched {
+	if !tokensecrets.IsWellFormedToken(lumigoToken) {
 		return "", fmt.Errorf(
 			"the value of the field '%s' of the secret '%s/%s' does not match the expected structure of Lumigo tokens: "+
 				"it should be `t_` followed by 21 alphanumeric characters; see https://docs.lumigo.io/docs/lumigo-tokens "+
@@ -397,6 +1162,132 @@ func (r *LumigoReconciler) validateCredentials(ctx context.Context, namespaceNam
 	return lumigoToken, nil
 }
 
+// Resolves the Lumigo tokens of the routes, by route name, from the secrets they reference
+func (r *LumigoReconciler) resolveRouteTokens(ctx context.Context, namespaceName string, routes []operatorv1alpha1.TracingRoute) (map[string]string, error) {
+	routeTokens := make(map[string]string, len(routes))
+	for i := range routes {
+		token, err := r.validateCredentials(ctx, namespaceName, &routes[i].LumigoToken)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Lumigo token secret reference of the route '%s': %w", routes[i].Name, err)
+		}
+
+		routeTokens[routes[i].Name] = token
+	}
+
+	return routeTokens, nil
+}
+
+// Resolves the headers of the additional exporters from the secrets they reference
+func (r *LumigoReconciler) resolveAdditionalExporters(ctx context.Context, namespaceName string, additionalExporters []operatorv1alpha1.OtlpExporterSpec) ([]telemetryproxyconfigs.OtlpExporterConfig, error) {
+	exporterConfigs := make([]telemetryproxyconfigs.OtlpExporterConfig, 0, len(additionalExporters))
+	for _, additionalExporter := range additionalExporters {
+		exporterConfig := telemetryproxyconfigs.OtlpExporterConfig{
+			Name:     additionalExporter.Name,
+			Endpoint: additionalExporter.Endpoint,
+		}
+
+		headers, err := r.resolveHeaders(ctx, namespaceName, additionalExporter.HeadersSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("%w of the exporter '%s'", err, additionalExporter.Name)
+		}
+		exporterConfig.Headers = headers
+
+		exporterConfigs = append(exporterConfigs, exporterConfig)
+	}
+
+	return exporterConfigs, nil
+}
+
+// Resolves the headers of the endpoints by signal from the secrets they reference; nil if no signal has an endpoint
+func (r *LumigoReconciler) resolveEndpoints(ctx context.Context, namespaceName string, endpoints *operatorv1alpha1.EndpointsSpec) (*telemetryproxyconfigs.EndpointsConfig, error) {
+	if endpoints.Traces == nil && endpoints.Logs == nil && endpoints.Metrics == nil {
+		return nil, nil
+	}
+
+	resolveEndpoint := func(signal string, endpoint *operatorv1alpha1.OtlpEndpointSpec) (*telemetryproxyconfigs.OtlpEndpointConfig, error) {
+		if endpoint == nil {
+			return nil, nil
+		}
+
+		headers, err := r.resolveHeaders(ctx, namespaceName, endpoint.HeadersSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("%w of the %s endpoint", err, signal)
+		}
+
+		return &telemetryproxyconfigs.OtlpEndpointConfig{
+			Endpoint: endpoint.Url,
+			Headers:  headers,
+		}, nil
+	}
+
+	endpointsConfig := &telemetryproxyconfigs.EndpointsConfig{}
+	var err error
+	if endpointsConfig.Traces, err = resolveEndpoint("traces", endpoints.Traces); err != nil {
+		return nil, err
+	}
+	if endpointsConfig.Logs, err = resolveEndpoint("logs", endpoints.Logs); err != nil {
+		return nil, err
+	}
+	if endpointsConfig.Metrics, err = resolveEndpoint("metrics", endpoints.Metrics); err != nil {
+		return nil, err
+	}
+
+	return endpointsConfig, nil
+}
+
+// Resolves the HTTP headers in the referenced secret, each key of which is a header; nil if no secret is referenced
+func (r *LumigoReconciler) resolveHeaders(ctx context.Context, namespaceName string, secretRef *corev1.LocalObjectReference) (map[string]string, error) {
+	if secretRef == nil {
+		return nil, nil
+	}
+
+	secret, err := r.fetchKubernetesSecret(ctx, namespaceName, secretRef.Name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve secret '%s/%s' with the headers", namespaceName, secretRef.Name)
+	}
+
+	headers := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		headers[key] = string(value)
+	}
+
+	return headers, nil
+}
+
+// Resolves the values of the OTLP headers, by header name, from the secret keys they reference; nil if there are none.
+// The surrounding whitespace of the values, e.g., the trailing newline of secrets created from files, is trimmed.
+func (r *LumigoReconciler) resolveOtlpHeaders(ctx context.Context, namespaceName string, otlpHeaders []operatorv1alpha1.OtlpHeaderSpec) (map[string]string, error) {
+	if len(otlpHeaders) < 1 {
+		return nil, nil
+	}
+
+	headers := make(map[string]string, len(otlpHeaders))
+	for _, otlpHeader := range otlpHeaders {
+		// The telemetry-proxy authenticates the telemetry to Lumigo with the Lumigo token in the `Authorization` header
+		if strings.EqualFold(otlpHeader.Name, "authorization") {
+			return nil, fmt.Errorf("the '%s' header carries the Lumigo token, and cannot be overridden", otlpHeader.Name)
+		}
+
+		if otlpHeader.SecretRef.Key == "" {
+			return nil, fmt.Errorf("no key is specified for the secret '%s/%s' of the header '%s'", namespaceName, otlpHeader.SecretRef.Name, otlpHeader.Name)
+		}
+
+		secret, err := r.fetchKubernetesSecret(ctx, namespaceName, otlpHeader.SecretRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot retrieve secret '%s/%s' with the value of the header '%s'", namespaceName, otlpHeader.SecretRef.Name, otlpHeader.Name)
+		}
+
+		value, isSet := secret.Data[otlpHeader.SecretRef.Key]
+		if !isSet {
+			return nil, fmt.Errorf("the secret '%s/%s' does not have the key '%s' with the value of the header '%s'", namespaceName, otlpHeader.SecretRef.Name, otlpHeader.SecretRef.Key, otlpHeader.Name)
+		}
+
+		headers[otlpHeader.Name] = strings.TrimSpace(string(value))
+	}
+
+	return headers, nil
+}
+
 func (r *LumigoReconciler) fetchKubernetesSecret(ctx context.Context, namespaceName string, secretName string) (*corev1.Secret, error) {
 	secret := &corev1.Secret{}
 	if err := r.Client.Get(ctx, client.ObjectKey{
@@ -409,11 +1300,168 @@ func (r *LumigoReconciler) fetchKubernetesSecret(ctx context.Context, namespaceN
 	return secret, nil
 }
 
+func isSecretReferencedByAdditionalExporters(lumigo *operatorv1alpha1.Lumigo, secretName string) bool {
+	for _, additionalExporter := range lumigo.Spec.Tracing.AdditionalExporters {
+		if additionalExporter.HeadersSecretRef != nil && additionalExporter.HeadersSecretRef.Name == secretName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSecretReferencedByEndpoints(lumigo *operatorv1alpha1.Lumigo, secretName string) bool {
+	for _, endpoint := range []*operatorv1alpha1.OtlpEndpointSpec{lumigo.Spec.Endpoints.Traces, lumigo.Spec.Endpoints.Logs, lumigo.Spec.Endpoints.Metrics} {
+		if endpoint != nil && endpoint.HeadersSecretRef != nil && endpoint.HeadersSecretRef.Name == secretName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSecretReferencedByOtlpHeaders(lumigo *operatorv1alpha1.Lumigo, secretName string) bool {
+	for _, otlpHeader := range lumigo.Spec.Tracing.OtlpHeaders {
+		if otlpHeader.SecretRef.Name == secretName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSecretReferencedByRoutes(lumigo *operatorv1alpha1.Lumigo, secretName string) bool {
+	for _, route := range lumigo.Spec.Tracing.Routes {
+		if route.LumigoToken.SecretRef.Name == secretName {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (r *LumigoReconciler) enqueueIfSecretReferencedByLumigo(obj client.Object) []reconcile.Request {
 	// Require the reconciliation for Lumigo instances that reference the provided secret
 	reconcileRequests := []reconcile.Request{{}}
 
+	// The rotation of the central token secret affects the Lumigo instances of every namespace
+	if r.CentralTokenSecretConfig != nil && r.CentralTokenSecretConfig.IsCentralTokenSecret(obj) {
+		lumigoes := &operatorv1alpha1.LumigoList{}
+		if err := r.Client.List(context.TODO(), lumigoes); err != nil {
+			r.Log.Error(err, "unable to list Lumigo instances")
+			return reconcileRequests
+		}
+
+		for _, lumigo := range lumigoes.Items {
+			reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: lumigo.Namespace,
+				Name:      lumigo.Name,
+			}})
+		}
+
+		return reconcileRequests
+	}
+
 	namespace := obj.GetNamespace()
+
+	// The token secrets of a namespace are copied into the descendant namespaces with Lumigo instances propagated by HNC
+	inheritedLumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(context.TODO(), inheritedLumigoes, client.MatchingLabels{tokendistribution.InheritedFromLabelKey: namespace}); err != nil {
+		r.Log.Error(err, "unable to list the Lumigo instances inherited from namespace", "namespace", namespace)
+	} else {
+		for _, lumigo := range inheritedLumigoes.Items {
+			if lumigo.Spec.LumigoToken.SecretRef.Name == obj.GetName() {
+				reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: lumigo.Namespace,
+					Name:      lumigo.Name,
+				}})
+			}
+		}
+	}
+
+	lumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(context.TODO(), lumigoes, &client.ListOptions{Namespace: namespace}); err != nil {
+		r.Log.Error(err, "unable to list Lumigo instances in namespace '%s'", namespace)
+		// TODO Can we re-enqueue or something? Should we signal an error in the Lumigo operator?
+		return reconcileRequests
+	}
+
+	for _, lumigo := range lumigoes.Items {
+		if isSecretReferencedByAdditionalExporters(&lumigo, obj.GetName()) || isSecretReferencedByEndpoints(&lumigo, obj.GetName()) || isSecretReferencedByOtlpHeaders(&lumigo, obj.GetName()) || isSecretReferencedByRoutes(&lumigo, obj.GetName()) {
+			reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: lumigo.Namespace,
+				Name:      lumigo.Name,
+			}})
+			continue
+		}
+
+		if lumigoToken := lumigo.Spec.LumigoToken; lumigoToken != (operatorv1alpha1.Credentials{}) {
+			if secretRef := lumigoToken.SecretRef; secretRef != (operatorv1alpha1.KubernetesSecretRef{}) {
+				if secretRef.Name == obj.GetName() {
+					reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
+						Namespace: lumigo.Namespace,
+						Name:      lumigo.Name,
+					}})
+				}
+			}
+		}
+	}
+
+	return reconcileRequests
+}
+
+func (r *LumigoReconciler) enqueueIfHasLumigoAutotraceLabel(obj client.Object) []reconcile.Request {
+	if _, ok := obj.GetLabels()[mutation.LumigoAutoTraceLabelKey]; !ok {
+		return []reconcile.Request{}
+	}
+
+	return r.lumigoRequestsOfNamespace(obj.GetNamespace())
+}
+
+// instrumentedResourcesEventHandler keeps the index of the instrumented resources up to date, and enqueues the Lumigo
+// instances of the namespaces whose instrumented resources are added or removed, so that the reconciliations follow
+// the changes of the resources rather than listing all the resources of the namespace
+func (r *LumigoReconciler) instrumentedResourcesEventHandler() handler.EventHandler {
+	update := func(obj client.Object, queue workqueue.RateLimitingInterface) {
+		isChanged, err := r.InstrumentedResources.Update(obj)
+		if err != nil {
+			r.Log.Error(err, "Cannot update the instrumented resources", "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return
+		}
+
+		r.enqueueIfInstrumentedResourcesChanged(obj, isChanged, queue)
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, queue workqueue.RateLimitingInterface) {
+			update(e.Object, queue)
+		},
+		UpdateFunc: func(e event.UpdateEvent, queue workqueue.RateLimitingInterface) {
+			update(e.ObjectNew, queue)
+		},
+		DeleteFunc: func(e event.DeleteEvent, queue workqueue.RateLimitingInterface) {
+			r.enqueueIfInstrumentedResourcesChanged(e.Object, r.InstrumentedResources.Forget(e.Object), queue)
+		},
+		GenericFunc: func(e event.GenericEvent, queue workqueue.RateLimitingInterface) {
+			update(e.Object, queue)
+		},
+	}
+}
+
+func (r *LumigoReconciler) enqueueIfInstrumentedResourcesChanged(obj client.Object, isChanged bool, queue workqueue.RateLimitingInterface) {
+	if !isChanged {
+		return
+	}
+
+	for _, request := range r.lumigoRequestsOfNamespace(obj.GetNamespace()) {
+		queue.Add(request)
+	}
+}
+
+// The reconciliation requests of the active Lumigo instances of the namespace or, if it has none, of the background
+// removal of the injection, to resume it, e.g., after a restart of the controller
+func (r *LumigoReconciler) lumigoRequestsOfNamespace(namespace string) []reconcile.Request {
+	reconcileRequests := []reconcile.Request{}
 	lumigoes := &operatorv1alpha1.LumigoList{}
 
 	if err := r.Client.List(context.TODO(), lumigoes, &client.ListOptions{Namespace: namespace}); err != nil {
@@ -422,558 +1470,1795 @@ func (r *LumigoReconciler) enqueueIfSecretReferencedByLumigo(obj client.Object)
 		return reconcileRequests
 	}
 
-	for _, lumigo := range lumigoes.Items {
-		if lumigoToken := lumigo.Spec.LumigoToken; lumigoToken != (operatorv1alpha1.Credentials{}) {
-			if secretRef := lumigoToken.SecretRef; secretRef != (operatorv1alpha1.KubernetesSecretRef{}) {
-				if secretRef.Name == obj.GetName() {
-					reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
-						Namespace: lumigo.Namespace,
-						Name:      lumigo.Name,
-					}})
-				}
+	for _, lumigo := range lumigoes.Items {
+		if conditions.IsActive(&lumigo) {
+			reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: lumigo.Namespace,
+				Name:      lumigo.Name,
+			}})
+		}
+	}
+
+	if len(lumigoes.Items) < 1 {
+		reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: namespace,
+			Name:      backgroundcleanup.ConfigMapName,
+		}})
+	}
+
+	return reconcileRequests
+}
+
+func (r *LumigoReconciler) updateStatusIfNeeded(ctx context.Context, logger logr.Logger, instance *operatorv1alpha1.Lumigo, result ctrl.Result) (ctrl.Result, error) {
+	// Updates the status of a Lumigo instance. Unfortunately updates do not seem reliable due
+	// to some mismatch between the results of apiequality.Semantic.DeepEqual() and Kubernetes'
+	// API (maybe due to bugs, maybe due to eventual consistency), which causes updates to be lost.
+	// To reduce the risk of losing updates, we reque the request after a grace period.
+
+	// Ensure status is valid (e.g., Lumigo instance just created and has no resources instrumented)
+	if instance.Status.InstrumentedResources == nil {
+		instance.Status.InstrumentedResources = make([]corev1.ObjectReference, 0)
+	}
+
+	// The status now reflects the current spec, which clients wait for after changing it
+	instance.Status.ObservedGeneration = instance.Generation
+
+	// The phase is derived from the conditions, so that the two never disagree
+	conditions.SetPhase(instance)
+
+	if err := r.Client.Status().Update(ctx, instance); err != nil {
+		logger.Error(err, "unable to update Lumigo instance's status")
+		return ctrl.Result{RequeueAfter: defaultErrRequeuePeriod}, nil
+	}
+
+	logger.Info("Status updated", "status", &instance.Status)
+
+	if hasError, _ := conditions.HasError(instance); hasError {
+		return ctrl.Result{RequeueAfter: defaultErrRequeuePeriod}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: defaultRequeuePeriod}, nil
+}
+
+// Failures to inject individual resources are recorded in the status of the Lumigo instance rather than returned,
+// so that they can be retried with a backoff by retryFailedInjections
+func (r *LumigoReconciler) injectLumigoIntoResources(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, budget *workloadpacing.Budget, now metav1.Time, log *logr.Logger) error {
+	ctx, span := r.SelfTelemetry.StartSpan(ctx, "Inject Lumigo into existing resources")
+	defer span.End()
+
+	telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := r.telemetryProxyOtlpServiceUrls(lumigo)
+	mutator, err := mutation.NewMutator(log, injectionspec.EffectiveSpec(lumigo), r.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		return fmt.Errorf("cannot instantiate mutator: %w", err)
+	}
+
+	namespace := lumigo.Namespace
+
+	lumigoWithoutAutotraceLabelListOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("!%s", mutation.LumigoAutoTraceLabelKey),
+	}
+
+	// Ensure that all the resources that could be injected, are injected
+	// TODO What to do about upgrades from former controller versions?
+	lumigoNotAutotracedLabelFalseOrNotSet, err := labels.NewRequirement(mutation.LumigoAutoTraceLabelKey, selection.NotIn, []string{"false", mutator.GetAutotraceLabelValue()})
+	if err != nil {
+		return fmt.Errorf("cannot create label selector for non-autotraced objects: %w", err)
+	}
+
+	lumigoNotAutotracedLabelSelector := labels.NewSelector()
+	lumigoNotAutotracedLabelSelector.Add(*lumigoNotAutotracedLabelFalseOrNotSet)
+
+	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+
+	injectionSteps := []struct {
+		kind     string
+		listPage listpaging.PageFunc
+	}{
+		{
+			// Mutate daemonsets
+			kind: "DaemonSet",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				daemonsets, err := r.Clientset.AppsV1().DaemonSets(namespace).List(ctx, listOptions)
+				if err != nil {
+					return "", fmt.Errorf("cannot list non-autotraced daemonsets: %w", err)
+				}
+
+				for _, daemonset := range daemonsets.Items {
+					if isInjectionCircuitBreakerOpen(lumigo, "DaemonSet", &daemonset) {
+						continue
+					}
+
+					if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s daemonset", daemonset.Namespace, daemonset.Name), func() error {
+						if err := r.Client.Get(ctx, client.ObjectKey{
+							Namespace: daemonset.Namespace,
+							Name:      daemonset.Name,
+						}, &daemonset); err != nil {
+							return fmt.Errorf("cannot retrieve details of daemonset '%s': %w", daemonset.GetName(), err)
+						}
+
+						mutatedDaemonset := daemonset.DeepCopy()
+						if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1DaemonSet(mutatedDaemonset); err != nil {
+							return fmt.Errorf("cannot prepare mutation of daemonset '%s': %w", daemonset.GetName(), err)
+						} else if mutationOccurred {
+							if !budget.Take(now.Time) {
+								return workloadpacing.ErrBudgetExhausted
+							}
+							return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, &daemonset, mutatedDaemonset, log)
+						} else {
+							return nil
+						}
+					}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+						r.enqueuePendingInjection(lumigo, &daemonset, log)
+					} else if mutation.IsSkipInjectionError(err) {
+						log.Info("Skipped instrumentation of daemonset", "name", daemonset.Name, "reason", err.Error())
+						r.recordSkippedInstrumentation(&daemonset, "DaemonSet", eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &daemonset, nil, now, log)
+					} else if err != nil {
+						// The other resources are still injected, and the injection of this one is retried later on
+						log.Error(err, "Cannot add instrumentation to daemonset", "name", daemonset.Name)
+						operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &daemonset, err, now, log)
+					} else {
+						log.Info("Added instrumentation to daemonset", "name", daemonset.Name)
+						operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger)
+						r.updateInjectionFailure(lumigo, &daemonset, nil, now, log)
+					}
+				}
+
+				return daemonsets.Continue, nil
+			},
+		},
+		{
+			// Mutate deployments
+			kind: "Deployment",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+				if err != nil {
+					return "", fmt.Errorf("cannot list non-autotraced deployments: %w", err)
+				}
+
+				for _, deployment := range deployments.Items {
+					if isInjectionCircuitBreakerOpen(lumigo, "Deployment", &deployment) {
+						continue
+					}
+
+					if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s deployment", deployment.Namespace, deployment.Name), func() error {
+						if err := r.Client.Get(ctx, client.ObjectKey{
+							Namespace: deployment.Namespace,
+							Name:      deployment.Name,
+						}, &deployment); err != nil {
+							return fmt.Errorf("cannot retrieve details of deployment '%s': %w", deployment.GetName(), err)
+						}
+
+						if deferredinjections.IsSuspended(&deployment) {
+							return deferredinjections.ErrInjectionDeferred
+						}
+
+						mutatedDeployment := deployment.DeepCopy()
+						if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1Deployment(mutatedDeployment); err != nil {
+							return fmt.Errorf("cannot prepare mutation of deployment '%s': %w", deployment.GetName(), err)
+						} else if mutationOccurred {
+							if !budget.Take(now.Time) {
+								return workloadpacing.ErrBudgetExhausted
+							}
+							return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, &deployment, mutatedDeployment, log)
+						} else {
+							return nil
+						}
+					}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+						r.enqueuePendingInjection(lumigo, &deployment, log)
+					} else if errors.Is(err, deferredinjections.ErrInjectionDeferred) {
+						r.deferInjection(lumigo, &deployment, log)
+						r.updateInjectionFailure(lumigo, &deployment, nil, now, log)
+					} else if mutation.IsSkipInjectionError(err) {
+						log.Info("Skipped instrumentation of deployment", "name", deployment.Name, "reason", err.Error())
+						r.recordSkippedInstrumentation(&deployment, "Deployment", eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &deployment, nil, now, log)
+					} else if err != nil {
+						// The other resources are still injected, and the injection of this one is retried later on
+						log.Error(err, "Cannot add instrumentation to deployment", "name", deployment.Name)
+						operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &deployment, err, now, log)
+					} else {
+						log.Info("Added instrumentation to deployment", "name", deployment.Name)
+						operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger)
+						r.updateInjectionFailure(lumigo, &deployment, nil, now, log)
+					}
+				}
+
+				return deployments.Continue, nil
+			},
+		},
+		{
+			// Mutate replicasets
+			kind: "ReplicaSet",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				replicasets, err := r.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+				if err != nil {
+					return "", fmt.Errorf("cannot list non-autotraced replicasets: %w", err)
+				}
+
+				for _, replicaset := range replicasets.Items {
+					if isInjectionCircuitBreakerOpen(lumigo, "ReplicaSet", &replicaset) {
+						continue
+					}
+
+					if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s replicaset", replicaset.Namespace, replicaset.Name), func() error {
+						if err := r.Client.Get(ctx, client.ObjectKey{
+							Namespace: replicaset.Namespace,
+							Name:      replicaset.Name,
+						}, &replicaset); err != nil {
+							return fmt.Errorf("cannot retrieve details of replicaset '%s': %w", replicaset.GetName(), err)
+						}
+
+						mutatedReplicaset := replicaset.DeepCopy()
+						if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1ReplicaSet(mutatedReplicaset); err != nil {
+							return fmt.Errorf("cannot prepare mutation of replicaset '%s': %w", replicaset.GetName(), err)
+						} else if mutationOccurred {
+							if !budget.Take(now.Time) {
+								return workloadpacing.ErrBudgetExhausted
+							}
+							return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, &replicaset, mutatedReplicaset, log)
+						} else {
+							return nil
+						}
+					}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+						r.enqueuePendingInjection(lumigo, &replicaset, log)
+					} else if mutation.IsSkipInjectionError(err) {
+						log.Info("Skipped instrumentation of replicaset", "name", replicaset.Name, "reason", err.Error())
+						r.recordSkippedInstrumentation(&replicaset, "ReplicaSet", eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &replicaset, nil, now, log)
+					} else if err != nil {
+						// The other resources are still injected, and the injection of this one is retried later on
+						log.Error(err, "Cannot add instrumentation to replicaset", "name", replicaset.Name)
+						operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &replicaset, err, now, log)
+					} else {
+						log.Info("Added instrumentation to replicaset", "name", replicaset.Name)
+						operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger)
+						r.updateInjectionFailure(lumigo, &replicaset, nil, now, log)
+					}
+				}
+
+				return replicasets.Continue, nil
+			},
+		},
+		{
+			// Mutate statefulsets
+			kind: "StatefulSet",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				statefulsets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, listOptions)
+				if err != nil {
+					return "", fmt.Errorf("cannot list non-autotraced statefulsets: %w", err)
+				}
+
+				for _, statefulset := range statefulsets.Items {
+					if isInjectionCircuitBreakerOpen(lumigo, "StatefulSet", &statefulset) {
+						continue
+					}
+
+					if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s statefulset", statefulset.Namespace, statefulset.Name), func() error {
+						if err := r.Client.Get(ctx, client.ObjectKey{
+							Namespace: statefulset.Namespace,
+							Name:      statefulset.Name,
+						}, &statefulset); err != nil {
+							return fmt.Errorf("cannot retrieve details of statefulset '%s': %w", statefulset.GetName(), err)
+						}
+
+						mutatedStatefulset := statefulset.DeepCopy()
+						if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1StatefulSet(mutatedStatefulset); err != nil {
+							return fmt.Errorf("cannot prepare mutation of statefulset '%s': %w", statefulset.GetName(), err)
+						} else if mutationOccurred {
+							if !budget.Take(now.Time) {
+								return workloadpacing.ErrBudgetExhausted
+							}
+							return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, &statefulset, mutatedStatefulset, log)
+						} else {
+							return nil
+						}
+					}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+						r.enqueuePendingInjection(lumigo, &statefulset, log)
+					} else if mutation.IsSkipInjectionError(err) {
+						log.Info("Skipped instrumentation of statefulset", "name", statefulset.Name, "reason", err.Error())
+						r.recordSkippedInstrumentation(&statefulset, "StatefulSet", eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &statefulset, nil, now, log)
+					} else if err != nil {
+						// The other resources are still injected, and the injection of this one is retried later on
+						log.Error(err, "Cannot add instrumentation to statefulset", "name", statefulset.Name)
+						operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &statefulset, err, now, log)
+					} else {
+						log.Info("Added instrumentation to statefulset", "name", statefulset.Name)
+						operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger)
+						r.updateInjectionFailure(lumigo, &statefulset, nil, now, log)
+					}
+				}
+
+				return statefulsets.Continue, nil
+			},
+		},
+		{
+			// Mutate cronjobs
+			kind: "CronJob",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				if !r.hasCapability(capabilities.CronJobs) {
+					return "", nil
+				}
+
+				cronjobs, err := r.Clientset.BatchV1().CronJobs(namespace).List(ctx, listOptions)
+				if err != nil {
+					return "", fmt.Errorf("cannot list non-autotraced cronjobs: %w", err)
+				}
+
+				for _, cronjob := range cronjobs.Items {
+					if isInjectionCircuitBreakerOpen(lumigo, "CronJob", &cronjob) {
+						continue
+					}
+
+					if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s cronjob", cronjob.Namespace, cronjob.Name), func() error {
+						if err := r.Client.Get(ctx, client.ObjectKey{
+							Namespace: cronjob.Namespace,
+							Name:      cronjob.Name,
+						}, &cronjob); err != nil {
+							return fmt.Errorf("cannot retrieve details of cronjob '%s': %w", cronjob.GetName(), err)
+						}
+
+						if deferredinjections.IsSuspended(&cronjob) {
+							return deferredinjections.ErrInjectionDeferred
+						}
+
+						mutatedCronjob := cronjob.DeepCopy()
+						if mutationOccurred, err := mutator.InjectLumigoIntoBatchV1CronJob(mutatedCronjob); err != nil {
+							return fmt.Errorf("cannot prepare mutation of cronjob '%s': %w", cronjob.GetName(), err)
+						} else if mutationOccurred {
+							if !budget.Take(now.Time) {
+								return workloadpacing.ErrBudgetExhausted
+							}
+							return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, &cronjob, mutatedCronjob, log)
+						} else {
+							return nil
+						}
+					}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+						r.enqueuePendingInjection(lumigo, &cronjob, log)
+					} else if errors.Is(err, deferredinjections.ErrInjectionDeferred) {
+						r.deferInjection(lumigo, &cronjob, log)
+						r.updateInjectionFailure(lumigo, &cronjob, nil, now, log)
+					} else if mutation.IsSkipInjectionError(err) {
+						log.Info("Skipped instrumentation of cronjob", "name", cronjob.Name, "reason", err.Error())
+						r.recordSkippedInstrumentation(&cronjob, "CronJob", eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &cronjob, nil, now, log)
+					} else if err != nil {
+						// The other resources are still injected, and the injection of this one is retried later on
+						log.Error(err, "Cannot add instrumentation to cronjob", "name", cronjob.Name)
+						operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger, err)
+						r.updateInjectionFailure(lumigo, &cronjob, err, now, log)
+					} else {
+						log.Info("Added instrumentation to cronjob", "name", cronjob.Name)
+						operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger)
+						r.updateInjectionFailure(lumigo, &cronjob, nil, now, log)
+					}
+				}
+
+				return cronjobs.Continue, nil
+			},
+		},
+		{
+			// Mutate the ScaledJobs of Keda, whose jobs are created out of their pod templates
+			kind: "ScaledJob",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				return "", r.injectLumigoIntoKedaScaledJobs(ctx, lumigo, mutator, listOptions, eventTrigger, budget, now, log)
+			},
+		},
+		{
+			// Cannot mutate existing jobs: their PodSpecs are immutable!
+			kind: "Job",
+			listPage: func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+				jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, listOptions)
+				if err != nil {
+					return "", fmt.Errorf("cannot list autotraced jobs: %w", err)
+				}
+
+				for _, job := range jobs.Items {
+					if isOwnedByKedaScaledJob, _ := mutation.IsOwnedByKedaScaledJob(job.OwnerReferences); isOwnedByKedaScaledJob {
+						// The next jobs of the ScaledJob are created out of its pod template, which is injected instead
+						continue
+					}
+
+					operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &job, eventTrigger, fmt.Errorf("the PodSpec of batchv1.Job resources is immutable once the job has been created"))
+					log.Info("Cannot instrumentation job: jobs are immutable once created", "namespace", job.Namespace, "name", job.Name)
+				}
+
+				return jobs.Continue, nil
+			},
+		},
+	}
+
+	// The resources are listed one page at a time and, once the pages listed in this reconciliation reach
+	// the limit, the injection is checkpointed in the status and resumed in the next reconciliation
+	stepIndex, continueToken := 0, ""
+	if progress := lumigo.Status.InjectionProgress; progress != nil {
+		for i, step := range injectionSteps {
+			if step.kind == progress.Kind {
+				stepIndex, continueToken = i, progress.Continue
+			}
+		}
+	}
+
+	pagesLeft := maxInjectionListPagesPerReconciliation
+	for ; stepIndex < len(injectionSteps); stepIndex++ {
+		step := injectionSteps[stepIndex]
+		if pagesLeft < 1 {
+			r.checkpointInjection(lumigo, step.kind, continueToken, log)
+			return nil
+		}
+
+		next, pages, err := listpaging.ListPages(ctx, lumigoWithoutAutotraceLabelListOptions, continueToken, pagesLeft, step.listPage)
+		if err != nil {
+			// The injection is resumed from the page that could not be listed in the next reconciliation
+			r.checkpointInjection(lumigo, step.kind, next, log)
+			return err
+		}
+
+		if len(next) > 0 {
+			r.checkpointInjection(lumigo, step.kind, next, log)
+			return nil
+		}
+
+		pagesLeft -= pages
+		continueToken = ""
+	}
+
+	lumigo.Status.InjectionProgress = nil
+
+	return nil
+}
+
+func (r *LumigoReconciler) checkpointInjection(lumigo *operatorv1alpha1.Lumigo, kind string, continueToken string, log *logr.Logger) {
+	log.Info("Checkpointing the injection of the resources in namespace", "kind", kind)
+	lumigo.Status.InjectionProgress = &operatorv1alpha1.InjectionProgress{
+		Kind:     kind,
+		Continue: continueToken,
+	}
+}
+
+// Records the event and the metric of the resource that has not been injected because of the given SkipInjectionError
+func (r *LumigoReconciler) recordSkippedInstrumentation(obj client.Object, kind string, eventTrigger string, err error) {
+	operatorv1alpha1.RecordSkippedInstrumentationEvent(r.EventRecorder, obj, eventTrigger, err)
+	injectionskips.Record(obj.GetNamespace(), kind, injectionskips.SourceController, err)
+}
+
+// Records the failure to inject the given resource in the status of the Lumigo instance or, if err is nil, clears it
+func (r *LumigoReconciler) updateInjectionFailure(lumigo *operatorv1alpha1.Lumigo, obj runtime.Object, err error, now metav1.Time, log *logr.Logger) {
+	objectReference, refErr := reference.GetReference(scheme.Scheme, obj)
+	if refErr != nil {
+		log.Error(refErr, "Cannot create the reference to the resource to track its injection")
+		return
+	}
+
+	if err == nil {
+		injectionfailures.ClearInjectionFailure(lumigo, *objectReference)
+		return
+	}
+
+	r.recordInjectionFailure(lumigo, obj, *objectReference, err, now, log)
+}
+
+// Records the failure to inject the given resource, to be retried with a backoff, and reports when the failures in a
+// row have opened its circuit breaker, after which its injection is no longer retried until reset
+func (r *LumigoReconciler) recordInjectionFailure(lumigo *operatorv1alpha1.Lumigo, obj runtime.Object, resource corev1.ObjectReference, err error, now metav1.Time, log *logr.Logger) {
+	if !injectionfailures.RecordInjectionFailure(lumigo, resource, err, now) {
+		return
+	}
+
+	log.Info("Stopped retrying the injection of resource after too many failures in a row", "kind", resource.Kind, "name", resource.Name, "resetAnnotation", injectionfailures.ResetAnnotationKey)
+	operatorv1alpha1.RecordInjectionRetriesStoppedEvent(r.EventRecorder, obj, fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name), injectionfailures.ResetAnnotationKey, err)
+}
+
+// Whether the injection of the resource has failed too many times in a row to be attempted again until reset
+func isInjectionCircuitBreakerOpen(lumigo *operatorv1alpha1.Lumigo, kind string, obj client.Object) bool {
+	return injectionfailures.IsCircuitBreakerOpen(lumigo, corev1.ObjectReference{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	})
+}
+
+// Resets the backoff and the circuit breaker of the failed injections selected by the reset annotation of the Lumigo
+// instance, which is then removed, so that they are retried in this reconciliation
+func (r *LumigoReconciler) resetInjectionBackoff(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, now metav1.Time, log *logr.Logger) error {
+	resources, err := injectionfailures.ResetRequestedInjectionFailures(ctx, r.Client, lumigo, now)
+	if err != nil {
+		return err
+	}
+
+	for _, resource := range resources {
+		log.Info("Reset the backoff of the failed injection of resource", "kind", resource.Kind, "name", resource.Name)
+	}
+
+	return nil
+}
+
+// Retries the injection of the resources whose earlier injection has failed and is due for retry
+func (r *LumigoReconciler) retryFailedInjections(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, budget *workloadpacing.Budget, now metav1.Time, log *logr.Logger) {
+	if len(lumigo.Status.FailedInjections) < 1 {
+		return
+	}
+
+	if !isTruthy(lumigo.Spec.Tracing.Injection.Enabled, true) {
+		// Nothing will be injected, so nothing is left to retry
+		injectionfailures.ClearAllInjectionFailures(lumigo)
+		return
+	}
+
+	telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := r.telemetryProxyOtlpServiceUrls(lumigo)
+	mutator, err := mutation.NewMutator(log, injectionspec.EffectiveSpec(lumigo), r.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		log.Error(err, "Cannot instantiate mutator to retry the failed injections")
+		return
+	}
+
+	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+
+	for _, resource := range injectionfailures.GetInjectionFailuresDueForRetry(lumigo, now) {
+		obj := newObjectOfKind(resource.Kind)
+		if obj == nil {
+			log.Info("Dropping failed injection of unsupported resource kind", "kind", resource.Kind, "name", resource.Name)
+			injectionfailures.ClearInjectionFailure(lumigo, resource)
+			continue
+		}
+
+		err := r.injectLumigoIntoObject(ctx, lumigo, mutator, resource, obj, budget, now, fmt.Sprintf("retry injecting instrumentation into the %s/%s %s", resource.Namespace, resource.Name, strings.ToLower(resource.Kind)), log)
+
+		if errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+			// The failed injections left are retried in the next reconciliations
+			return
+		} else if errors.Is(err, deferredinjections.ErrInjectionDeferred) {
+			log.Info("Deferred failed injection of suspended resource", "kind", resource.Kind, "name", resource.Name)
+			injectionfailures.ClearInjectionFailure(lumigo, resource)
+			deferredinjections.DeferInjection(lumigo, resource)
+		} else if apierrors.IsNotFound(err) {
+			log.Info("Dropping failed injection of deleted resource", "kind", resource.Kind, "name", resource.Name)
+			injectionfailures.ClearInjectionFailure(lumigo, resource)
+		} else if mutation.IsSkipInjectionError(err) {
+			log.Info("Skipped instrumentation on retry", "kind", resource.Kind, "name", resource.Name, "reason", err.Error())
+			injectionskips.Record(resource.Namespace, resource.Kind, injectionskips.SourceController, err)
+			injectionfailures.ClearInjectionFailure(lumigo, resource)
+		} else if err != nil {
+			log.Error(err, "Cannot add instrumentation on retry", "kind", resource.Kind, "name", resource.Name)
+			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, obj, eventTrigger, err)
+			r.recordInjectionFailure(lumigo, obj, resource, err, now, log)
+		} else {
+			log.Info("Added instrumentation on retry", "kind", resource.Kind, "name", resource.Name)
+			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, obj, eventTrigger)
+			injectionfailures.ClearInjectionFailure(lumigo, resource)
+		}
+	}
+}
+
+// Injects the resources queued because of the limit of the workloads updated in one reconciliation, in order,
+// until the limit is reached again; the resources that cannot be injected are handed over to retryFailedInjections
+func (r *LumigoReconciler) injectPendingInjections(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, budget *workloadpacing.Budget, now metav1.Time, log *logr.Logger) {
+	if len(lumigo.Status.PendingInjections) < 1 {
+		return
+	}
+
+	if !isTruthy(lumigo.Spec.Tracing.Injection.Enabled, true) {
+		// Nothing will be injected, so nothing is left pending
+		workloadpacing.ClearAllPendingInjections(lumigo)
+		return
+	}
+
+	telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := r.telemetryProxyOtlpServiceUrls(lumigo)
+	mutator, err := mutation.NewMutator(log, injectionspec.EffectiveSpec(lumigo), r.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		log.Error(err, "Cannot instantiate mutator to inject the pending resources")
+		return
+	}
+
+	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+
+	// Copied, as the pending injections are removed from the status while iterating
+	pendingInjections := append([]corev1.ObjectReference{}, lumigo.Status.PendingInjections...)
+	for _, resource := range pendingInjections {
+		obj := newObjectOfKind(resource.Kind)
+		if obj == nil {
+			log.Info("Dropping pending injection of unsupported resource kind", "kind", resource.Kind, "name", resource.Name)
+			workloadpacing.RemovePendingInjection(lumigo, resource)
+			continue
+		}
+
+		err := r.injectLumigoIntoObject(ctx, lumigo, mutator, resource, obj, budget, now, fmt.Sprintf("inject instrumentation into the pending %s/%s %s", resource.Namespace, resource.Name, strings.ToLower(resource.Kind)), log)
+
+		if errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+			log.Info("Limit of workloads updated at once reached, the other pending injections are carried out later on", "pending", len(lumigo.Status.PendingInjections))
+			return
+		}
+
+		workloadpacing.RemovePendingInjection(lumigo, resource)
+		if apierrors.IsNotFound(err) {
+			log.Info("Dropping pending injection of deleted resource", "kind", resource.Kind, "name", resource.Name)
+		} else if errors.Is(err, deferredinjections.ErrInjectionDeferred) {
+			log.Info("Deferred pending injection of suspended resource", "kind", resource.Kind, "name", resource.Name)
+			deferredinjections.DeferInjection(lumigo, resource)
+		} else if mutation.IsSkipInjectionError(err) {
+			log.Info("Skipped instrumentation of pending resource", "kind", resource.Kind, "name", resource.Name, "reason", err.Error())
+			r.recordSkippedInstrumentation(obj, resource.Kind, eventTrigger, err)
+		} else if err != nil {
+			log.Error(err, "Cannot add instrumentation to pending resource", "kind", resource.Kind, "name", resource.Name)
+			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, obj, eventTrigger, err)
+			r.recordInjectionFailure(lumigo, obj, resource, err, now, log)
+		} else {
+			log.Info("Added instrumentation to pending resource", "kind", resource.Kind, "name", resource.Name)
+			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, obj, eventTrigger)
+		}
+	}
+}
+
+// Queues the injection of the resource for the next reconciliations, as the limit of the workloads updated
+// in this one has been reached
+func (r *LumigoReconciler) enqueuePendingInjection(lumigo *operatorv1alpha1.Lumigo, obj runtime.Object, log *logr.Logger) {
+	objectReference, err := reference.GetReference(scheme.Scheme, obj)
+	if err != nil {
+		log.Error(err, "Cannot create the reference to the resource to queue its injection")
+		return
+	}
+
+	log.Info("Queued the injection of resource, as the limit of workloads updated at once has been reached", "kind", objectReference.Kind, "name", objectReference.Name)
+	workloadpacing.EnqueuePendingInjection(lumigo, *objectReference)
+}
+
+// Injects the paused Deployments and suspended CronJobs whose injection has been deferred and that have been
+// resumed meanwhile without being injected by the injector webhook, e.g., because it was unavailable; the ones
+// still suspended are kept deferred
+func (r *LumigoReconciler) injectDeferredInjections(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, budget *workloadpacing.Budget, now metav1.Time, log *logr.Logger) {
+	if len(lumigo.Status.DeferredInjections) < 1 {
+		return
+	}
+
+	if !isTruthy(lumigo.Spec.Tracing.Injection.Enabled, true) {
+		// Nothing will be injected, so nothing is left deferred
+		deferredinjections.ClearAllDeferredInjections(lumigo)
+		return
+	}
+
+	telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := r.telemetryProxyOtlpServiceUrls(lumigo)
+	mutator, err := mutation.NewMutator(log, injectionspec.EffectiveSpec(lumigo), r.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		log.Error(err, "Cannot instantiate mutator to inject the deferred resources")
+		return
+	}
+
+	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+
+	// Copied, as the deferred injections are removed from the status while iterating
+	deferredInjections := append([]corev1.ObjectReference{}, lumigo.Status.DeferredInjections...)
+	for _, resource := range deferredInjections {
+		obj := newObjectOfKind(resource.Kind)
+		if obj == nil {
+			log.Info("Dropping deferred injection of unsupported resource kind", "kind", resource.Kind, "name", resource.Name)
+			deferredinjections.RemoveDeferredInjection(lumigo, resource)
+			continue
+		}
+
+		err := r.injectLumigoIntoObject(ctx, lumigo, mutator, resource, obj, budget, now, fmt.Sprintf("inject instrumentation into the resumed %s/%s %s", resource.Namespace, resource.Name, strings.ToLower(resource.Kind)), log)
+
+		if errors.Is(err, deferredinjections.ErrInjectionDeferred) {
+			// Still suspended
+			continue
+		} else if errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+			log.Info("Limit of workloads updated at once reached, the other resumed resources are injected later on")
+			return
+		}
+
+		deferredinjections.RemoveDeferredInjection(lumigo, resource)
+		if apierrors.IsNotFound(err) {
+			log.Info("Dropping deferred injection of deleted resource", "kind", resource.Kind, "name", resource.Name)
+		} else if mutation.IsSkipInjectionError(err) {
+			log.Info("Skipped instrumentation of resumed resource", "kind", resource.Kind, "name", resource.Name, "reason", err.Error())
+			r.recordSkippedInstrumentation(obj, resource.Kind, eventTrigger, err)
+		} else if err != nil {
+			log.Error(err, "Cannot add instrumentation to resumed resource", "kind", resource.Kind, "name", resource.Name)
+			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, obj, eventTrigger, err)
+			r.recordInjectionFailure(lumigo, obj, resource, err, now, log)
+		} else {
+			log.Info("Added instrumentation to resumed resource", "kind", resource.Kind, "name", resource.Name)
+			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, obj, eventTrigger)
+		}
+	}
+}
+
+// Defers the injection of the paused or suspended resource until it is resumed
+func (r *LumigoReconciler) deferInjection(lumigo *operatorv1alpha1.Lumigo, obj runtime.Object, log *logr.Logger) {
+	objectReference, err := reference.GetReference(scheme.Scheme, obj)
+	if err != nil {
+		log.Error(err, "Cannot create the reference to the resource to defer its injection")
+		return
+	}
+
+	log.Info("Deferred the injection of resource until it is resumed", "kind", objectReference.Kind, "name", objectReference.Name)
+	deferredinjections.DeferInjection(lumigo, *objectReference)
+}
+
+// Retrieves the referenced resource into obj and injects it, if it is not injected already
+func (r *LumigoReconciler) injectLumigoIntoObject(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, mutator mutation.Mutator, resource corev1.ObjectReference, obj client.Object, budget *workloadpacing.Budget, now metav1.Time, description string, log *logr.Logger) error {
+	return retry(description, func() error {
+		if err := r.Client.Get(ctx, client.ObjectKey{
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		}, obj); err != nil {
+			return err
+		}
+
+		if deferredinjections.IsSuspended(obj) {
+			return deferredinjections.ErrInjectionDeferred
+		}
+
+		mutated := obj.DeepCopyObject().(client.Object)
+		if mutationOccurred, err := mutator.InjectLumigoInto(mutated); err != nil {
+			return fmt.Errorf("cannot prepare mutation of %s '%s': %w", strings.ToLower(resource.Kind), resource.Name, err)
+		} else if mutationOccurred {
+			if !budget.Take(now.Time) {
+				return workloadpacing.ErrBudgetExhausted
+			}
+			return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, obj, mutated, log)
+		}
+
+		return nil
+	}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log)
+}
+
+// Returns an empty object of the kind of injectable resource, or nil if the kind cannot be injected
+func newObjectOfKind(kind string) client.Object {
+	switch kind {
+	case "DaemonSet":
+		return &appsv1.DaemonSet{}
+	case "Deployment":
+		return &appsv1.Deployment{}
+	case "ReplicaSet":
+		return &appsv1.ReplicaSet{}
+	case "StatefulSet":
+		return &appsv1.StatefulSet{}
+	case "CronJob":
+		return &batchv1.CronJob{}
+	case mutation.KedaScaledJobGroupVersionKind.Kind:
+		scaledJob := &unstructured.Unstructured{}
+		scaledJob.SetGroupVersionKind(mutation.KedaScaledJobGroupVersionKind)
+		return scaledJob
+	default:
+		return nil
+	}
+}
+
+// removeLumigoFromResourcesInBackground removes the injection from the resources of the namespace of the deleted
+// Lumigo instance and, once done, its token secret, unless the namespace has a new Lumigo instance meanwhile
+func (r *LumigoReconciler) removeLumigoFromResourcesInBackground(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) (ctrl.Result, error) {
+	lumigoesInNamespace := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(ctx, lumigoesInNamespace, &client.ListOptions{Namespace: lumigo.Namespace}); err != nil {
+		return ctrl.Result{
+			RequeueAfter: defaultErrRequeuePeriod,
+		}, err
+	}
+
+	if len(lumigoesInNamespace.Items) > 0 {
+		log.Info("Dropping the background removal of instrumentation from resources in namespace, which has a new Lumigo instance")
+		return ctrl.Result{}, backgroundcleanup.CompleteCleanup(ctx, r.Clientset.CoreV1(), lumigo.Namespace)
+	}
+
+	log.Info("Removing instrumentation from resources in namespace in the background", "deleted-lumigo", lumigo.Name)
+	if err := r.removeLumigoFromResources(ctx, lumigo, log); err != nil {
+		// The resources the instrumentation has already been removed from are left as they are on the next attempt
+		log.Error(err, "cannot remove instrumentation from resources in the background", "namespace", lumigo.Namespace)
+		return ctrl.Result{
+			RequeueAfter: defaultRequeuePeriod,
+		}, nil
+	}
+
+	if isChanged, err := tokensecrets.RemoveTokenSecretOfNamespace(ctx, r.Client, lumigo.Namespace, log); err != nil {
+		log.Error(err, "Cannot remove the Lumigo token secret of the namespace")
+	} else if isChanged {
+		log.Info("Removed the Lumigo token secret of the namespace")
+	}
+
+	r.removeDedicatedTelemetryProxy(ctx, lumigo.Namespace, log)
+
+	if err := backgroundcleanup.CompleteCleanup(ctx, r.Clientset.CoreV1(), lumigo.Namespace); err != nil {
+		return ctrl.Result{
+			RequeueAfter: defaultErrRequeuePeriod,
+		}, err
+	}
+
+	log.Info("Removed instrumentation from resources in namespace in the background")
+	return ctrl.Result{}, nil
+}
+
+func (r *LumigoReconciler) removeLumigoFromResources(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) error {
+	namespace := lumigo.Namespace
+
+	mutator, err := mutation.NewMutator(log, nil, r.LumigoOperatorVersion, r.LumigoInjectorImage, r.TelemetryProxyOtlpServiceUrl, r.TelemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		return fmt.Errorf("cannot instantiate mutator: %w", err)
+	}
+
+	lumigoAutotracedListOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%[1]s,%[1]s != false", mutation.LumigoAutoTraceLabelKey),
+	}
+
+	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+
+	// Mutate daemonsets
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		daemonsets, err := r.Clientset.AppsV1().DaemonSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced daemonsets: %w", err)
+		}
+
+		for _, daemonset := range daemonsets.Items {
+			if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s daemonset", daemonset.Namespace, daemonset.Name), func() error {
+				_, err := optimisticupdate.Apply(ctx, r.apiReader(), &daemonset, func(obj client.Object) (bool, error) {
+					mutatedDaemonset := obj.(*appsv1.DaemonSet)
+					if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1DaemonSet(mutatedDaemonset); err != nil {
+						return false, fmt.Errorf("cannot prepare mutation of daemonset '%s': %w", mutatedDaemonset.Name, err)
+					} else if mutationOccurred {
+						addAutoTraceSkipNextInjectorLabel(&mutatedDaemonset.ObjectMeta)
+						return true, nil
+					} else {
+						return false, nil
+					}
+				}, r.mutatedResourceWriter(lumigo, audit.ActionUninject, log))
+				return err
+			}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
+				operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger, err)
+				return "", fmt.Errorf("cannot remove instrumentation from daemonset '%s': %w", daemonset.Name, err)
+			} else {
+				log.Info("Removed instrumentation from daemonset", "namespace", daemonset.Namespace, "name", daemonset.Name)
+				operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger)
+			}
+		}
+
+		return daemonsets.Continue, nil
+	}); err != nil {
+		return err
+	}
+
+	// Mutate deployments
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced deployments: %w", err)
+		}
+
+		for _, deployment := range deployments.Items {
+			if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s deployment", deployment.Namespace, deployment.Name), func() error {
+				_, err := optimisticupdate.Apply(ctx, r.apiReader(), &deployment, func(obj client.Object) (bool, error) {
+					mutatedDeployment := obj.(*appsv1.Deployment)
+					if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1Deployment(mutatedDeployment); err != nil {
+						return false, fmt.Errorf("cannot prepare mutation of deployment '%s': %w", mutatedDeployment.Name, err)
+					} else if mutationOccurred {
+						addAutoTraceSkipNextInjectorLabel(&mutatedDeployment.ObjectMeta)
+						return true, nil
+					} else {
+						return false, nil
+					}
+				}, r.mutatedResourceWriter(lumigo, audit.ActionUninject, log))
+				return err
+			}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
+				operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger, err)
+				return "", fmt.Errorf("cannot remove instrumentation from deployment '%s': %w", deployment.Name, err)
+			} else {
+				log.Info("Removed instrumentation from deployment", "namespace", deployment.Namespace, "name", deployment.Name)
+				operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger)
+			}
+		}
+
+		return deployments.Continue, nil
+	}); err != nil {
+		return err
+	}
+
+	// Mutate replicasets
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		replicasets, err := r.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced replicasets: %w", err)
+		}
+
+		for _, replicaset := range replicasets.Items {
+			if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s replicaset", replicaset.Namespace, replicaset.Name), func() error {
+				_, err := optimisticupdate.Apply(ctx, r.apiReader(), &replicaset, func(obj client.Object) (bool, error) {
+					mutatedReplicaset := obj.(*appsv1.ReplicaSet)
+					if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1ReplicaSet(mutatedReplicaset); err != nil {
+						return false, fmt.Errorf("cannot prepare mutation of replicaset '%s': %w", mutatedReplicaset.Name, err)
+					} else if mutationOccurred {
+						addAutoTraceSkipNextInjectorLabel(&mutatedReplicaset.ObjectMeta)
+						return true, nil
+					} else {
+						return false, nil
+					}
+				}, r.mutatedResourceWriter(lumigo, audit.ActionUninject, log))
+				return err
+			}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
+				operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger, err)
+				return "", fmt.Errorf("cannot remove instrumentation from replicaset '%s': %w", replicaset.Name, err)
+			} else {
+				log.Info("Removed instrumentation from replicaset", "namespace", replicaset.Namespace, "name", replicaset.Name)
+				operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger)
+			}
+		}
+
+		return replicasets.Continue, nil
+	}); err != nil {
+		return err
+	}
+
+	// Mutate statefulsets
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		statefulsets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced statefulsets: %w", err)
+		}
+
+		for _, statefulset := range statefulsets.Items {
+			if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s statefulset", statefulset.Namespace, statefulset.Name), func() error {
+				_, err := optimisticupdate.Apply(ctx, r.apiReader(), &statefulset, func(obj client.Object) (bool, error) {
+					mutatedStatefulset := obj.(*appsv1.StatefulSet)
+					if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1StatefulSet(mutatedStatefulset); err != nil {
+						return false, fmt.Errorf("cannot prepare mutation of statefulset '%s': %w", mutatedStatefulset.Name, err)
+					} else if mutationOccurred {
+						addAutoTraceSkipNextInjectorLabel(&mutatedStatefulset.ObjectMeta)
+						return true, nil
+					} else {
+						return false, nil
+					}
+				}, r.mutatedResourceWriter(lumigo, audit.ActionUninject, log))
+				return err
+			}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
+				operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger, err)
+				return "", fmt.Errorf("cannot remove instrumentation from statefulset '%s': %w", statefulset.Name, err)
+			} else {
+				log.Info("Removed instrumentation from statefulset", "namespace", statefulset.Namespace, "name", statefulset.Name)
+				operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger)
+			}
+		}
+
+		return statefulsets.Continue, nil
+	}); err != nil {
+		return err
+	}
+
+	// Mutate cronjobs
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		if !r.hasCapability(capabilities.CronJobs) {
+			return "", nil
+		}
+
+		cronjobs, err := r.Clientset.BatchV1().CronJobs(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced cronjobs: %w", err)
+		}
+
+		for _, cronjob := range cronjobs.Items {
+			if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s cronjob", cronjob.Namespace, cronjob.Name), func() error {
+				_, err := optimisticupdate.Apply(ctx, r.apiReader(), &cronjob, func(obj client.Object) (bool, error) {
+					mutatedCronjob := obj.(*batchv1.CronJob)
+					if mutationOccurred, err := mutator.RemoveLumigoFromBatchV1CronJob(mutatedCronjob); err != nil {
+						return false, fmt.Errorf("cannot prepare mutation of cronjob '%s': %w", mutatedCronjob.Name, err)
+					} else if mutationOccurred {
+						addAutoTraceSkipNextInjectorLabel(&mutatedCronjob.ObjectMeta)
+						return true, nil
+					} else {
+						return false, nil
+					}
+				}, r.mutatedResourceWriter(lumigo, audit.ActionUninject, log))
+				return err
+			}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
+				operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger, err)
+				return "", fmt.Errorf("cannot remove instrumentation from cronjob '%s': %w", cronjob.Name, err)
+			} else {
+				log.Info("Removed instrumentation from cronjob", "namespace", cronjob.Namespace, "name", cronjob.Name)
+				operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger)
+			}
+		}
+
+		return cronjobs.Continue, nil
+	}); err != nil {
+		return err
+	}
+
+	// Mutate the ScaledJobs of Keda, whose jobs are created out of their pod templates
+	if err := r.removeLumigoFromKedaScaledJobs(ctx, lumigo, mutator, lumigoAutotracedListOptions, eventTrigger, log); err != nil {
+		return err
+	}
+
+	// Cannot mutate existing jobs: their PodSpecs are immutable!
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced jobs: %w", err)
+		}
+
+		for _, job := range jobs.Items {
+			if isOwnedByKedaScaledJob, _ := mutation.IsOwnedByKedaScaledJob(job.OwnerReferences); isOwnedByKedaScaledJob {
+				// The next jobs of the ScaledJob are created out of its pod template, from which the injection is removed instead
+				continue
+			}
+
+			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &job, eventTrigger, fmt.Errorf("the PodSpec of batchv1.Job resources is immutable once the job has been created"))
+			log.Info("Cannot remove instrumentation from job: jobs are immutable once created", "namespace", job.Namespace, "name", job.Name)
+		}
+
+		return jobs.Continue, nil
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// listKedaScaledJobs lists the ScaledJobs of Keda in the namespace; if Keda is not installed
+// in the cluster, there are none
+func (r *LumigoReconciler) listKedaScaledJobs(ctx context.Context, namespace string, listOptions metav1.ListOptions) ([]unstructured.Unstructured, error) {
+	if !r.hasCapability(capabilities.KedaScaledJobs) {
+		return nil, nil
+	}
+
+	var items []unstructured.Unstructured
+	if err := listpaging.ListAll(ctx, listOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		scaledJobs, err := r.DynamicClient.Resource(mutation.KedaScaledJobGroupVersionResource).Namespace(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", err
+		}
+
+		items = append(items, scaledJobs.Items...)
+		return scaledJobs.GetContinue(), nil
+	}); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *LumigoReconciler) injectLumigoIntoKedaScaledJobs(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, mutator mutation.Mutator, listOptions metav1.ListOptions, eventTrigger string, budget *workloadpacing.Budget, now metav1.Time, log *logr.Logger) error {
+	scaledJobs, err := r.listKedaScaledJobs(ctx, lumigo.Namespace, listOptions)
+	if err != nil {
+		return fmt.Errorf("cannot list non-autotraced scaledjobs: %w", err)
+	}
+
+	for _, scaledJob := range scaledJobs {
+		if isInjectionCircuitBreakerOpen(lumigo, mutation.KedaScaledJobGroupVersionKind.Kind, &scaledJob) {
+			continue
+		}
+
+		if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s scaledjob", scaledJob.GetNamespace(), scaledJob.GetName()), func() error {
+			if err := r.Client.Get(ctx, client.ObjectKey{
+				Namespace: scaledJob.GetNamespace(),
+				Name:      scaledJob.GetName(),
+			}, &scaledJob); err != nil {
+				return fmt.Errorf("cannot retrieve details of scaledjob '%s': %w", scaledJob.GetName(), err)
+			}
+
+			mutatedScaledJob := scaledJob.DeepCopy()
+			if mutationOccurred, err := mutator.InjectLumigoIntoKedaV1alpha1ScaledJob(mutatedScaledJob); err != nil {
+				return fmt.Errorf("cannot prepare mutation of scaledjob '%s': %w", scaledJob.GetName(), err)
+			} else if mutationOccurred {
+				if !budget.Take(now.Time) {
+					return workloadpacing.ErrBudgetExhausted
+				}
+				return r.updateMutatedResource(ctx, lumigo, audit.ActionInject, &scaledJob, mutatedScaledJob, log)
+			} else {
+				return nil
+			}
+		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); errors.Is(err, workloadpacing.ErrBudgetExhausted) {
+			r.enqueuePendingInjection(lumigo, &scaledJob, log)
+		} else if mutation.IsSkipInjectionError(err) {
+			log.Info("Skipped instrumentation of scaledjob", "name", scaledJob.GetName(), "reason", err.Error())
+			r.recordSkippedInstrumentation(&scaledJob, mutation.KedaScaledJobGroupVersionKind.Kind, eventTrigger, err)
+			r.updateInjectionFailure(lumigo, &scaledJob, nil, now, log)
+		} else if err != nil {
+			// The other resources are still injected, and the injection of this one is retried later on
+			log.Error(err, "Cannot add instrumentation to scaledjob", "name", scaledJob.GetName())
+			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &scaledJob, eventTrigger, err)
+			r.updateInjectionFailure(lumigo, &scaledJob, err, now, log)
+		} else {
+			log.Info("Added instrumentation to scaledjob", "name", scaledJob.GetName())
+			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &scaledJob, eventTrigger)
+			r.updateInjectionFailure(lumigo, &scaledJob, nil, now, log)
+		}
+	}
+
+	return nil
+}
+
+// removeLumigoFromKedaScaledJobs removes the injection from the pod templates of the ScaledJobs of Keda;
+// the rest of the ScaledJobs, including the annotations with which Keda pauses their scaling, is left as is
+func (r *LumigoReconciler) removeLumigoFromKedaScaledJobs(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, mutator mutation.Mutator, listOptions metav1.ListOptions, eventTrigger string, log *logr.Logger) error {
+	scaledJobs, err := r.listKedaScaledJobs(ctx, lumigo.Namespace, listOptions)
+	if err != nil {
+		return fmt.Errorf("cannot list autotraced scaledjobs: %w", err)
+	}
+
+	for _, scaledJob := range scaledJobs {
+		if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s scaledjob", scaledJob.GetNamespace(), scaledJob.GetName()), func() error {
+			_, err := optimisticupdate.Apply(ctx, r.apiReader(), &scaledJob, func(obj client.Object) (bool, error) {
+				mutatedScaledJob := obj.(*unstructured.Unstructured)
+				if mutationOccurred, err := mutator.RemoveLumigoFromKedaV1alpha1ScaledJob(mutatedScaledJob); err != nil {
+					return false, fmt.Errorf("cannot prepare mutation of scaledjob '%s': %w", mutatedScaledJob.GetName(), err)
+				} else if mutationOccurred {
+					scaledJobLabels := mutatedScaledJob.GetLabels()
+					if scaledJobLabels == nil {
+						scaledJobLabels = map[string]string{}
+					}
+					scaledJobLabels[mutation.LumigoAutoTraceLabelKey] = mutation.LumigoAutoTraceLabelSkipNextInjectorValue
+					mutatedScaledJob.SetLabels(scaledJobLabels)
+					return true, nil
+				} else {
+					return false, nil
+				}
+			}, r.mutatedResourceWriter(lumigo, audit.ActionUninject, log))
+			return err
+		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
+			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &scaledJob, eventTrigger, err)
+			return fmt.Errorf("cannot remove instrumentation from scaledjob '%s': %w", scaledJob.GetName(), err)
+		} else {
+			log.Info("Removed instrumentation from scaledjob", "namespace", scaledJob.GetNamespace(), "name", scaledJob.GetName())
+			operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &scaledJob, eventTrigger)
+		}
+	}
+
+	return nil
+}
+
+// repairInjectionAnnotations adds the missing injection annotations to the injected resources in the
+// namespace, e.g., those injected by versions of the operator that did not write the annotations.
+func (r *LumigoReconciler) repairInjectionAnnotations(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) error {
+	now := time.Now()
+	namespace := lumigo.Namespace
+
+	for _, list := range []client.ObjectList{
+		&appsv1.DaemonSetList{},
+		&appsv1.DeploymentList{},
+		&appsv1.ReplicaSetList{},
+		&appsv1.StatefulSetList{},
+		&batchv1.CronJobList{},
+		&batchv1.JobList{},
+	} {
+		if err := r.Client.List(ctx, list, client.InNamespace(namespace), client.HasLabels{mutation.LumigoAutoTraceLabelKey}); err != nil {
+			return fmt.Errorf("cannot list autotraced resources: %w", err)
+		}
+
+		items, err := apimeta.ExtractList(list)
+		if err != nil {
+			return fmt.Errorf("cannot extract the autotraced resources: %w", err)
+		}
+
+		for _, item := range items {
+			resource := item.(client.Object)
+			if !resource.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			original := resource.DeepCopyObject().(client.Object)
+			if isChanged, err := mutation.RepairInjectionAnnotations(resource, now); err != nil {
+				return fmt.Errorf("cannot repair the injection annotations of '%s': %w", resource.GetName(), err)
+			} else if !isChanged {
+				continue
+			}
+
+			if err := r.updateMutatedResource(ctx, lumigo, audit.ActionRepairAnnotations, original, resource, log); err != nil {
+				if apierrors.IsConflict(err) {
+					// The resource has been modified meanwhile; we will try again at the next reconciliation
+					continue
+				}
+
+				return fmt.Errorf("cannot update the injection annotations of '%s': %w", resource.GetName(), err)
+			}
+
+			log.Info("Repaired the injection annotations", "kind", reflect.TypeOf(resource).Elem().Name(), "name", resource.GetName())
+		}
+	}
+
+	return nil
+}
+
+// updateMutatedResource updates a resource mutated by the operator and, if auditing is enabled, records the mutation
+func (r *LumigoReconciler) updateMutatedResource(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, action audit.Action, original client.Object, mutated client.Object, log *logr.Logger) error {
+	// The entry is created before the update, which changes fields of the mutated resource like its resource version
+	var entry *audit.AuditEntry
+	if r.Auditor != nil {
+		var err error
+		if entry, err = audit.NewEntry(action, audit.ActorController, lumigo, original, mutated); err != nil {
+			log.Error(err, "Cannot create the audit entry of the mutation", "namespace", mutated.GetNamespace(), "name", mutated.GetName())
+		}
+	}
+
+	// Only the changes of the operator are sent, and they conflict with the concurrent updates of the resource
+	if err := r.Client.Patch(ctx, mutated, optimisticupdate.NewPatch(original)); err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if err := r.Auditor.Record(ctx, entry); err != nil {
+			log.Error(err, "Cannot record the audit entry of the mutation", "namespace", mutated.GetNamespace(), "name", mutated.GetName())
+		}
+	}
+
+	return nil
+}
+
+// mutatedResourceWriter returns the writer of the mutations of resources applied with optimisticupdate.Apply
+func (r *LumigoReconciler) mutatedResourceWriter(lumigo *operatorv1alpha1.Lumigo, action audit.Action, log *logr.Logger) optimisticupdate.WriteFunc {
+	return func(ctx context.Context, original client.Object, mutated client.Object) error {
+		return r.updateMutatedResource(ctx, lumigo, action, original, mutated, log)
+	}
+}
+
+// apiReader returns the reader of the latest versions of the resources to be mutated
+func (r *LumigoReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+func (r *LumigoReconciler) getInstrumentedObjectReferences(ctx context.Context, namespace string) (*[]corev1.ObjectReference, error) {
+	objectReferences := make([]corev1.ObjectReference, 0)
+
+	lumigoAutotracedListOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%[1]s,%[1]s != false", mutation.LumigoAutoTraceLabelKey),
+	}
+
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		daemonSets, err := r.Clientset.AppsV1().DaemonSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced daemonsets: %w", err)
+		}
+
+		sort.Sort(sorting.ByDaemonsetName(daemonSets.Items))
+		for _, daemonSet := range daemonSets.Items {
+			objectReference, err := reference.GetReference(scheme.Scheme, &daemonSet)
+			if err != nil {
+				return "", err
+			}
+			objectReferences = append(objectReferences, *objectReference)
+		}
+
+		return daemonSets.Continue, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced deployments: %w", err)
+		}
+
+		sort.Sort(sorting.ByDeploymentName(deployments.Items))
+		for _, deployment := range deployments.Items {
+			objectReference, err := reference.GetReference(scheme.Scheme, &deployment)
+			if err != nil {
+				return "", err
+			}
+			objectReferences = append(objectReferences, *objectReference)
+		}
+
+		return deployments.Continue, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		replicaSets, err := r.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced replicasets: %w", err)
+		}
+
+		sort.Sort(sorting.ByReplicaSetName(replicaSets.Items))
+		for _, replicaSet := range replicaSets.Items {
+			objectReference, err := reference.GetReference(scheme.Scheme, &replicaSet)
+			if err != nil {
+				return "", err
 			}
+			objectReferences = append(objectReferences, *objectReference)
 		}
+
+		return replicaSets.Continue, nil
+	}); err != nil {
+		return nil, err
 	}
 
-	return reconcileRequests
-}
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		statefulSets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced statefulsets: %w", err)
+		}
 
-func (r *LumigoReconciler) enqueueIfHasLumigoAutotraceLabel(obj client.Object) []reconcile.Request {
-	reconcileRequests := []reconcile.Request{{}}
+		sort.Sort(sorting.ByStatefulSetName(statefulSets.Items))
+		for _, statefulSet := range statefulSets.Items {
+			objectReference, err := reference.GetReference(scheme.Scheme, &statefulSet)
+			if err != nil {
+				return "", err
+			}
+			objectReferences = append(objectReferences, *objectReference)
+		}
 
-	if _, ok := obj.GetLabels()[mutation.LumigoAutoTraceLabelKey]; ok {
-		namespace := obj.GetNamespace()
-		lumigoes := &operatorv1alpha1.LumigoList{}
+		return statefulSets.Continue, nil
+	}); err != nil {
+		return nil, err
+	}
 
-		if err := r.Client.List(context.TODO(), lumigoes, &client.ListOptions{Namespace: namespace}); err != nil {
-			r.Log.Error(err, "unable to list Lumigo instances in namespace '%s'", namespace)
-			// TODO Can we re-enqueue or something? Should we signal an error in the Lumigo operator?
-			return reconcileRequests
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		if !r.hasCapability(capabilities.CronJobs) {
+			return "", nil
 		}
 
-		for _, lumigo := range lumigoes.Items {
-			if conditions.IsActive(&lumigo) {
-				reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: types.NamespacedName{
-					Namespace: lumigo.Namespace,
-					Name:      lumigo.Name,
-				}})
+		cronJobs, err := r.Clientset.BatchV1().CronJobs(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced cronjobs: %w", err)
+		}
+
+		sort.Sort(sorting.ByCronJobName(cronJobs.Items))
+		for _, cronJob := range cronJobs.Items {
+			objectReference, err := reference.GetReference(scheme.Scheme, &cronJob)
+			if err != nil {
+				return "", err
 			}
+			objectReferences = append(objectReferences, *objectReference)
 		}
+
+		return cronJobs.Continue, nil
+	}); err != nil {
+		return nil, err
 	}
 
-	return reconcileRequests
-}
+	if err := listpaging.ListAll(ctx, lumigoAutotracedListOptions, func(ctx context.Context, listOptions metav1.ListOptions) (string, error) {
+		jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, listOptions)
+		if err != nil {
+			return "", fmt.Errorf("cannot list autotraced jobs: %w", err)
+		}
 
-func (r *LumigoReconciler) updateStatusIfNeeded(ctx context.Context, logger logr.Logger, instance *operatorv1alpha1.Lumigo, result ctrl.Result) (ctrl.Result, error) {
-	// Updates the status of a Lumigo instance. Unfortunately updates do not seem reliable due
-	// to some mismatch between the results of apiequality.Semantic.DeepEqual() and Kubernetes'
-	// API (maybe due to bugs, maybe due to eventual consistency), which causes updates to be lost.
-	// To reduce the risk of losing updates, we reque the request after a grace period.
+		sort.Sort(sorting.ByJobName(jobs.Items))
+		for _, job := range jobs.Items {
+			objectReference, err := reference.GetReference(scheme.Scheme, &job)
+			if err != nil {
+				return "", err
+			}
+			objectReferences = append(objectReferences, *objectReference)
+		}
 
-	// Ensure status is valid (e.g., Lumigo instance just created and has no resources instrumented)
-	if instance.Status.InstrumentedResources == nil {
-		instance.Status.InstrumentedResources = make([]corev1.ObjectReference, 0)
+		return jobs.Continue, nil
+	}); err != nil {
+		return nil, err
 	}
 
-	if err := r.Client.Status().Update(ctx, instance); err != nil {
-		logger.Error(err, "unable to update Lumigo instance's status")
-		return ctrl.Result{RequeueAfter: defaultErrRequeuePeriod}, nil
+	return &objectReferences, nil
+}
+
+func (r *LumigoReconciler) updateUnsupportedFeaturesCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
+	unsupportedFeatures := platform.GetUnsupportedFeatures(r.Platform, &lumigo.Spec, r.UnsupportedPlatformFeatures)
+	if len(unsupportedFeatures) == 0 {
+		conditions.SetUnsupportedFeaturesCondition(lumigo, now, false, "")
+		return
 	}
 
-	logger.Info("Status updated", "status", &instance.Status)
+	conditions.SetUnsupportedFeaturesCondition(lumigo, now, true, platform.DescribeUnsupportedFeatures(r.Platform, unsupportedFeatures))
+}
 
-	if hasError, _ := conditions.HasError(instance); hasError {
-		return ctrl.Result{RequeueAfter: defaultErrRequeuePeriod}, nil
+func (r *LumigoReconciler) updateUnsupportedOnThisClusterCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
+	unsupportedSettings := capabilities.GetUnsupportedSettings(r.CapabilitiesDiscoverer.Get(now.Time), &lumigo.Spec)
+	if len(unsupportedSettings) == 0 {
+		conditions.SetUnsupportedOnThisClusterCondition(lumigo, now, false, "")
+		return
 	}
 
-	return ctrl.Result{RequeueAfter: defaultRequeuePeriod}, nil
+	conditions.SetUnsupportedOnThisClusterCondition(lumigo, now, true, fmt.Sprintf("The following settings are not supported on this cluster, and have no effect: %s", strings.Join(unsupportedSettings, "; ")))
 }
 
-func (r *LumigoReconciler) injectLumigoIntoResources(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) error {
-	mutator, err := mutation.NewMutator(log, &lumigo.Spec, r.LumigoOperatorVersion, r.LumigoInjectorImage, r.TelemetryProxyOtlpServiceUrl, r.TelemetryProxyOtlpLogsServiceUrl)
-	if err != nil {
-		return fmt.Errorf("cannot instantiate mutator: %w", err)
+// Returns whether the cluster serves the API resources the capability needs
+func (r *LumigoReconciler) hasCapability(capability capabilities.Capability) bool {
+	return r.CapabilitiesDiscoverer.Get(time.Now()).Has(capability)
+}
+
+// The defaulter webhook rejects the inconsistencies of new Lumigo instances, but not those of the instances
+// created before a check was added, nor those of the instances created while the webhook was unavailable
+func (r *LumigoReconciler) updateInconsistentSpecCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
+	inconsistencies := specvalidation.GetInconsistencies(&lumigo.Spec)
+	if len(inconsistencies) == 0 {
+		conditions.SetInconsistentSpecCondition(lumigo, now, false, "")
+		return
 	}
 
-	namespace := lumigo.Namespace
+	conditions.SetInconsistentSpecCondition(lumigo, now, true, fmt.Sprintf("The spec has inconsistent settings: %s", strings.Join(inconsistencies, "; ")))
+}
 
-	lumigoWithoutAutotraceLabelListOptions := metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("!%s", mutation.LumigoAutoTraceLabelKey),
+func (r *LumigoReconciler) updateRateLimitedCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, log *logr.Logger) {
+	if lumigo.Spec.Tracing.MaxSpansPerSecond == nil {
+		conditions.SetRateLimitedCondition(lumigo, now, false, "")
+		return
 	}
 
-	// Ensure that all the resources that could be injected, are injected
-	// TODO What to do about upgrades from former controller versions?
-	lumigoNotAutotracedLabelFalseOrNotSet, err := labels.NewRequirement(mutation.LumigoAutoTraceLabelKey, selection.NotIn, []string{"false", mutator.GetAutotraceLabelValue()})
+	rateLimitingStatus, err := telemetryproxyconfigs.GetRateLimitingStatusOfNamespace(r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace)
 	if err != nil {
-		return fmt.Errorf("cannot create label selector for non-autotraced objects: %w", err)
+		log.Error(err, "Cannot read the rate-limiting status of the namespace from the telemetry-proxy")
+		return
 	}
 
-	lumigoNotAutotracedLabelSelector := labels.NewSelector()
-	lumigoNotAutotracedLabelSelector.Add(*lumigoNotAutotracedLabelFalseOrNotSet)
+	if rateLimitingStatus == nil || now.Sub(rateLimitingStatus.LastDroppedAt) > rateLimitingWindow {
+		conditions.SetRateLimitedCondition(lumigo, now, false, "")
+		return
+	}
 
-	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+	conditions.SetRateLimitedCondition(lumigo, now, true, fmt.Sprintf(
+		"The telemetry-proxy dropped spans exceeding the limit of %d spans per second (%d dropped in total, last at %s)",
+		*lumigo.Spec.Tracing.MaxSpansPerSecond,
+		rateLimitingStatus.DroppedSpans,
+		rateLimitingStatus.LastDroppedAt.Format(time.RFC3339),
+	))
+}
 
-	// Mutate daemonsets
-	daemonsets, err := r.Clientset.AppsV1().DaemonSets(namespace).List(ctx, lumigoWithoutAutotraceLabelListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list non-autotraced daemonsets: %w", err)
+func (r *LumigoReconciler) updateQuotaExceededCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, log *logr.Logger) {
+	quotaConfig := newQuotaConfig(&lumigo.Spec.Quota)
+	if quotaConfig == nil {
+		conditions.SetQuotaExceededCondition(lumigo, now, false, "")
+		return
 	}
 
-	for _, daemonset := range daemonsets.Items {
-		if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s daemonset", daemonset.Namespace, daemonset.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: daemonset.Namespace,
-				Name:      daemonset.Name,
-			}, &daemonset); err != nil {
-				return fmt.Errorf("cannot retrieve details of daemonset '%s': %w", daemonset.GetName(), err)
-			}
+	rateLimitingStatus, err := telemetryproxyconfigs.GetRateLimitingStatusOfNamespace(r.TelemetryProxyNamespaceConfigurationsPath, lumigo.Namespace)
+	if err != nil {
+		log.Error(err, "Cannot read the quota usage of the namespace from the telemetry-proxy")
+		return
+	}
 
-			mutatedDaemonset := daemonset.DeepCopy()
-			if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1DaemonSet(mutatedDaemonset); err != nil {
-				return fmt.Errorf("cannot prepare mutation of daemonset '%s': %w", daemonset.GetName(), err)
-			} else if mutationOccurred {
-				return r.Client.Update(ctx, mutatedDaemonset)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger, err)
-			return fmt.Errorf("cannot add instrumentation to daemonset '%s': %w", daemonset.GetName(), err)
-		} else {
-			log.Info("Added instrumentation to daemonset", "name", daemonset.Name)
-			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger)
-		}
+	// The quotas reset at midnight UTC, so the usage of the previous days does not count
+	if rateLimitingStatus == nil || rateLimitingStatus.Quota == nil || rateLimitingStatus.Quota.ExceededAt == nil || rateLimitingStatus.Quota.Day != now.UTC().Format(quotaDayFormat) {
+		conditions.SetQuotaExceededCondition(lumigo, now, false, "")
+		return
 	}
 
-	// Mutate deployments
-	deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, lumigoWithoutAutotraceLabelListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list non-autotraced deployments: %w", err)
+	quotaStatus := rateLimitingStatus.Quota
+	conditions.SetQuotaExceededCondition(lumigo, now, true, fmt.Sprintf(
+		"The namespace exceeded its daily quota at %s, and the telemetry-proxy keeps %d%% of its traces until midnight UTC (today: %d spans and %d bytes accepted, %d spans dropped)",
+		quotaStatus.ExceededAt.Format(time.RFC3339),
+		quotaConfig.SamplingPercentage,
+		quotaStatus.AcceptedSpans,
+		quotaStatus.AcceptedBytes,
+		quotaStatus.DroppedSpans,
+	))
+}
+
+func (r *LumigoReconciler) updateTelemetryExportDegradedCondition(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, now metav1.Time, log *logr.Logger) {
+	if r.TelemetryProxyExportMonitor == nil {
+		return
 	}
 
-	for _, deployment := range deployments.Items {
-		if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s deployment", deployment.Namespace, deployment.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: deployment.Namespace,
-				Name:      deployment.Name,
-			}, &deployment); err != nil {
-				return fmt.Errorf("cannot retrieve details of deployment '%s': %w", deployment.GetName(), err)
-			}
+	if err := r.TelemetryProxyExportMonitor.Refresh(ctx); err != nil {
+		log.Error(err, "Cannot retrieve the metrics of the telemetry-proxy")
+		return
+	}
 
-			mutatedDeployment := deployment.DeepCopy()
-			if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1Deployment(mutatedDeployment); err != nil {
-				return fmt.Errorf("cannot prepare mutation of deployment '%s': %w", deployment.GetName(), err)
-			} else if mutationOccurred {
-				return r.Client.Update(ctx, mutatedDeployment)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger, err)
-			return fmt.Errorf("cannot add instrumentation to deployment '%s': %w", deployment.GetName(), err)
-		} else {
-			log.Info("Added instrumentation to deployment", "name", deployment.Name)
-			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger)
+	// The exporters of traces and logs are shared by all namespaces, the others are specific to this one
+	degradedExporters := []string{}
+	var failedItems float64
+	for _, exporterName := range []string{"otlphttp/lumigo", "otlphttp/lumigo_logs", fmt.Sprintf("otlphttp/lumigo_ns_%s", lumigo.Namespace)} {
+		if isDegraded, failed := r.TelemetryProxyExportMonitor.IsExporterDegraded(exporterName, telemetryExportDegradedMinDuration); isDegraded {
+			degradedExporters = append(degradedExporters, exporterName)
+			failedItems += failed
 		}
 	}
 
-	// Mutate replicasets
-	replicasets, err := r.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, lumigoWithoutAutotraceLabelListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list non-autotraced replicasets: %w", err)
+	if len(degradedExporters) < 1 {
+		conditions.SetTelemetryExportDegradedCondition(lumigo, now, false, "")
+		return
 	}
 
-	for _, replicaset := range replicasets.Items {
-		if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s replicaset", replicaset.Namespace, replicaset.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: replicaset.Namespace,
-				Name:      replicaset.Name,
-			}, &replicaset); err != nil {
-				return fmt.Errorf("cannot retrieve details of replicaset '%s': %w", replicaset.GetName(), err)
-			}
+	conditions.SetTelemetryExportDegradedCondition(lumigo, now, true, fmt.Sprintf(
+		"The telemetry-proxy has been failing for at least %s to send telemetry to Lumigo, without any success, via the exporters %s; %.0f spans, metric points or log records failed to be sent",
+		telemetryExportDegradedMinDuration,
+		strings.Join(degradedExporters, ", "),
+		failedItems,
+	))
+}
 
-			mutatedReplicaset := replicaset.DeepCopy()
-			if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1ReplicaSet(mutatedReplicaset); err != nil {
-				return fmt.Errorf("cannot prepare mutation of replicaset '%s': %w", replicaset.GetName(), err)
-			} else if mutationOccurred {
-				return r.Client.Update(ctx, mutatedReplicaset)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger, err)
-			return fmt.Errorf("cannot add instrumentation to replicaset '%s': %w", replicaset.GetName(), err)
-		} else {
-			log.Info("Added instrumentation to replicaset", "name", replicaset.Name)
-			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger)
+// The usage is tracked in memory by the export monitor, and the status of the Lumigo instance is where it
+// survives restarts of the controller: the first reconciliation of each namespace restores it from there.
+func (r *LumigoReconciler) updateDailyUsage(lumigo *operatorv1alpha1.Lumigo) {
+	if r.TelemetryProxyExportMonitor == nil {
+		return
+	}
+
+	restoredUsage := make(map[string]telemetryproxymetrics.NamespaceUsage, len(lumigo.Status.DailyUsage))
+	for _, dailyUsage := range lumigo.Status.DailyUsage {
+		restoredUsage[dailyUsage.Day] = telemetryproxymetrics.NamespaceUsage{
+			Spans:        dailyUsage.Spans,
+			LogRecords:   dailyUsage.LogRecords,
+			MetricPoints: dailyUsage.MetricPoints,
+			Bytes:        dailyUsage.Bytes,
 		}
 	}
+	r.TelemetryProxyExportMonitor.RestoreNamespaceUsage(lumigo.Namespace, restoredUsage)
 
-	// Mutate statefulsets
-	statefulsets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, lumigoWithoutAutotraceLabelListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list non-autotraced statefulsets: %w", err)
+	usage := r.TelemetryProxyExportMonitor.GetNamespaceUsage(lumigo.Namespace)
+	if len(usage) < 1 {
+		lumigo.Status.DailyUsage = nil
+		return
 	}
 
-	for _, statefulset := range statefulsets.Items {
-		if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s statefulset", statefulset.Namespace, statefulset.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: statefulset.Namespace,
-				Name:      statefulset.Name,
-			}, &statefulset); err != nil {
-				return fmt.Errorf("cannot retrieve details of statefulset '%s': %w", statefulset.GetName(), err)
-			}
+	dailyUsages := make([]operatorv1alpha1.DailyUsage, 0, len(usage))
+	for _, day := range telemetryproxymetrics.SortedUsageDays(usage) {
+		dailyUsages = append(dailyUsages, operatorv1alpha1.DailyUsage{
+			Day:          day,
+			Spans:        usage[day].Spans,
+			LogRecords:   usage[day].LogRecords,
+			MetricPoints: usage[day].MetricPoints,
+			Bytes:        usage[day].Bytes,
+		})
+	}
+	lumigo.Status.DailyUsage = dailyUsages
+}
 
-			mutatedStatefulset := statefulset.DeepCopy()
-			if mutationOccurred, err := mutator.InjectLumigoIntoAppsV1StatefulSet(mutatedStatefulset); err != nil {
-				return fmt.Errorf("cannot prepare mutation of statefulset '%s': %w", statefulset.GetName(), err)
-			} else if mutationOccurred {
-				return r.Client.Update(ctx, mutatedStatefulset)
-			} else {
-				return nil
+// The analysis of the workloads lists all of them and dry-runs their updates, so it is refreshed only
+// every few minutes rather than at each reconciliation
+func (r *LumigoReconciler) updateCompatibilityReport(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, now metav1.Time, log *logr.Logger) {
+	if !isTruthy(lumigo.Spec.Tracing.Injection.CompatibilityReport.Enabled, false) {
+		if lumigo.Status.CompatibilityReport != nil {
+			if err := compatibility.RemoveReportConfigMap(ctx, r.Client, lumigo.Namespace); err != nil {
+				log.Error(err, "Cannot remove the compatibility report of the namespace")
+				return
 			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger, err)
-			return fmt.Errorf("cannot add instrumentation to statefulset '%s': %w", statefulset.GetName(), err)
-		} else {
-			log.Info("Added instrumentation to statefulset", "name", statefulset.Name)
-			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger)
+			lumigo.Status.CompatibilityReport = nil
 		}
+		return
 	}
 
-	// Mutate cronjobs
-	cronjobs, err := r.Clientset.BatchV1().CronJobs(namespace).List(ctx, lumigoWithoutAutotraceLabelListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list non-autotraced cronjobs: %w", err)
+	if !compatibility.IsDueForRefresh(lumigo, now) {
+		return
 	}
 
-	for _, cronjob := range cronjobs.Items {
-		if err := retry(fmt.Sprintf("inject instrumentation into the %s/%s cronjob", cronjob.Namespace, cronjob.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: cronjob.Namespace,
-				Name:      cronjob.Name,
-			}, &cronjob); err != nil {
-				return fmt.Errorf("cannot retrieve details of cronjob '%s': %w", cronjob.GetName(), err)
-			}
-
-			mutatedCronjob := cronjob.DeepCopy()
-			if mutationOccurred, err := mutator.InjectLumigoIntoBatchV1CronJob(mutatedCronjob); err != nil {
-				return fmt.Errorf("cannot prepare mutation of cronjob '%s': %w", cronjob.GetName(), err)
-			} else if mutationOccurred {
-				return r.Client.Update(ctx, mutatedCronjob)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger, err)
-			return fmt.Errorf("cannot add instrumentation to cronjob '%s': %w", cronjob.GetName(), err)
-		} else {
-			log.Info("Added instrumentation to cronjob", "name", cronjob.Name)
-			operatorv1alpha1.RecordAddedInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger)
-		}
+	telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := r.telemetryProxyOtlpServiceUrls(lumigo)
+	mutator, err := mutation.NewMutator(log, injectionspec.EffectiveSpec(lumigo), r.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		log.Error(err, "Cannot instantiate mutator for the compatibility report")
+		return
 	}
 
-	// Cannot mutate existing jobs: their PodSpecs are immutable!
-	jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, lumigoWithoutAutotraceLabelListOptions)
+	report, err := compatibility.Analyze(ctx, r.Client, mutator, lumigo.Namespace, now)
 	if err != nil {
-		return fmt.Errorf("cannot list autotraced jobs: %w", err)
+		log.Error(err, "Cannot analyze the compatibility of the workloads of the namespace")
+		return
 	}
 
-	for _, job := range jobs.Items {
-		operatorv1alpha1.RecordCannotAddInstrumentationEvent(r.EventRecorder, &job, eventTrigger, fmt.Errorf("the PodSpec of batchv1.Job resources is immutable once the job has been created"))
-		log.Info("Cannot instrumentation job: jobs are immutable once created", "namespace", job.Namespace, "name", job.Name)
+	if err := compatibility.UpsertReportConfigMap(ctx, r.Client, lumigo.Namespace, report); err != nil {
+		log.Error(err, "Cannot write the compatibility report of the namespace")
+		return
 	}
 
-	return nil
+	lumigo.Status.CompatibilityReport = compatibility.NewReportStatus(report)
 }
 
-func (r *LumigoReconciler) removeLumigoFromResources(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, log *logr.Logger) error {
-	namespace := lumigo.Namespace
+// Whether the secret referenced by the credentials, or its key, does not exist, as opposed to, e.g., holding
+// a malformed token or not being retrievable
+func (r *LumigoReconciler) isTokenSecretMissing(ctx context.Context, namespaceName string, credentials *operatorv1alpha1.Credentials) bool {
+	if len(credentials.Value) > 0 {
+		return false
+	}
 
-	mutator, err := mutation.NewMutator(log, nil, r.LumigoOperatorVersion, r.LumigoInjectorImage, r.TelemetryProxyOtlpServiceUrl, r.TelemetryProxyOtlpLogsServiceUrl)
+	secret, err := r.fetchKubernetesSecret(ctx, namespaceName, credentials.SecretRef.Name)
 	if err != nil {
-		return fmt.Errorf("cannot instantiate mutator: %w", err)
+		return apierrors.IsNotFound(err)
 	}
 
-	lumigoAutotracedListOptions := metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%[1]s,%[1]s != false", mutation.LumigoAutoTraceLabelKey),
-	}
+	_, hasKey := secret.Data[credentials.SecretRef.Key]
+	return !hasKey
+}
 
-	eventTrigger := fmt.Sprintf("controller, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name)
+func (r *LumigoReconciler) updateTokenMissingCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time, isTokenMissing bool, err error) {
+	wasTokenMissing := conditions.IsTokenMissing(lumigo)
 
-	// Mutate daemonsets
-	daemonsets, err := r.Clientset.AppsV1().DaemonSets(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list autotraced daemonsets: %w", err)
+	if !isTokenMissing {
+		conditions.SetTokenMissingCondition(lumigo, now, false, "")
+
+		if wasTokenMissing {
+			operatorv1alpha1.RecordTokenFoundEvent(r.EventRecorder, lumigo)
+		}
+		return
 	}
 
-	for _, daemonset := range daemonsets.Items {
-		if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s daemonset", daemonset.Namespace, daemonset.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: daemonset.Namespace,
-				Name:      daemonset.Name,
-			}, &daemonset); err != nil {
-				return fmt.Errorf("cannot retrieve details of daemonset '%s': %w", daemonset.GetName(), err)
-			}
+	message := err.Error()
+	if lumigo.Spec.Tracing.Injection.TokenMissingPolicy == operatorv1alpha1.TokenMissingPolicyKeepInjecting {
+		message += "; resources are injected with the last valid Lumigo token"
+	} else {
+		message += "; resources are no longer injected"
+	}
+	conditions.SetTokenMissingCondition(lumigo, now, true, message)
 
-			mutatedDaemonset := daemonset.DeepCopy()
-			if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1DaemonSet(mutatedDaemonset); err != nil {
-				return fmt.Errorf("cannot prepare mutation of daemonset '%s': %w", mutatedDaemonset.Name, err)
-			} else if mutationOccurred {
-				addAutoTraceSkipNextInjectorLabel(&mutatedDaemonset.ObjectMeta)
-				return r.Client.Update(ctx, mutatedDaemonset)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger, err)
-			return fmt.Errorf("cannot remove instrumentation from daemonset '%s': %w", daemonset.Name, err)
-		} else {
-			log.Info("Removed instrumentation from daemonset", "namespace", daemonset.Namespace, "name", daemonset.Name)
-			operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &daemonset, eventTrigger)
-		}
+	// Record the event only when the token goes missing, not at every reconciliation
+	if !wasTokenMissing {
+		operatorv1alpha1.RecordTokenMissingEvent(r.EventRecorder, lumigo, message)
 	}
+}
 
-	// Mutate deployments
-	deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list autotraced deployments: %w", err)
+func (r *LumigoReconciler) updateBackendUnreachableCondition(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
+	reasons := []string{}
+
+	if r.LumigoBackendProbe != nil {
+		r.LumigoBackendProbe.Probe(ctx)
+
+		if isUnreachable, err := r.LumigoBackendProbe.IsUnreachable(backendUnreachableMinDuration); isUnreachable {
+			reasons = append(reasons, fmt.Sprintf("the Lumigo backend has not been reachable for at least %s (%v)", backendUnreachableMinDuration, err))
+		}
 	}
 
-	for _, deployment := range deployments.Items {
-		if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s deployment", deployment.Namespace, deployment.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: deployment.Namespace,
-				Name:      deployment.Name,
-			}, &deployment); err != nil {
-				return fmt.Errorf("cannot retrieve details of deployment '%s': %w", deployment.GetName(), err)
+	// The metrics of the telemetry-proxy are refreshed when updating the TelemetryExportDegraded condition
+	if r.TelemetryProxyExportMonitor != nil {
+		// Unlike the exporters of the namespace-specific telemetry, these exporters are shared by all
+		// namespaces, so their failures mean that no namespace can send telemetry to Lumigo
+		for _, exporterName := range []string{"otlphttp/lumigo", "otlphttp/lumigo_logs"} {
+			if isDegraded, _ := r.TelemetryProxyExportMonitor.IsExporterDegraded(exporterName, backendUnreachableMinDuration); isDegraded {
+				reasons = append(reasons, fmt.Sprintf("the '%s' exporter of the telemetry-proxy has been failing to send telemetry for at least %s", exporterName, backendUnreachableMinDuration))
 			}
 
-			mutatedDeployment := deployment.DeepCopy()
-			if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1Deployment(mutatedDeployment); err != nil {
-				return fmt.Errorf("cannot prepare mutation of deployment '%s': %w", mutatedDeployment.Name, err)
-			} else if mutationOccurred {
-				addAutoTraceSkipNextInjectorLabel(&mutatedDeployment.ObjectMeta)
-				return r.Client.Update(ctx, mutatedDeployment)
-			} else {
-				return nil
+			// A full sending queue means the backend does not accept telemetry as fast as it is produced
+			if enqueueFailures := r.TelemetryProxyExportMonitor.GetEnqueueFailures(exporterName); enqueueFailures > 0 {
+				reasons = append(reasons, fmt.Sprintf("the sending queue of the '%s' exporter of the telemetry-proxy is full, and %.0f spans or log records have been dropped", exporterName, enqueueFailures))
 			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger, err)
-			return fmt.Errorf("cannot remove instrumentation from deployment '%s': %w", deployment.Name, err)
-		} else {
-			log.Info("Removed instrumentation from deployment", "namespace", deployment.Namespace, "name", deployment.Name)
-			operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &deployment, eventTrigger)
 		}
 	}
 
-	// Mutate replicasets
-	replicasets, err := r.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list autotraced replicasets: %w", err)
-	}
+	wasUnreachable := conditions.IsBackendUnreachable(lumigo)
 
-	for _, replicaset := range replicasets.Items {
-		if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s replicaset", replicaset.Namespace, replicaset.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: replicaset.Namespace,
-				Name:      replicaset.Name,
-			}, &replicaset); err != nil {
-				return fmt.Errorf("cannot retrieve details of replicaset '%s': %w", replicaset.GetName(), err)
-			}
+	if len(reasons) < 1 {
+		conditions.SetBackendUnreachableCondition(lumigo, now, false, "")
 
-			mutatedReplicaset := replicaset.DeepCopy()
-			if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1ReplicaSet(mutatedReplicaset); err != nil {
-				return fmt.Errorf("cannot prepare mutation of replicaset '%s': %w", mutatedReplicaset.Name, err)
-			} else if mutationOccurred {
-				addAutoTraceSkipNextInjectorLabel(&mutatedReplicaset.ObjectMeta)
-				return r.Client.Update(ctx, mutatedReplicaset)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger, err)
-			return fmt.Errorf("cannot remove instrumentation from replicaset '%s': %w", replicaset.Name, err)
-		} else {
-			log.Info("Removed instrumentation from replicaset", "namespace", replicaset.Namespace, "name", replicaset.Name)
-			operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &replicaset, eventTrigger)
+		if wasUnreachable {
+			operatorv1alpha1.RecordBackendReachableEvent(r.EventRecorder, lumigo)
 		}
+		return
 	}
 
-	// Mutate statefulsets
-	statefulsets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list autotraced statefulsets: %w", err)
+	message := strings.Join(reasons, "; ")
+	conditions.SetBackendUnreachableCondition(lumigo, now, true, message)
+
+	// Record the event only when the backend becomes unreachable, not at every reconciliation
+	if !wasUnreachable {
+		operatorv1alpha1.RecordBackendUnreachableEvent(r.EventRecorder, lumigo, message)
+	}
+}
+
+func (r *LumigoReconciler) updateTelemetryProxyUnreachableCondition(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
+	if r.TelemetryProxyProbe == nil {
+		return
 	}
 
-	for _, statefulset := range statefulsets.Items {
-		if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s statefulset", statefulset.Namespace, statefulset.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: statefulset.Namespace,
-				Name:      statefulset.Name,
-			}, &statefulset); err != nil {
-				return fmt.Errorf("cannot retrieve details of statefulset '%s': %w", statefulset.GetName(), err)
-			}
+	tracesUrl, logsUrl := r.telemetryProxyOtlpServiceUrls(lumigo)
+	r.TelemetryProxyProbe.Probe(ctx, tracesUrl, logsUrl)
 
-			mutatedStatefulset := statefulset.DeepCopy()
-			if mutationOccurred, err := mutator.RemoveLumigoFromAppsV1StatefulSet(mutatedStatefulset); err != nil {
-				return fmt.Errorf("cannot prepare mutation of statefulset '%s': %w", mutatedStatefulset.Name, err)
-			} else if mutationOccurred {
-				addAutoTraceSkipNextInjectorLabel(&mutatedStatefulset.ObjectMeta)
-				return r.Client.Update(ctx, mutatedStatefulset)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger, err)
-			return fmt.Errorf("cannot remove instrumentation from statefulset '%s': %w", statefulset.Name, err)
-		} else {
-			log.Info("Removed instrumentation from statefulset", "namespace", statefulset.Namespace, "name", statefulset.Name)
-			operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &statefulset, eventTrigger)
+	reasons := []string{}
+	for _, otlpUrl := range []string{tracesUrl, logsUrl} {
+		if isUnreachable, err := r.TelemetryProxyProbe.IsUnreachable(otlpUrl, telemetryProxyUnreachableMinDuration); isUnreachable {
+			reasons = append(reasons, fmt.Sprintf("the telemetry-proxy has not been reachable for at least %s (%v)", telemetryProxyUnreachableMinDuration, err))
 		}
 	}
 
-	// Mutate cronjobs
-	cronjobs, err := r.Clientset.BatchV1().CronJobs(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list autotraced cronjobs: %w", err)
-	}
+	wasUnreachable := conditions.IsTelemetryProxyUnreachable(lumigo)
 
-	for _, cronjob := range cronjobs.Items {
-		if err := retry(fmt.Sprintf("remove instrumentation from the %s/%s cronjob", cronjob.Namespace, cronjob.Name), func() error {
-			if err := r.Client.Get(ctx, client.ObjectKey{
-				Namespace: cronjob.Namespace,
-				Name:      cronjob.Name,
-			}, &cronjob); err != nil {
-				return fmt.Errorf("cannot retrieve details of cronjob '%s': %w", cronjob.GetName(), err)
-			}
+	if len(reasons) < 1 {
+		conditions.SetTelemetryProxyUnreachableCondition(lumigo, now, false, "")
 
-			mutatedCronjob := cronjob.DeepCopy()
-			if mutationOccurred, err := mutator.RemoveLumigoFromBatchV1CronJob(mutatedCronjob); err != nil {
-				return fmt.Errorf("cannot prepare mutation of cronjob '%s': %w", mutatedCronjob.Name, err)
-			} else if mutationOccurred {
-				addAutoTraceSkipNextInjectorLabel(&mutatedCronjob.ObjectMeta)
-				return r.Client.Update(ctx, mutatedCronjob)
-			} else {
-				return nil
-			}
-		}, maxMutationRetryAttempts, retryOnMutationErrorMatcher, log); err != nil {
-			operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger, err)
-			return fmt.Errorf("cannot remove instrumentation from cronjob '%s': %w", cronjob.Name, err)
-		} else {
-			log.Info("Removed instrumentation from cronjob", "namespace", cronjob.Namespace, "name", cronjob.Name)
-			operatorv1alpha1.RecordRemovedInstrumentationEvent(r.EventRecorder, &cronjob, eventTrigger)
+		if wasUnreachable {
+			operatorv1alpha1.RecordTelemetryProxyReachableEvent(r.EventRecorder, lumigo)
 		}
+		return
 	}
 
-	// Cannot mutate existing jobs: their PodSpecs are immutable!
-	jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return fmt.Errorf("cannot list autotraced jobs: %w", err)
-	}
+	message := strings.Join(reasons, "; ") + "; the workloads created or updated in the namespace are not injected until it is"
+	conditions.SetTelemetryProxyUnreachableCondition(lumigo, now, true, message)
 
-	for _, job := range jobs.Items {
-		operatorv1alpha1.RecordCannotRemoveInstrumentationEvent(r.EventRecorder, &job, eventTrigger, fmt.Errorf("the PodSpec of batchv1.Job resources is immutable once the job has been created"))
-		log.Info("Cannot remove instrumentation from job: jobs are immutable once created", "namespace", job.Namespace, "name", job.Name)
+	// Record the event only when the telemetry-proxy becomes unreachable, not at every reconciliation
+	if !wasUnreachable {
+		operatorv1alpha1.RecordTelemetryProxyUnreachableEvent(r.EventRecorder, lumigo, message)
 	}
-
-	return nil
 }
 
-func (r *LumigoReconciler) getInstrumentedObjectReferences(ctx context.Context, namespace string) (*[]corev1.ObjectReference, error) {
-	objectReferences := make([]corev1.ObjectReference, 0)
-
-	lumigoAutotracedListOptions := metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%[1]s,%[1]s != false", mutation.LumigoAutoTraceLabelKey),
+func (r *LumigoReconciler) updateInjectionRuntimeFailuresCondition(lumigo *operatorv1alpha1.Lumigo, now metav1.Time) {
+	if r.InjectorRuntimeFailureMonitor == nil {
+		return
 	}
 
-	daemonSets, err := r.Clientset.AppsV1().DaemonSets(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return nil, fmt.Errorf("cannot list autotraced daemonsets: %w", err)
+	failingPods, samples := r.InjectorRuntimeFailureMonitor.GetFailures(lumigo.Namespace)
+	if failingPods < 1 {
+		conditions.SetInjectionRuntimeFailuresCondition(lumigo, now, false, "")
+		return
 	}
 
-	sort.Sort(sorting.ByDaemonsetName(daemonSets.Items))
-	for _, daemonSet := range daemonSets.Items {
-		objectReference, err := reference.GetReference(scheme.Scheme, &daemonSet)
-		if err != nil {
-			return nil, err
-		}
-		objectReferences = append(objectReferences, *objectReference)
+	message := fmt.Sprintf("the Lumigo injector fails in %d pod(s): %s", failingPods, strings.Join(samples, "; "))
+	if failingPods > len(samples) {
+		message += fmt.Sprintf("; and %d more", failingPods-len(samples))
 	}
 
-	deployments, err := r.Clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%[1]s,%[1]s != false", mutation.LumigoAutoTraceLabelKey),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("cannot list autotraced deployments: %w", err)
+	hadFailures := conditions.HasInjectionRuntimeFailures(lumigo)
+	conditions.SetInjectionRuntimeFailuresCondition(lumigo, now, true, message)
+
+	// Record the event only when the failures start, not at every reconciliation
+	if !hadFailures {
+		operatorv1alpha1.RecordInjectionRuntimeFailuresEvent(r.EventRecorder, lumigo, message)
 	}
+}
 
-	sort.Sort(sorting.ByDeploymentName(deployments.Items))
-	for _, deployment := range deployments.Items {
-		objectReference, err := reference.GetReference(scheme.Scheme, &deployment)
-		if err != nil {
-			return nil, err
-		}
-		objectReferences = append(objectReferences, *objectReference)
+func newArchivalConfig(namespaceName string, s3Spec *operatorv1alpha1.S3ArchivalSpec) (*telemetryproxyconfigs.ArchivalConfig, error) {
+	if len(s3Spec.Bucket) < 1 {
+		return nil, fmt.Errorf("no S3 bucket is specified")
 	}
 
-	replicaSets, err := r.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return nil, fmt.Errorf("cannot list autotraced replicasets: %w", err)
+	archivalConfig := &telemetryproxyconfigs.ArchivalConfig{
+		Bucket:    s3Spec.Bucket,
+		Region:    s3Spec.Region,
+		Prefix:    s3Spec.Prefix,
+		Partition: s3Spec.Partition,
+		Endpoint:  s3Spec.Endpoint,
 	}
 
-	sort.Sort(sorting.ByReplicaSetName(replicaSets.Items))
-	for _, replicaSet := range replicaSets.Items {
-		objectReference, err := reference.GetReference(scheme.Scheme, &replicaSet)
-		if err != nil {
-			return nil, err
-		}
-		objectReferences = append(objectReferences, *objectReference)
+	if len(archivalConfig.Region) < 1 {
+		archivalConfig.Region = defaultArchivalRegion
 	}
 
-	statefulSets, err := r.Clientset.AppsV1().StatefulSets(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return nil, fmt.Errorf("cannot list autotraced statefulsets: %w", err)
+	if len(archivalConfig.Prefix) < 1 {
+		archivalConfig.Prefix = defaultArchivalPrefix
 	}
+	archivalConfig.Prefix = strings.ReplaceAll(archivalConfig.Prefix, archivalPrefixNamespacePlaceholder, namespaceName)
 
-	sort.Sort(sorting.ByStatefulSetName(statefulSets.Items))
-	for _, statefulSet := range statefulSets.Items {
-		objectReference, err := reference.GetReference(scheme.Scheme, &statefulSet)
-		if err != nil {
-			return nil, err
-		}
-		objectReferences = append(objectReferences, *objectReference)
+	if len(archivalConfig.Partition) < 1 {
+		archivalConfig.Partition = defaultArchivalPartition
 	}
 
-	cronJobs, err := r.Clientset.BatchV1().CronJobs(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return nil, fmt.Errorf("cannot list autotraced cronjobs: %w", err)
+	return archivalConfig, nil
+}
+
+// Returns nil if the namespace has no quota
+func newQuotaConfig(quotaSpec *operatorv1alpha1.QuotaSpec) *telemetryproxyconfigs.QuotaConfig {
+	if quotaSpec.MaxSpansPerDay == nil && quotaSpec.MaxGigabytesPerDay == nil {
+		return nil
 	}
 
-	sort.Sort(sorting.ByCronJobName(cronJobs.Items))
-	for _, cronJob := range cronJobs.Items {
-		objectReference, err := reference.GetReference(scheme.Scheme, &cronJob)
-		if err != nil {
-			return nil, err
-		}
-		objectReferences = append(objectReferences, *objectReference)
+	quotaConfig := &telemetryproxyconfigs.QuotaConfig{}
+	if quotaSpec.MaxSpansPerDay != nil {
+		quotaConfig.SpansPerDay = *quotaSpec.MaxSpansPerDay
+	}
+	if quotaSpec.MaxGigabytesPerDay != nil {
+		quotaConfig.BytesPerDay = int64(*quotaSpec.MaxGigabytesPerDay) * bytesPerGigabyte
+	}
+	if quotaSpec.SamplingPercentageWhenExceeded != nil {
+		quotaConfig.SamplingPercentage = *quotaSpec.SamplingPercentageWhenExceeded
 	}
 
-	jobs, err := r.Clientset.BatchV1().Jobs(namespace).List(ctx, lumigoAutotracedListOptions)
-	if err != nil {
-		return nil, fmt.Errorf("cannot list autotraced jobs: %w", err)
+	return quotaConfig
+}
+
+func newPrometheusScrapeConfig(prometheusSpec *operatorv1alpha1.PrometheusSpec) *telemetryproxyconfigs.PrometheusScrapeConfig {
+	scrapeConfig := &telemetryproxyconfigs.PrometheusScrapeConfig{
+		ScrapeAnnotatedPods: isTruthy(prometheusSpec.ScrapeAnnotatedPods, true),
 	}
 
-	sort.Sort(sorting.ByJobName(jobs.Items))
-	for _, job := range jobs.Items {
-		objectReference, err := reference.GetReference(scheme.Scheme, &job)
-		if err != nil {
-			return nil, err
+	for _, target := range prometheusSpec.ScrapeTargets {
+		metricsPath := target.MetricsPath
+		if metricsPath == "" {
+			metricsPath = "/metrics"
+		}
+
+		scrapeInterval := target.ScrapeInterval
+		if scrapeInterval == "" {
+			scrapeInterval = "1m"
 		}
-		objectReferences = append(objectReferences, *objectReference)
+
+		scrapeConfig.ScrapeTargets = append(scrapeConfig.ScrapeTargets, telemetryproxyconfigs.PrometheusScrapeTarget{
+			JobName:        target.JobName,
+			Targets:        target.Targets,
+			MetricsPath:    metricsPath,
+			ScrapeInterval: scrapeInterval,
+		})
 	}
 
-	return &objectReferences, nil
+	return scrapeConfig
 }
 
 func retry(description string, function func() error, maxAttempts int, retryOnErrorMatcher func(error) bool, log *logr.Logger) error {
@@ -994,8 +3279,15 @@ func retry(description string, function func() error, maxAttempts int, retryOnEr
 }
 
 func retryOnMutationErrorMatcher(err error) bool {
-	// Only match issues with intervealing object updates
-	return true
+	// Skipping the injection is a deliberate outcome, and so is leaving it for the next reconciliations
+	// once the limit of workload updates is reached: retrying would not change either
+	if mutation.IsSkipInjectionError(err) || errors.Is(err, workloadpacing.ErrBudgetExhausted) || errors.Is(err, deferredinjections.ErrInjectionDeferred) {
+		return false
+	}
+
+	// Neither would retrying right away the updates denied by admission webhooks and policies, which are instead
+	// retried with a backoff across reconciliations
+	return !apierrors.IsForbidden(err) && !apierrors.IsInvalid(err)
 }
 
 func addAutoTraceSkipNextInjectorLabel(objectMeta *metav1.ObjectMeta) {
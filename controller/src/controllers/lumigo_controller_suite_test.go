@@ -31,7 +31,9 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -44,7 +46,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/goinstrumentation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/instrumentedresources"
 	. "github.com/lumigo-io/lumigo-kubernetes-operator/controllers/internal/matchers"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/injectionannotations"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
 	//+kubebuilder:scaffold:imports
 )
@@ -65,6 +71,8 @@ var (
 	lumigoInjectorImage          = "localhost:5000/lumigo-injector:latest"
 	telemetryProxyOtlpServiceUrl = "http://localhost:4318"
 	telemetryProxyNamespacesFile string
+	lumigoOperatorNamespace      = "lumigo-system"
+	goInstrumentationAgentImage  = "localhost:5000/lumigo-go-instrumentation-agent:latest"
 )
 
 func TestAPIs(t *testing.T) {
@@ -104,6 +112,12 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(clientset).NotTo(BeNil())
 
+	Expect(k8sClient.Create(context.Background(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: lumigoOperatorNamespace,
+		},
+	})).Should(Succeed())
+
 	// Start controller
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
@@ -127,6 +141,20 @@ var _ = BeforeSuite(func() {
 		LumigoInjectorImage:          lumigoInjectorImage,
 		TelemetryProxyOtlpServiceUrl: telemetryProxyOtlpServiceUrl,
 		TelemetryProxyNamespaceConfigurationsPath: telemetryProxyNamespacesFile,
+		LumigoOperatorNamespace:                   lumigoOperatorNamespace,
+		LumigoOperatorServiceAccountName:          "default",
+		GoInstrumentationAgentImage:               goInstrumentationAgentImage,
+		InstrumentedResources:                     instrumentedresources.NewIndex(),
+	}).SetupWithManager(mgr); err != nil {
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	if err := (&NamespaceReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Namespace"),
+		Config: &NamespaceAutoInstrumentationConfig{
+			Selector: labels.SelectorFromSet(labels.Set{"lumigo.io/enabled": "true"}),
+		},
 	}).SetupWithManager(mgr); err != nil {
 		Expect(err).ToNot(HaveOccurred())
 	}
@@ -241,6 +269,13 @@ var _ = Context("Lumigo controller", func() {
 					g.Expect(telemetryProxyNamespacesFile).To(BeMonitoringNamespace(namespaceName))
 				}, defaultTimeout, defaultInterval).Should(Succeed())
 			})
+
+			By("the status reflects the current generation of the spec", func() {
+				Eventually(func(g Gomega) {
+					current := currentVersionOf(lumigo, g)
+					g.Expect(current.Status.ObservedGeneration).To(Equal(current.Generation))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
 		})
 
 		It("has an error if the referenced secret does not have the expected key", func() {
@@ -739,6 +774,535 @@ var _ = Context("Lumigo controller", func() {
 			})
 		})
 
+		It("should deploy the Go instrumentation agent if .Tracing.GoInstrumentation.Enabled is set to true", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+			expectedToken := "t_1234567890123456789AB"
+
+			By("Inititalizing the secret", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      lumigoSecretName,
+					},
+					Data: map[string][]byte{
+						expectedTokenKey: []byte(expectedToken),
+					},
+				})).Should(Succeed())
+			})
+
+			lumigoName := "lumigo1"
+			By("Initializing the Lumigo resource with Go instrumentation", func() {
+				lumigo := newLumigo(namespaceName, lumigoName, operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: lumigoSecretName,
+						Key:  expectedTokenKey,
+					},
+				}, true, true, true, true)
+				t := true
+				lumigo.Spec.Tracing.GoInstrumentation.Enabled = &t
+				Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeActive())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					agentConfigs := &corev1.Secret{}
+					g.Expect(k8sClient.Get(ctx, types.NamespacedName{
+						Namespace: lumigoOperatorNamespace,
+						Name:      goinstrumentation.AgentName,
+					}, agentConfigs)).To(Succeed())
+					g.Expect(agentConfigs.Data).To(HaveKeyWithValue("namespaces_to_instrument.json", ContainSubstring(fmt.Sprintf(`"name":"%s"`, namespaceName))))
+
+					agentDaemonSet := &appsv1.DaemonSet{}
+					g.Expect(k8sClient.Get(ctx, types.NamespacedName{
+						Namespace: lumigoOperatorNamespace,
+						Name:      goinstrumentation.AgentName,
+					}, agentDaemonSet)).To(Succeed())
+					g.Expect(agentDaemonSet.Spec.Template.Spec.Containers[0].Image).To(Equal(goInstrumentationAgentImage))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+
+			By("Disabling Go instrumentation", func() {
+				lumigo := &operatorv1alpha1.Lumigo{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Namespace: namespaceName,
+					Name:      lumigoName,
+				}, lumigo)).Should(Succeed())
+
+				f := false
+				lumigo.Spec.Tracing.GoInstrumentation.Enabled = &f
+
+				Expect(k8sClient.Update(ctx, lumigo)).To(Succeed())
+
+				Eventually(func(g Gomega) {
+					err := k8sClient.Get(ctx, types.NamespacedName{
+						Namespace: lumigoOperatorNamespace,
+						Name:      goinstrumentation.AgentName,
+					}, &appsv1.DaemonSet{})
+					g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+		})
+
+		It("should rate-limit the spans of the namespace if .Tracing.MaxSpansPerSecond is set", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+			expectedToken := "t_1234567890123456789AB"
+
+			By("Inititalizing the secret", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      lumigoSecretName,
+					},
+					Data: map[string][]byte{
+						expectedTokenKey: []byte(expectedToken),
+					},
+				})).Should(Succeed())
+			})
+
+			lumigoName := "lumigo1"
+			var lumigo *operatorv1alpha1.Lumigo
+			By("Initializing the Lumigo resource with a limit on spans per second", func() {
+				lumigo = newLumigo(namespaceName, lumigoName, operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: lumigoSecretName,
+						Key:  expectedTokenKey,
+					},
+				}, true, true, true, true)
+				maxSpansPerSecond := int32(100)
+				lumigo.Spec.Tracing.MaxSpansPerSecond = &maxSpansPerSecond
+				Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeActive())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					namespacesFileBytes, err := os.ReadFile(telemetryProxyNamespacesFile)
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(string(namespacesFileBytes)).To(ContainSubstring(`"maxSpansPerSecond":100`))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Expect(conditions.GetLumigoConditionByType(currentVersionOf(lumigo, Default), operatorv1alpha1.LumigoConditionTypeRateLimited)).To(BeNil())
+			})
+
+			By("Reporting dropped spans from the telemetry-proxy", func() {
+				rateLimitingStatusFile := filepath.Join(filepath.Dir(telemetryProxyNamespacesFile), "rate_limiting_status.json")
+				Expect(os.WriteFile(rateLimitingStatusFile, []byte(fmt.Sprintf(`{"%s":{"droppedSpans":42,"lastDroppedAt":"%s"}}`, namespaceName, time.Now().UTC().Format(time.RFC3339))), 0644)).To(Succeed())
+				DeferCleanup(os.Remove, rateLimitingStatusFile)
+
+				Eventually(func(g Gomega) {
+					rateLimitedCondition := conditions.GetLumigoConditionByType(currentVersionOf(lumigo, g), operatorv1alpha1.LumigoConditionTypeRateLimited)
+					g.Expect(rateLimitedCondition).NotTo(BeNil())
+					g.Expect(rateLimitedCondition.Status).To(Equal(corev1.ConditionTrue))
+					g.Expect(rateLimitedCondition.Message).To(ContainSubstring("42 dropped"))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+		})
+
+		It("should enforce the daily quota of the namespace if .Quota is set", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+			expectedToken := "t_1234567890123456789AB"
+
+			By("Inititalizing the secret", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      lumigoSecretName,
+					},
+					Data: map[string][]byte{
+						expectedTokenKey: []byte(expectedToken),
+					},
+				})).Should(Succeed())
+			})
+
+			lumigoName := "lumigo1"
+			var lumigo *operatorv1alpha1.Lumigo
+			By("Initializing the Lumigo resource with a daily quota", func() {
+				lumigo = newLumigo(namespaceName, lumigoName, operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: lumigoSecretName,
+						Key:  expectedTokenKey,
+					},
+				}, true, true, true, true)
+				maxSpansPerDay := int64(1000000)
+				maxGigabytesPerDay := int32(2)
+				samplingPercentage := int32(10)
+				lumigo.Spec.Quota = operatorv1alpha1.QuotaSpec{
+					MaxSpansPerDay:                 &maxSpansPerDay,
+					MaxGigabytesPerDay:             &maxGigabytesPerDay,
+					SamplingPercentageWhenExceeded: &samplingPercentage,
+				}
+				Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeActive())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					namespacesFileBytes, err := os.ReadFile(telemetryProxyNamespacesFile)
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(string(namespacesFileBytes)).To(ContainSubstring(`"quota":{"spansPerDay":1000000,"bytesPerDay":2000000000,"samplingPercentage":10}`))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Expect(conditions.GetLumigoConditionByType(currentVersionOf(lumigo, Default), operatorv1alpha1.LumigoConditionTypeQuotaExceeded)).To(BeNil())
+			})
+
+			By("Reporting the exceeded quota from the telemetry-proxy", func() {
+				now := time.Now().UTC()
+				rateLimitingStatusFile := filepath.Join(filepath.Dir(telemetryProxyNamespacesFile), "rate_limiting_status.json")
+				Expect(os.WriteFile(rateLimitingStatusFile, []byte(fmt.Sprintf(
+					`{"%s":{"droppedSpans":0,"lastDroppedAt":"0001-01-01T00:00:00Z","quota":{"day":"%s","acceptedSpans":1000042,"acceptedBytes":480000000,"droppedSpans":42,"exceededAt":"%s"}}}`,
+					namespaceName, now.Format("2006-01-02"), now.Format(time.RFC3339),
+				)), 0644)).To(Succeed())
+				DeferCleanup(os.Remove, rateLimitingStatusFile)
+
+				Eventually(func(g Gomega) {
+					quotaExceededCondition := conditions.GetLumigoConditionByType(currentVersionOf(lumigo, g), operatorv1alpha1.LumigoConditionTypeQuotaExceeded)
+					g.Expect(quotaExceededCondition).NotTo(BeNil())
+					g.Expect(quotaExceededCondition.Status).To(Equal(corev1.ConditionTrue))
+					g.Expect(quotaExceededCondition.Message).To(ContainSubstring("keeps 10% of its traces"))
+					g.Expect(quotaExceededCondition.Message).To(ContainSubstring("42 spans dropped"))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+
+			By("Resetting the quota on the next day", func() {
+				rateLimitingStatusFile := filepath.Join(filepath.Dir(telemetryProxyNamespacesFile), "rate_limiting_status.json")
+				Expect(os.WriteFile(rateLimitingStatusFile, []byte(fmt.Sprintf(
+					`{"%s":{"droppedSpans":0,"lastDroppedAt":"0001-01-01T00:00:00Z","quota":{"day":"2000-01-01","acceptedSpans":1000042,"acceptedBytes":480000000,"droppedSpans":42,"exceededAt":"2000-01-01T18:00:00Z"}}}`,
+					namespaceName,
+				)), 0644)).To(Succeed())
+
+				Eventually(func(g Gomega) {
+					quotaExceededCondition := conditions.GetLumigoConditionByType(currentVersionOf(lumigo, g), operatorv1alpha1.LumigoConditionTypeQuotaExceeded)
+					g.Expect(quotaExceededCondition).NotTo(BeNil())
+					g.Expect(quotaExceededCondition.Status).To(Equal(corev1.ConditionFalse))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+		})
+
+		It("should send traces to the additional exporters in .Tracing.AdditionalExporters", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+			expectedToken := "t_1234567890123456789AB"
+			headersSecretName := "tempo-headers"
+
+			By("Inititalizing the secret", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      lumigoSecretName,
+					},
+					Data: map[string][]byte{
+						expectedTokenKey: []byte(expectedToken),
+					},
+				})).Should(Succeed())
+			})
+
+			var lumigo *operatorv1alpha1.Lumigo
+			By("Initializing the Lumigo resource with an additional exporter whose headers secret does not exist", func() {
+				lumigo = newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: lumigoSecretName,
+						Key:  expectedTokenKey,
+					},
+				}, true, true, true, true)
+				lumigo.Spec.Tracing.AdditionalExporters = []operatorv1alpha1.OtlpExporterSpec{
+					{
+						Name:     "tempo",
+						Endpoint: "https://tempo.example.com:4318",
+						HeadersSecretRef: &corev1.LocalObjectReference{
+							Name: headersSecretName,
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeInErroneousState(""))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+
+			By("Creating the headers secret", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      headersSecretName,
+					},
+					Data: map[string][]byte{
+						"X-Scope-OrgID": []byte("my-tenant"),
+					},
+				})).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeActive())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					namespacesFileBytes, err := os.ReadFile(telemetryProxyNamespacesFile)
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(string(namespacesFileBytes)).To(ContainSubstring(`"additionalExporters":[{"name":"tempo","endpoint":"https://tempo.example.com:4318","headers":{"X-Scope-OrgID":"my-tenant"}}]`))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+		})
+
+		It("should send the telemetry of the namespace to the endpoints in .Endpoints", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+			expectedToken := "t_1234567890123456789AB"
+			headersSecretName := "gateway-headers"
+
+			By("Inititalizing the secrets", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      lumigoSecretName,
+					},
+					Data: map[string][]byte{
+						expectedTokenKey: []byte(expectedToken),
+					},
+				})).Should(Succeed())
+
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      headersSecretName,
+					},
+					Data: map[string][]byte{
+						"X-Gateway-Key": []byte("my-key"),
+					},
+				})).Should(Succeed())
+			})
+
+			By("Initializing the Lumigo resource with a traces and a metrics endpoint", func() {
+				lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: lumigoSecretName,
+						Key:  expectedTokenKey,
+					},
+				}, true, true, true, true)
+				lumigo.Spec.Endpoints = operatorv1alpha1.EndpointsSpec{
+					Traces: &operatorv1alpha1.OtlpEndpointSpec{
+						Url: "https://traces.example.com",
+						HeadersSecretRef: &corev1.LocalObjectReference{
+							Name: headersSecretName,
+						},
+					},
+					Metrics: &operatorv1alpha1.OtlpEndpointSpec{
+						Url: "https://metrics.example.com",
+					},
+				}
+				Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeActive())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					namespacesFileBytes, err := os.ReadFile(telemetryProxyNamespacesFile)
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(string(namespacesFileBytes)).To(ContainSubstring(`"endpoints":{"traces":{"endpoint":"https://traces.example.com","headers":{"X-Gateway-Key":"my-key"}},"metrics":{"endpoint":"https://metrics.example.com"}}`))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+		})
+
+		It("should archive the telemetry of the namespace if .Archival.Enabled is set to true", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+			expectedToken := "t_1234567890123456789AB"
+
+			By("Inititalizing the secret", func() {
+				Expect(k8sClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: namespaceName,
+						Name:      lumigoSecretName,
+					},
+					Data: map[string][]byte{
+						expectedTokenKey: []byte(expectedToken),
+					},
+				})).Should(Succeed())
+			})
+
+			lumigoName := "lumigo1"
+			var lumigo *operatorv1alpha1.Lumigo
+			By("Initializing the Lumigo resource with archival but no bucket", func() {
+				lumigo = newLumigo(namespaceName, lumigoName, operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: lumigoSecretName,
+						Key:  expectedTokenKey,
+					},
+				}, true, true, true, true)
+				t := true
+				lumigo.Spec.Archival.Enabled = &t
+				Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeInErroneousState("invalid archival settings: no S3 bucket is specified"))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+
+			By("Setting the bucket", func() {
+				lumigo := &operatorv1alpha1.Lumigo{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Namespace: namespaceName,
+					Name:      lumigoName,
+				}, lumigo)).Should(Succeed())
+
+				lumigo.Spec.Archival.S3.Bucket = "my-archive"
+				lumigo.Spec.Archival.S3.Prefix = "telemetry/{namespace}"
+				Expect(k8sClient.Update(ctx, lumigo)).To(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(currentVersionOf(lumigo, g)).To(BeActive())
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					namespacesFileBytes, err := os.ReadFile(telemetryProxyNamespacesFile)
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(string(namespacesFileBytes)).To(ContainSubstring(fmt.Sprintf(`"archival":{"bucket":"my-archive","region":"us-east-1","prefix":"telemetry/%s","partition":"hour"}`, namespaceName)))
+				}, defaultTimeout, defaultInterval).Should(Succeed())
+			})
+		})
+	})
+
+	Context("with resources injected by earlier versions of the operator", func() {
+		It("should add the missing injection annotations", func() {
+			lumigoSecretName := "lumigo-credentials"
+			expectedTokenKey := "token"
+
+			Expect(k8sClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      lumigoSecretName,
+				},
+				Data: map[string][]byte{
+					expectedTokenKey: []byte("t_1234567890123456789AB"),
+				},
+			})).Should(Succeed())
+
+			deploymentName := "test-deployment"
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deploymentName,
+					Namespace: namespaceName,
+					Labels: map[string]string{
+						mutation.LumigoAutoTraceLabelKey: mutation.LumigoAutoTraceLabelVersionPrefixValue + "0.9.0",
+					},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": deploymentName,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment":                     deploymentName,
+								mutation.LumigoAutoTraceLabelKey: mutation.LumigoAutoTraceLabelVersionPrefixValue + "0.9.0",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+									VolumeMounts: []corev1.VolumeMount{
+										{
+											Name:      mutation.LumigoInjectorVolumeName,
+											MountPath: mutation.LumigoInjectorVolumeMountPoint,
+											ReadOnly:  true,
+										},
+									},
+								},
+							},
+							Volumes: []corev1.Volume{
+								{
+									Name: mutation.LumigoInjectorVolumeName,
+									VolumeSource: corev1.VolumeSource{
+										EmptyDir: &corev1.EmptyDirVolumeSource{},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: lumigoSecretName,
+					Key:  expectedTokenKey,
+				},
+			}, true, false, false, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				deploymentAfter := &appsv1.Deployment{}
+				g.Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Namespace: namespaceName,
+					Name:      deploymentName,
+				}, deploymentAfter)).To(Succeed())
+
+				for _, objectMeta := range []*metav1.ObjectMeta{&deploymentAfter.ObjectMeta, &deploymentAfter.Spec.Template.ObjectMeta} {
+					injectionAnnotations, err := injectionannotations.Get(objectMeta)
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(injectionAnnotations).NotTo(BeNil())
+					g.Expect(injectionAnnotations.Version).To(Equal("0.9.0"))
+					g.Expect(injectionAnnotations.Containers).To(Equal([]string{"myapp"}))
+				}
+			}, defaultTimeout, defaultInterval).Should(Succeed())
+		})
+	})
+
+	Context("with a namespace labeled for auto-instrumentation", func() {
+
+		It("should create the Lumigo instance of the namespace, and delete it when the label is removed", func() {
+			Expect(k8sClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "lumigo-credentials",
+				},
+				Data: map[string][]byte{
+					"token": []byte("t_1234567890123456789AB"),
+				},
+			})).Should(Succeed())
+
+			namespace := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace)).To(Succeed())
+			namespace.Labels["lumigo.io/enabled"] = "true"
+			Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+
+			lumigo := &operatorv1alpha1.Lumigo{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "lumigo",
+				},
+			}
+			Eventually(func(g Gomega) {
+				current := currentVersionOf(lumigo, g)
+				g.Expect(current.Labels).To(HaveKeyWithValue(AutoInstrumentedNamespaceLabelKey, AutoInstrumentedNamespaceLabelValue))
+				g.Expect(current).To(BeActive())
+			}, defaultTimeout, defaultInterval).Should(Succeed())
+
+			Expect(telemetryProxyNamespacesFile).To(BeMonitoringNamespace(namespaceName))
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace)).To(Succeed())
+			delete(namespace.Labels, "lumigo.io/enabled")
+			Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+
+			Eventually(func(g Gomega) {
+				err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: "lumigo"}, &operatorv1alpha1.Lumigo{})
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				g.Expect(telemetryProxyNamespacesFile).NotTo(BeMonitoringNamespace(namespaceName))
+			}, defaultTimeout, defaultInterval).Should(Succeed())
+		})
+
 	})
 
 	Context("with two Lumigo instances in the namespace", func() {
@@ -0,0 +1,195 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/audit"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/backgroundcleanup"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectiongc"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectionspec"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/internal/sorting"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/optimisticupdate"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// collectInjectionArtifacts completes or removes the partial injection artifacts of the resources, e.g., the
+// `lumigo-injector` volume without the `LD_PRELOAD` env vars, and removes the orphaned partial ones of the namespaces
+// without Lumigo instance, which are left behind when the injection, or its removal, is interrupted
+func (r *LumigoReconciler) collectInjectionArtifacts(ctx context.Context) error {
+	log := r.Log.WithName("injection-gc")
+
+	lumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(ctx, lumigoes); err != nil {
+		return fmt.Errorf("cannot list the Lumigo instances: %w", err)
+	}
+
+	// Only the first Lumigo instance created in a namespace is active
+	sort.Sort(sorting.ByCreationTime(lumigoes.Items))
+	lumigoesByNamespace := map[string]*operatorv1alpha1.Lumigo{}
+	for i := range lumigoes.Items {
+		if _, isSet := lumigoesByNamespace[lumigoes.Items[i].Namespace]; !isSet {
+			lumigoesByNamespace[lumigoes.Items[i].Namespace] = &lumigoes.Items[i]
+		}
+	}
+
+	pendingCleanups := map[string]bool{}
+
+	for _, list := range []client.ObjectList{
+		&appsv1.DaemonSetList{},
+		&appsv1.DeploymentList{},
+		&appsv1.ReplicaSetList{},
+		&appsv1.StatefulSetList{},
+		&batchv1.CronJobList{},
+	} {
+		if err := r.Client.List(ctx, list); err != nil {
+			return fmt.Errorf("cannot list the resources: %w", err)
+		}
+
+		items, err := apimeta.ExtractList(list)
+		if err != nil {
+			return fmt.Errorf("cannot extract the resources: %w", err)
+		}
+
+		for _, item := range items {
+			resource := item.(client.Object)
+			if !resource.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			artifacts, err := mutation.GetInjectionArtifacts(resource)
+			if err != nil {
+				log.Error(err, "Cannot look up the injection artifacts", "namespace", resource.GetNamespace(), "name", resource.GetName())
+				continue
+			}
+
+			namespace := resource.GetNamespace()
+			lumigo := lumigoesByNamespace[namespace]
+
+			hasPendingCleanup, isLookedUp := pendingCleanups[namespace]
+			if lumigo == nil && artifacts != nil && artifacts.IsPresent() && !isLookedUp {
+				pendingCleanup, err := backgroundcleanup.GetPendingCleanup(ctx, r.Clientset.CoreV1(), namespace)
+				if err != nil {
+					log.Error(err, "Cannot look up the background removal of instrumentation from resources in namespace", "namespace", namespace)
+					continue
+				}
+				hasPendingCleanup = pendingCleanup != nil
+				pendingCleanups[namespace] = hasPendingCleanup
+			}
+
+			action := injectiongc.Decide(artifacts, lumigo, hasPendingCleanup)
+			if action == injectiongc.ActionNone {
+				continue
+			}
+
+			kind := reflect.TypeOf(resource).Elem().Name()
+			if isChanged, err := r.fixInjectionArtifacts(ctx, lumigo, resource, action, &log); err != nil {
+				if !apierrors.IsNotFound(err) {
+					log.Error(err, "Cannot fix the injection artifacts", "kind", kind, "namespace", namespace, "name", resource.GetName(), "action", action)
+				}
+				continue
+			} else if !isChanged {
+				continue
+			}
+
+			injectiongc.Record(namespace, kind, action)
+			log.Info("Fixed the injection artifacts", "kind", kind, "namespace", namespace, "name", resource.GetName(), "action", action)
+		}
+	}
+
+	return nil
+}
+
+// fixInjectionArtifacts completes the injection artifacts of the resource by injecting it anew, or removes them, and
+// returns whether the resource has changed; the Lumigo instance is nil for the orphaned artifacts of the namespaces
+// without Lumigo instance
+func (r *LumigoReconciler) fixInjectionArtifacts(ctx context.Context, lumigo *operatorv1alpha1.Lumigo, resource client.Object, action injectiongc.Action, log *logr.Logger) (bool, error) {
+	var spec *operatorv1alpha1.LumigoSpec
+	lumigoInjectorImage := r.LumigoInjectorImage
+	telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := r.TelemetryProxyOtlpServiceUrl, r.TelemetryProxyOtlpLogsServiceUrl
+	if lumigo != nil {
+		spec = injectionspec.EffectiveSpec(lumigo)
+		telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl = r.telemetryProxyOtlpServiceUrls(lumigo)
+	} else {
+		// The audit entries reference the namespace of the orphaned artifacts
+		lumigo = &operatorv1alpha1.Lumigo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: resource.GetNamespace(),
+			},
+		}
+	}
+
+	if action == injectiongc.ActionCompleted && r.InjectorImageVerifier != nil {
+		var err error
+		if lumigoInjectorImage, err = r.InjectorImageVerifier.Verify(ctx, r.LumigoInjectorImage); err != nil {
+			return false, fmt.Errorf("the signature of the Lumigo injector image cannot be verified: %w", err)
+		}
+	}
+
+	mutator, err := mutation.NewMutator(log, spec, r.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, r.Platform.InjectorResources(), r.InjectorDefaults, r.ProtectedWorkloads)
+	if err != nil {
+		return false, fmt.Errorf("cannot instantiate mutator: %w", err)
+	}
+
+	return optimisticupdate.Apply(ctx, r.apiReader(), resource, func(obj client.Object) (bool, error) {
+		// The resource may have been fixed meanwhile, e.g., by a reconciliation of the Lumigo instance
+		if artifacts, err := mutation.GetInjectionArtifacts(obj); err != nil || artifacts == nil || !(artifacts.IsPartial() || action == injectiongc.ActionRemoved && artifacts.IsPresent()) {
+			return false, err
+		}
+
+		isRemoved, err := mutator.RemoveLumigoFrom(obj)
+		if err != nil {
+			return false, err
+		}
+
+		if action == injectiongc.ActionCompleted {
+			if _, err := mutator.InjectLumigoInto(obj); err == nil {
+				return true, nil
+			} else if !mutation.IsSkipInjectionError(err) {
+				return false, err
+			}
+			// The resource is not to be injected, e.g., it has opted out since, so the artifacts are removed instead
+		}
+
+		if !isRemoved {
+			// Only the autotrace label of the pod template is left, which the removal of the injection does not touch
+			return false, nil
+		}
+
+		// Like after the removal of the injection by the controller, the webhook does not inject the resource on this update
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[mutation.LumigoAutoTraceLabelKey] = mutation.LumigoAutoTraceLabelSkipNextInjectorValue
+		obj.SetLabels(labels)
+		return true, nil
+	}, r.mutatedResourceWriter(lumigo, audit.ActionCollectArtifacts, log))
+}
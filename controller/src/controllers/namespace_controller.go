@@ -0,0 +1,193 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokendistribution"
+)
+
+const (
+	// The label selector of the namespaces in which the operator creates a Lumigo instance, unless configured otherwise
+	DefaultNamespaceAutoInstrumentationSelector = "lumigo.io/enabled=true"
+
+	// The label of the Lumigo instances created by the operator in the selected namespaces, which tells them apart
+	// from the ones created by users
+	AutoInstrumentedNamespaceLabelKey   = "lumigo.io/auto-instrumented-namespace"
+	AutoInstrumentedNamespaceLabelValue = "true"
+
+	autoInstrumentedLumigoName            = "lumigo"
+	autoInstrumentedLumigoTokenSecretName = "lumigo-credentials"
+	autoInstrumentedLumigoTokenSecretKey  = "token"
+)
+
+// NamespaceAutoInstrumentationConfig selects the namespaces in which the operator creates a Lumigo instance
+type NamespaceAutoInstrumentationConfig struct {
+	// The labels of the namespaces to auto-instrument
+	Selector labels.Selector
+}
+
+// NamespaceReconciler creates a Lumigo instance in the namespaces matching the auto-instrumentation selector, and
+// deletes it when the namespace no longer matches. The Lumigo instances it creates reference a token secret that
+// does not exist, so that the LumigoReconciler copies the central token secret into the namespace; the LumigoReconciler
+// then configures the telemetry-proxy and injects the resources like for the Lumigo instances created by users.
+type NamespaceReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Config *NamespaceAutoInstrumentationConfig
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Namespace{}).
+		// Recreate the Lumigo instances of the operator if they are deleted while their namespace still matches the selector
+		Watches(&source.Kind{Type: &operatorv1alpha1.Lumigo{}}, handler.EnqueueRequestsFromMapFunc(enqueueNamespaceIfAutoInstrumented)).
+		Complete(r)
+}
+
+// Reconcile ensures that the namespace has a Lumigo instance if, and only if, it matches the auto-instrumentation selector.
+//
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.lumigo.io,resources=lumigoes,verbs=get;list;watch;create;delete
+func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespace", req.NamespacedName.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, req.NamespacedName, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The Lumigo instances are deleted together with their namespace
+			return ctrl.Result{}, nil
+		}
+		// Error reading the namespace - requeue the request.
+		return ctrl.Result{
+			RequeueAfter: defaultErrRequeuePeriod,
+		}, nil
+	}
+
+	if !namespace.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	lumigoes := &operatorv1alpha1.LumigoList{}
+	if err := r.Client.List(ctx, lumigoes, client.InNamespace(namespace.Name)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("cannot list the Lumigo instances in namespace '%s': %w", namespace.Name, err)
+	}
+
+	if !r.Config.Selector.Matches(labels.Set(namespace.Labels)) {
+		return ctrl.Result{}, r.removeAutoInstrumentedLumigoes(ctx, lumigoes, &log)
+	}
+
+	for _, lumigo := range lumigoes.Items {
+		if lumigo.ObjectMeta.DeletionTimestamp.IsZero() {
+			// The namespace already has a Lumigo instance, either ours or one created by users, which we leave alone
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if len(lumigoes.Items) > 0 {
+		// Wait for the Lumigo instances being deleted, e.g., after the label has been removed and re-added, to be gone
+		return ctrl.Result{
+			RequeueAfter: defaultRequeuePeriod,
+		}, nil
+	}
+
+	if err := r.Client.Create(ctx, newAutoInstrumentedLumigo(namespace.Name)); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("cannot create the Lumigo instance in namespace '%s': %w", namespace.Name, err)
+	}
+
+	log.Info("Created Lumigo instance in the auto-instrumented namespace", "name", autoInstrumentedLumigoName)
+	return ctrl.Result{}, nil
+}
+
+// Deletes the Lumigo instances created by the operator; the LumigoReconciler removes the instrumentation, the
+// copy of the central token secret and the telemetry-proxy configuration of the namespace on their deletion
+func (r *NamespaceReconciler) removeAutoInstrumentedLumigoes(ctx context.Context, lumigoes *operatorv1alpha1.LumigoList, log *logr.Logger) error {
+	for i := range lumigoes.Items {
+		lumigo := &lumigoes.Items[i]
+		if !isAutoInstrumentedLumigo(lumigo) || !lumigo.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, lumigo); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return fmt.Errorf("cannot delete the Lumigo instance '%s/%s': %w", lumigo.Namespace, lumigo.Name, err)
+		}
+
+		log.Info("Deleted Lumigo instance, as the namespace is no longer auto-instrumented", "name", lumigo.Name)
+	}
+
+	return nil
+}
+
+// The copies that HNC propagates into the descendant namespaces carry the labels of the Lumigo instances of the
+// operator, but belong to HNC, which would recreate them if deleted
+func isAutoInstrumentedLumigo(lumigo *operatorv1alpha1.Lumigo) bool {
+	return lumigo.Labels[AutoInstrumentedNamespaceLabelKey] == AutoInstrumentedNamespaceLabelValue && len(tokendistribution.GetInheritedFrom(lumigo)) < 1
+}
+
+func newAutoInstrumentedLumigo(namespaceName string) *operatorv1alpha1.Lumigo {
+	return &operatorv1alpha1.Lumigo{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      autoInstrumentedLumigoName,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":       "lumigo",
+				"app.kubernetes.io/managed-by":    "lumigo-operator",
+				AutoInstrumentedNamespaceLabelKey: AutoInstrumentedNamespaceLabelValue,
+			},
+		},
+		Spec: operatorv1alpha1.LumigoSpec{
+			LumigoToken: operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: autoInstrumentedLumigoTokenSecretName,
+					Key:  autoInstrumentedLumigoTokenSecretKey,
+				},
+			},
+		},
+	}
+}
+
+func enqueueNamespaceIfAutoInstrumented(obj client.Object) []reconcile.Request {
+	if obj.GetLabels()[AutoInstrumentedNamespaceLabelKey] != AutoInstrumentedNamespaceLabelValue {
+		return []reconcile.Request{}
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+}
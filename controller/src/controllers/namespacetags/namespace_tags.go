@@ -0,0 +1,141 @@
+package namespacetags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	// TagAnnotationKeyPrefix is the prefix of the annotations of the namespaces whose values are added, as the
+	// resource attributes named after the rest of the annotation key, to the telemetry of the namespace, e.g.,
+	// `lumigo.io/tag.team: payments` adds the `team=payments` resource attribute
+	TagAnnotationKeyPrefix = "lumigo.io/tag."
+
+	// OtelResourceAttributesEnvVarName holds the comma-separated `key=value` resource attributes of the telemetry
+	// of the OpenTelemetry SDKs, on which the Lumigo distros are based
+	OtelResourceAttributesEnvVarName = "OTEL_RESOURCE_ATTRIBUTES"
+)
+
+// The resource attributes that the operator, the telemetry-proxy and the Lumigo distros set themselves
+var reservedTagPrefixes = []string{"k8s.", "lumigo.", "service."}
+
+// GetNamespaceTags returns the tags of the `lumigo.io/tag.<key>` annotations of the namespace, if any, and the
+// annotations that cannot be used as tags, e.g., because their values have quotes, backslashes or control
+// characters, which the telemetry-proxy configurations cannot embed, or because they set reserved attributes.
+func GetNamespaceTags(namespace *corev1.Namespace) (map[string]string, []string) {
+	if namespace == nil {
+		return nil, nil
+	}
+
+	var tags map[string]string
+	invalidAnnotations := []string{}
+	for annotationKey, value := range namespace.Annotations {
+		if !strings.HasPrefix(annotationKey, TagAnnotationKeyPrefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(annotationKey, TagAnnotationKeyPrefix)
+		if !isValidTag(key, value) {
+			invalidAnnotations = append(invalidAnnotations, annotationKey)
+			continue
+		}
+
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		tags[key] = value
+	}
+
+	sort.Strings(invalidAnnotations)
+	return tags, invalidAnnotations
+}
+
+// SpecWithNamespaceTags returns the spec with the `OTEL_RESOURCE_ATTRIBUTES` env var of the tags of the namespace,
+// stored in the status of the Lumigo instance, added to the extra env vars; an `OTEL_RESOURCE_ATTRIBUTES` entry of
+// `.spec.tracing.injection.extraEnv` is kept, with the tags prepended to its value, so that its attributes take
+// precedence.
+func SpecWithNamespaceTags(lumigo *operatorv1alpha1.Lumigo, spec *operatorv1alpha1.LumigoSpec) *operatorv1alpha1.LumigoSpec {
+	if len(lumigo.Status.NamespaceTags) < 1 {
+		return spec
+	}
+
+	tagsSpec := spec.DeepCopy()
+	resourceAttributes := FormatResourceAttributes(lumigo.Status.NamespaceTags)
+
+	extraEnv := tagsSpec.Tracing.Injection.ExtraEnv
+	if index := slices.IndexFunc(extraEnv, func(e corev1.EnvVar) bool { return e.Name == OtelResourceAttributesEnvVarName }); index > -1 {
+		// An env var from a `valueFrom` source cannot be merged with the tags, and is left as it is
+		if extraEnv[index].ValueFrom == nil && len(extraEnv[index].Value) > 0 {
+			extraEnv[index].Value = resourceAttributes + "," + extraEnv[index].Value
+		} else if extraEnv[index].ValueFrom == nil {
+			extraEnv[index].Value = resourceAttributes
+		}
+		return tagsSpec
+	}
+
+	tagsSpec.Tracing.Injection.ExtraEnv = append([]corev1.EnvVar{
+		{
+			Name:  OtelResourceAttributesEnvVarName,
+			Value: resourceAttributes,
+		},
+	}, extraEnv...)
+
+	return tagsSpec
+}
+
+// FormatResourceAttributes returns the tags in the format of `OTEL_RESOURCE_ATTRIBUTES`, sorted by key, with
+// the values percent-encoded as the W3C Baggage values that the format is based on
+func FormatResourceAttributes(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]string, len(keys))
+	for i, key := range keys {
+		attributes[i] = fmt.Sprintf("%s=%s", key, encodeValue(tags[key]))
+	}
+
+	return strings.Join(attributes, ",")
+}
+
+func encodeValue(value string) string {
+	var builder strings.Builder
+	for _, b := range []byte(value) {
+		if b <= ' ' || b >= 0x7f || b == '%' || b == ',' || b == ';' || b == '=' || b == '"' || b == '\\' {
+			fmt.Fprintf(&builder, "%%%02X", b)
+		} else {
+			builder.WriteByte(b)
+		}
+	}
+	return builder.String()
+}
+
+// The keys are the name parts of the annotation keys, which Kubernetes restricts to alphanumeric
+// characters, `-`, `_` and `.`
+func isValidTag(key string, value string) bool {
+	if len(key) < 1 || len(value) < 1 {
+		return false
+	}
+
+	for _, prefix := range reservedTagPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+
+	for _, r := range value {
+		if r == '"' || r == '\'' || r == '\\' || r < ' ' || r == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespacetags
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Namespace Tags Suite")
+}
+
+var _ = Context("Namespace tags", func() {
+
+	It("reads the tags from the annotations of the namespace", func() {
+		tags, invalidAnnotations := GetNamespaceTags(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "my-namespace",
+				Annotations: map[string]string{
+					"lumigo.io/tag.team":               "payments",
+					"lumigo.io/tag.cost-center":        "cc 42",
+					"lumigo.io/tag.k8s.namespace.name": "other",
+					"lumigo.io/tag.quoted":             `"payments"`,
+					"lumigo.io/tag.single-quoted":      "payments'",
+					"lumigo.io/tag.":                   "empty",
+					"lumigo.io/injected-extra-env":     "LUMIGO_DEBUG",
+				},
+			},
+		})
+
+		Expect(tags).To(Equal(map[string]string{
+			"team":        "payments",
+			"cost-center": "cc 42",
+		}))
+		Expect(invalidAnnotations).To(Equal([]string{"lumigo.io/tag.", "lumigo.io/tag.k8s.namespace.name", "lumigo.io/tag.quoted", "lumigo.io/tag.single-quoted"}))
+	})
+
+	It("returns no tags for a namespace without tag annotations", func() {
+		tags, invalidAnnotations := GetNamespaceTags(&corev1.Namespace{})
+		Expect(tags).To(BeNil())
+		Expect(invalidAnnotations).To(BeEmpty())
+	})
+
+	It("formats the tags as resource attributes", func() {
+		Expect(FormatResourceAttributes(map[string]string{
+			"team":        "payments",
+			"cost-center": "cc 42,=%",
+		})).To(Equal("cost-center=cc%2042%2C%3D%25,team=payments"))
+	})
+
+	It("adds the tags to the extra env vars", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				Tracing: operatorv1alpha1.TracingSpec{
+					Injection: operatorv1alpha1.InjectionSpec{
+						ExtraEnv: []corev1.EnvVar{
+							{Name: "LUMIGO_DEBUG", Value: "true"},
+						},
+					},
+				},
+			},
+			Status: operatorv1alpha1.LumigoStatus{
+				NamespaceTags: map[string]string{"team": "payments"},
+			},
+		}
+
+		spec := SpecWithNamespaceTags(lumigo, &lumigo.Spec)
+		Expect(spec.Tracing.Injection.ExtraEnv).To(Equal([]corev1.EnvVar{
+			{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "team=payments"},
+			{Name: "LUMIGO_DEBUG", Value: "true"},
+		}))
+		Expect(lumigo.Spec.Tracing.Injection.ExtraEnv).To(HaveLen(1))
+	})
+
+	It("prepends the tags to the resource attributes of the extra env vars", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				Tracing: operatorv1alpha1.TracingSpec{
+					Injection: operatorv1alpha1.InjectionSpec{
+						ExtraEnv: []corev1.EnvVar{
+							{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "team=checkout"},
+						},
+					},
+				},
+			},
+			Status: operatorv1alpha1.LumigoStatus{
+				NamespaceTags: map[string]string{"team": "payments"},
+			},
+		}
+
+		spec := SpecWithNamespaceTags(lumigo, &lumigo.Spec)
+		Expect(spec.Tracing.Injection.ExtraEnv).To(Equal([]corev1.EnvVar{
+			{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "team=payments,team=checkout"},
+		}))
+		Expect(lumigo.Spec.Tracing.Injection.ExtraEnv[0].Value).To(Equal("team=checkout"))
+	})
+
+	It("leaves the spec as it is without tags", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		Expect(SpecWithNamespaceTags(lumigo, &lumigo.Spec)).To(BeIdenticalTo(&lumigo.Spec))
+	})
+})
@@ -0,0 +1,376 @@
+package networkpolicies
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// The NetworkPolicy of the pods of the operator, which run the webhooks and the telemetry-proxy
+	OperatorNetworkPolicyName = "lumigo-operator"
+	// The NetworkPolicy of the pods of the telemetry-proxy DaemonSet
+	TelemetryProxyDaemonSetNetworkPolicyName = "lumigo-telemetry-proxy"
+	// The NetworkPolicy of the pods of the telemetry-proxy shards
+	TelemetryProxyShardsNetworkPolicyName = "lumigo-telemetry-proxy-shards"
+	// The NetworkPolicy in each namespace with a Lumigo instance, which lets its pods send telemetry
+	NamespaceNetworkPolicyName = "lumigo-telemetry"
+	// The NetworkPolicy of the pods of the dedicated telemetry-proxy in the namespaces that have one
+	DedicatedTelemetryProxyNetworkPolicyName = "lumigo-dedicated-telemetry-proxy"
+
+	kubernetesNamespaceNameLabelKey  = "kubernetes.io/metadata.name"
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+
+	webhookPort = 9443
+	otlpPort    = 4318
+	// The gRPC port of the OTLP receiver, to which the injected containers export when it is enabled
+	otlpGrpcPort = 4317
+	dnsPort      = 53
+	// The Kubernetes API server and the Lumigo endpoints; NetworkPolicies cannot select
+	// destinations by domain name, so these ports are allowed to any destination
+	httpsPort          = 443
+	kubernetesApiPort  = 6443
+	allIPv4AddressCidr = "0.0.0.0/0"
+)
+
+// NetworkPoliciesConfig contains the settings of the operator that apply to the NetworkPolicies
+// allowing the traffic of the operator, of the telemetry-proxy and of the instrumented pods
+type NetworkPoliciesConfig struct {
+	// The namespace the operator runs in
+	OperatorNamespace string
+	// The labels of the pods of the operator
+	OperatorPodLabels map[string]string
+	// The labels of the pods of the telemetry-proxy DaemonSet, if the telemetry-proxy runs as a DaemonSet
+	TelemetryProxyDaemonSetPodLabels map[string]string
+	// The labels of the pods of all the telemetry-proxy shards, if the telemetry-proxy is sharded
+	TelemetryProxyShardsPodLabels map[string]string
+	// The labels of the pods of the dedicated telemetry-proxies, if the operator deploys them
+	DedicatedTelemetryProxyPodLabels map[string]string
+}
+
+// MonitoredNamespaces describes the namespaces whose traffic to and from the operator must be allowed
+type MonitoredNamespaces struct {
+	// The namespaces with a Lumigo instance, whose pods send telemetry to the telemetry-proxy
+	Names []string
+	// The namespaces whose pods the telemetry-proxy scrapes for Prometheus metrics
+	PrometheusScrapedNames []string
+}
+
+// SyncOperatorNetworkPolicies makes the NetworkPolicies in the namespace of the operator allow the traffic
+// of the webhooks and the telemetry-proxy, given the namespaces that are currently monitored.
+func SyncOperatorNetworkPolicies(ctx context.Context, c client.Client, config *NetworkPoliciesConfig, monitoredNamespaces *MonitoredNamespaces, log *logr.Logger) (bool, error) {
+	// The API server calls the webhooks from addresses that cannot be selected by NetworkPolicies
+	operatorIngress := []networkingv1.NetworkPolicyIngressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(webhookPort)},
+		},
+	}
+	operatorIngress = append(operatorIngress, otlpIngressRules(monitoredNamespaces)...)
+
+	isChanged, err := upsertNetworkPolicy(ctx, c, newNetworkPolicy(
+		config.OperatorNamespace,
+		OperatorNetworkPolicyName,
+		config.OperatorPodLabels,
+		operatorIngress,
+		telemetryProxyEgressRules(monitoredNamespaces),
+	), log)
+	if err != nil {
+		return isChanged, err
+	}
+
+	isDaemonSetPolicyChanged, err := syncTelemetryProxyNetworkPolicy(ctx, c, config.OperatorNamespace, TelemetryProxyDaemonSetNetworkPolicyName, config.TelemetryProxyDaemonSetPodLabels, monitoredNamespaces, log)
+	isChanged = isChanged || isDaemonSetPolicyChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	isShardsPolicyChanged, err := syncTelemetryProxyNetworkPolicy(ctx, c, config.OperatorNamespace, TelemetryProxyShardsNetworkPolicyName, config.TelemetryProxyShardsPodLabels, monitoredNamespaces, log)
+	return isChanged || isShardsPolicyChanged, err
+}
+
+// syncTelemetryProxyNetworkPolicy makes the NetworkPolicy of the pods of the telemetry-proxy with the given labels,
+// which the operator deploys besides the one next to the controller, allow the telemetry of the monitored namespaces;
+// the NetworkPolicy is removed if there are no such pods
+func syncTelemetryProxyNetworkPolicy(ctx context.Context, c client.Client, namespace string, name string, podLabels map[string]string, monitoredNamespaces *MonitoredNamespaces, log *logr.Logger) (bool, error) {
+	if podLabels == nil {
+		return removeNetworkPolicy(ctx, c, namespace, name, log)
+	}
+
+	return upsertNetworkPolicy(ctx, c, newNetworkPolicy(
+		namespace,
+		name,
+		podLabels,
+		otlpIngressRules(monitoredNamespaces),
+		telemetryProxyEgressRules(&MonitoredNamespaces{}),
+	), log)
+}
+
+// UpsertNetworkPolicyOfNamespace makes the NetworkPolicy in the given namespace allow its pods to send
+// telemetry to the telemetry-proxy.
+func UpsertNetworkPolicyOfNamespace(ctx context.Context, c client.Client, config *NetworkPoliciesConfig, namespaceName string, log *logr.Logger) (bool, error) {
+	operatorNamespaceSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			kubernetesNamespaceNameLabelKey: config.OperatorNamespace,
+		},
+	}
+
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort), tcpPort(otlpGrpcPort)},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: operatorNamespaceSelector,
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: config.OperatorPodLabels,
+					},
+				},
+			},
+		},
+		{
+			// The telemetry-proxy service is looked up via DNS
+			Ports: []networkingv1.NetworkPolicyPort{udpPort(dnsPort), tcpPort(dnsPort)},
+		},
+	}
+
+	if config.TelemetryProxyShardsPodLabels != nil {
+		// The pods send telemetry to the telemetry-proxy shard of their namespace
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort)},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: operatorNamespaceSelector,
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: config.TelemetryProxyShardsPodLabels,
+					},
+				},
+			},
+		})
+	}
+
+	if config.DedicatedTelemetryProxyPodLabels != nil {
+		// The pods send telemetry to the dedicated telemetry-proxy of their namespace, if any
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort)},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: config.DedicatedTelemetryProxyPodLabels,
+					},
+				},
+			},
+		})
+	}
+
+	if config.TelemetryProxyDaemonSetPodLabels != nil {
+		// The pods send telemetry to the host port of the telemetry-proxy on their node, whose IP
+		// cannot be known in advance
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort)},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					IPBlock: &networkingv1.IPBlock{
+						CIDR: allIPv4AddressCidr,
+					},
+				},
+			},
+		})
+	}
+
+	return upsertNetworkPolicy(ctx, c, newNetworkPolicy(namespaceName, NamespaceNetworkPolicyName, nil, nil, egress), log)
+}
+
+// RemoveNetworkPolicyOfNamespace removes the NetworkPolicy that allows the pods of the given
+// namespace to send telemetry to the telemetry-proxy.
+func RemoveNetworkPolicyOfNamespace(ctx context.Context, c client.Client, namespaceName string, log *logr.Logger) (bool, error) {
+	return removeNetworkPolicy(ctx, c, namespaceName, NamespaceNetworkPolicyName, log)
+}
+
+// UpsertDedicatedTelemetryProxyNetworkPolicy makes the NetworkPolicy of the dedicated telemetry-proxy of the given
+// namespace allow only the telemetry of the pods of the namespace in, and the telemetry sent to Lumigo out.
+func UpsertDedicatedTelemetryProxyNetworkPolicy(ctx context.Context, c client.Client, config *NetworkPoliciesConfig, namespaceName string, log *logr.Logger) (bool, error) {
+	ingress := []networkingv1.NetworkPolicyIngressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort)},
+			From: []networkingv1.NetworkPolicyPeer{
+				{
+					PodSelector: &metav1.LabelSelector{},
+				},
+			},
+		},
+	}
+
+	return upsertNetworkPolicy(ctx, c, newNetworkPolicy(
+		namespaceName,
+		DedicatedTelemetryProxyNetworkPolicyName,
+		config.DedicatedTelemetryProxyPodLabels,
+		ingress,
+		telemetryProxyEgressRules(&MonitoredNamespaces{}),
+	), log)
+}
+
+// RemoveDedicatedTelemetryProxyNetworkPolicy removes the NetworkPolicy of the dedicated telemetry-proxy of the given namespace.
+func RemoveDedicatedTelemetryProxyNetworkPolicy(ctx context.Context, c client.Client, namespaceName string, log *logr.Logger) (bool, error) {
+	return removeNetworkPolicy(ctx, c, namespaceName, DedicatedTelemetryProxyNetworkPolicyName, log)
+}
+
+func otlpIngressRules(monitoredNamespaces *MonitoredNamespaces) []networkingv1.NetworkPolicyIngressRule {
+	if len(monitoredNamespaces.Names) < 1 {
+		return []networkingv1.NetworkPolicyIngressRule{}
+	}
+
+	return []networkingv1.NetworkPolicyIngressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort), tcpPort(otlpGrpcPort)},
+			From: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: namespacesSelector(monitoredNamespaces.Names),
+				},
+			},
+		},
+	}
+}
+
+func telemetryProxyEgressRules(monitoredNamespaces *MonitoredNamespaces) []networkingv1.NetworkPolicyEgressRule {
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{udpPort(dnsPort), tcpPort(dnsPort)},
+		},
+		{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(httpsPort), tcpPort(kubernetesApiPort)},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					IPBlock: &networkingv1.IPBlock{
+						CIDR: allIPv4AddressCidr,
+					},
+				},
+			},
+		},
+	}
+
+	if len(monitoredNamespaces.PrometheusScrapedNames) > 0 {
+		// The metrics endpoints of the scraped pods can be on any port
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: namespacesSelector(monitoredNamespaces.PrometheusScrapedNames),
+				},
+			},
+		})
+	}
+
+	return egress
+}
+
+func namespacesSelector(namespaceNames []string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      kubernetesNamespaceNameLabelKey,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   namespaceNames,
+			},
+		},
+	}
+}
+
+func newNetworkPolicy(namespaceName string, name string, podLabels map[string]string, ingress []networkingv1.NetworkPolicyIngressRule, egress []networkingv1.NetworkPolicyEgressRule) *networkingv1.NetworkPolicy {
+	policyTypes := []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+	if ingress != nil {
+		policyTypes = append([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, policyTypes...)
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      name,
+			Labels: map[string]string{
+				kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+				kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: podLabels,
+			},
+			PolicyTypes: policyTypes,
+			Ingress:     ingress,
+			Egress:      egress,
+		},
+	}
+}
+
+func upsertNetworkPolicy(ctx context.Context, c client.Client, desiredNetworkPolicy *networkingv1.NetworkPolicy, log *logr.Logger) (bool, error) {
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: desiredNetworkPolicy.Namespace, Name: desiredNetworkPolicy.Name}, networkPolicy); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the '%s' NetworkPolicy in namespace '%s': %w", desiredNetworkPolicy.Name, desiredNetworkPolicy.Namespace, err)
+		}
+
+		if err := c.Create(ctx, desiredNetworkPolicy); err != nil {
+			return false, fmt.Errorf("cannot create the '%s' NetworkPolicy in namespace '%s': %w", desiredNetworkPolicy.Name, desiredNetworkPolicy.Namespace, err)
+		}
+
+		log.Info("Created NetworkPolicy", "namespace", desiredNetworkPolicy.Namespace, "name", desiredNetworkPolicy.Name)
+		return true, nil
+	}
+
+	// Empty and nil lists are the same to the API server, which drops the former
+	if equality.Semantic.DeepEqual(networkPolicy.Spec, desiredNetworkPolicy.Spec) {
+		return false, nil
+	}
+
+	networkPolicy.ObjectMeta.Labels = desiredNetworkPolicy.ObjectMeta.Labels
+	networkPolicy.Spec = desiredNetworkPolicy.Spec
+	if err := c.Update(ctx, networkPolicy); err != nil {
+		return false, fmt.Errorf("cannot update the '%s' NetworkPolicy in namespace '%s': %w", desiredNetworkPolicy.Name, desiredNetworkPolicy.Namespace, err)
+	}
+
+	log.Info("Updated NetworkPolicy", "namespace", desiredNetworkPolicy.Namespace, "name", desiredNetworkPolicy.Name)
+	return true, nil
+}
+
+func removeNetworkPolicy(ctx context.Context, c client.Client, namespaceName string, name string, log *logr.Logger) (bool, error) {
+	if err := c.Delete(ctx, &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      name,
+		},
+	}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot delete the '%s' NetworkPolicy in namespace '%s': %w", name, namespaceName, err)
+	}
+
+	log.Info("Deleted NetworkPolicy", "namespace", namespaceName, "name", name)
+	return true, nil
+}
+
+func tcpPort(port int) networkingv1.NetworkPolicyPort {
+	return networkPolicyPort(corev1.ProtocolTCP, port)
+}
+
+func udpPort(port int) networkingv1.NetworkPolicyPort {
+	return networkPolicyPort(corev1.ProtocolUDP, port)
+}
+
+func networkPolicyPort(protocol corev1.Protocol, port int) networkingv1.NetworkPolicyPort {
+	portValue := intstr.FromInt(port)
+	return networkingv1.NetworkPolicyPort{
+		Protocol: &protocol,
+		Port:     &portValue,
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networkpolicies
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const operatorNamespace = "lumigo-system"
+
+var logger logr.Logger
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Network Policies Suite")
+}
+
+func getNetworkPolicy(c client.Client, namespace string, name string) (*networkingv1.NetworkPolicy, error) {
+	networkPolicy := &networkingv1.NetworkPolicy{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, networkPolicy)
+	return networkPolicy, err
+}
+
+var _ = Context("Network policies", func() {
+
+	var c client.Client
+	var config *NetworkPoliciesConfig
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+		config = &NetworkPoliciesConfig{
+			OperatorNamespace: operatorNamespace,
+			OperatorPodLabels: map[string]string{
+				"control-plane": "controller-manager",
+			},
+		}
+	})
+
+	It("allows the webhooks and the telemetry of the monitored namespaces to reach the operator", func() {
+		isChanged, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names:                  []string{"ns-a", "ns-b"},
+			PrometheusScrapedNames: []string{"ns-b"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		networkPolicy, err := getNetworkPolicy(c, operatorNamespace, OperatorNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(Equal(config.OperatorPodLabels))
+		Expect(networkPolicy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+
+		Expect(networkPolicy.Spec.Ingress).To(HaveLen(2))
+		Expect(networkPolicy.Spec.Ingress[0].Ports[0].Port.IntValue()).To(Equal(webhookPort))
+		Expect(networkPolicy.Spec.Ingress[0].From).To(BeEmpty())
+		Expect(networkPolicy.Spec.Ingress[1].Ports[0].Port.IntValue()).To(Equal(otlpPort))
+		Expect(networkPolicy.Spec.Ingress[1].Ports[1].Port.IntValue()).To(Equal(otlpGrpcPort))
+		Expect(networkPolicy.Spec.Ingress[1].From[0].NamespaceSelector.MatchExpressions[0].Values).To(Equal([]string{"ns-a", "ns-b"}))
+
+		Expect(networkPolicy.Spec.Egress).To(HaveLen(3))
+		Expect(networkPolicy.Spec.Egress[2].To[0].NamespaceSelector.MatchExpressions[0].Values).To(Equal([]string{"ns-b"}))
+
+		// Syncing again without changes is idempotent
+		isChanged, err = SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names:                  []string{"ns-a", "ns-b"},
+			PrometheusScrapedNames: []string{"ns-b"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		// The telemetry-proxy DaemonSet is not deployed
+		_, err = getNetworkPolicy(c, operatorNamespace, TelemetryProxyDaemonSetNetworkPolicyName)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("allows only the webhooks when no namespace is monitored", func() {
+		_, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names: []string{"ns-a"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		isChanged, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		networkPolicy, err := getNetworkPolicy(c, operatorNamespace, OperatorNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+		Expect(networkPolicy.Spec.Ingress[0].Ports[0].Port.IntValue()).To(Equal(webhookPort))
+		Expect(networkPolicy.Spec.Egress).To(HaveLen(2))
+	})
+
+	It("covers the pods of the telemetry-proxy DaemonSet, if any", func() {
+		config.TelemetryProxyDaemonSetPodLabels = map[string]string{
+			"app.kubernetes.io/name": "lumigo-telemetry-proxy",
+		}
+
+		_, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names: []string{"ns-a"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		networkPolicy, err := getNetworkPolicy(c, operatorNamespace, TelemetryProxyDaemonSetNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(Equal(config.TelemetryProxyDaemonSetPodLabels))
+		Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+		Expect(networkPolicy.Spec.Ingress[0].Ports[0].Port.IntValue()).To(Equal(otlpPort))
+
+		// Switching back to the deployment mode removes the policy
+		config.TelemetryProxyDaemonSetPodLabels = nil
+		isChanged, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names: []string{"ns-a"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getNetworkPolicy(c, operatorNamespace, TelemetryProxyDaemonSetNetworkPolicyName)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("covers the pods of the telemetry-proxy shards, if any", func() {
+		config.TelemetryProxyShardsPodLabels = map[string]string{
+			"app.kubernetes.io/name": "lumigo-telemetry-proxy-shard",
+		}
+
+		_, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names: []string{"ns-a"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		networkPolicy, err := getNetworkPolicy(c, operatorNamespace, TelemetryProxyShardsNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(Equal(config.TelemetryProxyShardsPodLabels))
+		Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+		Expect(networkPolicy.Spec.Ingress[0].Ports[0].Port.IntValue()).To(Equal(otlpPort))
+
+		// The pods of the monitored namespaces can reach the shards
+		_, err = UpsertNetworkPolicyOfNamespace(context.TODO(), c, config, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		networkPolicy, err = getNetworkPolicy(c, "ns-a", NamespaceNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		egressPodLabels := []map[string]string{}
+		for _, rule := range networkPolicy.Spec.Egress {
+			for _, peer := range rule.To {
+				if peer.PodSelector != nil {
+					egressPodLabels = append(egressPodLabels, peer.PodSelector.MatchLabels)
+				}
+			}
+		}
+		Expect(egressPodLabels).To(ContainElement(config.TelemetryProxyShardsPodLabels))
+
+		// Turning off the sharding removes the policy
+		config.TelemetryProxyShardsPodLabels = nil
+		isChanged, err := SyncOperatorNetworkPolicies(context.TODO(), c, config, &MonitoredNamespaces{
+			Names: []string{"ns-a"},
+		}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getNetworkPolicy(c, operatorNamespace, TelemetryProxyShardsNetworkPolicyName)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("allows the pods of a monitored namespace to send telemetry to the telemetry-proxy", func() {
+		isChanged, err := UpsertNetworkPolicyOfNamespace(context.TODO(), c, config, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		networkPolicy, err := getNetworkPolicy(c, "ns-a", NamespaceNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		// All the pods in the namespace
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(BeEmpty())
+		Expect(networkPolicy.Spec.PodSelector.MatchExpressions).To(BeEmpty())
+		Expect(networkPolicy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeEgress))
+		Expect(networkPolicy.Spec.Ingress).To(BeEmpty())
+		Expect(networkPolicy.Spec.Egress[0].Ports[0].Port.IntValue()).To(Equal(otlpPort))
+		Expect(networkPolicy.Spec.Egress[0].Ports[1].Port.IntValue()).To(Equal(otlpGrpcPort))
+		Expect(networkPolicy.Spec.Egress[0].To[0].NamespaceSelector.MatchLabels).To(Equal(map[string]string{
+			kubernetesNamespaceNameLabelKey: operatorNamespace,
+		}))
+		Expect(networkPolicy.Spec.Egress[0].To[0].PodSelector.MatchLabels).To(Equal(config.OperatorPodLabels))
+
+		isChanged, err = UpsertNetworkPolicyOfNamespace(context.TODO(), c, config, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		isChanged, err = RemoveNetworkPolicyOfNamespace(context.TODO(), c, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getNetworkPolicy(c, "ns-a", NamespaceNetworkPolicyName)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		// Removing a policy that does not exist is not an error
+		isChanged, err = RemoveNetworkPolicyOfNamespace(context.TODO(), c, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+	})
+
+	It("isolates the dedicated telemetry-proxy of a namespace within it", func() {
+		config.DedicatedTelemetryProxyPodLabels = map[string]string{
+			"app.kubernetes.io/name": "lumigo-dedicated-telemetry-proxy",
+		}
+
+		_, err := UpsertNetworkPolicyOfNamespace(context.TODO(), c, config, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		networkPolicy, err := getNetworkPolicy(c, "ns-a", NamespaceNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkPolicy.Spec.Egress).To(ContainElement(networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(otlpPort)},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: config.DedicatedTelemetryProxyPodLabels,
+					},
+				},
+			},
+		}))
+
+		isChanged, err := UpsertDedicatedTelemetryProxyNetworkPolicy(context.TODO(), c, config, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		networkPolicy, err = getNetworkPolicy(c, "ns-a", DedicatedTelemetryProxyNetworkPolicyName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(Equal(config.DedicatedTelemetryProxyPodLabels))
+		Expect(networkPolicy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+		// Only the pods of the namespace can send telemetry to it
+		Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+		Expect(networkPolicy.Spec.Ingress[0].From).To(Equal([]networkingv1.NetworkPolicyPeer{
+			{
+				PodSelector: &metav1.LabelSelector{},
+			},
+		}))
+		Expect(networkPolicy.Spec.Egress).To(Equal(telemetryProxyEgressRules(&MonitoredNamespaces{})))
+
+		isChanged, err = RemoveDedicatedTelemetryProxyNetworkPolicy(context.TODO(), c, "ns-a", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getNetworkPolicy(c, "ns-a", DedicatedTelemetryProxyNetworkPolicyName)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+})
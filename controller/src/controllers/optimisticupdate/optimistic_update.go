@@ -0,0 +1,49 @@
+package optimisticupdate
+
+import (
+	"context"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MutateFunc changes the object, returning whether it did
+type MutateFunc func(obj client.Object) (bool, error)
+
+// WriteFunc writes the changes of the mutated object to the original one, which must fail with a conflict
+// if the object has been updated since the original one was read
+type WriteFunc func(ctx context.Context, original client.Object, mutated client.Object) error
+
+// NewPatch returns the merge patch of the changes to the original object, with the resource version of the
+// original object as precondition: only the fields changed by the operator are sent, and the patch conflicts
+// if the object has been updated in the meantime, rather than overwriting the changes of the concurrent update.
+func NewPatch(original client.Object) client.Patch {
+	return client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+}
+
+// Apply reads the latest version of the object, of which only the namespace and name need to be set, applies
+// the mutation to a copy of it and, if the mutation changed it, writes the changes. If the write conflicts,
+// e.g., because the owner of a workload rolled out a new generation of it at the same time, the latest
+// version of the object is read again and the mutation is applied anew, with a backoff, so that the concurrent
+// changes are kept. The object is left with the version it was mutated from; returns whether it was changed.
+func Apply(ctx context.Context, reader client.Reader, obj client.Object, mutate MutateFunc, write WriteFunc) (bool, error) {
+	key := client.ObjectKeyFromObject(obj)
+
+	changed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := reader.Get(ctx, key, obj); err != nil {
+			return err
+		}
+
+		mutated := obj.DeepCopyObject().(client.Object)
+
+		var err error
+		if changed, err = mutate(mutated); err != nil || !changed {
+			return err
+		}
+
+		return write(ctx, obj, mutated)
+	})
+
+	return changed, err
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package optimisticupdate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	namespaceName  = "my-namespace"
+	deploymentName = "my-app"
+	injectedEnvVar = "LUMIGO_TRACER_TOKEN"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Optimistic Update Suite")
+}
+
+// Rolls out a new image of the deployment right after the first reads of it, as if its owner
+// updated it while the operator was removing the injection
+type racingReader struct {
+	client.Client
+	concurrentUpdates int
+	reads             int
+}
+
+func (r *racingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := r.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+
+	r.reads++
+	if r.reads > r.concurrentUpdates {
+		return nil
+	}
+
+	rollout := &appsv1.Deployment{}
+	if err := r.Client.Get(ctx, key, rollout); err != nil {
+		return err
+	}
+	rollout.Spec.Template.Spec.Containers[0].Image = fmt.Sprintf("my-app:v%d", r.reads+1)
+	return r.Client.Update(ctx, rollout)
+}
+
+func removeInjectedEnvVar(obj client.Object) (bool, error) {
+	deployment := obj.(*appsv1.Deployment)
+	container := &deployment.Spec.Template.Spec.Containers[0]
+	for i, envVar := range container.Env {
+		if envVar.Name == injectedEnvVar {
+			container.Env = append(container.Env[:i], container.Env[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var _ = Context("Optimistic updates", func() {
+
+	var c client.Client
+	var writes int
+	var write WriteFunc
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().WithObjects(&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespaceName,
+				Name:      deploymentName,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "app",
+								Image: "my-app:v1",
+								Env: []corev1.EnvVar{
+									{Name: "APP_ENV", Value: "production"},
+									{Name: injectedEnvVar, Value: "t_123456789012345678901"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}).Build()
+
+		writes = 0
+		write = func(ctx context.Context, original client.Object, mutated client.Object) error {
+			writes++
+			return c.Patch(ctx, mutated, NewPatch(original))
+		}
+	})
+
+	getDeployment := func() *appsv1.Deployment {
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: deploymentName}, deployment)).To(Succeed())
+		return deployment
+	}
+
+	It("writes the mutation of the latest version of the object", func() {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: deploymentName}}
+
+		changed, err := Apply(context.TODO(), c, deployment, removeInjectedEnvVar, write)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(writes).To(Equal(1))
+
+		Expect(getDeployment().Spec.Template.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+			{Name: "APP_ENV", Value: "production"},
+		}))
+	})
+
+	It("keeps the changes of the updates that race with the mutation", func() {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: deploymentName}}
+		reader := &racingReader{Client: c, concurrentUpdates: 2}
+
+		changed, err := Apply(context.TODO(), reader, deployment, removeInjectedEnvVar, write)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(writes).To(Equal(3))
+
+		container := getDeployment().Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("my-app:v3"))
+		Expect(container.Env).To(Equal([]corev1.EnvVar{
+			{Name: "APP_ENV", Value: "production"},
+		}))
+	})
+
+	It("does not overwrite concurrent updates with a stale patch", func() {
+		stale := getDeployment()
+		mutated := stale.DeepCopy()
+		Expect(removeInjectedEnvVar(mutated)).To(BeTrue())
+
+		rollout := getDeployment()
+		rollout.Spec.Template.Spec.Containers[0].Image = "my-app:v2"
+		Expect(c.Update(context.TODO(), rollout)).To(Succeed())
+
+		Expect(write(context.TODO(), stale, mutated)).NotTo(Succeed())
+		Expect(getDeployment().Spec.Template.Spec.Containers[0].Image).To(Equal("my-app:v2"))
+	})
+
+	It("does not write objects the mutation leaves unchanged", func() {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: deploymentName}}
+		Expect(Apply(context.TODO(), c, deployment, removeInjectedEnvVar, write)).To(BeTrue())
+
+		changed, err := Apply(context.TODO(), c, deployment, removeInjectedEnvVar, write)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(writes).To(Equal(1))
+	})
+
+	It("returns the errors of the mutation", func() {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: deploymentName}}
+
+		_, err := Apply(context.TODO(), c, deployment, func(obj client.Object) (bool, error) {
+			return false, fmt.Errorf("boom")
+		}, write)
+		Expect(err).To(MatchError("boom"))
+		Expect(writes).To(BeZero())
+	})
+})
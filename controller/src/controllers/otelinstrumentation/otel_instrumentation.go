@@ -0,0 +1,146 @@
+package otelinstrumentation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	OtelTracesSamplerEnvVarName    = "OTEL_TRACES_SAMPLER"
+	OtelTracesSamplerArgEnvVarName = "OTEL_TRACES_SAMPLER_ARG"
+	OtelPropagatorsEnvVarName      = "OTEL_PROPAGATORS"
+)
+
+// The `Instrumentation` resources of the OpenTelemetry operator (https://github.com/open-telemetry/opentelemetry-operator);
+// as with Keda, its types are not vendored, and the Instrumentations are read as unstructured objects
+var InstrumentationGroupVersionResource = schema.GroupVersionResource{
+	Group:    "opentelemetry.io",
+	Version:  "v1alpha1",
+	Resource: "instrumentations",
+}
+
+// The env vars that are not imported: the injected containers send their telemetry to the telemetry-proxy
+// rather than to the exporters of the Instrumentation, and the operator manages the Lumigo env vars itself
+var ignoredEnvVarNamePrefixes = []string{"OTEL_EXPORTER_", "LUMIGO_"}
+var ignoredEnvVarNames = []string{"LD_PRELOAD"}
+
+// The settings of the spec of `Instrumentation` resources that are imported
+type instrumentationSpec struct {
+	Env         []corev1.EnvVar `json:"env,omitempty"`
+	Propagators []string        `json:"propagators,omitempty"`
+	Sampler     struct {
+		Type     string `json:"type,omitempty"`
+		Argument string `json:"argument,omitempty"`
+	} `json:"sampler,omitempty"`
+}
+
+// GetImportedEnv retrieves the referenced Instrumentation, defaulting its namespace to the given one, and returns
+// the env vars equivalent to its settings.
+func GetImportedEnv(ctx context.Context, dynamicClient dynamic.Interface, ref *operatorv1alpha1.OpenTelemetryInstrumentationRef, defaultNamespace string) ([]corev1.EnvVar, error) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	instrumentation, err := dynamicClient.Resource(InstrumentationGroupVersionResource).Namespace(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve the Instrumentation '%s/%s': %w", namespace, ref.Name, err)
+	}
+
+	return ToEnv(instrumentation)
+}
+
+// ToEnv converts the sampler, propagators and env vars of an Instrumentation into the env vars the OpenTelemetry
+// operator would set on the instrumented containers. As with the OpenTelemetry operator, the env vars of the
+// Instrumentation take precedence over the ones for its sampler and propagators.
+func ToEnv(instrumentation *unstructured.Unstructured) ([]corev1.EnvVar, error) {
+	spec := &instrumentationSpec{}
+	if specContent, found, err := unstructured.NestedMap(instrumentation.Object, "spec"); err != nil {
+		return nil, fmt.Errorf("cannot read the spec of the Instrumentation '%s/%s': %w", instrumentation.GetNamespace(), instrumentation.GetName(), err)
+	} else if found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specContent, spec); err != nil {
+			return nil, fmt.Errorf("cannot parse the spec of the Instrumentation '%s/%s': %w", instrumentation.GetNamespace(), instrumentation.GetName(), err)
+		}
+	}
+
+	env := []corev1.EnvVar{}
+	for _, envVar := range spec.Env {
+		if isImported(envVar.Name) {
+			env = append(env, envVar)
+		}
+	}
+
+	if len(spec.Propagators) > 0 {
+		env = appendIfNotSet(env, corev1.EnvVar{Name: OtelPropagatorsEnvVarName, Value: strings.Join(spec.Propagators, ",")})
+	}
+	if spec.Sampler.Type != "" {
+		env = appendIfNotSet(env, corev1.EnvVar{Name: OtelTracesSamplerEnvVarName, Value: spec.Sampler.Type})
+		if spec.Sampler.Argument != "" {
+			env = appendIfNotSet(env, corev1.EnvVar{Name: OtelTracesSamplerArgEnvVarName, Value: spec.Sampler.Argument})
+		}
+	}
+
+	return env, nil
+}
+
+// SpecWithImportedEnv returns the spec of the Lumigo instance with the env vars imported in its status added
+// to `.spec.tracing.injection.extraEnv`, whose env vars take precedence, so that the imported env vars are
+// injected, and their injection removed, like the extra ones. The imported propagators are also overridden
+// by `.spec.tracing.propagators` and `.spec.tracing.awsXRay`. The spec of the Lumigo instance is not changed.
+func SpecWithImportedEnv(lumigo *operatorv1alpha1.Lumigo) *operatorv1alpha1.LumigoSpec {
+	if lumigo.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef == nil || len(lumigo.Status.ImportedEnv) < 1 {
+		return &lumigo.Spec
+	}
+
+	spec := lumigo.Spec.DeepCopy()
+	extraEnv := []corev1.EnvVar{}
+	for _, envVar := range lumigo.Status.ImportedEnv {
+		if envVar.Name == OtelPropagatorsEnvVarName && (len(spec.Tracing.Propagators) > 0 || isAwsXRayEnabled(spec)) {
+			continue
+		}
+		extraEnv = appendIfNotSet(extraEnv, *envVar.DeepCopy(), spec.Tracing.Injection.ExtraEnv...)
+	}
+	spec.Tracing.Injection.ExtraEnv = append(extraEnv, spec.Tracing.Injection.ExtraEnv...)
+
+	return spec
+}
+
+// Like mutation.IsAwsXRayEnabled, which cannot be imported here, as the mutation package imports this one
+func isAwsXRayEnabled(spec *operatorv1alpha1.LumigoSpec) bool {
+	return spec.Tracing.AwsXRay.Enabled != nil && *spec.Tracing.AwsXRay.Enabled
+}
+
+func isImported(envVarName string) bool {
+	if slices.Contains(ignoredEnvVarNames, envVarName) {
+		return false
+	}
+
+	for _, prefix := range ignoredEnvVarNamePrefixes {
+		if strings.HasPrefix(envVarName, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Appends the env var unless an env var with the same name is among the given ones or the others
+func appendIfNotSet(env []corev1.EnvVar, envVar corev1.EnvVar, others ...corev1.EnvVar) []corev1.EnvVar {
+	hasName := func(e corev1.EnvVar) bool { return e.Name == envVar.Name }
+	if slices.IndexFunc(env, hasName) > -1 || slices.IndexFunc(others, hasName) > -1 {
+		return env
+	}
+
+	return append(env, envVar)
+}
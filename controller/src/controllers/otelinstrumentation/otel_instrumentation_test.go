@@ -0,0 +1,147 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otelinstrumentation
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "OpenTelemetry Instrumentation Suite")
+}
+
+func newInstrumentation(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "opentelemetry.io/v1alpha1",
+			"kind":       "Instrumentation",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+			"spec": spec,
+		},
+	}
+}
+
+var _ = Context("OpenTelemetry Instrumentation import", func() {
+
+	It("converts the sampler, propagators and env of an Instrumentation", func() {
+		instrumentation := newInstrumentation("otel", "my-instrumentation", map[string]interface{}{
+			"propagators": []interface{}{"tracecontext", "baggage"},
+			"sampler": map[string]interface{}{
+				"type":     "parentbased_traceidratio",
+				"argument": "0.25",
+			},
+			"env": []interface{}{
+				map[string]interface{}{"name": "OTEL_SERVICE_NAME", "value": "checkout"},
+				map[string]interface{}{"name": "OTEL_EXPORTER_OTLP_ENDPOINT", "value": "http://collector:4318"},
+				map[string]interface{}{"name": "LUMIGO_DEBUG", "value": "true"},
+			},
+		})
+
+		Expect(ToEnv(instrumentation)).To(Equal([]corev1.EnvVar{
+			{Name: "OTEL_SERVICE_NAME", Value: "checkout"},
+			{Name: OtelPropagatorsEnvVarName, Value: "tracecontext,baggage"},
+			{Name: OtelTracesSamplerEnvVarName, Value: "parentbased_traceidratio"},
+			{Name: OtelTracesSamplerArgEnvVarName, Value: "0.25"},
+		}))
+	})
+
+	It("gives precedence to the env of the Instrumentation over its sampler", func() {
+		instrumentation := newInstrumentation("otel", "my-instrumentation", map[string]interface{}{
+			"sampler": map[string]interface{}{
+				"type": "always_on",
+			},
+			"env": []interface{}{
+				map[string]interface{}{"name": OtelTracesSamplerEnvVarName, "value": "always_off"},
+			},
+		})
+
+		Expect(ToEnv(instrumentation)).To(Equal([]corev1.EnvVar{
+			{Name: OtelTracesSamplerEnvVarName, Value: "always_off"},
+		}))
+	})
+
+	It("retrieves the Instrumentation in the namespace of the Lumigo instance by default", func() {
+		instrumentation := newInstrumentation("my-namespace", "my-instrumentation", map[string]interface{}{
+			"sampler": map[string]interface{}{
+				"type": "always_on",
+			},
+		})
+		dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			InstrumentationGroupVersionResource: "InstrumentationList",
+		}, instrumentation)
+
+		env, err := GetImportedEnv(context.Background(), dynamicClient, &operatorv1alpha1.OpenTelemetryInstrumentationRef{
+			Name: "my-instrumentation",
+		}, "my-namespace")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(env).To(Equal([]corev1.EnvVar{
+			{Name: OtelTracesSamplerEnvVarName, Value: "always_on"},
+		}))
+
+		_, err = GetImportedEnv(context.Background(), dynamicClient, &operatorv1alpha1.OpenTelemetryInstrumentationRef{
+			Name:      "my-instrumentation",
+			Namespace: "other-namespace",
+		}, "my-namespace")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("adds the imported env to the extra env of the spec, without overriding it", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				Tracing: operatorv1alpha1.TracingSpec{
+					Injection: operatorv1alpha1.InjectionSpec{
+						ExtraEnv: []corev1.EnvVar{{Name: OtelTracesSamplerEnvVarName, Value: "always_on"}},
+						OpenTelemetryInstrumentationRef: &operatorv1alpha1.OpenTelemetryInstrumentationRef{
+							Name: "my-instrumentation",
+						},
+					},
+				},
+			},
+			Status: operatorv1alpha1.LumigoStatus{
+				ImportedEnv: []corev1.EnvVar{
+					{Name: OtelPropagatorsEnvVarName, Value: "b3"},
+					{Name: OtelTracesSamplerEnvVarName, Value: "always_off"},
+				},
+			},
+		}
+
+		Expect(SpecWithImportedEnv(lumigo).Tracing.Injection.ExtraEnv).To(Equal([]corev1.EnvVar{
+			{Name: OtelPropagatorsEnvVarName, Value: "b3"},
+			{Name: OtelTracesSamplerEnvVarName, Value: "always_on"},
+		}))
+		Expect(lumigo.Spec.Tracing.Injection.ExtraEnv).To(HaveLen(1))
+
+		lumigo.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef = nil
+		Expect(SpecWithImportedEnv(lumigo)).To(BeIdenticalTo(&lumigo.Spec))
+	})
+})
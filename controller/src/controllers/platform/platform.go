@@ -0,0 +1,154 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// Platform is the flavor of Kubernetes the operator runs on; some flavors
+// restrict host paths, DaemonSets and the resources of init containers
+type Platform string
+
+const (
+	// Auto is the value of the platform setting that lets the operator detect the platform
+	Auto = "auto"
+
+	Standard     Platform = "standard"
+	GKEAutopilot Platform = "gke-autopilot"
+	EKSFargate   Platform = "eks-fargate"
+
+	// API group that only GKE Autopilot clusters serve
+	gkeAutopilotApiGroup = "auto.gke.io"
+	// Label that EKS puts on the virtual nodes running Fargate pods
+	eksComputeTypeLabel   = "eks.amazonaws.com/compute-type"
+	eksComputeTypeFargate = "fargate"
+)
+
+// Feature is a capability of the operator that some platforms do not support
+type Feature string
+
+const (
+	FeatureTelemetryProxyDaemonSet Feature = "telemetry-proxy DaemonSet mode"
+	FeatureGoInstrumentation       Feature = "Go instrumentation"
+)
+
+// Resolve returns the platform named by the value of the platform setting,
+// detecting it from the cluster if the value is empty or 'auto'. If the detection
+// fails, e.g., because the operator is not allowed to list nodes in the
+// namespace-scoped mode, the cluster is assumed to be a standard one.
+func Resolve(ctx context.Context, clientset kubernetes.Interface, value string, log *logr.Logger) (Platform, error) {
+	switch value {
+	case "", Auto:
+		platform, err := Detect(ctx, clientset)
+		if err != nil {
+			log.Error(err, "Cannot detect the platform, assuming a standard Kubernetes cluster; set the platform explicitly to run in compatibility mode")
+			return Standard, nil
+		}
+		return platform, nil
+	case string(Standard), string(GKEAutopilot), string(EKSFargate):
+		return Platform(value), nil
+	default:
+		return "", fmt.Errorf("unsupported platform '%s'; supported values: '%s', '%s', '%s', '%s'", value, Auto, Standard, GKEAutopilot, EKSFargate)
+	}
+}
+
+// Detect looks at the API groups and nodes of the cluster to tell which platform it is
+func Detect(ctx context.Context, clientset kubernetes.Interface) (Platform, error) {
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return "", fmt.Errorf("cannot list the API groups of the cluster: %w", err)
+	}
+
+	for _, group := range groups.Groups {
+		if group.Name == gkeAutopilotApiGroup {
+			return GKEAutopilot, nil
+		}
+	}
+
+	// The operator itself needs EC2 nodes, so EKS clusters with Fargate always mix the two;
+	// the pods on Fargate can reach neither DaemonSets nor their node, so any Fargate node
+	// is enough for the cluster to be treated as restricted
+	fargateNodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", eksComputeTypeLabel, eksComputeTypeFargate),
+		Limit:         1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot list the nodes of the cluster: %w", err)
+	}
+
+	if len(fargateNodes.Items) > 0 {
+		return EKSFargate, nil
+	}
+
+	return Standard, nil
+}
+
+// IsRestricted returns whether the operator runs in compatibility mode on the platform
+func (p Platform) IsRestricted() bool {
+	return p == GKEAutopilot || p == EKSFargate
+}
+
+// Supports returns whether the feature can be used on the platform; DaemonSets,
+// and the host access the Go instrumentation agent needs, are not available on
+// restricted platforms
+func (p Platform) Supports(feature Feature) bool {
+	return !p.IsRestricted()
+}
+
+// InjectorResources returns the resources of the `lumigo-injector` init container;
+// restricted platforms reject or resize pods whose containers set no resources, so
+// on those the init container gets small, explicit requests and limits.
+// If nil, the init container sets no resources.
+func (p Platform) InjectorResources() *corev1.ResourceRequirements {
+	if !p.IsRestricted() {
+		return nil
+	}
+
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("50m"),
+		corev1.ResourceMemory:           resource.MustParse("64Mi"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("100Mi"),
+	}
+
+	return &corev1.ResourceRequirements{
+		Requests: resources,
+		Limits:   resources.DeepCopy(),
+	}
+}
+
+// GetUnsupportedFeatures returns the features requested in the spec, or by the
+// operator's own configuration, that the platform does not support
+func GetUnsupportedFeatures(p Platform, spec *operatorv1alpha1.LumigoSpec, requestedFeatures []Feature) []Feature {
+	features := []Feature{}
+
+	for _, feature := range requestedFeatures {
+		if !p.Supports(feature) {
+			features = append(features, feature)
+		}
+	}
+
+	if spec != nil && spec.Tracing.GoInstrumentation.Enabled != nil && *spec.Tracing.GoInstrumentation.Enabled && !p.Supports(FeatureGoInstrumentation) {
+		features = append(features, FeatureGoInstrumentation)
+	}
+
+	return features
+}
+
+// DescribeUnsupportedFeatures returns the message of the UnsupportedFeatures condition
+func DescribeUnsupportedFeatures(p Platform, features []Feature) string {
+	names := make([]string, len(features))
+	for i, feature := range features {
+		names[i] = string(feature)
+	}
+
+	return fmt.Sprintf("The following features are not supported on %s and are disabled: %s", p, strings.Join(names, ", "))
+}
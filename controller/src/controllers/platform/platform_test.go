@@ -0,0 +1,121 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Platform Suite")
+}
+
+func newNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+var _ = Context("Platform", func() {
+
+	log := logr.Discard()
+	fargateLabels := map[string]string{eksComputeTypeLabel: eksComputeTypeFargate}
+
+	Context("detection", func() {
+
+		It("detects GKE Autopilot by its API group", func() {
+			clientset := fake.NewSimpleClientset(newNode("gk3-node", nil))
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+				{GroupVersion: "auto.gke.io/v1"},
+			}
+
+			Expect(Detect(context.TODO(), clientset)).To(Equal(GKEAutopilot))
+		})
+
+		It("detects EKS on Fargate when some nodes are Fargate ones", func() {
+			objects := []runtime.Object{
+				newNode("fargate-1", fargateLabels),
+				newNode("ec2-1", map[string]string{eksComputeTypeLabel: "ec2"}),
+			}
+			clientset := fake.NewSimpleClientset(objects...)
+
+			Expect(Detect(context.TODO(), clientset)).To(Equal(EKSFargate))
+		})
+
+		It("detects a standard cluster without Fargate nodes", func() {
+			clientset := fake.NewSimpleClientset(newNode("ec2-1", map[string]string{eksComputeTypeLabel: "ec2"}))
+
+			Expect(Detect(context.TODO(), clientset)).To(Equal(Standard))
+		})
+
+		It("uses the platform set explicitly without detecting it", func() {
+			clientset := fake.NewSimpleClientset()
+
+			Expect(Resolve(context.TODO(), clientset, "eks-fargate", &log)).To(Equal(EKSFargate))
+			Expect(Resolve(context.TODO(), clientset, "auto", &log)).To(Equal(Standard))
+
+			_, err := Resolve(context.TODO(), clientset, "aks", &log)
+			Expect(err).To(MatchError(ContainSubstring("unsupported platform 'aks'")))
+		})
+	})
+
+	Context("compatibility mode", func() {
+
+		goInstrumentationEnabled := true
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				GoInstrumentation: operatorv1alpha1.GoInstrumentationSpec{
+					Enabled: &goInstrumentationEnabled,
+				},
+			},
+		}
+
+		It("sets the resources of the injector only on restricted platforms", func() {
+			Expect(Standard.InjectorResources()).To(BeNil())
+
+			resources := GKEAutopilot.InjectorResources()
+			Expect(resources).NotTo(BeNil())
+			Expect(resources.Requests.Cpu().String()).To(Equal("50m"))
+			Expect(resources.Limits.Memory().String()).To(Equal("64Mi"))
+		})
+
+		It("reports the features the platform does not support", func() {
+			Expect(GetUnsupportedFeatures(Standard, spec, []Feature{FeatureTelemetryProxyDaemonSet})).To(BeEmpty())
+
+			features := GetUnsupportedFeatures(EKSFargate, spec, []Feature{FeatureTelemetryProxyDaemonSet})
+			Expect(features).To(Equal([]Feature{FeatureTelemetryProxyDaemonSet, FeatureGoInstrumentation}))
+			Expect(DescribeUnsupportedFeatures(EKSFargate, features)).To(Equal("The following features are not supported on eks-fargate and are disabled: telemetry-proxy DaemonSet mode, Go instrumentation"))
+		})
+	})
+})
@@ -0,0 +1,342 @@
+package selftelemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	DefaultServiceName = "lumigo-kubernetes-operator"
+
+	defaultExportInterval = 5 * time.Second
+	// Spans are dropped rather than buffered without bounds when the telemetry proxy cannot keep up
+	maxQueuedSpans = 2048
+
+	scopeName = "github.com/lumigo-io/lumigo-kubernetes-operator"
+
+	// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+	spanKindInternal = 1
+	spanKindServer   = 2
+	statusCodeError  = 2
+)
+
+// Tracer records the spans of the operator itself, like its reconciliations and admissions, and exports them
+// in OTLP/JSON to the telemetry proxy. A nil tracer is valid and records nothing, so that callers do not need
+// to check whether self-telemetry is enabled.
+type Tracer struct {
+	endpoint    string
+	token       string
+	serviceName string
+	httpClient  *http.Client
+	log         logr.Logger
+	// Added to the service name in the resource of the exported spans
+	resourceAttributes []Attribute
+
+	mutex sync.Mutex
+	spans []*Span
+}
+
+// NewTracer returns a tracer exporting spans to the given OTLP/HTTP traces endpoint, e.g., the one of the telemetry
+// proxy, authenticated with the given Lumigo token.
+func NewTracer(endpoint string, token string, serviceName string, log logr.Logger) *Tracer {
+	if len(serviceName) < 1 {
+		serviceName = DefaultServiceName
+	}
+
+	return &Tracer{
+		endpoint:    endpoint,
+		token:       token,
+		serviceName: serviceName,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		log:         log,
+	}
+}
+
+// WithResourceAttributes adds the given attributes to the resource of the exported spans, e.g., the namespace
+// that the telemetry-proxy attributes them to; it returns the tracer itself.
+func (t *Tracer) WithResourceAttributes(attributes ...Attribute) *Tracer {
+	t.resourceAttributes = append(t.resourceAttributes, attributes...)
+	return t
+}
+
+// Attribute is a key-value pair describing a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String returns an attribute with the given key and value.
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation of the operator; it must be ended with End for it to be exported
+type Span struct {
+	tracer       *Tracer
+	traceId      string
+	spanId       string
+	parentSpanId string
+	name         string
+	kind         int
+	start        time.Time
+	end          time.Time
+	attributes   []Attribute
+	errorMessage string
+}
+
+type spanContextKey struct{}
+
+// StartSpan starts a span with the given name, child of the span in the context, if any, and returns a context with the new span.
+func (t *Tracer) StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return t.start(ctx, name, spanKindInternal, attributes)
+}
+
+// StartServerSpan starts a span like StartSpan, but for the handling of a request the operator receives, like an admission.
+func (t *Tracer) StartServerSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return t.start(ctx, name, spanKindServer, attributes)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind int, attributes []Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:     t,
+		spanId:     randomHex(8),
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+
+	if parent, ok := ctx.Value(spanContextKey{}).(*Span); ok && parent != nil {
+		span.traceId = parent.traceId
+		span.parentSpanId = parent.spanId
+	} else {
+		span.traceId = randomHex(16)
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SetAttributes adds the given attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// RecordError marks the span as failed with the given error, if not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.errorMessage = err.Error()
+}
+
+// End ends the span and queues it for export.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.end = time.Now()
+
+	t := s.tracer
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.spans) >= maxQueuedSpans {
+		return
+	}
+
+	t.spans = append(t.spans, s)
+}
+
+// Start exports the queued spans periodically until the context is done, which makes the tracer a manager.Runnable.
+func (t *Tracer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(defaultExportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Flush the last spans with a fresh context, as the one of the manager is done
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := t.Flush(flushCtx); err != nil {
+				t.log.Error(err, "Cannot export the self-telemetry spans of the operator")
+			}
+			return nil
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.log.Error(err, "Cannot export the self-telemetry spans of the operator")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, as every replica of the operator exports its own spans.
+func (t *Tracer) NeedLeaderElection() bool {
+	return false
+}
+
+// Flush exports the queued spans.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mutex.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mutex.Unlock()
+
+	if len(spans) < 1 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.newExportRequest(spans))
+	if err != nil {
+		return fmt.Errorf("cannot marshal the spans: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("cannot create the export request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "LumigoToken "+t.token)
+
+	response, err := t.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("cannot send %d spans to '%s': %w", len(spans), t.endpoint, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("cannot send %d spans to '%s': unexpected status code %d", len(spans), t.endpoint, response.StatusCode)
+	}
+
+	return nil
+}
+
+// The OTLP/JSON encoding of an ExportTraceServiceRequest, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJson `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJson struct {
+	TraceId           string     `json:"traceId"`
+	SpanId            string     `json:"spanId"`
+	ParentSpanId      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (t *Tracer) newExportRequest(spans []*Span) *exportRequest {
+	spansJson := make([]spanJson, 0, len(spans))
+	for _, span := range spans {
+		s := spanJson{
+			TraceId:           span.traceId,
+			SpanId:            span.spanId,
+			ParentSpanId:      span.parentSpanId,
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        toKeyValues(span.attributes),
+		}
+		if len(span.errorMessage) > 0 {
+			s.Status = &status{
+				Code:    statusCodeError,
+				Message: span.errorMessage,
+			}
+		}
+		spansJson = append(spansJson, s)
+	}
+
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{
+			{
+				Resource: resource{
+					Attributes: toKeyValues(append([]Attribute{
+						String("service.name", t.serviceName),
+						String("telemetry.sdk.language", "go"),
+					}, t.resourceAttributes...)),
+				},
+				ScopeSpans: []scopeSpans{
+					{
+						Scope: scope{Name: scopeName},
+						Spans: spansJson,
+					},
+				},
+			},
+		},
+	}
+}
+
+func toKeyValues(attributes []Attribute) []keyValue {
+	keyValues := make([]keyValue, 0, len(attributes))
+	for _, attribute := range attributes {
+		keyValues = append(keyValues, keyValue{
+			Key:   attribute.Key,
+			Value: anyValue{StringValue: attribute.Value},
+		})
+	}
+
+	return keyValues
+}
+
+func randomHex(length int) string {
+	bytes := make([]byte, length)
+	// crypto/rand does not fail on the supported platforms
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
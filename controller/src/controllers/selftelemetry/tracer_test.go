@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selftelemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var t *testing.T
+
+func TestAPIs(tt *testing.T) {
+	t = tt
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Self-telemetry Suite")
+}
+
+var _ = Context("Self-telemetry", func() {
+
+	It("does nothing when disabled", func() {
+		var tracer *Tracer
+
+		ctx, span := tracer.StartSpan(context.TODO(), "Reconcile")
+		span.SetAttributes(String("k8s.namespace.name", "my-namespace"))
+		span.RecordError(fmt.Errorf("boom"))
+		span.End()
+
+		Expect(ctx).To(Equal(context.TODO()))
+		Expect(span).To(BeNil())
+	})
+
+	It("exports the spans in OTLP/JSON", func() {
+		var authorization string
+		var request exportRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(body, &request)).To(Succeed())
+		}))
+		defer server.Close()
+
+		tracer := NewTracer(server.URL+"/v1/traces", "t_123456789012345678901", "", testr.New(t))
+
+		ctx, parent := tracer.StartSpan(context.TODO(), "Reconcile", String("k8s.namespace.name", "my-namespace"))
+		_, child := tracer.StartSpan(ctx, "UpsertTelemetryProxyConfig")
+		child.RecordError(fmt.Errorf("boom"))
+		child.End()
+		parent.End()
+
+		Expect(tracer.Flush(context.TODO())).To(Succeed())
+
+		Expect(authorization).To(Equal("LumigoToken t_123456789012345678901"))
+		Expect(request.ResourceSpans).To(HaveLen(1))
+		Expect(request.ResourceSpans[0].Resource.Attributes).To(ContainElement(keyValue{Key: "service.name", Value: anyValue{StringValue: DefaultServiceName}}))
+
+		spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("UpsertTelemetryProxyConfig"))
+		Expect(spans[0].TraceId).To(Equal(spans[1].TraceId))
+		Expect(spans[0].ParentSpanId).To(Equal(spans[1].SpanId))
+		Expect(spans[0].Status).To(Equal(&status{Code: statusCodeError, Message: "boom"}))
+		Expect(spans[1].ParentSpanId).To(BeEmpty())
+		Expect(spans[1].Attributes).To(ConsistOf(keyValue{Key: "k8s.namespace.name", Value: anyValue{StringValue: "my-namespace"}}))
+
+		// The exported spans are not exported again
+		request = exportRequest{}
+		Expect(tracer.Flush(context.TODO())).To(Succeed())
+		Expect(request.ResourceSpans).To(BeEmpty())
+	})
+})
@@ -0,0 +1,298 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicemonitors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	DefaultSyncInterval   = 5 * time.Minute
+	DefaultScrapeInterval = "30s"
+
+	// The token of the service account of Prometheus, which the metrics endpoint of the controller manager
+	// authenticates and authorizes with a TokenReview and a SubjectAccessReview
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+)
+
+// The `ServiceMonitor` resources of the Prometheus operator (https://github.com/prometheus-operator/prometheus-operator);
+// as with the OpenTelemetry operator, its types are not vendored, and the ServiceMonitors are managed as unstructured objects
+var ServiceMonitorGroupVersionResource = schema.GroupVersionResource{
+	Group:    "monitoring.coreos.com",
+	Version:  "v1",
+	Resource: "servicemonitors",
+}
+
+// The labels of the Services that select them across upgrades of the Helm chart, unlike, e.g., the version of the chart
+var serviceSelectorLabelKeys = []string{
+	"app.kubernetes.io/name",
+	"app.kubernetes.io/instance",
+	"app.kubernetes.io/component",
+}
+
+// Target is a Service of the operator whose metrics are scraped through a ServiceMonitor named after it
+type Target struct {
+	ServiceName string
+	// The name of the port of the Service with the metrics
+	Port string
+	// Either `http` or `https`
+	Scheme string
+	// How often the metrics are scraped, e.g., `30s`
+	Interval string
+	// Whether the scrapers authenticate with the token of their service account
+	BearerTokenAuth bool
+	// The Secret with the `ca.crt` key that signs the certificate of an `https` endpoint; if empty, the
+	// certificate is self-signed and not verified
+	CASecretName string
+}
+
+// Syncer creates the ServiceMonitors of the Services of the operator, so that the Prometheus operator keeps scraping
+// their metrics across upgrades, and deletes the ServiceMonitors of the Services that are no longer to be scraped.
+// The ServiceMonitors are managed only while the cluster serves them, i.e., while the Prometheus operator CRDs
+// are installed.
+type Syncer struct {
+	serviceMonitors dynamic.NamespaceableResourceInterface
+	services        corev1client.ServicesGetter
+	isServed        func() bool
+	namespace       string
+	targets         []Target
+	interval        time.Duration
+	log             logr.Logger
+}
+
+// NewSyncer creates a Syncer of the ServiceMonitors of the given targets, in the namespace of the operator; isServed
+// returns whether the cluster currently serves the ServiceMonitors
+func NewSyncer(dynamicClient dynamic.Interface, services corev1client.ServicesGetter, isServed func() bool, namespace string, targets []Target, interval time.Duration, log logr.Logger) *Syncer {
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+
+	return &Syncer{
+		serviceMonitors: dynamicClient.Resource(ServiceMonitorGroupVersionResource),
+		services:        services,
+		isServed:        isServed,
+		namespace:       namespace,
+		targets:         targets,
+		interval:        interval,
+		log:             log,
+	}
+}
+
+// Start syncs the ServiceMonitors right away, and then periodically until the context is done, which makes the
+// syncer a manager.Runnable.
+func (s *Syncer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil {
+			s.log.Error(err, "Cannot sync the ServiceMonitors of the operator")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns true, as only one replica of the operator manages the ServiceMonitors.
+func (s *Syncer) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync upserts the ServiceMonitors of the targets, and deletes the other ones managed by the operator
+func (s *Syncer) Sync(ctx context.Context) error {
+	if !s.isServed() {
+		return nil
+	}
+
+	desiredNames := map[string]bool{}
+	for _, target := range s.targets {
+		service, err := s.services.Services(s.namespace).Get(ctx, target.ServiceName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				// E.g., the Helm chart is being uninstalled
+				continue
+			}
+			return fmt.Errorf("cannot retrieve the '%s' Service in namespace '%s': %w", target.ServiceName, s.namespace, err)
+		}
+
+		desiredNames[target.ServiceName] = true
+		if err := s.upsertServiceMonitor(ctx, NewServiceMonitor(service, &target)); err != nil {
+			return err
+		}
+	}
+
+	serviceMonitors, err := s.serviceMonitors.Namespace(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(newLabels()).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list the ServiceMonitors in namespace '%s': %w", s.namespace, err)
+	}
+
+	for _, serviceMonitor := range serviceMonitors.Items {
+		if desiredNames[serviceMonitor.GetName()] {
+			continue
+		}
+
+		if err := s.serviceMonitors.Namespace(s.namespace).Delete(ctx, serviceMonitor.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete the '%s' ServiceMonitor in namespace '%s': %w", serviceMonitor.GetName(), s.namespace, err)
+		}
+
+		s.log.Info("Deleted ServiceMonitor", "namespace", s.namespace, "name", serviceMonitor.GetName())
+	}
+
+	return nil
+}
+
+func (s *Syncer) upsertServiceMonitor(ctx context.Context, desiredServiceMonitor *unstructured.Unstructured) error {
+	serviceMonitors := s.serviceMonitors.Namespace(s.namespace)
+
+	serviceMonitor, err := serviceMonitors.Get(ctx, desiredServiceMonitor.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("cannot retrieve the '%s' ServiceMonitor in namespace '%s': %w", desiredServiceMonitor.GetName(), s.namespace, err)
+		}
+
+		if _, err := serviceMonitors.Create(ctx, desiredServiceMonitor, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("cannot create the '%s' ServiceMonitor in namespace '%s': %w", desiredServiceMonitor.GetName(), s.namespace, err)
+		}
+
+		s.log.Info("Created ServiceMonitor", "namespace", s.namespace, "name", desiredServiceMonitor.GetName())
+		return nil
+	}
+
+	if equality.Semantic.DeepEqual(serviceMonitor.Object["spec"], desiredServiceMonitor.Object["spec"]) &&
+		equality.Semantic.DeepEqual(serviceMonitor.GetLabels(), desiredServiceMonitor.GetLabels()) &&
+		equality.Semantic.DeepEqual(serviceMonitor.GetOwnerReferences(), desiredServiceMonitor.GetOwnerReferences()) {
+		return nil
+	}
+
+	serviceMonitor.SetLabels(desiredServiceMonitor.GetLabels())
+	serviceMonitor.SetOwnerReferences(desiredServiceMonitor.GetOwnerReferences())
+	serviceMonitor.Object["spec"] = desiredServiceMonitor.Object["spec"]
+	if _, err := serviceMonitors.Update(ctx, serviceMonitor, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("cannot update the '%s' ServiceMonitor in namespace '%s': %w", desiredServiceMonitor.GetName(), s.namespace, err)
+	}
+
+	s.log.Info("Updated ServiceMonitor", "namespace", s.namespace, "name", desiredServiceMonitor.GetName())
+	return nil
+}
+
+// NewServiceMonitor returns the ServiceMonitor of the target, which selects its Service by labels and is owned by
+// it, so that it is deleted with the Service, e.g., when the operator is uninstalled
+func NewServiceMonitor(service *corev1.Service, target *Target) *unstructured.Unstructured {
+	selectorLabels := map[string]interface{}{}
+	for _, key := range serviceSelectorLabelKeys {
+		if value, isSet := service.Labels[key]; isSet {
+			selectorLabels[key] = value
+		}
+	}
+
+	scheme := target.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	interval := target.Interval
+	if interval == "" {
+		interval = DefaultScrapeInterval
+	}
+
+	endpoint := map[string]interface{}{
+		"port":     target.Port,
+		"path":     "/metrics",
+		"scheme":   scheme,
+		"interval": interval,
+	}
+	if target.BearerTokenAuth {
+		endpoint["bearerTokenFile"] = serviceAccountTokenFile
+	}
+	if scheme == "https" {
+		if target.CASecretName == "" {
+			endpoint["tlsConfig"] = map[string]interface{}{
+				"insecureSkipVerify": true,
+			}
+		} else {
+			endpoint["tlsConfig"] = map[string]interface{}{
+				"serverName": fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace),
+				"ca": map[string]interface{}{
+					"secret": map[string]interface{}{
+						"name": target.CASecretName,
+						"key":  "ca.crt",
+					},
+				},
+			}
+		}
+	}
+
+	serviceMonitor := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": selectorLabels,
+				},
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{service.Namespace},
+				},
+				"endpoints": []interface{}{endpoint},
+			},
+		},
+	}
+	serviceMonitor.SetAPIVersion(ServiceMonitorGroupVersionResource.GroupVersion().String())
+	serviceMonitor.SetKind("ServiceMonitor")
+	serviceMonitor.SetNamespace(service.Namespace)
+	serviceMonitor.SetName(target.ServiceName)
+	serviceMonitor.SetLabels(newLabels())
+	serviceMonitor.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Name:       service.Name,
+			UID:        service.UID,
+		},
+	})
+
+	return serviceMonitor
+}
+
+func newLabels() map[string]string {
+	return map[string]string{
+		kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicemonitors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "ServiceMonitors Suite")
+}
+
+var _ = Context("ServiceMonitors", func() {
+
+	const namespace = "lumigo-system"
+
+	var dynamicClient *dynamicfake.FakeDynamicClient
+	var clientset *fake.Clientset
+	var isServed bool
+
+	newService := func(name string, component string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				UID:       "1234",
+				Labels: map[string]string{
+					"app.kubernetes.io/name":      "lumigo-operator",
+					"app.kubernetes.io/instance":  "lumigo",
+					"app.kubernetes.io/component": component,
+					"helm.sh/chart":               "lumigo-operator-1.0.0",
+				},
+			},
+		}
+	}
+
+	metricsTarget := Target{
+		ServiceName:     "lumigo-lumigo-operator-controller-manager-metrics-service",
+		Port:            "https",
+		Scheme:          "https",
+		BearerTokenAuth: true,
+	}
+	telemetryProxyTarget := Target{
+		ServiceName: "lumigo-lumigo-operator-telemetry-proxy-service",
+		Port:        "metrics",
+		Interval:    "1m",
+	}
+
+	newSyncer := func(targets ...Target) *Syncer {
+		return NewSyncer(dynamicClient, clientset.CoreV1(), func() bool { return isServed }, namespace, targets, time.Minute, logr.Discard())
+	}
+
+	getServiceMonitor := func(name string) *unstructured.Unstructured {
+		serviceMonitor, err := dynamicClient.Resource(ServiceMonitorGroupVersionResource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return serviceMonitor
+	}
+
+	listServiceMonitors := func() []unstructured.Unstructured {
+		serviceMonitors, err := dynamicClient.Resource(ServiceMonitorGroupVersionResource).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return serviceMonitors.Items
+	}
+
+	BeforeEach(func() {
+		dynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			ServiceMonitorGroupVersionResource: "ServiceMonitorList",
+		})
+		clientset = fake.NewSimpleClientset(
+			newService(metricsTarget.ServiceName, "metrics"),
+			newService(telemetryProxyTarget.ServiceName, "telemetry-proxy"),
+		)
+		isServed = true
+	})
+
+	It("scrapes the metrics of the Services of the operator", func() {
+		Expect(newSyncer(metricsTarget, telemetryProxyTarget).Sync(context.TODO())).To(Succeed())
+
+		serviceMonitor := getServiceMonitor(telemetryProxyTarget.ServiceName)
+		Expect(serviceMonitor.GetLabels()).To(Equal(map[string]string{
+			"app.kubernetes.io/part-of":    "lumigo",
+			"app.kubernetes.io/managed-by": "lumigo-operator",
+		}))
+		Expect(serviceMonitor.GetOwnerReferences()).To(ConsistOf(HaveField("Name", telemetryProxyTarget.ServiceName)))
+		Expect(serviceMonitor.Object["spec"]).To(Equal(map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{
+					"app.kubernetes.io/name":      "lumigo-operator",
+					"app.kubernetes.io/instance":  "lumigo",
+					"app.kubernetes.io/component": "telemetry-proxy",
+				},
+			},
+			"namespaceSelector": map[string]interface{}{
+				"matchNames": []interface{}{namespace},
+			},
+			"endpoints": []interface{}{
+				map[string]interface{}{
+					"port":     "metrics",
+					"path":     "/metrics",
+					"scheme":   "http",
+					"interval": "1m",
+				},
+			},
+		}))
+
+		// The metrics endpoint of the controller manager requires the token of Prometheus, and has a self-signed certificate
+		endpoints, _, err := unstructured.NestedSlice(getServiceMonitor(metricsTarget.ServiceName).Object, "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints).To(Equal([]interface{}{
+			map[string]interface{}{
+				"port":            "https",
+				"path":            "/metrics",
+				"scheme":          "https",
+				"interval":        DefaultScrapeInterval,
+				"bearerTokenFile": "/var/run/secrets/kubernetes.io/serviceaccount/token",
+				"tlsConfig": map[string]interface{}{
+					"insecureSkipVerify": true,
+				},
+			},
+		}))
+	})
+
+	It("verifies the certificate of the metrics endpoint with the CA of its Secret", func() {
+		metricsTarget := metricsTarget
+		metricsTarget.CASecretName = "lumigo-metrics-tls"
+		Expect(newSyncer(metricsTarget).Sync(context.TODO())).To(Succeed())
+
+		endpoints, _, err := unstructured.NestedSlice(getServiceMonitor(metricsTarget.ServiceName).Object, "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints[0].(map[string]interface{})["tlsConfig"]).To(Equal(map[string]interface{}{
+			"serverName": "lumigo-lumigo-operator-controller-manager-metrics-service.lumigo-system.svc",
+			"ca": map[string]interface{}{
+				"secret": map[string]interface{}{
+					"name": "lumigo-metrics-tls",
+					"key":  "ca.crt",
+				},
+			},
+		}))
+	})
+
+	It("updates the ServiceMonitors that drift, and deletes the disabled ones", func() {
+		Expect(newSyncer(metricsTarget, telemetryProxyTarget).Sync(context.TODO())).To(Succeed())
+		Expect(listServiceMonitors()).To(HaveLen(2))
+
+		serviceMonitor := getServiceMonitor(telemetryProxyTarget.ServiceName)
+		Expect(unstructured.SetNestedField(serviceMonitor.Object, []interface{}{}, "spec", "endpoints")).To(Succeed())
+		_, err := dynamicClient.Resource(ServiceMonitorGroupVersionResource).Namespace(namespace).Update(context.TODO(), serviceMonitor, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(newSyncer(telemetryProxyTarget).Sync(context.TODO())).To(Succeed())
+
+		Expect(listServiceMonitors()).To(ConsistOf(HaveField("Object", HaveKeyWithValue("metadata", HaveKeyWithValue("name", telemetryProxyTarget.ServiceName)))))
+		endpoints, _, err := unstructured.NestedSlice(getServiceMonitor(telemetryProxyTarget.ServiceName).Object, "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints).To(HaveLen(1))
+	})
+
+	It("leaves alone the ServiceMonitors it does not manage", func() {
+		_, err := dynamicClient.Resource(ServiceMonitorGroupVersionResource).Namespace(namespace).Create(context.TODO(), &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "monitoring.coreos.com/v1",
+				"kind":       "ServiceMonitor",
+				"metadata": map[string]interface{}{
+					"namespace": namespace,
+					"name":      "my-service-monitor",
+				},
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(newSyncer().Sync(context.TODO())).To(Succeed())
+
+		Expect(listServiceMonitors()).To(HaveLen(1))
+	})
+
+	It("does nothing while the cluster does not serve ServiceMonitors", func() {
+		isServed = false
+
+		Expect(newSyncer(metricsTarget, telemetryProxyTarget).Sync(context.TODO())).To(Succeed())
+
+		Expect(dynamicClient.Actions()).To(BeEmpty())
+	})
+
+	It("skips the Services that do not exist", func() {
+		Expect(newSyncer(Target{ServiceName: "my-service", Port: "metrics"}, telemetryProxyTarget).Sync(context.TODO())).To(Succeed())
+
+		Expect(listServiceMonitors()).To(HaveLen(1))
+	})
+
+})
@@ -0,0 +1,221 @@
+package specvalidation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// GetInconsistencies returns the combinations of settings of the spec that contradict each other,
+// or that have no effect because of other settings, each described with an explicit message.
+func GetInconsistencies(spec *operatorv1alpha1.LumigoSpec) []string {
+	inconsistencies := []string{}
+
+	injection := &spec.Tracing.Injection
+	if !isTruthy(injection.Enabled, true) {
+		if isTruthy(injection.InjectLumigoIntoExistingResourcesOnCreation, false) {
+			inconsistencies = append(inconsistencies, "'.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation' is 'true', but no resource is injected as '.Spec.Tracing.Injection.Enabled' is 'false'")
+		}
+
+		if ignoredSettings := getInjectionSettings(spec); len(ignoredSettings) > 0 {
+			inconsistencies = append(inconsistencies, fmt.Sprintf("%s %s no effect, as '.Spec.Tracing.Injection.Enabled' is 'false'", strings.Join(ignoredSettings, ", "), hasOrHave(ignoredSettings)))
+		}
+	}
+
+	if injection.RemovalMode != "" && !isTruthy(injection.RemoveLumigoFromResourcesOnDeletion, true) {
+		inconsistencies = append(inconsistencies, "'.Spec.Tracing.Injection.RemovalMode' has no effect, as '.Spec.Tracing.Injection.RemoveLumigoFromResourcesOnDeletion' is 'false'")
+	}
+
+	for _, overriddenSetting := range getSettingsOverriddenByExtraEnv(spec) {
+		inconsistencies = append(inconsistencies, fmt.Sprintf("'%s' has no effect, as '.Spec.Tracing.Injection.ExtraEnv' sets '%s'", overriddenSetting.setting, overriddenSetting.envVarName))
+	}
+
+	infrastructure := &spec.Infrastructure
+	if !isTruthy(infrastructure.Enabled, true) {
+		enabledFeatures := []string{}
+		if isTruthy(infrastructure.Prometheus.Enabled, false) {
+			enabledFeatures = append(enabledFeatures, "'.Spec.Infrastructure.Prometheus.Enabled'")
+		}
+		if isTruthy(infrastructure.NodeLifecycle.Enabled, false) {
+			enabledFeatures = append(enabledFeatures, "'.Spec.Infrastructure.NodeLifecycle.Enabled'")
+		}
+
+		if len(enabledFeatures) > 0 {
+			inconsistencies = append(inconsistencies, fmt.Sprintf("%s %s 'true', but no infrastructure telemetry is collected as '.Spec.Infrastructure.Enabled' is 'false'", strings.Join(enabledFeatures, ", "), isOrAre(enabledFeatures)))
+		}
+	} else if !isTruthy(infrastructure.Prometheus.Enabled, false) && len(infrastructure.Prometheus.ScrapeTargets) > 0 {
+		inconsistencies = append(inconsistencies, "'.Spec.Infrastructure.Prometheus.ScrapeTargets' has no effect, as '.Spec.Infrastructure.Prometheus.Enabled' is not 'true'")
+	}
+
+	pipelines := &spec.Pipelines
+	if !isTruthy(pipelines.Logs.Enabled, true) && isTruthy(spec.Logging.Enabled, false) {
+		inconsistencies = append(inconsistencies, "'.Spec.Logging.Enabled' is 'true', but no application logs are sent as '.Spec.Pipelines.Logs.Enabled' is 'false'")
+	}
+	if !isTruthy(pipelines.Traces.Enabled, true) && isTruthy(spec.Tracing.SpanMetrics.Enabled, false) {
+		inconsistencies = append(inconsistencies, "'.Spec.Tracing.SpanMetrics.Enabled' is 'true', but no span metrics are sent as '.Spec.Pipelines.Traces.Enabled' is 'false'")
+	}
+	if !isTruthy(pipelines.Metrics.Enabled, true) {
+		enabledFeatures := []string{}
+		if isTruthy(infrastructure.Enabled, true) && isTruthy(infrastructure.Prometheus.Enabled, false) {
+			enabledFeatures = append(enabledFeatures, "'.Spec.Infrastructure.Prometheus.Enabled'")
+		}
+		// Already reported above when the traces are disabled
+		if isTruthy(pipelines.Traces.Enabled, true) && isTruthy(spec.Tracing.SpanMetrics.Enabled, false) {
+			enabledFeatures = append(enabledFeatures, "'.Spec.Tracing.SpanMetrics.Enabled'")
+		}
+		if len(enabledFeatures) > 0 {
+			inconsistencies = append(inconsistencies, fmt.Sprintf("%s %s 'true', but no metrics are sent as '.Spec.Pipelines.Metrics.Enabled' is 'false'", strings.Join(enabledFeatures, ", "), isOrAre(enabledFeatures)))
+		}
+	}
+
+	quota := &spec.Quota
+	if quota.SamplingPercentageWhenExceeded != nil && quota.MaxSpansPerDay == nil && quota.MaxGigabytesPerDay == nil {
+		inconsistencies = append(inconsistencies, "'.Spec.Quota.SamplingPercentageWhenExceeded' has no effect, as neither '.Spec.Quota.MaxSpansPerDay' nor '.Spec.Quota.MaxGigabytesPerDay' is set")
+	}
+
+	return inconsistencies
+}
+
+// GetNewInconsistencies returns the inconsistencies of the updated spec that the original spec did not have,
+// so that the updates of Lumigo instances that predate a check are not rejected because of it.
+func GetNewInconsistencies(original *operatorv1alpha1.LumigoSpec, updated *operatorv1alpha1.LumigoSpec) []string {
+	existingInconsistencies := map[string]bool{}
+	for _, inconsistency := range GetInconsistencies(original) {
+		existingInconsistencies[inconsistency] = true
+	}
+
+	newInconsistencies := []string{}
+	for _, inconsistency := range GetInconsistencies(updated) {
+		if !existingInconsistencies[inconsistency] {
+			newInconsistencies = append(newInconsistencies, inconsistency)
+		}
+	}
+
+	return newInconsistencies
+}
+
+// The settings that only affect how resources are injected; the unsupported architecture policy is
+// left out, as it is set on all Lumigo instances by the defaulter webhook
+func getInjectionSettings(spec *operatorv1alpha1.LumigoSpec) []string {
+	injection := &spec.Tracing.Injection
+	settings := []string{}
+	if injection.InjectorImagePullPolicy != "" {
+		settings = append(settings, "'.Spec.Tracing.Injection.InjectorImagePullPolicy'")
+	}
+	if len(injection.InjectorImagePullSecrets) > 0 {
+		settings = append(settings, "'.Spec.Tracing.Injection.InjectorImagePullSecrets'")
+	}
+	if injection.MaxConcurrentWorkloadUpdates != nil {
+		settings = append(settings, "'.Spec.Tracing.Injection.MaxConcurrentWorkloadUpdates'")
+	}
+	if len(injection.ExtraEnv) > 0 {
+		settings = append(settings, "'.Spec.Tracing.Injection.ExtraEnv'")
+	}
+	if injection.OpenTelemetryInstrumentationRef != nil {
+		settings = append(settings, "'.Spec.Tracing.Injection.OpenTelemetryInstrumentationRef'")
+	}
+	if !reflect.DeepEqual(injection.PayloadCapture, operatorv1alpha1.PayloadCaptureSpec{}) {
+		settings = append(settings, "'.Spec.Tracing.Injection.PayloadCapture'")
+	}
+	if injection.ServiceNameTemplate != "" {
+		settings = append(settings, "'.Spec.Tracing.Injection.ServiceNameTemplate'")
+	}
+	if injection.TokenInjectionMode != "" {
+		settings = append(settings, "'.Spec.Tracing.Injection.TokenInjectionMode'")
+	}
+	if injection.TokenMissingPolicy != "" {
+		settings = append(settings, "'.Spec.Tracing.Injection.TokenMissingPolicy'")
+	}
+	if len(injection.WorkloadTypes) > 0 {
+		settings = append(settings, "'.Spec.Tracing.Injection.WorkloadTypes'")
+	}
+	if injection.OnlyWorkloadsCreatedAfter != nil {
+		settings = append(settings, "'.Spec.Tracing.Injection.OnlyWorkloadsCreatedAfter'")
+	}
+	if len(spec.Tracing.Routes) > 0 {
+		settings = append(settings, "'.Spec.Tracing.Routes'")
+	}
+	if len(spec.Tracing.Propagators) > 0 {
+		settings = append(settings, "'.Spec.Tracing.Propagators'")
+	}
+
+	return settings
+}
+
+type overriddenSetting struct {
+	setting    string
+	envVarName string
+}
+
+// The settings injected as env vars that are also set by the extra env, which takes precedence over them
+func getSettingsOverriddenByExtraEnv(spec *operatorv1alpha1.LumigoSpec) []overriddenSetting {
+	injection := &spec.Tracing.Injection
+	isSetByExtraEnv := func(envVarName string) bool {
+		return slices.IndexFunc(injection.ExtraEnv, func(e corev1.EnvVar) bool { return e.Name == envVarName }) > -1
+	}
+
+	overriddenSettings := []overriddenSetting{}
+	if len(spec.Tracing.Propagators) > 0 && isSetByExtraEnv(mutation.OtelPropagatorsEnvVarName) {
+		overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.Propagators", mutation.OtelPropagatorsEnvVarName})
+	}
+	if mutation.IsAwsXRayEnabled(spec) && isSetByExtraEnv(mutation.OtelPropagatorsEnvVarName) {
+		overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.AwsXRay", mutation.OtelPropagatorsEnvVarName})
+	}
+	if spec.Tracing.Sampling.Percentage != nil || spec.Tracing.Sampling.ParentBased != nil {
+		for _, envVarName := range []string{mutation.OtelTracesSamplerEnvVarName, mutation.OtelTracesSamplerArgEnvVarName} {
+			if isSetByExtraEnv(envVarName) {
+				overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.Sampling", envVarName})
+			}
+		}
+	}
+
+	payloadCapture := &injection.PayloadCapture
+	if payloadCapture.MaxEntrySize != nil && isSetByExtraEnv(mutation.LumigoMaxEntrySizeEnvVarName) {
+		overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.Injection.PayloadCapture.MaxEntrySize", mutation.LumigoMaxEntrySizeEnvVarName})
+	}
+	if !isTruthy(payloadCapture.CaptureRequestBodies, true) && isSetByExtraEnv(mutation.LumigoSecretMaskingRequestBodiesEnvVarName) {
+		overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.Injection.PayloadCapture.CaptureRequestBodies", mutation.LumigoSecretMaskingRequestBodiesEnvVarName})
+	}
+	if !isTruthy(payloadCapture.CaptureResponseBodies, true) && isSetByExtraEnv(mutation.LumigoSecretMaskingResponseBodiesEnvVarName) {
+		overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.Injection.PayloadCapture.CaptureResponseBodies", mutation.LumigoSecretMaskingResponseBodiesEnvVarName})
+	}
+	if len(payloadCapture.AllowedHeaders) > 0 {
+		for _, envVarName := range []string{mutation.LumigoSecretMaskingRequestHeadersEnvVarName, mutation.LumigoSecretMaskingResponseHeadersEnvVarName} {
+			if isSetByExtraEnv(envVarName) {
+				overriddenSettings = append(overriddenSettings, overriddenSetting{".Spec.Tracing.Injection.PayloadCapture.AllowedHeaders", envVarName})
+			}
+		}
+	}
+
+	return overriddenSettings
+}
+
+func hasOrHave(settings []string) string {
+	if len(settings) > 1 {
+		return "have"
+	}
+
+	return "has"
+}
+
+func isOrAre(settings []string) string {
+	if len(settings) > 1 {
+		return "are"
+	}
+
+	return "is"
+}
+
+func isTruthy(value *bool, defaultIfNil bool) bool {
+	if value == nil {
+		return defaultIfNil
+	}
+
+	return *value
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package specvalidation
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Spec Validation Suite")
+}
+
+func newBool(value bool) *bool {
+	return &value
+}
+
+func newInt32(value int32) *int32 {
+	return &value
+}
+
+var _ = Context("Spec validation", func() {
+
+	It("accepts the defaulted spec", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					Enabled: newBool(true),
+					InjectLumigoIntoExistingResourcesOnCreation: newBool(true),
+					RemoveLumigoFromResourcesOnDeletion:         newBool(true),
+					UnsupportedArchitecturePolicy:               operatorv1alpha1.UnsupportedArchitecturePolicySkip,
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(BeEmpty())
+	})
+
+	It("reports the injection settings that have no effect when the injection is disabled", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					Enabled: newBool(false),
+					InjectLumigoIntoExistingResourcesOnCreation: newBool(true),
+					// Removing the injection of the resources injected earlier is meaningful
+					RemoveLumigoFromResourcesOnDeletion: newBool(true),
+					ExtraEnv:                            []corev1.EnvVar{{Name: "LUMIGO_DEBUG", Value: "true"}},
+					WorkloadTypes:                       []operatorv1alpha1.WorkloadType{operatorv1alpha1.WorkloadTypeDeployment},
+					OnlyWorkloadsCreatedAfter:           &metav1.Time{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation' is 'true', but no resource is injected as '.Spec.Tracing.Injection.Enabled' is 'false'",
+			"'.Spec.Tracing.Injection.ExtraEnv', '.Spec.Tracing.Injection.WorkloadTypes', '.Spec.Tracing.Injection.OnlyWorkloadsCreatedAfter' have no effect, as '.Spec.Tracing.Injection.Enabled' is 'false'",
+		))
+	})
+
+	It("reports the removal mode when the injection is not removed on deletion", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					RemoveLumigoFromResourcesOnDeletion: newBool(false),
+					RemovalMode:                         operatorv1alpha1.RemovalModeBackground,
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.Injection.RemovalMode' has no effect, as '.Spec.Tracing.Injection.RemoveLumigoFromResourcesOnDeletion' is 'false'",
+		))
+	})
+
+	It("reports the routes that have no effect when the injection is disabled", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					Enabled: newBool(false),
+				},
+				Routes: []operatorv1alpha1.TracingRoute{
+					{
+						Name: "payments",
+						LumigoToken: operatorv1alpha1.Credentials{
+							SecretRef: operatorv1alpha1.KubernetesSecretRef{
+								Name: "lumigo-payments",
+								Key:  "token",
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.Routes' has no effect, as '.Spec.Tracing.Injection.Enabled' is 'false'",
+		))
+	})
+
+	It("reports the propagators overridden by the extra env", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					ExtraEnv: []corev1.EnvVar{{Name: "OTEL_PROPAGATORS", Value: "b3"}},
+				},
+				Propagators: []operatorv1alpha1.Propagator{operatorv1alpha1.PropagatorXRay},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.Propagators' has no effect, as '.Spec.Tracing.Injection.ExtraEnv' sets 'OTEL_PROPAGATORS'",
+		))
+	})
+
+	It("reports the X-Ray propagation overridden by the extra env", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					ExtraEnv: []corev1.EnvVar{{Name: "OTEL_PROPAGATORS", Value: "b3"}},
+				},
+				AwsXRay: operatorv1alpha1.AwsXRaySpec{
+					Enabled: newBool(true),
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.AwsXRay' has no effect, as '.Spec.Tracing.Injection.ExtraEnv' sets 'OTEL_PROPAGATORS'",
+		))
+	})
+
+	It("reports the sampling overridden by the extra env", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					ExtraEnv: []corev1.EnvVar{{Name: "OTEL_TRACES_SAMPLER_ARG", Value: "0.1"}},
+				},
+				Sampling: operatorv1alpha1.SamplingSpec{
+					Percentage: newInt32(50),
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.Sampling' has no effect, as '.Spec.Tracing.Injection.ExtraEnv' sets 'OTEL_TRACES_SAMPLER_ARG'",
+		))
+	})
+
+	It("reports the payload capture settings overridden by the extra env", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					ExtraEnv: []corev1.EnvVar{
+						{Name: "LUMIGO_MAX_ENTRY_SIZE", Value: "1024"},
+						{Name: "LUMIGO_SECRET_MASKING_REGEX_HTTP_REQUEST_BODIES", Value: "none"},
+					},
+					PayloadCapture: operatorv1alpha1.PayloadCaptureSpec{
+						// The request bodies are captured, so the masking of the extra env does not contradict it
+						CaptureRequestBodies: newBool(true),
+						MaxEntrySize:         newInt32(2048),
+					},
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Tracing.Injection.PayloadCapture.MaxEntrySize' has no effect, as '.Spec.Tracing.Injection.ExtraEnv' sets 'LUMIGO_MAX_ENTRY_SIZE'",
+		))
+	})
+
+	It("reports the infrastructure features enabled when the infrastructure telemetry is disabled", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Infrastructure: operatorv1alpha1.InfrastructureSpec{
+				Enabled: newBool(false),
+				NodeLifecycle: operatorv1alpha1.NodeLifecycleSpec{
+					Enabled: newBool(true),
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Infrastructure.NodeLifecycle.Enabled' is 'true', but no infrastructure telemetry is collected as '.Spec.Infrastructure.Enabled' is 'false'",
+		))
+	})
+
+	It("reports the telemetry enabled when its pipeline is disabled", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				SpanMetrics: operatorv1alpha1.SpanMetricsSpec{
+					Enabled: newBool(true),
+				},
+			},
+			Logging: operatorv1alpha1.LoggingSpec{
+				Enabled: newBool(true),
+			},
+			Infrastructure: operatorv1alpha1.InfrastructureSpec{
+				Prometheus: operatorv1alpha1.PrometheusSpec{
+					Enabled: newBool(true),
+				},
+			},
+			Pipelines: operatorv1alpha1.PipelinesSpec{
+				Logs: operatorv1alpha1.PipelineSpec{
+					Enabled: newBool(false),
+				},
+				Metrics: operatorv1alpha1.PipelineSpec{
+					Enabled: newBool(false),
+				},
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Logging.Enabled' is 'true', but no application logs are sent as '.Spec.Pipelines.Logs.Enabled' is 'false'",
+			"'.Spec.Infrastructure.Prometheus.Enabled', '.Spec.Tracing.SpanMetrics.Enabled' are 'true', but no metrics are sent as '.Spec.Pipelines.Metrics.Enabled' is 'false'",
+		))
+
+		spec.Pipelines.Traces.Enabled = newBool(false)
+		Expect(GetInconsistencies(spec)).To(ConsistOf(
+			"'.Spec.Logging.Enabled' is 'true', but no application logs are sent as '.Spec.Pipelines.Logs.Enabled' is 'false'",
+			"'.Spec.Tracing.SpanMetrics.Enabled' is 'true', but no span metrics are sent as '.Spec.Pipelines.Traces.Enabled' is 'false'",
+			"'.Spec.Infrastructure.Prometheus.Enabled' is 'true', but no metrics are sent as '.Spec.Pipelines.Metrics.Enabled' is 'false'",
+		))
+	})
+
+	It("reports the sampling of a quota that is not set", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			Quota: operatorv1alpha1.QuotaSpec{
+				SamplingPercentageWhenExceeded: newInt32(10),
+			},
+		}
+
+		Expect(GetInconsistencies(spec)).To(HaveLen(1))
+
+		spec.Quota.MaxGigabytesPerDay = newInt32(1)
+		Expect(GetInconsistencies(spec)).To(BeEmpty())
+	})
+
+	It("reports only the inconsistencies introduced by an update", func() {
+		original := &operatorv1alpha1.LumigoSpec{
+			Tracing: operatorv1alpha1.TracingSpec{
+				Injection: operatorv1alpha1.InjectionSpec{
+					Enabled: newBool(false),
+					InjectLumigoIntoExistingResourcesOnCreation: newBool(true),
+				},
+			},
+		}
+
+		updated := original.DeepCopy()
+		Expect(GetNewInconsistencies(original, updated)).To(BeEmpty())
+
+		updated.Quota.SamplingPercentageWhenExceeded = newInt32(10)
+		Expect(GetNewInconsistencies(original, updated)).To(ConsistOf(
+			"'.Spec.Quota.SamplingPercentageWhenExceeded' has no effect, as neither '.Spec.Quota.MaxSpansPerDay' nor '.Spec.Quota.MaxGigabytesPerDay' is set",
+		))
+	})
+})
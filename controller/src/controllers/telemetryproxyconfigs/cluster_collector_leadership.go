@@ -0,0 +1,61 @@
+package telemetryproxyconfigs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+)
+
+// ClusterCollectorLeadership tells the telemetry-proxy next to the controller that it collects the cluster-wide
+// telemetry, like Kubernetes events and Prometheus metrics, by creating the leader file it watches. As it needs leader
+// election, it is started only on the leader of the replicas of the controller manager, so that the cluster-wide
+// telemetry is collected exactly once, while the telemetry-proxies of all replicas receive the telemetry of the workloads.
+type ClusterCollectorLeadership struct {
+	leaderFilePath string
+	log            logr.Logger
+}
+
+// NewClusterCollectorLeadership creates a ClusterCollectorLeadership that signals the leadership with the file at the
+// given path, in the volume shared with the telemetry-proxy.
+func NewClusterCollectorLeadership(leaderFilePath string, log logr.Logger) *ClusterCollectorLeadership {
+	return &ClusterCollectorLeadership{
+		leaderFilePath: leaderFilePath,
+		log:            log,
+	}
+}
+
+// Start creates the leader file, and removes it when the context is done, i.e., when the leadership is released.
+// If the leadership is lost instead, the controller manager exits without removing it: the file is removed with
+// ResignClusterCollectorLeadership when it starts again.
+func (l *ClusterCollectorLeadership) Start(ctx context.Context) error {
+	if err := os.WriteFile(l.leaderFilePath, []byte{}, 0644); err != nil {
+		return fmt.Errorf("cannot create the cluster collector leader file '%s': %w", l.leaderFilePath, err)
+	}
+	l.log.Info("Elected to collect the cluster-wide telemetry", "leaderFile", l.leaderFilePath)
+
+	<-ctx.Done()
+
+	if err := ResignClusterCollectorLeadership(l.leaderFilePath); err != nil {
+		return err
+	}
+	l.log.Info("Stopped collecting the cluster-wide telemetry", "leaderFile", l.leaderFilePath)
+
+	return nil
+}
+
+// NeedLeaderElection returns true, as only the telemetry-proxy of the leader collects the cluster-wide telemetry.
+func (l *ClusterCollectorLeadership) NeedLeaderElection() bool {
+	return true
+}
+
+// ResignClusterCollectorLeadership removes the leader file, if any, e.g., the one left behind by the previous run of
+// the controller manager, whose volumes outlive its restarts.
+func ResignClusterCollectorLeadership(leaderFilePath string) error {
+	if err := os.Remove(leaderFilePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove the cluster collector leader file '%s': %w", leaderFilePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryproxyconfigs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Context("Cluster collector leadership", func() {
+
+	var leaderFilePath string
+
+	BeforeEach(func() {
+		leaderFilePath = filepath.Join(GinkgoT().TempDir(), "cluster_collector_leader")
+	})
+
+	It("needs leader election", func() {
+		Expect(NewClusterCollectorLeadership(leaderFilePath, logger).NeedLeaderElection()).To(BeTrue())
+	})
+
+	It("creates the leader file while running, and removes it once stopped", func() {
+		leadership := NewClusterCollectorLeadership(leaderFilePath, logger)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error)
+		go func() {
+			stopped <- leadership.Start(ctx)
+		}()
+
+		Eventually(leaderFilePath).Should(BeAnExistingFile())
+
+		cancel()
+		Eventually(stopped).Should(Receive(BeNil()))
+		Expect(leaderFilePath).NotTo(BeAnExistingFile())
+	})
+
+	It("removes the leader file left behind by a previous run", func() {
+		Expect(os.WriteFile(leaderFilePath, []byte{}, 0644)).To(Succeed())
+
+		Expect(ResignClusterCollectorLeadership(leaderFilePath)).To(Succeed())
+		Expect(leaderFilePath).NotTo(BeAnExistingFile())
+
+		// Resigning without the leader file is a no-op
+		Expect(ResignClusterCollectorLeadership(leaderFilePath)).To(Succeed())
+	})
+})
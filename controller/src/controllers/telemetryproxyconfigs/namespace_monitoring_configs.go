@@ -9,12 +9,189 @@ import (
 	"sort"
 
 	"github.com/go-logr/logr"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/redaction"
 )
 
 type NamespaceMonitoringConfig struct {
 	Token string `json:"token"`
 	Name  string `json:"name"`
 	Uid   string `json:"uid"`
+	// Whether the collection of Kubernetes objects and events is disabled for the namespace,
+	// e.g., because it is monitored only to scrape Prometheus metrics
+	KubeEventsDisabled bool `json:"kubeEventsDisabled,omitempty"`
+	// Whether the spans, the application logs and the metrics of the namespace are not sent to Lumigo
+	TracesDisabled  bool `json:"tracesDisabled,omitempty"`
+	LogsDisabled    bool `json:"logsDisabled,omitempty"`
+	MetricsDisabled bool `json:"metricsDisabled,omitempty"`
+	// Whether the events of the nodes of the cluster, e.g., scale-ups, scale-downs and spot
+	// interruptions, are sent to Lumigo with the telemetry of the namespace
+	NodeLifecycle bool                    `json:"nodeLifecycle,omitempty"`
+	Prometheus    *PrometheusScrapeConfig `json:"prometheus,omitempty"`
+	SpanMetrics   *SpanMetricsConfig      `json:"spanMetrics,omitempty"`
+	// The maximum amount of spans per second accepted for the namespace; zero means no limit
+	MaxSpansPerSecond int32        `json:"maxSpansPerSecond,omitempty"`
+	Quota             *QuotaConfig `json:"quota,omitempty"`
+	// Whether the telemetry-proxy logs the telemetry of the namespace, for troubleshooting
+	Debug bool `json:"debug,omitempty"`
+	// Additional OTLP backends to which the traces of the namespace are sent
+	AdditionalExporters []OtlpExporterConfig `json:"additionalExporters,omitempty"`
+	Archival            *ArchivalConfig      `json:"archival,omitempty"`
+	// The endpoints to which the telemetry of the namespace is sent instead of the Lumigo endpoints, by signal
+	Endpoints *EndpointsConfig `json:"endpoints,omitempty"`
+	// Whether the trace IDs of the spans of the namespace are added in the AWS X-Ray format as attributes
+	AwsXRay bool `json:"awsXRay,omitempty"`
+	// The unanchored regex of the hosts whose HTTP calls are dropped from the traces of the namespace
+	SkipHostsRegex string `json:"skipHostsRegex,omitempty"`
+	// The resource attributes added to the telemetry of the namespace
+	Tags map[string]string `json:"tags,omitempty"`
+	// The HTTP headers sent with the telemetry of the namespace to Lumigo, which are merged as well into the
+	// headers of the Endpoints; their values are secrets, see Redacted
+	OtlpHeaders map[string]string `json:"otlpHeaders,omitempty"`
+}
+
+// RedactedValue replaces the values of the headers in the logs and in the status of the Lumigo instances
+const RedactedValue = "***"
+
+// Redacted returns a copy of the configuration, to be logged, in which the Lumigo token and the values of the HTTP
+// headers, which carry credentials, are replaced with redaction.RedactedToken and RedactedValue respectively
+func (c *NamespaceMonitoringConfig) Redacted() NamespaceMonitoringConfig {
+	redacted := *c
+	redacted.Token = redaction.String(c.Token)
+	redacted.OtlpHeaders = RedactHeaders(c.OtlpHeaders)
+
+	if c.AdditionalExporters != nil {
+		redacted.AdditionalExporters = make([]OtlpExporterConfig, len(c.AdditionalExporters))
+		for i, additionalExporter := range c.AdditionalExporters {
+			additionalExporter.Headers = RedactHeaders(additionalExporter.Headers)
+			redacted.AdditionalExporters[i] = additionalExporter
+		}
+	}
+
+	if c.Endpoints != nil {
+		redactEndpoint := func(endpoint *OtlpEndpointConfig) *OtlpEndpointConfig {
+			if endpoint == nil {
+				return nil
+			}
+			return &OtlpEndpointConfig{
+				Endpoint: endpoint.Endpoint,
+				Headers:  RedactHeaders(endpoint.Headers),
+			}
+		}
+		redacted.Endpoints = &EndpointsConfig{
+			Traces:  redactEndpoint(c.Endpoints.Traces),
+			Logs:    redactEndpoint(c.Endpoints.Logs),
+			Metrics: redactEndpoint(c.Endpoints.Metrics),
+		}
+	}
+
+	return redacted
+}
+
+// RedactHeaders returns the names of the headers with RedactedValue as value; nil if there are no headers
+func RedactHeaders(headers map[string]string) map[string]string {
+	if len(headers) < 1 {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(headers))
+	for name := range headers {
+		redacted[name] = RedactedValue
+	}
+
+	return redacted
+}
+
+// ArchivalConfig specifies the S3 bucket in which the raw telemetry of the namespace is archived
+type ArchivalConfig struct {
+	Bucket    string `json:"bucket"`
+	Region    string `json:"region"`
+	Prefix    string `json:"prefix"`
+	Partition string `json:"partition"`
+	Endpoint  string `json:"endpoint,omitempty"`
+}
+
+// QuotaConfig specifies how much telemetry of the namespace the telemetry-proxy accepts per day
+type QuotaConfig struct {
+	// Zero means no limit
+	SpansPerDay int64 `json:"spansPerDay,omitempty"`
+	// Zero means no limit
+	BytesPerDay        int64 `json:"bytesPerDay,omitempty"`
+	SamplingPercentage int32 `json:"samplingPercentage,omitempty"`
+}
+
+type OtlpExporterConfig struct {
+	Name     string            `json:"name"`
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// EndpointsConfig specifies the OTLP endpoints to which the telemetry-proxy sends each signal of the namespace;
+// the signals without one are sent to the Lumigo endpoints
+type EndpointsConfig struct {
+	Traces  *OtlpEndpointConfig `json:"traces,omitempty"`
+	Logs    *OtlpEndpointConfig `json:"logs,omitempty"`
+	Metrics *OtlpEndpointConfig `json:"metrics,omitempty"`
+}
+
+type OtlpEndpointConfig struct {
+	// Empty for the Lumigo endpoint of the signal, e.g., to send the OtlpHeaders of a namespace without endpoints
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// MergeOtlpHeaders returns the endpoints by signal with the OTLP headers merged into their headers, which take
+// precedence; the signals without endpoint get one without URL, i.e., the Lumigo endpoint of the signal, so that the
+// telemetry-proxy sends all the telemetry of the namespace with the OTLP headers
+func MergeOtlpHeaders(endpoints *EndpointsConfig, otlpHeaders map[string]string) *EndpointsConfig {
+	if len(otlpHeaders) < 1 {
+		return endpoints
+	}
+
+	if endpoints == nil {
+		endpoints = &EndpointsConfig{}
+	}
+
+	mergeHeaders := func(endpoint *OtlpEndpointConfig) *OtlpEndpointConfig {
+		merged := &OtlpEndpointConfig{
+			Headers: make(map[string]string, len(otlpHeaders)),
+		}
+		for name, value := range otlpHeaders {
+			merged.Headers[name] = value
+		}
+
+		if endpoint != nil {
+			merged.Endpoint = endpoint.Endpoint
+			for name, value := range endpoint.Headers {
+				merged.Headers[name] = value
+			}
+		}
+
+		return merged
+	}
+
+	return &EndpointsConfig{
+		Traces:  mergeHeaders(endpoints.Traces),
+		Logs:    mergeHeaders(endpoints.Logs),
+		Metrics: mergeHeaders(endpoints.Metrics),
+	}
+}
+
+type SpanMetricsConfig struct {
+	// Not omitted when empty, so that the telemetry-proxy templates see a non-empty object
+	Dimensions []string `json:"dimensions"`
+}
+
+type PrometheusScrapeConfig struct {
+	ScrapeAnnotatedPods bool                     `json:"scrapeAnnotatedPods,omitempty"`
+	ScrapeTargets       []PrometheusScrapeTarget `json:"scrapeTargets,omitempty"`
+}
+
+type PrometheusScrapeTarget struct {
+	JobName        string   `json:"jobName"`
+	Targets        []string `json:"targets"`
+	MetricsPath    string   `json:"metricsPath"`
+	ScrapeInterval string   `json:"scrapeInterval"`
 }
 
 func RemoveTelemetryProxyMonitoringOfNamespace(ctx context.Context, telemetryProxyNamespaceConfigurationsPath string, namespaceName string, log *logr.Logger) (bool, error) {
@@ -31,6 +208,12 @@ func UpsertTelemetryProxyMonitoringOfNamespace(ctx context.Context, telemetryPro
 	}, log)
 }
 
+// UpsertTelemetryProxyMonitoringConfigOfNamespace is like UpsertTelemetryProxyMonitoringOfNamespace, but
+// also sets which telemetry, like Kubernetes events and Prometheus metrics, is collected for the namespace
+func UpsertTelemetryProxyMonitoringConfigOfNamespace(ctx context.Context, telemetryProxyNamespaceConfigurationsPath string, namespaceMonitoringConfig *NamespaceMonitoringConfig, log *logr.Logger) (bool, error) {
+	return updateTelemetryProxyMonitoringOfNamespace(ctx, telemetryProxyNamespaceConfigurationsPath, namespaceMonitoringConfig, log)
+}
+
 func updateTelemetryProxyMonitoringOfNamespace(ctx context.Context, telemetryProxyNamespaceConfigurationsPath string, namespaceMonitoringConfig *NamespaceMonitoringConfig, log *logr.Logger) (bool, error) {
 	upsert := len(namespaceMonitoringConfig.Uid) > 0
 
@@ -76,7 +259,11 @@ func updateTelemetryProxyMonitoringOfNamespace(ctx context.Context, telemetryPro
 		return false, fmt.Errorf("cannot write the updated namespace configuration file '%s': %w", telemetryProxyNamespaceConfigurationsPath, err)
 	}
 
-	log.Info("Updated namespace monitoring configurations", "new_configurations", newNamespaces)
+	redactedNamespaces := make([]NamespaceMonitoringConfig, len(newNamespaces))
+	for i := range newNamespaces {
+		redactedNamespaces[i] = newNamespaces[i].Redacted()
+	}
+	log.Info("Updated namespace monitoring configurations", "new_configurations", redactedNamespaces)
 
 	return true, nil
 }
@@ -96,6 +96,43 @@ var _ = Context("Lumigo controller", func() {
 		Expect(parseJsonFile(file)).To(ContainElement(*testConfig))
 	})
 
+	It("Adds a namespace with Prometheus scraping correctly", func() {
+		file := createEmptyNamespaceFile()
+
+		testConfig := &NamespaceMonitoringConfig{
+			Name:               "ns-test",
+			Uid:                "123456",
+			Token:              "t_123456",
+			KubeEventsDisabled: true,
+			Prometheus: &PrometheusScrapeConfig{
+				ScrapeAnnotatedPods: true,
+				ScrapeTargets: []PrometheusScrapeTarget{
+					{
+						JobName:        "my-service",
+						Targets:        []string{"my-service:9090"},
+						MetricsPath:    "/metrics",
+						ScrapeInterval: "1m",
+					},
+				},
+			},
+		}
+
+		modified, err := UpsertTelemetryProxyMonitoringConfigOfNamespace(context.TODO(), file, testConfig, &logger)
+		Expect(modified).To(BeTrue())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(parseJsonFile(file)).To(ContainElement(*testConfig))
+
+		// Disabling Prometheus scraping updates the namespace configuration
+		testConfig.Prometheus = nil
+		modified, err = UpsertTelemetryProxyMonitoringConfigOfNamespace(context.TODO(), file, testConfig, &logger)
+		Expect(modified).To(BeTrue())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(parseJsonFile(file)).To(HaveLen(1))
+		Expect(parseJsonFile(file)).To(ContainElement(*testConfig))
+	})
+
 	It("Upserts and removes a namespace correctly", func() {
 		file := createEmptyNamespaceFile()
 
@@ -149,4 +186,55 @@ var _ = Context("Lumigo controller", func() {
 		Expect(parseJsonFile(file)).NotTo(ContainElement(*testConfig))
 	})
 
+	It("Redacts the values of the headers", func() {
+		testConfig := &NamespaceMonitoringConfig{
+			Name:  "ns-test",
+			Uid:   "123456",
+			Token: "t_123456",
+			AdditionalExporters: []OtlpExporterConfig{
+				{Name: "my-backend", Endpoint: "https://my-backend:4318", Headers: map[string]string{"X-Api-Key": "my-key"}},
+			},
+			Endpoints: &EndpointsConfig{
+				Traces: &OtlpEndpointConfig{Endpoint: "https://my-gateway:4318", Headers: map[string]string{"X-Gateway-Auth": "my-auth"}},
+			},
+			OtlpHeaders: map[string]string{"X-Scope-OrgID": "my-tenant"},
+		}
+
+		redacted := testConfig.Redacted()
+		Expect(redacted.Token).To(Equal("t_123456"))
+		Expect(redacted.OtlpHeaders).To(Equal(map[string]string{"X-Scope-OrgID": RedactedValue}))
+		Expect(redacted.AdditionalExporters[0].Headers).To(Equal(map[string]string{"X-Api-Key": RedactedValue}))
+		Expect(redacted.Endpoints.Traces).To(Equal(&OtlpEndpointConfig{Endpoint: "https://my-gateway:4318", Headers: map[string]string{"X-Gateway-Auth": RedactedValue}}))
+		Expect(redacted.Endpoints.Logs).To(BeNil())
+
+		// The configuration written for the telemetry-proxy keeps the values
+		Expect(testConfig.OtlpHeaders).To(Equal(map[string]string{"X-Scope-OrgID": "my-tenant"}))
+		Expect(testConfig.AdditionalExporters[0].Headers).To(Equal(map[string]string{"X-Api-Key": "my-key"}))
+		Expect(testConfig.Endpoints.Traces.Headers).To(Equal(map[string]string{"X-Gateway-Auth": "my-auth"}))
+
+		Expect(RedactHeaders(nil)).To(BeNil())
+
+		testConfig.Token = "t_0123456789abcdef01234"
+		Expect(testConfig.Redacted().Token).To(Equal("t_***"))
+	})
+
+	It("Merges the OTLP headers into the endpoints", func() {
+		Expect(MergeOtlpHeaders(nil, nil)).To(BeNil())
+
+		endpoints := &EndpointsConfig{
+			Traces: &OtlpEndpointConfig{Endpoint: "https://my-gateway:4318", Headers: map[string]string{"X-Scope-OrgID": "my-other-tenant"}},
+		}
+		Expect(MergeOtlpHeaders(endpoints, nil)).To(BeIdenticalTo(endpoints))
+
+		otlpHeaders := map[string]string{"X-Scope-OrgID": "my-tenant", "X-Gateway-Auth": "my-auth"}
+		Expect(MergeOtlpHeaders(endpoints, otlpHeaders)).To(Equal(&EndpointsConfig{
+			// The headers of the endpoint take precedence
+			Traces: &OtlpEndpointConfig{Endpoint: "https://my-gateway:4318", Headers: map[string]string{"X-Scope-OrgID": "my-other-tenant", "X-Gateway-Auth": "my-auth"}},
+			// The signals without endpoint are sent to the Lumigo endpoints
+			Logs:    &OtlpEndpointConfig{Headers: otlpHeaders},
+			Metrics: &OtlpEndpointConfig{Headers: otlpHeaders},
+		}))
+		Expect(endpoints.Traces.Headers).To(Equal(map[string]string{"X-Scope-OrgID": "my-other-tenant"}))
+	})
+
 })
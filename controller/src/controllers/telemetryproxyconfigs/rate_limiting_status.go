@@ -0,0 +1,53 @@
+package telemetryproxyconfigs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The telemetry-proxy writes the rate-limiting status next to the namespace configurations
+const rateLimitingStatusFileName = "rate_limiting_status.json"
+
+// NamespaceRateLimitingStatus mirrors what the `ratelimiter` processor of the telemetry-proxy
+// reports about the spans it dropped for one namespace
+type NamespaceRateLimitingStatus struct {
+	DroppedSpans  int64                 `json:"droppedSpans"`
+	LastDroppedAt time.Time             `json:"lastDroppedAt"`
+	Quota         *NamespaceQuotaStatus `json:"quota,omitempty"`
+}
+
+// NamespaceQuotaStatus mirrors what the `ratelimiter` processor of the telemetry-proxy
+// reports about the usage of the daily quota of one namespace
+type NamespaceQuotaStatus struct {
+	// The day, in UTC and in the `2006-01-02` format, the usage refers to
+	Day           string     `json:"day"`
+	AcceptedSpans int64      `json:"acceptedSpans"`
+	AcceptedBytes int64      `json:"acceptedBytes"`
+	DroppedSpans  int64      `json:"droppedSpans"`
+	ExceededAt    *time.Time `json:"exceededAt,omitempty"`
+}
+
+// GetRateLimitingStatusOfNamespace returns the rate-limiting status of the namespace, or nil
+// if the telemetry-proxy has never dropped spans for it
+func GetRateLimitingStatusOfNamespace(telemetryProxyNamespaceConfigurationsPath string, namespaceName string) (*NamespaceRateLimitingStatus, error) {
+	statusFilePath := filepath.Join(filepath.Dir(telemetryProxyNamespaceConfigurationsPath), rateLimitingStatusFileName)
+
+	statusFileBytes, err := os.ReadFile(statusFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("cannot read rate-limiting status file '%s': %w", statusFilePath, err)
+	}
+
+	var statuses map[string]*NamespaceRateLimitingStatus
+	if err := json.Unmarshal(statusFileBytes, &statuses); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal rate-limiting status file '%s': %w", statusFilePath, err)
+	}
+
+	return statuses[namespaceName], nil
+}
@@ -0,0 +1,346 @@
+package telemetryproxydaemonset
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	DaemonSetName                    = "lumigo-telemetry-proxy"
+	namespacesSecretKey              = "namespaces_to_monitor.json"
+	namespacesMountPath              = "/lumigo/etc/namespaces/"
+	namespacesVolumeName             = "namespace-configurations"
+	otelcolConfigMountPath           = "/lumigo/etc/otelcol/"
+	otelcolConfigVolumeName          = "telemetry-proxy-configurations"
+	templateChecksumAnnotation       = "lumigo.io/template-checksum"
+	kubernetesAppNameLabelKey        = "app.kubernetes.io/name"
+	kubernetesAppComponentLabelKey   = "app.kubernetes.io/component"
+	kubernetesAppComponentLabelValue = "telemetry-proxy"
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+	telemetryProxyModeEnvVar         = "LUMIGO_TELEMETRY_PROXY_MODE"
+	telemetryProxyModeNode           = "node"
+	otlpPortName                     = "otlphttp"
+
+	// The port, on the IP of each node, at which the telemetry-proxy pod on that node
+	// receives OTLP data from the instrumented workloads
+	HostPort = 4318
+)
+
+// DaemonSetConfig contains the settings of the operator that apply to the
+// telemetry-proxy DaemonSet
+type DaemonSetConfig struct {
+	// The namespace the operator runs in, in which the telemetry-proxy DaemonSet is deployed
+	Namespace string
+	// The service account of the operator, which the telemetry-proxy uses to enrich telemetry with Kubernetes metadata
+	ServiceAccountName string
+	// The image of the telemetry-proxy
+	Image string
+	// The environment variables of the telemetry-proxy, e.g., the Lumigo endpoints and the cluster name
+	Env []corev1.EnvVar
+	// The resources of the telemetry-proxy container; optional
+	Resources corev1.ResourceRequirements
+}
+
+// SyncTelemetryProxyDaemonSet makes the telemetry-proxy DaemonSet monitor the same namespaces as the
+// telemetry-proxy running next to the controller, whose configurations are in the given file. When no
+// namespace is monitored, there are no workloads to send telemetry to the DaemonSet, which is removed.
+func SyncTelemetryProxyDaemonSet(ctx context.Context, c client.Client, daemonSetConfig *DaemonSetConfig, namespaceConfigurationsPath string, log *logr.Logger) (bool, error) {
+	namespacesBytes, err := os.ReadFile(namespaceConfigurationsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("cannot read the namespace configurations file '%s': %w", namespaceConfigurationsPath, err)
+	}
+
+	var namespaces []json.RawMessage
+	if len(namespacesBytes) > 0 {
+		if err := json.Unmarshal(namespacesBytes, &namespaces); err != nil {
+			return false, fmt.Errorf("cannot unmarshal the namespace configurations file '%s': %w", namespaceConfigurationsPath, err)
+		}
+	}
+
+	if len(namespaces) == 0 {
+		return removeTelemetryProxyDaemonSet(ctx, c, daemonSetConfig, log)
+	}
+
+	secret := &corev1.Secret{}
+	secretExists := true
+	if err := c.Get(ctx, types.NamespacedName{Namespace: daemonSetConfig.Namespace, Name: DaemonSetName}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the telemetry-proxy DaemonSet configuration secret: %w", err)
+		}
+		secretExists = false
+	}
+
+	isChanged := false
+	if !bytes.Equal(secret.Data[namespacesSecretKey], namespacesBytes) {
+		secret.ObjectMeta.Namespace = daemonSetConfig.Namespace
+		secret.ObjectMeta.Name = DaemonSetName
+		secret.ObjectMeta.Labels = telemetryProxyLabels()
+		// The namespace configurations contain the Lumigo tokens, hence the secret
+		secret.Data = map[string][]byte{
+			namespacesSecretKey: namespacesBytes,
+		}
+
+		if secretExists {
+			err = c.Update(ctx, secret)
+		} else {
+			err = c.Create(ctx, secret)
+		}
+		if err != nil {
+			return false, fmt.Errorf("cannot write the telemetry-proxy DaemonSet configuration secret: %w", err)
+		}
+
+		isChanged = true
+		log.Info("Updated the telemetry-proxy DaemonSet namespace configurations")
+	}
+
+	isDaemonSetChanged, err := upsertTelemetryProxyDaemonSet(ctx, c, daemonSetConfig, log)
+	if err != nil {
+		return isChanged, err
+	}
+
+	return isChanged || isDaemonSetChanged, nil
+}
+
+func upsertTelemetryProxyDaemonSet(ctx context.Context, c client.Client, daemonSetConfig *DaemonSetConfig, log *logr.Logger) (bool, error) {
+	desiredDaemonSet, err := newTelemetryProxyDaemonSet(daemonSetConfig)
+	if err != nil {
+		return false, err
+	}
+
+	daemonSet := &appsv1.DaemonSet{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: daemonSetConfig.Namespace, Name: DaemonSetName}, daemonSet); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the telemetry-proxy DaemonSet: %w", err)
+		}
+
+		if err := c.Create(ctx, desiredDaemonSet); err != nil {
+			return false, fmt.Errorf("cannot create the telemetry-proxy DaemonSet: %w", err)
+		}
+
+		log.Info("Created the telemetry-proxy DaemonSet", "namespace", daemonSetConfig.Namespace, "name", DaemonSetName)
+		return true, nil
+	}
+
+	// Unlike the Go instrumentation agent, the telemetry-proxy reloads its namespace configurations
+	// when the secret changes, so the pods are rolled out only when the settings of the operator change
+	if daemonSet.Spec.Template.Annotations[templateChecksumAnnotation] == desiredDaemonSet.Spec.Template.Annotations[templateChecksumAnnotation] {
+		return false, nil
+	}
+
+	daemonSet.ObjectMeta.Labels = desiredDaemonSet.ObjectMeta.Labels
+	daemonSet.Spec.Template = desiredDaemonSet.Spec.Template
+	if err := c.Update(ctx, daemonSet); err != nil {
+		return false, fmt.Errorf("cannot update the telemetry-proxy DaemonSet: %w", err)
+	}
+
+	log.Info("Updated the telemetry-proxy DaemonSet", "namespace", daemonSetConfig.Namespace, "name", DaemonSetName)
+	return true, nil
+}
+
+func removeTelemetryProxyDaemonSet(ctx context.Context, c client.Client, daemonSetConfig *DaemonSetConfig, log *logr.Logger) (bool, error) {
+	isChanged := false
+
+	if err := c.Delete(ctx, &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: daemonSetConfig.Namespace,
+			Name:      DaemonSetName,
+		},
+	}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot delete the telemetry-proxy DaemonSet: %w", err)
+		}
+	} else {
+		isChanged = true
+		log.Info("Deleted the telemetry-proxy DaemonSet, as no namespace is left to monitor", "namespace", daemonSetConfig.Namespace, "name", DaemonSetName)
+	}
+
+	if err := c.Delete(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: daemonSetConfig.Namespace,
+			Name:      DaemonSetName,
+		},
+	}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return isChanged, fmt.Errorf("cannot delete the telemetry-proxy DaemonSet configuration secret: %w", err)
+		}
+	} else {
+		isChanged = true
+	}
+
+	return isChanged, nil
+}
+
+func newTelemetryProxyDaemonSet(daemonSetConfig *DaemonSetConfig) (*appsv1.DaemonSet, error) {
+	allowPrivilegeEscalation := false
+	runAsNonRoot := true
+	var runAsUser int64 = 1234
+	automountServiceAccountToken := true
+	labels := telemetryProxyLabels()
+
+	env := []corev1.EnvVar{
+		{
+			// Makes the telemetry-proxy leave the collection of cluster-wide telemetry, like
+			// Kubernetes events, to the telemetry-proxy running next to the controller
+			Name:  telemetryProxyModeEnvVar,
+			Value: telemetryProxyModeNode,
+		},
+		{
+			Name: "LUMIGO_OPERATOR_NODE_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "spec.nodeName",
+				},
+			},
+		},
+	}
+	env = append(env, daemonSetConfig.Env...)
+
+	podSpec := corev1.PodSpec{
+		ServiceAccountName:           daemonSetConfig.ServiceAccountName,
+		AutomountServiceAccountToken: &automountServiceAccountToken,
+		Affinity: &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{
+									Key:      "kubernetes.io/os",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"linux"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Tolerations: []corev1.Toleration{
+			{
+				// Instrumented workloads send telemetry to the telemetry-proxy on their own node
+				Operator: corev1.TolerationOpExists,
+			},
+		},
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot: &runAsNonRoot,
+			FSGroup:      &runAsUser,
+		},
+		Containers: []corev1.Container{
+			{
+				Name:      "telemetry-proxy",
+				Image:     daemonSetConfig.Image,
+				Env:       env,
+				Resources: daemonSetConfig.Resources,
+				Ports: []corev1.ContainerPort{
+					{
+						Name:          otlpPortName,
+						ContainerPort: HostPort,
+						HostPort:      HostPort,
+						Protocol:      corev1.ProtocolTCP,
+					},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+					Capabilities: &corev1.Capabilities{
+						Drop: []corev1.Capability{"ALL"},
+					},
+					RunAsNonRoot: &runAsNonRoot,
+					RunAsUser:    &runAsUser,
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{
+						Name:      otelcolConfigVolumeName,
+						MountPath: otelcolConfigMountPath,
+					},
+					{
+						Name:      namespacesVolumeName,
+						MountPath: namespacesMountPath,
+						ReadOnly:  true,
+					},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{
+			{
+				Name: otelcolConfigVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+			{
+				Name: namespacesVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: DaemonSetName,
+					},
+				},
+			},
+		},
+	}
+
+	// The checksum annotation rolls out the telemetry-proxy pods when the settings of the operator change
+	podSpecBytes, err := json.Marshal(podSpec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the telemetry-proxy DaemonSet pod spec: %w", err)
+	}
+	checksum := sha256.Sum256(podSpecBytes)
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: daemonSetConfig.Namespace,
+			Name:      DaemonSetName,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: PodSelectorLabels(),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						templateChecksumAnnotation: hex.EncodeToString(checksum[:]),
+					},
+				},
+				Spec: podSpec,
+			},
+		},
+	}, nil
+}
+
+// PodSelectorLabels returns the labels that select the pods of the telemetry-proxy DaemonSet
+func PodSelectorLabels() map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      DaemonSetName,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+	}
+}
+
+func telemetryProxyLabels() map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      DaemonSetName,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+		kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+		// We do not need the operator to inject the telemetry-proxy
+		mutation.LumigoAutoTraceLabelKey: "false",
+	}
+}
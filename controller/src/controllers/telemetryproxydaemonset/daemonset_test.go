@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryproxydaemonset
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const operatorNamespace = "lumigo-system"
+
+var (
+	tempDir string
+	logger  logr.Logger
+)
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	tempDir = t.TempDir()
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Telemetry Proxy DaemonSet Suite")
+}
+
+func writeNamespacesFile(content string) string {
+	namespacesFile := filepath.Join(tempDir, "namespaces_to_monitor.json")
+	Expect(os.WriteFile(namespacesFile, []byte(content), 0644)).To(Succeed())
+	return namespacesFile
+}
+
+func getDaemonSet(c client.Client) (*appsv1.DaemonSet, error) {
+	daemonSet := &appsv1.DaemonSet{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: DaemonSetName}, daemonSet)
+	return daemonSet, err
+}
+
+func getSecret(c client.Client) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: DaemonSetName}, secret)
+	return secret, err
+}
+
+var _ = Context("Telemetry-proxy DaemonSet", func() {
+
+	var c client.Client
+	var daemonSetConfig *DaemonSetConfig
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+		daemonSetConfig = &DaemonSetConfig{
+			Namespace:          operatorNamespace,
+			ServiceAccountName: "lumigo-kubernetes-operator",
+			Image:              "public.ecr.aws/lumigo/lumigo-kubernetes-telemetry-proxy:latest",
+			Env: []corev1.EnvVar{
+				{
+					Name:  "LUMIGO_ENDPOINT",
+					Value: "https://ga-otlp.lumigo-tracer-edge.golumigo.com",
+				},
+			},
+		}
+	})
+
+	It("is deployed with the namespace configurations when namespaces are monitored", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-test","uid":"123456","token":"t_123456"}]`)
+
+		isChanged, err := SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getSecret(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(secret.Data[namespacesSecretKey])).To(Equal(`[{"name":"ns-test","uid":"123456","token":"t_123456"}]`))
+
+		daemonSet, err := getDaemonSet(c)
+		Expect(err).NotTo(HaveOccurred())
+
+		container := daemonSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(daemonSetConfig.Image))
+		Expect(container.Ports).To(ContainElement(corev1.ContainerPort{
+			Name:          otlpPortName,
+			ContainerPort: HostPort,
+			HostPort:      HostPort,
+			Protocol:      corev1.ProtocolTCP,
+		}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{
+			Name:  telemetryProxyModeEnvVar,
+			Value: telemetryProxyModeNode,
+		}))
+		Expect(container.Env).To(ContainElement(daemonSetConfig.Env[0]))
+
+		// Syncing again without changes is idempotent
+		isChanged, err = SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+	})
+
+	It("does not roll out the pods when only the namespace configurations change", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-test","uid":"123456","token":"t_123456"}]`)
+
+		_, err := SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		daemonSet, err := getDaemonSet(c)
+		Expect(err).NotTo(HaveOccurred())
+		checksum := daemonSet.Spec.Template.Annotations[templateChecksumAnnotation]
+
+		writeNamespacesFile(`[{"name":"ns-other","uid":"654321","token":"t_654321"},{"name":"ns-test","uid":"123456","token":"t_123456"}]`)
+		isChanged, err := SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		daemonSet, err = getDaemonSet(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(daemonSet.Spec.Template.Annotations[templateChecksumAnnotation]).To(Equal(checksum))
+
+		// Changing the image rolls out the pods
+		daemonSetConfig.Image = "public.ecr.aws/lumigo/lumigo-kubernetes-telemetry-proxy:other"
+		isChanged, err = SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		daemonSet, err = getDaemonSet(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(daemonSet.Spec.Template.Annotations[templateChecksumAnnotation]).NotTo(Equal(checksum))
+		Expect(daemonSet.Spec.Template.Spec.Containers[0].Image).To(Equal(daemonSetConfig.Image))
+	})
+
+	It("is removed when no namespace is left to monitor", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-test","uid":"123456","token":"t_123456"}]`)
+
+		_, err := SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		writeNamespacesFile(`[]`)
+		isChanged, err := SyncTelemetryProxyDaemonSet(context.TODO(), c, daemonSetConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getDaemonSet(c)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		_, err = getSecret(c)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+})
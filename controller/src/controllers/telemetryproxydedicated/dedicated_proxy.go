@@ -0,0 +1,476 @@
+package telemetryproxydedicated
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/optimisticupdate"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyconfigs"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	// The name of the ServiceAccount, Secret, Deployment and Service of the dedicated telemetry-proxy in a namespace
+	Name = "lumigo-dedicated-telemetry-proxy"
+	// The port at which the dedicated telemetry-proxy receives OTLP data from the instrumented workloads of its namespace
+	Port = 4318
+
+	namespacesSecretKey              = "namespaces_to_monitor.json"
+	namespacesMountPath              = "/lumigo/etc/namespaces/"
+	namespacesVolumeName             = "namespace-configurations"
+	otelcolConfigMountPath           = "/lumigo/etc/otelcol/"
+	otelcolConfigVolumeName          = "telemetry-proxy-configurations"
+	templateChecksumAnnotation       = "lumigo.io/template-checksum"
+	kubernetesAppNameLabelKey        = "app.kubernetes.io/name"
+	kubernetesAppNameLabelValue      = "lumigo-dedicated-telemetry-proxy"
+	kubernetesAppComponentLabelKey   = "app.kubernetes.io/component"
+	kubernetesAppComponentLabelValue = "telemetry-proxy"
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+	telemetryProxyModeEnvVar         = "LUMIGO_TELEMETRY_PROXY_MODE"
+	telemetryProxyModeNode           = "node"
+	otlpPortName                     = "otlphttp"
+)
+
+// DedicatedProxyConfig contains the settings of the operator that apply to the dedicated telemetry-proxies,
+// which the operator deploys in the namespaces whose Lumigo instance requests one
+type DedicatedProxyConfig struct {
+	// The image of the telemetry-proxy
+	Image string
+	// The environment variables of the telemetry-proxy, e.g., the Lumigo endpoints and the cluster name
+	Env []corev1.EnvVar
+	// The resources of the telemetry-proxy container of each dedicated telemetry-proxy; optional
+	Resources corev1.ResourceRequirements
+	// The ClusterRoleBinding, created with the operator, that grants the dedicated telemetry-proxies the read-only
+	// access to the metadata of the cluster they need to enrich the telemetry; the operator adds the ServiceAccount
+	// of each dedicated telemetry-proxy to its subjects
+	ClusterRoleBindingName string
+}
+
+// IsRequested returns whether the Lumigo instance requests a dedicated telemetry-proxy in its namespace
+func IsRequested(lumigo *operatorv1alpha1.Lumigo) bool {
+	dedicatedProxy := lumigo.Spec.Tracing.DedicatedProxy
+	return dedicatedProxy != nil && *dedicatedProxy
+}
+
+// OtlpServiceUrls returns the URLs to which the workloads of the namespace send traces and logs when it has
+// a dedicated telemetry-proxy
+func OtlpServiceUrls(namespace string) (string, string) {
+	serviceUrl := fmt.Sprintf("http://%s.%s.svc:%d", Name, namespace, Port)
+	return serviceUrl + "/v1/traces", serviceUrl + "/v1/logs"
+}
+
+// UpsertDedicatedProxyOfNamespace deploys the dedicated telemetry-proxy of the namespace of the given monitoring
+// configuration, which contains the Lumigo token of the namespace, or updates it if its configuration changed.
+// The dedicated telemetry-proxy receives the traces and logs of the workloads of its namespace, and leaves the
+// collection of cluster-wide telemetry, like Kubernetes events, to the telemetry-proxy next to the controller.
+func UpsertDedicatedProxyOfNamespace(ctx context.Context, c client.Client, config *DedicatedProxyConfig, namespaceMonitoringConfig *telemetryproxyconfigs.NamespaceMonitoringConfig, log *logr.Logger) (bool, error) {
+	namespace := namespaceMonitoringConfig.Name
+
+	isChanged, err := upsertServiceAccount(ctx, c, namespace, log)
+	if err != nil {
+		return isChanged, err
+	}
+
+	isBindingChanged, err := updateClusterRoleBindingSubject(ctx, c, config.ClusterRoleBindingName, namespace, true)
+	isChanged = isChanged || isBindingChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	isSecretChanged, err := upsertSecret(ctx, c, namespaceMonitoringConfig, log)
+	isChanged = isChanged || isSecretChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	isDeploymentChanged, err := upsertDeployment(ctx, c, config, namespace, log)
+	isChanged = isChanged || isDeploymentChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	isServiceChanged, err := upsertService(ctx, c, namespace, log)
+	return isChanged || isServiceChanged, err
+}
+
+// RemoveDedicatedProxyOfNamespace removes the dedicated telemetry-proxy of the namespace, if any
+func RemoveDedicatedProxyOfNamespace(ctx context.Context, c client.Client, config *DedicatedProxyConfig, namespace string, log *logr.Logger) (bool, error) {
+	isChanged := false
+
+	objectMeta := metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      Name,
+	}
+	for _, obj := range []client.Object{
+		&appsv1.Deployment{ObjectMeta: objectMeta},
+		&corev1.Service{ObjectMeta: objectMeta},
+		&corev1.Secret{ObjectMeta: objectMeta},
+		&corev1.ServiceAccount{ObjectMeta: objectMeta},
+	} {
+		if err := c.Delete(ctx, obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return isChanged, fmt.Errorf("cannot delete the resources of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+			}
+		} else {
+			isChanged = true
+		}
+	}
+
+	isBindingChanged, err := updateClusterRoleBindingSubject(ctx, c, config.ClusterRoleBindingName, namespace, false)
+	isChanged = isChanged || isBindingChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	if isChanged {
+		log.Info("Deleted the dedicated telemetry-proxy", "namespace", namespace, "name", Name)
+	}
+
+	return isChanged, nil
+}
+
+func upsertServiceAccount(ctx context.Context, c client.Client, namespace string, log *logr.Logger) (bool, error) {
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: Name}, serviceAccount); err == nil {
+		return false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("cannot retrieve the ServiceAccount of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	if err := c.Create(ctx, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      Name,
+			Labels:    labels(),
+		},
+	}); err != nil {
+		return false, fmt.Errorf("cannot create the ServiceAccount of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	log.Info("Created the ServiceAccount of the dedicated telemetry-proxy", "namespace", namespace, "name", Name)
+	return true, nil
+}
+
+// updateClusterRoleBindingSubject adds the ServiceAccount of the dedicated telemetry-proxy of the namespace to the
+// subjects of the ClusterRoleBinding, or removes it. The ClusterRoleBinding is shared by the dedicated telemetry-proxies
+// of all the namespaces, whose reconciliations may update it at the same time, hence the optimistic lock.
+func updateClusterRoleBindingSubject(ctx context.Context, c client.Client, clusterRoleBindingName string, namespace string, isBound bool) (bool, error) {
+	subject := rbacv1.Subject{
+		Kind:      rbacv1.ServiceAccountKind,
+		Namespace: namespace,
+		Name:      Name,
+	}
+
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterRoleBindingName,
+		},
+	}
+	isChanged, err := optimisticupdate.Apply(ctx, c, clusterRoleBinding, func(obj client.Object) (bool, error) {
+		binding := obj.(*rbacv1.ClusterRoleBinding)
+		if containsSubject(binding.Subjects, subject) == isBound {
+			return false, nil
+		}
+
+		if isBound {
+			binding.Subjects = append(binding.Subjects, subject)
+		} else {
+			subjects := []rbacv1.Subject{}
+			for _, s := range binding.Subjects {
+				if s != subject {
+					subjects = append(subjects, s)
+				}
+			}
+			binding.Subjects = subjects
+		}
+		return true, nil
+	}, func(ctx context.Context, original client.Object, mutated client.Object) error {
+		return c.Patch(ctx, mutated, optimisticupdate.NewPatch(original))
+	})
+	if err != nil && !(apierrors.IsNotFound(err) && !isBound) {
+		return false, fmt.Errorf("cannot update the '%s' ClusterRoleBinding of the dedicated telemetry-proxies: %w", clusterRoleBindingName, err)
+	}
+
+	return isChanged, nil
+}
+
+func containsSubject(subjects []rbacv1.Subject, subject rbacv1.Subject) bool {
+	for _, s := range subjects {
+		if s == subject {
+			return true
+		}
+	}
+	return false
+}
+
+func upsertSecret(ctx context.Context, c client.Client, namespaceMonitoringConfig *telemetryproxyconfigs.NamespaceMonitoringConfig, log *logr.Logger) (bool, error) {
+	namespace := namespaceMonitoringConfig.Name
+
+	// The dedicated telemetry-proxy monitors only its own namespace
+	namespacesBytes, err := json.Marshal([]*telemetryproxyconfigs.NamespaceMonitoringConfig{namespaceMonitoringConfig})
+	if err != nil {
+		return false, fmt.Errorf("cannot marshal the namespace configurations of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	secret := &corev1.Secret{}
+	secretExists := true
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: Name}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the configuration secret of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+		}
+		secretExists = false
+	}
+
+	if bytes.Equal(secret.Data[namespacesSecretKey], namespacesBytes) {
+		return false, nil
+	}
+
+	secret.ObjectMeta.Namespace = namespace
+	secret.ObjectMeta.Name = Name
+	secret.ObjectMeta.Labels = labels()
+	// The namespace configuration contains the Lumigo token, hence the secret
+	secret.Data = map[string][]byte{
+		namespacesSecretKey: namespacesBytes,
+	}
+
+	if secretExists {
+		err = c.Update(ctx, secret)
+	} else {
+		err = c.Create(ctx, secret)
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot write the configuration secret of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	log.Info("Updated the namespace configuration of the dedicated telemetry-proxy", "namespace", namespace)
+	return true, nil
+}
+
+func upsertDeployment(ctx context.Context, c client.Client, config *DedicatedProxyConfig, namespace string, log *logr.Logger) (bool, error) {
+	desiredDeployment, err := newDeployment(config, namespace)
+	if err != nil {
+		return false, err
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(desiredDeployment), deployment); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the Deployment of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+		}
+
+		if err := c.Create(ctx, desiredDeployment); err != nil {
+			return false, fmt.Errorf("cannot create the Deployment of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+		}
+
+		log.Info("Created the dedicated telemetry-proxy", "namespace", namespace, "name", Name)
+		return true, nil
+	}
+
+	// The pods reload their namespace configuration when the secret changes, so they are rolled out
+	// only when the settings of the operator change
+	if deployment.Spec.Template.Annotations[templateChecksumAnnotation] == desiredDeployment.Spec.Template.Annotations[templateChecksumAnnotation] {
+		return false, nil
+	}
+
+	deployment.ObjectMeta.Labels = desiredDeployment.ObjectMeta.Labels
+	deployment.Spec.Template = desiredDeployment.Spec.Template
+	if err := c.Update(ctx, deployment); err != nil {
+		return false, fmt.Errorf("cannot update the Deployment of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	log.Info("Updated the dedicated telemetry-proxy", "namespace", namespace, "name", Name)
+	return true, nil
+}
+
+func upsertService(ctx context.Context, c client.Client, namespace string, log *logr.Logger) (bool, error) {
+	service := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: Name}, service); err == nil {
+		// The ports and selector of the service never change
+		return false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("cannot retrieve the Service of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	if err := c.Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      Name,
+			Labels:    labels(),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: PodSelectorLabels(),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       otlpPortName,
+					Port:       Port,
+					TargetPort: intstr.FromString(otlpPortName),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}); err != nil {
+		return false, fmt.Errorf("cannot create the Service of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+
+	log.Info("Created the Service of the dedicated telemetry-proxy", "namespace", namespace, "name", Name)
+	return true, nil
+}
+
+func newDeployment(config *DedicatedProxyConfig, namespace string) (*appsv1.Deployment, error) {
+	allowPrivilegeEscalation := false
+	runAsNonRoot := true
+	var runAsUser int64 = 1234
+	automountServiceAccountToken := true
+
+	env := []corev1.EnvVar{
+		{
+			// Makes the telemetry-proxy leave the collection of cluster-wide telemetry, like
+			// Kubernetes events, to the telemetry-proxy running next to the controller
+			Name:  telemetryProxyModeEnvVar,
+			Value: telemetryProxyModeNode,
+		},
+	}
+	env = append(env, config.Env...)
+
+	podSpec := corev1.PodSpec{
+		ServiceAccountName:           Name,
+		AutomountServiceAccountToken: &automountServiceAccountToken,
+		Affinity: &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{
+									Key:      "kubernetes.io/os",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"linux"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot: &runAsNonRoot,
+			FSGroup:      &runAsUser,
+		},
+		Containers: []corev1.Container{
+			{
+				Name:      "telemetry-proxy",
+				Image:     config.Image,
+				Env:       env,
+				Resources: config.Resources,
+				Ports: []corev1.ContainerPort{
+					{
+						Name:          otlpPortName,
+						ContainerPort: Port,
+						Protocol:      corev1.ProtocolTCP,
+					},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+					Capabilities: &corev1.Capabilities{
+						Drop: []corev1.Capability{"ALL"},
+					},
+					RunAsNonRoot: &runAsNonRoot,
+					RunAsUser:    &runAsUser,
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{
+						Name:      otelcolConfigVolumeName,
+						MountPath: otelcolConfigMountPath,
+					},
+					{
+						Name:      namespacesVolumeName,
+						MountPath: namespacesMountPath,
+						ReadOnly:  true,
+					},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{
+			{
+				Name: otelcolConfigVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+			{
+				Name: namespacesVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: Name,
+					},
+				},
+			},
+		},
+	}
+
+	// The checksum annotation rolls out the telemetry-proxy pods when the settings of the operator change
+	podSpecBytes, err := json.Marshal(podSpec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the pod spec of the dedicated telemetry-proxy in namespace '%s': %w", namespace, err)
+	}
+	checksum := sha256.Sum256(podSpecBytes)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      Name,
+			Labels:    labels(),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: PodSelectorLabels(),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels(),
+					Annotations: map[string]string{
+						templateChecksumAnnotation: hex.EncodeToString(checksum[:]),
+					},
+				},
+				Spec: podSpec,
+			},
+		},
+	}, nil
+}
+
+// PodSelectorLabels returns the labels that select the pods of the dedicated telemetry-proxy of a namespace
+func PodSelectorLabels() map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      kubernetesAppNameLabelValue,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+	}
+}
+
+func labels() map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      kubernetesAppNameLabelValue,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+		kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+		// We do not need the operator to inject the telemetry-proxy
+		mutation.LumigoAutoTraceLabelKey: "false",
+	}
+}
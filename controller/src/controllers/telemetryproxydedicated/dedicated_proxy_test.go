@@ -0,0 +1,188 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryproxydedicated
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyconfigs"
+)
+
+const clusterRoleBindingName = "lumigo-lumigo-operator-dedicated-telemetry-proxy"
+
+var logger logr.Logger
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Dedicated Telemetry Proxy Suite")
+}
+
+func getClusterRoleBinding(c client.Client) *rbacv1.ClusterRoleBinding {
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{}
+	Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterRoleBindingName}, clusterRoleBinding)).To(Succeed())
+	return clusterRoleBinding
+}
+
+var _ = Context("Dedicated telemetry-proxy", func() {
+
+	var c client.Client
+	var config *DedicatedProxyConfig
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().WithObjects(&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterRoleBindingName,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     clusterRoleBindingName,
+			},
+		}).Build()
+		config = &DedicatedProxyConfig{
+			Image: "public.ecr.aws/lumigo/lumigo-kubernetes-telemetry-proxy:latest",
+			Env: []corev1.EnvVar{
+				{
+					Name:  "LUMIGO_ENDPOINT",
+					Value: "https://ga-otlp.lumigo-tracer-edge.golumigo.com",
+				},
+			},
+			ClusterRoleBindingName: clusterRoleBindingName,
+		}
+	})
+
+	It("is deployed only if requested by the Lumigo instance", func() {
+		dedicatedProxy := true
+		lumigo := &operatorv1alpha1.Lumigo{}
+		Expect(IsRequested(lumigo)).To(BeFalse())
+
+		lumigo.Spec.Tracing.DedicatedProxy = &dedicatedProxy
+		Expect(IsRequested(lumigo)).To(BeTrue())
+	})
+
+	It("receives the telemetry of the workloads of its namespace", func() {
+		tracesUrl, logsUrl := OtlpServiceUrls("my-namespace")
+		Expect(tracesUrl).To(Equal("http://lumigo-dedicated-telemetry-proxy.my-namespace.svc:4318/v1/traces"))
+		Expect(logsUrl).To(Equal("http://lumigo-dedicated-telemetry-proxy.my-namespace.svc:4318/v1/logs"))
+	})
+
+	It("is deployed in its namespace with the configuration of the namespace", func() {
+		namespaceMonitoringConfig := &telemetryproxyconfigs.NamespaceMonitoringConfig{
+			Name:  "my-namespace",
+			Uid:   "123456",
+			Token: "t_123456",
+		}
+
+		isChanged, err := UpsertDedicatedProxyOfNamespace(context.TODO(), c, config, namespaceMonitoringConfig, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, secret)).To(Succeed())
+		Expect(string(secret.Data[namespacesSecretKey])).To(Equal(`[{"token":"t_123456","name":"my-namespace","uid":"123456"}]`))
+
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, deployment)).To(Succeed())
+		Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal(Name))
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(config.Image))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{
+			Name:  telemetryProxyModeEnvVar,
+			Value: telemetryProxyModeNode,
+		}))
+		Expect(container.Env).To(ContainElement(config.Env[0]))
+
+		service := &corev1.Service{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, service)).To(Succeed())
+		Expect(service.Spec.Selector).To(Equal(deployment.Spec.Selector.MatchLabels))
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, &corev1.ServiceAccount{})).To(Succeed())
+		Expect(getClusterRoleBinding(c).Subjects).To(ConsistOf(rbacv1.Subject{
+			Kind:      rbacv1.ServiceAccountKind,
+			Namespace: "my-namespace",
+			Name:      Name,
+		}))
+
+		// Upserting again without changes is idempotent
+		isChanged, err = UpsertDedicatedProxyOfNamespace(context.TODO(), c, config, namespaceMonitoringConfig, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		// The new token is picked up by the running telemetry-proxy, without rolling it out
+		namespaceMonitoringConfig.Token = "t_654321"
+		isChanged, err = UpsertDedicatedProxyOfNamespace(context.TODO(), c, config, namespaceMonitoringConfig, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, secret)).To(Succeed())
+		Expect(string(secret.Data[namespacesSecretKey])).To(ContainSubstring(`"token":"t_654321"`))
+
+		updatedDeployment := &appsv1.Deployment{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, updatedDeployment)).To(Succeed())
+		Expect(updatedDeployment.ResourceVersion).To(Equal(deployment.ResourceVersion))
+	})
+
+	It("is removed from its namespace, leaving the dedicated telemetry-proxies of the other namespaces", func() {
+		for _, namespace := range []string{"my-namespace", "other-namespace"} {
+			_, err := UpsertDedicatedProxyOfNamespace(context.TODO(), c, config, &telemetryproxyconfigs.NamespaceMonitoringConfig{
+				Name:  namespace,
+				Uid:   "123456",
+				Token: "t_123456",
+			}, &logger)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		isChanged, err := RemoveDedicatedProxyOfNamespace(context.TODO(), c, config, "my-namespace", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: "my-namespace", Name: Name}, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "other-namespace", Name: Name}, &appsv1.Deployment{})).To(Succeed())
+		Expect(getClusterRoleBinding(c).Subjects).To(ConsistOf(rbacv1.Subject{
+			Kind:      rbacv1.ServiceAccountKind,
+			Namespace: "other-namespace",
+			Name:      Name,
+		}))
+
+		isChanged, err = RemoveDedicatedProxyOfNamespace(context.TODO(), c, config, "my-namespace", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+	})
+
+})
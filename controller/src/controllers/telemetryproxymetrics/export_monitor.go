@@ -0,0 +1,234 @@
+package telemetryproxymetrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// The exporter name label of the internal metrics of the OpenTelemetry Collector
+	exporterLabel = "exporter"
+
+	sentMetricPrefix          = "otelcol_exporter_sent_"
+	sendFailedMetricPrefix    = "otelcol_exporter_send_failed_"
+	enqueueFailedMetricPrefix = "otelcol_exporter_enqueue_failed_"
+
+	// How long the samples of the internal metrics of the telemetry-proxy are retained by default
+	DefaultWindow = 5 * time.Minute
+)
+
+type exporterCounters struct {
+	sent   float64
+	failed float64
+	// Data dropped because the sending queue of the exporter was full
+	enqueueFailed float64
+}
+
+type sample struct {
+	time      time.Time
+	exporters map[string]exporterCounters
+}
+
+// ExportMonitor keeps track of how many spans, metric points and log records the exporters
+// of the telemetry-proxy have sent or failed to send, based on the internal metrics of the
+// telemetry-proxy, to detect exporters that have been failing over a period of time. It also
+// aggregates by day how much telemetry of each namespace the telemetry-proxy sent to Lumigo.
+type ExportMonitor struct {
+	metricsUrl        string
+	httpClient        *http.Client
+	window            time.Duration
+	minScrapeInterval time.Duration
+
+	mutex   sync.Mutex
+	samples []sample
+	usage   usageTracker
+}
+
+// NewExportMonitor creates an ExportMonitor scraping the internal metrics of the telemetry-proxy
+// from the given URL, and considering the samples collected within the given window of time.
+func NewExportMonitor(metricsUrl string, window time.Duration) *ExportMonitor {
+	return &ExportMonitor{
+		metricsUrl:        metricsUrl,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		window:            window,
+		minScrapeInterval: 10 * time.Second,
+		usage:             newUsageTracker(),
+	}
+}
+
+// Refresh scrapes the internal metrics of the telemetry-proxy, unless they have been scraped
+// recently; it is meant to be called at every reconciliation of every Lumigo instance.
+func (m *ExportMonitor) Refresh(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if len(m.samples) > 0 && now.Sub(m.samples[len(m.samples)-1].time) < m.minScrapeInterval {
+		return nil
+	}
+
+	exporters, namespaceUsage, err := m.scrape(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.usage.record(now, namespaceUsage)
+
+	if len(m.samples) > 0 && isCounterReset(m.samples[len(m.samples)-1].exporters, exporters) {
+		// The telemetry-proxy has restarted, and the previous samples are no longer comparable
+		m.samples = nil
+	}
+
+	m.samples = append(m.samples, sample{
+		time:      now,
+		exporters: exporters,
+	})
+
+	// Drop the samples that fell out of the window
+	firstInWindow := 0
+	for firstInWindow < len(m.samples)-1 && now.Sub(m.samples[firstInWindow].time) > m.window {
+		firstInWindow++
+	}
+	m.samples = m.samples[firstInWindow:]
+
+	return nil
+}
+
+// IsExporterDegraded returns whether the exporter with the given name has failed to send data
+// without any success for at least the given duration, and how much data it failed to send.
+func (m *ExportMonitor) IsExporterDegraded(exporterName string, minDuration time.Duration) (bool, float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.samples) < 2 {
+		return false, 0
+	}
+
+	oldest := m.samples[0]
+	newest := m.samples[len(m.samples)-1]
+	if newest.time.Sub(oldest.time) < minDuration {
+		return false, 0
+	}
+
+	oldestCounters := oldest.exporters[exporterName]
+	newestCounters, found := newest.exporters[exporterName]
+	if !found {
+		return false, 0
+	}
+
+	failed := newestCounters.failed - oldestCounters.failed
+	sent := newestCounters.sent - oldestCounters.sent
+
+	return failed > 0 && sent == 0, failed
+}
+
+// GetEnqueueFailures returns how much data the exporter with the given name dropped within the
+// window because its sending queue was full, which happens when the destination cannot keep up
+// with, or is not accepting, the data sent to it.
+func (m *ExportMonitor) GetEnqueueFailures(exporterName string) float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.samples) < 2 {
+		return 0
+	}
+
+	oldestCounters := m.samples[0].exporters[exporterName]
+	newestCounters := m.samples[len(m.samples)-1].exporters[exporterName]
+
+	return newestCounters.enqueueFailed - oldestCounters.enqueueFailed
+}
+
+func (m *ExportMonitor) scrape(ctx context.Context) (map[string]exporterCounters, map[string]NamespaceUsage, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, m.metricsUrl, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create the request for the telemetry-proxy metrics: %w", err)
+	}
+
+	response, err := m.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot scrape the telemetry-proxy metrics from '%s': %w", m.metricsUrl, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("cannot scrape the telemetry-proxy metrics from '%s': unexpected status code %d", m.metricsUrl, response.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	metricFamilies, err := parser.TextToMetricFamilies(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse the telemetry-proxy metrics: %w", err)
+	}
+
+	exporters := make(map[string]exporterCounters)
+	for name, metricFamily := range metricFamilies {
+		isSent := strings.HasPrefix(name, sentMetricPrefix)
+		isFailed := strings.HasPrefix(name, sendFailedMetricPrefix)
+		isEnqueueFailed := strings.HasPrefix(name, enqueueFailedMetricPrefix)
+		if !isSent && !isFailed && !isEnqueueFailed {
+			continue
+		}
+
+		for _, metric := range metricFamily.GetMetric() {
+			exporterName := getLabelValue(metric, exporterLabel)
+			if len(exporterName) < 1 {
+				continue
+			}
+
+			counters := exporters[exporterName]
+			switch {
+			case isSent:
+				counters.sent += getValue(metric)
+			case isFailed:
+				counters.failed += getValue(metric)
+			default:
+				counters.enqueueFailed += getValue(metric)
+			}
+			exporters[exporterName] = counters
+		}
+	}
+
+	return exporters, scrapeNamespaceUsage(metricFamilies), nil
+}
+
+func isCounterReset(previous map[string]exporterCounters, current map[string]exporterCounters) bool {
+	for exporterName, previousCounters := range previous {
+		if currentCounters, found := current[exporterName]; found {
+			if currentCounters.sent < previousCounters.sent || currentCounters.failed < previousCounters.failed || currentCounters.enqueueFailed < previousCounters.enqueueFailed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func getLabelValue(metric *dto.Metric, labelName string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == labelName {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
+
+func getValue(metric *dto.Metric) float64 {
+	if counter := metric.GetCounter(); counter != nil {
+		return counter.GetValue()
+	}
+
+	if untyped := metric.GetUntyped(); untyped != nil {
+		return untyped.GetValue()
+	}
+
+	return 0
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryproxymetrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var t *testing.T
+
+func TestAPIs(tt *testing.T) {
+	t = tt
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Telemetry Proxy Metrics Suite")
+}
+
+// fakeTelemetryProxy serves the exporter metrics the way the telemetry-proxy does
+type fakeTelemetryProxy struct {
+	mutex              sync.Mutex
+	sentSpans          map[string]int
+	failedSpans        map[string]int
+	enqueueFailedSpans map[string]int
+	// The counters of the `namespaceusage` processor, by namespace and signal
+	usageItems map[string]map[string]int
+	usageBytes map[string]map[string]int
+}
+
+func (p *fakeTelemetryProxy) set(exporterName string, sent int, failed int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.sentSpans[exporterName] = sent
+	p.failedSpans[exporterName] = failed
+}
+
+func (p *fakeTelemetryProxy) setEnqueueFailed(exporterName string, enqueueFailed int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.enqueueFailedSpans[exporterName] = enqueueFailed
+}
+
+func (p *fakeTelemetryProxy) setUsage(namespaceName string, signal string, items int, bytes int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.usageItems[namespaceName] == nil {
+		p.usageItems[namespaceName] = make(map[string]int)
+		p.usageBytes[namespaceName] = make(map[string]int)
+	}
+	p.usageItems[namespaceName][signal] = items
+	p.usageBytes[namespaceName][signal] = bytes
+}
+
+func (p *fakeTelemetryProxy) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fmt.Fprintln(w, "# HELP otelcol_exporter_sent_spans Number of spans successfully sent to destination.")
+	fmt.Fprintln(w, "# TYPE otelcol_exporter_sent_spans counter")
+	for exporterName, sent := range p.sentSpans {
+		fmt.Fprintf(w, "otelcol_exporter_sent_spans{exporter=\"%s\",service_name=\"lumigo-telemetry-proxy\"} %d\n", exporterName, sent)
+	}
+
+	fmt.Fprintln(w, "# HELP otelcol_exporter_send_failed_spans Number of spans in failed attempts to send to destination.")
+	fmt.Fprintln(w, "# TYPE otelcol_exporter_send_failed_spans counter")
+	for exporterName, failed := range p.failedSpans {
+		fmt.Fprintf(w, "otelcol_exporter_send_failed_spans{exporter=\"%s\",service_name=\"lumigo-telemetry-proxy\"} %d\n", exporterName, failed)
+	}
+
+	fmt.Fprintln(w, "# HELP otelcol_exporter_enqueue_failed_spans Number of spans failed to be added to the sending queue.")
+	fmt.Fprintln(w, "# TYPE otelcol_exporter_enqueue_failed_spans counter")
+	for exporterName, enqueueFailed := range p.enqueueFailedSpans {
+		fmt.Fprintf(w, "otelcol_exporter_enqueue_failed_spans{exporter=\"%s\",service_name=\"lumigo-telemetry-proxy\"} %d\n", exporterName, enqueueFailed)
+	}
+
+	fmt.Fprintln(w, "# HELP otelcol_processor_namespaceusage_items_total Number of spans, log records or metric points that went through the processor, by namespace")
+	fmt.Fprintln(w, "# TYPE otelcol_processor_namespaceusage_items_total counter")
+	for namespaceName, signals := range p.usageItems {
+		for signal, items := range signals {
+			fmt.Fprintf(w, "otelcol_processor_namespaceusage_items_total{k8s_namespace_name=\"%s\",signal=\"%s\",service_name=\"lumigo-telemetry-proxy\"} %d\n", namespaceName, signal, items)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP otelcol_processor_namespaceusage_bytes_total Size, in the OTLP protobuf encoding, of the telemetry that went through the processor, by namespace")
+	fmt.Fprintln(w, "# TYPE otelcol_processor_namespaceusage_bytes_total counter")
+	for namespaceName, signals := range p.usageBytes {
+		for signal, bytes := range signals {
+			fmt.Fprintf(w, "otelcol_processor_namespaceusage_bytes_total{k8s_namespace_name=\"%s\",signal=\"%s\",service_name=\"lumigo-telemetry-proxy\"} %d\n", namespaceName, signal, bytes)
+		}
+	}
+}
+
+var _ = Context("Export monitor", func() {
+
+	var telemetryProxy *fakeTelemetryProxy
+	var server *httptest.Server
+	var monitor *ExportMonitor
+
+	BeforeEach(func() {
+		telemetryProxy = &fakeTelemetryProxy{
+			sentSpans:          make(map[string]int),
+			failedSpans:        make(map[string]int),
+			enqueueFailedSpans: make(map[string]int),
+			usageItems:         make(map[string]map[string]int),
+			usageBytes:         make(map[string]map[string]int),
+		}
+		server = httptest.NewServer(telemetryProxy)
+
+		monitor = NewExportMonitor(server.URL, time.Minute)
+		// Scrape at every refresh, rather than waiting between scrapes
+		monitor.minScrapeInterval = 0
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reports an exporter that fails without any success as degraded", func() {
+		telemetryProxy.set("otlphttp/lumigo", 10, 0)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		time.Sleep(50 * time.Millisecond)
+
+		telemetryProxy.set("otlphttp/lumigo", 10, 25)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		degraded, failed := monitor.IsExporterDegraded("otlphttp/lumigo", 10*time.Millisecond)
+		Expect(degraded).To(BeTrue())
+		Expect(failed).To(Equal(float64(25)))
+
+		// Failures have not been going on for long enough
+		degraded, _ = monitor.IsExporterDegraded("otlphttp/lumigo", time.Minute)
+		Expect(degraded).To(BeFalse())
+
+		// Unknown exporters are never degraded
+		degraded, _ = monitor.IsExporterDegraded("otlphttp/lumigo_ns_other", 10*time.Millisecond)
+		Expect(degraded).To(BeFalse())
+	})
+
+	It("does not report an exporter with occasional successes as degraded", func() {
+		telemetryProxy.set("otlphttp/lumigo", 10, 0)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		time.Sleep(50 * time.Millisecond)
+
+		telemetryProxy.set("otlphttp/lumigo", 15, 25)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		degraded, _ := monitor.IsExporterDegraded("otlphttp/lumigo", 10*time.Millisecond)
+		Expect(degraded).To(BeFalse())
+	})
+
+	It("forgets about failures before the telemetry-proxy restarted", func() {
+		telemetryProxy.set("otlphttp/lumigo", 10, 0)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		time.Sleep(50 * time.Millisecond)
+
+		telemetryProxy.set("otlphttp/lumigo", 10, 25)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		// The counters of the restarted telemetry-proxy start from zero
+		telemetryProxy.set("otlphttp/lumigo", 0, 1)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		degraded, _ := monitor.IsExporterDegraded("otlphttp/lumigo", 10*time.Millisecond)
+		Expect(degraded).To(BeFalse())
+	})
+
+	It("reports the data dropped because of full sending queues", func() {
+		telemetryProxy.set("otlphttp/lumigo", 10, 0)
+		telemetryProxy.setEnqueueFailed("otlphttp/lumigo", 5)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		Expect(monitor.GetEnqueueFailures("otlphttp/lumigo")).To(Equal(float64(0)))
+
+		telemetryProxy.setEnqueueFailed("otlphttp/lumigo", 12)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		Expect(monitor.GetEnqueueFailures("otlphttp/lumigo")).To(Equal(float64(7)))
+	})
+
+	It("aggregates the usage of each namespace by day", func() {
+		today := time.Now().UTC().Format(UsageDayFormat)
+
+		// The first scrape is the baseline
+		telemetryProxy.setUsage("my-namespace", "spans", 100, 10000)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+		Expect(monitor.GetNamespaceUsage("my-namespace")).To(BeEmpty())
+
+		telemetryProxy.setUsage("my-namespace", "spans", 150, 15000)
+		telemetryProxy.setUsage("my-namespace", "log_records", 20, 2000)
+		telemetryProxy.setUsage("other-namespace", "metric_points", 30, 300)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		Expect(monitor.GetNamespaceUsage("my-namespace")).To(Equal(map[string]NamespaceUsage{
+			today: {Spans: 50, LogRecords: 20, Bytes: 7000},
+		}))
+		Expect(monitor.GetDailyUsage()[today]).To(HaveKeyWithValue("other-namespace", NamespaceUsage{MetricPoints: 30, Bytes: 300}))
+		Expect(monitor.GetNamespaceSentSpans("my-namespace")).To(Equal(int64(150)))
+		Expect(monitor.GetNamespaceSentSpans("unknown-namespace")).To(BeZero())
+
+		// The counters of the restarted telemetry-proxy start from zero
+		telemetryProxy.setUsage("my-namespace", "spans", 5, 500)
+		telemetryProxy.setUsage("my-namespace", "log_records", 0, 0)
+		Expect(monitor.Refresh(context.TODO())).To(Succeed())
+
+		Expect(monitor.GetNamespaceUsage("my-namespace")[today]).To(Equal(NamespaceUsage{Spans: 55, LogRecords: 20, Bytes: 7500}))
+	})
+
+	It("restores the usage tracked before the controller started once per namespace", func() {
+		today := time.Now().UTC().Format(UsageDayFormat)
+		longAgo := time.Now().UTC().AddDate(0, 0, -UsageRetentionDays).Format(UsageDayFormat)
+
+		monitor.RestoreNamespaceUsage("my-namespace", map[string]NamespaceUsage{
+			today:   {Spans: 1000},
+			longAgo: {Spans: 42},
+		})
+		monitor.RestoreNamespaceUsage("my-namespace", map[string]NamespaceUsage{
+			today: {Spans: 1000},
+		})
+
+		Expect(monitor.GetNamespaceUsage("my-namespace")).To(Equal(map[string]NamespaceUsage{
+			today: {Spans: 1000},
+		}))
+	})
+
+	It("writes the usage of all namespaces in the ConfigMap of the operator", func() {
+		today := time.Now().UTC().Format(UsageDayFormat)
+		clientset := fake.NewSimpleClientset()
+		reporter := NewUsageReporter(monitor, clientset.CoreV1(), "lumigo-system", time.Minute, testr.New(t))
+
+		telemetryProxy.setUsage("my-namespace", "spans", 100, 10000)
+		Expect(reporter.Report(context.TODO())).To(Succeed())
+		telemetryProxy.setUsage("my-namespace", "spans", 120, 12000)
+		Expect(reporter.Report(context.TODO())).To(Succeed())
+
+		configMap, err := clientset.CoreV1().ConfigMaps("lumigo-system").Get(context.TODO(), UsageConfigMapName, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configMap.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "lumigo-operator"))
+
+		var usage map[string]map[string]NamespaceUsage
+		Expect(json.Unmarshal([]byte(configMap.Data[UsageConfigMapKey]), &usage)).To(Succeed())
+		Expect(usage).To(Equal(map[string]map[string]NamespaceUsage{
+			today: {"my-namespace": {Spans: 20, Bytes: 2000}},
+		}))
+	})
+
+	It("fails to refresh when the telemetry-proxy is not reachable", func() {
+		server.Close()
+
+		Expect(monitor.Refresh(context.TODO())).NotTo(Succeed())
+	})
+
+})
@@ -0,0 +1,219 @@
+package telemetryproxymetrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// The counters of the `namespaceusage` processor of the telemetry-proxy; the suffix of counters may be added
+	// by the Prometheus exporter of the OpenTelemetry Collector, so the names are matched by prefix
+	namespaceUsageItemsMetricPrefix = "otelcol_processor_namespaceusage_items"
+	namespaceUsageBytesMetricPrefix = "otelcol_processor_namespaceusage_bytes"
+	namespaceLabel                  = "k8s_namespace_name"
+	signalLabel                     = "signal"
+
+	signalSpans        = "spans"
+	signalLogRecords   = "log_records"
+	signalMetricPoints = "metric_points"
+
+	// The format of the days (UTC) the usage is aggregated by
+	UsageDayFormat = "2006-01-02"
+	// How many days of usage, including the current one, are retained
+	UsageRetentionDays = 7
+)
+
+// NamespaceUsage is how much telemetry of one namespace the telemetry-proxy sent to Lumigo
+type NamespaceUsage struct {
+	Spans        int64 `json:"spans"`
+	LogRecords   int64 `json:"logRecords"`
+	MetricPoints int64 `json:"metricPoints"`
+	// The size of the telemetry, in the OTLP protobuf encoding
+	Bytes int64 `json:"bytes"`
+}
+
+func (u NamespaceUsage) add(other NamespaceUsage) NamespaceUsage {
+	return NamespaceUsage{
+		Spans:        u.Spans + other.Spans,
+		LogRecords:   u.LogRecords + other.LogRecords,
+		MetricPoints: u.MetricPoints + other.MetricPoints,
+		Bytes:        u.Bytes + other.Bytes,
+	}
+}
+
+// The counters only grow, unless the telemetry-proxy restarts, in which case they start over from zero
+func (u NamespaceUsage) since(previous NamespaceUsage) NamespaceUsage {
+	if u.Spans < previous.Spans || u.LogRecords < previous.LogRecords || u.MetricPoints < previous.MetricPoints || u.Bytes < previous.Bytes {
+		return u
+	}
+
+	return NamespaceUsage{
+		Spans:        u.Spans - previous.Spans,
+		LogRecords:   u.LogRecords - previous.LogRecords,
+		MetricPoints: u.MetricPoints - previous.MetricPoints,
+		Bytes:        u.Bytes - previous.Bytes,
+	}
+}
+
+// usageTracker aggregates by day the counters of the `namespaceusage` processor of the telemetry-proxy
+type usageTracker struct {
+	// The counters of the latest scrape; nil before the first one
+	counters map[string]NamespaceUsage
+	// By day, then by namespace
+	daily map[string]map[string]NamespaceUsage
+	// The namespaces whose usage before the controller started has been restored
+	restored map[string]bool
+}
+
+func newUsageTracker() usageTracker {
+	return usageTracker{
+		daily:    make(map[string]map[string]NamespaceUsage),
+		restored: make(map[string]bool),
+	}
+}
+
+func (t *usageTracker) record(now time.Time, counters map[string]NamespaceUsage) {
+	// The first scrape is only the baseline, as the counters include the telemetry sent before the
+	// controller started, which is restored from the Lumigo instances instead
+	if t.counters != nil {
+		day := now.UTC().Format(UsageDayFormat)
+		for namespaceName, namespaceCounters := range counters {
+			t.add(day, namespaceName, namespaceCounters.since(t.counters[namespaceName]))
+		}
+	}
+	t.counters = counters
+
+	t.prune(now)
+}
+
+func (t *usageTracker) add(day string, namespaceName string, usage NamespaceUsage) {
+	if usage == (NamespaceUsage{}) {
+		return
+	}
+
+	namespaces, found := t.daily[day]
+	if !found {
+		namespaces = make(map[string]NamespaceUsage)
+		t.daily[day] = namespaces
+	}
+
+	namespaces[namespaceName] = namespaces[namespaceName].add(usage)
+}
+
+// Drop the days that fell out of the retention
+func (t *usageTracker) prune(now time.Time) {
+	oldestRetainedDay := now.UTC().AddDate(0, 0, 1-UsageRetentionDays).Format(UsageDayFormat)
+	for day := range t.daily {
+		if day < oldestRetainedDay {
+			delete(t.daily, day)
+		}
+	}
+}
+
+func scrapeNamespaceUsage(metricFamilies map[string]*dto.MetricFamily) map[string]NamespaceUsage {
+	counters := make(map[string]NamespaceUsage)
+	for name, metricFamily := range metricFamilies {
+		isItems := strings.HasPrefix(name, namespaceUsageItemsMetricPrefix)
+		isBytes := strings.HasPrefix(name, namespaceUsageBytesMetricPrefix)
+		if !isItems && !isBytes {
+			continue
+		}
+
+		for _, metric := range metricFamily.GetMetric() {
+			namespaceName := getLabelValue(metric, namespaceLabel)
+			if len(namespaceName) < 1 {
+				continue
+			}
+
+			value := int64(getValue(metric))
+			usage := counters[namespaceName]
+			switch {
+			case isBytes:
+				usage.Bytes += value
+			case getLabelValue(metric, signalLabel) == signalSpans:
+				usage.Spans += value
+			case getLabelValue(metric, signalLabel) == signalLogRecords:
+				usage.LogRecords += value
+			case getLabelValue(metric, signalLabel) == signalMetricPoints:
+				usage.MetricPoints += value
+			}
+			counters[namespaceName] = usage
+		}
+	}
+
+	return counters
+}
+
+// GetNamespaceUsage returns, by day, how much telemetry of the namespace the
+// telemetry-proxy sent to Lumigo in the retained days.
+func (m *ExportMonitor) GetNamespaceUsage(namespaceName string) map[string]NamespaceUsage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	usage := make(map[string]NamespaceUsage)
+	for day, namespaces := range m.usage.daily {
+		if namespaceUsage, found := namespaces[namespaceName]; found {
+			usage[day] = namespaceUsage
+		}
+	}
+
+	return usage
+}
+
+// GetNamespaceSentSpans returns how many spans of the namespace the telemetry-proxy has sent to Lumigo
+// since it started, as of the latest scrape; the count starts over when the telemetry-proxy restarts.
+func (m *ExportMonitor) GetNamespaceSentSpans(namespaceName string) int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.usage.counters[namespaceName].Spans
+}
+
+// GetDailyUsage returns, by day and then by namespace, how much telemetry the telemetry-proxy
+// sent to Lumigo in the retained days.
+func (m *ExportMonitor) GetDailyUsage() map[string]map[string]NamespaceUsage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	daily := make(map[string]map[string]NamespaceUsage, len(m.usage.daily))
+	for day, namespaces := range m.usage.daily {
+		daily[day] = make(map[string]NamespaceUsage, len(namespaces))
+		for namespaceName, namespaceUsage := range namespaces {
+			daily[day][namespaceName] = namespaceUsage
+		}
+	}
+
+	return daily
+}
+
+// RestoreNamespaceUsage adds the usage of the namespace tracked before the controller started, e.g.,
+// as reported in the status of its Lumigo instance, to the usage tracked since. Only the first
+// restore of each namespace is taken into account.
+func (m *ExportMonitor) RestoreNamespaceUsage(namespaceName string, usage map[string]NamespaceUsage) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.usage.restored[namespaceName] {
+		return
+	}
+	m.usage.restored[namespaceName] = true
+
+	for day, namespaceUsage := range usage {
+		m.usage.add(day, namespaceName, namespaceUsage)
+	}
+	m.usage.prune(time.Now())
+}
+
+// SortedUsageDays returns the days of the usage, most recent first
+func SortedUsageDays(usage map[string]NamespaceUsage) []string {
+	days := make([]string, 0, len(usage))
+	for day := range usage {
+		days = append(days, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	return days
+}
@@ -0,0 +1,159 @@
+package telemetryproxymetrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// UsageConfigMapName is the name of the ConfigMap, in the namespace of the operator, with the usage of all namespaces
+	UsageConfigMapName = "lumigo-telemetry-usage"
+	// UsageConfigMapKey is the key of the ConfigMap data with the usage, as a JSON object by day and then by namespace
+	UsageConfigMapKey = "usage.json"
+
+	DefaultUsageReportInterval = time.Minute
+)
+
+var (
+	// The usage of the current day (UTC), by namespace and unit, i.e., spans, log_records, metric_points or bytes
+	namespaceUsageToday = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "lumigo_operator_namespace_usage_today",
+			Help: "Telemetry of the namespace sent to Lumigo by the telemetry-proxy since midnight UTC",
+		},
+		[]string{"namespace", "unit"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(namespaceUsageToday)
+}
+
+// UsageReporter periodically writes the usage of all namespaces tracked by the export monitor in the
+// `lumigo-telemetry-usage` ConfigMap of the namespace of the operator, and exposes the usage of the
+// current day as metrics of the operator.
+type UsageReporter struct {
+	monitor    *ExportMonitor
+	configMaps corev1client.ConfigMapsGetter
+	namespace  string
+	interval   time.Duration
+	log        logr.Logger
+}
+
+// NewUsageReporter creates a UsageReporter that writes the usage tracked by the given monitor in the
+// ConfigMap of the given namespace.
+func NewUsageReporter(monitor *ExportMonitor, configMaps corev1client.ConfigMapsGetter, namespace string, interval time.Duration, log logr.Logger) *UsageReporter {
+	if interval <= 0 {
+		interval = DefaultUsageReportInterval
+	}
+
+	return &UsageReporter{
+		monitor:    monitor,
+		configMaps: configMaps,
+		namespace:  namespace,
+		interval:   interval,
+		log:        log,
+	}
+}
+
+// Start reports the usage periodically until the context is done, which makes the reporter a manager.Runnable.
+func (r *UsageReporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Report(ctx); err != nil {
+				r.log.Error(err, "Cannot report the telemetry usage of the namespaces")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns true, as only one replica of the operator writes the ConfigMap.
+func (r *UsageReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Report refreshes the usage tracked by the monitor, writes it in the ConfigMap and updates the metrics.
+func (r *UsageReporter) Report(ctx context.Context) error {
+	if err := r.monitor.Refresh(ctx); err != nil {
+		// Report the usage tracked so far anyhow
+		r.log.Error(err, "Cannot retrieve the metrics of the telemetry-proxy")
+	}
+
+	dailyUsage := r.monitor.GetDailyUsage()
+
+	namespaceUsageToday.Reset()
+	for namespaceName, usage := range dailyUsage[time.Now().UTC().Format(UsageDayFormat)] {
+		namespaceUsageToday.WithLabelValues(namespaceName, signalSpans).Set(float64(usage.Spans))
+		namespaceUsageToday.WithLabelValues(namespaceName, signalLogRecords).Set(float64(usage.LogRecords))
+		namespaceUsageToday.WithLabelValues(namespaceName, signalMetricPoints).Set(float64(usage.MetricPoints))
+		namespaceUsageToday.WithLabelValues(namespaceName, "bytes").Set(float64(usage.Bytes))
+	}
+
+	usageJson, err := json.Marshal(dailyUsage)
+	if err != nil {
+		return fmt.Errorf("cannot marshal the telemetry usage: %w", err)
+	}
+
+	return r.writeConfigMap(ctx, string(usageJson))
+}
+
+func (r *UsageReporter) writeConfigMap(ctx context.Context, usageJson string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := r.configMaps.ConfigMaps(r.namespace).Get(ctx, UsageConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: r.namespace,
+					Name:      UsageConfigMapName,
+					Labels: map[string]string{
+						"app.kubernetes.io/part-of":    "lumigo",
+						"app.kubernetes.io/managed-by": "lumigo-operator",
+					},
+				},
+				Data: map[string]string{
+					UsageConfigMapKey: usageJson,
+				},
+			}
+
+			_, err = r.configMaps.ConfigMaps(r.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(corev1.Resource("configmaps"), UsageConfigMapName, err)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot create the '%s/%s' ConfigMap: %w", r.namespace, UsageConfigMapName, err)
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if configMap.Data[UsageConfigMapKey] == usageJson {
+			return nil
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[UsageConfigMapKey] = usageJson
+
+		_, err = r.configMaps.ConfigMaps(r.namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,165 @@
+package telemetryproxyprobe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How often each endpoint of the telemetry-proxy is probed at most
+	defaultMinProbeInterval = 30 * time.Second
+	// How long a probe waits for the name of the endpoint to resolve, and then for the connection to be accepted
+	defaultProbeTimeout = 5 * time.Second
+)
+
+// TelemetryProxyProbe periodically checks whether the OTLP endpoints of the telemetry-proxy, which the
+// injection advertises to the instrumented workloads, resolve and accept connections from within the cluster.
+type TelemetryProxyProbe struct {
+	// Whether the probes connect to the endpoints, rather than only resolving their names; NetworkPolicies
+	// that let only the instrumented workloads reach the telemetry-proxy would fail the connections of the operator
+	isConnectionChecked bool
+	minProbeInterval    time.Duration
+	lookupHost          func(ctx context.Context, host string) ([]string, error)
+	dialContext         func(ctx context.Context, network string, address string) (net.Conn, error)
+
+	mutex  sync.Mutex
+	probes map[string]*endpointProbe
+}
+
+type endpointProbe struct {
+	lastProbeTime time.Time
+	lastError     error
+	failingSince  time.Time
+}
+
+// NewTelemetryProxyProbe creates a TelemetryProxyProbe; if isConnectionChecked is false, the
+// endpoints are only checked to resolve.
+func NewTelemetryProxyProbe(isConnectionChecked bool) *TelemetryProxyProbe {
+	dialer := &net.Dialer{Timeout: defaultProbeTimeout}
+
+	return &TelemetryProxyProbe{
+		isConnectionChecked: isConnectionChecked,
+		minProbeInterval:    defaultMinProbeInterval,
+		lookupHost:          net.DefaultResolver.LookupHost,
+		dialContext:         dialer.DialContext,
+		probes:              make(map[string]*endpointProbe),
+	}
+}
+
+// Probe checks the host and port of each of the given OTLP URLs, e.g.,
+// `http://lumigo-telemetry-proxy.lumigo-system.svc:4318/v1/traces`, unless they have been probed recently;
+// it is meant to be called at every reconciliation of every Lumigo instance. The URLs resolved by the
+// instrumented workloads themselves, like those of the telemetry-proxy DaemonSet on their node, are not probed.
+func (p *TelemetryProxyProbe) Probe(ctx context.Context, otlpUrls ...string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	for _, otlpUrl := range otlpUrls {
+		address, err := probedAddress(otlpUrl)
+		if len(address) < 1 && err == nil {
+			continue
+		}
+
+		probe, isFound := p.probes[otlpUrl]
+		if !isFound {
+			probe = &endpointProbe{}
+			p.probes[otlpUrl] = probe
+		} else if now.Sub(probe.lastProbeTime) < p.minProbeInterval {
+			continue
+		}
+
+		if err == nil {
+			err = p.probeAddress(ctx, address)
+		}
+		if err != nil {
+			err = fmt.Errorf("cannot reach '%s': %w", otlpUrl, err)
+		}
+
+		if err != nil && probe.lastError == nil {
+			probe.failingSince = now
+		}
+
+		probe.lastProbeTime = now
+		probe.lastError = err
+	}
+}
+
+// IsUnreachable returns whether the probes of the given OTLP URL have been failing for at least the
+// given duration, and the error of the latest probe; URLs that have not been probed are not unreachable.
+func (p *TelemetryProxyProbe) IsUnreachable(otlpUrl string, minDuration time.Duration) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	probe, isFound := p.probes[otlpUrl]
+	if !isFound || probe.lastError == nil {
+		return false, nil
+	}
+
+	return probe.lastProbeTime.Sub(probe.failingSince) >= minDuration, probe.lastError
+}
+
+func (p *TelemetryProxyProbe) probeAddress(ctx context.Context, address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, defaultProbeTimeout)
+	defer cancel()
+
+	addresses, err := p.lookupHost(lookupCtx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve '%s': %w", host, err)
+	}
+	if len(addresses) < 1 {
+		return fmt.Errorf("'%s' resolves to no address", host)
+	}
+
+	if !p.isConnectionChecked {
+		return nil
+	}
+
+	conn, err := p.dialContext(ctx, "tcp", net.JoinHostPort(addresses[0], port))
+	if err != nil {
+		return fmt.Errorf("cannot connect to port %s of '%s': %w", port, host, err)
+	}
+
+	return conn.Close()
+}
+
+// probedAddress returns the host and port to probe for the given OTLP URL, or an empty string if the URL
+// references environment variables of the instrumented containers, e.g., `$(LUMIGO_TELEMETRY_PROXY_HOST_IP)`
+func probedAddress(otlpUrl string) (string, error) {
+	if len(otlpUrl) < 1 || strings.Contains(otlpUrl, "$(") {
+		return "", nil
+	}
+
+	parsedUrl, err := url.Parse(otlpUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if len(parsedUrl.Hostname()) < 1 {
+		return "", fmt.Errorf("the URL has no host")
+	}
+
+	port := parsedUrl.Port()
+	if len(port) < 1 {
+		switch parsedUrl.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		default:
+			return "", fmt.Errorf("the URL has no port, and the '%s' scheme has no default one", parsedUrl.Scheme)
+		}
+	}
+
+	return net.JoinHostPort(parsedUrl.Hostname(), port), nil
+}
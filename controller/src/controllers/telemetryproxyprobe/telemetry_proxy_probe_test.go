@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryproxyprobe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Telemetry Proxy Probe Suite")
+}
+
+var _ = Context("Telemetry-proxy probe", func() {
+
+	var listener net.Listener
+	var probe *TelemetryProxyProbe
+	var otlpUrl string
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		probe = NewTelemetryProxyProbe(true)
+		// Probe at every call, rather than waiting between probes
+		probe.minProbeInterval = 0
+		// Resolve the names of the services of the telemetry-proxy without a cluster DNS
+		probe.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+			if host == "lumigo-telemetry-proxy.lumigo-system.svc" {
+				return []string{"127.0.0.1"}, nil
+			}
+
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+
+		_, port, err := net.SplitHostPort(listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		otlpUrl = fmt.Sprintf("http://lumigo-telemetry-proxy.lumigo-system.svc:%s/v1/traces", port)
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("considers the telemetry-proxy reachable when its endpoint resolves and accepts connections", func() {
+		probe.Probe(context.TODO(), otlpUrl)
+
+		isUnreachable, err := probe.IsUnreachable(otlpUrl, 0)
+		Expect(isUnreachable).To(BeFalse())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("considers the telemetry-proxy unreachable when its endpoint does not resolve", func() {
+		staleUrl := "http://lumigo-telemetry-proxy.lumigo-operator.svc:4318/v1/traces"
+
+		probe.Probe(context.TODO(), staleUrl)
+
+		isUnreachable, err := probe.IsUnreachable(staleUrl, 0)
+		Expect(isUnreachable).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("cannot resolve 'lumigo-telemetry-proxy.lumigo-operator.svc'")))
+	})
+
+	It("considers the telemetry-proxy unreachable when its endpoint refuses connections, but only after long enough", func() {
+		listener.Close()
+
+		probe.Probe(context.TODO(), otlpUrl)
+
+		isUnreachable, err := probe.IsUnreachable(otlpUrl, 10*time.Millisecond)
+		Expect(isUnreachable).To(BeFalse())
+		Expect(err).To(MatchError(ContainSubstring("cannot connect")))
+
+		time.Sleep(20 * time.Millisecond)
+		probe.Probe(context.TODO(), otlpUrl)
+
+		isUnreachable, err = probe.IsUnreachable(otlpUrl, 10*time.Millisecond)
+		Expect(isUnreachable).To(BeTrue())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("only resolves the endpoint when the connections are not checked", func() {
+		probe.isConnectionChecked = false
+		listener.Close()
+
+		probe.Probe(context.TODO(), otlpUrl)
+
+		isUnreachable, err := probe.IsUnreachable(otlpUrl, 0)
+		Expect(isUnreachable).To(BeFalse())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not probe the endpoints resolved by the instrumented workloads", func() {
+		nodeUrl := "http://$(LUMIGO_TELEMETRY_PROXY_HOST_IP):4318/v1/traces"
+
+		probe.Probe(context.TODO(), nodeUrl)
+
+		Expect(probe.probes).NotTo(HaveKey(nodeUrl))
+		isUnreachable, err := probe.IsUnreachable(nodeUrl, 0)
+		Expect(isUnreachable).To(BeFalse())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+})
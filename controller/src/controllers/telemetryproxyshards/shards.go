@@ -0,0 +1,602 @@
+package telemetryproxyshards
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	// The prefix of the names of the Deployment, Service and Secret of each shard, followed by its index
+	ShardNamePrefix = "lumigo-telemetry-proxy-shard-"
+	// The label of the resources of the shards with the index of their shard
+	ShardLabelKey = "lumigo.io/telemetry-proxy-shard"
+	// The port at which the telemetry-proxy of each shard receives OTLP data from the instrumented workloads
+	Port = 4318
+
+	namespacesSecretKey              = "namespaces_to_monitor.json"
+	namespacesMountPath              = "/lumigo/etc/namespaces/"
+	namespacesVolumeName             = "namespace-configurations"
+	otelcolConfigMountPath           = "/lumigo/etc/otelcol/"
+	otelcolConfigVolumeName          = "telemetry-proxy-configurations"
+	templateChecksumAnnotation       = "lumigo.io/template-checksum"
+	kubernetesAppNameLabelKey        = "app.kubernetes.io/name"
+	kubernetesAppNameLabelValue      = "lumigo-telemetry-proxy-shard"
+	kubernetesAppComponentLabelKey   = "app.kubernetes.io/component"
+	kubernetesAppComponentLabelValue = "telemetry-proxy"
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+	telemetryProxyModeEnvVar         = "LUMIGO_TELEMETRY_PROXY_MODE"
+	telemetryProxyModeNode           = "node"
+	otlpPortName                     = "otlphttp"
+	hostnameTopologyKey              = "kubernetes.io/hostname"
+)
+
+// The scheduling constraints that spread the replicas of each shard across nodes
+const (
+	PodAntiAffinityNone      = "none"
+	PodAntiAffinityPreferred = "preferred"
+	PodAntiAffinityRequired  = "required"
+)
+
+// ShardsConfig contains the settings of the operator that apply to the telemetry-proxy shards, each of
+// which is a Deployment that receives the telemetry of the namespaces assigned to it
+type ShardsConfig struct {
+	// The namespace the operator runs in, in which the telemetry-proxy shards are deployed
+	Namespace string
+	// The service account of the operator, which the telemetry-proxy uses to enrich telemetry with Kubernetes metadata
+	ServiceAccountName string
+	// The image of the telemetry-proxy
+	Image string
+	// The environment variables of the telemetry-proxy, e.g., the Lumigo endpoints and the cluster name
+	Env []corev1.EnvVar
+	// The resources of the telemetry-proxy container of each shard; optional
+	Resources corev1.ResourceRequirements
+	// The amount of shards
+	ShardCount int
+	// The shards assigned explicitly to namespaces, e.g., to isolate a noisy namespace in a shard of its own;
+	// the other namespaces are assigned to shards by the hash of their name
+	Assignments map[string]int
+	// The replicas of the Deployment of each shard; if less than 1, each shard has one replica
+	Replicas int32
+	// Whether each shard with more than one replica gets a PodDisruptionBudget that lets node drains evict
+	// only one of its replicas at a time
+	PodDisruptionBudgetEnabled bool
+	// How the replicas of each shard are spread across nodes: `none`, `preferred` or `required`; if empty,
+	// the replicas are not spread
+	PodAntiAffinity string
+}
+
+// Validate returns an error if there are no shards, if namespaces are assigned to shards that do not exist, or if
+// the pod anti-affinity is unknown
+func (c *ShardsConfig) Validate() error {
+	if c.ShardCount < 1 {
+		return fmt.Errorf("the amount of telemetry-proxy shards must be positive, found %d", c.ShardCount)
+	}
+
+	for namespace, shard := range c.Assignments {
+		if shard < 0 || shard >= c.ShardCount {
+			return fmt.Errorf("the '%s' namespace is assigned to the telemetry-proxy shard %d, but the shards go from 0 to %d", namespace, shard, c.ShardCount-1)
+		}
+	}
+
+	switch c.PodAntiAffinity {
+	case "", PodAntiAffinityNone, PodAntiAffinityPreferred, PodAntiAffinityRequired:
+	default:
+		return fmt.Errorf("the pod anti-affinity of the telemetry-proxy shards must be either '%s', '%s' or '%s', found '%s'", PodAntiAffinityNone, PodAntiAffinityPreferred, PodAntiAffinityRequired, c.PodAntiAffinity)
+	}
+
+	return nil
+}
+
+func (c *ShardsConfig) replicas() int32 {
+	if c.Replicas < 1 {
+		return 1
+	}
+
+	return c.Replicas
+}
+
+// ShardOfNamespace returns the index of the shard that receives the telemetry of the namespace
+func (c *ShardsConfig) ShardOfNamespace(namespace string) int {
+	if shard, ok := c.Assignments[namespace]; ok {
+		return shard
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(namespace))
+	return int(hash.Sum32() % uint32(c.ShardCount))
+}
+
+// ShardName returns the name of the Deployment, Service and Secret of the shard
+func ShardName(shard int) string {
+	return ShardNamePrefix + strconv.Itoa(shard)
+}
+
+// OtlpServiceUrls returns the URLs to which the workloads of the namespace send traces and logs: those of the
+// service of the shard of the namespace if the telemetry-proxy is sharded, the given default ones otherwise
+func OtlpServiceUrls(shardsConfig *ShardsConfig, namespace string, defaultTracesUrl string, defaultLogsUrl string) (string, string) {
+	if shardsConfig == nil {
+		return defaultTracesUrl, defaultLogsUrl
+	}
+
+	serviceUrl := fmt.Sprintf("http://%s.%s.svc:%d", ShardName(shardsConfig.ShardOfNamespace(namespace)), shardsConfig.Namespace, Port)
+	return serviceUrl + "/v1/traces", serviceUrl + "/v1/logs"
+}
+
+// SyncTelemetryProxyShards makes each telemetry-proxy shard monitor the namespaces assigned to it among those
+// monitored by the telemetry-proxy running next to the controller, whose configurations are in the given file.
+// Shards with no namespace to monitor have no workloads to send telemetry to them, and are removed.
+func SyncTelemetryProxyShards(ctx context.Context, c client.Client, shardsConfig *ShardsConfig, namespaceConfigurationsPath string, log *logr.Logger) (bool, error) {
+	namespacesBytes, err := os.ReadFile(namespaceConfigurationsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("cannot read the namespace configurations file '%s': %w", namespaceConfigurationsPath, err)
+	}
+
+	var namespaces []json.RawMessage
+	if len(namespacesBytes) > 0 {
+		if err := json.Unmarshal(namespacesBytes, &namespaces); err != nil {
+			return false, fmt.Errorf("cannot unmarshal the namespace configurations file '%s': %w", namespaceConfigurationsPath, err)
+		}
+	}
+
+	namespacesByShard := make([][]json.RawMessage, shardsConfig.ShardCount)
+	for _, namespace := range namespaces {
+		namespaceName := struct {
+			Name string `json:"name"`
+		}{}
+		if err := json.Unmarshal(namespace, &namespaceName); err != nil {
+			return false, fmt.Errorf("cannot unmarshal the namespace configurations file '%s': %w", namespaceConfigurationsPath, err)
+		}
+
+		shard := shardsConfig.ShardOfNamespace(namespaceName.Name)
+		namespacesByShard[shard] = append(namespacesByShard[shard], namespace)
+	}
+
+	isChanged := false
+	for shard, shardNamespaces := range namespacesByShard {
+		var isShardChanged bool
+		if len(shardNamespaces) == 0 {
+			isShardChanged, err = removeShard(ctx, c, shardsConfig.Namespace, shard, log)
+		} else {
+			isShardChanged, err = upsertShard(ctx, c, shardsConfig, shard, shardNamespaces, log)
+		}
+		isChanged = isChanged || isShardChanged
+		if err != nil {
+			return isChanged, err
+		}
+	}
+
+	// The shards left over from when there were more of them
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(shardsConfig.Namespace), client.MatchingLabels(PodSelectorLabels())); err != nil {
+		return isChanged, fmt.Errorf("cannot list the telemetry-proxy shards: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		shard, err := strconv.Atoi(deployment.Labels[ShardLabelKey])
+		if err != nil || shard < shardsConfig.ShardCount {
+			continue
+		}
+
+		isShardRemoved, err := removeShard(ctx, c, shardsConfig.Namespace, shard, log)
+		isChanged = isChanged || isShardRemoved
+		if err != nil {
+			return isChanged, err
+		}
+	}
+
+	return isChanged, nil
+}
+
+func upsertShard(ctx context.Context, c client.Client, shardsConfig *ShardsConfig, shard int, namespaces []json.RawMessage, log *logr.Logger) (bool, error) {
+	name := ShardName(shard)
+
+	// The namespace configurations of the shard are sorted by name as those of the file are
+	namespacesBytes, err := json.Marshal(namespaces)
+	if err != nil {
+		return false, fmt.Errorf("cannot marshal the namespace configurations of the telemetry-proxy shard %d: %w", shard, err)
+	}
+
+	secret := &corev1.Secret{}
+	secretExists := true
+	if err := c.Get(ctx, types.NamespacedName{Namespace: shardsConfig.Namespace, Name: name}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the configuration secret of the telemetry-proxy shard %d: %w", shard, err)
+		}
+		secretExists = false
+	}
+
+	isChanged := false
+	if !bytes.Equal(secret.Data[namespacesSecretKey], namespacesBytes) {
+		secret.ObjectMeta.Namespace = shardsConfig.Namespace
+		secret.ObjectMeta.Name = name
+		secret.ObjectMeta.Labels = shardLabels(shard)
+		// The namespace configurations contain the Lumigo tokens, hence the secret
+		secret.Data = map[string][]byte{
+			namespacesSecretKey: namespacesBytes,
+		}
+
+		if secretExists {
+			err = c.Update(ctx, secret)
+		} else {
+			err = c.Create(ctx, secret)
+		}
+		if err != nil {
+			return false, fmt.Errorf("cannot write the configuration secret of the telemetry-proxy shard %d: %w", shard, err)
+		}
+
+		isChanged = true
+		log.Info("Updated the namespace configurations of the telemetry-proxy shard", "shard", shard)
+	}
+
+	isDeploymentChanged, err := upsertShardDeployment(ctx, c, shardsConfig, shard, log)
+	isChanged = isChanged || isDeploymentChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	isServiceChanged, err := upsertShardService(ctx, c, shardsConfig, shard, log)
+	isChanged = isChanged || isServiceChanged
+	if err != nil {
+		return isChanged, err
+	}
+
+	isPodDisruptionBudgetChanged, err := syncShardPodDisruptionBudget(ctx, c, shardsConfig, shard, log)
+	return isChanged || isPodDisruptionBudgetChanged, err
+}
+
+func upsertShardDeployment(ctx context.Context, c client.Client, shardsConfig *ShardsConfig, shard int, log *logr.Logger) (bool, error) {
+	desiredDeployment, err := newShardDeployment(shardsConfig, shard)
+	if err != nil {
+		return false, err
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(desiredDeployment), deployment); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the Deployment of the telemetry-proxy shard %d: %w", shard, err)
+		}
+
+		if err := c.Create(ctx, desiredDeployment); err != nil {
+			return false, fmt.Errorf("cannot create the Deployment of the telemetry-proxy shard %d: %w", shard, err)
+		}
+
+		log.Info("Created the telemetry-proxy shard", "shard", shard, "namespace", desiredDeployment.Namespace, "name", desiredDeployment.Name)
+		return true, nil
+	}
+
+	// As for the telemetry-proxy DaemonSet, the pods reload their namespace configurations when the secret
+	// changes, so they are rolled out only when the settings of the operator change
+	if deployment.Spec.Template.Annotations[templateChecksumAnnotation] == desiredDeployment.Spec.Template.Annotations[templateChecksumAnnotation] &&
+		deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == *desiredDeployment.Spec.Replicas {
+		return false, nil
+	}
+
+	deployment.ObjectMeta.Labels = desiredDeployment.ObjectMeta.Labels
+	deployment.Spec.Replicas = desiredDeployment.Spec.Replicas
+	deployment.Spec.Template = desiredDeployment.Spec.Template
+	if err := c.Update(ctx, deployment); err != nil {
+		return false, fmt.Errorf("cannot update the Deployment of the telemetry-proxy shard %d: %w", shard, err)
+	}
+
+	log.Info("Updated the telemetry-proxy shard", "shard", shard, "namespace", deployment.Namespace, "name", deployment.Name)
+	return true, nil
+}
+
+func upsertShardService(ctx context.Context, c client.Client, shardsConfig *ShardsConfig, shard int, log *logr.Logger) (bool, error) {
+	service := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: shardsConfig.Namespace, Name: ShardName(shard)}, service); err == nil {
+		// The ports and selector of the service never change
+		return false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("cannot retrieve the Service of the telemetry-proxy shard %d: %w", shard, err)
+	}
+
+	if err := c.Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: shardsConfig.Namespace,
+			Name:      ShardName(shard),
+			Labels:    shardLabels(shard),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: shardPodSelectorLabels(shard),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       otlpPortName,
+					Port:       Port,
+					TargetPort: intstr.FromString(otlpPortName),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}); err != nil {
+		return false, fmt.Errorf("cannot create the Service of the telemetry-proxy shard %d: %w", shard, err)
+	}
+
+	log.Info("Created the Service of the telemetry-proxy shard", "shard", shard)
+	return true, nil
+}
+
+// A shard with a single replica gets no PodDisruptionBudget, as it would block node drains altogether; with more
+// replicas, node drains evict them one at a time, so that the shard keeps receiving telemetry
+func syncShardPodDisruptionBudget(ctx context.Context, c client.Client, shardsConfig *ShardsConfig, shard int, log *logr.Logger) (bool, error) {
+	podDisruptionBudget := &policyv1.PodDisruptionBudget{}
+	podDisruptionBudgetExists := true
+	if err := c.Get(ctx, types.NamespacedName{Namespace: shardsConfig.Namespace, Name: ShardName(shard)}, podDisruptionBudget); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the PodDisruptionBudget of the telemetry-proxy shard %d: %w", shard, err)
+		}
+		podDisruptionBudgetExists = false
+	}
+
+	if !shardsConfig.PodDisruptionBudgetEnabled || shardsConfig.replicas() < 2 {
+		if !podDisruptionBudgetExists {
+			return false, nil
+		}
+
+		if err := c.Delete(ctx, podDisruptionBudget); err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot delete the PodDisruptionBudget of the telemetry-proxy shard %d: %w", shard, err)
+		}
+
+		log.Info("Deleted the PodDisruptionBudget of the telemetry-proxy shard", "shard", shard)
+		return true, nil
+	}
+
+	// The selector of the PodDisruptionBudget never changes
+	maxUnavailable := intstr.FromInt(1)
+	if podDisruptionBudgetExists && podDisruptionBudget.Spec.MaxUnavailable != nil && *podDisruptionBudget.Spec.MaxUnavailable == maxUnavailable {
+		return false, nil
+	}
+
+	podDisruptionBudget.ObjectMeta.Namespace = shardsConfig.Namespace
+	podDisruptionBudget.ObjectMeta.Name = ShardName(shard)
+	podDisruptionBudget.ObjectMeta.Labels = shardLabels(shard)
+	podDisruptionBudget.Spec = policyv1.PodDisruptionBudgetSpec{
+		MaxUnavailable: &maxUnavailable,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: shardPodSelectorLabels(shard),
+		},
+	}
+
+	var err error
+	if podDisruptionBudgetExists {
+		err = c.Update(ctx, podDisruptionBudget)
+	} else {
+		err = c.Create(ctx, podDisruptionBudget)
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot write the PodDisruptionBudget of the telemetry-proxy shard %d: %w", shard, err)
+	}
+
+	log.Info("Updated the PodDisruptionBudget of the telemetry-proxy shard", "shard", shard)
+	return true, nil
+}
+
+func removeShard(ctx context.Context, c client.Client, namespace string, shard int, log *logr.Logger) (bool, error) {
+	isChanged := false
+
+	objectMeta := metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      ShardName(shard),
+	}
+	for _, obj := range []client.Object{
+		&appsv1.Deployment{ObjectMeta: objectMeta},
+		&corev1.Service{ObjectMeta: objectMeta},
+		&corev1.Secret{ObjectMeta: objectMeta},
+		&policyv1.PodDisruptionBudget{ObjectMeta: objectMeta},
+	} {
+		if err := c.Delete(ctx, obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return isChanged, fmt.Errorf("cannot delete the resources of the telemetry-proxy shard %d: %w", shard, err)
+			}
+		} else {
+			isChanged = true
+		}
+	}
+
+	if isChanged {
+		log.Info("Deleted the telemetry-proxy shard, as no namespace is left to monitor in it", "shard", shard, "namespace", namespace, "name", objectMeta.Name)
+	}
+
+	return isChanged, nil
+}
+
+func newShardDeployment(shardsConfig *ShardsConfig, shard int) (*appsv1.Deployment, error) {
+	allowPrivilegeEscalation := false
+	runAsNonRoot := true
+	var runAsUser int64 = 1234
+	automountServiceAccountToken := true
+	labels := shardLabels(shard)
+
+	env := []corev1.EnvVar{
+		{
+			// Makes the telemetry-proxy leave the collection of cluster-wide telemetry, like
+			// Kubernetes events, to the telemetry-proxy running next to the controller
+			Name:  telemetryProxyModeEnvVar,
+			Value: telemetryProxyModeNode,
+		},
+	}
+	env = append(env, shardsConfig.Env...)
+
+	podSpec := corev1.PodSpec{
+		ServiceAccountName:           shardsConfig.ServiceAccountName,
+		AutomountServiceAccountToken: &automountServiceAccountToken,
+		Affinity: &corev1.Affinity{
+			PodAntiAffinity: newShardPodAntiAffinity(shardsConfig, shard),
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{
+									Key:      "kubernetes.io/os",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"linux"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot: &runAsNonRoot,
+			FSGroup:      &runAsUser,
+		},
+		Containers: []corev1.Container{
+			{
+				Name:      "telemetry-proxy",
+				Image:     shardsConfig.Image,
+				Env:       env,
+				Resources: shardsConfig.Resources,
+				Ports: []corev1.ContainerPort{
+					{
+						Name:          otlpPortName,
+						ContainerPort: Port,
+						Protocol:      corev1.ProtocolTCP,
+					},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+					Capabilities: &corev1.Capabilities{
+						Drop: []corev1.Capability{"ALL"},
+					},
+					RunAsNonRoot: &runAsNonRoot,
+					RunAsUser:    &runAsUser,
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{
+						Name:      otelcolConfigVolumeName,
+						MountPath: otelcolConfigMountPath,
+					},
+					{
+						Name:      namespacesVolumeName,
+						MountPath: namespacesMountPath,
+						ReadOnly:  true,
+					},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{
+			{
+				Name: otelcolConfigVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+			{
+				Name: namespacesVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: ShardName(shard),
+					},
+				},
+			},
+		},
+	}
+
+	// The checksum annotation rolls out the telemetry-proxy pods when the settings of the operator change
+	podSpecBytes, err := json.Marshal(podSpec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the pod spec of the telemetry-proxy shard %d: %w", shard, err)
+	}
+	checksum := sha256.Sum256(podSpecBytes)
+	replicas := shardsConfig.replicas()
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: shardsConfig.Namespace,
+			Name:      ShardName(shard),
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: shardPodSelectorLabels(shard),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						templateChecksumAnnotation: hex.EncodeToString(checksum[:]),
+					},
+				},
+				Spec: podSpec,
+			},
+		},
+	}, nil
+}
+
+// Spreads the replicas of the shard across nodes, so that a node going down does not take the whole shard with it
+func newShardPodAntiAffinity(shardsConfig *ShardsConfig, shard int) *corev1.PodAntiAffinity {
+	podAffinityTerm := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: shardPodSelectorLabels(shard),
+		},
+		TopologyKey: hostnameTopologyKey,
+	}
+
+	switch shardsConfig.PodAntiAffinity {
+	case PodAntiAffinityPreferred:
+		return &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight:          100,
+					PodAffinityTerm: podAffinityTerm,
+				},
+			},
+		}
+	case PodAntiAffinityRequired:
+		return &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{podAffinityTerm},
+		}
+	default:
+		return nil
+	}
+}
+
+// PodSelectorLabels returns the labels that select the pods of all the telemetry-proxy shards
+func PodSelectorLabels() map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      kubernetesAppNameLabelValue,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+	}
+}
+
+func shardPodSelectorLabels(shard int) map[string]string {
+	labels := PodSelectorLabels()
+	labels[ShardLabelKey] = strconv.Itoa(shard)
+	return labels
+}
+
+func shardLabels(shard int) map[string]string {
+	return map[string]string{
+		kubernetesAppNameLabelKey:      kubernetesAppNameLabelValue,
+		kubernetesAppComponentLabelKey: kubernetesAppComponentLabelValue,
+		kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+		ShardLabelKey:                  strconv.Itoa(shard),
+		// We do not need the operator to inject the telemetry-proxy
+		mutation.LumigoAutoTraceLabelKey: "false",
+	}
+}
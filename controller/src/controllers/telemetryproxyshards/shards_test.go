@@ -0,0 +1,276 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryproxyshards
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const operatorNamespace = "lumigo-system"
+
+var (
+	tempDir string
+	logger  logr.Logger
+)
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	tempDir = t.TempDir()
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Telemetry Proxy Shards Suite")
+}
+
+func writeNamespacesFile(content string) string {
+	namespacesFile := filepath.Join(tempDir, "namespaces_to_monitor.json")
+	Expect(os.WriteFile(namespacesFile, []byte(content), 0644)).To(Succeed())
+	return namespacesFile
+}
+
+func getDeployment(c client.Client, shard int) (*appsv1.Deployment, error) {
+	deployment := &appsv1.Deployment{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: ShardName(shard)}, deployment)
+	return deployment, err
+}
+
+func getSecret(c client.Client, shard int) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: ShardName(shard)}, secret)
+	return secret, err
+}
+
+func getService(c client.Client, shard int) (*corev1.Service, error) {
+	service := &corev1.Service{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: ShardName(shard)}, service)
+	return service, err
+}
+
+func getPodDisruptionBudget(c client.Client, shard int) (*policyv1.PodDisruptionBudget, error) {
+	podDisruptionBudget := &policyv1.PodDisruptionBudget{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: operatorNamespace, Name: ShardName(shard)}, podDisruptionBudget)
+	return podDisruptionBudget, err
+}
+
+var _ = Context("Telemetry-proxy shards", func() {
+
+	var c client.Client
+	var shardsConfig *ShardsConfig
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+		shardsConfig = &ShardsConfig{
+			Namespace:          operatorNamespace,
+			ServiceAccountName: "lumigo-kubernetes-operator",
+			Image:              "public.ecr.aws/lumigo/lumigo-kubernetes-telemetry-proxy:latest",
+			Env: []corev1.EnvVar{
+				{
+					Name:  "LUMIGO_ENDPOINT",
+					Value: "https://ga-otlp.lumigo-tracer-edge.golumigo.com",
+				},
+			},
+			ShardCount: 2,
+			Assignments: map[string]int{
+				"ns-a": 0,
+				"ns-b": 1,
+			},
+		}
+	})
+
+	It("assigns the namespaces to shards explicitly or by hash", func() {
+		Expect(shardsConfig.ShardOfNamespace("ns-a")).To(Equal(0))
+		Expect(shardsConfig.ShardOfNamespace("ns-b")).To(Equal(1))
+
+		shard := shardsConfig.ShardOfNamespace("ns-c")
+		Expect(shard).To(BeNumerically(">=", 0))
+		Expect(shard).To(BeNumerically("<", 2))
+		Expect(shardsConfig.ShardOfNamespace("ns-c")).To(Equal(shard))
+	})
+
+	It("rejects namespaces assigned to shards that do not exist", func() {
+		Expect(shardsConfig.Validate()).To(Succeed())
+
+		shardsConfig.Assignments["ns-c"] = 2
+		Expect(shardsConfig.Validate()).To(MatchError(ContainSubstring("'ns-c'")))
+
+		shardsConfig.ShardCount = 0
+		Expect(shardsConfig.Validate()).NotTo(Succeed())
+	})
+
+	It("rejects unknown pod anti-affinities", func() {
+		shardsConfig.PodAntiAffinity = PodAntiAffinityRequired
+		Expect(shardsConfig.Validate()).To(Succeed())
+
+		shardsConfig.PodAntiAffinity = "sometimes"
+		Expect(shardsConfig.Validate()).To(MatchError(ContainSubstring("'sometimes'")))
+	})
+
+	It("sends the telemetry of the namespaces to the service of their shard", func() {
+		tracesUrl, logsUrl := OtlpServiceUrls(shardsConfig, "ns-b", "http://lumigo-telemetry-proxy/v1/traces", "http://lumigo-telemetry-proxy/v1/logs")
+		Expect(tracesUrl).To(Equal("http://lumigo-telemetry-proxy-shard-1.lumigo-system.svc:4318/v1/traces"))
+		Expect(logsUrl).To(Equal("http://lumigo-telemetry-proxy-shard-1.lumigo-system.svc:4318/v1/logs"))
+
+		tracesUrl, logsUrl = OtlpServiceUrls(nil, "ns-b", "http://lumigo-telemetry-proxy/v1/traces", "http://lumigo-telemetry-proxy/v1/logs")
+		Expect(tracesUrl).To(Equal("http://lumigo-telemetry-proxy/v1/traces"))
+		Expect(logsUrl).To(Equal("http://lumigo-telemetry-proxy/v1/logs"))
+	})
+
+	It("deploys each shard with the configurations of its namespaces", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-a","uid":"123456","token":"t_123456"},{"name":"ns-b","uid":"654321","token":"t_654321"}]`)
+
+		isChanged, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getSecret(c, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(secret.Data[namespacesSecretKey])).To(Equal(`[{"name":"ns-a","uid":"123456","token":"t_123456"}]`))
+
+		secret, err = getSecret(c, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(secret.Data[namespacesSecretKey])).To(Equal(`[{"name":"ns-b","uid":"654321","token":"t_654321"}]`))
+
+		for _, shard := range []int{0, 1} {
+			deployment, err := getDeployment(c, shard)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue(ShardLabelKey, strconv.Itoa(shard)))
+
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal(shardsConfig.Image))
+			Expect(container.Env).To(ContainElement(corev1.EnvVar{
+				Name:  telemetryProxyModeEnvVar,
+				Value: telemetryProxyModeNode,
+			}))
+			Expect(container.Env).To(ContainElement(shardsConfig.Env[0]))
+			Expect(deployment.Spec.Template.Spec.Volumes[1].Secret.SecretName).To(Equal(ShardName(shard)))
+
+			service, err := getService(c, shard)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Spec.Selector).To(Equal(deployment.Spec.Selector.MatchLabels))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(Port)))
+		}
+
+		// Syncing again without changes is idempotent
+		isChanged, err = SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+	})
+
+	It("removes the shards with no namespace left to monitor", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-a","uid":"123456","token":"t_123456"},{"name":"ns-b","uid":"654321","token":"t_654321"}]`)
+
+		_, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		writeNamespacesFile(`[{"name":"ns-a","uid":"123456","token":"t_123456"}]`)
+		isChanged, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getDeployment(c, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = getDeployment(c, 1)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		_, err = getService(c, 1)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		_, err = getSecret(c, 1)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("protects the shards with more than one replica with PodDisruptionBudgets", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-a","uid":"123456","token":"t_123456"}]`)
+		shardsConfig.PodDisruptionBudgetEnabled = true
+		shardsConfig.PodAntiAffinity = PodAntiAffinityPreferred
+
+		_, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		// A PodDisruptionBudget would block the drains of the node of a single replica
+		_, err = getPodDisruptionBudget(c, 0)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		shardsConfig.Replicas = 2
+		isChanged, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		deployment, err := getDeployment(c, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+		podAffinityTerm := deployment.Spec.Template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+		Expect(podAffinityTerm.LabelSelector.MatchLabels).To(Equal(deployment.Spec.Selector.MatchLabels))
+		Expect(podAffinityTerm.TopologyKey).To(Equal("kubernetes.io/hostname"))
+
+		podDisruptionBudget, err := getPodDisruptionBudget(c, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podDisruptionBudget.Spec.MaxUnavailable.IntValue()).To(Equal(1))
+		Expect(podDisruptionBudget.Spec.Selector.MatchLabels).To(Equal(deployment.Spec.Selector.MatchLabels))
+
+		isChanged, err = SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		shardsConfig.Replicas = 1
+		isChanged, err = SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getPodDisruptionBudget(c, 0)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("removes the shards left over when the amount of shards decreases", func() {
+		namespacesFile := writeNamespacesFile(`[{"name":"ns-a","uid":"123456","token":"t_123456"},{"name":"ns-b","uid":"654321","token":"t_654321"}]`)
+
+		_, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		shardsConfig.ShardCount = 1
+		shardsConfig.Assignments = nil
+		isChanged, err := SyncTelemetryProxyShards(context.TODO(), c, shardsConfig, namespacesFile, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getSecret(c, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(secret.Data[namespacesSecretKey])).To(Equal(`[{"name":"ns-a","uid":"123456","token":"t_123456"},{"name":"ns-b","uid":"654321","token":"t_654321"}]`))
+
+		_, err = getDeployment(c, 1)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+})
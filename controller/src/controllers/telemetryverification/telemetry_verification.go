@@ -0,0 +1,266 @@
+package telemetryverification
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/selftelemetry"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	// The name of the Job that sends the synthetic trace in the namespace
+	JobName = "lumigo-telemetry-verification"
+	// The flag of the manager that makes it send the synthetic trace and exit, rather than run the controllers
+	VerifyTelemetryFlag = "verify-telemetry"
+	// How long after a failed verification it is run again
+	RetryPeriod = 10 * time.Minute
+
+	// The spans of the namespace that the telemetry-proxy had sent to Lumigo when the Job was created
+	baselineSpansAnnotationKey = "lumigo.io/telemetry-verification-baseline-spans"
+	// The generation of the Lumigo instance that the Job verifies
+	generationAnnotationKey = "lumigo.io/telemetry-verification-generation"
+	// How long, after the Job has sent the synthetic trace, the telemetry-proxy has to send it on to Lumigo
+	exportTimeout = 2 * time.Minute
+
+	endpointEnvVarName  = "LUMIGO_TELEMETRY_VERIFICATION_ENDPOINT"
+	namespaceEnvVarName = "LUMIGO_TELEMETRY_VERIFICATION_NAMESPACE"
+	tokenEnvVarName     = "LUMIGO_TRACER_TOKEN"
+	serviceName         = "lumigo-telemetry-verification"
+	containerName       = "verify-telemetry"
+
+	kubernetesAppNameLabelKey        = "app.kubernetes.io/name"
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+)
+
+// Config contains the settings of the operator that apply to the telemetry verification Jobs
+type Config struct {
+	// The image of the Job, i.e., the one of the controller, whose manager sends the synthetic trace
+	// when run with the VerifyTelemetryFlag flag
+	Image string
+}
+
+// IsEnabled returns whether the Lumigo instance requests its telemetry to be verified
+func IsEnabled(lumigo *operatorv1alpha1.Lumigo) bool {
+	enabled := lumigo.Spec.Tracing.Injection.TelemetryVerification.Enabled
+	return enabled != nil && *enabled
+}
+
+// IsDue returns whether the telemetry of the Lumigo instance has to be verified, i.e., it has never been, the
+// spec changed since it last was, or the last verification failed more than RetryPeriod ago
+func IsDue(lumigo *operatorv1alpha1.Lumigo, now time.Time) bool {
+	condition := conditions.GetLumigoConditionByType(lumigo, operatorv1alpha1.LumigoConditionTypeTelemetryVerified)
+	if condition == nil || lumigo.Status.TelemetryVerifiedGeneration != lumigo.Generation {
+		return true
+	}
+
+	return condition.Status != corev1.ConditionTrue && now.Sub(condition.LastUpdateTime.Time) >= RetryPeriod
+}
+
+// NewJob returns the Job that sends the synthetic trace of the namespace of the Lumigo instance to the given
+// traces endpoint of the telemetry-proxy, authenticated with the Lumigo token in the given secret; the count of
+// the spans of the namespace sent by the telemetry-proxy, against which the verification is made, is kept on it.
+func NewJob(config *Config, lumigo *operatorv1alpha1.Lumigo, endpoint string, tokenSecretRef *operatorv1alpha1.KubernetesSecretRef, baselineSentSpans int64) *batchv1.Job {
+	labels := map[string]string{
+		kubernetesAppNameLabelKey:      JobName,
+		kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+		// The synthetic trace is sent by the Job itself, rather than by an injected Lumigo distro
+		mutation.LumigoAutoTraceLabelKey: "false",
+	}
+	isController := true
+	backoffLimit := int32(2)
+	activeDeadlineSeconds := int64(exportTimeout.Seconds())
+	ttlSecondsAfterFinished := int32(time.Hour.Seconds())
+	runAsNonRoot := true
+	allowPrivilegeEscalation := false
+	automountServiceAccountToken := false
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: lumigo.Namespace,
+			Name:      JobName,
+			Labels:    labels,
+			Annotations: map[string]string{
+				baselineSpansAnnotationKey: strconv.FormatInt(baselineSentSpans, 10),
+				generationAnnotationKey:    strconv.FormatInt(lumigo.Generation, 10),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: operatorv1alpha1.GroupVersion.String(),
+					Kind:       "Lumigo",
+					Name:       lumigo.Name,
+					UID:        lumigo.UID,
+					Controller: &isController,
+				},
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &activeDeadlineSeconds,
+			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: &automountServiceAccountToken,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: &runAsNonRoot,
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:    containerName,
+							Image:   config.Image,
+							Command: []string{"/manager", "--" + VerifyTelemetryFlag},
+							Env: []corev1.EnvVar{
+								{
+									Name:  endpointEnvVarName,
+									Value: endpoint,
+								},
+								{
+									Name: tokenEnvVarName,
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{
+											LocalObjectReference: corev1.LocalObjectReference{
+												Name: tokenSecretRef.Name,
+											},
+											Key: tokenSecretRef.Key,
+										},
+									},
+								},
+								{
+									Name: namespaceEnvVarName,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.namespace",
+										},
+									},
+								},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{"ALL"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// GetJob returns the verification Job of the namespace, or nil if there is none
+func GetJob(ctx context.Context, c client.Client, namespace string) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: JobName}, job); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot get the telemetry verification Job in namespace '%s': %w", namespace, err)
+	}
+
+	return job, nil
+}
+
+// DeleteJob deletes the verification Job of the namespace and its pod, if any
+func DeleteJob(ctx context.Context, c client.Client, namespace string) (bool, error) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      JobName,
+		},
+	}
+	if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot delete the telemetry verification Job in namespace '%s': %w", namespace, err)
+	}
+
+	return true, nil
+}
+
+// GetVerifiedGeneration returns the generation of the Lumigo instance that the Job verifies
+func GetVerifiedGeneration(job *batchv1.Job) int64 {
+	generation, _ := strconv.ParseInt(job.Annotations[generationAnnotationKey], 10, 64)
+	return generation
+}
+
+// Evaluate returns whether the verification of the Job is over and, if so, whether the telemetry is verified,
+// with a message for the TelemetryVerified condition. The telemetry is verified once the spans of the namespace
+// sent to Lumigo by the telemetry-proxy, as of the latest scrape of its metrics, exceed the baseline of the Job;
+// fewer spans than the baseline mean that the telemetry-proxy restarted, and counts them from zero.
+func Evaluate(job *batchv1.Job, sentSpans int64, now time.Time) (bool, bool, string) {
+	if condition := getJobCondition(job, batchv1.JobFailed); condition != nil {
+		return true, false, fmt.Sprintf("The synthetic trace could not be sent to the telemetry-proxy, see the logs of the '%s' Job: %s", JobName, condition.Message)
+	}
+
+	completion := getJobCondition(job, batchv1.JobComplete)
+	if completion == nil {
+		return false, false, ""
+	}
+
+	baselineSentSpans, _ := strconv.ParseInt(job.Annotations[baselineSpansAnnotationKey], 10, 64)
+	if sentSpans > baselineSentSpans || (sentSpans < baselineSentSpans && sentSpans > 0) {
+		return true, true, "The synthetic trace has been sent to Lumigo by the telemetry-proxy"
+	}
+
+	if now.Sub(completion.LastTransitionTime.Time) < exportTimeout {
+		return false, false, ""
+	}
+
+	return true, false, fmt.Sprintf("The telemetry-proxy has not sent the synthetic trace to Lumigo within %s; check the Lumigo token and the endpoint, and the '%s' condition", exportTimeout, operatorv1alpha1.LumigoConditionTypeTelemetryExportDegraded)
+}
+
+func getJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		if condition := &job.Status.Conditions[i]; condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return condition
+		}
+	}
+
+	return nil
+}
+
+// SendSyntheticTrace sends a trace of the given namespace, made of a single span, to the given traces endpoint of
+// the telemetry-proxy; it is what the verification Job runs.
+func SendSyntheticTrace(ctx context.Context, endpoint string, token string, namespace string, log logr.Logger) error {
+	tracer := selftelemetry.NewTracer(endpoint, token, serviceName, log).WithResourceAttributes(selftelemetry.String("k8s.namespace.name", namespace))
+
+	_, span := tracer.StartSpan(ctx, "lumigo.telemetry-verification", selftelemetry.String("lumigo.synthetic", "true"))
+	span.End()
+
+	return tracer.Flush(ctx)
+}
+
+// SendSyntheticTraceFromEnv sends the synthetic trace with the settings in the environment variables of the Job
+func SendSyntheticTraceFromEnv(ctx context.Context, getenv func(string) string, log logr.Logger) error {
+	endpoint := getenv(endpointEnvVarName)
+	if len(endpoint) < 1 {
+		return fmt.Errorf("the '%s' environment variable is not set", endpointEnvVarName)
+	}
+
+	return SendSyntheticTrace(ctx, endpoint, getenv(tokenEnvVarName), getenv(namespaceEnvVarName), log)
+}
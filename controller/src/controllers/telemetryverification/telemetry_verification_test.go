@@ -0,0 +1,187 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package telemetryverification
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+var logger logr.Logger
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Telemetry Verification Suite")
+}
+
+var _ = Context("Telemetry verification", func() {
+
+	var lumigo *operatorv1alpha1.Lumigo
+	now := time.Now()
+
+	BeforeEach(func() {
+		enabled := true
+		lumigo = &operatorv1alpha1.Lumigo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "my-namespace",
+				Name:       "lumigo",
+				UID:        "lumigo-uid",
+				Generation: 2,
+			},
+			Spec: operatorv1alpha1.LumigoSpec{
+				Tracing: operatorv1alpha1.TracingSpec{
+					Injection: operatorv1alpha1.InjectionSpec{
+						TelemetryVerification: operatorv1alpha1.TelemetryVerificationSpec{
+							Enabled: &enabled,
+						},
+					},
+				},
+			},
+		}
+	})
+
+	completedJob := func(baselineSentSpans int64, completionTime time.Time) *batchv1.Job {
+		job := NewJob(&Config{Image: "controller:latest"}, lumigo, "http://localhost:4318/v1/traces", &operatorv1alpha1.KubernetesSecretRef{Name: "lumigo-credentials", Key: "token"}, baselineSentSpans)
+		job.Status.Conditions = []batchv1.JobCondition{
+			{
+				Type:               batchv1.JobComplete,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(completionTime),
+			},
+		}
+		return job
+	}
+
+	It("is due until the current generation has been verified, and again a while after a failure", func() {
+		Expect(IsDue(lumigo, now)).To(BeTrue())
+
+		conditions.SetTelemetryVerifiedCondition(lumigo, metav1.NewTime(now), true, "")
+		lumigo.Status.TelemetryVerifiedGeneration = 1
+		Expect(IsDue(lumigo, now)).To(BeTrue())
+
+		lumigo.Status.TelemetryVerifiedGeneration = 2
+		Expect(IsDue(lumigo, now.Add(RetryPeriod))).To(BeFalse())
+
+		conditions.SetTelemetryVerifiedCondition(lumigo, metav1.NewTime(now), false, "")
+		Expect(IsDue(lumigo, now.Add(time.Minute))).To(BeFalse())
+		Expect(IsDue(lumigo, now.Add(RetryPeriod))).To(BeTrue())
+	})
+
+	It("creates a Job owned by the Lumigo instance and not injected", func() {
+		c := fake.NewClientBuilder().Build()
+		job := NewJob(&Config{Image: "controller:latest"}, lumigo, "http://localhost:4318/v1/traces", &operatorv1alpha1.KubernetesSecretRef{Name: "lumigo-credentials", Key: "token"}, 10)
+		Expect(c.Create(context.TODO(), job)).To(Succeed())
+
+		job, err := GetJob(context.TODO(), c, "my-namespace")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.OwnerReferences).To(HaveLen(1))
+		Expect(job.OwnerReferences[0].UID).To(BeEquivalentTo("lumigo-uid"))
+		Expect(job.Spec.Template.Labels).To(HaveKeyWithValue(mutation.LumigoAutoTraceLabelKey, "false"))
+		Expect(job.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{"/manager", "--verify-telemetry"}))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(HaveField("ValueFrom.SecretKeyRef.Key", "token")))
+		Expect(GetVerifiedGeneration(job)).To(Equal(int64(2)))
+
+		Expect(DeleteJob(context.TODO(), c, "my-namespace")).To(BeTrue())
+		Expect(GetJob(context.TODO(), c, "my-namespace")).To(BeNil())
+		Expect(DeleteJob(context.TODO(), c, "my-namespace")).To(BeFalse())
+	})
+
+	It("verifies the telemetry once the telemetry-proxy has sent more spans of the namespace", func() {
+		job := completedJob(10, now)
+
+		isDone, _, _ := Evaluate(job, 10, now.Add(time.Minute))
+		Expect(isDone).To(BeFalse())
+
+		isDone, isVerified, _ := Evaluate(job, 11, now.Add(time.Minute))
+		Expect(isDone).To(BeTrue())
+		Expect(isVerified).To(BeTrue())
+
+		// The telemetry-proxy restarted in the meantime
+		isDone, isVerified, _ = Evaluate(job, 1, now.Add(time.Minute))
+		Expect(isDone).To(BeTrue())
+		Expect(isVerified).To(BeTrue())
+	})
+
+	It("fails the verification if the telemetry-proxy does not send the synthetic trace in time", func() {
+		isDone, isVerified, message := Evaluate(completedJob(10, now), 10, now.Add(exportTimeout))
+		Expect(isDone).To(BeTrue())
+		Expect(isVerified).To(BeFalse())
+		Expect(message).To(ContainSubstring("Lumigo token"))
+	})
+
+	It("fails the verification if the Job fails", func() {
+		job := completedJob(10, now)
+		job.Status.Conditions = []batchv1.JobCondition{
+			{
+				Type:    batchv1.JobFailed,
+				Status:  corev1.ConditionTrue,
+				Message: "Job has reached the specified backoff limit",
+			},
+		}
+
+		isDone, isVerified, message := Evaluate(job, 10, now)
+		Expect(isDone).To(BeTrue())
+		Expect(isVerified).To(BeFalse())
+		Expect(message).To(ContainSubstring("backoff limit"))
+	})
+
+	It("sends a synthetic trace of the namespace", func() {
+		var body string
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bytes, _ := io.ReadAll(r.Body)
+			body = string(bytes)
+			authorization = r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+
+		env := map[string]string{
+			endpointEnvVarName:  server.URL + "/v1/traces",
+			tokenEnvVarName:     "t_1234567890123456789AB",
+			namespaceEnvVarName: "my-namespace",
+		}
+		Expect(SendSyntheticTraceFromEnv(context.TODO(), func(name string) string { return env[name] }, logger)).To(Succeed())
+
+		Expect(authorization).To(Equal("LumigoToken t_1234567890123456789AB"))
+		Expect(body).To(ContainSubstring(`{"key":"k8s.namespace.name","value":{"stringValue":"my-namespace"}}`))
+		Expect(body).To(ContainSubstring("lumigo.telemetry-verification"))
+	})
+
+	It("does not send the synthetic trace without an endpoint", func() {
+		Expect(SendSyntheticTraceFromEnv(context.TODO(), func(string) string { return "" }, logger)).NotTo(Succeed())
+	})
+})
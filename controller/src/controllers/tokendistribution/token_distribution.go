@@ -0,0 +1,194 @@
+package tokendistribution
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	// The label of the copies of the central token secret, which tells them apart from the secrets created by users
+	TokenSecretCopyLabelKey   = "lumigo.io/central-token-copy"
+	TokenSecretCopyLabelValue = "true"
+
+	// The annotation of the copies of the central token secret with the namespace and name of the central one
+	TokenSecretCopySourceAnnotationKey = "lumigo.io/central-token-secret"
+
+	// The label that HNC, the hierarchical namespace controller, sets on the objects it propagates from an
+	// ancestor namespace into its descendants, with the name of the ancestor namespace
+	InheritedFromLabelKey = "hnc.x-k8s.io/inherited-from"
+
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+)
+
+// CentralTokenSecretConfig identifies the secret with the Lumigo token that is the source of truth for the
+// namespaces whose Lumigo instances reference a token secret that does not exist
+type CentralTokenSecretConfig struct {
+	// The namespace of the central secret, usually the one the operator runs in
+	Namespace string
+	// The name of the central secret
+	Name string
+	// The key of the Lumigo token in the central secret
+	Key string
+}
+
+// GetTokenSecretSource returns the secret the token secret of the Lumigo instance is copied from if it does
+// not exist in the namespace: for the Lumigo instances propagated by HNC, the token secret of the namespace they
+// are inherited from, so that the descendant namespaces do not need a copy of their own; otherwise, the central
+// token secret, which is nil if not configured.
+func GetTokenSecretSource(lumigo *operatorv1alpha1.Lumigo, centralConfig *CentralTokenSecretConfig) *CentralTokenSecretConfig {
+	if ancestorNamespaceName := GetInheritedFrom(lumigo); len(ancestorNamespaceName) > 0 {
+		return &CentralTokenSecretConfig{
+			Namespace: ancestorNamespaceName,
+			Name:      lumigo.Spec.LumigoToken.SecretRef.Name,
+			Key:       lumigo.Spec.LumigoToken.SecretRef.Key,
+		}
+	}
+
+	return centralConfig
+}
+
+// GetInheritedFrom returns the namespace from which HNC propagated the given object, or an empty string
+// if the object has not been propagated.
+func GetInheritedFrom(obj client.Object) string {
+	return obj.GetLabels()[InheritedFromLabelKey]
+}
+
+// IsCentralTokenSecret returns whether the given secret is the central token secret.
+func (config *CentralTokenSecretConfig) IsCentralTokenSecret(secret client.Object) bool {
+	return secret.GetNamespace() == config.Namespace && secret.GetName() == config.Name
+}
+
+// SyncTokenSecretCopyOfNamespace ensures that the secret with the given name in the given namespace has the
+// Lumigo token of the central secret under the given key, creating a read-only copy if the secret does not
+// exist and replacing the copy when the central token is rotated. A secret with the given name that is not
+// a copy of the central one is left untouched, and the copies with other names in the namespace, e.g., after
+// the Lumigo instance references another secret, are removed.
+func SyncTokenSecretCopyOfNamespace(ctx context.Context, c client.Client, config *CentralTokenSecretConfig, namespaceName string, secretName string, secretKey string, log *logr.Logger) (bool, error) {
+	isRemoved, err := removeTokenSecretCopies(ctx, c, namespaceName, secretName, log)
+	if err != nil {
+		return isRemoved, err
+	}
+
+	centralSecret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: config.Namespace, Name: config.Name}, centralSecret); err != nil {
+		return isRemoved, fmt.Errorf("cannot retrieve the central token secret '%s/%s': %w", config.Namespace, config.Name, err)
+	}
+
+	token, ok := centralSecret.Data[config.Key]
+	if !ok {
+		return isRemoved, fmt.Errorf("the central token secret '%s/%s' does not have the key '%s'", config.Namespace, config.Name, config.Key)
+	}
+
+	desiredSecret := newTokenSecretCopy(config, namespaceName, secretName, secretKey, token)
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: secretName}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return isRemoved, fmt.Errorf("cannot retrieve the '%s' secret in namespace '%s': %w", secretName, namespaceName, err)
+		}
+
+		if err := c.Create(ctx, desiredSecret); err != nil {
+			return isRemoved, fmt.Errorf("cannot create the '%s' copy of the central token secret in namespace '%s': %w", secretName, namespaceName, err)
+		}
+
+		log.Info("Created copy of the central token secret", "namespace", namespaceName, "name", secretName)
+		return true, nil
+	}
+
+	if !isTokenSecretCopy(secret) {
+		// The namespace has its own token secret
+		return isRemoved, nil
+	}
+
+	if string(secret.Data[secretKey]) == string(token) && len(secret.Data) == 1 {
+		return isRemoved, nil
+	}
+
+	// The copies are immutable, so they are replaced rather than updated
+	if err := c.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return isRemoved, fmt.Errorf("cannot delete the outdated '%s' copy of the central token secret in namespace '%s': %w", secretName, namespaceName, err)
+	}
+
+	if err := c.Create(ctx, desiredSecret); err != nil {
+		return true, fmt.Errorf("cannot create the '%s' copy of the central token secret in namespace '%s': %w", secretName, namespaceName, err)
+	}
+
+	log.Info("Replaced copy of the central token secret", "namespace", namespaceName, "name", secretName)
+	return true, nil
+}
+
+// RemoveTokenSecretCopiesOfNamespace removes the copies of the central token secret in the given namespace.
+func RemoveTokenSecretCopiesOfNamespace(ctx context.Context, c client.Client, namespaceName string, log *logr.Logger) (bool, error) {
+	return removeTokenSecretCopies(ctx, c, namespaceName, "", log)
+}
+
+// Removes the copies of the central token secret in the given namespace, except the one with the given name
+func removeTokenSecretCopies(ctx context.Context, c client.Client, namespaceName string, exceptSecretName string, log *logr.Logger) (bool, error) {
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace(namespaceName), client.MatchingLabels{
+		kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+		TokenSecretCopyLabelKey:        TokenSecretCopyLabelValue,
+	}); err != nil {
+		return false, fmt.Errorf("cannot list the copies of the central token secret in namespace '%s': %w", namespaceName, err)
+	}
+
+	isChanged := false
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Name == exceptSecretName {
+			continue
+		}
+
+		if err := c.Delete(ctx, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return isChanged, fmt.Errorf("cannot delete the '%s' copy of the central token secret in namespace '%s': %w", secret.Name, namespaceName, err)
+		}
+
+		log.Info("Deleted copy of the central token secret", "namespace", namespaceName, "name", secret.Name)
+		isChanged = true
+	}
+
+	return isChanged, nil
+}
+
+func isTokenSecretCopy(secret *corev1.Secret) bool {
+	return secret.Labels[kubernetesAppManagedByLabelKey] == kubernetesAppManagedByLabelValue && secret.Labels[TokenSecretCopyLabelKey] == TokenSecretCopyLabelValue
+}
+
+func newTokenSecretCopy(config *CentralTokenSecretConfig, namespaceName string, secretName string, secretKey string, token []byte) *corev1.Secret {
+	immutable := true
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      secretName,
+			Labels: map[string]string{
+				kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+				kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+				TokenSecretCopyLabelKey:        TokenSecretCopyLabelValue,
+			},
+			Annotations: map[string]string{
+				TokenSecretCopySourceAnnotationKey: fmt.Sprintf("%s/%s", config.Namespace, config.Name),
+			},
+		},
+		Type:      corev1.SecretTypeOpaque,
+		Immutable: &immutable,
+		Data: map[string][]byte{
+			secretKey: token,
+		},
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokendistribution
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	operatorNamespace = "lumigo-system"
+	namespaceName     = "my-namespace"
+)
+
+var logger logr.Logger
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Token Distribution Suite")
+}
+
+func getSecret(c client.Client, namespace string, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, secret)
+	return secret, err
+}
+
+func setCentralToken(c client.Client, token string) {
+	secret, err := getSecret(c, operatorNamespace, "lumigo-central-token")
+	if apierrors.IsNotFound(err) {
+		Expect(c.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: operatorNamespace,
+				Name:      "lumigo-central-token",
+			},
+			Data: map[string][]byte{
+				"token": []byte(token),
+			},
+		})).To(Succeed())
+		return
+	}
+
+	Expect(err).NotTo(HaveOccurred())
+	secret.Data["token"] = []byte(token)
+	Expect(c.Update(context.TODO(), secret)).To(Succeed())
+}
+
+var _ = Context("Token distribution", func() {
+
+	var c client.Client
+	var config *CentralTokenSecretConfig
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+		config = &CentralTokenSecretConfig{
+			Namespace: operatorNamespace,
+			Name:      "lumigo-central-token",
+			Key:       "token",
+		}
+		setCentralToken(c, "t_123456789012345678901")
+	})
+
+	It("copies the central token secret into the namespace", func() {
+		isChanged, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "lumigo-token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getSecret(c, namespaceName, "lumigo-credentials")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Labels).To(HaveKeyWithValue(TokenSecretCopyLabelKey, TokenSecretCopyLabelValue))
+		Expect(secret.Annotations).To(HaveKeyWithValue(TokenSecretCopySourceAnnotationKey, "lumigo-system/lumigo-central-token"))
+		Expect(*secret.Immutable).To(BeTrue())
+		Expect(secret.Data).To(Equal(map[string][]byte{"lumigo-token": []byte("t_123456789012345678901")}))
+
+		isChanged, err = SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "lumigo-token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+	})
+
+	It("replaces the copy when the central token is rotated", func() {
+		_, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		setCentralToken(c, "t_abcdefabcdefabcdefabc")
+
+		isChanged, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getSecret(c, namespaceName, "lumigo-credentials")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("token", []byte("t_abcdefabcdefabcdefabc")))
+	})
+
+	It("leaves alone the token secrets of the namespace", func() {
+		Expect(c.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespaceName,
+				Name:      "lumigo-credentials",
+			},
+			Data: map[string][]byte{
+				"token": []byte("t_000000000000000000000"),
+			},
+		})).To(Succeed())
+
+		isChanged, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		secret, err := getSecret(c, namespaceName, "lumigo-credentials")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("token", []byte("t_000000000000000000000")))
+
+		isChanged, err = RemoveTokenSecretCopiesOfNamespace(context.TODO(), c, namespaceName, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		_, err = getSecret(c, namespaceName, "lumigo-credentials")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("removes the copies that are no longer referenced", func() {
+		_, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "other-credentials", "token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = getSecret(c, namespaceName, "lumigo-credentials")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		isChanged, err := RemoveTokenSecretCopiesOfNamespace(context.TODO(), c, namespaceName, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getSecret(c, namespaceName, "other-credentials")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("copies the token secret of the namespace a Lumigo instance propagated by HNC is inherited from", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespaceName,
+				Name:      "lumigo",
+				Labels: map[string]string{
+					InheritedFromLabelKey: "my-parent-namespace",
+				},
+			},
+			Spec: operatorv1alpha1.LumigoSpec{
+				LumigoToken: operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: "lumigo-credentials",
+						Key:  "token",
+					},
+				},
+			},
+		}
+		Expect(c.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-parent-namespace",
+				Name:      "lumigo-credentials",
+			},
+			Data: map[string][]byte{
+				"token": []byte("t_abcdefabcdefabcdefabc"),
+			},
+		})).To(Succeed())
+
+		source := GetTokenSecretSource(lumigo, config)
+		Expect(source).To(Equal(&CentralTokenSecretConfig{Namespace: "my-parent-namespace", Name: "lumigo-credentials", Key: "token"}))
+
+		_, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, source, namespaceName, "lumigo-credentials", "token", &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret, err := getSecret(c, namespaceName, "lumigo-credentials")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Annotations).To(HaveKeyWithValue(TokenSecretCopySourceAnnotationKey, "my-parent-namespace/lumigo-credentials"))
+		Expect(secret.Data).To(Equal(map[string][]byte{"token": []byte("t_abcdefabcdefabcdefabc")}))
+
+		// The Lumigo instances created in the namespace itself use the central token secret
+		delete(lumigo.Labels, InheritedFromLabelKey)
+		Expect(GetTokenSecretSource(lumigo, config)).To(Equal(config))
+	})
+
+	It("fails if the central token secret does not have the token", func() {
+		config.Key = "missing"
+
+		_, err := SyncTokenSecretCopyOfNamespace(context.TODO(), c, config, namespaceName, "lumigo-credentials", "token", &logger)
+		Expect(err).To(MatchError(ContainSubstring("does not have the key 'missing'")))
+	})
+})
@@ -0,0 +1,212 @@
+package tokensecrets
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	kubernetesAppPartOfLabelKey      = "app.kubernetes.io/part-of"
+	kubernetesAppPartOfLabelValue    = "lumigo"
+	kubernetesAppManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	kubernetesAppManagedByLabelValue = "lumigo-operator"
+)
+
+// The general shape of Lumigo tokens
+var tokenRegexp = regexp.MustCompile(`^t_[[:xdigit:]]{21}$`)
+
+// IsWellFormedToken returns whether the given Lumigo token has the general shape of Lumigo tokens,
+// that is, `t_` followed by 21 hexadecimal characters.
+func IsWellFormedToken(token string) bool {
+	return tokenRegexp.MatchString(token)
+}
+
+// UpsertTokenSecretOfNamespace makes the secret that is projected into the containers injected with
+// Lumigo in the `ProjectedSecret` token injection mode, or that they reference for the tokens set as values,
+// contain the given Lumigo token, and the tokens of the routes of `.spec.tracing.routes` by route name.
+func UpsertTokenSecretOfNamespace(ctx context.Context, c client.Client, namespaceName string, token string, routeTokens map[string]string, log *logr.Logger) (bool, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: mutation.LumigoTracerTokenSecretName}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot retrieve the '%s' secret in namespace '%s': %w", mutation.LumigoTracerTokenSecretName, namespaceName, err)
+		}
+
+		if err := c.Create(ctx, newTokenSecret(namespaceName, token, routeTokens)); err != nil {
+			return false, fmt.Errorf("cannot create the '%s' secret in namespace '%s': %w", mutation.LumigoTracerTokenSecretName, namespaceName, err)
+		}
+
+		log.Info("Created the Lumigo token secret", "namespace", namespaceName, "name", mutation.LumigoTracerTokenSecretName)
+		return true, nil
+	}
+
+	desiredSecret := newTokenSecret(namespaceName, token, routeTokens)
+	if reflect.DeepEqual(secret.Data, desiredSecret.Data) {
+		return false, nil
+	}
+
+	secret.ObjectMeta.Labels = desiredSecret.ObjectMeta.Labels
+	secret.Type = desiredSecret.Type
+	secret.Data = desiredSecret.Data
+	if err := c.Update(ctx, secret); err != nil {
+		return false, fmt.Errorf("cannot update the '%s' secret in namespace '%s': %w", mutation.LumigoTracerTokenSecretName, namespaceName, err)
+	}
+
+	log.Info("Updated the Lumigo token secret", "namespace", namespaceName, "name", mutation.LumigoTracerTokenSecretName)
+	return true, nil
+}
+
+// RemoveTokenSecretOfNamespace removes the secret with the Lumigo token that is projected into the
+// containers injected with Lumigo in the `ProjectedSecret` token injection mode.
+func RemoveTokenSecretOfNamespace(ctx context.Context, c client.Client, namespaceName string, log *logr.Logger) (bool, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: mutation.LumigoTracerTokenSecretName}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot retrieve the '%s' secret in namespace '%s': %w", mutation.LumigoTracerTokenSecretName, namespaceName, err)
+	}
+
+	// Never delete a secret with the same name that the operator has not created
+	if secret.Labels[kubernetesAppManagedByLabelKey] != kubernetesAppManagedByLabelValue {
+		return false, nil
+	}
+
+	if err := c.Delete(ctx, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot delete the '%s' secret in namespace '%s': %w", mutation.LumigoTracerTokenSecretName, namespaceName, err)
+	}
+
+	log.Info("Deleted the Lumigo token secret", "namespace", namespaceName, "name", mutation.LumigoTracerTokenSecretName)
+	return true, nil
+}
+
+// GetCachedToken returns the Lumigo token last written by UpsertTokenSecretOfNamespace, which is used in
+// place of the one of the Lumigo instance if its secret is deleted, with the `KeepInjecting` token missing policy.
+func GetCachedToken(ctx context.Context, c client.Client, namespaceName string) (string, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: mutation.LumigoTracerTokenSecretName}, secret); err != nil {
+		return "", fmt.Errorf("cannot retrieve the '%s' secret in namespace '%s': %w", mutation.LumigoTracerTokenSecretName, namespaceName, err)
+	}
+
+	// Only the secrets created by the operator hold a token known to be valid
+	if secret.Labels[kubernetesAppManagedByLabelKey] != kubernetesAppManagedByLabelValue {
+		return "", fmt.Errorf("the '%s' secret in namespace '%s' is not managed by the Lumigo operator", mutation.LumigoTracerTokenSecretName, namespaceName)
+	}
+
+	token := secret.Data[mutation.LumigoTracerTokenSecretKey]
+	if len(token) < 1 {
+		return "", fmt.Errorf("the '%s' secret in namespace '%s' has no Lumigo token", mutation.LumigoTracerTokenSecretName, namespaceName)
+	}
+
+	return string(token), nil
+}
+
+// IsUsingCachedToken returns whether the resources are injected with the cached Lumigo token, because the
+// secret of the Lumigo instance is missing and its token missing policy is `KeepInjecting`.
+func IsUsingCachedToken(lumigo *operatorv1alpha1.Lumigo) bool {
+	return lumigo.Spec.Tracing.Injection.TokenMissingPolicy == operatorv1alpha1.TokenMissingPolicyKeepInjecting && conditions.IsTokenMissing(lumigo)
+}
+
+// SpecWithCachedToken returns the given spec of the Lumigo instance with its Lumigo token referencing the
+// cached one, if IsUsingCachedToken; the given spec is not changed.
+func SpecWithCachedToken(lumigo *operatorv1alpha1.Lumigo, spec *operatorv1alpha1.LumigoSpec) *operatorv1alpha1.LumigoSpec {
+	if !IsUsingCachedToken(lumigo) {
+		return spec
+	}
+
+	specWithCachedToken := spec.DeepCopy()
+	specWithCachedToken.LumigoToken.SecretRef = operatorv1alpha1.KubernetesSecretRef{
+		Name: mutation.LumigoTracerTokenSecretName,
+		Key:  mutation.LumigoTracerTokenSecretKey,
+	}
+
+	return specWithCachedToken
+}
+
+// HasTokenValues returns whether the Lumigo token of the spec, or of one of its routes, is set as a value
+// rather than referencing a secret, in which case the token secret of the namespace is needed to inject it.
+func HasTokenValues(spec *operatorv1alpha1.LumigoSpec) bool {
+	if len(spec.LumigoToken.Value) > 0 {
+		return true
+	}
+
+	for _, route := range spec.Tracing.Routes {
+		if len(route.LumigoToken.Value) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SpecWithTokenValuesInSecret returns the given spec of the Lumigo instance with the Lumigo tokens set as values
+// referencing their copies in the token secret of the namespace, so that the values are never injected in clear
+// into the containers; the given spec is not changed.
+func SpecWithTokenValuesInSecret(spec *operatorv1alpha1.LumigoSpec) *operatorv1alpha1.LumigoSpec {
+	if !HasTokenValues(spec) {
+		return spec
+	}
+
+	specWithTokenSecret := spec.DeepCopy()
+	if len(specWithTokenSecret.LumigoToken.Value) > 0 {
+		specWithTokenSecret.LumigoToken = operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: mutation.LumigoTracerTokenSecretName,
+				Key:  mutation.LumigoTracerTokenSecretKey,
+			},
+		}
+	}
+
+	for i := range specWithTokenSecret.Tracing.Routes {
+		route := &specWithTokenSecret.Tracing.Routes[i]
+		if len(route.LumigoToken.Value) > 0 {
+			route.LumigoToken = operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: mutation.LumigoTracerTokenSecretName,
+					Key:  mutation.LumigoTracerTokenSecretKeyOfRoute(route.Name),
+				},
+			}
+		}
+	}
+
+	return specWithTokenSecret
+}
+
+func newTokenSecret(namespaceName string, token string, routeTokens map[string]string) *corev1.Secret {
+	data := map[string][]byte{
+		mutation.LumigoTracerTokenSecretKey: []byte(token),
+	}
+	for routeName, routeToken := range routeTokens {
+		data[mutation.LumigoTracerTokenSecretKeyOfRoute(routeName)] = []byte(routeToken)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespaceName,
+			Name:      mutation.LumigoTracerTokenSecretName,
+			Labels: map[string]string{
+				kubernetesAppPartOfLabelKey:    kubernetesAppPartOfLabelValue,
+				kubernetesAppManagedByLabelKey: kubernetesAppManagedByLabelValue,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
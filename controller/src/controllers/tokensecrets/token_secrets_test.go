@@ -0,0 +1,267 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tokensecrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const namespaceName = "my-namespace"
+
+var logger logr.Logger
+
+func TestAPIs(t *testing.T) {
+	logger = testr.New(t)
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Token Secrets Suite")
+}
+
+func getTokenSecret(c client.Client) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespaceName, Name: mutation.LumigoTracerTokenSecretName}, secret)
+	return secret, err
+}
+
+var _ = Context("Token secrets", func() {
+
+	var c client.Client
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+	})
+
+	It("accepts only the tokens that are well formed as a whole", func() {
+		Expect(IsWellFormedToken("t_123456789012345678901")).To(BeTrue())
+		Expect(IsWellFormedToken("t_abcdefabcdefabcdefabc")).To(BeTrue())
+
+		Expect(IsWellFormedToken("t_123")).To(BeFalse())
+		Expect(IsWellFormedToken("t_abcdefghijklmnopqrstu")).To(BeFalse())
+		Expect(IsWellFormedToken("my-token t_123456789012345678901")).To(BeFalse())
+		Expect(IsWellFormedToken("t_123456789012345678901 ")).To(BeFalse())
+		Expect(IsWellFormedToken("t_1234567890123456789012")).To(BeFalse())
+	})
+
+	It("creates the token secret of the namespace", func() {
+		isChanged, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getTokenSecret(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Labels).To(HaveKeyWithValue(kubernetesAppManagedByLabelKey, kubernetesAppManagedByLabelValue))
+		Expect(secret.Data).To(HaveKeyWithValue(mutation.LumigoTracerTokenSecretKey, []byte("t_123456789012345678901")))
+	})
+
+	It("updates the token secret only when the token changes", func() {
+		_, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		isChanged, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		isChanged, err = UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_abcdefghijklmnopqrstu", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getTokenSecret(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue(mutation.LumigoTracerTokenSecretKey, []byte("t_abcdefghijklmnopqrstu")))
+	})
+
+	It("adds the tokens of the routes to the token secret", func() {
+		_, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		isChanged, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", map[string]string{"payments": "t_abcdefghijklmnopqrstu"}, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err := getTokenSecret(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Data).To(Equal(map[string][]byte{
+			mutation.LumigoTracerTokenSecretKey:                    []byte("t_123456789012345678901"),
+			mutation.LumigoTracerTokenSecretKeyOfRoute("payments"): []byte("t_abcdefghijklmnopqrstu"),
+		}))
+
+		isChanged, err = UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		secret, err = getTokenSecret(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Data).NotTo(HaveKey(mutation.LumigoTracerTokenSecretKeyOfRoute("payments")))
+	})
+
+	It("removes the token secret of the namespace", func() {
+		_, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		isChanged, err := RemoveTokenSecretOfNamespace(context.TODO(), c, namespaceName, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeTrue())
+
+		_, err = getTokenSecret(c)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		isChanged, err = RemoveTokenSecretOfNamespace(context.TODO(), c, namespaceName, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+	})
+
+	It("does not remove a secret with the same name that it has not created", func() {
+		Expect(c.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespaceName,
+				Name:      mutation.LumigoTracerTokenSecretName,
+			},
+		})).To(Succeed())
+
+		isChanged, err := RemoveTokenSecretOfNamespace(context.TODO(), c, namespaceName, &logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isChanged).To(BeFalse())
+
+		_, err = getTokenSecret(c)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns the cached token", func() {
+		_, err := UpsertTokenSecretOfNamespace(context.TODO(), c, namespaceName, "t_123456789012345678901", nil, &logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := GetCachedToken(context.TODO(), c, namespaceName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("t_123456789012345678901"))
+	})
+
+	It("does not return the token of a secret with the same name that it has not created", func() {
+		Expect(c.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespaceName,
+				Name:      mutation.LumigoTracerTokenSecretName,
+			},
+			Data: map[string][]byte{
+				mutation.LumigoTracerTokenSecretKey: []byte("t_123456789012345678901"),
+			},
+		})).To(Succeed())
+
+		_, err := GetCachedToken(context.TODO(), c, namespaceName)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("references the cached token only if the token is missing with the KeepInjecting policy", func() {
+		lumigo := &operatorv1alpha1.Lumigo{
+			Spec: operatorv1alpha1.LumigoSpec{
+				LumigoToken: operatorv1alpha1.Credentials{
+					SecretRef: operatorv1alpha1.KubernetesSecretRef{
+						Name: "lumigo-credentials",
+						Key:  "token",
+					},
+				},
+				Tracing: operatorv1alpha1.TracingSpec{
+					Injection: operatorv1alpha1.InjectionSpec{
+						TokenMissingPolicy: operatorv1alpha1.TokenMissingPolicyKeepInjecting,
+					},
+				},
+			},
+		}
+		Expect(SpecWithCachedToken(lumigo, &lumigo.Spec)).To(BeIdenticalTo(&lumigo.Spec))
+
+		conditions.SetTokenMissingCondition(lumigo, metav1.Now(), true, "the secret is gone")
+		spec := SpecWithCachedToken(lumigo, &lumigo.Spec)
+		Expect(spec.LumigoToken.SecretRef).To(Equal(operatorv1alpha1.KubernetesSecretRef{
+			Name: mutation.LumigoTracerTokenSecretName,
+			Key:  mutation.LumigoTracerTokenSecretKey,
+		}))
+		Expect(lumigo.Spec.LumigoToken.SecretRef.Name).To(Equal("lumigo-credentials"))
+
+		lumigo.Spec.Tracing.Injection.TokenMissingPolicy = operatorv1alpha1.TokenMissingPolicyStopInjecting
+		Expect(SpecWithCachedToken(lumigo, &lumigo.Spec)).To(BeIdenticalTo(&lumigo.Spec))
+	})
+	It("references the copies of the tokens set as values in the token secret", func() {
+		spec := &operatorv1alpha1.LumigoSpec{
+			LumigoToken: operatorv1alpha1.Credentials{
+				Value: "t_123456789012345678901",
+			},
+			Tracing: operatorv1alpha1.TracingSpec{
+				Routes: []operatorv1alpha1.TracingRoute{
+					{
+						Name: "payments",
+						LumigoToken: operatorv1alpha1.Credentials{
+							SecretRef: operatorv1alpha1.KubernetesSecretRef{
+								Name: "lumigo-payments-credentials",
+								Key:  "token",
+							},
+						},
+					},
+					{
+						Name: "previews",
+						LumigoToken: operatorv1alpha1.Credentials{
+							Value: "t_109876543210987654321",
+						},
+					},
+				},
+			},
+		}
+		Expect(HasTokenValues(spec)).To(BeTrue())
+
+		specWithTokenSecret := SpecWithTokenValuesInSecret(spec)
+		Expect(specWithTokenSecret.LumigoToken).To(Equal(operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: mutation.LumigoTracerTokenSecretName,
+				Key:  mutation.LumigoTracerTokenSecretKey,
+			},
+		}))
+		Expect(specWithTokenSecret.Tracing.Routes[0].LumigoToken).To(Equal(spec.Tracing.Routes[0].LumigoToken))
+		Expect(specWithTokenSecret.Tracing.Routes[1].LumigoToken).To(Equal(operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: mutation.LumigoTracerTokenSecretName,
+				Key:  mutation.LumigoTracerTokenSecretKeyOfRoute("previews"),
+			},
+		}))
+		Expect(spec.LumigoToken.Value).To(Equal("t_123456789012345678901"))
+
+		spec.LumigoToken = operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: "lumigo-credentials",
+				Key:  "token",
+			},
+		}
+		spec.Tracing.Routes = spec.Tracing.Routes[:1]
+		Expect(HasTokenValues(spec)).To(BeFalse())
+		Expect(SpecWithTokenValuesInSecret(spec)).To(BeIdenticalTo(spec))
+	})
+
+})
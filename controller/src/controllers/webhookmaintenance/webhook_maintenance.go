@@ -0,0 +1,177 @@
+package webhookmaintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionregistrationv1client "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const (
+	// FailurePoliciesAnnotationKey is the annotation of the webhook configurations with the failure policies of their
+	// webhooks by name, as a JSON object, which the webhook configurations are installed without, i.e., with the
+	// `Ignore` failure policy, so that the admissions do not fail while the webhooks are being rolled out
+	FailurePoliciesAnnotationKey = "lumigo.io/failure-policies"
+
+	DefaultRestoreInterval = 10 * time.Second
+)
+
+// Restorer restores the failure policies of the webhook configurations once the rollout of the controller manager
+// that serves their webhooks has completed. The webhook configurations are installed and upgraded in maintenance
+// mode, i.e., with the `Ignore` failure policy, so that the creations and updates of the resources in the cluster are
+// not denied while the webhook endpoint is unavailable, e.g., while its serving certificate is being replaced.
+type Restorer struct {
+	webhookConfigurations     admissionregistrationv1client.MutatingWebhookConfigurationsGetter
+	webhookConfigurationNames []string
+	deployments               appsv1client.DeploymentsGetter
+	namespace                 string
+	deploymentName            string
+	webhookServerStarted      healthz.Checker
+	interval                  time.Duration
+	log                       logr.Logger
+}
+
+// NewRestorer creates a Restorer of the failure policies of the given webhook configurations, served by the given
+// Deployment of the controller manager once the webhook server has started.
+func NewRestorer(webhookConfigurations admissionregistrationv1client.MutatingWebhookConfigurationsGetter, webhookConfigurationNames []string, deployments appsv1client.DeploymentsGetter, namespace string, deploymentName string, webhookServerStarted healthz.Checker, interval time.Duration, log logr.Logger) *Restorer {
+	if interval <= 0 {
+		interval = DefaultRestoreInterval
+	}
+
+	return &Restorer{
+		webhookConfigurations:     webhookConfigurations,
+		webhookConfigurationNames: webhookConfigurationNames,
+		deployments:               deployments,
+		namespace:                 namespace,
+		deploymentName:            deploymentName,
+		webhookServerStarted:      webhookServerStarted,
+		interval:                  interval,
+		log:                       log,
+	}
+}
+
+// Start restores the failure policies periodically until the context is done, which makes the restorer a
+// manager.Runnable; it keeps running, as upgrades put the webhook configurations back into maintenance mode.
+func (r *Restorer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Restore(ctx); err != nil {
+				r.log.Error(err, "Cannot restore the failure policies of the webhooks")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns true, as only one replica of the operator updates the webhook configurations.
+func (r *Restorer) NeedLeaderElection() bool {
+	return true
+}
+
+// Restore sets the failure policies of the webhook configurations in maintenance mode, if the webhook server has
+// started and the rollout of the controller manager has completed.
+func (r *Restorer) Restore(ctx context.Context) error {
+	if err := r.webhookServerStarted(&http.Request{}); err != nil {
+		r.log.V(1).Info("Webhook server not started yet, keeping the webhooks in maintenance mode", "reason", err.Error())
+		return nil
+	}
+
+	deployment, err := r.deployments.Deployments(r.namespace).Get(ctx, r.deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("cannot retrieve the '%s/%s' deployment: %w", r.namespace, r.deploymentName, err)
+	}
+
+	if !IsRolloutComplete(deployment) {
+		r.log.V(1).Info("Rollout of the controller manager in progress, keeping the webhooks in maintenance mode")
+		return nil
+	}
+
+	for _, name := range r.webhookConfigurationNames {
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			webhookConfiguration, err := r.webhookConfigurations.MutatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if isChanged, err := RestoreFailurePolicies(webhookConfiguration); err != nil || !isChanged {
+				return err
+			}
+
+			if _, err := r.webhookConfigurations.MutatingWebhookConfigurations().Update(ctx, webhookConfiguration, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+
+			r.log.Info("Restored the failure policies of the webhooks", "webhookConfiguration", name)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("cannot restore the failure policies of the '%s' webhook configuration: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// IsRolloutComplete returns whether all the replicas of the Deployment are updated and available, and no replica
+// of an earlier revision is left.
+func IsRolloutComplete(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := &deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.Replicas == replicas &&
+		status.AvailableReplicas == replicas
+}
+
+// RestoreFailurePolicies sets the failure policies of the webhooks from the annotation of the webhook configuration,
+// and returns whether any changed.
+func RestoreFailurePolicies(webhookConfiguration *admissionregistrationv1.MutatingWebhookConfiguration) (bool, error) {
+	value, ok := webhookConfiguration.Annotations[FailurePoliciesAnnotationKey]
+	if !ok {
+		return false, nil
+	}
+
+	failurePolicies := map[string]admissionregistrationv1.FailurePolicyType{}
+	if err := json.Unmarshal([]byte(value), &failurePolicies); err != nil {
+		return false, fmt.Errorf("invalid '%s' annotation: %w", FailurePoliciesAnnotationKey, err)
+	}
+
+	isChanged := false
+	for i := range webhookConfiguration.Webhooks {
+		webhook := &webhookConfiguration.Webhooks[i]
+
+		failurePolicy, ok := failurePolicies[webhook.Name]
+		if !ok {
+			continue
+		}
+
+		if failurePolicy != admissionregistrationv1.Fail && failurePolicy != admissionregistrationv1.Ignore {
+			return false, fmt.Errorf("invalid failure policy '%s' of the '%s' webhook", failurePolicy, webhook.Name)
+		}
+
+		if webhook.FailurePolicy == nil || *webhook.FailurePolicy != failurePolicy {
+			webhook.FailurePolicy = &failurePolicy
+			isChanged = true
+		}
+	}
+
+	return isChanged, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookmaintenance
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Webhook Maintenance Suite")
+}
+
+func newWebhookConfiguration(failurePolicies string) *admissionregistrationv1.MutatingWebhookConfiguration {
+	ignore := admissionregistrationv1.Ignore
+
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "lumigo-injector-webhook-configuration",
+			Annotations: map[string]string{FailurePoliciesAnnotationKey: failurePolicies},
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "lumigoinjector.kb.io", FailurePolicy: &ignore},
+			{Name: "other.kb.io", FailurePolicy: &ignore},
+		},
+	}
+}
+
+func newDeployment(generation int64, replicas int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Generation: generation},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     status,
+	}
+}
+
+var _ = Context("Webhook maintenance", func() {
+
+	It("restores the failure policies of the annotation", func() {
+		webhookConfiguration := newWebhookConfiguration(`{"lumigoinjector.kb.io":"Fail"}`)
+
+		Expect(RestoreFailurePolicies(webhookConfiguration)).To(BeTrue())
+		Expect(*webhookConfiguration.Webhooks[0].FailurePolicy).To(Equal(admissionregistrationv1.Fail))
+		Expect(*webhookConfiguration.Webhooks[1].FailurePolicy).To(Equal(admissionregistrationv1.Ignore))
+
+		Expect(RestoreFailurePolicies(webhookConfiguration)).To(BeFalse())
+	})
+
+	It("leaves alone the webhook configurations without the annotation", func() {
+		webhookConfiguration := newWebhookConfiguration("")
+		delete(webhookConfiguration.Annotations, FailurePoliciesAnnotationKey)
+
+		Expect(RestoreFailurePolicies(webhookConfiguration)).To(BeFalse())
+	})
+
+	It("rejects invalid failure policies", func() {
+		_, err := RestoreFailurePolicies(newWebhookConfiguration(`{"lumigoinjector.kb.io":"Maybe"}`))
+		Expect(err).To(MatchError(ContainSubstring("invalid failure policy 'Maybe'")))
+
+		_, err = RestoreFailurePolicies(newWebhookConfiguration(`not json`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("tells whether the rollout of the deployment has completed", func() {
+		Expect(IsRolloutComplete(newDeployment(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}))).To(BeTrue())
+		// The new revision has not been observed yet
+		Expect(IsRolloutComplete(newDeployment(3, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}))).To(BeFalse())
+		// A replica of the earlier revision is still running
+		Expect(IsRolloutComplete(newDeployment(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 3}))).To(BeFalse())
+		// An updated replica is not available yet
+		Expect(IsRolloutComplete(newDeployment(2, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1}))).To(BeFalse())
+	})
+})
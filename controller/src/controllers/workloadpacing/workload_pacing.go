@@ -0,0 +1,139 @@
+package workloadpacing
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// DefaultPeriod is the period of the cluster-wide limit of workload updates, which matches how often
+// the Lumigo instances are reconciled
+const DefaultPeriod = 10 * time.Second
+
+// ErrBudgetExhausted is returned in place of updating a workload once the limit of the workloads updated
+// in one reconciliation, or the cluster-wide one, has been reached
+var ErrBudgetExhausted = errors.New("the limit of workloads updated at once has been reached")
+
+// Pacer limits how many workloads the operator updates to add the injection across all the Lumigo instances
+// of the cluster within each period; it is safe for concurrent use by the reconciliations.
+type Pacer struct {
+	maxUpdatesPerPeriod int
+	period              time.Duration
+
+	mutex       sync.Mutex
+	periodStart time.Time
+	updates     int
+}
+
+func NewPacer(maxUpdatesPerPeriod int, period time.Duration) *Pacer {
+	return &Pacer{
+		maxUpdatesPerPeriod: maxUpdatesPerPeriod,
+		period:              period,
+	}
+}
+
+func (p *Pacer) take(now time.Time) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if now.Sub(p.periodStart) >= p.period {
+		p.periodStart = now
+		p.updates = 0
+	}
+
+	if p.updates >= p.maxUpdatesPerPeriod {
+		return false
+	}
+
+	p.updates++
+	return true
+}
+
+// Budget tracks the workload updates left to one reconciliation of a Lumigo instance
+type Budget struct {
+	pacer *Pacer
+	// Negative if the Lumigo instance does not limit its workload updates
+	remaining int
+}
+
+// NewBudget returns the budget of one reconciliation with the limit of the Lumigo instance, if any,
+// drawing also from the cluster-wide pacer, if not nil.
+func NewBudget(pacer *Pacer, maxUpdates *int32) *Budget {
+	remaining := -1
+	if maxUpdates != nil {
+		remaining = int(*maxUpdates)
+	}
+
+	return &Budget{
+		pacer:     pacer,
+		remaining: remaining,
+	}
+}
+
+// Take returns whether one more workload can be updated, and accounts for its update if so.
+func (b *Budget) Take(now time.Time) bool {
+	if b.remaining == 0 {
+		return false
+	}
+
+	if b.pacer != nil && !b.pacer.take(now) {
+		return false
+	}
+
+	if b.remaining > 0 {
+		b.remaining--
+	}
+
+	return true
+}
+
+// EnqueuePendingInjection adds the resource at the end of the pending injections of the Lumigo instance,
+// unless it is already queued.
+func EnqueuePendingInjection(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference) {
+	if getPendingInjectionIndex(&lumigo.Status, &resource) > -1 {
+		return
+	}
+
+	lumigo.Status.PendingInjections = append(lumigo.Status.PendingInjections, resource)
+}
+
+// RemovePendingInjection removes the resource from the pending injections of the Lumigo instance,
+// returning whether it was queued.
+func RemovePendingInjection(lumigo *operatorv1alpha1.Lumigo, resource corev1.ObjectReference) bool {
+	status := &lumigo.Status
+	index := getPendingInjectionIndex(status, &resource)
+	if index < 0 {
+		return false
+	}
+
+	status.PendingInjections = append(status.PendingInjections[:index], status.PendingInjections[index+1:]...)
+	if len(status.PendingInjections) < 1 {
+		status.PendingInjections = nil
+	}
+
+	return true
+}
+
+// ClearAllPendingInjections removes all the pending injections of the Lumigo instance.
+func ClearAllPendingInjections(lumigo *operatorv1alpha1.Lumigo) {
+	lumigo.Status.PendingInjections = nil
+}
+
+func getPendingInjectionIndex(status *operatorv1alpha1.LumigoStatus, resource *corev1.ObjectReference) int {
+	for i, pending := range status.PendingInjections {
+		if isSameResource(&pending, resource) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// The UIDs and resource versions are not compared, as the references are created from different copies of the resources
+func isSameResource(a *corev1.ObjectReference, b *corev1.ObjectReference) bool {
+	return a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
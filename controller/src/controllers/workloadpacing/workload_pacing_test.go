@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloadpacing
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Workload Pacing Suite")
+}
+
+func newInt32(value int32) *int32 {
+	return &value
+}
+
+var _ = Context("Workload pacing", func() {
+
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	It("does not limit the updates without limits", func() {
+		budget := NewBudget(nil, nil)
+		for i := 0; i < 1000; i++ {
+			Expect(budget.Take(now)).To(BeTrue())
+		}
+	})
+
+	It("limits the updates of a reconciliation to the limit of the Lumigo instance", func() {
+		budget := NewBudget(nil, newInt32(2))
+		Expect(budget.Take(now)).To(BeTrue())
+		Expect(budget.Take(now)).To(BeTrue())
+		Expect(budget.Take(now)).To(BeFalse())
+
+		Expect(NewBudget(nil, newInt32(2)).Take(now)).To(BeTrue())
+	})
+
+	It("limits the updates across reconciliations to the cluster-wide limit within each period", func() {
+		pacer := NewPacer(3, 10*time.Second)
+
+		first := NewBudget(pacer, newInt32(2))
+		Expect(first.Take(now)).To(BeTrue())
+		Expect(first.Take(now)).To(BeTrue())
+		Expect(first.Take(now)).To(BeFalse())
+
+		second := NewBudget(pacer, nil)
+		Expect(second.Take(now.Add(time.Second))).To(BeTrue())
+		Expect(second.Take(now.Add(time.Second))).To(BeFalse())
+
+		Expect(second.Take(now.Add(10 * time.Second))).To(BeTrue())
+	})
+
+	It("queues the pending injections in order and without duplicates", func() {
+		lumigo := &operatorv1alpha1.Lumigo{}
+		deployment := corev1.ObjectReference{Kind: "Deployment", Namespace: "my-namespace", Name: "my-app"}
+		statefulSet := corev1.ObjectReference{Kind: "StatefulSet", Namespace: "my-namespace", Name: "my-db"}
+
+		EnqueuePendingInjection(lumigo, deployment)
+		EnqueuePendingInjection(lumigo, statefulSet)
+		EnqueuePendingInjection(lumigo, corev1.ObjectReference{Kind: "Deployment", Namespace: "my-namespace", Name: "my-app", ResourceVersion: "2"})
+		Expect(lumigo.Status.PendingInjections).To(Equal([]corev1.ObjectReference{deployment, statefulSet}))
+
+		Expect(RemovePendingInjection(lumigo, deployment)).To(BeTrue())
+		Expect(RemovePendingInjection(lumigo, deployment)).To(BeFalse())
+		Expect(lumigo.Status.PendingInjections).To(Equal([]corev1.ObjectReference{statefulSet}))
+
+		Expect(RemovePendingInjection(lumigo, statefulSet)).To(BeTrue())
+		Expect(lumigo.Status.PendingInjections).To(BeNil())
+	})
+})
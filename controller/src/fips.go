@@ -0,0 +1,24 @@
+//go:build fips
+
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// In FIPS builds, which require `GOEXPERIMENT=boringcrypto`, all the TLS connections of the manager,
+// from the webhook server to the clients of the Kubernetes API and container registries, are
+// restricted to FIPS-approved versions, cipher suites and curves.
+import _ "crypto/tls/fipsonly"
@@ -0,0 +1,85 @@
+package healthchecks
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// The timeout of the DNS lookups of the readiness checks, well below the timeout of the readiness probe
+const resolveTimeout = 2 * time.Second
+
+// Resolver looks up the addresses of hosts; *net.Resolver implements it
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// NewCertificateChecker returns a check that fails if the PEM certificate at the given path, e.g., the
+// serving certificate of the webhook server, cannot be read or is not valid at the time of the check.
+// The certificate is read at every check, as it may be rotated while the manager runs.
+func NewCertificateChecker(certificatePath string, now func() time.Time) healthz.Checker {
+	return func(_ *http.Request) error {
+		certificatePem, err := os.ReadFile(certificatePath)
+		if err != nil {
+			return fmt.Errorf("cannot read the certificate '%s': %w", certificatePath, err)
+		}
+
+		block, _ := pem.Decode(certificatePem)
+		if block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("the file '%s' does not contain a PEM certificate", certificatePath)
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("cannot parse the certificate '%s': %w", certificatePath, err)
+		}
+
+		t := now()
+		if t.Before(certificate.NotBefore) {
+			return fmt.Errorf("the certificate '%s' is not valid before %s", certificatePath, certificate.NotBefore.Format(time.RFC3339))
+		}
+
+		if t.After(certificate.NotAfter) {
+			return fmt.Errorf("the certificate '%s' expired at %s", certificatePath, certificate.NotAfter.Format(time.RFC3339))
+		}
+
+		return nil
+	}
+}
+
+// NewServiceResolvableChecker returns a check that fails if the host of the given URL, e.g., the one of the
+// telemetry-proxy Service, cannot be resolved.
+func NewServiceResolvableChecker(serviceUrl string, resolver Resolver) (healthz.Checker, error) {
+	u, err := url.Parse(serviceUrl)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the service URL '%s': %w", serviceUrl, err)
+	}
+
+	host := u.Hostname()
+	if len(host) < 1 {
+		return nil, fmt.Errorf("the service URL '%s' has no host", serviceUrl)
+	}
+
+	return func(req *http.Request) error {
+		if net.ParseIP(host) != nil {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), resolveTimeout)
+		defer cancel()
+
+		if _, err := resolver.LookupHost(ctx, host); err != nil {
+			return fmt.Errorf("cannot resolve the host '%s': %w", host, err)
+		}
+
+		return nil
+	}, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthchecks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Health Checks Suite")
+}
+
+type fakeResolver struct {
+	hosts map[string][]string
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addresses, ok := r.hosts[host]; ok {
+		return addresses, nil
+	}
+
+	return nil, fmt.Errorf("no such host")
+}
+
+func writeCertificate(path string, notBefore time.Time, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lumigo-lumigo-operator-webhooks-service.lumigo-system.svc"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	certificateDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDer}), 0600)).To(Succeed())
+}
+
+var _ = Context("Health checks", func() {
+
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	Context("of the webhook certificate", func() {
+
+		var certificatePath string
+
+		BeforeEach(func() {
+			certificatePath = filepath.Join(GinkgoT().TempDir(), "tls.crt")
+		})
+
+		It("pass with a valid certificate", func() {
+			writeCertificate(certificatePath, now.Add(-time.Hour), now.Add(time.Hour))
+
+			Expect(NewCertificateChecker(certificatePath, clock)(httptest.NewRequest("GET", "/readyz", nil))).To(Succeed())
+		})
+
+		It("fail with an expired certificate", func() {
+			writeCertificate(certificatePath, now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+			err := NewCertificateChecker(certificatePath, clock)(httptest.NewRequest("GET", "/readyz", nil))
+			Expect(err).To(MatchError(ContainSubstring("expired")))
+		})
+
+		It("fail without a certificate", func() {
+			err := NewCertificateChecker(certificatePath, clock)(httptest.NewRequest("GET", "/readyz", nil))
+			Expect(err).To(MatchError(ContainSubstring("cannot read the certificate")))
+		})
+	})
+
+	Context("of the telemetry-proxy service", func() {
+
+		resolver := &fakeResolver{
+			hosts: map[string][]string{
+				"lumigo-telemetry-proxy.lumigo-system.svc.cluster.local": {"10.0.0.1"},
+			},
+		}
+
+		It("pass if the service resolves", func() {
+			checker, err := NewServiceResolvableChecker("http://lumigo-telemetry-proxy.lumigo-system.svc.cluster.local:80/v1/traces", resolver)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(checker(httptest.NewRequest("GET", "/readyz", nil))).To(Succeed())
+		})
+
+		It("fail if the service does not resolve", func() {
+			checker, err := NewServiceResolvableChecker("http://lumigo-telemetry-proxy.other.svc.cluster.local:80/v1/traces", resolver)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(checker(httptest.NewRequest("GET", "/readyz", nil))).To(MatchError(ContainSubstring("cannot resolve the host")))
+		})
+	})
+})
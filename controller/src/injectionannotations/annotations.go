@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package injectionannotations defines the annotations that the Lumigo operator writes on the
+// resources it injects with the Lumigo distros, and on their pod templates, so that the pods
+// created from them carry the annotations too.
+//
+// The keys of the annotations and the format of their values are a stable contract meant for
+// policy engines like OPA Gatekeeper and Kyverno: they will not be renamed, and new annotations
+// may be added, but existing ones will not be removed. The package has no dependencies beyond
+// the Kubernetes API types, so that tooling can import it without pulling in the operator.
+package injectionannotations
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// InjectedVersionAnnotationKey holds the version of the Lumigo operator that injected the
+	// resource, e.g., `1.2.3`
+	InjectedVersionAnnotationKey = "lumigo.io/injected-version"
+	// InjectedAtAnnotationKey holds when the resource was injected, as an RFC 3339 timestamp
+	// in UTC, e.g., `2023-05-04T12:34:56Z`
+	InjectedAtAnnotationKey = "lumigo.io/injected-at"
+	// InjectedContainersAnnotationKey holds the comma-separated names of the injected containers,
+	// in the order they appear in the pod spec, e.g., `app,sidecar`
+	InjectedContainersAnnotationKey = "lumigo.io/injected-containers"
+
+	injectedContainersSeparator = ","
+)
+
+// AnnotationKeys lists the keys of all the injection annotations
+var AnnotationKeys = []string{
+	InjectedVersionAnnotationKey,
+	InjectedAtAnnotationKey,
+	InjectedContainersAnnotationKey,
+}
+
+// InjectionAnnotations is the parsed content of the injection annotations
+type InjectionAnnotations struct {
+	Version    string
+	InjectedAt time.Time
+	Containers []string
+}
+
+// Set writes the injection annotations on the given object metadata, overwriting existing ones.
+func Set(objectMeta *metav1.ObjectMeta, annotations *InjectionAnnotations) {
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+
+	objectMeta.Annotations[InjectedVersionAnnotationKey] = annotations.Version
+	objectMeta.Annotations[InjectedAtAnnotationKey] = annotations.InjectedAt.UTC().Format(time.RFC3339)
+	objectMeta.Annotations[InjectedContainersAnnotationKey] = strings.Join(annotations.Containers, injectedContainersSeparator)
+}
+
+// Remove deletes the injection annotations from the given object metadata.
+func Remove(objectMeta *metav1.ObjectMeta) {
+	if objectMeta == nil || objectMeta.Annotations == nil {
+		return
+	}
+
+	for _, key := range AnnotationKeys {
+		delete(objectMeta.Annotations, key)
+	}
+}
+
+// IsComplete returns whether the given object metadata has all the injection annotations.
+func IsComplete(objectMeta *metav1.ObjectMeta) bool {
+	for _, key := range AnnotationKeys {
+		if _, isSet := objectMeta.Annotations[key]; !isSet {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get parses the injection annotations of the given object metadata; it returns nil if the
+// object metadata has none of them, and an error if they are incomplete or malformed.
+func Get(objectMeta *metav1.ObjectMeta) (*InjectionAnnotations, error) {
+	isAnySet := false
+	for _, key := range AnnotationKeys {
+		if _, isSet := objectMeta.Annotations[key]; isSet {
+			isAnySet = true
+			break
+		}
+	}
+
+	if !isAnySet {
+		return nil, nil
+	}
+
+	if !IsComplete(objectMeta) {
+		return nil, fmt.Errorf("the injection annotations are incomplete: all of %s must be set", strings.Join(AnnotationKeys, ", "))
+	}
+
+	injectedAt, err := time.Parse(time.RFC3339, objectMeta.Annotations[InjectedAtAnnotationKey])
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the '%s' annotation: %w", InjectedAtAnnotationKey, err)
+	}
+
+	containers := []string{}
+	if value := objectMeta.Annotations[InjectedContainersAnnotationKey]; len(value) > 0 {
+		containers = strings.Split(value, injectedContainersSeparator)
+	}
+
+	return &InjectionAnnotations{
+		Version:    objectMeta.Annotations[InjectedVersionAnnotationKey],
+		InjectedAt: injectedAt,
+		Containers: containers,
+	}, nil
+}
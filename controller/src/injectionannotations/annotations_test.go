@@ -0,0 +1,110 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injectionannotations
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Injection Annotations Suite")
+}
+
+var _ = Context("Injection annotations", func() {
+
+	It("are written in the documented format and parsed back", func() {
+		objectMeta := &metav1.ObjectMeta{}
+		injectedAt := time.Date(2023, 5, 4, 12, 34, 56, 0, time.UTC)
+
+		Set(objectMeta, &InjectionAnnotations{
+			Version:    "1.2.3",
+			InjectedAt: injectedAt,
+			Containers: []string{"app", "sidecar"},
+		})
+
+		Expect(objectMeta.Annotations).To(Equal(map[string]string{
+			"lumigo.io/injected-version":    "1.2.3",
+			"lumigo.io/injected-at":         "2023-05-04T12:34:56Z",
+			"lumigo.io/injected-containers": "app,sidecar",
+		}))
+		Expect(IsComplete(objectMeta)).To(BeTrue())
+
+		injectionAnnotations, err := Get(objectMeta)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(injectionAnnotations.Version).To(Equal("1.2.3"))
+		Expect(injectionAnnotations.InjectedAt).To(BeTemporally("==", injectedAt))
+		Expect(injectionAnnotations.Containers).To(Equal([]string{"app", "sidecar"}))
+	})
+
+	It("are absent from resources that have not been injected", func() {
+		objectMeta := &metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"other": "annotation",
+			},
+		}
+
+		injectionAnnotations, err := Get(objectMeta)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(injectionAnnotations).To(BeNil())
+		Expect(IsComplete(objectMeta)).To(BeFalse())
+	})
+
+	It("are reported as invalid when incomplete or malformed", func() {
+		_, err := Get(&metav1.ObjectMeta{
+			Annotations: map[string]string{
+				InjectedVersionAnnotationKey: "1.2.3",
+			},
+		})
+		Expect(err).To(HaveOccurred())
+
+		_, err = Get(&metav1.ObjectMeta{
+			Annotations: map[string]string{
+				InjectedVersionAnnotationKey:    "1.2.3",
+				InjectedAtAnnotationKey:         "yesterday",
+				InjectedContainersAnnotationKey: "app",
+			},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("are removed without touching other annotations", func() {
+		objectMeta := &metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"other": "annotation",
+			},
+		}
+		Set(objectMeta, &InjectionAnnotations{
+			Version:    "1.2.3",
+			InjectedAt: time.Now(),
+			Containers: []string{"app"},
+		})
+
+		Remove(objectMeta)
+
+		Expect(objectMeta.Annotations).To(Equal(map[string]string{
+			"other": "annotation",
+		}))
+	})
+
+})
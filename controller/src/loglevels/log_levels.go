@@ -0,0 +1,278 @@
+package loglevels
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// The components whose log level can be changed separately
+	ComponentReconciler  = "reconciler"
+	ComponentWebhook     = "webhook"
+	ComponentProxyConfig = "proxy-config"
+
+	// The highest verbosity the loggers are created with; log lines more verbose than this are never emitted
+	MaxVerbosity = 10
+
+	// The key of the logger values with the namespace the log lines are about
+	namespaceKey = "namespace"
+)
+
+var (
+	// The names of the loggers that identify the component logging through them; the name closest to the
+	// log line wins, e.g., the `controllers.proxy-config` logger logs as the proxy-config component
+	componentsByLoggerName = map[string]string{
+		"controllers":             ComponentReconciler,
+		ComponentReconciler:       ComponentReconciler,
+		ComponentWebhook:          ComponentWebhook,
+		"lumigo-injector-webhook": ComponentWebhook,
+		ComponentProxyConfig:      ComponentProxyConfig,
+	}
+
+	supportedComponents = []string{ComponentReconciler, ComponentWebhook, ComponentProxyConfig}
+)
+
+// Config sets the verbosity of the log lines, following the logr conventions: `0` logs the info lines, `1` the
+// debug lines, and so on, while a negative verbosity logs only the errors. A log line is emitted if any of the
+// verbosities that apply to it allows it, so the component and namespace verbosities can only add log lines.
+type Config struct {
+	// The verbosity of all the log lines
+	Default int `json:"default"`
+	// The verbosity of the log lines of the given components, e.g., `webhook`
+	Components map[string]int `json:"components,omitempty"`
+	// The verbosity of the log lines about the given namespaces, regardless of the component
+	Namespaces map[string]int `json:"namespaces,omitempty"`
+}
+
+// Validate returns an error if the config references unknown components or verbosities out of range.
+func (c *Config) Validate() error {
+	if c.Default > MaxVerbosity {
+		return fmt.Errorf("the default verbosity %d is higher than the maximum %d", c.Default, MaxVerbosity)
+	}
+
+	for component, verbosity := range c.Components {
+		if !isSupportedComponent(component) {
+			return fmt.Errorf("unknown component '%s'; supported components: %v", component, supportedComponents)
+		}
+
+		if verbosity > MaxVerbosity {
+			return fmt.Errorf("the verbosity %d of the '%s' component is higher than the maximum %d", verbosity, component, MaxVerbosity)
+		}
+	}
+
+	for namespace, verbosity := range c.Namespaces {
+		if verbosity > MaxVerbosity {
+			return fmt.Errorf("the verbosity %d of the '%s' namespace is higher than the maximum %d", verbosity, namespace, MaxVerbosity)
+		}
+	}
+
+	return nil
+}
+
+// Levels holds the log level config, which can be changed at runtime.
+type Levels struct {
+	mutex  sync.RWMutex
+	config Config
+}
+
+// NewLevels returns levels with the given default verbosity and no component or namespace overrides.
+func NewLevels(defaultVerbosity int) *Levels {
+	return &Levels{
+		config: Config{
+			Default: defaultVerbosity,
+		},
+	}
+}
+
+// Apply merges the given JSON config into the current one, e.g., to apply the `--log-levels` flag; the fields
+// missing in the JSON config, like the default verbosity, keep their current values.
+func (l *Levels) Apply(jsonConfig string) error {
+	config := l.GetConfig()
+	if err := json.Unmarshal([]byte(jsonConfig), &config); err != nil {
+		return fmt.Errorf("cannot parse the log levels: %w", err)
+	}
+
+	return l.SetConfig(config)
+}
+
+// GetConfig returns a copy of the current config.
+func (l *Levels) GetConfig() Config {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	config := Config{
+		Default: l.config.Default,
+	}
+	if l.config.Components != nil {
+		config.Components = make(map[string]int, len(l.config.Components))
+		for component, verbosity := range l.config.Components {
+			config.Components[component] = verbosity
+		}
+	}
+	if l.config.Namespaces != nil {
+		config.Namespaces = make(map[string]int, len(l.config.Namespaces))
+		for namespace, verbosity := range l.config.Namespaces {
+			config.Namespaces[namespace] = verbosity
+		}
+	}
+
+	return config
+}
+
+// SetConfig validates and applies the given config, which affects immediately all the existing loggers.
+func (l *Levels) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.config = config
+	return nil
+}
+
+// SetDefault changes the default verbosity, keeping the component and namespace overrides.
+func (l *Levels) SetDefault(verbosity int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.config.Default = verbosity
+}
+
+// IsEnabled returns whether log lines with the given verbosity are emitted for the given component and namespace,
+// either of which may be empty.
+func (l *Levels) IsEnabled(component string, namespace string, verbosity int) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if verbosity <= l.config.Default {
+		return true
+	}
+
+	if v, ok := l.config.Components[component]; ok && len(component) > 0 && verbosity <= v {
+		return true
+	}
+
+	if v, ok := l.config.Namespaces[namespace]; ok && len(namespace) > 0 && verbosity <= v {
+		return true
+	}
+
+	return false
+}
+
+// NewLogger wraps the given logger, which must be created with at least MaxVerbosity, so that its log
+// lines are filtered according to the levels.
+func (l *Levels) NewLogger(logger logr.Logger) logr.Logger {
+	delegate := logger.GetSink()
+	// The filtering sink adds a frame to the call stack between the log call and the delegate
+	if callDepthSink, ok := delegate.(logr.CallDepthLogSink); ok {
+		delegate = callDepthSink.WithCallDepth(1)
+	}
+
+	return logr.New(&filteringSink{
+		delegate: delegate,
+		levels:   l,
+	})
+}
+
+// ServeHTTP returns the current config on GET, and replaces it with the one in the request body on PUT.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		config := Config{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			http.Error(w, fmt.Sprintf("cannot parse the log levels: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if err := l.SetConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(l.GetConfig()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func isSupportedComponent(component string) bool {
+	for _, supportedComponent := range supportedComponents {
+		if component == supportedComponent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filteringSink decides which log lines are emitted based on the component and namespace it logs for,
+// which it picks up from the names and values of the logger
+type filteringSink struct {
+	delegate  logr.LogSink
+	levels    *Levels
+	component string
+	namespace string
+}
+
+// The delegate has already been initialized by the logger it comes from
+func (s *filteringSink) Init(info logr.RuntimeInfo) {}
+
+func (s *filteringSink) WithCallDepth(depth int) logr.LogSink {
+	if delegate, ok := s.delegate.(logr.CallDepthLogSink); ok {
+		sink := *s
+		sink.delegate = delegate.WithCallDepth(depth)
+		return &sink
+	}
+
+	return s
+}
+
+func (s *filteringSink) Enabled(level int) bool {
+	return s.levels.IsEnabled(s.component, s.namespace, level)
+}
+
+func (s *filteringSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.delegate.Info(level, msg, keysAndValues...)
+}
+
+func (s *filteringSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.delegate.Error(err, msg, keysAndValues...)
+}
+
+func (s *filteringSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	sink := *s
+	sink.delegate = s.delegate.WithValues(keysAndValues...)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i].(string); ok && key == namespaceKey {
+			if namespace, ok := keysAndValues[i+1].(string); ok {
+				sink.namespace = namespace
+			}
+		}
+	}
+
+	return &sink
+}
+
+func (s *filteringSink) WithName(name string) logr.LogSink {
+	sink := *s
+	sink.delegate = s.delegate.WithName(name)
+	if component, ok := componentsByLoggerName[name]; ok {
+		sink.component = component
+	}
+
+	return &sink
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loglevels
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Log Levels Suite")
+}
+
+var _ = Context("Log levels", func() {
+
+	var levels *Levels
+	var lines []string
+
+	BeforeEach(func() {
+		levels = NewLevels(0)
+		lines = []string{}
+	})
+
+	It("filter the log lines by component and namespace", func() {
+		logger := levels.NewLogger(funcr.New(func(prefix, args string) {
+			lines = append(lines, prefix)
+		}, funcr.Options{Verbosity: MaxVerbosity}))
+
+		Expect(levels.Apply(`{"components":{"webhook":1},"namespaces":{"my-namespace":2}}`)).To(Succeed())
+
+		reconcilerLogger := logger.WithName("controllers")
+		reconcilerLogger.V(1).Info("hidden")
+		reconcilerLogger.WithValues("namespace", "my-namespace").V(2).Info("shown")
+		reconcilerLogger.WithName("proxy-config").WithValues("namespace", "other-namespace").V(1).Info("hidden")
+		logger.WithName("webhook").V(1).Info("shown")
+		logger.WithName("webhook").V(2).Info("hidden")
+
+		Expect(lines).To(Equal([]string{"controllers", "webhook"}))
+	})
+
+	It("are changed at runtime over HTTP", func() {
+		recorder := httptest.NewRecorder()
+		levels.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/log-levels", strings.NewReader(`{"default":1,"components":{"proxy-config":3}}`)))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		Expect(levels.IsEnabled(ComponentProxyConfig, "", 3)).To(BeTrue())
+		Expect(levels.IsEnabled(ComponentReconciler, "", 1)).To(BeTrue())
+		Expect(levels.IsEnabled(ComponentReconciler, "", 2)).To(BeFalse())
+
+		recorder = httptest.NewRecorder()
+		levels.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/log-levels", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"default":1,"components":{"proxy-config":3}}`))
+	})
+
+	It("reject unknown components", func() {
+		recorder := httptest.NewRecorder()
+		levels.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/log-levels", strings.NewReader(`{"components":{"telemetry-proxy":1}}`)))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		Expect(recorder.Body.String()).To(ContainSubstring("unknown component 'telemetry-proxy'"))
+
+		Expect(levels.GetConfig()).To(Equal(Config{}))
+	})
+})
@@ -18,30 +18,74 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"go.uber.org/zap/zapcore"
+
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	operatorv1beta1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1beta1"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/audit"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/backendprobe"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/capabilities"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/imageverification"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectiongc"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectorruntimefailures"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/instrumentednamespaces"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/instrumentedresources"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/networkpolicies"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/platform"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/selftelemetry"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/servicemonitors"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyconfigs"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxydaemonset"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxydedicated"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxymetrics"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyprobe"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyshards"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryverification"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokendistribution"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/webhookmaintenance"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/workloadpacing"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/healthchecks"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/loglevels"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/metricsserver"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/redaction"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/tlsoptions"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/webhooks/defaulter"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/webhooks/injector"
 	//+kubebuilder:scaffold:imports
@@ -56,6 +100,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(operatorv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -64,6 +109,8 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var uninstall bool
+	var verifyTelemetry bool
+	var watchNamespaces string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -71,32 +118,144 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&uninstall, "uninstall", false,
 		"Whether the execution of this manager is actually aimed at initiating the uninstallation procedure.")
+	flag.BoolVar(&verifyTelemetry, telemetryverification.VerifyTelemetryFlag, false,
+		"Whether the execution of this manager is actually aimed at sending the synthetic trace of a telemetry verification Job.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma-separated namespaces that the manager watches and changes resources in, besides its own. "+
+			"Defaults to all the namespaces of the cluster.")
+	var logLevelsConfig string
+	flag.StringVar(&logLevelsConfig, "log-levels", "",
+		"JSON config of the verbosity of the logs by component and namespace, e.g., "+
+			"'{\"components\":{\"webhook\":1},\"namespaces\":{\"my-namespace\":1}}'. "+
+			"The verbosity of the log lines of all components and namespaces comes from the zap flags. "+
+			"The config can be changed at runtime on the '/log-levels' path of the metrics endpoint.")
+	var injectorDefaultsConfig string
+	flag.StringVar(&injectorDefaultsConfig, "injector-defaults", "",
+		"JSON config of the cluster-wide defaults of the settings of the Lumigo distros in the injected containers, e.g., "+
+			"'{\"debug\":false,\"endpointTimeout\":\"10s\",\"exporterTimeout\":\"30s\",\"env\":[{\"name\":\"LUMIGO_SWITCH_OFF\",\"value\":\"false\"}]}'. "+
+			"The settings of the Lumigo resources take precedence over the defaults.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
+	tlsOpts := tlsoptions.Options{}
+	tlsOpts.BindFlags(flag.CommandLine)
+	metricsOpts := metricsserver.Options{}
+	metricsOpts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	logger := zap.New(zap.UseFlagOptions(&opts))
+	logLevels, err := newLogLevels(&opts, logLevelsConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log levels: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// The Lumigo tokens never make it to the logs, whatever the log line
+	logger := redaction.NewLogger(logLevels.NewLogger(zap.New(zap.UseFlagOptions(&opts))))
 	ctrl.SetLogger(logger)
 
-	if !uninstall {
+	var injectorDefaults *mutation.InjectorDefaults
+	if len(injectorDefaultsConfig) > 0 {
+		if injectorDefaults, err = mutation.ParseInjectorDefaults(injectorDefaultsConfig); err != nil {
+			setupLog.Error(err, "Invalid injector defaults")
+			os.Exit(1)
+		}
+	}
+	// The name and environment of the cluster are added to the telemetry of the injected containers as well as by
+	// the telemetry-proxy, so that the telemetry of multiple clusters can be told apart
+	injectorDefaults = injectorDefaults.WithClusterResourceAttributes(os.Getenv("KUBERNETES_CLUSTER_NAME"), os.Getenv("LUMIGO_DEPLOYMENT_ENVIRONMENT"))
+
+	if verifyTelemetry {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := telemetryverification.SendSyntheticTraceFromEnv(ctx, os.Getenv, ctrl.Log.WithName("telemetry-verification")); err != nil {
+			setupLog.Error(err, "Cannot send the synthetic trace")
+			os.Exit(1)
+		}
+		setupLog.Info("Sent the synthetic trace")
+	} else if !uninstall {
 		setupLog.Info("starting manager")
-		if err := startManager(metricsAddr, probeAddr, enableLeaderElection); err != nil {
+		if err := startManager(metricsAddr, &metricsOpts, probeAddr, enableLeaderElection, &tlsOpts, logLevels, injectorDefaults, parseNamespaces(watchNamespaces)); err != nil {
 			logger.Error(err, "Manager failed")
 			os.Exit(1)
 		}
-	} else if err := uninstallHook(); err != nil {
+	} else if err := uninstallHook(parseNamespaces(watchNamespaces)); err != nil {
 		setupLog.Error(err, "Unistallation hook failed")
 		os.Exit(1)
 	}
 }
 
-func startManager(metricsAddr string, probeAddr string, enableLeaderElection bool) error {
+// The default verbosity comes from the zap flags, while the zap logger is made to log everything and
+// leave the filtering to the log levels, so that the verbosity of components and namespaces can be raised
+func newLogLevels(opts *zap.Options, logLevelsConfig string) (*loglevels.Levels, error) {
+	defaultVerbosity := 0
+	if opts.Development {
+		defaultVerbosity = 1
+	}
+	if level, ok := opts.Level.(interface{ Level() zapcore.Level }); ok {
+		defaultVerbosity = -int(level.Level())
+	} else if level, ok := opts.Level.(zapcore.Level); ok {
+		defaultVerbosity = -int(level)
+	}
+
+	logLevels := loglevels.NewLevels(defaultVerbosity)
+	if len(logLevelsConfig) > 0 {
+		if err := logLevels.Apply(logLevelsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Level = zapcore.Level(-loglevels.MaxVerbosity)
+	return logLevels, nil
+}
+
+// The path of the serving certificate of the webhook server, following the defaults of controller-runtime
+func webhookCertificatePath(mgr ctrl.Manager) string {
+	certDir := filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	certName := "tls.crt"
+	if server := mgr.GetWebhookServer(); server != nil {
+		if len(server.CertDir) > 0 {
+			certDir = server.CertDir
+		}
+		if len(server.CertName) > 0 {
+			certName = server.CertName
+		}
+	}
+
+	return filepath.Join(certDir, certName)
+}
+
+func startManager(metricsAddr string, metricsOpts *metricsserver.Options, probeAddr string, enableLeaderElection bool, tlsOpts *tlsoptions.Options, logLevels *loglevels.Levels, injectorDefaults *mutation.InjectorDefaults, watchNamespaces []string) error {
+	configureTLS, err := tlsOpts.ConfigureTLS()
+	if err != nil {
+		return fmt.Errorf("invalid TLS options: %w", err)
+	}
+
+	lumigoOperatorNamespace, isSet := os.LookupEnv("LUMIGO_CONTROLLER_NAMESPACE")
+	if !isSet {
+		return fmt.Errorf("unable to create controller: environment variable 'LUMIGO_CONTROLLER_NAMESPACE' is not set")
+	}
+
+	// In the namespace-scoped mode, the manager has permissions only in the watched namespaces and its own,
+	// in which it manages the telemetry-proxy DaemonSet and the NetworkPolicies of the operator
+	var newCache ctrlcache.NewCacheFunc
+	if len(watchNamespaces) > 0 {
+		setupLog.Info("Watching only the allowed namespaces", "namespaces", watchNamespaces)
+		newCache = ctrlcache.MultiNamespacedCacheBuilder(appendIfMissing(watchNamespaces, lumigoOperatorNamespace))
+	}
+
+	// With secure serving, the metrics are served by the metrics server of the operator instead of the manager's
+	managerMetricsAddr := metricsAddr
+	if metricsOpts.SecureServing {
+		managerMetricsAddr = "0"
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
+		NewCache:               newCache,
+		MetricsBindAddress:     managerMetricsAddr,
 		Port:                   9443,
+		TLSOpts:                []func(*tls.Config){configureTLS},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "1447aab8.lumigo.io",
@@ -141,58 +300,463 @@ func startManager(metricsAddr string, probeAddr string, enableLeaderElection boo
 		return fmt.Errorf("unable to create controller: environment variable 'LUMIGO_INJECTOR_IMAGE' is not set")
 	}
 
+	lumigoOperatorServiceAccountName, isSet := os.LookupEnv("LUMIGO_CONTROLLER_SERVICE_ACCOUNT_NAME")
+	if !isSet {
+		return fmt.Errorf("unable to create controller: environment variable 'LUMIGO_CONTROLLER_SERVICE_ACCOUNT_NAME' is not set")
+	}
+
+	// The Go instrumentation agent is optional: if its image is not set, Go instrumentation cannot be enabled
+	goInstrumentationAgentImage := os.Getenv("LUMIGO_GO_INSTRUMENTATION_AGENT_IMAGE")
+
+	// The telemetry-proxy metrics are optional: if their URL is not set, export failures are not detected
+	var telemetryProxyExportMonitor *telemetryproxymetrics.ExportMonitor
+	if telemetryProxyMetricsUrl, isSet := os.LookupEnv("LUMIGO_TELEMETRY_PROXY_METRICS_URL"); isSet {
+		telemetryProxyExportMonitor = telemetryproxymetrics.NewExportMonitor(telemetryProxyMetricsUrl, telemetryproxymetrics.DefaultWindow)
+	}
+
+	// The Lumigo endpoints are optional: if they are not set, the Lumigo backend is not probed
+	var lumigoBackendProbe *backendprobe.BackendProbe
+	lumigoBackendEndpoints := []string{}
+	for _, envVarName := range []string{"LUMIGO_ENDPOINT", "LUMIGO_LOGS_ENDPOINT"} {
+		if endpoint := os.Getenv(envVarName); len(endpoint) > 0 {
+			lumigoBackendEndpoints = append(lumigoBackendEndpoints, endpoint)
+		}
+	}
+	if len(lumigoBackendEndpoints) > 0 {
+		lumigoBackendProbe = backendprobe.NewBackendProbe(lumigoBackendEndpoints)
+	}
+
 	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
 	if err != nil {
 		return fmt.Errorf("cannot create the clientset client for the controller")
 	}
 
+	// GKE Autopilot and EKS on Fargate restrict host paths, DaemonSets and the resources of init containers;
+	// on those, the operator runs in compatibility mode. The platform is detected unless set explicitly.
+	lumigoPlatform, err := platform.Resolve(context.Background(), clientset, os.Getenv("LUMIGO_PLATFORM"), &setupLog)
+	if err != nil {
+		return fmt.Errorf("unable to create controller: %w", err)
+	}
+	if lumigoPlatform.IsRestricted() {
+		setupLog.Info("Running in compatibility mode", "platform", lumigoPlatform)
+	}
+	unsupportedPlatformFeatures := []platform.Feature{}
+
+	// Older clusters, or the ones without Keda or the OpenTelemetry operator, do not serve all the API resources
+	// the settings of the Lumigo instances may rely on
+	capabilitiesDiscoverer := capabilities.NewDiscoverer(clientset.Discovery(), capabilities.DefaultRefreshPeriod, ctrl.Log.WithName("capabilities"))
+
+	// In the DaemonSet mode, the workloads send telemetry to the telemetry-proxy on their own node
+	var telemetryProxyDaemonSetConfig *telemetryproxydaemonset.DaemonSetConfig
+	// In the sharded mode, the workloads send telemetry to the telemetry-proxy Deployment of the shard of their namespace
+	var telemetryProxyShardsConfig *telemetryproxyshards.ShardsConfig
+	if telemetryProxyMode := os.Getenv("LUMIGO_TELEMETRY_PROXY_MODE"); telemetryProxyMode == "daemonset" && !lumigoPlatform.Supports(platform.FeatureTelemetryProxyDaemonSet) {
+		// Fall back to the Deployment mode, and report it in the UnsupportedFeatures condition of the Lumigo instances
+		setupLog.Info("The telemetry-proxy DaemonSet mode is not supported on the platform, falling back to the Deployment mode", "platform", lumigoPlatform)
+		unsupportedPlatformFeatures = append(unsupportedPlatformFeatures, platform.FeatureTelemetryProxyDaemonSet)
+	} else if telemetryProxyMode == "daemonset" {
+		telemetryProxyDaemonSetConfig, err = newTelemetryProxyDaemonSetConfig(lumigoOperatorNamespace, lumigoOperatorServiceAccountName)
+		if err != nil {
+			return fmt.Errorf("unable to create controller: %w", err)
+		}
+
+		telemetryProxyNodeLocalEndpoint := fmt.Sprintf("http://$(%s):%d", mutation.TelemetryProxyHostIpEnvVarName, telemetryproxydaemonset.HostPort)
+		telemetryProxyOtlpService = telemetryProxyNodeLocalEndpoint + "/v1/traces"
+		telemetryProxyOtlpLogsService = telemetryProxyNodeLocalEndpoint + "/v1/logs"
+	} else if telemetryProxyMode == "sharded" {
+		// Each namespace sends its telemetry to the telemetry-proxy shard it is assigned to, so that a noisy namespace cannot starve the others
+		telemetryProxyShardsConfig, err = newTelemetryProxyShardsConfig(lumigoOperatorNamespace, lumigoOperatorServiceAccountName)
+		if err != nil {
+			return fmt.Errorf("unable to create controller: %w", err)
+		}
+		setupLog.Info("Running the telemetry-proxy in shards", "shards", telemetryProxyShardsConfig.ShardCount, "assignments", telemetryProxyShardsConfig.Assignments)
+	} else if telemetryProxyMode != "" && telemetryProxyMode != "deployment" {
+		return fmt.Errorf("unable to create controller: unsupported value '%s' of the 'LUMIGO_TELEMETRY_PROXY_MODE' environment variable; supported values: 'deployment', 'daemonset', 'sharded'", telemetryProxyMode)
+	}
+
+	// The dedicated telemetry-proxies are opt-in, as the operator needs to create Deployments in the monitored namespaces
+	var dedicatedTelemetryProxyConfig *telemetryproxydedicated.DedicatedProxyConfig
+	if os.Getenv("LUMIGO_DEDICATED_TELEMETRY_PROXIES_ENABLED") == "true" {
+		dedicatedTelemetryProxyConfig, err = newDedicatedTelemetryProxyConfig()
+		if err != nil {
+			return fmt.Errorf("unable to create controller: %w", err)
+		}
+	}
+
+	// The injected containers export over gRPC when the telemetry-proxy next to the controller receives it; the
+	// workloads that export to the telemetry-proxy DaemonSet, shards or dedicated proxies keep exporting over HTTP
+	if telemetryProxyOtlpGrpcService := os.Getenv("TELEMETRY_PROXY_OTLP_GRPC_SERVICE"); len(telemetryProxyOtlpGrpcService) > 0 {
+		if telemetryProxyDaemonSetConfig != nil || telemetryProxyShardsConfig != nil || dedicatedTelemetryProxyConfig != nil {
+			setupLog.Info("Not exporting over gRPC, which is supported only in the Deployment mode of the telemetry-proxy, without dedicated telemetry-proxies")
+		} else {
+			injectorDefaults = injectorDefaults.WithOtlpGrpcEndpoint(telemetryProxyOtlpGrpcService)
+		}
+	}
+
+	// NetworkPolicies are opt-in, as they isolate the pods they select in clusters without a default-deny policy
+	var networkPoliciesConfig *networkpolicies.NetworkPoliciesConfig
+	if os.Getenv("LUMIGO_NETWORK_POLICIES_ENABLED") == "true" {
+		networkPoliciesConfig = &networkpolicies.NetworkPoliciesConfig{
+			OperatorNamespace: lumigoOperatorNamespace,
+			OperatorPodLabels: map[string]string{
+				"control-plane": "controller-manager",
+			},
+		}
+
+		if telemetryProxyDaemonSetConfig != nil {
+			networkPoliciesConfig.TelemetryProxyDaemonSetPodLabels = telemetryproxydaemonset.PodSelectorLabels()
+		}
+		if telemetryProxyShardsConfig != nil {
+			networkPoliciesConfig.TelemetryProxyShardsPodLabels = telemetryproxyshards.PodSelectorLabels()
+		}
+		if dedicatedTelemetryProxyConfig != nil {
+			networkPoliciesConfig.DedicatedTelemetryProxyPodLabels = telemetryproxydedicated.PodSelectorLabels()
+		}
+	}
+
+	// The telemetry-proxy endpoints advertised to the workloads are probed unless opted out; with NetworkPolicies,
+	// only the names of the endpoints are resolved, as the telemetry-proxies may not accept connections from the operator
+	var telemetryProxyProbe *telemetryproxyprobe.TelemetryProxyProbe
+	if os.Getenv("LUMIGO_TELEMETRY_PROXY_PROBE_ENABLED") != "false" {
+		telemetryProxyProbe = telemetryproxyprobe.NewTelemetryProxyProbe(networkPoliciesConfig == nil)
+	}
+
+	// The failures of the `lumigo-injector` init containers in the injected pods are reported unless opted out
+	var injectorRuntimeFailureMonitor *injectorruntimefailures.Monitor
+	if os.Getenv("LUMIGO_INJECTOR_FAILURE_MONITORING_ENABLED") != "false" {
+		injectorRuntimeFailureMonitor = injectorruntimefailures.NewMonitor()
+	}
+
+	// The central token secret is opt-in: when configured, it is copied into the namespaces whose Lumigo instances reference a token secret that does not exist
+	var centralTokenSecretConfig *tokendistribution.CentralTokenSecretConfig
+	if centralTokenSecretName := os.Getenv("LUMIGO_CENTRAL_TOKEN_SECRET_NAME"); len(centralTokenSecretName) > 0 {
+		centralTokenSecretConfig = &tokendistribution.CentralTokenSecretConfig{
+			Namespace: lumigoOperatorNamespace,
+			Name:      centralTokenSecretName,
+			Key:       os.Getenv("LUMIGO_CENTRAL_TOKEN_SECRET_KEY"),
+		}
+		if len(centralTokenSecretConfig.Key) < 1 {
+			centralTokenSecretConfig.Key = "token"
+		}
+	}
+
+	// The auto-instrumentation of namespaces is opt-in: when configured, the operator creates a Lumigo instance in the namespaces
+	// matching the selector, whose token secret is copied from the central one
+	var namespaceAutoInstrumentationConfig *controllers.NamespaceAutoInstrumentationConfig
+	if namespaceSelector, isSet := os.LookupEnv("LUMIGO_NAMESPACE_AUTO_INSTRUMENTATION_SELECTOR"); isSet {
+		if len(watchNamespaces) > 0 {
+			return fmt.Errorf("unable to create controller: the auto-instrumentation of namespaces is not supported in the namespace-scoped mode")
+		}
+
+		if centralTokenSecretConfig == nil {
+			return fmt.Errorf("unable to create controller: the auto-instrumentation of namespaces requires the 'LUMIGO_CENTRAL_TOKEN_SECRET_NAME' environment variable to be set")
+		}
+
+		if len(namespaceSelector) < 1 {
+			namespaceSelector = controllers.DefaultNamespaceAutoInstrumentationSelector
+		}
+
+		selector, err := labels.Parse(namespaceSelector)
+		if err != nil {
+			return fmt.Errorf("unable to create controller: invalid value '%s' of the 'LUMIGO_NAMESPACE_AUTO_INSTRUMENTATION_SELECTOR' environment variable: %w", namespaceSelector, err)
+		}
+
+		namespaceAutoInstrumentationConfig = &controllers.NamespaceAutoInstrumentationConfig{
+			Selector: selector,
+		}
+	}
+
+	// The instrumented namespaces of the Helm values are opt-in: when configured, the operator creates, updates and deletes
+	// the Lumigo instances of the listed namespaces, whose token secret defaults to a copy of the central one
+	var instrumentedNamespacesList []instrumentednamespaces.InstrumentedNamespace
+	if instrumentedNamespacesValue := os.Getenv("LUMIGO_INSTRUMENTED_NAMESPACES"); len(instrumentedNamespacesValue) > 0 {
+		if len(watchNamespaces) > 0 {
+			return fmt.Errorf("unable to create controller: the instrumented namespaces are not supported in the namespace-scoped mode")
+		}
+
+		instrumentedNamespacesList, err = instrumentednamespaces.Parse(instrumentedNamespacesValue)
+		if err != nil {
+			return fmt.Errorf("unable to create controller: invalid value of the 'LUMIGO_INSTRUMENTED_NAMESPACES' environment variable: %w", err)
+		}
+
+		for _, instrumentedNamespace := range instrumentedNamespacesList {
+			if !instrumentedNamespace.HasToken() && centralTokenSecretConfig == nil {
+				return fmt.Errorf("unable to create controller: the instrumented namespace '%s' has no Lumigo token secret, and the 'LUMIGO_CENTRAL_TOKEN_SECRET_NAME' environment variable is not set", instrumentedNamespace.Name)
+			}
+		}
+	}
+
+	// The telemetry verification Jobs run the image of the controller, which sends the synthetic trace with the
+	// '--verify-telemetry' flag; if it is not set, the telemetry of the Lumigo instances cannot be verified
+	var telemetryVerificationConfig *telemetryverification.Config
+	if controllerImage := os.Getenv("LUMIGO_CONTROLLER_IMAGE"); len(controllerImage) > 0 {
+		telemetryVerificationConfig = &telemetryverification.Config{
+			Image: controllerImage,
+		}
+	}
+
+	// With the leader file set, the telemetry-proxy next to the controller collects the cluster-wide telemetry only
+	// while this replica of the controller manager is the leader; the file of a previous leadership is removed first
+	if leaderFilePath := os.Getenv("LUMIGO_CLUSTER_COLLECTOR_LEADER_FILE"); len(leaderFilePath) > 0 {
+		if err := telemetryproxyconfigs.ResignClusterCollectorLeadership(leaderFilePath); err != nil {
+			return fmt.Errorf("unable to set up the cluster collector leadership: %w", err)
+		}
+
+		if err := mgr.Add(telemetryproxyconfigs.NewClusterCollectorLeadership(leaderFilePath, ctrl.Log.WithName("cluster-collector-leadership"))); err != nil {
+			return fmt.Errorf("unable to set up the cluster collector leadership: %w", err)
+		}
+	}
+
+	// Self-telemetry is optional: if the Lumigo token to send it with is not set, the operator does not trace itself
+	var selfTelemetry *selftelemetry.Tracer
+	if selfTelemetryToken := os.Getenv("LUMIGO_SELF_TELEMETRY_TOKEN"); len(selfTelemetryToken) > 0 {
+		selfTelemetry = selftelemetry.NewTracer(telemetryProxyOtlpService, selfTelemetryToken, os.Getenv("LUMIGO_SELF_TELEMETRY_SERVICE_NAME"), ctrl.Log.WithName("self-telemetry"))
+		if err := mgr.Add(selfTelemetry); err != nil {
+			return fmt.Errorf("unable to set up self-telemetry: %w", err)
+		}
+	}
+
+	// The usage of the namespaces is reported only if the metrics of the telemetry-proxy are available
+	if telemetryProxyExportMonitor != nil {
+		usageReporter := telemetryproxymetrics.NewUsageReporter(telemetryProxyExportMonitor, clientset.CoreV1(), lumigoOperatorNamespace, telemetryproxymetrics.DefaultUsageReportInterval, ctrl.Log.WithName("usage-reporter"))
+		if err := mgr.Add(usageReporter); err != nil {
+			return fmt.Errorf("unable to set up the usage reporter: %w", err)
+		}
+	}
+
+	// The webhook configurations are installed with the `Ignore` failure policy during upgrades only if the maintenance mode is enabled
+	if webhookConfigurationNames := os.Getenv("LUMIGO_WEBHOOK_MAINTENANCE_CONFIGURATIONS"); len(webhookConfigurationNames) > 0 {
+		deploymentName, isSet := os.LookupEnv("LUMIGO_CONTROLLER_DEPLOYMENT_NAME")
+		if !isSet {
+			return fmt.Errorf("unable to set up the webhook maintenance mode: environment variable 'LUMIGO_CONTROLLER_DEPLOYMENT_NAME' is not set")
+		}
+
+		restorer := webhookmaintenance.NewRestorer(clientset.AdmissionregistrationV1(), strings.Split(webhookConfigurationNames, ","), clientset.AppsV1(), lumigoOperatorNamespace, deploymentName, mgr.GetWebhookServer().StartedChecker(), webhookmaintenance.DefaultRestoreInterval, ctrl.Log.WithName("webhook-maintenance"))
+		if err := mgr.Add(restorer); err != nil {
+			return fmt.Errorf("unable to set up the webhook maintenance mode: %w", err)
+		}
+	}
+
+	// The verification of the injector image is opt-in: it is enabled by configuring either a public key or a keyless identity
+	injectorImageVerifier, err := newInjectorImageVerifier()
+	if err != nil {
+		return fmt.Errorf("unable to create controller: %w", err)
+	}
+
 	dynamicClient, err := dynamic.NewForConfig(mgr.GetConfig())
 	if err != nil {
 		return fmt.Errorf("cannot create the dynamic client for the controller")
 	}
 
+	// The ServiceMonitors of the operator are managed only when the Prometheus operator CRDs are installed; the syncer
+	// runs regardless of the enabled ServiceMonitors, so that it deletes those that have been disabled
+	serviceMonitorSyncer := servicemonitors.NewSyncer(dynamicClient, clientset.CoreV1(), func() bool {
+		return capabilitiesDiscoverer.Get(time.Now()).Has(capabilities.PrometheusServiceMonitors)
+	}, lumigoOperatorNamespace, newServiceMonitorTargets(metricsOpts), servicemonitors.DefaultSyncInterval, ctrl.Log.WithName("service-monitors"))
+	if err := mgr.Add(serviceMonitorSyncer); err != nil {
+		return fmt.Errorf("unable to set up the ServiceMonitors: %w", err)
+	}
+
+	// The audit of the mutations is opt-in: its entries can be written to stdout, separately from the logs on stderr, and to ConfigMaps
+	auditor, err := newAuditor(lumigoOperatorVersion, clientset)
+	if err != nil {
+		return fmt.Errorf("unable to create controller: %w", err)
+	}
+
+	// The cluster-wide limit of the workloads updated to add the injection is opt-in; the Lumigo instances can set their own limits regardless
+	var workloadUpdatePacer *workloadpacing.Pacer
+	if value, isSet := os.LookupEnv("LUMIGO_MAX_CONCURRENT_WORKLOAD_UPDATES"); isSet && len(value) > 0 {
+		maxConcurrentWorkloadUpdates, err := strconv.Atoi(value)
+		if err != nil || maxConcurrentWorkloadUpdates < 1 {
+			return fmt.Errorf("unable to create controller: the 'LUMIGO_MAX_CONCURRENT_WORKLOAD_UPDATES' environment variable must be a positive integer, found '%s'", value)
+		}
+		workloadUpdatePacer = workloadpacing.NewPacer(maxConcurrentWorkloadUpdates, workloadpacing.DefaultPeriod)
+	}
+
+	// The garbage collection of the partial and orphaned injection artifacts runs unless opted out
+	var injectionGCInterval time.Duration
+	if os.Getenv("LUMIGO_INJECTION_GC_ENABLED") != "false" {
+		injectionGCInterval = injectiongc.DefaultInterval
+		if value := os.Getenv("LUMIGO_INJECTION_GC_INTERVAL"); len(value) > 0 {
+			if injectionGCInterval, err = time.ParseDuration(value); err != nil || injectionGCInterval <= 0 {
+				return fmt.Errorf("unable to create controller: the 'LUMIGO_INJECTION_GC_INTERVAL' environment variable must be a positive duration, found '%s'", value)
+			}
+		}
+	}
+
+	// The workloads of the operator are never injected, lest the injection breaks the webhooks and the telemetry-proxy
+	// that the injection of all the other workloads depends on
+	var protectedWorkloadSelectors []string
+	if value := os.Getenv("LUMIGO_PROTECTED_WORKLOAD_SELECTORS"); len(strings.TrimSpace(value)) > 0 {
+		// The label selectors contain commas, so they are separated by semicolons
+		for _, selector := range strings.Split(value, ";") {
+			if selector = strings.TrimSpace(selector); len(selector) > 0 {
+				protectedWorkloadSelectors = append(protectedWorkloadSelectors, selector)
+			}
+		}
+	}
+	protectedWorkloads, err := mutation.NewProtectedWorkloads(append([]string{lumigoOperatorNamespace}, parseNamespaces(os.Getenv("LUMIGO_PROTECTED_WORKLOAD_NAMESPACES"))...), protectedWorkloadSelectors)
+	if err != nil {
+		return fmt.Errorf("unable to create controller: the 'LUMIGO_PROTECTED_WORKLOAD_SELECTORS' environment variable is invalid: %w", err)
+	}
+
 	if err = (&controllers.LumigoReconciler{
 		Client:                           mgr.GetClient(),
 		Clientset:                        clientset,
 		DynamicClient:                    dynamicClient,
-		EventRecorder:                    mgr.GetEventRecorderFor(fmt.Sprintf("lumigo-operator.v%s/controller", lumigoOperatorVersion)),
+		APIReader:                        mgr.GetAPIReader(),
+		EventRecorder:                    redaction.NewEventRecorder(mgr.GetEventRecorderFor(fmt.Sprintf("lumigo-operator.v%s/controller", lumigoOperatorVersion))),
 		Scheme:                           mgr.GetScheme(),
 		LumigoOperatorVersion:            lumigoOperatorVersion,
 		LumigoInjectorImage:              lumigoInjectorImage,
 		TelemetryProxyOtlpServiceUrl:     telemetryProxyOtlpService,
 		TelemetryProxyOtlpLogsServiceUrl: telemetryProxyOtlpLogsService,
 		TelemetryProxyNamespaceConfigurationsPath: namespaceConfigurationsPath,
-		Log: logger,
+		LumigoOperatorNamespace:                   lumigoOperatorNamespace,
+		LumigoOperatorServiceAccountName:          lumigoOperatorServiceAccountName,
+		GoInstrumentationAgentImage:               goInstrumentationAgentImage,
+		TelemetryProxyExportMonitor:               telemetryProxyExportMonitor,
+		LumigoBackendProbe:                        lumigoBackendProbe,
+		TelemetryProxyProbe:                       telemetryProxyProbe,
+		TelemetryProxyDaemonSetConfig:             telemetryProxyDaemonSetConfig,
+		TelemetryProxyShardsConfig:                telemetryProxyShardsConfig,
+		DedicatedTelemetryProxyConfig:             dedicatedTelemetryProxyConfig,
+		NetworkPoliciesConfig:                     networkPoliciesConfig,
+		InjectorImageVerifier:                     injectorImageVerifier,
+		InjectorDefaults:                          injectorDefaults,
+		ProtectedWorkloads:                        protectedWorkloads,
+		Auditor:                                   auditor,
+		InjectorRuntimeFailureMonitor:             injectorRuntimeFailureMonitor,
+		InstrumentedResources:                     instrumentedresources.NewIndex(),
+		CentralTokenSecretConfig:                  centralTokenSecretConfig,
+		SelfTelemetry:                             selfTelemetry,
+		TelemetryVerificationConfig:               telemetryVerificationConfig,
+		WorkloadUpdatePacer:                       workloadUpdatePacer,
+		Platform:                                  lumigoPlatform,
+		UnsupportedPlatformFeatures:               unsupportedPlatformFeatures,
+		CapabilitiesDiscoverer:                    capabilitiesDiscoverer,
+		InjectionGCInterval:                       injectionGCInterval,
+		Log:                                       logger,
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create controller: %w", err)
 	}
 
-	if err = (&injector.LumigoInjectorWebhookHandler{
-		EventRecorder:                    mgr.GetEventRecorderFor(fmt.Sprintf("lumigo-operator.v%s/injector-webhook", lumigoOperatorVersion)),
+	if namespaceAutoInstrumentationConfig != nil {
+		if err = (&controllers.NamespaceReconciler{
+			Client: mgr.GetClient(),
+			Config: namespaceAutoInstrumentationConfig,
+			Log:    ctrl.Log.WithName("controllers").WithName("Namespace"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create namespace controller: %w", err)
+		}
+	}
+
+	if instrumentedNamespacesList != nil {
+		if err = (&controllers.InstrumentedNamespacesReconciler{
+			Client:                 mgr.GetClient(),
+			InstrumentedNamespaces: instrumentedNamespacesList,
+			Log:                    ctrl.Log.WithName("controllers").WithName("InstrumentedNamespaces"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create instrumented namespaces controller: %w", err)
+		}
+	}
+
+	// The migration of the legacy annotations is opt-in, as it changes the namespaces and workloads that carry them
+	if os.Getenv("LUMIGO_LEGACY_ANNOTATIONS_MIGRATION_ENABLED") == "true" {
+		if len(watchNamespaces) > 0 {
+			return fmt.Errorf("unable to create controller: the migration of the legacy annotations is not supported in the namespace-scoped mode")
+		}
+
+		if err = (&controllers.LegacyAnnotationsReconciler{
+			Client:                    mgr.GetClient(),
+			CentralTokenSecretEnabled: centralTokenSecretConfig != nil,
+			Log:                       ctrl.Log.WithName("controllers").WithName("LegacyAnnotations"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create legacy annotations controller: %w", err)
+		}
+	}
+
+	if injectorRuntimeFailureMonitor != nil {
+		if err = (&controllers.InjectorPodReconciler{
+			Client:  mgr.GetClient(),
+			Monitor: injectorRuntimeFailureMonitor,
+			Log:     ctrl.Log.WithName("controllers").WithName("InjectorPod"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create injector pod controller: %w", err)
+		}
+	}
+
+	injectorWebhookHandler := &injector.LumigoInjectorWebhookHandler{
+		EventRecorder:                    redaction.NewEventRecorder(mgr.GetEventRecorderFor(fmt.Sprintf("lumigo-operator.v%s/injector-webhook", lumigoOperatorVersion))),
 		LumigoOperatorVersion:            lumigoOperatorVersion,
 		LumigoInjectorImage:              lumigoInjectorImage,
 		TelemetryProxyOtlpServiceUrl:     telemetryProxyOtlpService,
 		TelemetryProxyOtlpLogsServiceUrl: telemetryProxyOtlpLogsService,
-		Log:                              logger,
-	}).SetupWebhookWithManager(mgr); err != nil {
+		TelemetryProxyShardsConfig:       telemetryProxyShardsConfig,
+		DedicatedTelemetryProxyConfig:    dedicatedTelemetryProxyConfig,
+		InjectorImageVerifier:            injectorImageVerifier,
+		InjectorDefaults:                 injectorDefaults,
+		ProtectedWorkloads:               protectedWorkloads,
+		Auditor:                          auditor,
+		LumigoLookupFreshness:            injector.DefaultLumigoLookupFreshness,
+		SelfTelemetry:                    selfTelemetry,
+		Platform:                         lumigoPlatform,
+		RejectionEventsEnabled:           os.Getenv("LUMIGO_INJECTOR_WEBHOOK_REJECTION_EVENTS_ENABLED") == "true",
+		Log:                              ctrl.Log.WithName("webhook").WithName("Lumigo"),
+	}
+	if err = injectorWebhookHandler.SetupWebhookWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create injector webhook: %w", err)
 	}
 
 	if err = (&defaulter.LumigoDefaulterWebhookHandler{
-		LumigoOperatorVersion: lumigoOperatorVersion,
-		Log:                   logger,
+		LumigoOperatorVersion:  lumigoOperatorVersion,
+		Log:                    ctrl.Log.WithName("webhook").WithName("Lumigo"),
+		CapabilitiesDiscoverer: capabilitiesDiscoverer,
 	}).SetupWebhookWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create defaulter webhook: %w", err)
 	}
 
+	if err = (&operatorv1beta1.Lumigo{}).SetupWebhookWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create conversion webhook: %w", err)
+	}
+
 	//+kubebuilder:scaffold:builder
 
+	addMetricsHandler := mgr.AddMetricsExtraHandler
+	if metricsOpts.SecureServing {
+		metricsServer := metricsserver.NewServer(metricsAddr, metricsOpts, configureTLS, clientset.AuthenticationV1().TokenReviews(), clientset.AuthorizationV1().SubjectAccessReviews(), ctrl.Log.WithName("metrics-server"))
+		if err := mgr.Add(metricsServer); err != nil {
+			return fmt.Errorf("unable to set up the metrics server: %w", err)
+		}
+		addMetricsHandler = metricsServer.AddHandler
+	}
+
+	// The metrics endpoint authorizes the changes of the log levels like the non-resource URLs of the Kubernetes API
+	if err := addMetricsHandler("/log-levels", logLevels); err != nil {
+		return fmt.Errorf("unable to set up the log levels endpoint: %w", err)
+	}
+	// The platform tooling can ask whether workloads would be injected before shipping them, with the same authorization
+	if err := addMetricsHandler("/injection-preview", &injector.InjectionPreviewHandler{Webhook: injectorWebhookHandler}); err != nil {
+		return fmt.Errorf("unable to set up the injection preview endpoint: %w", err)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		return fmt.Errorf("unable to set up health check: %w", err)
 	}
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		return fmt.Errorf("unable to set up ready check: %w", err)
 	}
+	// The operator is not ready if the API server cannot call its webhooks, or if the instrumented workloads cannot send telemetry
+	if err := mgr.AddReadyzCheck("webhook-certificate", healthchecks.NewCertificateChecker(webhookCertificatePath(mgr), time.Now)); err != nil {
+		return fmt.Errorf("unable to set up the webhook certificate ready check: %w", err)
+	}
+	telemetryProxyChecker, err := healthchecks.NewServiceResolvableChecker(lumigoEndpoint, net.DefaultResolver)
+	if err != nil {
+		return fmt.Errorf("unable to set up the telemetry-proxy ready check: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("telemetry-proxy", telemetryProxyChecker); err != nil {
+		return fmt.Errorf("unable to set up the telemetry-proxy ready check: %w", err)
+	}
 
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		return fmt.Errorf("problem running manager: %w", err)
@@ -201,7 +765,7 @@ func startManager(metricsAddr string, probeAddr string, enableLeaderElection boo
 	return nil
 }
 
-func uninstallHook() error {
+func uninstallHook(watchNamespaces []string) error {
 	logger := ctrl.Log.WithName("uninstaller").WithName("Lumigo")
 
 	config := ctrl.GetConfigOrDie()
@@ -221,62 +785,94 @@ func uninstallHook() error {
 		return fmt.Errorf("cannot initialize client: %w", err)
 	}
 
+	// In the namespace-scoped mode, the Lumigo resources can be listed and watched only namespace by namespace
+	namespaces := watchNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{"" /* all namespaces */}
+	}
+
 	ctx := context.TODO()
-	lumigoes := &operatorv1alpha1.LumigoList{}
-	if err := Client.List(ctx, lumigoes); err != nil {
-		return fmt.Errorf("an error occurred while listing existing Lumigo resources: %w", err)
+	lumigoes := []operatorv1alpha1.Lumigo{}
+	for _, namespace := range namespaces {
+		lumigoesInNamespace := &operatorv1alpha1.LumigoList{}
+		if err := Client.List(ctx, lumigoesInNamespace, client.InNamespace(namespace)); err != nil {
+			return fmt.Errorf("an error occurred while listing existing Lumigo resources: %w", err)
+		}
+		lumigoes = append(lumigoes, lumigoesInNamespace.Items...)
 	}
 
-	if len(lumigoes.Items) == 0 {
+	if len(lumigoes) == 0 {
 		logger.Info("No Lumigo resources to delete")
 		return nil
 	}
 
-	logger.Info("Deleting all Lumigo resources", "lumigo-count", len(lumigoes.Items))
+	logger.Info("Deleting all Lumigo resources", "lumigo-count", len(lumigoes))
 
-	lumigoesLeft := make([]operatorv1alpha1.Lumigo, len(lumigoes.Items))
-	copy(lumigoesLeft, lumigoes.Items)
+	lumigoesLeft := make([]operatorv1alpha1.Lumigo, len(lumigoes))
+	copy(lumigoesLeft, lumigoes)
+	var lumigoesLeftMutex sync.Mutex
 
 	resource := operatorv1alpha1.GroupVersion.WithResource("lumigoes")
-	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 0 /* TODO */, "" /* all namespaces */, nil)
 
 	deletionCompletedChannel := make(chan error)
 	stopInformerChannel := make(chan struct{})
 
-	informer := factory.ForResource(resource).Informer()
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			logger.Info(fmt.Sprintf("Unexpected 'add' event for Lumigo resources: %+v", obj))
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			logger.Info(fmt.Sprintf("Unexpected 'update' event for Lumigo resources: %+v", newObj))
-		},
-		DeleteFunc: func(obj interface{}) {
-			deletedLumigo := obj.(unstructured.Unstructured)
-			for i, l := range lumigoesLeft {
-				if l.Namespace == deletedLumigo.GetNamespace() && l.Name == deletedLumigo.GetName() {
-					lumigoesLeft = append(lumigoesLeft[:i], lumigoesLeft[i+1:]...)
+	informers := []cache.SharedIndexInformer{}
+	for _, namespace := range namespaces {
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 0 /* TODO */, namespace, nil)
+
+		informer := factory.ForResource(resource).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				logger.Info(fmt.Sprintf("Unexpected 'add' event for Lumigo resources: %+v", obj))
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				logger.Info(fmt.Sprintf("Unexpected 'update' event for Lumigo resources: %+v", newObj))
+			},
+			DeleteFunc: func(obj interface{}) {
+				lumigoesLeftMutex.Lock()
+				defer lumigoesLeftMutex.Unlock()
+
+				if len(lumigoesLeft) == 0 {
+					// The informers are already stopping
+					return
 				}
-			}
 
-			if len(lumigoesLeft) == 0 {
-				close(stopInformerChannel)
-			}
-		},
-	})
+				deletedLumigo := obj.(unstructured.Unstructured)
+				for i, l := range lumigoesLeft {
+					if l.Namespace == deletedLumigo.GetNamespace() && l.Name == deletedLumigo.GetName() {
+						lumigoesLeft = append(lumigoesLeft[:i], lumigoesLeft[i+1:]...)
+					}
+				}
+
+				if len(lumigoesLeft) == 0 {
+					close(stopInformerChannel)
+				}
+			},
+		})
+		informers = append(informers, informer)
+	}
 
 	go func() {
 		/*
 		 * Informer.Run blocks until stopInformerChannel is closed,
 		 * which we will close when there are no more Lumigo resources left.
 		 */
+		var informersRunning sync.WaitGroup
+		for _, informer := range informers {
+			informersRunning.Add(1)
+			go func(informer cache.SharedIndexInformer) {
+				defer informersRunning.Done()
+				informer.Run(stopInformerChannel)
+			}(informer)
+		}
 		logger.Info("Informer started")
-		informer.Run(stopInformerChannel)
+		informersRunning.Wait()
 		logger.Info("Informer stopped")
 		deletionCompletedChannel <- nil
 	}()
 
-	for _, lumigo := range lumigoes.Items {
+	for _, lumigo := range lumigoes {
 		if err := Client.Delete(ctx, &lumigo); err != nil {
 			logger.Error(err, "An error occurred while deleting a Lumigo resource", "namespace", lumigo.Namespace, "name", lumigo.Name, "lumigo", lumigo)
 		} else {
@@ -286,3 +882,273 @@ func uninstallHook() error {
 
 	return <-deletionCompletedChannel
 }
+
+// parseNamespaces returns the non-empty names in the comma-separated list of namespaces
+func parseNamespaces(commaSeparatedNamespaces string) []string {
+	namespaces := []string{}
+	for _, namespace := range strings.Split(commaSeparatedNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); len(namespace) > 0 {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+func appendIfMissing(namespaces []string, namespace string) []string {
+	for _, n := range namespaces {
+		if n == namespace {
+			return namespaces
+		}
+	}
+
+	return append(namespaces, namespace)
+}
+
+// The environment variables of the telemetry-proxy next to the controller that the telemetry-proxy
+// DaemonSet and shards need as well, and which are therefore passed to the controller too
+var telemetryProxyDaemonSetEnvVarNames = []string{
+	"KUBERNETES_CLUSTER_NAME",
+	"LUMIGO_DEBUG",
+	"LUMIGO_DEPLOYMENT_ENVIRONMENT",
+	"LUMIGO_ENDPOINT",
+	"LUMIGO_LOGS_ENDPOINT",
+	"LUMIGO_OPERATOR_VERSION",
+	"LUMIGO_OPERATOR_DEPLOYMENT_METHOD",
+	"LUMIGO_MEMORY_LIMITER_CHECK_INTERVAL",
+	"LUMIGO_MEMORY_LIMITER_LIMIT_PERCENTAGE",
+	"LUMIGO_MEMORY_LIMITER_SPIKE_LIMIT_PERCENTAGE",
+	"LUMIGO_BATCH_SEND_BATCH_SIZE",
+	"LUMIGO_BATCH_SEND_BATCH_MAX_SIZE",
+	"LUMIGO_BATCH_TIMEOUT",
+	"LUMIGO_EXPORT_COMPRESSION",
+	"LUMIGO_EXPORT_WRITE_BUFFER_SIZE",
+	"LUMIGO_EXPORT_RETRY_INITIAL_INTERVAL",
+	"LUMIGO_EXPORT_RETRY_MAX_INTERVAL",
+	"LUMIGO_EXPORT_RETRY_MAX_ELAPSED_TIME",
+	"LUMIGO_EXPORT_QUEUE_NUM_CONSUMERS",
+	"LUMIGO_EXPORT_QUEUE_SIZE",
+	"LUMIGO_TLS_MIN_VERSION",
+}
+
+// newTelemetryProxySettings returns the image, environment variables and resources of the telemetry-proxy
+// pods that the operator deploys; the condition is the one under which they are deployed, for the error messages
+func newTelemetryProxySettings(condition string) (string, []corev1.EnvVar, corev1.ResourceRequirements, error) {
+	var telemetryProxyResources corev1.ResourceRequirements
+
+	telemetryProxyImage, isSet := os.LookupEnv("LUMIGO_TELEMETRY_PROXY_IMAGE")
+	if !isSet {
+		return "", nil, telemetryProxyResources, fmt.Errorf("environment variable 'LUMIGO_TELEMETRY_PROXY_IMAGE' is not set, but it is required when %s", condition)
+	}
+
+	if telemetryProxyResourcesJson := os.Getenv("LUMIGO_TELEMETRY_PROXY_RESOURCES"); len(telemetryProxyResourcesJson) > 0 {
+		if err := json.Unmarshal([]byte(telemetryProxyResourcesJson), &telemetryProxyResources); err != nil {
+			return "", nil, telemetryProxyResources, fmt.Errorf("cannot parse the 'LUMIGO_TELEMETRY_PROXY_RESOURCES' environment variable: %w", err)
+		}
+	}
+
+	telemetryProxyEnv := []corev1.EnvVar{}
+	for _, envVarName := range telemetryProxyDaemonSetEnvVarNames {
+		if value, isSet := os.LookupEnv(envVarName); isSet {
+			telemetryProxyEnv = append(telemetryProxyEnv, corev1.EnvVar{
+				Name:  envVarName,
+				Value: value,
+			})
+		}
+	}
+
+	return telemetryProxyImage, telemetryProxyEnv, telemetryProxyResources, nil
+}
+
+func newTelemetryProxyDaemonSetConfig(lumigoOperatorNamespace string, lumigoOperatorServiceAccountName string) (*telemetryproxydaemonset.DaemonSetConfig, error) {
+	telemetryProxyImage, telemetryProxyEnv, telemetryProxyResources, err := newTelemetryProxySettings("'LUMIGO_TELEMETRY_PROXY_MODE' is 'daemonset'")
+	if err != nil {
+		return nil, err
+	}
+
+	return &telemetryproxydaemonset.DaemonSetConfig{
+		Namespace:          lumigoOperatorNamespace,
+		ServiceAccountName: lumigoOperatorServiceAccountName,
+		Image:              telemetryProxyImage,
+		Env:                telemetryProxyEnv,
+		Resources:          telemetryProxyResources,
+	}, nil
+}
+
+func newTelemetryProxyShardsConfig(lumigoOperatorNamespace string, lumigoOperatorServiceAccountName string) (*telemetryproxyshards.ShardsConfig, error) {
+	telemetryProxyImage, telemetryProxyEnv, telemetryProxyResources, err := newTelemetryProxySettings("'LUMIGO_TELEMETRY_PROXY_MODE' is 'sharded'")
+	if err != nil {
+		return nil, err
+	}
+
+	shardCount, err := strconv.Atoi(os.Getenv("LUMIGO_TELEMETRY_PROXY_SHARDS"))
+	if err != nil {
+		return nil, fmt.Errorf("the 'LUMIGO_TELEMETRY_PROXY_SHARDS' environment variable must be a positive integer when 'LUMIGO_TELEMETRY_PROXY_MODE' is 'sharded', found '%s'", os.Getenv("LUMIGO_TELEMETRY_PROXY_SHARDS"))
+	}
+
+	assignments := map[string]int{}
+	if assignmentsJson := os.Getenv("LUMIGO_TELEMETRY_PROXY_SHARD_ASSIGNMENTS"); len(assignmentsJson) > 0 {
+		if err := json.Unmarshal([]byte(assignmentsJson), &assignments); err != nil {
+			return nil, fmt.Errorf("cannot parse the 'LUMIGO_TELEMETRY_PROXY_SHARD_ASSIGNMENTS' environment variable: %w", err)
+		}
+	}
+
+	var replicas int64 = 1
+	if replicasString := os.Getenv("LUMIGO_TELEMETRY_PROXY_SHARD_REPLICAS"); len(replicasString) > 0 {
+		replicas, err = strconv.ParseInt(replicasString, 10, 32)
+		if err != nil || replicas < 1 {
+			return nil, fmt.Errorf("the 'LUMIGO_TELEMETRY_PROXY_SHARD_REPLICAS' environment variable must be a positive integer, found '%s'", replicasString)
+		}
+	}
+
+	shardsConfig := &telemetryproxyshards.ShardsConfig{
+		Namespace:                  lumigoOperatorNamespace,
+		ServiceAccountName:         lumigoOperatorServiceAccountName,
+		Image:                      telemetryProxyImage,
+		Env:                        telemetryProxyEnv,
+		Resources:                  telemetryProxyResources,
+		ShardCount:                 shardCount,
+		Assignments:                assignments,
+		Replicas:                   int32(replicas),
+		PodDisruptionBudgetEnabled: os.Getenv("LUMIGO_POD_DISRUPTION_BUDGETS_ENABLED") == "true",
+		PodAntiAffinity:            os.Getenv("LUMIGO_POD_ANTI_AFFINITY"),
+	}
+	if err := shardsConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	return shardsConfig, nil
+}
+
+func newDedicatedTelemetryProxyConfig() (*telemetryproxydedicated.DedicatedProxyConfig, error) {
+	telemetryProxyImage, telemetryProxyEnv, telemetryProxyResources, err := newTelemetryProxySettings("'LUMIGO_DEDICATED_TELEMETRY_PROXIES_ENABLED' is 'true'")
+	if err != nil {
+		return nil, err
+	}
+
+	clusterRoleBindingName, isSet := os.LookupEnv("LUMIGO_DEDICATED_TELEMETRY_PROXY_CLUSTER_ROLE_BINDING")
+	if !isSet {
+		return nil, fmt.Errorf("environment variable 'LUMIGO_DEDICATED_TELEMETRY_PROXY_CLUSTER_ROLE_BINDING' is not set, but it is required when 'LUMIGO_DEDICATED_TELEMETRY_PROXIES_ENABLED' is 'true'")
+	}
+
+	return &telemetryproxydedicated.DedicatedProxyConfig{
+		Image:                  telemetryProxyImage,
+		Env:                    telemetryProxyEnv,
+		Resources:              telemetryProxyResources,
+		ClusterRoleBindingName: clusterRoleBindingName,
+	}, nil
+}
+
+func newInjectorImageVerifier() (*imageverification.Verifier, error) {
+	publicKeyPem := os.Getenv("LUMIGO_INJECTOR_IMAGE_VERIFICATION_PUBLIC_KEY")
+	identity := os.Getenv("LUMIGO_INJECTOR_IMAGE_VERIFICATION_IDENTITY")
+	if len(publicKeyPem) < 1 && len(identity) < 1 {
+		return nil, nil
+	}
+
+	policy := &imageverification.Policy{}
+	if len(publicKeyPem) > 0 {
+		publicKey, err := imageverification.ParsePublicKey([]byte(publicKeyPem))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the 'LUMIGO_INJECTOR_IMAGE_VERIFICATION_PUBLIC_KEY' environment variable: %w", err)
+		}
+		policy.PublicKey = publicKey
+	}
+
+	if len(identity) > 0 {
+		fulcioRoots, err := imageverification.ParseCertificatePool([]byte(os.Getenv("LUMIGO_INJECTOR_IMAGE_VERIFICATION_FULCIO_ROOTS")))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the 'LUMIGO_INJECTOR_IMAGE_VERIFICATION_FULCIO_ROOTS' environment variable: %w", err)
+		}
+
+		rekorPublicKey, err := imageverification.ParsePublicKey([]byte(os.Getenv("LUMIGO_INJECTOR_IMAGE_VERIFICATION_REKOR_PUBLIC_KEY")))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the 'LUMIGO_INJECTOR_IMAGE_VERIFICATION_REKOR_PUBLIC_KEY' environment variable: %w", err)
+		}
+
+		policy.Keyless = &imageverification.KeylessPolicy{
+			Identity:       identity,
+			Issuer:         os.Getenv("LUMIGO_INJECTOR_IMAGE_VERIFICATION_ISSUER"),
+			FulcioRoots:    fulcioRoots,
+			RekorPublicKey: rekorPublicKey,
+		}
+	}
+
+	var registryCredentials map[string]imageverification.RegistryCredentials
+	if registryAuthFile, isSet := os.LookupEnv("LUMIGO_INJECTOR_IMAGE_VERIFICATION_REGISTRY_AUTH_FILE"); isSet {
+		dockerConfigJson, err := os.ReadFile(registryAuthFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the registry credentials file '%s': %w", registryAuthFile, err)
+		}
+
+		if registryCredentials, err = imageverification.ParseDockerConfigJson(dockerConfigJson); err != nil {
+			return nil, fmt.Errorf("cannot parse the registry credentials file '%s': %w", registryAuthFile, err)
+		}
+	}
+
+	verifier, err := imageverification.NewVerifier(policy, registryCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration of the injector image verification: %w", err)
+	}
+
+	return verifier, nil
+}
+
+// newServiceMonitorTargets returns the Services of the operator whose metrics are scraped through ServiceMonitors:
+// the metrics endpoint of the controller manager and the one of the telemetry-proxy next to it
+func newServiceMonitorTargets(metricsOpts *metricsserver.Options) []servicemonitors.Target {
+	targets := []servicemonitors.Target{}
+
+	if os.Getenv("LUMIGO_METRICS_SERVICE_MONITOR_ENABLED") == "true" {
+		target := servicemonitors.Target{
+			ServiceName: os.Getenv("LUMIGO_METRICS_SERVICE_NAME"),
+			Port:        "https",
+			Scheme:      "http",
+			Interval:    os.Getenv("LUMIGO_METRICS_SERVICE_MONITOR_INTERVAL"),
+		}
+		if metricsOpts.SecureServing {
+			// The metrics endpoint authenticates and authorizes Prometheus with the token of its service account
+			target.Scheme = "https"
+			target.BearerTokenAuth = true
+			target.CASecretName = os.Getenv("LUMIGO_METRICS_CA_SECRET_NAME")
+		}
+		targets = append(targets, target)
+	}
+
+	if os.Getenv("LUMIGO_TELEMETRY_PROXY_SERVICE_MONITOR_ENABLED") == "true" {
+		targets = append(targets, servicemonitors.Target{
+			ServiceName: os.Getenv("LUMIGO_TELEMETRY_PROXY_SERVICE_NAME"),
+			Port:        "metrics",
+			Scheme:      "http",
+			Interval:    os.Getenv("LUMIGO_TELEMETRY_PROXY_SERVICE_MONITOR_INTERVAL"),
+		})
+	}
+
+	return targets
+}
+
+func newAuditor(lumigoOperatorVersion string, clientset *kubernetes.Clientset) (*audit.Auditor, error) {
+	var writer io.Writer
+	if os.Getenv("LUMIGO_AUDIT_LOG_ENABLED") == "true" {
+		writer = os.Stdout
+	}
+
+	var configMaps corev1client.ConfigMapsGetter
+	maxConfigMapEntries := audit.DefaultMaxConfigMapEntries
+	if os.Getenv("LUMIGO_AUDIT_CONFIGMAPS_ENABLED") == "true" {
+		configMaps = clientset.CoreV1()
+
+		if value, isSet := os.LookupEnv("LUMIGO_AUDIT_CONFIGMAPS_MAX_ENTRIES"); isSet {
+			var err error
+			if maxConfigMapEntries, err = strconv.Atoi(value); err != nil || maxConfigMapEntries < 1 {
+				return nil, fmt.Errorf("the 'LUMIGO_AUDIT_CONFIGMAPS_MAX_ENTRIES' environment variable must be a positive integer, found '%s'", value)
+			}
+		}
+	}
+
+	if writer == nil && configMaps == nil {
+		return nil, nil
+	}
+
+	return audit.NewAuditor(lumigoOperatorVersion, writer, configMaps, maxConfigMapEntries), nil
+}
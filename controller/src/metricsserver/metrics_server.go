@@ -0,0 +1,318 @@
+package metricsserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// The path at which the metrics of the manager are served
+	MetricsPath = "/metrics"
+
+	selfSignedCertificateValidity = 365 * 24 * time.Hour
+	shutdownTimeout               = 30 * time.Second
+)
+
+// Options configure the secure serving of the metrics endpoint, which replaces the kube-rbac-proxy sidecar: the
+// endpoint is served over TLS, and the requests are authenticated with bearer tokens or client certificates and
+// authorized with SubjectAccessReviews on their path, like the non-resource URLs of the Kubernetes API.
+type Options struct {
+	// Whether the metrics endpoint is served over TLS with authentication and authorization; otherwise, it is
+	// served over plain HTTP to anyone who can reach it, which is meant for local development
+	SecureServing bool
+	// The directory with the certificate and key of the metrics endpoint, which are reloaded when they change;
+	// if empty, the metrics endpoint is served with a self-signed certificate generated at startup
+	CertDir string
+	// The name of the certificate file in CertDir
+	CertName string
+	// The name of the key file in CertDir
+	KeyName string
+	// The file with the CA certificates that sign the client certificates accepted as an alternative to bearer
+	// tokens; if empty, only bearer tokens are accepted
+	ClientCAFile string
+}
+
+// BindFlags binds the flags of the options to the given flag set.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.SecureServing, "metrics-secure", false,
+		"Serve the metrics endpoint over TLS, authenticating and authorizing the requests against the Kubernetes API.")
+	fs.StringVar(&o.CertDir, "metrics-cert-dir", "",
+		"The directory with the certificate and key of the metrics endpoint. Defaults to a self-signed certificate.")
+	fs.StringVar(&o.CertName, "metrics-cert-name", "tls.crt", "The name of the certificate file in the metrics certificate directory.")
+	fs.StringVar(&o.KeyName, "metrics-key-name", "tls.key", "The name of the key file in the metrics certificate directory.")
+	fs.StringVar(&o.ClientCAFile, "metrics-client-ca-file", "",
+		"The file with the CA certificates of the client certificates accepted by the metrics endpoint besides bearer tokens.")
+}
+
+// Server serves the metrics of the manager, and the extra handlers added to it, securely; it is the counterpart
+// of the metrics server of the manager, which serves them over plain HTTP
+type Server struct {
+	bindAddress          string
+	options              *Options
+	configureTLS         func(*tls.Config)
+	tokenReviews         authenticationv1client.TokenReviewInterface
+	subjectAccessReviews authorizationv1client.SubjectAccessReviewInterface
+	mux                  *http.ServeMux
+	log                  logr.Logger
+}
+
+// NewServer returns the server of the metrics endpoint, which authenticates the requests with the given token
+// reviews and authorizes them with the given subject access reviews
+func NewServer(bindAddress string, options *Options, configureTLS func(*tls.Config), tokenReviews authenticationv1client.TokenReviewInterface, subjectAccessReviews authorizationv1client.SubjectAccessReviewInterface, log logr.Logger) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+	}))
+
+	return &Server{
+		bindAddress:          bindAddress,
+		options:              options,
+		configureTLS:         configureTLS,
+		tokenReviews:         tokenReviews,
+		subjectAccessReviews: subjectAccessReviews,
+		mux:                  mux,
+		log:                  log,
+	}
+}
+
+// AddHandler serves the handler at the given path, with the same authentication and authorization as the metrics.
+func (s *Server) AddHandler(path string, handler http.Handler) error {
+	if path == MetricsPath {
+		return fmt.Errorf("the '%s' path is reserved for the metrics", MetricsPath)
+	}
+
+	s.mux.Handle(path, handler)
+	return nil
+}
+
+// Start serves the metrics endpoint until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	s.configureTLS(tlsConfig)
+
+	if len(s.options.CertDir) > 0 {
+		certWatcher, err := certwatcher.New(filepath.Join(s.options.CertDir, s.options.CertName), filepath.Join(s.options.CertDir, s.options.KeyName))
+		if err != nil {
+			return fmt.Errorf("cannot load the certificate of the metrics endpoint: %w", err)
+		}
+		go func() {
+			if err := certWatcher.Start(ctx); err != nil {
+				s.log.Error(err, "Cannot watch the certificate of the metrics endpoint")
+			}
+		}()
+		tlsConfig.GetCertificate = certWatcher.GetCertificate
+	} else {
+		certificate, err := newSelfSignedCertificate(time.Now())
+		if err != nil {
+			return fmt.Errorf("cannot generate the self-signed certificate of the metrics endpoint: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{*certificate}
+	}
+
+	if len(s.options.ClientCAFile) > 0 {
+		clientCAs, err := os.ReadFile(s.options.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("cannot read the client CA file '%s' of the metrics endpoint: %w", s.options.ClientCAFile, err)
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(clientCAs) {
+			return fmt.Errorf("the client CA file '%s' of the metrics endpoint has no PEM-encoded certificate", s.options.ClientCAFile)
+		}
+		// The clients without certificates authenticate with bearer tokens instead
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	listener, err := tls.Listen("tcp", s.bindAddress, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("cannot listen on the metrics endpoint address '%s': %w", s.bindAddress, err)
+	}
+
+	server := &http.Server{
+		Handler:           s.WithAuthenticationAndAuthorization(s.mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.log.Error(err, "Cannot shut down the metrics endpoint")
+		}
+	}()
+
+	s.log.Info("Serving the metrics endpoint securely", "address", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection returns false, as every replica of the manager serves its own metrics.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// WithAuthenticationAndAuthorization wraps the handler so that it serves only the requests of the users that the
+// Kubernetes API authorizes to use the path of the request, e.g., `get` on the `/metrics` non-resource URL;
+// the users are identified by their verified client certificate, if any, or else by their bearer token.
+func (s *Server) WithAuthenticationAndAuthorization(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userInfo, err := s.authenticate(r)
+		if err != nil {
+			s.log.V(1).Info("Unauthenticated request to the metrics endpoint", "path", r.URL.Path, "reason", err.Error())
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		subjectAccessReview, err := s.subjectAccessReviews.Create(r.Context(), &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   userInfo.Username,
+				UID:    userInfo.UID,
+				Groups: userInfo.Groups,
+				Extra:  toExtraValues(userInfo.Extra),
+				NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+					Path: r.URL.Path,
+					Verb: verbOf(r.Method),
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			s.log.Error(err, "Cannot authorize the request to the metrics endpoint", "path", r.URL.Path)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !subjectAccessReview.Status.Allowed {
+			s.log.V(1).Info("Forbidden request to the metrics endpoint", "path", r.URL.Path, "user", userInfo.Username, "reason", subjectAccessReview.Status.Reason)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authenticate(r *http.Request) (*authenticationv1.UserInfo, error) {
+	// Only the certificates verified against the client CAs are ever in the verified chains
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		subject := r.TLS.VerifiedChains[0][0].Subject
+		return &authenticationv1.UserInfo{
+			Username: subject.CommonName,
+			Groups:   subject.Organization,
+		}, nil
+	}
+
+	token, isBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !isBearer || len(strings.TrimSpace(token)) < 1 {
+		return nil, fmt.Errorf("no client certificate or bearer token")
+	}
+
+	tokenReview, err := s.tokenReviews.Create(r.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: strings.TrimSpace(token),
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot review the bearer token: %w", err)
+	}
+
+	if !tokenReview.Status.Authenticated {
+		return nil, fmt.Errorf("invalid bearer token: %s", tokenReview.Status.Error)
+	}
+
+	return &tokenReview.Status.User, nil
+}
+
+// The verbs of the non-resource URLs, as the Kubernetes API maps them from the HTTP methods
+func verbOf(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
+}
+
+func toExtraValues(extra map[string]authenticationv1.ExtraValue) map[string]authorizationv1.ExtraValue {
+	if extra == nil {
+		return nil
+	}
+
+	extraValues := make(map[string]authorizationv1.ExtraValue, len(extra))
+	for key, value := range extra {
+		extraValues[key] = authorizationv1.ExtraValue(value)
+	}
+
+	return extraValues
+}
+
+func newSelfSignedCertificate(now time.Time) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: "lumigo-operator-metrics",
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	certificateDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{certificateDer},
+		PrivateKey:  key,
+	}, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricsserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Metrics Server Suite")
+}
+
+var _ = Context("Metrics server", func() {
+
+	var clientset *fake.Clientset
+	var reviewedAttributes []authorizationv1.NonResourceAttributes
+	var reviewedUsers []string
+
+	newServer := func() *Server {
+		return NewServer(":0", &Options{SecureServing: true}, func(*tls.Config) {}, clientset.AuthenticationV1().TokenReviews(), clientset.AuthorizationV1().SubjectAccessReviews(), logr.Discard())
+	}
+
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		newServer().WithAuthenticationAndAuthorization(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	BeforeEach(func() {
+		reviewedAttributes = nil
+		reviewedUsers = nil
+
+		clientset = fake.NewSimpleClientset()
+		clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			tokenReview := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+			if tokenReview.Spec.Token == "valid-token" {
+				tokenReview.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:monitoring:prometheus",
+					},
+				}
+			}
+			return true, tokenReview, nil
+		})
+		clientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			subjectAccessReview := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			reviewedAttributes = append(reviewedAttributes, *subjectAccessReview.Spec.NonResourceAttributes)
+			reviewedUsers = append(reviewedUsers, subjectAccessReview.Spec.User)
+			// Only reading the metrics is allowed
+			subjectAccessReview.Status.Allowed = subjectAccessReview.Spec.NonResourceAttributes.Path == MetricsPath &&
+				subjectAccessReview.Spec.NonResourceAttributes.Verb == "get"
+			return true, subjectAccessReview, nil
+		})
+	})
+
+	It("rejects the requests without credentials", func() {
+		Expect(serve(httptest.NewRequest(http.MethodGet, MetricsPath, nil)).Code).To(Equal(http.StatusUnauthorized))
+		Expect(reviewedAttributes).To(BeEmpty())
+	})
+
+	It("rejects the requests with invalid bearer tokens", func() {
+		request := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+		request.Header.Set("Authorization", "Bearer invalid-token")
+
+		Expect(serve(request).Code).To(Equal(http.StatusUnauthorized))
+		Expect(reviewedAttributes).To(BeEmpty())
+	})
+
+	It("authorizes the requests with valid bearer tokens on their path and verb", func() {
+		request := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+		request.Header.Set("Authorization", "Bearer valid-token")
+		Expect(serve(request).Code).To(Equal(http.StatusOK))
+
+		request = httptest.NewRequest(http.MethodPost, "/log-levels", nil)
+		request.Header.Set("Authorization", "Bearer valid-token")
+		Expect(serve(request).Code).To(Equal(http.StatusForbidden))
+
+		Expect(reviewedAttributes).To(Equal([]authorizationv1.NonResourceAttributes{
+			{Path: MetricsPath, Verb: "get"},
+			{Path: "/log-levels", Verb: "create"},
+		}))
+		Expect(reviewedUsers).To(HaveEach("system:serviceaccount:monitoring:prometheus"))
+	})
+
+	It("authenticates the requests with verified client certificates without reviewing tokens", func() {
+		request := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+		request.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{
+				Subject: pkix.Name{CommonName: "prometheus", Organization: []string{"monitoring"}},
+			}}},
+		}
+
+		Expect(serve(request).Code).To(Equal(http.StatusOK))
+		Expect(reviewedUsers).To(Equal([]string{"prometheus"}))
+		Expect(clientset.Actions()).To(HaveLen(1))
+	})
+
+	It("reserves the metrics path", func() {
+		server := newServer()
+
+		Expect(server.AddHandler(MetricsPath, http.NotFoundHandler())).NotTo(Succeed())
+		Expect(server.AddHandler("/log-levels", http.NotFoundHandler())).To(Succeed())
+	})
+
+	It("binds the flags with the default certificate names", func() {
+		options := Options{}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		options.BindFlags(fs)
+
+		Expect(fs.Parse([]string{"--metrics-secure", "--metrics-cert-dir=/certs", "--metrics-client-ca-file=/ca/ca.crt"})).To(Succeed())
+		Expect(options).To(Equal(Options{
+			SecureServing: true,
+			CertDir:       "/certs",
+			CertName:      "tls.crt",
+			KeyName:       "tls.key",
+			ClientCAFile:  "/ca/ca.crt",
+		}))
+	})
+
+	It("generates a self-signed certificate for serving", func() {
+		now := time.Now()
+		certificate, err := newSelfSignedCertificate(now)
+		Expect(err).NotTo(HaveOccurred())
+
+		parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.NotAfter).To(BeTemporally(">", now.Add(30*24*time.Hour)))
+		Expect(parsed.ExtKeyUsage).To(ContainElement(x509.ExtKeyUsageServerAuth))
+	})
+})
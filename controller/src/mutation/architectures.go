@@ -0,0 +1,162 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"fmt"
+	"reflect"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const KubernetesArchLabelKey = "kubernetes.io/arch"
+
+// The Lumigo injector image is published as a multi-arch image for these architectures
+var LumigoInjectorSupportedArchitectures = []string{"amd64", "arm64"}
+
+var supportedArchitecturesNodeSelectorRequirement = corev1.NodeSelectorRequirement{
+	Key:      KubernetesArchLabelKey,
+	Operator: corev1.NodeSelectorOpIn,
+	Values:   LumigoInjectorSupportedArchitectures,
+}
+
+// Returns an error if the pod spec can only be scheduled on nodes with architectures
+// that the Lumigo injector does not support, based on its node selector and required
+// node affinity. Pods without architecture constraints are considered supported.
+func validateArchitectureIsSupported(podSpec *corev1.PodSpec) error {
+	if arch, ok := podSpec.NodeSelector[KubernetesArchLabelKey]; ok && !slices.Contains(LumigoInjectorSupportedArchitectures, arch) {
+		return &SkipInjectionError{
+			Code:   SkipReasonUnsupportedArchitecture,
+			Reason: fmt.Sprintf("the pod spec selects nodes with the '%s' architecture, which is not supported by the Lumigo injector", arch),
+		}
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil || podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+
+	// Node selector terms are ORed: the pod is unsupported only if no term allows a supported architecture
+	terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return nil
+	}
+
+	for _, term := range terms {
+		if termAllowsSupportedArchitecture(&term) {
+			return nil
+		}
+	}
+
+	return &SkipInjectionError{
+		Code:   SkipReasonUnsupportedArchitecture,
+		Reason: fmt.Sprintf("the required node affinity of the pod spec only allows architectures that are not supported by the Lumigo injector (supported: %v)", LumigoInjectorSupportedArchitectures),
+	}
+}
+
+func termAllowsSupportedArchitecture(term *corev1.NodeSelectorTerm) bool {
+	for _, expression := range term.MatchExpressions {
+		if expression.Key != KubernetesArchLabelKey {
+			continue
+		}
+
+		switch expression.Operator {
+		case corev1.NodeSelectorOpIn:
+			if !slices.ContainsFunc(expression.Values, func(arch string) bool { return slices.Contains(LumigoInjectorSupportedArchitectures, arch) }) {
+				return false
+			}
+		case corev1.NodeSelectorOpNotIn:
+			if !slices.ContainsFunc(LumigoInjectorSupportedArchitectures, func(arch string) bool { return !slices.Contains(expression.Values, arch) }) {
+				return false
+			}
+		case corev1.NodeSelectorOpDoesNotExist:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Adds to all the required node selector terms of the pod spec a requirement on
+// the architectures supported by the Lumigo injector
+func addSupportedArchitecturesNodeAffinity(podSpec *corev1.PodSpec) {
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+
+	nodeSelector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+
+	for i := range nodeSelector.NodeSelectorTerms {
+		term := &nodeSelector.NodeSelectorTerms[i]
+		if slices.IndexFunc(term.MatchExpressions, isSupportedArchitecturesNodeSelectorRequirement) < 0 {
+			term.MatchExpressions = append(term.MatchExpressions, *supportedArchitecturesNodeSelectorRequirement.DeepCopy())
+		}
+	}
+}
+
+// Removes the requirement added by addSupportedArchitecturesNodeAffinity, as well as
+// the affinity structures that are left empty as a result
+func removeSupportedArchitecturesNodeAffinity(podSpec *corev1.PodSpec) {
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil || podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return
+	}
+
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	newTerms := []corev1.NodeSelectorTerm{}
+	for _, term := range nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		index := slices.IndexFunc(term.MatchExpressions, isSupportedArchitecturesNodeSelectorRequirement)
+		if index < 0 {
+			newTerms = append(newTerms, term)
+			continue
+		}
+
+		term.MatchExpressions = slices.Delete(term.MatchExpressions, index, index+1)
+		if len(term.MatchExpressions) > 0 || len(term.MatchFields) > 0 {
+			newTerms = append(newTerms, term)
+		}
+	}
+
+	if len(newTerms) > 0 {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = newTerms
+		return
+	}
+
+	nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
+	if reflect.DeepEqual(nodeAffinity, &corev1.NodeAffinity{}) {
+		podSpec.Affinity.NodeAffinity = nil
+	}
+
+	if reflect.DeepEqual(podSpec.Affinity, &corev1.Affinity{}) {
+		podSpec.Affinity = nil
+	}
+}
+
+func isSupportedArchitecturesNodeSelectorRequirement(requirement corev1.NodeSelectorRequirement) bool {
+	return reflect.DeepEqual(requirement, supportedArchitecturesNodeSelectorRequirement)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// InjectionArtifacts are the parts of the injection found in the pod template of a resource, which are either all
+// there, or all missing, unless the injection or its removal has been interrupted, e.g., by a crash of the operator
+type InjectionArtifacts struct {
+	// Whether the pod template has the autotrace label of a version of the operator
+	HasAutoTraceLabel bool
+	// Whether the pod template has the `lumigo-injector` init container
+	HasInjectorContainer bool
+	// Whether the pod template has the `lumigo-injector` volume
+	HasInjectorVolume bool
+	// The containers that mount the `lumigo-injector` volume
+	MountingContainers []string
+	// The containers with the `LD_PRELOAD` env var of the injector
+	PreloadingContainers []string
+}
+
+// GetInjectionArtifacts returns the injection artifacts of the pod template of the resource, or nil for the resources
+// whose pod template cannot be changed, like Jobs, or belongs to another resource, like the ReplicaSets of Deployments
+func GetInjectionArtifacts(resource interface{}) (*InjectionArtifacts, error) {
+	switch a := resource.(type) {
+	case *appsv1.DaemonSet:
+		return getInjectionArtifacts(&a.Spec.Template), nil
+	case *appsv1.Deployment:
+		return getInjectionArtifacts(&a.Spec.Template), nil
+	case *appsv1.ReplicaSet:
+		if hasDeploymentOwner, err := hasDeploymentOwnerReference(a.OwnerReferences); err != nil {
+			return nil, err
+		} else if hasDeploymentOwner {
+			return nil, nil
+		}
+		return getInjectionArtifacts(&a.Spec.Template), nil
+	case *appsv1.StatefulSet:
+		return getInjectionArtifacts(&a.Spec.Template), nil
+	case *batchv1.CronJob:
+		return getInjectionArtifacts(&a.Spec.JobTemplate.Spec.Template), nil
+	default:
+		return nil, nil
+	}
+}
+
+func getInjectionArtifacts(podTemplateSpec *corev1.PodTemplateSpec) *InjectionArtifacts {
+	artifacts := &InjectionArtifacts{
+		HasAutoTraceLabel:    strings.HasPrefix(podTemplateSpec.Labels[LumigoAutoTraceLabelKey], LumigoAutoTraceLabelVersionPrefixValue),
+		HasInjectorContainer: slices.IndexFunc(podTemplateSpec.Spec.InitContainers, func(c corev1.Container) bool { return c.Name == LumigoInjectorContainerName }) > -1,
+		HasInjectorVolume:    slices.IndexFunc(podTemplateSpec.Spec.Volumes, func(v corev1.Volume) bool { return v.Name == LumigoInjectorVolumeName }) > -1,
+		MountingContainers:   InjectedContainerNames(&podTemplateSpec.Spec),
+		PreloadingContainers: []string{},
+	}
+
+	for _, container := range podTemplateSpec.Spec.Containers {
+		if slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == LdPreloadEnvVarName && e.Value == LdPreloadEnvVarValue }) > -1 {
+			artifacts.PreloadingContainers = append(artifacts.PreloadingContainers, container.Name)
+		}
+	}
+
+	return artifacts
+}
+
+// IsPresent returns whether the pod template has any of the changes of the injection, besides the autotrace label
+func (a *InjectionArtifacts) IsPresent() bool {
+	return a.HasInjectorContainer || a.HasInjectorVolume || len(a.MountingContainers) > 0 || len(a.PreloadingContainers) > 0
+}
+
+// IsPartial returns whether the pod template has some, but not all, of the changes of the injection, e.g., the
+// `lumigo-injector` volume without the `LD_PRELOAD` env var, which either breaks the pods or leaves them untraced
+func (a *InjectionArtifacts) IsPartial() bool {
+	if !a.IsPresent() {
+		// The autotrace label of an injection whose changes of the pod template are gone
+		return a.HasAutoTraceLabel
+	}
+
+	return !a.HasAutoTraceLabel || !a.HasInjectorContainer || !a.HasInjectorVolume || len(a.MountingContainers) < 1 || !slices.Equal(a.MountingContainers, a.PreloadingContainers)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LumigoDebugAnnotationKey, set to `true` on a workload or on its pod template, makes the Lumigo
+// distros of its injected containers log debug information, regardless of the settings of the
+// Lumigo resource and of the injector defaults; dropping the annotation reverts them
+const LumigoDebugAnnotationKey = "lumigo.io/debug"
+
+// OtelLogLevelEnvVarName is the environment variable with the verbosity of the logs of the
+// OpenTelemetry SDKs that the Lumigo distros are built on
+const OtelLogLevelEnvVarName = "OTEL_LOG_LEVEL"
+
+// LumigoInjectedDebugAnnotationKey holds, on the pod template, the comma-separated names of the
+// containers whose debug env vars have been set because of the LumigoDebugAnnotationKey annotation,
+// so that they are removed when the annotation is dropped, or with the rest of the injection
+const LumigoInjectedDebugAnnotationKey = "lumigo.io/injected-debug"
+const injectedDebugSeparator = ","
+
+// The env vars set on the injected containers of the workloads with the debug annotation
+var debugEnv = []corev1.EnvVar{
+	{
+		Name:  LumigoDebugEnvVarName,
+		Value: "true",
+	},
+	{
+		Name:  OtelLogLevelEnvVarName,
+		Value: "debug",
+	},
+}
+
+// IsDebugEnabled returns whether the workload or its pod template has the LumigoDebugAnnotationKey
+// annotation set to `true`
+func IsDebugEnabled(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) bool {
+	return topLevelObjectMeta.Annotations[LumigoDebugAnnotationKey] == "true" || podTemplateSpec.Annotations[LumigoDebugAnnotationKey] == "true"
+}
+
+// injectDebug sets the debug env vars on the instrumented containers of the workloads with the debug
+// annotation, overriding the ones of the extra env vars. Containers that set the debug env vars
+// themselves are left untouched; the debug env vars set by an earlier injection are removed if the
+// annotation has been dropped, in which case the extra env vars, injected before, are left in place.
+func (m *mutatorImpl) injectDebug(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) {
+	injectedContainerNames := getInjectedDebugContainerNames(&podTemplateSpec.ObjectMeta)
+	isEnabled := IsDebugEnabled(topLevelObjectMeta, podTemplateSpec)
+
+	debugContainerNames := []string{}
+	for i := range podTemplateSpec.Spec.Containers {
+		container := &podTemplateSpec.Spec.Containers[i]
+		isInjected := slices.Contains(injectedContainerNames, container.Name)
+
+		if !isEnabled || !m.isInstrumented(container) {
+			if isInjected {
+				container.Env = removeEnvVars(container.Env, m.debugEnvNamesNotInExtraEnv())
+			}
+			continue
+		}
+
+		isSetByContainer := false
+		for _, debugEnvVar := range debugEnv {
+			isExtraEnv := slices.IndexFunc(m.lumigoExtraEnv, func(e corev1.EnvVar) bool { return e.Name == debugEnvVar.Name }) > -1
+			if !isInjected && !isExtraEnv && slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == debugEnvVar.Name }) > -1 {
+				isSetByContainer = true
+				break
+			}
+		}
+		if isSetByContainer {
+			// The workload sets its own debug env vars
+			continue
+		}
+
+		for _, debugEnvVar := range debugEnv {
+			debugEnvVarIndex := slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == debugEnvVar.Name })
+			if debugEnvVarIndex < 0 {
+				container.Env = append(container.Env, debugEnvVar)
+			} else {
+				container.Env[debugEnvVarIndex] = debugEnvVar
+			}
+		}
+		debugContainerNames = append(debugContainerNames, container.Name)
+	}
+
+	setInjectedDebugContainerNames(&podTemplateSpec.ObjectMeta, debugContainerNames)
+}
+
+// The extra env vars are replaced at every injection, so the debug env vars that are also extra env vars
+// already have the values of the Lumigo resource or of the injector defaults
+func (m *mutatorImpl) debugEnvNamesNotInExtraEnv() []string {
+	names := []string{}
+	for _, debugEnvVar := range debugEnv {
+		if slices.IndexFunc(m.lumigoExtraEnv, func(e corev1.EnvVar) bool { return e.Name == debugEnvVar.Name }) < 0 {
+			names = append(names, debugEnvVar.Name)
+		}
+	}
+
+	return names
+}
+
+// removeDebug removes the debug env vars that were set because of the debug annotation
+func removeDebug(podTemplateSpec *corev1.PodTemplateSpec) {
+	injectedContainerNames := getInjectedDebugContainerNames(&podTemplateSpec.ObjectMeta)
+
+	debugEnvNames := []string{}
+	for _, debugEnvVar := range debugEnv {
+		debugEnvNames = append(debugEnvNames, debugEnvVar.Name)
+	}
+
+	for i := range podTemplateSpec.Spec.Containers {
+		container := &podTemplateSpec.Spec.Containers[i]
+		if slices.Contains(injectedContainerNames, container.Name) {
+			container.Env = removeEnvVars(container.Env, debugEnvNames)
+		}
+	}
+
+	setInjectedDebugContainerNames(&podTemplateSpec.ObjectMeta, nil)
+}
+
+func getInjectedDebugContainerNames(objectMeta *metav1.ObjectMeta) []string {
+	value := objectMeta.Annotations[LumigoInjectedDebugAnnotationKey]
+	if len(value) < 1 {
+		return []string{}
+	}
+
+	return strings.Split(value, injectedDebugSeparator)
+}
+
+func setInjectedDebugContainerNames(objectMeta *metav1.ObjectMeta, containerNames []string) {
+	if len(containerNames) < 1 {
+		if objectMeta.Annotations != nil {
+			delete(objectMeta.Annotations, LumigoInjectedDebugAnnotationKey)
+		}
+		return
+	}
+
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[LumigoInjectedDebugAnnotationKey] = strings.Join(containerNames, injectedDebugSeparator)
+}
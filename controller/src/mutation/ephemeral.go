@@ -0,0 +1,70 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Ephemeral containers, e.g., the ones `kubectl debug` adds to running pods, are never injected:
+// they are not part of pod templates, and the containers of pod specs are the only ones the mutator
+// changes. Debugging profiles may nevertheless copy into them the environment of the injected
+// containers, whose LD_PRELOAD breaks the debugging images that cannot load the Lumigo injector.
+// With this annotation set to `true` on a pod, or on the pod template of a workload, the injector
+// webhook strips the Lumigo environment variables from the ephemeral containers added to the pod.
+const LumigoStripEphemeralContainersEnvAnnotationKey = "lumigo.io/strip-ephemeral-containers-env"
+
+// EphemeralContainersSubresource is the subresource of pods through which ephemeral containers are added
+const EphemeralContainersSubresource = "ephemeralcontainers"
+
+// StripLumigoFromEphemeralContainers removes the environment variables set by the Lumigo operator from
+// the ephemeral containers of the pod, if the pod has the LumigoStripEphemeralContainersEnvAnnotationKey
+// annotation set to `true`, and returns whether any ephemeral container has been changed.
+func StripLumigoFromEphemeralContainers(pod *corev1.Pod) bool {
+	if pod.Annotations[LumigoStripEphemeralContainersEnvAnnotationKey] != "true" {
+		return false
+	}
+
+	envVarsToRemove := append([]string{
+		LumigoTracerTokenEnvVarName,
+		LumigoTracerTokenFileEnvVarName,
+		LumigoEndpointEnvVarName,
+		LumigoLogsEndpointEnvVarName,
+		LumigoEnableLogsEnvVarName,
+		LumigoContainerNameEnvVarName,
+		TelemetryProxyHostIpEnvVarName,
+	}, getInjectedExtraEnvNames(&pod.ObjectMeta)...)
+
+	isChanged := false
+	for i := range pod.Spec.EphemeralContainers {
+		container := &pod.Spec.EphemeralContainers[i]
+
+		env := removeEnvVars(container.Env, envVarsToRemove)
+		// LD_PRELOAD is removed only if it preloads the Lumigo injector, as debugging images may set their own
+		if ldPreloadEnvVarIndex := slices.IndexFunc(env, func(e corev1.EnvVar) bool { return e.Name == LdPreloadEnvVarName }); ldPreloadEnvVarIndex > -1 && env[ldPreloadEnvVarIndex].Value == LdPreloadEnvVarValue {
+			env = slices.Delete(env, ldPreloadEnvVarIndex, ldPreloadEnvVarIndex+1)
+		}
+
+		if len(env) != len(container.Env) {
+			container.Env = env
+			isChanged = true
+		}
+	}
+
+	return isChanged
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// RegexImagePatternPrefix marks the patterns of `.spec.tracing.injection.imagePatterns` that are regular
+// expressions rather than globs
+const RegexImagePatternPrefix = "regex:"
+
+// ImagePatterns selects the containers to instrument by their image, from `.spec.tracing.injection.imagePatterns`
+type ImagePatterns struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// ParseImagePatterns compiles the allowed and denied image patterns; it returns nil if there are none,
+// in which case the containers of all the images are instrumented
+func ParseImagePatterns(spec *operatorv1alpha1.ImagePatternsSpec) (*ImagePatterns, error) {
+	if len(spec.Allow) < 1 && len(spec.Deny) < 1 {
+		return nil, nil
+	}
+
+	allow, err := parseImagePatterns(spec.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowed image pattern: %w", err)
+	}
+
+	deny, err := parseImagePatterns(spec.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid denied image pattern: %w", err)
+	}
+
+	return &ImagePatterns{
+		allow: allow,
+		deny:  deny,
+	}, nil
+}
+
+func parseImagePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		var expr string
+		if strings.HasPrefix(pattern, RegexImagePatternPrefix) {
+			expr = strings.TrimPrefix(pattern, RegexImagePatternPrefix)
+		} else {
+			// In globs, `*` matches any sequence of characters, including the `/` separators of the repositories
+			expr = strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+			expr = strings.ReplaceAll(expr, `\?`, ".")
+		}
+
+		regex, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", expr))
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %w", pattern, err)
+		}
+		regexes[i] = regex
+	}
+
+	return regexes, nil
+}
+
+// IsInstrumented returns whether the containers with the given image are instrumented, that is, whether the
+// image matches one of the allowed patterns, if any, and none of the denied ones; with nil image patterns,
+// the containers of all the images are instrumented.
+func (p *ImagePatterns) IsInstrumented(image string) bool {
+	if p == nil {
+		return true
+	}
+
+	for _, deny := range p.deny {
+		if deny.MatchString(image) {
+			return false
+		}
+	}
+
+	if len(p.allow) < 1 {
+		return true
+	}
+
+	for _, allow := range p.allow {
+		if allow.MatchString(image) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *mutatorImpl) validateContainerImagesAreInstrumented(podSpec *corev1.PodSpec) error {
+	if m.imagePatterns == nil {
+		return nil
+	}
+
+	images := make([]string, len(podSpec.Containers))
+	for i, container := range podSpec.Containers {
+		if m.imagePatterns.IsInstrumented(container.Image) {
+			return nil
+		}
+		images[i] = container.Image
+	}
+
+	return &SkipInjectionError{
+		Code:   SkipReasonImageNotMatched,
+		Reason: fmt.Sprintf("none of the images of the containers match the image patterns to instrument: %s", strings.Join(images, ", ")),
+	}
+}
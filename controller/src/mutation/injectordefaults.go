@@ -0,0 +1,241 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const LumigoDebugEnvVarName = "LUMIGO_DEBUG"
+
+// OtelExporterOtlpTimeoutEnvVarName is the environment variable with how long, in milliseconds, the
+// Lumigo distros wait for each export to the telemetry-proxy endpoints
+const OtelExporterOtlpTimeoutEnvVarName = "OTEL_EXPORTER_OTLP_TIMEOUT"
+
+// OtelBspExportTimeoutEnvVarName is the environment variable with how long, in milliseconds, the
+// Lumigo distros wait for a batch of spans to be exported before dropping it
+const OtelBspExportTimeoutEnvVarName = "OTEL_BSP_EXPORT_TIMEOUT"
+
+// OtelExporterOtlpProtocolEnvVarName and OtelExporterOtlpEndpointEnvVarName are the environment variables with
+// the protocol, e.g., `grpc`, and the endpoint of the OTLP exporters of the OpenTelemetry SDKs
+const (
+	OtelExporterOtlpProtocolEnvVarName = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	OtelExporterOtlpEndpointEnvVarName = "OTEL_EXPORTER_OTLP_ENDPOINT"
+)
+
+// OtelResourceAttributesEnvVarName is the environment variable with the resource attributes that the Lumigo
+// distros add to the telemetry of the injected containers, as comma-separated `key=value` pairs
+const OtelResourceAttributesEnvVarName = "OTEL_RESOURCE_ATTRIBUTES"
+
+// The resource attributes that tell apart the telemetry of the clusters in which the operator is installed
+const (
+	K8sClusterNameResourceAttribute        = "k8s.cluster.name"
+	DeploymentEnvironmentResourceAttribute = "deployment.environment"
+)
+
+// InjectorDefaults are the cluster-wide defaults of the settings of the Lumigo distros in the injected
+// containers, which the operator is configured with, e.g., with the `--injector-defaults` flag, so that
+// they can be changed without forking the operator. They are injected like the extra env vars of the
+// Lumigo resources, which take precedence over them, as do the env vars of the other settings of the
+// Lumigo resources, like `.spec.tracing.propagators`.
+type InjectorDefaults struct {
+	// Whether the Lumigo distros log debug information, set as `LUMIGO_DEBUG`
+	Debug *bool `json:"debug,omitempty"`
+	// How long the Lumigo distros wait for each export to the telemetry-proxy endpoints,
+	// e.g., `10s`, set as `OTEL_EXPORTER_OTLP_TIMEOUT`
+	EndpointTimeout *metav1.Duration `json:"endpointTimeout,omitempty"`
+	// How long the Lumigo distros wait for a batch of spans to be exported, e.g., `30s`,
+	// set as `OTEL_BSP_EXPORT_TIMEOUT`
+	ExporterTimeout *metav1.Duration `json:"exporterTimeout,omitempty"`
+	// The cluster-wide sampling of the traces, e.g., `{"percentage": 10}`; the Lumigo resources
+	// override only the fields they set in `.spec.tracing.sampling`
+	Sampling *operatorv1alpha1.SamplingSpec `json:"sampling,omitempty"`
+	// The resource attributes added to the telemetry of the injected containers, e.g.,
+	// `{"k8s.cluster.name": "my-cluster"}`, set as `OTEL_RESOURCE_ATTRIBUTES`
+	ResourceAttributes map[string]string `json:"resourceAttributes,omitempty"`
+	// Any other env vars of the Lumigo distros, e.g., `LUMIGO_SECRET_MASKING_REGEX`
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// ParseInjectorDefaults parses the injector defaults from their JSON config, e.g.,
+// `{"debug":false,"endpointTimeout":"10s","env":[{"name":"LUMIGO_SWITCH_OFF","value":"false"}]}`
+func ParseInjectorDefaults(config string) (*InjectorDefaults, error) {
+	injectorDefaults := &InjectorDefaults{}
+	if err := json.Unmarshal([]byte(config), injectorDefaults); err != nil {
+		return nil, fmt.Errorf("cannot parse the injector defaults: %w", err)
+	}
+
+	if err := injectorDefaults.Validate(); err != nil {
+		return nil, err
+	}
+
+	return injectorDefaults, nil
+}
+
+// Validate returns an error if the injector defaults set the same env var twice, or with a negative timeout
+func (d *InjectorDefaults) Validate() error {
+	for name, timeout := range map[string]*metav1.Duration{"endpointTimeout": d.EndpointTimeout, "exporterTimeout": d.ExporterTimeout} {
+		if timeout != nil && timeout.Duration < 0 {
+			return fmt.Errorf("the '%s' injector default is negative: %s", name, timeout.Duration)
+		}
+	}
+
+	if d.Sampling != nil && d.Sampling.Percentage != nil && (*d.Sampling.Percentage < 0 || *d.Sampling.Percentage > 100) {
+		return fmt.Errorf("the 'sampling.percentage' injector default is not between 0 and 100: %d", *d.Sampling.Percentage)
+	}
+
+	for key := range d.ResourceAttributes {
+		if len(key) < 1 || strings.ContainsAny(key, ",=") {
+			return fmt.Errorf("the injector defaults have an invalid resource attribute key: '%s'", key)
+		}
+	}
+
+	names := []string{}
+	for _, envVar := range d.EnvVars() {
+		if len(envVar.Name) < 1 {
+			return fmt.Errorf("the injector defaults have an env var without name")
+		}
+
+		if slices.Contains(names, envVar.Name) {
+			return fmt.Errorf("the injector defaults set the '%s' env var more than once", envVar.Name)
+		}
+		names = append(names, envVar.Name)
+	}
+
+	return nil
+}
+
+// EnvVars returns the env vars of the injector defaults, except the ones of the sampling, which is layered under
+// the one of the Lumigo resources by EffectiveSampling; it is safe to call on nil injector defaults
+func (d *InjectorDefaults) EnvVars() []corev1.EnvVar {
+	if d == nil {
+		return nil
+	}
+
+	envVars := []corev1.EnvVar{}
+	if d.Debug != nil {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  LumigoDebugEnvVarName,
+			Value: strconv.FormatBool(*d.Debug),
+		})
+	}
+	if d.EndpointTimeout != nil {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  OtelExporterOtlpTimeoutEnvVarName,
+			Value: strconv.FormatInt(d.EndpointTimeout.Milliseconds(), 10),
+		})
+	}
+	if d.ExporterTimeout != nil {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  OtelBspExportTimeoutEnvVarName,
+			Value: strconv.FormatInt(d.ExporterTimeout.Milliseconds(), 10),
+		})
+	}
+
+	if len(d.ResourceAttributes) > 0 {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  OtelResourceAttributesEnvVarName,
+			Value: formatResourceAttributes(d.ResourceAttributes),
+		})
+	}
+
+	return append(envVars, d.Env...)
+}
+
+// WithClusterResourceAttributes returns a copy of the injector defaults that also add the name and environment of
+// the cluster, when set, to the resource attributes of the injected containers; the resource attributes that the
+// injector defaults already set are kept. It is safe to call on nil injector defaults.
+func (d *InjectorDefaults) WithClusterResourceAttributes(clusterName string, deploymentEnvironment string) *InjectorDefaults {
+	if len(clusterName) < 1 && len(deploymentEnvironment) < 1 {
+		return d
+	}
+
+	injectorDefaults := &InjectorDefaults{}
+	if d != nil {
+		*injectorDefaults = *d
+	}
+
+	resourceAttributes := map[string]string{}
+	for _, attribute := range []struct{ key, value string }{
+		{K8sClusterNameResourceAttribute, clusterName},
+		{DeploymentEnvironmentResourceAttribute, deploymentEnvironment},
+	} {
+		if len(attribute.value) > 0 {
+			resourceAttributes[attribute.key] = attribute.value
+		}
+	}
+	for key, value := range injectorDefaults.ResourceAttributes {
+		resourceAttributes[key] = value
+	}
+	injectorDefaults.ResourceAttributes = resourceAttributes
+
+	return injectorDefaults
+}
+
+// WithOtlpGrpcEndpoint returns a copy of the injector defaults that also make the OTLP exporters of the injected
+// containers export over gRPC to the given endpoint, whose HTTP/2 connections multiplex their concurrent exports,
+// unless the env of the injector defaults already sets their protocol or endpoint. It is safe to call on nil
+// injector defaults.
+func (d *InjectorDefaults) WithOtlpGrpcEndpoint(endpoint string) *InjectorDefaults {
+	if len(endpoint) < 1 {
+		return d
+	}
+
+	injectorDefaults := &InjectorDefaults{}
+	if d != nil {
+		*injectorDefaults = *d
+	}
+
+	if slices.ContainsFunc(injectorDefaults.Env, func(envVar corev1.EnvVar) bool {
+		return envVar.Name == OtelExporterOtlpProtocolEnvVarName || envVar.Name == OtelExporterOtlpEndpointEnvVarName
+	}) {
+		return injectorDefaults
+	}
+
+	injectorDefaults.Env = append(append([]corev1.EnvVar{}, injectorDefaults.Env...),
+		corev1.EnvVar{Name: OtelExporterOtlpProtocolEnvVarName, Value: "grpc"},
+		corev1.EnvVar{Name: OtelExporterOtlpEndpointEnvVarName, Value: endpoint},
+	)
+
+	return injectorDefaults
+}
+
+// The resource attributes sorted by key, so that the injected env var does not change across admissions
+func formatResourceAttributes(resourceAttributes map[string]string) string {
+	keys := make([]string, 0, len(resourceAttributes))
+	for key := range resourceAttributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%s", key, resourceAttributes[key])
+	}
+
+	return strings.Join(pairs, ",")
+}
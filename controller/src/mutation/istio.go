@@ -0,0 +1,198 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IstioSidecarInjectKey, set to `false` as label or annotation of a pod template, keeps the Istio sidecar out
+// of its pods; the injection does not set the Istio annotations on such pod templates
+const IstioSidecarInjectKey = "sidecar.istio.io/inject"
+
+// IstioExcludeOutboundPortsAnnotationKey holds the comma-separated ports whose outbound traffic bypasses the Istio sidecar
+const IstioExcludeOutboundPortsAnnotationKey = "traffic.sidecar.istio.io/excludeOutboundPorts"
+
+// IstioProxyConfigAnnotationKey holds the settings of the Istio sidecar of the pods, which override the ones of the mesh
+const IstioProxyConfigAnnotationKey = "proxy.istio.io/config"
+
+// The value of the IstioProxyConfigAnnotationKey annotation set by `.spec.tracing.injection.istio.holdApplicationUntilProxyStarts`
+const istioHoldApplicationUntilProxyStartsConfig = "holdApplicationUntilProxyStarts: true"
+
+// LumigoInjectedExcludedOutboundPortsAnnotationKey holds, on the pod template, the comma-separated ports that the injection
+// added to the IstioExcludeOutboundPortsAnnotationKey annotation, so that they are removed with the rest of the injection,
+// while the ports that the workload excludes itself are left alone
+const LumigoInjectedExcludedOutboundPortsAnnotationKey = "lumigo.io/injected-excluded-outbound-ports"
+const injectedExcludedOutboundPortsSeparator = ","
+
+// LumigoInjectedProxyConfigAnnotationKey is set to `true`, on the pod template, when the injection has set the
+// IstioProxyConfigAnnotationKey annotation, so that it is removed with the rest of the injection
+const LumigoInjectedProxyConfigAnnotationKey = "lumigo.io/injected-proxy-config"
+
+// isOptedOutOfIstio returns whether the pod template keeps the Istio sidecar out of its pods
+func isOptedOutOfIstio(podTemplateSpec *corev1.PodTemplateSpec) bool {
+	return podTemplateSpec.Labels[IstioSidecarInjectKey] == "false" || podTemplateSpec.Annotations[IstioSidecarInjectKey] == "false"
+}
+
+// injectIstio sets the Istio annotations of `.spec.tracing.injection.istio` on the pod template, and removes the
+// ones of an earlier injection that are no longer enabled; it returns whether the annotations have changed
+func (m *mutatorImpl) injectIstio(podTemplateSpec *corev1.PodTemplateSpec) bool {
+	isMeshed := !isOptedOutOfIstio(podTemplateSpec)
+	originalAnnotations := getIstioAnnotations(&podTemplateSpec.ObjectMeta)
+
+	ports := []string{}
+	if isMeshed && m.istioExcludeProxyPorts {
+		ports = m.telemetryProxyPorts()
+	}
+	setExcludedOutboundPorts(&podTemplateSpec.ObjectMeta, ports)
+	setHoldApplicationUntilProxyStarts(&podTemplateSpec.ObjectMeta, isMeshed && m.istioHoldApplication)
+
+	return !slices.Equal(originalAnnotations, getIstioAnnotations(&podTemplateSpec.ObjectMeta))
+}
+
+// removeIstio removes the Istio annotations set by the injection; it returns whether the annotations have changed
+func removeIstio(podTemplateSpec *corev1.PodTemplateSpec) bool {
+	originalAnnotations := getIstioAnnotations(&podTemplateSpec.ObjectMeta)
+
+	setExcludedOutboundPorts(&podTemplateSpec.ObjectMeta, nil)
+	setHoldApplicationUntilProxyStarts(&podTemplateSpec.ObjectMeta, false)
+
+	return !slices.Equal(originalAnnotations, getIstioAnnotations(&podTemplateSpec.ObjectMeta))
+}
+
+func getIstioAnnotations(objectMeta *metav1.ObjectMeta) []string {
+	return []string{
+		objectMeta.Annotations[IstioExcludeOutboundPortsAnnotationKey],
+		objectMeta.Annotations[IstioProxyConfigAnnotationKey],
+	}
+}
+
+// The ports of the OTLP endpoints of the telemetry-proxy that the injected containers send their telemetry to
+func (m *mutatorImpl) telemetryProxyPorts() []string {
+	endpoints := []string{m.lumigoEndpoint, m.lumigoLogsEndpoint}
+	for _, envVar := range m.lumigoExtraEnv {
+		if envVar.Name == OtelExporterOtlpEndpointEnvVarName {
+			endpoints = append(endpoints, envVar.Value)
+		}
+	}
+
+	ports := []string{}
+	for _, endpoint := range endpoints {
+		if port := getPortOf(endpoint); port != "" && !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports
+}
+
+// getPortOf returns the explicit port of the URL of an endpoint, or an empty string; the URLs may not be
+// parseable with net/url, as their host may reference env vars, e.g., `$(LUMIGO_TELEMETRY_PROXY_HOST_IP)`
+func getPortOf(endpoint string) string {
+	hostPort := endpoint
+	if i := strings.Index(hostPort, "://"); i > -1 {
+		hostPort = hostPort[i+len("://"):]
+	}
+	if i := strings.Index(hostPort, "/"); i > -1 {
+		hostPort = hostPort[:i]
+	}
+
+	i := strings.LastIndex(hostPort, ":")
+	if i < 0 {
+		return ""
+	}
+
+	port := hostPort[i+1:]
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return ""
+	}
+
+	return port
+}
+
+// setExcludedOutboundPorts replaces the ports that an earlier injection added to the IstioExcludeOutboundPortsAnnotationKey
+// annotation with the given ones; the ports that the annotation already has are not recorded as injected
+func setExcludedOutboundPorts(objectMeta *metav1.ObjectMeta, ports []string) {
+	injectedPorts := splitAnnotationValue(objectMeta.Annotations[LumigoInjectedExcludedOutboundPortsAnnotationKey], injectedExcludedOutboundPortsSeparator)
+
+	excludedPorts := []string{}
+	for _, port := range splitAnnotationValue(objectMeta.Annotations[IstioExcludeOutboundPortsAnnotationKey], ",") {
+		if !slices.Contains(injectedPorts, port) {
+			excludedPorts = append(excludedPorts, port)
+		}
+	}
+
+	injectedPorts = []string{}
+	for _, port := range ports {
+		if !slices.Contains(excludedPorts, port) {
+			excludedPorts = append(excludedPorts, port)
+			injectedPorts = append(injectedPorts, port)
+		}
+	}
+
+	setAnnotation(objectMeta, IstioExcludeOutboundPortsAnnotationKey, strings.Join(excludedPorts, ","))
+	setAnnotation(objectMeta, LumigoInjectedExcludedOutboundPortsAnnotationKey, strings.Join(injectedPorts, injectedExcludedOutboundPortsSeparator))
+}
+
+// setHoldApplicationUntilProxyStarts sets the IstioProxyConfigAnnotationKey annotation, unless the pod template has its own,
+// or removes the one set by an earlier injection, unless it has been changed since
+func setHoldApplicationUntilProxyStarts(objectMeta *metav1.ObjectMeta, isEnabled bool) {
+	if objectMeta.Annotations[LumigoInjectedProxyConfigAnnotationKey] == "true" {
+		if objectMeta.Annotations[IstioProxyConfigAnnotationKey] == istioHoldApplicationUntilProxyStartsConfig {
+			setAnnotation(objectMeta, IstioProxyConfigAnnotationKey, "")
+		}
+		setAnnotation(objectMeta, LumigoInjectedProxyConfigAnnotationKey, "")
+	}
+
+	if !isEnabled || objectMeta.Annotations[IstioProxyConfigAnnotationKey] != "" {
+		return
+	}
+
+	setAnnotation(objectMeta, IstioProxyConfigAnnotationKey, istioHoldApplicationUntilProxyStartsConfig)
+	setAnnotation(objectMeta, LumigoInjectedProxyConfigAnnotationKey, "true")
+}
+
+func splitAnnotationValue(value string, separator string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, separator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// setAnnotation sets the annotation, or removes it if the value is empty
+func setAnnotation(objectMeta *metav1.ObjectMeta, key string, value string) {
+	if value == "" {
+		if objectMeta.Annotations != nil {
+			delete(objectMeta.Annotations, key)
+		}
+		return
+	}
+
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[key] = value
+}
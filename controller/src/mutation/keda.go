@@ -0,0 +1,121 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Keda (https://keda.sh) creates a job out of the `spec.jobTargetRef` of a ScaledJob for each scaling
+// event, so the pod template to inject is the one of the ScaledJob rather than the ones of its jobs,
+// which are immutable. The Keda types are not vendored: ScaledJobs are handled as unstructured objects.
+var KedaScaledJobGroupVersionKind = schema.GroupVersionKind{
+	Group:   "keda.sh",
+	Version: "v1alpha1",
+	Kind:    "ScaledJob",
+}
+
+var KedaScaledJobGroupVersionResource = schema.GroupVersionResource{
+	Group:    "keda.sh",
+	Version:  "v1alpha1",
+	Resource: "scaledjobs",
+}
+
+// Annotations Keda uses to pause the scaling of ScaledJobs and ScaledObjects; the operator never
+// changes them, neither when injecting nor when removing the injection
+const KedaPausedAnnotationKey = "autoscaling.keda.sh/paused"
+const KedaPausedReplicasAnnotationKey = "autoscaling.keda.sh/paused-replicas"
+
+var kedaScaledJobPodTemplatePath = []string{"spec", "jobTargetRef", "template"}
+
+func IsKedaScaledJob(resource *unstructured.Unstructured) bool {
+	return resource.GroupVersionKind() == KedaScaledJobGroupVersionKind
+}
+
+// IsOwnedByKedaScaledJob returns whether the resource, typically a job, has been created by a Keda ScaledJob
+func IsOwnedByKedaScaledJob(ownerReferences []metav1.OwnerReference) (bool, error) {
+	for _, ownerReference := range ownerReferences {
+		gv, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+		if err != nil {
+			return false, err
+		}
+
+		if gv.Group == KedaScaledJobGroupVersionKind.Group && ownerReference.Kind == KedaScaledJobGroupVersionKind.Kind {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *mutatorImpl) InjectLumigoIntoKedaV1alpha1ScaledJob(scaledJob *unstructured.Unstructured) (bool, error) {
+	return mutateKedaScaledJob(scaledJob, func(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) (bool, error) {
+		return m.injectLumigoInto(KedaScaledJobGroupVersionKind.Kind, topLevelObjectMeta, podTemplateSpec)
+	})
+}
+
+func (m *mutatorImpl) RemoveLumigoFromKedaV1alpha1ScaledJob(scaledJob *unstructured.Unstructured) (bool, error) {
+	return mutateKedaScaledJob(scaledJob, m.removeLumigoFrom)
+}
+
+// mutateKedaScaledJob converts the metadata and the pod template of the ScaledJob to their typed
+// counterparts, mutates them, and writes them back; the other fields of the ScaledJob, including
+// the annotations of Keda, are left as they are
+func mutateKedaScaledJob(scaledJob *unstructured.Unstructured, mutate func(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) (bool, error)) (bool, error) {
+	podTemplate, found, err := unstructured.NestedMap(scaledJob.Object, kedaScaledJobPodTemplatePath...)
+	if err != nil {
+		return false, fmt.Errorf("cannot read the pod template of the ScaledJob: %w", err)
+	} else if !found {
+		return false, fmt.Errorf("the ScaledJob has no pod template in 'spec.jobTargetRef.template'")
+	}
+
+	podTemplateSpec := &corev1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podTemplate, podTemplateSpec); err != nil {
+		return false, fmt.Errorf("cannot parse the pod template of the ScaledJob: %w", err)
+	}
+
+	topLevelObjectMeta := &metav1.ObjectMeta{
+		Name:            scaledJob.GetName(),
+		Namespace:       scaledJob.GetNamespace(),
+		Labels:          scaledJob.GetLabels(),
+		Annotations:     scaledJob.GetAnnotations(),
+		OwnerReferences: scaledJob.GetOwnerReferences(),
+	}
+
+	if mutationOccurred, err := mutate(topLevelObjectMeta, podTemplateSpec); err != nil || !mutationOccurred {
+		return mutationOccurred, err
+	}
+
+	mutatedPodTemplate, err := runtime.DefaultUnstructuredConverter.ToUnstructured(podTemplateSpec)
+	if err != nil {
+		return false, fmt.Errorf("cannot serialize the mutated pod template of the ScaledJob: %w", err)
+	}
+
+	if err := unstructured.SetNestedMap(scaledJob.Object, mutatedPodTemplate, kedaScaledJobPodTemplatePath...); err != nil {
+		return false, fmt.Errorf("cannot set the mutated pod template of the ScaledJob: %w", err)
+	}
+	scaledJob.SetLabels(topLevelObjectMeta.Labels)
+	scaledJob.SetAnnotations(topLevelObjectMeta.Annotations)
+
+	return true, nil
+}
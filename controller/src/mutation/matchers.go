@@ -269,6 +269,12 @@ func (m *beInstrumentedWithLumigo) isContainerInstrumentedWithLumigo(container *
 			}
 			lumigoTracerTokenEnvVarFound = true
 
+		case LumigoTracerTokenFileEnvVarName:
+			if envVar.Value != LumigoTracerTokenFilePath {
+				return false, fmt.Errorf("unexpected value for '%s' env var: expected '%s', found '%s'", LumigoTracerTokenFileEnvVarName, LumigoTracerTokenFilePath, envVar.Value)
+			}
+			lumigoTracerTokenEnvVarFound = true
+
 		case LumigoEndpointEnvVarName:
 			if envVar.Value != m.lumigoEndpointUrl {
 				return false, fmt.Errorf("unexpected value for '%s' env var: expected '%s', found '%s'", LumigoEndpointEnvVarName, m.lumigoEndpointUrl, envVar.Value)
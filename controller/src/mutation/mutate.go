@@ -20,19 +20,25 @@ import (
 	// appsv1 "k8s.io/api/apps/v1"
 	// batchv1 "k8s.io/api/batch/v1"
 
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/go-logr/logr"
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/injectionannotations"
 	"golang.org/x/exp/slices"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -50,21 +56,84 @@ const LumigoEndpointEnvVarName = "LUMIGO_ENDPOINT"
 const LumigoLogsEndpointEnvVarName = "LUMIGO_LOGS_ENDPOINT"
 const LumigoEnableLogsEnvVarName = "LUMIGO_ENABLE_LOGS"
 const LumigoContainerNameEnvVarName = "LUMIGO_CONTAINER_NAME"
+
+// TelemetryProxyHostIpEnvVarName is the environment variable with the IP of the node, which the
+// telemetry-proxy endpoints reference as `$(LUMIGO_TELEMETRY_PROXY_HOST_IP)` when the
+// telemetry-proxy runs as a DaemonSet
+const TelemetryProxyHostIpEnvVarName = "LUMIGO_TELEMETRY_PROXY_HOST_IP"
 const LdPreloadEnvVarName = "LD_PRELOAD"
+
+// OtelPropagatorsEnvVarName is the environment variable with the context propagators of `.spec.tracing.propagators`
+const OtelPropagatorsEnvVarName = "OTEL_PROPAGATORS"
 const LdPreloadEnvVarValue = LumigoInjectorVolumeMountPoint + "/injector/lumigo_injector.so"
 
+// In the `ProjectedSecret` token injection mode, the Lumigo token is not set as env var: the
+// operator copies it in the LumigoTracerTokenSecretName secret of the namespace, which is mounted
+// in the containers, and the Lumigo distros read it from the file in LUMIGO_TRACER_TOKEN_FILE
+const LumigoTracerTokenFileEnvVarName = "LUMIGO_TRACER_TOKEN_FILE"
+const LumigoTracerTokenSecretName = "lumigo-tracer-token"
+const LumigoTracerTokenSecretKey = "token"
+const LumigoTracerTokenVolumeName = "lumigo-tracer-token"
+const LumigoTracerTokenVolumeMountPoint = "/var/run/secrets/lumigo"
+const LumigoTracerTokenFilePath = LumigoTracerTokenVolumeMountPoint + "/" + LumigoTracerTokenSecretKey
+
+// LumigoTracerTokenSecretKeyOfRoute returns the key of the LumigoTracerTokenSecretName secret with
+// the Lumigo token of a route of `.spec.tracing.routes`, which is projected in the containers of the
+// workloads matched by the route at the same path as the token of the namespace
+func LumigoTracerTokenSecretKeyOfRoute(routeName string) string {
+	return "route-" + routeName
+}
+
+// LumigoInjectedExtraEnvAnnotationKey holds, on the pod template, the comma-separated names of the
+// `spec.tracing.injection.extraEnv` env vars of the Lumigo resource that were added to the containers,
+// so that they are removed with the rest of the injection even if the Lumigo resource has changed since
+const LumigoInjectedExtraEnvAnnotationKey = "lumigo.io/injected-extra-env"
+const injectedExtraEnvSeparator = ","
+
+// LumigoInjectedImagePullSecretsAnnotationKey holds, on the pod template, the comma-separated names of the
+// `spec.tracing.injection.injectorImagePullSecrets` of the Lumigo resource that were added to the pod spec, so
+// that they are removed with the rest of the injection, while those the workload had already are left alone
+const LumigoInjectedImagePullSecretsAnnotationKey = "lumigo.io/injected-image-pull-secrets"
+const injectedImagePullSecretsSeparator = ","
+
 var defaultLumigoInitContainerUser int64 = 1234
 var defaultLumigoInitContainerGroup int64 = defaultLumigoInitContainerUser
 
+// SkipInjectionError is returned when a resource must not be injected, e.g., because its
+// pods would run on a platform the Lumigo injector does not support. Unlike other errors,
+// it is not a failure of the mutation, and retrying it is pointless.
+type SkipInjectionError struct {
+	// Why the resource is not injected, for metrics and reports
+	Code SkipReason
+	// Why the resource is not injected, for humans
+	Reason string
+}
+
+func (e *SkipInjectionError) Error() string {
+	return e.Reason
+}
+
+// SkipReasonCode returns the Code of the error, for the packages that cannot import this one
+func (e *SkipInjectionError) SkipReasonCode() string {
+	return string(e.Code)
+}
+
+func IsSkipInjectionError(err error) bool {
+	var skipInjectionError *SkipInjectionError
+	return errors.As(err, &skipInjectionError)
+}
+
 type Mutator interface {
 	GetAutotraceLabelValue() string
 	InjectLumigoInto(resource interface{}) (bool, error)
+	InjectLumigoIntoWithResult(resource interface{}) (*InjectionResult, error)
 	InjectLumigoIntoAppsV1DaemonSet(daemonSet *appsv1.DaemonSet) (bool, error)
 	InjectLumigoIntoAppsV1Deployment(deployment *appsv1.Deployment) (bool, error)
 	InjectLumigoIntoAppsV1ReplicaSet(replicaSet *appsv1.ReplicaSet) (bool, error)
 	InjectLumigoIntoAppsV1StatefulSet(statefulSet *appsv1.StatefulSet) (bool, error)
 	InjectLumigoIntoBatchV1CronJob(deployment *batchv1.CronJob) (bool, error)
 	InjectLumigoIntoBatchV1Job(deployment *batchv1.Job) (bool, error)
+	InjectLumigoIntoKedaV1alpha1ScaledJob(scaledJob *unstructured.Unstructured) (bool, error)
 	RemoveLumigoFrom(resource interface{}) (bool, error)
 	RemoveLumigoFromAppsV1DaemonSet(daemonSet *appsv1.DaemonSet) (bool, error)
 	RemoveLumigoFromAppsV1Deployment(deployment *appsv1.Deployment) (bool, error)
@@ -72,6 +141,7 @@ type Mutator interface {
 	RemoveLumigoFromAppsV1StatefulSet(statefulSet *appsv1.StatefulSet) (bool, error)
 	RemoveLumigoFromBatchV1CronJob(deployment *batchv1.CronJob) (bool, error)
 	RemoveLumigoFromBatchV1Job(deployment *batchv1.Job) (bool, error)
+	RemoveLumigoFromKedaV1alpha1ScaledJob(scaledJob *unstructured.Unstructured) (bool, error)
 }
 
 var f = false
@@ -79,19 +149,95 @@ var t = true
 
 type mutatorImpl struct {
 	log                       *logr.Logger
+	lumigoOperatorVersion     string
 	lumigoAutotraceLabelValue string
 	lumigoEndpoint            string
 	lumigoLogsEndpoint        string
 	lumigoEnableLogs					bool
 	lumigoToken               *operatorv1alpha1.Credentials
+	routes                    []tracingRoute
 	lumigoInjectorImage       string
+	lumigoInjectorPullPolicy  corev1.PullPolicy
+	lumigoInjectorPullSecrets []corev1.LocalObjectReference
+	lumigoExtraEnv            []corev1.EnvVar
+	serviceNameTemplate       *template.Template
+	tokenInjectionMode        operatorv1alpha1.TokenInjectionMode
+	workloadTypes             []operatorv1alpha1.WorkloadType
+	// Optional: if nil, workloads are injected regardless of when they were created
+	onlyWorkloadsCreatedAfter *metav1.Time
+	// Optional: if nil, the containers of all the images are instrumented
+	imagePatterns             *ImagePatterns
+	unsupportedArchPolicy     operatorv1alpha1.UnsupportedArchitecturePolicy
+	// Optional: if nil, the `lumigo-injector` init container sets no resources
+	lumigoInjectorResources *corev1.ResourceRequirements
+	// The Istio annotations set on the pod templates, see injectIstio
+	istioExcludeProxyPorts bool
+	istioHoldApplication   bool
+	// Optional: if nil, no workload is protected from the injection, see ProtectedWorkloads
+	protectedWorkloads *ProtectedWorkloads
+}
+
+// The Lumigo token injected into the workloads whose labels match the selector of a route, or into the
+// workload with the `lumigo.io/token-secret` annotation
+type tracingRoute struct {
+	name        string
+	selector    labels.Selector
+	lumigoToken *operatorv1alpha1.Credentials
+	// Whether the route is the one of the `lumigo.io/token-secret` annotation of the workload, whose token is
+	// projected from the annotated secret, as it is not in the LumigoTracerTokenSecretName secret
+	isWorkloadTokenSecret bool
+}
+
+// The env vars of settings like the propagators are injected as extra env vars, so that they are removed
+// with the other ones; the entries of `.spec.tracing.injection.extraEnv` take precedence over them, and
+// they take precedence over the injector defaults
+func getExtraEnv(spec *operatorv1alpha1.LumigoSpec, injectorDefaults *InjectorDefaults) []corev1.EnvVar {
+	settingsEnv := []corev1.EnvVar{}
+	if propagators := EffectivePropagators(spec); len(propagators) > 0 {
+		settingsEnv = append(settingsEnv, newPropagatorsEnvVar(propagators))
+	}
+	if len(spec.Tracing.SkipDomains) > 0 {
+		settingsEnv = append(settingsEnv, newSkipDomainsEnvVar(spec.Tracing.SkipDomains))
+	}
+	settingsEnv = append(settingsEnv, newPayloadCaptureEnv(&spec.Tracing.Injection.PayloadCapture)...)
+	settingsEnv = append(settingsEnv, newSamplingEnv(EffectiveSampling(spec, injectorDefaults))...)
+	for _, envVar := range injectorDefaults.EnvVars() {
+		if slices.IndexFunc(settingsEnv, func(e corev1.EnvVar) bool { return e.Name == envVar.Name }) < 0 {
+			settingsEnv = append(settingsEnv, envVar)
+		}
+	}
+
+	if len(settingsEnv) < 1 {
+		return spec.Tracing.Injection.ExtraEnv
+	}
+
+	extraEnv := []corev1.EnvVar{}
+	for _, envVar := range settingsEnv {
+		if slices.IndexFunc(spec.Tracing.Injection.ExtraEnv, func(e corev1.EnvVar) bool { return e.Name == envVar.Name }) < 0 {
+			extraEnv = append(extraEnv, envVar)
+		}
+	}
+
+	return append(extraEnv, spec.Tracing.Injection.ExtraEnv...)
+}
+
+func newPropagatorsEnvVar(propagators []operatorv1alpha1.Propagator) corev1.EnvVar {
+	names := make([]string, len(propagators))
+	for i, propagator := range propagators {
+		names[i] = string(propagator)
+	}
+
+	return corev1.EnvVar{
+		Name:  OtelPropagatorsEnvVarName,
+		Value: strings.Join(names, ","),
+	}
 }
 
 func (m *mutatorImpl) GetAutotraceLabelValue() string {
 	return m.lumigoAutotraceLabelValue
 }
 
-func NewMutator(Log *logr.Logger, LumigoSpec *operatorv1alpha1.LumigoSpec, LumigoOperatorVersion string, LumigoInjectorImage string, TelemetryProxyOtlpServiceUrl string, TelemetryProxyOtlpLogsServiceUrl string) (Mutator, error) {
+func NewMutator(Log *logr.Logger, LumigoSpec *operatorv1alpha1.LumigoSpec, LumigoOperatorVersion string, LumigoInjectorImage string, TelemetryProxyOtlpServiceUrl string, TelemetryProxyOtlpLogsServiceUrl string, LumigoInjectorResources *corev1.ResourceRequirements, LumigoInjectorDefaults *InjectorDefaults, ProtectedWorkloads *ProtectedWorkloads) (Mutator, error) {
 	version := LumigoOperatorVersion
 
 	if len(version) > 8 {
@@ -104,18 +250,84 @@ func NewMutator(Log *logr.Logger, LumigoSpec *operatorv1alpha1.LumigoSpec, Lumig
 	}
 
 	lumigoToken := &operatorv1alpha1.Credentials{}
+	var lumigoInjectorPullPolicy corev1.PullPolicy
+	var lumigoInjectorPullSecrets []corev1.LocalObjectReference
+	var lumigoExtraEnv []corev1.EnvVar
+	var serviceNameTemplate *template.Template
+	tokenInjectionMode := operatorv1alpha1.TokenInjectionModeEnvVar
+	var workloadTypes []operatorv1alpha1.WorkloadType
+	var onlyWorkloadsCreatedAfter *metav1.Time
+	var imagePatterns *ImagePatterns
+	var routes []tracingRoute
+	unsupportedArchPolicy := operatorv1alpha1.UnsupportedArchitecturePolicySkip
+	istioExcludeProxyPorts := false
+	istioHoldApplication := false
 	if LumigoSpec != nil {
 		lumigoToken = &LumigoSpec.LumigoToken
+		lumigoInjectorPullPolicy = LumigoSpec.Tracing.Injection.InjectorImagePullPolicy
+		lumigoInjectorPullSecrets = LumigoSpec.Tracing.Injection.InjectorImagePullSecrets
+		lumigoExtraEnv = getExtraEnv(LumigoSpec, LumigoInjectorDefaults)
+		workloadTypes = LumigoSpec.Tracing.Injection.WorkloadTypes
+		onlyWorkloadsCreatedAfter = LumigoSpec.Tracing.Injection.OnlyWorkloadsCreatedAfter
+		if LumigoSpec.Tracing.Injection.TokenInjectionMode != "" {
+			tokenInjectionMode = LumigoSpec.Tracing.Injection.TokenInjectionMode
+		}
+		if LumigoSpec.Tracing.Injection.ServiceNameTemplate != "" {
+			var err error
+			if serviceNameTemplate, err = ParseServiceNameTemplate(LumigoSpec.Tracing.Injection.ServiceNameTemplate); err != nil {
+				return nil, fmt.Errorf("cannot parse the service name template: %w", err)
+			}
+		}
+		var err error
+		if imagePatterns, err = ParseImagePatterns(&LumigoSpec.Tracing.Injection.ImagePatterns); err != nil {
+			return nil, fmt.Errorf("cannot parse the image patterns: %w", err)
+		}
+		if LumigoSpec.Tracing.Injection.UnsupportedArchitecturePolicy != "" {
+			unsupportedArchPolicy = LumigoSpec.Tracing.Injection.UnsupportedArchitecturePolicy
+		}
+		if istio := LumigoSpec.Tracing.Injection.Istio; istio.ExcludeTelemetryProxyPorts != nil {
+			istioExcludeProxyPorts = *istio.ExcludeTelemetryProxyPorts
+		}
+		if istio := LumigoSpec.Tracing.Injection.Istio; istio.HoldApplicationUntilProxyStarts != nil {
+			istioHoldApplication = *istio.HoldApplicationUntilProxyStarts
+		}
+		for i := range LumigoSpec.Tracing.Routes {
+			route := &LumigoSpec.Tracing.Routes[i]
+			selector, err := metav1.LabelSelectorAsSelector(&route.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse the selector of the '%s' route: %w", route.Name, err)
+			}
+			routes = append(routes, tracingRoute{
+				name:        route.Name,
+				selector:    selector,
+				lumigoToken: &route.LumigoToken,
+			})
+		}
 	}
 
 	return &mutatorImpl{
 		log:                       Log,
+		lumigoOperatorVersion:     LumigoOperatorVersion,
 		lumigoAutotraceLabelValue: LumigoAutoTraceLabelVersionPrefixValue + version,
 		lumigoEndpoint:            TelemetryProxyOtlpServiceUrl,
 		lumigoLogsEndpoint:        TelemetryProxyOtlpLogsServiceUrl,
 		lumigoEnableLogs: 				 lumigoEnableLogs,
 		lumigoToken:               lumigoToken,
+		routes:                    routes,
 		lumigoInjectorImage:       LumigoInjectorImage,
+		lumigoInjectorPullPolicy:  lumigoInjectorPullPolicy,
+		lumigoInjectorPullSecrets: lumigoInjectorPullSecrets,
+		lumigoExtraEnv:            lumigoExtraEnv,
+		serviceNameTemplate:       serviceNameTemplate,
+		tokenInjectionMode:        tokenInjectionMode,
+		workloadTypes:             workloadTypes,
+		onlyWorkloadsCreatedAfter: onlyWorkloadsCreatedAfter,
+		imagePatterns:             imagePatterns,
+		unsupportedArchPolicy:     unsupportedArchPolicy,
+		lumigoInjectorResources:   LumigoInjectorResources,
+		istioExcludeProxyPorts:    istioExcludeProxyPorts,
+		istioHoldApplication:      istioHoldApplication,
+		protectedWorkloads:        ProtectedWorkloads,
 	}, nil
 }
 
@@ -133,6 +345,11 @@ func (m *mutatorImpl) InjectLumigoInto(resource interface{}) (bool, error) {
 		return m.InjectLumigoIntoBatchV1CronJob(a)
 	case *batchv1.Job:
 		return m.InjectLumigoIntoBatchV1Job(a)
+	case *unstructured.Unstructured:
+		if IsKedaScaledJob(a) {
+			return m.InjectLumigoIntoKedaV1alpha1ScaledJob(a)
+		}
+		return false, fmt.Errorf("unexpected resource type to mutate: %s", a.GroupVersionKind())
 	default:
 		return false, fmt.Errorf("unexpected resource type to mutate: %+v", a)
 	}
@@ -152,6 +369,11 @@ func (m *mutatorImpl) RemoveLumigoFrom(resource interface{}) (bool, error) {
 		return m.RemoveLumigoFromBatchV1CronJob(a)
 	case *batchv1.Job:
 		return m.RemoveLumigoFromBatchV1Job(a)
+	case *unstructured.Unstructured:
+		if IsKedaScaledJob(a) {
+			return m.RemoveLumigoFromKedaV1alpha1ScaledJob(a)
+		}
+		return false, fmt.Errorf("unexpected resource type to mutate: %s", a.GroupVersionKind())
 	default:
 		return false, fmt.Errorf("unexpected resource type to mutate: %+v", a)
 	}
@@ -159,7 +381,7 @@ func (m *mutatorImpl) RemoveLumigoFrom(resource interface{}) (bool, error) {
 }
 
 func (m *mutatorImpl) InjectLumigoIntoAppsV1DaemonSet(daemonSet *appsv1.DaemonSet) (bool, error) {
-	return m.injectLumigoInto(&daemonSet.ObjectMeta, &daemonSet.Spec.Template)
+	return m.injectLumigoInto("DaemonSet", &daemonSet.ObjectMeta, &daemonSet.Spec.Template)
 }
 
 func (m *mutatorImpl) RemoveLumigoFromAppsV1DaemonSet(daemonSet *appsv1.DaemonSet) (bool, error) {
@@ -167,7 +389,7 @@ func (m *mutatorImpl) RemoveLumigoFromAppsV1DaemonSet(daemonSet *appsv1.DaemonSe
 }
 
 func (m *mutatorImpl) InjectLumigoIntoAppsV1Deployment(deployment *appsv1.Deployment) (bool, error) {
-	return m.injectLumigoInto(&deployment.ObjectMeta, &deployment.Spec.Template)
+	return m.injectLumigoInto("Deployment", &deployment.ObjectMeta, &deployment.Spec.Template)
 }
 
 func (m *mutatorImpl) RemoveLumigoFromAppsV1Deployment(deployment *appsv1.Deployment) (bool, error) {
@@ -181,7 +403,7 @@ func (m *mutatorImpl) InjectLumigoIntoAppsV1ReplicaSet(replicaSet *appsv1.Replic
 		return false, nil
 	}
 
-	return m.injectLumigoInto(&replicaSet.ObjectMeta, &replicaSet.Spec.Template)
+	return m.injectLumigoInto("ReplicaSet", &replicaSet.ObjectMeta, &replicaSet.Spec.Template)
 }
 
 func (m *mutatorImpl) RemoveLumigoFromAppsV1ReplicaSet(replicaSet *appsv1.ReplicaSet) (bool, error) {
@@ -195,7 +417,7 @@ func (m *mutatorImpl) RemoveLumigoFromAppsV1ReplicaSet(replicaSet *appsv1.Replic
 }
 
 func (m *mutatorImpl) InjectLumigoIntoAppsV1StatefulSet(statefulSet *appsv1.StatefulSet) (bool, error) {
-	return m.injectLumigoInto(&statefulSet.ObjectMeta, &statefulSet.Spec.Template)
+	return m.injectLumigoInto("StatefulSet", &statefulSet.ObjectMeta, &statefulSet.Spec.Template)
 }
 
 func (m *mutatorImpl) RemoveLumigoFromAppsV1StatefulSet(statefulSet *appsv1.StatefulSet) (bool, error) {
@@ -203,7 +425,7 @@ func (m *mutatorImpl) RemoveLumigoFromAppsV1StatefulSet(statefulSet *appsv1.Stat
 }
 
 func (m *mutatorImpl) InjectLumigoIntoBatchV1CronJob(batchJob *batchv1.CronJob) (bool, error) {
-	return m.injectLumigoInto(&batchJob.ObjectMeta, &batchJob.Spec.JobTemplate.Spec.Template)
+	return m.injectLumigoInto("CronJob", &batchJob.ObjectMeta, &batchJob.Spec.JobTemplate.Spec.Template)
 }
 
 func (m *mutatorImpl) RemoveLumigoFromBatchV1CronJob(batchJob *batchv1.CronJob) (bool, error) {
@@ -211,31 +433,85 @@ func (m *mutatorImpl) RemoveLumigoFromBatchV1CronJob(batchJob *batchv1.CronJob)
 }
 
 func (m *mutatorImpl) InjectLumigoIntoBatchV1Job(job *batchv1.Job) (bool, error) {
-	return m.injectLumigoInto(&job.ObjectMeta, &job.Spec.Template)
+	return m.injectLumigoInto("Job", &job.ObjectMeta, &job.Spec.Template)
 }
 
 func (m *mutatorImpl) RemoveLumigoFromBatchV1Job(job *batchv1.Job) (bool, error) {
 	return m.removeLumigoFrom(&job.ObjectMeta, &job.Spec.Template)
 }
 
-func (m *mutatorImpl) injectLumigoInto(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) (bool, error) {
+func (m *mutatorImpl) injectLumigoInto(workloadKind string, topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) (bool, error) {
+	// The workloads of the operator are protected even from the Lumigo instances of their namespace
+	if err := m.protectedWorkloads.Validate(topLevelObjectMeta, &podTemplateSpec.ObjectMeta); err != nil {
+		return false, err
+	}
+
 	if err := m.validateShouldInjectLumigoInto(topLevelObjectMeta); err != nil {
 		return false, err
 	}
 
+	if err := m.validateWorkloadTypeIsInjected(workloadKind); err != nil {
+		return false, err
+	}
+
+	if err := m.validateWorkloadIsCreatedAfterCutoff(topLevelObjectMeta); err != nil {
+		return false, err
+	}
+
+	if err := validateOperatingSystemIsSupported(&podTemplateSpec.Spec); err != nil {
+		return false, err
+	}
+
+	if m.unsupportedArchPolicy == operatorv1alpha1.UnsupportedArchitecturePolicySkip {
+		if err := validateArchitectureIsSupported(&podTemplateSpec.Spec); err != nil {
+			return false, err
+		}
+	}
+
+	if err := m.validateContainerImagesAreInstrumented(&podTemplateSpec.Spec); err != nil {
+		return false, err
+	}
+
 	originalSpec := podTemplateSpec.Spec.DeepCopy()
 
-	if err := m.injectLumigoIntoPodSpec(&podTemplateSpec.Spec); err != nil {
+	route, err := getTokenSecretRouteOf(topLevelObjectMeta)
+	if err != nil {
 		return false, err
 	}
+	if route == nil {
+		route = m.getRouteOf(topLevelObjectMeta)
+	}
+
+	if err := m.injectLumigoIntoPodSpec(&podTemplateSpec.Spec, getInjectedExtraEnvNames(&podTemplateSpec.ObjectMeta), route); err != nil {
+		return false, err
+	}
+
+	if err := m.injectServiceName(workloadKind, topLevelObjectMeta, podTemplateSpec); err != nil {
+		return false, err
+	}
+
+	m.injectDebug(topLevelObjectMeta, podTemplateSpec)
+
+	m.injectImagePullSecrets(podTemplateSpec)
 
-	if reflect.DeepEqual(originalSpec, &podTemplateSpec.Spec) {
+	isIstioChanged := m.injectIstio(podTemplateSpec)
+
+	if !isIstioChanged && reflect.DeepEqual(originalSpec, &podTemplateSpec.Spec) {
 		return false, nil
 	}
 
 	addAutoTraceLabel(topLevelObjectMeta, m.lumigoAutotraceLabelValue)
 	addAutoTraceLabel(&podTemplateSpec.ObjectMeta, m.lumigoAutotraceLabelValue)
 
+	injectionAnnotations := &injectionannotations.InjectionAnnotations{
+		Version:    m.lumigoOperatorVersion,
+		InjectedAt: time.Now(),
+		Containers: InjectedContainerNames(&podTemplateSpec.Spec),
+	}
+	injectionannotations.Set(topLevelObjectMeta, injectionAnnotations)
+	injectionannotations.Set(&podTemplateSpec.ObjectMeta, injectionAnnotations)
+	setInjectedExtraEnvNames(&podTemplateSpec.ObjectMeta, m.lumigoExtraEnv)
+
 	return true, nil
 }
 
@@ -252,17 +528,26 @@ func addAutoTraceLabel(objectMeta *metav1.ObjectMeta, value string) {
 func (m *mutatorImpl) removeLumigoFrom(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) (bool, error) {
 	originalSpec := podTemplateSpec.Spec.DeepCopy()
 
-	if err := m.removeLumigoFromPodSpec(&podTemplateSpec.Spec); err != nil {
+	if err := m.removeLumigoFromPodSpec(&podTemplateSpec.Spec, getInjectedExtraEnvNames(&podTemplateSpec.ObjectMeta), getInjectedImagePullSecretNames(&podTemplateSpec.ObjectMeta)); err != nil {
 		return false, err
 	}
 
-	if reflect.DeepEqual(originalSpec, &podTemplateSpec.Spec) {
+	removeServiceName(podTemplateSpec)
+	removeDebug(podTemplateSpec)
+	isIstioChanged := removeIstio(podTemplateSpec)
+
+	if !isIstioChanged && reflect.DeepEqual(originalSpec, &podTemplateSpec.Spec) {
 		return false, nil
 	}
 
 	removeAutoTraceLabel(topLevelObjectMeta)
 	removeAutoTraceLabel(&podTemplateSpec.ObjectMeta)
 
+	injectionannotations.Remove(topLevelObjectMeta)
+	injectionannotations.Remove(&podTemplateSpec.ObjectMeta)
+	setInjectedExtraEnvNames(&podTemplateSpec.ObjectMeta, nil)
+	setInjectedImagePullSecretNames(&podTemplateSpec.ObjectMeta, nil)
+
 	return true, nil
 }
 
@@ -272,17 +557,191 @@ func removeAutoTraceLabel(objectMeta *metav1.ObjectMeta) {
 	}
 }
 
+func getInjectedExtraEnvNames(objectMeta *metav1.ObjectMeta) []string {
+	value := objectMeta.Annotations[LumigoInjectedExtraEnvAnnotationKey]
+	if len(value) < 1 {
+		return []string{}
+	}
+
+	return strings.Split(value, injectedExtraEnvSeparator)
+}
+
+func setInjectedExtraEnvNames(objectMeta *metav1.ObjectMeta, extraEnv []corev1.EnvVar) {
+	if len(extraEnv) < 1 {
+		if objectMeta.Annotations != nil {
+			delete(objectMeta.Annotations, LumigoInjectedExtraEnvAnnotationKey)
+		}
+		return
+	}
+
+	names := []string{}
+	for _, envVar := range extraEnv {
+		names = append(names, envVar.Name)
+	}
+
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[LumigoInjectedExtraEnvAnnotationKey] = strings.Join(names, injectedExtraEnvSeparator)
+}
+
+// injectImagePullSecrets appends the pull secrets of the injector image to the ones of the workload, recording
+// those it added; the ones added by an earlier injection that are no longer configured are removed.
+func (m *mutatorImpl) injectImagePullSecrets(podTemplateSpec *corev1.PodTemplateSpec) {
+	podSpec := &podTemplateSpec.Spec
+	injectedNames := getInjectedImagePullSecretNames(&podTemplateSpec.ObjectMeta)
+
+	configuredNames := []string{}
+	for _, pullSecret := range m.lumigoInjectorPullSecrets {
+		configuredNames = append(configuredNames, pullSecret.Name)
+	}
+
+	staleNames := []string{}
+	for _, name := range injectedNames {
+		if !slices.Contains(configuredNames, name) {
+			staleNames = append(staleNames, name)
+		}
+	}
+	podSpec.ImagePullSecrets = removeImagePullSecrets(podSpec.ImagePullSecrets, staleNames)
+
+	addedNames := []string{}
+	for _, pullSecret := range m.lumigoInjectorPullSecrets {
+		if slices.Contains(podSpec.ImagePullSecrets, pullSecret) {
+			if slices.Contains(injectedNames, pullSecret.Name) {
+				addedNames = append(addedNames, pullSecret.Name)
+			}
+			continue
+		}
+
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, pullSecret)
+		addedNames = append(addedNames, pullSecret.Name)
+	}
+
+	setInjectedImagePullSecretNames(&podTemplateSpec.ObjectMeta, addedNames)
+}
+
+func removeImagePullSecrets(pullSecrets []corev1.LocalObjectReference, names []string) []corev1.LocalObjectReference {
+	if len(names) < 1 {
+		return pullSecrets
+	}
+
+	newPullSecrets := []corev1.LocalObjectReference{}
+	for _, pullSecret := range pullSecrets {
+		if !slices.Contains(names, pullSecret.Name) {
+			newPullSecrets = append(newPullSecrets, pullSecret)
+		}
+	}
+
+	if len(newPullSecrets) < 1 {
+		return nil
+	}
+
+	return newPullSecrets
+}
+
+func getInjectedImagePullSecretNames(objectMeta *metav1.ObjectMeta) []string {
+	value := objectMeta.Annotations[LumigoInjectedImagePullSecretsAnnotationKey]
+	if len(value) < 1 {
+		return []string{}
+	}
+
+	return strings.Split(value, injectedImagePullSecretsSeparator)
+}
+
+func setInjectedImagePullSecretNames(objectMeta *metav1.ObjectMeta, names []string) {
+	if len(names) < 1 {
+		if objectMeta.Annotations != nil {
+			delete(objectMeta.Annotations, LumigoInjectedImagePullSecretsAnnotationKey)
+		}
+		return
+	}
+
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[LumigoInjectedImagePullSecretsAnnotationKey] = strings.Join(names, injectedImagePullSecretsSeparator)
+}
+
 func (m *mutatorImpl) validateShouldInjectLumigoInto(resourceMeta *metav1.ObjectMeta) error {
 	autoTraceLabelValue := resourceMeta.Labels[LumigoAutoTraceLabelKey]
 	if strings.ToLower(autoTraceLabelValue) == "false" {
 		// Opt-out for this resource, skip injection
-		return fmt.Errorf("the resource has the '%s' label set to 'false'", LumigoAutoTraceLabelKey)
+		return &SkipInjectionError{
+			Code:   SkipReasonOptedOut,
+			Reason: fmt.Sprintf("the resource has the '%s' label set to 'false'", LumigoAutoTraceLabelKey),
+		}
 	}
 
 	return nil
 }
 
-func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
+func (m *mutatorImpl) validateWorkloadTypeIsInjected(workloadKind string) error {
+	if len(m.workloadTypes) < 1 || slices.Contains(m.workloadTypes, operatorv1alpha1.WorkloadType(workloadKind)) {
+		return nil
+	}
+
+	workloadTypes := make([]string, len(m.workloadTypes))
+	for i, workloadType := range m.workloadTypes {
+		workloadTypes[i] = string(workloadType)
+	}
+
+	return &SkipInjectionError{
+		Code:   SkipReasonWorkloadTypeNotInjected,
+		Reason: fmt.Sprintf("the workload type '%s' is not among the workload types to inject: %s", workloadKind, strings.Join(workloadTypes, ", ")),
+	}
+}
+
+// The workloads being created have no creation timestamp yet, and are created now; the ones already injected
+// keep their injection up to date, so that the cutoff does not leave them with the settings of an older injection
+func (m *mutatorImpl) validateWorkloadIsCreatedAfterCutoff(topLevelObjectMeta *metav1.ObjectMeta) error {
+	if m.onlyWorkloadsCreatedAfter == nil {
+		return nil
+	}
+
+	if strings.HasPrefix(topLevelObjectMeta.Labels[LumigoAutoTraceLabelKey], LumigoAutoTraceLabelVersionPrefixValue) {
+		return nil
+	}
+
+	creationTime := topLevelObjectMeta.CreationTimestamp.Time
+	if creationTime.IsZero() {
+		creationTime = time.Now()
+	}
+
+	if creationTime.After(m.onlyWorkloadsCreatedAfter.Time) {
+		return nil
+	}
+
+	return &SkipInjectionError{
+		Code:   SkipReasonCreatedBeforeCutoff,
+		Reason: fmt.Sprintf("the workload was created at %s, not after %s, from which on workloads are injected", creationTime.UTC().Format(time.RFC3339), m.onlyWorkloadsCreatedAfter.UTC().Format(time.RFC3339)),
+	}
+}
+
+// The first route of `.spec.tracing.routes` whose selector matches the labels of the workload, or nil
+// if the workload reports with the Lumigo token of the namespace
+func (m *mutatorImpl) getRouteOf(topLevelObjectMeta *metav1.ObjectMeta) *tracingRoute {
+	for i := range m.routes {
+		if m.routes[i].selector.Matches(labels.Set(topLevelObjectMeta.Labels)) {
+			return &m.routes[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec, injectedExtraEnvNames []string, route *tracingRoute) error {
+	lumigoToken := m.lumigoToken
+	lumigoTracerTokenSecretName := LumigoTracerTokenSecretName
+	lumigoTracerTokenSecretKey := LumigoTracerTokenSecretKey
+	if route != nil && route.isWorkloadTokenSecret {
+		lumigoToken = route.lumigoToken
+		lumigoTracerTokenSecretName = route.lumigoToken.SecretRef.Name
+		lumigoTracerTokenSecretKey = route.lumigoToken.SecretRef.Key
+	} else if route != nil {
+		lumigoToken = route.lumigoToken
+		lumigoTracerTokenSecretKey = LumigoTracerTokenSecretKeyOfRoute(route.name)
+	}
+
 	lumigoInjectorVolume := &corev1.Volume{
 		Name: LumigoInjectorVolumeName,
 		VolumeSource: corev1.VolumeSource{
@@ -305,18 +764,59 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 	}
 	podSpec.Volumes = volumes
 
+	lumigoTracerTokenVolumeIndex := slices.IndexFunc(podSpec.Volumes, func(c corev1.Volume) bool { return c.Name == LumigoTracerTokenVolumeName })
+	if m.tokenInjectionMode == operatorv1alpha1.TokenInjectionModeProjectedSecret {
+		lumigoTracerTokenVolume := corev1.Volume{
+			Name: LumigoTracerTokenVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{
+							Secret: &corev1.SecretProjection{
+								LocalObjectReference: corev1.LocalObjectReference{
+									Name: lumigoTracerTokenSecretName,
+								},
+								Items: []corev1.KeyToPath{
+									{
+										Key:  lumigoTracerTokenSecretKey,
+										Path: LumigoTracerTokenSecretKey,
+									},
+								},
+								// The secret is created by the reconciler, possibly after the pod
+								Optional: newTrue(),
+							},
+						},
+					},
+				},
+			},
+		}
+		if lumigoTracerTokenVolumeIndex < 0 {
+			podSpec.Volumes = append(podSpec.Volumes, lumigoTracerTokenVolume)
+		} else {
+			podSpec.Volumes[lumigoTracerTokenVolumeIndex] = lumigoTracerTokenVolume
+		}
+	} else if lumigoTracerTokenVolumeIndex > -1 {
+		podSpec.Volumes = slices.Delete(podSpec.Volumes, lumigoTracerTokenVolumeIndex, lumigoTracerTokenVolumeIndex+1)
+	}
+
 	// The `lumigo-injector` init-container must be able to write to the `lumigo-injector`` volume.
 	// To ensure that, if FSGroup is set, the `lumigo-injector` init-container should use it as group.
 	initContainerUser := &defaultLumigoInitContainerUser
 	initContainerGroup := &defaultLumigoInitContainerGroup
-	if podSpec.SecurityContext.FSGroup != nil {
-		initContainerUser = podSpec.SecurityContext.FSGroup
-		initContainerGroup = podSpec.SecurityContext.FSGroup
+	// The pod specs not read from the API server, e.g., those mutated through the mutation API, may have no security context
+	podSecurityContext := podSpec.SecurityContext
+	if podSecurityContext == nil {
+		podSecurityContext = &corev1.PodSecurityContext{}
+	}
+	if podSecurityContext.FSGroup != nil {
+		initContainerUser = podSecurityContext.FSGroup
+		initContainerGroup = podSecurityContext.FSGroup
 	}
 
 	lumigoInjectorContainer := &corev1.Container{
-		Name:  LumigoInjectorContainerName,
-		Image: m.lumigoInjectorImage,
+		Name:            LumigoInjectorContainerName,
+		Image:           m.lumigoInjectorImage,
+		ImagePullPolicy: m.lumigoInjectorPullPolicy,
 		Env: []corev1.EnvVar{
 			{
 				Name:  TargetDirectoryEnvVarName,
@@ -328,7 +828,7 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 			Privileged:               &f,
 			ReadOnlyRootFilesystem:   &t,
 			// We need to have no more privileges than the rest of the pod
-			RunAsNonRoot: podSpec.SecurityContext.RunAsNonRoot,
+			RunAsNonRoot: podSecurityContext.RunAsNonRoot,
 			RunAsUser:    initContainerUser,
 			RunAsGroup:   initContainerGroup,
 		},
@@ -340,6 +840,9 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 			},
 		},
 	}
+	if m.lumigoInjectorResources != nil {
+		lumigoInjectorContainer.Resources = *m.lumigoInjectorResources.DeepCopy()
+	}
 
 	initContainers := podSpec.InitContainers
 	if initContainers == nil {
@@ -352,10 +855,22 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 	} else {
 		initContainers[lumigoInjectorContainerIndex] = *lumigoInjectorContainer
 	}
-	podSpec.InitContainers = initContainers
+	podSpec.InitContainers = orderLumigoInjectorAfterMeshInitContainers(initContainers)
+
+	if m.unsupportedArchPolicy == operatorv1alpha1.UnsupportedArchitecturePolicyNodeAffinity {
+		addSupportedArchitecturesNodeAffinity(podSpec)
+	}
 
+	// Only the containers are injected; the ephemeral containers are never changed, see StripLumigoFromEphemeralContainers
 	patchedContainers := []corev1.Container{}
 	for _, container := range podSpec.Containers {
+		// The containers that are not instrumented any longer, e.g., because of their image, lose the injection of earlier mutations
+		if !m.isInstrumented(&container) {
+			removeLumigoFromContainer(&container, injectedExtraEnvNames)
+			patchedContainers = append(patchedContainers, container)
+			continue
+		}
+
 		lumigoInjectorVolumeMount := &corev1.VolumeMount{
 			Name:      LumigoInjectorVolumeName,
 			ReadOnly:  true,
@@ -380,6 +895,18 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 			envVars = []corev1.EnvVar{}
 		}
 
+		// The extra env vars of an earlier injection are replaced with the current ones of the Lumigo
+		// resource; the env vars set below are managed by the operator and take precedence over them
+		envVars = removeEnvVars(envVars, injectedExtraEnvNames)
+		for _, extraEnvVar := range m.lumigoExtraEnv {
+			extraEnvVarIndex := slices.IndexFunc(envVars, func(c corev1.EnvVar) bool { return c.Name == extraEnvVar.Name })
+			if extraEnvVarIndex < 0 {
+				envVars = append(envVars, *extraEnvVar.DeepCopy())
+			} else {
+				envVars[extraEnvVarIndex] = *extraEnvVar.DeepCopy()
+			}
+		}
+
 		ldPreloadEnvVar := &corev1.EnvVar{
 			Name:  LdPreloadEnvVarName,
 			Value: LdPreloadEnvVarValue,
@@ -391,23 +918,71 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 			envVars[ldPreloadEnvVarIndex] = *ldPreloadEnvVar
 		}
 
-		lumigoTracerTokenEnvVar := &corev1.EnvVar{
-			Name: LumigoTracerTokenEnvVarName,
-			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: m.lumigoToken.SecretRef.Name,
+		// The token is either set as env var, or mounted as a file, depending on the token injection mode
+		lumigoTracerTokenVolumeMountIndex := slices.IndexFunc(container.VolumeMounts, func(c corev1.VolumeMount) bool { return c.Name == LumigoTracerTokenVolumeName })
+		if m.tokenInjectionMode == operatorv1alpha1.TokenInjectionModeProjectedSecret {
+			lumigoTracerTokenVolumeMount := corev1.VolumeMount{
+				Name:      LumigoTracerTokenVolumeName,
+				ReadOnly:  true,
+				MountPath: LumigoTracerTokenVolumeMountPoint,
+			}
+			if lumigoTracerTokenVolumeMountIndex < 0 {
+				container.VolumeMounts = append(container.VolumeMounts, lumigoTracerTokenVolumeMount)
+			} else {
+				container.VolumeMounts[lumigoTracerTokenVolumeMountIndex] = lumigoTracerTokenVolumeMount
+			}
+
+			envVars = removeEnvVars(envVars, []string{LumigoTracerTokenEnvVarName})
+			lumigoTracerTokenFileEnvVar := &corev1.EnvVar{
+				Name:  LumigoTracerTokenFileEnvVarName,
+				Value: LumigoTracerTokenFilePath,
+			}
+			lumigoTracerTokenFileEnvVarIndex := slices.IndexFunc(envVars, func(c corev1.EnvVar) bool { return c.Name == LumigoTracerTokenFileEnvVarName })
+			if lumigoTracerTokenFileEnvVarIndex < 0 {
+				envVars = append(envVars, *lumigoTracerTokenFileEnvVar)
+			} else {
+				envVars[lumigoTracerTokenFileEnvVarIndex] = *lumigoTracerTokenFileEnvVar
+			}
+		} else {
+			if lumigoTracerTokenVolumeMountIndex > -1 {
+				container.VolumeMounts = slices.Delete(container.VolumeMounts, lumigoTracerTokenVolumeMountIndex, lumigoTracerTokenVolumeMountIndex+1)
+			}
+
+			envVars = removeEnvVars(envVars, []string{LumigoTracerTokenFileEnvVarName})
+			lumigoTracerTokenEnvVar := &corev1.EnvVar{
+				Name: LumigoTracerTokenEnvVarName,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: lumigoToken.SecretRef.Name,
+						},
+						Key:      lumigoToken.SecretRef.Key,
+						Optional: newTrue(),
 					},
-					Key:      m.lumigoToken.SecretRef.Key,
-					Optional: newTrue(),
 				},
-			},
+			}
+			lumigoTracerTokenEnvVarIndex := slices.IndexFunc(envVars, func(c corev1.EnvVar) bool { return c.Name == LumigoTracerTokenEnvVarName })
+			if lumigoTracerTokenEnvVarIndex < 0 {
+				envVars = append(envVars, *lumigoTracerTokenEnvVar)
+			} else {
+				envVars[lumigoTracerTokenEnvVarIndex] = *lumigoTracerTokenEnvVar
+			}
 		}
-		lumigoTracerTokenEnvVarIndex := slices.IndexFunc(envVars, func(c corev1.EnvVar) bool { return c.Name == LumigoTracerTokenEnvVarName })
-		if lumigoTracerTokenEnvVarIndex < 0 {
-			envVars = append(envVars, *lumigoTracerTokenEnvVar)
-		} else {
-			envVars[lumigoTracerTokenEnvVarIndex] = *lumigoTracerTokenEnvVar
+
+		// Kubernetes expands references to environment variables only if they are defined earlier in the list
+		telemetryProxyHostIpEnvVarIndex := slices.IndexFunc(envVars, func(c corev1.EnvVar) bool { return c.Name == TelemetryProxyHostIpEnvVarName })
+		if telemetryProxyHostIpEnvVarIndex > -1 {
+			envVars = slices.Delete(envVars, telemetryProxyHostIpEnvVarIndex, telemetryProxyHostIpEnvVarIndex+1)
+		}
+		if m.isTelemetryProxyNodeLocal() {
+			envVars = slices.Insert(envVars, 0, corev1.EnvVar{
+				Name: TelemetryProxyHostIpEnvVarName,
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "status.hostIP",
+					},
+				},
+			})
 		}
 
 		lumigoEndpointEnvVar := &corev1.EnvVar{
@@ -462,7 +1037,7 @@ func (m *mutatorImpl) injectLumigoIntoPodSpec(podSpec *corev1.PodSpec) error {
 	return nil
 }
 
-func (m *mutatorImpl) removeLumigoFromPodSpec(podSpec *corev1.PodSpec) error {
+func (m *mutatorImpl) removeLumigoFromPodSpec(podSpec *corev1.PodSpec, injectedExtraEnvNames []string, injectedImagePullSecretNames []string) error {
 	if podSpec.InitContainers != nil {
 		newInitContainers := []corev1.Container{}
 		for _, initContainer := range podSpec.InitContainers {
@@ -476,40 +1051,133 @@ func (m *mutatorImpl) removeLumigoFromPodSpec(podSpec *corev1.PodSpec) error {
 	if podSpec.Volumes != nil {
 		newVolumes := []corev1.Volume{}
 		for _, volume := range podSpec.Volumes {
-			if isLumigoInjectorVolume, _ := BeTheLumigoInjectorVolume().Match(volume); !isLumigoInjectorVolume {
+			if isLumigoInjectorVolume, _ := BeTheLumigoInjectorVolume().Match(volume); !isLumigoInjectorVolume && volume.Name != LumigoTracerTokenVolumeName {
 				newVolumes = append(newVolumes, volume)
 			}
 		}
 		podSpec.Volumes = newVolumes
 	}
 
-	envVarsToRemove := []string{LumigoTracerTokenEnvVarName, LumigoEndpointEnvVarName, LdPreloadEnvVarName}
+	removeSupportedArchitecturesNodeAffinity(podSpec)
+
+	podSpec.ImagePullSecrets = removeImagePullSecrets(podSpec.ImagePullSecrets, injectedImagePullSecretNames)
+
 	newContainers := []corev1.Container{}
 	for _, container := range podSpec.Containers {
-		if container.VolumeMounts != nil {
-			newVolumeMounts := []corev1.VolumeMount{}
-			for _, volumeMount := range container.VolumeMounts {
-				if volumeMount.Name != LumigoInjectorVolumeName {
-					newVolumeMounts = append(newVolumeMounts, volumeMount)
-				}
+		removeLumigoFromContainer(&container, injectedExtraEnvNames)
+		newContainers = append(newContainers, container)
+	}
+	podSpec.Containers = newContainers
+
+	return nil
+}
+
+func removeLumigoFromContainer(container *corev1.Container, injectedExtraEnvNames []string) {
+	if container.VolumeMounts != nil {
+		newVolumeMounts := []corev1.VolumeMount{}
+		for _, volumeMount := range container.VolumeMounts {
+			if volumeMount.Name != LumigoInjectorVolumeName && volumeMount.Name != LumigoTracerTokenVolumeName {
+				newVolumeMounts = append(newVolumeMounts, volumeMount)
 			}
-			container.VolumeMounts = newVolumeMounts
 		}
+		container.VolumeMounts = newVolumeMounts
+	}
 
-		newEnvVar := []corev1.EnvVar{}
-		for _, envVar := range container.Env {
-			if !slices.Contains(envVarsToRemove, envVar.Name) {
-				newEnvVar = append(newEnvVar, envVar)
-			}
+	envVarsToRemove := append([]string{LumigoTracerTokenEnvVarName, LumigoTracerTokenFileEnvVarName, LumigoEndpointEnvVarName, LdPreloadEnvVarName, TelemetryProxyHostIpEnvVarName}, injectedExtraEnvNames...)
+	container.Env = removeEnvVars(container.Env, envVarsToRemove)
+}
+
+func removeEnvVars(envVars []corev1.EnvVar, names []string) []corev1.EnvVar {
+	newEnvVars := []corev1.EnvVar{}
+	for _, envVar := range envVars {
+		if !slices.Contains(names, envVar.Name) {
+			newEnvVars = append(newEnvVars, envVar)
 		}
+	}
 
-		container.Env = newEnvVar
+	return newEnvVars
+}
 
-		newContainers = append(newContainers, container)
+// InjectedContainerNames returns the names of the containers of the pod spec that mount the
+// Lumigo injector volume, in the order they appear in the pod spec
+func InjectedContainerNames(podSpec *corev1.PodSpec) []string {
+	containerNames := []string{}
+	for _, container := range podSpec.Containers {
+		if slices.IndexFunc(container.VolumeMounts, func(v corev1.VolumeMount) bool { return v.Name == LumigoInjectorVolumeName }) > -1 {
+			containerNames = append(containerNames, container.Name)
+		}
 	}
-	podSpec.Containers = newContainers
 
-	return nil
+	return containerNames
+}
+
+// RepairInjectionAnnotations adds the injection annotations to a resource that has been injected
+// by a version of the Lumigo operator that did not write them, taking the version from its
+// autotrace label; resources that are not injected are left untouched.
+func RepairInjectionAnnotations(resource interface{}, now time.Time) (bool, error) {
+	switch a := resource.(type) {
+	case *appsv1.DaemonSet:
+		return repairInjectionAnnotations(&a.ObjectMeta, &a.Spec.Template, now), nil
+	case *appsv1.Deployment:
+		return repairInjectionAnnotations(&a.ObjectMeta, &a.Spec.Template, now), nil
+	case *appsv1.ReplicaSet:
+		if hasDeploymentOwner, err := hasDeploymentOwnerReference(a.OwnerReferences); err != nil {
+			return false, err
+		} else if hasDeploymentOwner {
+			// The Deployment owns the pod template of the ReplicaSet
+			return false, nil
+		}
+		return repairInjectionAnnotations(&a.ObjectMeta, &a.Spec.Template, now), nil
+	case *appsv1.StatefulSet:
+		return repairInjectionAnnotations(&a.ObjectMeta, &a.Spec.Template, now), nil
+	case *batchv1.CronJob:
+		return repairInjectionAnnotations(&a.ObjectMeta, &a.Spec.JobTemplate.Spec.Template, now), nil
+	case *batchv1.Job:
+		// The pod template of jobs is immutable, so only the job itself can be repaired
+		podTemplateSpec := a.Spec.Template.DeepCopy()
+		return repairInjectionAnnotations(&a.ObjectMeta, podTemplateSpec, now), nil
+	default:
+		return false, fmt.Errorf("unexpected resource type to repair: %+v", a)
+	}
+}
+
+func repairInjectionAnnotations(topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec, now time.Time) bool {
+	autoTraceLabelValue := topLevelObjectMeta.Labels[LumigoAutoTraceLabelKey]
+	if !strings.HasPrefix(autoTraceLabelValue, LumigoAutoTraceLabelVersionPrefixValue) {
+		return false
+	}
+
+	containerNames := InjectedContainerNames(&podTemplateSpec.Spec)
+	if len(containerNames) < 1 {
+		return false
+	}
+
+	isChanged := false
+	injectionAnnotations, err := injectionannotations.Get(&podTemplateSpec.ObjectMeta)
+	if err != nil || injectionAnnotations == nil {
+		// When the resource was injected is not known, so we use when the annotations are repaired
+		injectionAnnotations = &injectionannotations.InjectionAnnotations{
+			Version:    strings.TrimPrefix(autoTraceLabelValue, LumigoAutoTraceLabelVersionPrefixValue),
+			InjectedAt: now,
+			Containers: containerNames,
+		}
+		injectionannotations.Set(&podTemplateSpec.ObjectMeta, injectionAnnotations)
+		isChanged = true
+	}
+
+	if !injectionannotations.IsComplete(topLevelObjectMeta) {
+		injectionannotations.Set(topLevelObjectMeta, injectionAnnotations)
+		isChanged = true
+	}
+
+	return isChanged
+}
+
+// isTelemetryProxyNodeLocal returns whether the workloads send telemetry to the telemetry-proxy
+// on their own node, rather than to the telemetry-proxy service
+func (m *mutatorImpl) isTelemetryProxyNodeLocal() bool {
+	hostIpReference := fmt.Sprintf("$(%s)", TelemetryProxyHostIpEnvVarName)
+	return strings.Contains(m.lumigoEndpoint, hostIpReference) || strings.Contains(m.lumigoLogsEndpoint, hostIpReference)
 }
 
 func newTrue() *bool {
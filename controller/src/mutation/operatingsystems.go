@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const KubernetesOsLabelKey = "kubernetes.io/os"
+
+// The Lumigo injector relies on LD_PRELOAD and Linux paths, and cannot run on Windows nodes
+var windowsOsName = string(corev1.Windows)
+
+// Returns an error if the pod spec is meant to run on Windows nodes, based on its
+// OS field, node selector and required node affinity.
+func validateOperatingSystemIsSupported(podSpec *corev1.PodSpec) error {
+	if podSpec.OS != nil && podSpec.OS.Name == corev1.Windows {
+		return &SkipInjectionError{
+			Code:   SkipReasonUnsupportedOperatingSystem,
+			Reason: "the pod spec has the 'windows' OS, which is not supported by the Lumigo injector",
+		}
+	}
+
+	if os, ok := podSpec.NodeSelector[KubernetesOsLabelKey]; ok && os == windowsOsName {
+		return &SkipInjectionError{
+			Code:   SkipReasonUnsupportedOperatingSystem,
+			Reason: "the pod spec selects nodes with the 'windows' OS, which is not supported by the Lumigo injector",
+		}
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil || podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+
+	// Node selector terms are ORed: the pod is unsupported only if all terms require Windows nodes
+	terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return nil
+	}
+
+	for _, term := range terms {
+		if !termRequiresWindows(&term) {
+			return nil
+		}
+	}
+
+	return &SkipInjectionError{
+		Code:   SkipReasonUnsupportedOperatingSystem,
+		Reason: "the required node affinity of the pod spec only allows nodes with the 'windows' OS, which is not supported by the Lumigo injector",
+	}
+}
+
+func termRequiresWindows(term *corev1.NodeSelectorTerm) bool {
+	for _, expression := range term.MatchExpressions {
+		if expression.Key != KubernetesOsLabelKey {
+			continue
+		}
+
+		switch expression.Operator {
+		case corev1.NodeSelectorOpIn:
+			if len(expression.Values) > 0 && !slices.ContainsFunc(expression.Values, func(os string) bool { return os != windowsOsName }) {
+				return true
+			}
+		case corev1.NodeSelectorOpNotIn:
+			if slices.Contains(expression.Values, string(corev1.Linux)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
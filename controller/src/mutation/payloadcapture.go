@@ -0,0 +1,94 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const LumigoMaxEntrySizeEnvVarName = "LUMIGO_MAX_ENTRY_SIZE"
+const LumigoSecretMaskingRequestBodiesEnvVarName = "LUMIGO_SECRET_MASKING_REGEX_HTTP_REQUEST_BODIES"
+const LumigoSecretMaskingResponseBodiesEnvVarName = "LUMIGO_SECRET_MASKING_REGEX_HTTP_RESPONSE_BODIES"
+const LumigoSecretMaskingRequestHeadersEnvVarName = "LUMIGO_SECRET_MASKING_REGEX_HTTP_REQUEST_HEADERS"
+const LumigoSecretMaskingResponseHeadersEnvVarName = "LUMIGO_SECRET_MASKING_REGEX_HTTP_RESPONSE_HEADERS"
+
+// The value of the secret masking env vars with which the Lumigo distros mask the payloads entirely
+const lumigoSecretMaskingAll = "all"
+
+// newPayloadCaptureEnv returns the env vars with which the Lumigo distros apply the payload capture settings
+func newPayloadCaptureEnv(payloadCapture *operatorv1alpha1.PayloadCaptureSpec) []corev1.EnvVar {
+	env := []corev1.EnvVar{}
+
+	if payloadCapture.MaxEntrySize != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  LumigoMaxEntrySizeEnvVarName,
+			Value: strconv.Itoa(int(*payloadCapture.MaxEntrySize)),
+		})
+	}
+
+	if payloadCapture.CaptureRequestBodies != nil && !*payloadCapture.CaptureRequestBodies {
+		env = append(env, corev1.EnvVar{
+			Name:  LumigoSecretMaskingRequestBodiesEnvVarName,
+			Value: lumigoSecretMaskingAll,
+		})
+	}
+
+	if payloadCapture.CaptureResponseBodies != nil && !*payloadCapture.CaptureResponseBodies {
+		env = append(env, corev1.EnvVar{
+			Name:  LumigoSecretMaskingResponseBodiesEnvVarName,
+			Value: lumigoSecretMaskingAll,
+		})
+	}
+
+	if len(payloadCapture.AllowedHeaders) > 0 {
+		headersMaskingRegexes := newHeadersMaskingRegexes(payloadCapture.AllowedHeaders)
+		env = append(env,
+			corev1.EnvVar{
+				Name:  LumigoSecretMaskingRequestHeadersEnvVarName,
+				Value: headersMaskingRegexes,
+			},
+			corev1.EnvVar{
+				Name:  LumigoSecretMaskingResponseHeadersEnvVarName,
+				Value: headersMaskingRegexes,
+			},
+		)
+	}
+
+	return env
+}
+
+// The secret masking env vars of the Lumigo distros hold a JSON array of the regexes of the keys to mask;
+// the allowed headers are turned into a single regex matching all the other header names. Header names
+// are matched in lowercase, as the HTTP libraries instrumented by the Lumigo distros normalize them so.
+func newHeadersMaskingRegexes(allowedHeaders []operatorv1alpha1.HeaderName) string {
+	quotedHeaderNames := make([]string, len(allowedHeaders))
+	for i, headerName := range allowedHeaders {
+		quotedHeaderNames[i] = regexp.QuoteMeta(strings.ToLower(string(headerName)))
+	}
+
+	// Marshaling a slice of strings cannot fail
+	regexes, _ := json.Marshal([]string{fmt.Sprintf("^(?!(%s)$).*$", strings.Join(quotedHeaderNames, "|"))})
+	return string(regexes)
+}
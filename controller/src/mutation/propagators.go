@@ -0,0 +1,48 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"golang.org/x/exp/slices"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// The propagators to which `xray` is added when `.spec.tracing.awsXRay` is enabled without `.spec.tracing.propagators`,
+// i.e., the default propagators of the OpenTelemetry SDKs
+var defaultPropagators = []operatorv1alpha1.Propagator{operatorv1alpha1.PropagatorTraceContext, operatorv1alpha1.PropagatorBaggage}
+
+// IsAwsXRayEnabled returns whether the injected containers continue the traces of the `X-Amzn-Trace-Id` headers
+func IsAwsXRayEnabled(spec *operatorv1alpha1.LumigoSpec) bool {
+	return spec.Tracing.AwsXRay.Enabled != nil && *spec.Tracing.AwsXRay.Enabled
+}
+
+// EffectivePropagators returns the propagators set in the `OTEL_PROPAGATORS` env var of the injected containers:
+// those of `.spec.tracing.propagators`, with `xray` added if `.spec.tracing.awsXRay` is enabled, or none if the
+// Lumigo distros use their default propagators
+func EffectivePropagators(spec *operatorv1alpha1.LumigoSpec) []operatorv1alpha1.Propagator {
+	propagators := spec.Tracing.Propagators
+	if !IsAwsXRayEnabled(spec) || slices.Contains(propagators, operatorv1alpha1.PropagatorXRay) {
+		return propagators
+	}
+
+	if len(propagators) < 1 {
+		propagators = defaultPropagators
+	}
+
+	return append(append([]operatorv1alpha1.Propagator{}, propagators...), operatorv1alpha1.PropagatorXRay)
+}
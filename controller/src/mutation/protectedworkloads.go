@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DefaultProtectedWorkloadSelectors select the workloads of the operator itself, which are never injected: the
+// controller-manager, which runs the webhooks and the telemetry proxy, and the resources the operator creates,
+// like the dedicated and DaemonSet telemetry proxies and the telemetry verification jobs
+var DefaultProtectedWorkloadSelectors = []string{
+	"app.kubernetes.io/managed-by=lumigo-operator",
+	"app.kubernetes.io/part-of=lumigo,control-plane=controller-manager",
+}
+
+// ProtectedWorkloads are the workloads that are never injected, regardless of the Lumigo instance of their
+// namespace, as injecting the operator into itself breaks the webhooks and the telemetry of the whole cluster
+type ProtectedWorkloads struct {
+	namespaces []string
+	selectors  []labels.Selector
+}
+
+// NewProtectedWorkloads returns the ProtectedWorkloads made of the workloads of the given namespaces, e.g., the one
+// of the operator, and of the workloads matching the DefaultProtectedWorkloadSelectors or the given extra ones
+func NewProtectedWorkloads(namespaces []string, extraSelectors []string) (*ProtectedWorkloads, error) {
+	selectors := []labels.Selector{}
+	for _, rawSelector := range append(slices.Clone(DefaultProtectedWorkloadSelectors), extraSelectors...) {
+		selector, err := labels.Parse(rawSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protected workload selector '%s': %w", rawSelector, err)
+		}
+		selectors = append(selectors, selector)
+	}
+
+	protectedNamespaces := []string{}
+	for _, namespace := range namespaces {
+		if len(namespace) > 0 && !slices.Contains(protectedNamespaces, namespace) {
+			protectedNamespaces = append(protectedNamespaces, namespace)
+		}
+	}
+
+	return &ProtectedWorkloads{
+		namespaces: protectedNamespaces,
+		selectors:  selectors,
+	}, nil
+}
+
+// Validate returns a SkipInjectionError if the workload, or its pod template, is protected
+func (p *ProtectedWorkloads) Validate(topLevelObjectMeta *metav1.ObjectMeta, podTemplateObjectMeta *metav1.ObjectMeta) error {
+	if p == nil {
+		return nil
+	}
+
+	if slices.Contains(p.namespaces, topLevelObjectMeta.Namespace) {
+		return &SkipInjectionError{
+			Code:   SkipReasonProtectedWorkload,
+			Reason: fmt.Sprintf("the workloads of the namespace '%s' are protected from injection", topLevelObjectMeta.Namespace),
+		}
+	}
+
+	for _, selector := range p.selectors {
+		if selector.Matches(labels.Set(topLevelObjectMeta.Labels)) || selector.Matches(labels.Set(podTemplateObjectMeta.Labels)) {
+			return &SkipInjectionError{
+				Code:   SkipReasonProtectedWorkload,
+				Reason: fmt.Sprintf("the workload matches the protected workload selector '%s'", selector.String()),
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// SkipReason is why a resource is not injected, among a fixed set of values that can be counted in metrics
+// and reports, unlike the messages of the SkipInjectionError, which are meant for humans
+type SkipReason string
+
+const (
+	// The resource has the `lumigo.auto-trace` label set to `false`
+	SkipReasonOptedOut SkipReason = "OptedOut"
+	// The kind of the resource is not among the `.spec.tracing.injection.workloadTypes`
+	SkipReasonWorkloadTypeNotInjected SkipReason = "WorkloadTypeNotInjected"
+	// The resource was created before the `.spec.tracing.injection.onlyWorkloadsCreatedAfter` cutoff
+	SkipReasonCreatedBeforeCutoff SkipReason = "CreatedBeforeCutoff"
+	// None of the images of the containers match the `.spec.tracing.injection.imagePatterns`
+	SkipReasonImageNotMatched SkipReason = "ImageNotMatched"
+	// The pods run on CPU architectures that the Lumigo injector does not support
+	SkipReasonUnsupportedArchitecture SkipReason = "UnsupportedArchitecture"
+	// The pods run on operating systems that the Lumigo injector does not support
+	SkipReasonUnsupportedOperatingSystem SkipReason = "UnsupportedOperatingSystem"
+	// The `lumigo.io/token-secret` annotation of the resource does not reference a secret key
+	SkipReasonInvalidTokenSecret SkipReason = "InvalidTokenSecret"
+	// The resource belongs to the operator, or is otherwise protected from the injection, see ProtectedWorkloads
+	SkipReasonProtectedWorkload SkipReason = "ProtectedWorkload"
+	// The SkipInjectionError does not say why
+	SkipReasonUnspecified SkipReason = "Unspecified"
+)
+
+// GetSkipReason returns why the resource has been skipped if the error is a SkipInjectionError, and an
+// empty SkipReason otherwise
+func GetSkipReason(err error) SkipReason {
+	var skipInjectionError *SkipInjectionError
+	if !errors.As(err, &skipInjectionError) {
+		return ""
+	}
+
+	if skipInjectionError.Code == "" {
+		return SkipReasonUnspecified
+	}
+
+	return skipInjectionError.Code
+}
+
+// InjectionResult is the outcome of the injection of a resource
+type InjectionResult struct {
+	// Whether the resource has been changed
+	Mutated bool
+	// Why the resource has not been injected; empty unless it has been skipped
+	SkipReason SkipReason
+	// The explanation of the SkipReason, for humans
+	SkipMessage string
+	// The paths of the fields of the resource that the injection changed, e.g., `spec.template.spec.initContainers`,
+	// sorted; the lists are compared as a whole, so that a changed container is reported as its list
+	ChangedFields []string
+}
+
+// IsSkipped returns whether the resource has not been injected, and why is in the SkipReason
+func (r *InjectionResult) IsSkipped() bool {
+	return r.SkipReason != ""
+}
+
+func (m *mutatorImpl) InjectLumigoIntoWithResult(resource interface{}) (*InjectionResult, error) {
+	before, err := toFields(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	mutated, err := m.InjectLumigoInto(resource)
+	if IsSkipInjectionError(err) {
+		return &InjectionResult{
+			SkipReason:  GetSkipReason(err),
+			SkipMessage: err.Error(),
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := &InjectionResult{
+		Mutated: mutated,
+	}
+	if mutated {
+		after, err := toFields(resource)
+		if err != nil {
+			return nil, err
+		}
+		result.ChangedFields = getChangedFields("", before, after)
+		sort.Strings(result.ChangedFields)
+	}
+
+	return result, nil
+}
+
+// The fields of the resource as they are serialized, so that the typed and the unstructured resources are compared alike
+func toFields(resource interface{}) (map[string]interface{}, error) {
+	serialized, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serialize the resource: %w", err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(serialized, &fields); err != nil {
+		return nil, fmt.Errorf("cannot deserialize the resource: %w", err)
+	}
+
+	return fields, nil
+}
+
+func getChangedFields(prefix string, before map[string]interface{}, after map[string]interface{}) []string {
+	changedFields := []string{}
+
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		path := prefix + key
+		beforeValue, afterValue := before[key], after[key]
+
+		beforeMap, isBeforeMap := beforeValue.(map[string]interface{})
+		afterMap, isAfterMap := afterValue.(map[string]interface{})
+		if isBeforeMap && isAfterMap {
+			changedFields = append(changedFields, getChangedFields(path+".", beforeMap, afterMap)...)
+		} else if !reflect.DeepEqual(beforeValue, afterValue) {
+			changedFields = append(changedFields, path)
+		}
+	}
+
+	return changedFields
+}
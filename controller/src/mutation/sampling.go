@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	OtelTracesSamplerEnvVarName    = "OTEL_TRACES_SAMPLER"
+	OtelTracesSamplerArgEnvVarName = "OTEL_TRACES_SAMPLER_ARG"
+)
+
+// EffectiveSampling layers the fields set in `.spec.tracing.sampling` of the Lumigo instance over the
+// sampling of the injector defaults, so that a namespace can override, e.g., only the percentage of the
+// cluster-wide sampling; it returns nil if neither sets the sampling.
+func EffectiveSampling(spec *operatorv1alpha1.LumigoSpec, injectorDefaults *InjectorDefaults) *operatorv1alpha1.SamplingSpec {
+	sampling := &operatorv1alpha1.SamplingSpec{}
+	if injectorDefaults != nil && injectorDefaults.Sampling != nil {
+		sampling = injectorDefaults.Sampling.DeepCopy()
+	}
+
+	if spec != nil {
+		if spec.Tracing.Sampling.Percentage != nil {
+			percentage := *spec.Tracing.Sampling.Percentage
+			sampling.Percentage = &percentage
+		}
+		if spec.Tracing.Sampling.ParentBased != nil {
+			parentBased := *spec.Tracing.Sampling.ParentBased
+			sampling.ParentBased = &parentBased
+		}
+	}
+
+	if sampling.Percentage == nil && sampling.ParentBased == nil {
+		return nil
+	}
+
+	return sampling
+}
+
+// newSamplingEnv returns the `OTEL_TRACES_SAMPLER` and `OTEL_TRACES_SAMPLER_ARG` env vars of the sampling,
+// or none if the sampling is nil
+func newSamplingEnv(sampling *operatorv1alpha1.SamplingSpec) []corev1.EnvVar {
+	if sampling == nil {
+		return nil
+	}
+
+	percentage := int32(100)
+	if sampling.Percentage != nil {
+		percentage = *sampling.Percentage
+	}
+
+	sampler := "traceidratio"
+	if sampling.ParentBased == nil || *sampling.ParentBased {
+		sampler = "parentbased_traceidratio"
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name:  OtelTracesSamplerEnvVarName,
+			Value: sampler,
+		},
+		{
+			Name:  OtelTracesSamplerArgEnvVarName,
+			Value: strconv.FormatFloat(float64(percentage)/100, 'f', -1, 64),
+		},
+	}
+}
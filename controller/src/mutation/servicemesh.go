@@ -0,0 +1,76 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The init containers that service meshes add to the pods, which the `lumigo-injector` init container runs after
+var meshInitContainerNames = []string{
+	// Istio sets up the redirection of the traffic of the pod to the sidecar in `istio-init`, or checks it
+	// in `istio-validation` when the Istio CNI plugin sets it up; `istio-proxy` is the sidecar itself when
+	// it runs as a native sidecar
+	"istio-init",
+	"istio-validation",
+	"istio-proxy",
+	// Linkerd does the same in `linkerd-init`, or `linkerd-network-validator` with the Linkerd CNI plugin,
+	// and runs `linkerd-proxy` as a native sidecar if the `config.alpha.linkerd.io/proxy-enable-native-sidecar`
+	// annotation is set
+	"linkerd-init",
+	"linkerd-network-validator",
+	"linkerd-proxy",
+}
+
+// The sidecar containers of the service meshes, which are never instrumented, regardless of the image patterns:
+// preloading the Lumigo injector into the proxies would trace the traffic of the mesh and break their startup
+var meshProxyContainerNames = []string{
+	"istio-proxy",
+	"linkerd-proxy",
+}
+
+// orderLumigoInjectorAfterMeshInitContainers moves the `lumigo-injector` init container after the init containers
+// of the service meshes, so that it runs with the network of the pod set up like the containers it instruments,
+// regardless of the order in which the webhooks of the operator and of the meshes have mutated the pod
+func orderLumigoInjectorAfterMeshInitContainers(initContainers []corev1.Container) []corev1.Container {
+	lumigoInjectorIndex := slices.IndexFunc(initContainers, func(c corev1.Container) bool { return c.Name == LumigoInjectorContainerName })
+	if lumigoInjectorIndex < 0 {
+		return initContainers
+	}
+
+	lastMeshInitContainerIndex := -1
+	for i, initContainer := range initContainers {
+		if slices.Contains(meshInitContainerNames, initContainer.Name) {
+			lastMeshInitContainerIndex = i
+		}
+	}
+	if lastMeshInitContainerIndex < lumigoInjectorIndex {
+		return initContainers
+	}
+
+	lumigoInjectorContainer := initContainers[lumigoInjectorIndex]
+	initContainers = slices.Delete(initContainers, lumigoInjectorIndex, lumigoInjectorIndex+1)
+	// The mesh init containers after the `lumigo-injector` one have moved up by one
+	return slices.Insert(initContainers, lastMeshInitContainerIndex, lumigoInjectorContainer)
+}
+
+// isInstrumented returns whether the container is instrumented: its image matches the image patterns,
+// and it is not the proxy of a service mesh
+func (m *mutatorImpl) isInstrumented(container *corev1.Container) bool {
+	return !slices.Contains(meshProxyContainerNames, container.Name) && m.imagePatterns.IsInstrumented(container.Image)
+}
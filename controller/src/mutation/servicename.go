@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const OtelServiceNameEnvVarName = "OTEL_SERVICE_NAME"
+
+// LumigoInjectedServiceNameAnnotationKey holds, on the pod template, the comma-separated names of
+// the containers whose `OTEL_SERVICE_NAME` env var has been set from the service name template of
+// the Lumigo resource, so that it is removed with the rest of the injection
+const LumigoInjectedServiceNameAnnotationKey = "lumigo.io/injected-service-name"
+const injectedServiceNameSeparator = ","
+
+// ServiceNameTemplateData is what the `spec.tracing.injection.serviceNameTemplate` of Lumigo
+// resources is executed with, once for each injected container
+type ServiceNameTemplateData struct {
+	// The namespace of the workload
+	Namespace string
+	// The kind of the workload, e.g., `Deployment`
+	WorkloadKind string
+	// The name of the workload
+	WorkloadName string
+	// The name of the container
+	ContainerName string
+	// The labels of the pod template of the workload
+	Labels map[string]string
+}
+
+// ParseServiceNameTemplate parses a service name template as a Go text/template; referencing
+// missing labels yields empty strings
+func ParseServiceNameTemplate(serviceNameTemplate string) (*template.Template, error) {
+	return template.New("serviceName").Option("missingkey=zero").Parse(serviceNameTemplate)
+}
+
+// injectServiceName sets the `OTEL_SERVICE_NAME` env var of the containers from the service name
+// template. Containers that set `OTEL_SERVICE_NAME` themselves, or get it from the extra env vars,
+// are left untouched; the service names set by an earlier injection are updated or removed.
+func (m *mutatorImpl) injectServiceName(workloadKind string, topLevelObjectMeta *metav1.ObjectMeta, podTemplateSpec *corev1.PodTemplateSpec) error {
+	injectedContainerNames := getInjectedServiceNameContainerNames(&podTemplateSpec.ObjectMeta)
+	isSetByExtraEnv := slices.IndexFunc(m.lumigoExtraEnv, func(e corev1.EnvVar) bool { return e.Name == OtelServiceNameEnvVarName }) > -1
+
+	serviceNameContainerNames := []string{}
+	for i := range podTemplateSpec.Spec.Containers {
+		container := &podTemplateSpec.Spec.Containers[i]
+
+		if isSetByExtraEnv {
+			continue
+		}
+
+		serviceNameEnvVarIndex := slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == OtelServiceNameEnvVarName })
+		isInjected := slices.Contains(injectedContainerNames, container.Name)
+		if serviceNameEnvVarIndex > -1 && !isInjected {
+			// The workload sets its own service name
+			continue
+		}
+
+		serviceName := ""
+		if m.serviceNameTemplate != nil && m.isInstrumented(container) {
+			var sb strings.Builder
+			if err := m.serviceNameTemplate.Execute(&sb, &ServiceNameTemplateData{
+				Namespace:     topLevelObjectMeta.Namespace,
+				WorkloadKind:  workloadKind,
+				WorkloadName:  topLevelObjectMeta.Name,
+				ContainerName: container.Name,
+				Labels:        podTemplateSpec.Labels,
+			}); err != nil {
+				return fmt.Errorf("cannot render the service name of the '%s' container: %w", container.Name, err)
+			}
+			serviceName = strings.TrimSpace(sb.String())
+		}
+
+		if len(serviceName) < 1 {
+			if serviceNameEnvVarIndex > -1 {
+				container.Env = slices.Delete(container.Env, serviceNameEnvVarIndex, serviceNameEnvVarIndex+1)
+			}
+			continue
+		}
+
+		serviceNameEnvVar := corev1.EnvVar{
+			Name:  OtelServiceNameEnvVarName,
+			Value: serviceName,
+		}
+		if serviceNameEnvVarIndex < 0 {
+			container.Env = append(container.Env, serviceNameEnvVar)
+		} else {
+			container.Env[serviceNameEnvVarIndex] = serviceNameEnvVar
+		}
+		serviceNameContainerNames = append(serviceNameContainerNames, container.Name)
+	}
+
+	setInjectedServiceNameContainerNames(&podTemplateSpec.ObjectMeta, serviceNameContainerNames)
+
+	return nil
+}
+
+// removeServiceName removes the `OTEL_SERVICE_NAME` env vars that were set from the service name
+// template of the Lumigo resource
+func removeServiceName(podTemplateSpec *corev1.PodTemplateSpec) {
+	injectedContainerNames := getInjectedServiceNameContainerNames(&podTemplateSpec.ObjectMeta)
+
+	for i := range podTemplateSpec.Spec.Containers {
+		container := &podTemplateSpec.Spec.Containers[i]
+		if slices.Contains(injectedContainerNames, container.Name) {
+			container.Env = removeEnvVars(container.Env, []string{OtelServiceNameEnvVarName})
+		}
+	}
+
+	setInjectedServiceNameContainerNames(&podTemplateSpec.ObjectMeta, nil)
+}
+
+func getInjectedServiceNameContainerNames(objectMeta *metav1.ObjectMeta) []string {
+	value := objectMeta.Annotations[LumigoInjectedServiceNameAnnotationKey]
+	if len(value) < 1 {
+		return []string{}
+	}
+
+	return strings.Split(value, injectedServiceNameSeparator)
+}
+
+func setInjectedServiceNameContainerNames(objectMeta *metav1.ObjectMeta, containerNames []string) {
+	if len(containerNames) < 1 {
+		if objectMeta.Annotations != nil {
+			delete(objectMeta.Annotations, LumigoInjectedServiceNameAnnotationKey)
+		}
+		return
+	}
+
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[LumigoInjectedServiceNameAnnotationKey] = strings.Join(containerNames, injectedServiceNameSeparator)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// LumigoFilterHttpClientEndpointsEnvVarName holds the JSON array of the regexes of the URLs whose HTTP calls
+// the Lumigo distros do not trace
+const LumigoFilterHttpClientEndpointsEnvVarName = "LUMIGO_FILTER_HTTP_ENDPOINTS_REGEX_CLIENT"
+
+// SkipDomainsHostsRegex returns the unanchored regex, e.g., `(vault[.]example[.]com|[-a-z0-9.]+[.]okta[.]com)`,
+// matching the hosts of the domains of `.spec.tracing.skipDomains`, or an empty string if there are none.
+// The regexes have no backslashes, so that they are embedded verbatim in the JSON of the env vars of the Lumigo
+// distros and in the OTTL conditions of the telemetry-proxy.
+func SkipDomainsHostsRegex(domains []operatorv1alpha1.Domain) string {
+	if len(domains) < 1 {
+		return ""
+	}
+
+	hostRegexes := make([]string, len(domains))
+	for i, domain := range domains {
+		hostRegex := strings.ReplaceAll(string(domain), ".", "[.]")
+		// The leading wildcard, validated by the CRD, matches one or more labels
+		hostRegex = strings.Replace(hostRegex, "*", "[-a-z0-9.]+", 1)
+		hostRegexes[i] = hostRegex
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(hostRegexes, "|"))
+}
+
+// SkipDomainsUrlRegex returns the anchored regex matching the URLs of the hosts matched by the hosts regex
+func SkipDomainsUrlRegex(hostsRegex string) string {
+	return fmt.Sprintf("^[a-z]+://%s(:[0-9]+)?([/?#].*)?$", hostsRegex)
+}
+
+func newSkipDomainsEnvVar(domains []operatorv1alpha1.Domain) corev1.EnvVar {
+	// Marshaling a slice of strings cannot fail
+	regexes, _ := json.Marshal([]string{SkipDomainsUrlRegex(SkipDomainsHostsRegex(domains))})
+
+	return corev1.EnvVar{
+		Name:  LumigoFilterHttpClientEndpointsEnvVarName,
+		Value: string(regexes),
+	}
+}
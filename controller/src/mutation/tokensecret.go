@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// LumigoTokenSecretAnnotationKey is the annotation of a workload, e.g., `lumigo.io/token-secret: partner-credentials/token`,
+// with the `<name>/<key>` of the secret, in the namespace of the workload, with the Lumigo token that the workload
+// reports with instead of the one of the namespace; it takes precedence over the routes of `.spec.tracing.routes`
+const LumigoTokenSecretAnnotationKey = "lumigo.io/token-secret"
+
+const tokenSecretAnnotationSeparator = "/"
+
+// ParseTokenSecretAnnotation parses the `<name>/<key>` value of the LumigoTokenSecretAnnotationKey annotation
+func ParseTokenSecretAnnotation(value string) (*operatorv1alpha1.KubernetesSecretRef, error) {
+	name, key, found := strings.Cut(value, tokenSecretAnnotationSeparator)
+	if !found {
+		return nil, fmt.Errorf("the '%s' annotation must have the '<name>/<key>' format of a secret key, found '%s'", LumigoTokenSecretAnnotationKey, value)
+	}
+
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("the '%s' annotation has an invalid secret name '%s': %s", LumigoTokenSecretAnnotationKey, name, strings.Join(errs, "; "))
+	}
+
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return nil, fmt.Errorf("the '%s' annotation has an invalid secret key '%s': %s", LumigoTokenSecretAnnotationKey, key, strings.Join(errs, "; "))
+	}
+
+	return &operatorv1alpha1.KubernetesSecretRef{
+		Name: name,
+		Key:  key,
+	}, nil
+}
+
+// The route of the workload to the Lumigo token of the secret of its LumigoTokenSecretAnnotationKey annotation,
+// if any; workloads with an invalid annotation are not injected, rather than reporting to the project of the namespace
+func getTokenSecretRouteOf(topLevelObjectMeta *metav1.ObjectMeta) (*tracingRoute, error) {
+	value, isSet := topLevelObjectMeta.Annotations[LumigoTokenSecretAnnotationKey]
+	if !isSet {
+		return nil, nil
+	}
+
+	secretRef, err := ParseTokenSecretAnnotation(value)
+	if err != nil {
+		return nil, &SkipInjectionError{
+			Code:   SkipReasonInvalidTokenSecret,
+			Reason: err.Error(),
+		}
+	}
+
+	return &tracingRoute{
+		lumigoToken: &operatorv1alpha1.Credentials{
+			SecretRef: *secretRef,
+		},
+		isWorkloadTokenSecret: true,
+	}, nil
+}
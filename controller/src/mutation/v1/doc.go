@@ -0,0 +1,13 @@
+// Package v1 is the stable Go API of the injection of Lumigo into Kubernetes workloads, for tools like admission
+// controllers and CI checks that inject workloads, or check that they are injected, the way the operator does,
+// without a live cluster: the workloads are mutated in memory, and nothing is read from or written to the API server.
+//
+// The package follows semantic versioning: within v1, only backward-compatible changes are made, i.e., new
+// functions, new fields of Options, and new supported workload types; changes that would break the callers go
+// into a new v2 package. The mutation package it is built on is internal to the operator, and may change at any
+// release.
+//
+// Import it with an alias, as its name clashes with the ones of the Kubernetes API packages:
+//
+//	import lumigomutationv1 "github.com/lumigo-io/lumigo-kubernetes-operator/mutation/v1"
+package v1
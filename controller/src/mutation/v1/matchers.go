@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"github.com/onsi/gomega/types"
+
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// BeInstrumentedWithLumigo returns a Gomega matcher that succeeds on the workloads supported by Mutator whose
+// pods are injected with the given operator version, injector image and traces endpoint, and, if logsEnabled,
+// send their logs to Lumigo
+func BeInstrumentedWithLumigo(operatorVersion string, injectorImage string, tracesEndpoint string, logsEnabled bool) types.GomegaMatcher {
+	return mutation.BeInstrumentedWithLumigo(operatorVersion, injectorImage, tracesEndpoint, logsEnabled)
+}
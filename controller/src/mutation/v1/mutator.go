@@ -0,0 +1,174 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+const (
+	// AutoTraceLabelKey is the label of the injected workloads; set to `false`, it opts a workload out of the injection
+	AutoTraceLabelKey = mutation.LumigoAutoTraceLabelKey
+	// InjectorContainerName is the name of the init container that copies the Lumigo distros into the injected pods
+	InjectorContainerName = mutation.LumigoInjectorContainerName
+	// TokenSecretAnnotationKey is the annotation of a workload with the `<name>/<key>` of the secret with the Lumigo
+	// token it reports with, instead of the one of the spec
+	TokenSecretAnnotationKey = mutation.LumigoTokenSecretAnnotationKey
+	// TracerTokenSecretName is the default name of the secret with the Lumigo token of the injected containers
+	TracerTokenSecretName = mutation.LumigoTracerTokenSecretName
+)
+
+// Options are the settings the workloads are injected with
+type Options struct {
+	// The spec of the Lumigo resource of the namespace of the workloads, with the Lumigo token, which must
+	// reference a secret, and the settings of the injection; optional: if nil, the workloads are injected
+	// with the default settings, and without a Lumigo token
+	Spec *operatorv1alpha1.LumigoSpec
+	// The version of the operator, e.g., `1.2.3`, which is recorded in the labels of the injected workloads
+	OperatorVersion string
+	// The image of the `lumigo-injector` init container, e.g., `public.ecr.aws/lumigo/lumigo-autotrace:latest`
+	InjectorImage string
+	// The OTLP/HTTP endpoint to which the injected containers send traces, e.g., the one of the telemetry-proxy
+	TracesEndpoint string
+	// The OTLP/HTTP endpoint to which the injected containers send logs, if enabled in the spec
+	LogsEndpoint string
+	// The resources of the `lumigo-injector` init container; optional: if nil, none are set
+	InjectorResources *corev1.ResourceRequirements
+	// The JSON config of the cluster-wide defaults of the settings of the Lumigo distros, in the format of the
+	// `--injector-defaults` flag of the operator; optional: if empty, the defaults of the distros apply
+	InjectorDefaults string
+	// The namespaces whose workloads are never injected, e.g., the one of the operator; the workloads of the operator
+	// itself, like its controller-manager and telemetry-proxy, are never injected, wherever they are
+	ProtectedNamespaces []string
+	// The logger of the mutations; optional: if unset, nothing is logged
+	Log logr.Logger
+}
+
+// Mutator injects Lumigo into workloads and removes it from them, in memory. The supported workloads are the
+// *appsv1.DaemonSet, *appsv1.Deployment, *appsv1.ReplicaSet (those owned by a Deployment are left alone, as
+// the Deployment is injected instead), *appsv1.StatefulSet, *batchv1.CronJob and *batchv1.Job, and the Keda
+// ScaledJobs as *unstructured.Unstructured.
+type Mutator interface {
+	// Inject adds Lumigo to the given workload, and returns whether it has been changed. Workloads that must
+	// not be injected, e.g., because they opted out with the AutoTraceLabelKey label, are left unchanged,
+	// with an error for which IsSkipInjectionError returns true.
+	Inject(workload runtime.Object) (bool, error)
+	// Remove removes Lumigo from the given workload, and returns whether it has been changed
+	Remove(workload runtime.Object) (bool, error)
+}
+
+type mutator struct {
+	delegate mutation.Mutator
+}
+
+// NewMutator returns a Mutator that injects the workloads with the given options
+func NewMutator(options Options) (Mutator, error) {
+	var injectorDefaults *mutation.InjectorDefaults
+	if len(options.InjectorDefaults) > 0 {
+		var err error
+		if injectorDefaults, err = mutation.ParseInjectorDefaults(options.InjectorDefaults); err != nil {
+			return nil, fmt.Errorf("invalid injector defaults: %w", err)
+		}
+	}
+
+	log := options.Log
+	if log.GetSink() == nil {
+		log = logr.Discard()
+	}
+
+	protectedWorkloads, err := mutation.NewProtectedWorkloads(options.ProtectedNamespaces, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	delegate, err := mutation.NewMutator(&log, options.Spec, options.OperatorVersion, options.InjectorImage, options.TracesEndpoint, options.LogsEndpoint, options.InjectorResources, injectorDefaults, protectedWorkloads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mutator{delegate: delegate}, nil
+}
+
+func (m *mutator) Inject(workload runtime.Object) (bool, error) {
+	return m.delegate.InjectLumigoInto(workload)
+}
+
+func (m *mutator) Remove(workload runtime.Object) (bool, error) {
+	return m.delegate.RemoveLumigoFrom(workload)
+}
+
+// SkipReason is why a workload is not injected, among a fixed set of values; new values may be added within v1
+type SkipReason string
+
+const (
+	// The workload has the AutoTraceLabelKey label set to `false`
+	SkipReasonOptedOut = SkipReason(mutation.SkipReasonOptedOut)
+	// The kind of the workload is not among the workload types to inject of the spec
+	SkipReasonWorkloadTypeNotInjected = SkipReason(mutation.SkipReasonWorkloadTypeNotInjected)
+	// The workload was created before the cutoff of the spec
+	SkipReasonCreatedBeforeCutoff = SkipReason(mutation.SkipReasonCreatedBeforeCutoff)
+	// None of the images of the containers match the image patterns of the spec
+	SkipReasonImageNotMatched = SkipReason(mutation.SkipReasonImageNotMatched)
+	// The pods run on CPU architectures that the Lumigo injector does not support
+	SkipReasonUnsupportedArchitecture = SkipReason(mutation.SkipReasonUnsupportedArchitecture)
+	// The pods run on operating systems that the Lumigo injector does not support
+	SkipReasonUnsupportedOperatingSystem = SkipReason(mutation.SkipReasonUnsupportedOperatingSystem)
+	// The TokenSecretAnnotationKey annotation of the workload does not reference a secret key
+	SkipReasonInvalidTokenSecret = SkipReason(mutation.SkipReasonInvalidTokenSecret)
+	// The workload belongs to the Lumigo operator, or is otherwise protected from the injection
+	SkipReasonProtectedWorkload = SkipReason(mutation.SkipReasonProtectedWorkload)
+	// The workload is skipped for a reason that is not among the others
+	SkipReasonUnspecified = SkipReason(mutation.SkipReasonUnspecified)
+)
+
+// Result is the outcome of the injection of a workload
+type Result struct {
+	// Whether the workload has been changed
+	Mutated bool
+	// Why the workload has not been injected; empty unless it has been skipped
+	SkipReason SkipReason
+	// The explanation of the SkipReason, for humans
+	SkipMessage string
+	// The sorted paths of the fields of the workload that the injection changed, e.g.,
+	// `spec.template.spec.initContainers`; lists are reported as a whole
+	ChangedFields []string
+}
+
+// InjectWithResult adds Lumigo to the given workload like Mutator.Inject, but returns what has been changed and,
+// rather than an error, why the workload has been skipped, if it has. Only the Mutators returned by NewMutator
+// are supported.
+func InjectWithResult(m Mutator, workload runtime.Object) (*Result, error) {
+	impl, ok := m.(*mutator)
+	if !ok {
+		return nil, fmt.Errorf("unsupported mutator %T: only the ones returned by NewMutator are supported", m)
+	}
+
+	result, err := impl.delegate.InjectLumigoIntoWithResult(workload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Mutated:       result.Mutated,
+		SkipReason:    SkipReason(result.SkipReason),
+		SkipMessage:   result.SkipMessage,
+		ChangedFields: result.ChangedFields,
+	}, nil
+}
+
+// GetSkipReason returns why the workload has been skipped if the error returned by Mutator.Inject is one for
+// which IsSkipInjectionError returns true, and an empty SkipReason otherwise
+func GetSkipReason(err error) SkipReason {
+	return SkipReason(mutation.GetSkipReason(err))
+}
+
+// IsSkipInjectionError returns whether the error returned by Mutator.Inject means that the workload must not be
+// injected, e.g., because it opted out, rather than that its injection failed
+func IsSkipInjectionError(err error) bool {
+	return mutation.IsSkipInjectionError(err)
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+const (
+	operatorVersion = "1.2.3"
+	injectorImage   = "public.ecr.aws/lumigo/lumigo-autotrace:latest"
+	tracesEndpoint  = "http://lumigo-telemetry-proxy.lumigo-system.svc.cluster.local/v1/traces"
+)
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func newTrue() *bool {
+	t := true
+	return &t
+}
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Mutation API Suite")
+}
+
+var _ = Context("Mutator", func() {
+
+	var spec *operatorv1alpha1.LumigoSpec
+
+	newDeployment := func(labels map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "my-namespace",
+				Name:      "my-app",
+				Labels:    labels,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "my-app",
+								Image: "my-app:1.0",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	newMutator := func(options Options) Mutator {
+		mutator, err := NewMutator(options)
+		Expect(err).NotTo(HaveOccurred())
+		return mutator
+	}
+
+	BeforeEach(func() {
+		spec = &operatorv1alpha1.LumigoSpec{
+			LumigoToken: operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigo-credentials",
+					Key:  "token",
+				},
+			},
+		}
+	})
+
+	It("injects and removes Lumigo without a cluster", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment).To(BeInstrumentedWithLumigo(operatorVersion, injectorImage, tracesEndpoint, false))
+		Expect(deployment.Spec.Template.Spec.InitContainers).To(ContainElement(HaveField("Name", InjectorContainerName)))
+
+		Expect(mutator.Remove(deployment)).To(BeTrue())
+		Expect(deployment).NotTo(BeInstrumentedWithLumigo(operatorVersion, injectorImage, tracesEndpoint, false))
+		Expect(deployment.Labels).NotTo(HaveKey(AutoTraceLabelKey))
+	})
+
+	It("skips the workloads that opted out", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(map[string]string{AutoTraceLabelKey: "false"})
+
+		isChanged, err := mutator.Inject(deployment)
+		Expect(isChanged).To(BeFalse())
+		Expect(IsSkipInjectionError(err)).To(BeTrue())
+		Expect(GetSkipReason(err)).To(Equal(SkipReasonOptedOut))
+
+		result, err := InjectWithResult(mutator, deployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Mutated).To(BeFalse())
+		Expect(result.SkipReason).To(Equal(SkipReasonOptedOut))
+		Expect(result.SkipMessage).To(ContainSubstring(AutoTraceLabelKey))
+	})
+
+	It("returns the fields changed by the injection", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+
+		result, err := InjectWithResult(mutator, deployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Mutated).To(BeTrue())
+		Expect(result.SkipReason).To(BeEmpty())
+		Expect(result.ChangedFields).To(ContainElements(
+			"metadata.labels",
+			"spec.template.spec.initContainers",
+			"spec.template.spec.containers",
+			"spec.template.spec.volumes",
+		))
+		Expect(result.ChangedFields).To(Equal(sortedCopy(result.ChangedFields)))
+
+		result, err = InjectWithResult(mutator, deployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Mutated).To(BeFalse())
+		Expect(result.ChangedFields).To(BeEmpty())
+	})
+
+	It("skips the workload types that are not injected", func() {
+		spec.Tracing.Injection.WorkloadTypes = []operatorv1alpha1.WorkloadType{"StatefulSet"}
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+
+		result, err := InjectWithResult(mutator, newDeployment(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.SkipReason).To(Equal(SkipReasonWorkloadTypeNotInjected))
+	})
+
+	It("injects the injector defaults", func() {
+		mutator := newMutator(Options{
+			Spec:             spec,
+			OperatorVersion:  operatorVersion,
+			InjectorImage:    injectorImage,
+			TracesEndpoint:   tracesEndpoint,
+			InjectorDefaults: `{"env":[{"name":"LUMIGO_SWITCH_OFF","value":"true"}]}`,
+		})
+		deployment := newDeployment(nil)
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "LUMIGO_SWITCH_OFF", Value: "true"}))
+	})
+
+	It("injects the Lumigo token of the secret of the token-secret annotation", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+		deployment.Annotations = map[string]string{TokenSecretAnnotationKey: "partner-credentials/lumigo-token"}
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(And(
+			HaveField("Name", "LUMIGO_TRACER_TOKEN"),
+			HaveField("ValueFrom.SecretKeyRef.Name", "partner-credentials"),
+			HaveField("ValueFrom.SecretKeyRef.Key", "lumigo-token"),
+		)))
+	})
+
+	It("projects the Lumigo token of the secret of the token-secret annotation", func() {
+		spec.Tracing.Injection.TokenInjectionMode = operatorv1alpha1.TokenInjectionModeProjectedSecret
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+		deployment.Annotations = map[string]string{TokenSecretAnnotationKey: "partner-credentials/lumigo-token"}
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Volumes).To(ContainElement(And(
+			HaveField("Projected.Sources", ContainElement(HaveField("Secret.LocalObjectReference.Name", "partner-credentials"))),
+			HaveField("Projected.Sources", ContainElement(HaveField("Secret.Items", ConsistOf(corev1.KeyToPath{Key: "lumigo-token", Path: "token"})))),
+		)))
+	})
+
+	It("skips the workloads with an invalid token-secret annotation", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+		deployment.Annotations = map[string]string{TokenSecretAnnotationKey: "partner-credentials"}
+
+		result, err := InjectWithResult(mutator, deployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Mutated).To(BeFalse())
+		Expect(result.SkipReason).To(Equal(SkipReasonInvalidTokenSecret))
+		Expect(result.SkipMessage).To(ContainSubstring(TokenSecretAnnotationKey))
+	})
+
+	It("skips the workloads of the operator and of the protected namespaces", func() {
+		mutator := newMutator(Options{
+			Spec:                spec,
+			OperatorVersion:     operatorVersion,
+			InjectorImage:       injectorImage,
+			TracesEndpoint:      tracesEndpoint,
+			ProtectedNamespaces: []string{"lumigo-system"},
+		})
+
+		controllerManager := newDeployment(map[string]string{
+			"app.kubernetes.io/part-of": "lumigo",
+			"control-plane":             "controller-manager",
+		})
+		result, err := InjectWithResult(mutator, controllerManager)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Mutated).To(BeFalse())
+		Expect(result.SkipReason).To(Equal(SkipReasonProtectedWorkload))
+
+		telemetryProxy := newDeployment(nil)
+		telemetryProxy.Spec.Template.Labels = map[string]string{"app.kubernetes.io/managed-by": "lumigo-operator"}
+		result, err = InjectWithResult(mutator, telemetryProxy)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.SkipReason).To(Equal(SkipReasonProtectedWorkload))
+
+		operatorNamespaceDeployment := newDeployment(nil)
+		operatorNamespaceDeployment.Namespace = "lumigo-system"
+		result, err = InjectWithResult(mutator, operatorNamespaceDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.SkipReason).To(Equal(SkipReasonProtectedWorkload))
+		Expect(result.SkipMessage).To(ContainSubstring("lumigo-system"))
+
+		result, err = InjectWithResult(mutator, newDeployment(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Mutated).To(BeTrue())
+	})
+
+	It("sets and removes the Istio annotations of the spec", func() {
+		spec.Tracing.Injection.Istio = operatorv1alpha1.IstioSpec{
+			ExcludeTelemetryProxyPorts:      newTrue(),
+			HoldApplicationUntilProxyStarts: newTrue(),
+		}
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  "http://lumigo-telemetry-proxy.lumigo-system.svc.cluster.local:4318/v1/traces",
+		})
+		deployment := newDeployment(nil)
+		deployment.Spec.Template.Annotations = map[string]string{"traffic.sidecar.istio.io/excludeOutboundPorts": "5432"}
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue("traffic.sidecar.istio.io/excludeOutboundPorts", "5432,4318"))
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue("proxy.istio.io/config", "holdApplicationUntilProxyStarts: true"))
+
+		Expect(mutator.Inject(deployment)).To(BeFalse())
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue("traffic.sidecar.istio.io/excludeOutboundPorts", "5432,4318"))
+
+		Expect(mutator.Remove(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue("traffic.sidecar.istio.io/excludeOutboundPorts", "5432"))
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey("proxy.istio.io/config"))
+	})
+
+	It("leaves the Istio annotations of the workloads out of the mesh alone", func() {
+		spec.Tracing.Injection.Istio = operatorv1alpha1.IstioSpec{
+			ExcludeTelemetryProxyPorts:      newTrue(),
+			HoldApplicationUntilProxyStarts: newTrue(),
+		}
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  "http://lumigo-telemetry-proxy.lumigo-system.svc.cluster.local:4318/v1/traces",
+		})
+		deployment := newDeployment(nil)
+		deployment.Spec.Template.Labels = map[string]string{"sidecar.istio.io/inject": "false"}
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey("traffic.sidecar.istio.io/excludeOutboundPorts"))
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey("proxy.istio.io/config"))
+	})
+
+	It("runs the injector init container after the Istio ones", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		// As after `istioctl kube-inject` of the manifest of the injected workload
+		deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers,
+			corev1.Container{Name: "istio-init", Image: "istio/proxyv2"},
+			corev1.Container{Name: "istio-proxy", Image: "istio/proxyv2"},
+		)
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.InitContainers).To(HaveLen(3))
+		Expect(deployment.Spec.Template.Spec.InitContainers[0].Name).To(Equal("istio-init"))
+		Expect(deployment.Spec.Template.Spec.InitContainers[1].Name).To(Equal("istio-proxy"))
+		Expect(deployment.Spec.Template.Spec.InitContainers[2].Name).To(Equal(InjectorContainerName))
+	})
+
+	It("leaves the Linkerd proxy uninstrumented and runs the injector init container after the Linkerd ones", func() {
+		mutator := newMutator(Options{
+			Spec:            spec,
+			OperatorVersion: operatorVersion,
+			InjectorImage:   injectorImage,
+			TracesEndpoint:  tracesEndpoint,
+		})
+		deployment := newDeployment(nil)
+		// As injected with `linkerd inject --manual`
+		deployment.Spec.Template.Spec.InitContainers = []corev1.Container{
+			{Name: "linkerd-init", Image: "cr.l5d.io/linkerd/proxy-init"},
+		}
+		deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers,
+			corev1.Container{Name: "linkerd-proxy", Image: "cr.l5d.io/linkerd/proxy"},
+		)
+
+		Expect(mutator.Inject(deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.InitContainers[0].Name).To(Equal("linkerd-init"))
+		Expect(deployment.Spec.Template.Spec.InitContainers[1].Name).To(Equal(InjectorContainerName))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(HaveField("Name", "LD_PRELOAD")))
+		Expect(deployment.Spec.Template.Spec.Containers[1].Env).NotTo(ContainElement(HaveField("Name", "LD_PRELOAD")))
+		Expect(deployment.Spec.Template.Spec.Containers[1].VolumeMounts).To(BeEmpty())
+	})
+
+	It("rejects invalid injector defaults", func() {
+		_, err := NewMutator(Options{InjectorDefaults: "{"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unsupported workloads", func() {
+		_, err := newMutator(Options{Spec: spec}).Inject(&corev1.Pod{})
+		Expect(err).To(HaveOccurred())
+		Expect(IsSkipInjectionError(err)).To(BeFalse())
+	})
+})
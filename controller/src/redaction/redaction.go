@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redaction scrubs the Lumigo tokens from everything the operator writes for humans to read: its logs,
+// the Kubernetes events it records and the conditions of the Lumigo instances.
+package redaction
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// RedactedToken replaces the Lumigo tokens
+const RedactedToken = "t_***"
+
+// The Lumigo tokens anywhere in a text, e.g., in the message of an error
+var tokenRegexp = regexp.MustCompile(`t_[[:xdigit:]]{21}`)
+
+// String returns the text with the Lumigo tokens replaced with RedactedToken
+func String(text string) string {
+	return tokenRegexp.ReplaceAllLiteralString(text, RedactedToken)
+}
+
+// Error returns the error with the Lumigo tokens of its message replaced with RedactedToken; nil if the error is nil
+func Error(err error) error {
+	if err == nil || !tokenRegexp.MatchString(err.Error()) {
+		return err
+	}
+
+	return &redactedError{err: err}
+}
+
+// Value returns the value, as logged, with the Lumigo tokens replaced with RedactedToken: the strings and the errors
+// are redacted as such, and the other values, e.g., the structs of configurations, through their JSON encoding
+func Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return String(v)
+	case error:
+		return Error(v)
+	case fmt.Stringer:
+		if s := v.String(); tokenRegexp.MatchString(s) {
+			return String(s)
+		}
+		return v
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil || !tokenRegexp.Match(encoded) {
+		return value
+	}
+
+	// The tokens are within JSON strings, so their replacement keeps the encoding valid
+	return json.RawMessage(tokenRegexp.ReplaceAllLiteral(encoded, []byte(RedactedToken)))
+}
+
+// redactedError is an error whose message is redacted, and which still wraps the original error for errors.Is
+// and errors.As
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return String(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// NewLogger wraps the given logger so that the Lumigo tokens in the messages, names and values of its log lines
+// are replaced with RedactedToken.
+func NewLogger(logger logr.Logger) logr.Logger {
+	delegate := logger.GetSink()
+	// The redacting sink adds a frame to the call stack between the log call and the delegate
+	if callDepthSink, ok := delegate.(logr.CallDepthLogSink); ok {
+		delegate = callDepthSink.WithCallDepth(1)
+	}
+
+	return logr.New(&redactingSink{
+		delegate: delegate,
+	})
+}
+
+// redactingSink redacts the log lines before handing them to its delegate
+type redactingSink struct {
+	delegate logr.LogSink
+}
+
+// The delegate has already been initialized by the logger it comes from
+func (s *redactingSink) Init(info logr.RuntimeInfo) {}
+
+func (s *redactingSink) WithCallDepth(depth int) logr.LogSink {
+	if delegate, ok := s.delegate.(logr.CallDepthLogSink); ok {
+		return &redactingSink{
+			delegate: delegate.WithCallDepth(depth),
+		}
+	}
+
+	return s
+}
+
+func (s *redactingSink) Enabled(level int) bool {
+	return s.delegate.Enabled(level)
+}
+
+func (s *redactingSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.delegate.Info(level, String(msg), redactKeysAndValues(keysAndValues)...)
+}
+
+func (s *redactingSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.delegate.Error(Error(err), String(msg), redactKeysAndValues(keysAndValues)...)
+}
+
+func (s *redactingSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &redactingSink{
+		delegate: s.delegate.WithValues(redactKeysAndValues(keysAndValues)...),
+	}
+}
+
+func (s *redactingSink) WithName(name string) logr.LogSink {
+	return &redactingSink{
+		delegate: s.delegate.WithName(String(name)),
+	}
+}
+
+func redactKeysAndValues(keysAndValues []interface{}) []interface{} {
+	if len(keysAndValues) < 1 {
+		return keysAndValues
+	}
+
+	redacted := make([]interface{}, len(keysAndValues))
+	for i, keyOrValue := range keysAndValues {
+		redacted[i] = Value(keyOrValue)
+	}
+
+	return redacted
+}
+
+// NewEventRecorder wraps the given event recorder so that the Lumigo tokens in the messages of the events are
+// replaced with RedactedToken.
+func NewEventRecorder(eventRecorder record.EventRecorder) record.EventRecorder {
+	return &redactingEventRecorder{
+		delegate: eventRecorder,
+	}
+}
+
+type redactingEventRecorder struct {
+	delegate record.EventRecorder
+}
+
+func (r *redactingEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.delegate.Event(object, eventtype, reason, String(message))
+}
+
+func (r *redactingEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.delegate.Event(object, eventtype, reason, String(fmt.Sprintf(messageFmt, args...)))
+}
+
+func (r *redactingEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.delegate.AnnotatedEventf(object, annotations, eventtype, reason, "%s", String(fmt.Sprintf(messageFmt, args...)))
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const token = "t_0123456789abcdef01234"
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Redaction Suite")
+}
+
+var _ = Context("Redaction", func() {
+
+	It("replaces the Lumigo tokens anywhere in a text", func() {
+		Expect(String(fmt.Sprintf("invalid token '%s'", token))).To(Equal("invalid token 't_***'"))
+		Expect(String(token + "," + token)).To(Equal("t_***,t_***"))
+		Expect(String("the Lumigo token is not valid")).To(Equal("the Lumigo token is not valid"))
+	})
+
+	It("keeps the errors it redacts in the chain", func() {
+		cause := fmt.Errorf("cannot use token '%s'", token)
+		err := Error(fmt.Errorf("invalid configuration: %w", cause))
+
+		Expect(err.Error()).To(Equal("invalid configuration: cannot use token 't_***'"))
+		Expect(errors.Is(err, cause)).To(BeTrue())
+
+		plainErr := fmt.Errorf("no token")
+		Expect(Error(plainErr)).To(BeIdenticalTo(plainErr))
+		Expect(Error(nil)).To(BeNil())
+	})
+
+	It("never logs the Lumigo tokens", func() {
+		output := &bytes.Buffer{}
+		log := NewLogger(zap.New(zap.WriteTo(output), zap.UseDevMode(true)))
+
+		type config struct {
+			Name  string `json:"name"`
+			Token string `json:"token"`
+		}
+
+		log.WithName("namespace-"+token).WithValues("token", token).Info("Token "+token, "config", &config{Name: "my-namespace", Token: token}, "configs", []config{{Token: token}})
+		log.Error(fmt.Errorf("invalid token '%s'", token), "Cannot use the token", "secret", &corev1.Secret{StringData: map[string]string{"token": token}})
+		log.V(0).Info("Not a token", "count", 1, "enabled", true, "error", fmt.Errorf("wrapped: %w", fmt.Errorf("%s", token)))
+
+		Expect(output.String()).NotTo(ContainSubstring(token))
+		Expect(output.String()).To(ContainSubstring(RedactedToken))
+		Expect(output.String()).To(ContainSubstring("my-namespace"))
+	})
+
+	It("never records the Lumigo tokens in events", func() {
+		recorder := record.NewFakeRecorder(3)
+		eventRecorder := NewEventRecorder(recorder)
+
+		eventRecorder.Event(&corev1.Pod{}, corev1.EventTypeWarning, "InvalidToken", "invalid token "+token)
+		eventRecorder.Eventf(&corev1.Pod{}, corev1.EventTypeWarning, "InvalidToken", "invalid token %s", token)
+		eventRecorder.AnnotatedEventf(&corev1.Pod{}, map[string]string{"key": "value"}, corev1.EventTypeWarning, "InvalidToken", "invalid token %s", token)
+
+		for i := 0; i < 3; i++ {
+			Expect(<-recorder.Events).To(Equal("Warning InvalidToken invalid token t_***"))
+		}
+	})
+
+})
@@ -0,0 +1,134 @@
+package tlsoptions
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	// The TLS 1.2 cipher suites approved by FIPS 140-2 that Go implements; the TLS 1.3 ones are not configurable
+	FipsApprovedCipherSuites = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	}
+
+	fipsApprovedCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+)
+
+// Options restrict the TLS versions and cipher suites of the servers of the manager, like the webhook server.
+type Options struct {
+	// The minimum TLS version, e.g., `1.2`; if empty, the Go default is used
+	MinVersion string
+	// The names of the allowed TLS 1.2 cipher suites, e.g., `TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256`; if empty, the Go defaults are used
+	CipherSuites []string
+	// Whether to allow only TLS 1.2+ and FIPS-approved cipher suites and curves
+	FipsApprovedOnly bool
+}
+
+// BindFlags binds the flags of the TLS options to the given flag set.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.MinVersion, "tls-min-version", "",
+		"The minimum TLS version of the webhook server; one of '1.0', '1.1', '1.2', '1.3'. Defaults to the Go default.")
+	fs.Func("tls-cipher-suites",
+		"Comma-separated names of the TLS 1.2 cipher suites of the webhook server, e.g., 'TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256'. Defaults to the Go defaults.",
+		func(value string) error {
+			o.CipherSuites = []string{}
+			for _, cipherSuite := range strings.Split(value, ",") {
+				if cipherSuite = strings.TrimSpace(cipherSuite); len(cipherSuite) > 0 {
+					o.CipherSuites = append(o.CipherSuites, cipherSuite)
+				}
+			}
+			return nil
+		})
+	fs.BoolVar(&o.FipsApprovedOnly, "tls-fips-approved-only", false,
+		"Allow only TLS 1.2+ and FIPS-approved cipher suites in the webhook server.")
+}
+
+// ConfigureTLS returns a function that applies the options to a TLS config, or an error if the
+// options are invalid or, with FipsApprovedOnly, not FIPS-approved.
+func (o *Options) ConfigureTLS() (func(*tls.Config), error) {
+	var minVersion uint16
+	if len(o.MinVersion) > 0 {
+		var isSupported bool
+		if minVersion, isSupported = tlsVersions[o.MinVersion]; !isSupported {
+			return nil, fmt.Errorf("unsupported minimum TLS version '%s'; supported values: '1.0', '1.1', '1.2', '1.3'", o.MinVersion)
+		}
+	}
+
+	cipherSuiteNames := o.CipherSuites
+	if o.FipsApprovedOnly {
+		if minVersion == 0 {
+			minVersion = tls.VersionTLS12
+		} else if minVersion < tls.VersionTLS12 {
+			return nil, fmt.Errorf("the minimum TLS version '%s' is not FIPS-approved; use '1.2' or '1.3'", o.MinVersion)
+		}
+
+		if len(cipherSuiteNames) < 1 {
+			cipherSuiteNames = FipsApprovedCipherSuites
+		}
+
+		for _, cipherSuiteName := range cipherSuiteNames {
+			if !isFipsApproved(cipherSuiteName) {
+				return nil, fmt.Errorf("the TLS cipher suite '%s' is not FIPS-approved; approved cipher suites: %s", cipherSuiteName, strings.Join(FipsApprovedCipherSuites, ", "))
+			}
+		}
+	}
+
+	cipherSuites, err := parseCipherSuites(cipherSuiteNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(config *tls.Config) {
+		if minVersion > 0 {
+			config.MinVersion = minVersion
+		}
+
+		if len(cipherSuites) > 0 {
+			config.CipherSuites = cipherSuites
+		}
+
+		if o.FipsApprovedOnly {
+			config.CurvePreferences = fipsApprovedCurves
+		}
+	}, nil
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	cipherSuiteIds := make(map[string]uint16)
+	for _, cipherSuite := range tls.CipherSuites() {
+		cipherSuiteIds[cipherSuite.Name] = cipherSuite.ID
+	}
+
+	cipherSuites := []uint16{}
+	for _, name := range names {
+		id, isSupported := cipherSuiteIds[name]
+		if !isSupported {
+			return nil, fmt.Errorf("unsupported or insecure TLS cipher suite '%s'", name)
+		}
+		cipherSuites = append(cipherSuites, id)
+	}
+
+	return cipherSuites, nil
+}
+
+func isFipsApproved(cipherSuiteName string) bool {
+	for _, approved := range FipsApprovedCipherSuites {
+		if approved == cipherSuiteName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsoptions
+
+import (
+	"crypto/tls"
+	"flag"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "TLS Options Suite")
+}
+
+var _ = Context("TLS options", func() {
+
+	It("leave the TLS config untouched by default", func() {
+		configureTLS, err := (&Options{}).ConfigureTLS()
+		Expect(err).NotTo(HaveOccurred())
+
+		config := &tls.Config{}
+		configureTLS(config)
+
+		Expect(config.MinVersion).To(Equal(uint16(0)))
+		Expect(config.CipherSuites).To(BeEmpty())
+		Expect(config.CurvePreferences).To(BeEmpty())
+	})
+
+	It("are parsed from the flags", func() {
+		options := &Options{}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		options.BindFlags(fs)
+
+		Expect(fs.Parse([]string{
+			"--tls-min-version=1.3",
+			"--tls-cipher-suites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		})).To(Succeed())
+
+		configureTLS, err := options.ConfigureTLS()
+		Expect(err).NotTo(HaveOccurred())
+
+		config := &tls.Config{}
+		configureTLS(config)
+
+		Expect(config.MinVersion).To(Equal(uint16(tls.VersionTLS13)))
+		Expect(config.CipherSuites).To(Equal([]uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		}))
+	})
+
+	It("restrict the TLS config to FIPS-approved settings", func() {
+		configureTLS, err := (&Options{FipsApprovedOnly: true}).ConfigureTLS()
+		Expect(err).NotTo(HaveOccurred())
+
+		config := &tls.Config{}
+		configureTLS(config)
+
+		Expect(config.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(config.CipherSuites).To(Equal([]uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		}))
+		Expect(config.CurvePreferences).To(Equal([]tls.CurveID{tls.CurveP256, tls.CurveP384}))
+	})
+
+	It("reject settings that are not FIPS-approved", func() {
+		_, err := (&Options{FipsApprovedOnly: true, MinVersion: "1.1"}).ConfigureTLS()
+		Expect(err).To(MatchError(ContainSubstring("is not FIPS-approved")))
+
+		_, err = (&Options{FipsApprovedOnly: true, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"}}).ConfigureTLS()
+		Expect(err).To(MatchError(ContainSubstring("is not FIPS-approved")))
+	})
+
+	It("reject unknown versions and cipher suites", func() {
+		_, err := (&Options{MinVersion: "TLS12"}).ConfigureTLS()
+		Expect(err).To(MatchError(ContainSubstring("unsupported minimum TLS version")))
+
+		_, err = (&Options{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}).ConfigureTLS()
+		Expect(err).To(MatchError(ContainSubstring("unsupported or insecure TLS cipher suite")))
+	})
+
+})
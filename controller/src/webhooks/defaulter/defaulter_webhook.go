@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,17 +36,27 @@ import (
 	"github.com/go-logr/logr"
 
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/capabilities"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/specvalidation"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokendistribution"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/tokensecrets"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
 )
 
 var (
 	decoder = scheme.Codecs.UniversalDecoder()
 )
 
+// Returned to the clients that set Lumigo tokens as values, e.g., shown by `kubectl apply`
+const tokenValueWarning = "the Lumigo token is set as a value, and is readable in clear by whoever can read this Lumigo resource; reference a secret with '.Spec.LumigoToken.SecretRef' instead, unless this is a short-lived environment"
+
 type LumigoDefaulterWebhookHandler struct {
 	client                client.Client
 	decoder               *admission.Decoder
 	LumigoOperatorVersion string
 	Log                   logr.Logger
+	// Optional: if nil, the cluster is assumed to serve all the API resources the settings of the spec rely on
+	CapabilitiesDiscoverer *capabilities.Discoverer
 }
 
 func (h *LumigoDefaulterWebhookHandler) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -112,32 +124,112 @@ func (h *LumigoDefaulterWebhookHandler) Handle(ctx context.Context, request admi
 
 		if len(otherLumigos.Items) > 0 {
 			log.Info("Denied the creation of an instance of Lumigo in a namespace that already had one")
-			return admission.Denied(fmt.Sprintf("There is already an instance of operator.lumigo.io/v1alpha1.Lumigo in the '%s' namespace", namespace))
+			return admission.Denied(describeExistingLumigo(newLumigo, &otherLumigos.Items[0]))
 		}
 	}
 
 	log = log.WithValues("name", newLumigo.Name)
 
-	if newLumigo.Spec.LumigoToken.SecretRef.Name == "" {
-		log.Info("Denied the creation of an instance of Lumigo with no reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Name' is blank)")
-		return admission.Denied("no reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Name' is blank)")
+	// The Lumigo tokens set as values are never logged
+	warnings := []string{}
+	if newLumigo.Spec.LumigoToken.Value != "" {
+		if newLumigo.Spec.LumigoToken.SecretRef != (operatorv1alpha1.KubernetesSecretRef{}) {
+			log.Info("Denied the creation of an instance of Lumigo with both a reference to a Lumigo token and its value")
+			return admission.Denied("'.Spec.LumigoToken.SecretRef' and '.Spec.LumigoToken.Value' are mutually exclusive")
+		}
+
+		if !tokensecrets.IsWellFormedToken(newLumigo.Spec.LumigoToken.Value) {
+			log.Info("Denied the creation of an instance of Lumigo with an invalid Lumigo token ('.Spec.LumigoToken.Value')")
+			return admission.Denied("invalid Lumigo token ('.Spec.LumigoToken.Value' should be `t_` followed by 21 alphanumeric characters; see https://docs.lumigo.io/docs/lumigo-tokens)")
+		}
+
+		warnings = append(warnings, tokenValueWarning)
+	} else {
+		if newLumigo.Spec.LumigoToken.SecretRef.Name == "" {
+			log.Info("Denied the creation of an instance of Lumigo with no reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Name' is blank)")
+			return admission.Denied("no reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Name' is blank)")
+		}
+
+		if newLumigo.Spec.LumigoToken.SecretRef.Key == "" {
+			log.Info("Denied the creation of an instance of Lumigo with invalid reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Key' is blank)")
+			return admission.Denied("invalid reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Key' is blank)")
+		}
+	}
+
+	if serviceNameTemplate := newLumigo.Spec.Tracing.Injection.ServiceNameTemplate; serviceNameTemplate != "" {
+		if _, err := mutation.ParseServiceNameTemplate(serviceNameTemplate); err != nil {
+			log.Info("Denied the creation of an instance of Lumigo with an invalid service name template", "error", err.Error())
+			return admission.Denied(fmt.Sprintf("invalid service name template ('.Spec.Tracing.Injection.ServiceNameTemplate'): %s", err.Error()))
+		}
+	}
+
+	if _, err := mutation.ParseImagePatterns(&newLumigo.Spec.Tracing.Injection.ImagePatterns); err != nil {
+		log.Info("Denied the creation of an instance of Lumigo with invalid image patterns", "error", err.Error())
+		return admission.Denied(fmt.Sprintf("invalid image patterns ('.Spec.Tracing.Injection.ImagePatterns'): %s", err.Error()))
+	}
+
+	for _, route := range newLumigo.Spec.Tracing.Routes {
+		if route.LumigoToken.Value != "" {
+			if route.LumigoToken.SecretRef != (operatorv1alpha1.KubernetesSecretRef{}) {
+				log.Info("Denied the creation of an instance of Lumigo with a route with both a reference to a Lumigo token and its value", "route", route.Name)
+				return admission.Denied(fmt.Sprintf("'.Spec.Tracing.Routes[].LumigoToken.SecretRef' and '.Spec.Tracing.Routes[].LumigoToken.Value' of the route '%s' are mutually exclusive", route.Name))
+			}
+
+			if !tokensecrets.IsWellFormedToken(route.LumigoToken.Value) {
+				log.Info("Denied the creation of an instance of Lumigo with a route with an invalid Lumigo token", "route", route.Name)
+				return admission.Denied(fmt.Sprintf("invalid Lumigo token of the route '%s' ('.Spec.Tracing.Routes[].LumigoToken.Value' should be `t_` followed by 21 alphanumeric characters; see https://docs.lumigo.io/docs/lumigo-tokens)", route.Name))
+			}
+
+			if len(warnings) < 1 {
+				warnings = append(warnings, tokenValueWarning)
+			}
+		} else if route.LumigoToken.SecretRef.Name == "" || route.LumigoToken.SecretRef.Key == "" {
+			log.Info("Denied the creation of an instance of Lumigo with a route with an invalid reference to a Lumigo token", "route", route.Name)
+			return admission.Denied(fmt.Sprintf("invalid reference to a Lumigo token of the route '%s' ('.Spec.Tracing.Routes[].LumigoToken.SecretRef.Name' and '.Spec.Tracing.Routes[].LumigoToken.SecretRef.Key' must not be blank)", route.Name))
+		}
+
+		if _, err := metav1.LabelSelectorAsSelector(&route.Selector); err != nil {
+			log.Info("Denied the creation of an instance of Lumigo with a route with an invalid selector", "route", route.Name, "error", err.Error())
+			return admission.Denied(fmt.Sprintf("invalid selector of the route '%s' ('.Spec.Tracing.Routes[].Selector'): %s", route.Name, err.Error()))
+		}
+	}
+
+	inconsistencies := specvalidation.GetInconsistencies(&newLumigo.Spec)
+	if request.Operation == admissionv1.Update {
+		// Reject only the inconsistencies introduced by the update, so that the Lumigo instances created
+		// before a check was added can still be updated; the controller reports all of them in the status
+		oldLumigo := &operatorv1alpha1.Lumigo{}
+		if _, _, err := decoder.Decode(request.OldObject.Raw, nil, oldLumigo); err != nil {
+			log.Error(err, "cannot parse the original resource")
+			return admission.Errored(http.StatusInternalServerError, fmt.Errorf("cannot parse the original resource: %w", err))
+		}
+		inconsistencies = specvalidation.GetNewInconsistencies(&oldLumigo.Spec, &newLumigo.Spec)
+	}
+	if len(inconsistencies) > 0 {
+		log.Info("Denied an instance of Lumigo with inconsistent settings", "inconsistencies", inconsistencies)
+		return admission.Denied(fmt.Sprintf("inconsistent settings: %s", strings.Join(inconsistencies, "; ")))
 	}
 
-	if newLumigo.Spec.LumigoToken.SecretRef.Key == "" {
-		log.Info("Denied the creation of an instance of Lumigo with invalid reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Key' is blank)")
-		return admission.Denied("invalid reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Key' is blank)")
+	// The settings the cluster does not support are admitted, as the cluster may serve them later on, e.g.,
+	// once Keda is installed, and reported in the UnsupportedOnThisCluster condition in the meantime
+	for _, unsupportedSetting := range capabilities.GetUnsupportedSettings(h.CapabilitiesDiscoverer.Get(time.Now()), &newLumigo.Spec) {
+		warnings = append(warnings, fmt.Sprintf("not supported on this cluster: %s", unsupportedSetting))
 	}
 
 	newTrue := true
 	if newLumigo.Spec.Tracing.Injection.Enabled == nil {
 		newLumigo.Spec.Tracing.Injection.Enabled = &newTrue
 	}
-	if newLumigo.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation == nil {
+	// Existing resources are injected only if the injection is enabled
+	if newLumigo.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation == nil && *newLumigo.Spec.Tracing.Injection.Enabled {
 		newLumigo.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation = &newTrue
 	}
 	if newLumigo.Spec.Tracing.Injection.RemoveLumigoFromResourcesOnDeletion == nil {
 		newLumigo.Spec.Tracing.Injection.RemoveLumigoFromResourcesOnDeletion = &newTrue
 	}
+	if newLumigo.Spec.Tracing.Injection.UnsupportedArchitecturePolicy == "" {
+		newLumigo.Spec.Tracing.Injection.UnsupportedArchitecturePolicy = operatorv1alpha1.UnsupportedArchitecturePolicySkip
+	}
 
 	if newLumigo.Spec.Infrastructure.Enabled == nil {
 		newLumigo.Spec.Infrastructure.Enabled = &newTrue
@@ -145,16 +237,55 @@ func (h *LumigoDefaulterWebhookHandler) Handle(ctx context.Context, request admi
 	if newLumigo.Spec.Infrastructure.KubeEvents.Enabled == nil {
 		newLumigo.Spec.Infrastructure.KubeEvents.Enabled = &newTrue
 	}
+	if newLumigo.Spec.Infrastructure.Prometheus.ScrapeAnnotatedPods == nil {
+		newLumigo.Spec.Infrastructure.Prometheus.ScrapeAnnotatedPods = &newTrue
+	}
 
 	newFalse := false
 	if newLumigo.Spec.Logging.Enabled == nil {
 		newLumigo.Spec.Logging.Enabled = &newFalse
 	}
+	if newLumigo.Spec.Tracing.GoInstrumentation.Enabled == nil {
+		newLumigo.Spec.Tracing.GoInstrumentation.Enabled = &newFalse
+	}
+	if newLumigo.Spec.Infrastructure.Prometheus.Enabled == nil {
+		newLumigo.Spec.Infrastructure.Prometheus.Enabled = &newFalse
+	}
+	if newLumigo.Spec.Infrastructure.NodeLifecycle.Enabled == nil {
+		newLumigo.Spec.Infrastructure.NodeLifecycle.Enabled = &newFalse
+	}
+	if newLumigo.Spec.Tracing.SpanMetrics.Enabled == nil {
+		newLumigo.Spec.Tracing.SpanMetrics.Enabled = &newFalse
+	}
+	if newLumigo.Spec.Debug.LogTelemetry == nil {
+		newLumigo.Spec.Debug.LogTelemetry = &newFalse
+	}
+	if newLumigo.Spec.Archival.Enabled == nil {
+		newLumigo.Spec.Archival.Enabled = &newFalse
+	}
 
 	marshalled, err := json.Marshal(newLumigo)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("cannot marshal object %w", err))
 	}
 
-	return admission.PatchResponseFromRaw(request.Object.Raw, marshalled)
+	response := admission.PatchResponseFromRaw(request.Object.Raw, marshalled)
+	response.Warnings = warnings
+	return response
+}
+
+// The Lumigo instances that HNC propagates from an ancestor namespace are denied like the others in the namespaces
+// that already have a Lumigo instance, so that the Lumigo instances of a namespace take precedence over the inherited ones
+func describeExistingLumigo(newLumigo *operatorv1alpha1.Lumigo, existingLumigo *operatorv1alpha1.Lumigo) string {
+	namespace := newLumigo.Namespace
+
+	if ancestorNamespace := tokendistribution.GetInheritedFrom(existingLumigo); len(ancestorNamespace) > 0 {
+		return fmt.Sprintf("There is already an instance of operator.lumigo.io/v1alpha1.Lumigo in the '%s' namespace, inherited from the '%s' namespace; exclude the '%s' namespace from the propagation with the 'propagate.hnc.x-k8s.io/treeSelect' annotation of the Lumigo instance in the '%s' namespace", namespace, ancestorNamespace, namespace, ancestorNamespace)
+	}
+
+	if ancestorNamespace := tokendistribution.GetInheritedFrom(newLumigo); len(ancestorNamespace) > 0 {
+		return fmt.Sprintf("There is already an instance of operator.lumigo.io/v1alpha1.Lumigo in the '%s' namespace, which takes precedence over the one inherited from the '%s' namespace", namespace, ancestorNamespace)
+	}
+
+	return fmt.Sprintf("There is already an instance of operator.lumigo.io/v1alpha1.Lumigo in the '%s' namespace", namespace)
 }
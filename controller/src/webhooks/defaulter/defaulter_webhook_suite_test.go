@@ -222,10 +222,18 @@ var _ = Context("Lumigo defaulter webhook", func() {
 
 			Expect(newLumigo.Spec.Infrastructure.Enabled).To(&beBoolPointer{expectedValue: true})
 			Expect(newLumigo.Spec.Infrastructure.KubeEvents.Enabled).To(&beBoolPointer{expectedValue: true})
+			Expect(newLumigo.Spec.Infrastructure.Prometheus.Enabled).To(&beBoolPointer{expectedValue: false})
+			Expect(newLumigo.Spec.Infrastructure.NodeLifecycle.Enabled).To(&beBoolPointer{expectedValue: false})
+			Expect(newLumigo.Spec.Infrastructure.Prometheus.ScrapeAnnotatedPods).To(&beBoolPointer{expectedValue: true})
 			Expect(newLumigo.Spec.Tracing.Injection.Enabled).To(&beBoolPointer{expectedValue: true})
 			Expect(newLumigo.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation).To(&beBoolPointer{expectedValue: true})
 			Expect(newLumigo.Spec.Tracing.Injection.RemoveLumigoFromResourcesOnDeletion).To(&beBoolPointer{expectedValue: true})
+			Expect(newLumigo.Spec.Tracing.Injection.UnsupportedArchitecturePolicy).To(Equal(operatorv1alpha1.UnsupportedArchitecturePolicySkip))
 			Expect(newLumigo.Spec.Logging.Enabled).To(&beBoolPointer{expectedValue: false})
+			Expect(newLumigo.Spec.Tracing.GoInstrumentation.Enabled).To(&beBoolPointer{expectedValue: false})
+			Expect(newLumigo.Spec.Tracing.SpanMetrics.Enabled).To(&beBoolPointer{expectedValue: false})
+			Expect(newLumigo.Spec.Debug.LogTelemetry).To(&beBoolPointer{expectedValue: false})
+			Expect(newLumigo.Spec.Archival.Enabled).To(&beBoolPointer{expectedValue: false})
 		})
 
 		It("it rejects instances with blank .LumigoToken.Spec.LumigoToken.SecretRef.Name", func() {
@@ -268,6 +276,186 @@ var _ = Context("Lumigo defaulter webhook", func() {
 			Expect(k8sClient.Create(ctx, &newLumigo)).To(MatchError("admission webhook \"lumigodefaulter.kb.io\" denied the request: invalid reference to a Lumigo token ('.Spec.LumigoToken.SecretRef.Key' is blank)"))
 		})
 
+		It("it accepts instances with the Lumigo token set as .Spec.LumigoToken.Value", func() {
+			newLumigo := operatorv1alpha1.Lumigo{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Lumigo",
+					APIVersion: lumigoApiVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "lumigo",
+					Labels:    map[string]string{},
+				},
+				Spec: operatorv1alpha1.LumigoSpec{
+					LumigoToken: operatorv1alpha1.Credentials{
+						Value: "t_123456789012345678901",
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, &newLumigo)).To(Succeed())
+			Expect(newLumigo.Spec.LumigoToken.Value).To(Equal("t_123456789012345678901"))
+		})
+
+		It("it rejects instances with both .Spec.LumigoToken.SecretRef and .Spec.LumigoToken.Value", func() {
+			newLumigo := operatorv1alpha1.Lumigo{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Lumigo",
+					APIVersion: lumigoApiVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "lumigo",
+					Labels:    map[string]string{},
+				},
+				Spec: operatorv1alpha1.LumigoSpec{
+					LumigoToken: operatorv1alpha1.Credentials{
+						SecretRef: operatorv1alpha1.KubernetesSecretRef{
+							Name: "lumigo-token",
+							Key:  "token",
+						},
+						Value: "t_123456789012345678901",
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, &newLumigo)).To(MatchError("admission webhook \"lumigodefaulter.kb.io\" denied the request: '.Spec.LumigoToken.SecretRef' and '.Spec.LumigoToken.Value' are mutually exclusive"))
+		})
+
+		It("it rejects instances with an invalid .Spec.LumigoToken.Value without revealing it", func() {
+			newLumigo := operatorv1alpha1.Lumigo{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Lumigo",
+					APIVersion: lumigoApiVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "lumigo",
+					Labels:    map[string]string{},
+				},
+				Spec: operatorv1alpha1.LumigoSpec{
+					LumigoToken: operatorv1alpha1.Credentials{
+						Value: "not-a-token",
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, &newLumigo)
+			Expect(err).To(MatchError(ContainSubstring("invalid Lumigo token ('.Spec.LumigoToken.Value'")))
+			Expect(err.Error()).NotTo(ContainSubstring("not-a-token"))
+		})
+
+		It("it rejects instances with an invalid .Spec.Tracing.Injection.ServiceNameTemplate", func() {
+			newLumigo := operatorv1alpha1.Lumigo{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Lumigo",
+					APIVersion: lumigoApiVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      "lumigo",
+					Labels:    map[string]string{},
+				},
+				Spec: operatorv1alpha1.LumigoSpec{
+					LumigoToken: operatorv1alpha1.Credentials{
+						SecretRef: operatorv1alpha1.KubernetesSecretRef{
+							Name: "lumigo-token",
+							Key:  "token",
+						},
+					},
+					Tracing: operatorv1alpha1.TracingSpec{
+						Injection: operatorv1alpha1.InjectionSpec{
+							ServiceNameTemplate: "{{ .Namespace }-{{ .WorkloadName }}",
+						},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, &newLumigo)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid service name template ('.Spec.Tracing.Injection.ServiceNameTemplate')"))
+		})
+
+		It("it rejects instances with an invalid .Spec.Tracing.Injection.ImagePatterns", func() {
+			newLumigo := newLumigo(namespaceName, "lumigo", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigo-token",
+					Key:  "token",
+				},
+			}, true)
+			newLumigo.Spec.Tracing.Injection.ImagePatterns = operatorv1alpha1.ImagePatternsSpec{
+				Allow: []string{"internal-registry/payments/*"},
+				Deny:  []string{"regex:(nginx|redis"},
+			}
+
+			err := k8sClient.Create(ctx, newLumigo)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid image patterns ('.Spec.Tracing.Injection.ImagePatterns'): invalid denied image pattern: 'regex:(nginx|redis'"))
+		})
+
+		It("it rejects instances with a route with an invalid selector", func() {
+			newLumigo := newLumigo(namespaceName, "lumigo", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigo-token",
+					Key:  "token",
+				},
+			}, true)
+			newLumigo.Spec.Tracing.Routes = []operatorv1alpha1.TracingRoute{
+				{
+					Name: "payments",
+					Selector: metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{
+								Key:      "team",
+								Operator: "Matches",
+								Values:   []string{"payments"},
+							},
+						},
+					},
+					LumigoToken: operatorv1alpha1.Credentials{
+						SecretRef: operatorv1alpha1.KubernetesSecretRef{
+							Name: "lumigo-payments",
+							Key:  "token",
+						},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, newLumigo)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid selector of the route 'payments'"))
+		})
+
+		It("it rejects instances with inconsistent injection settings", func() {
+			newTrue := true
+			newLumigo := newLumigo(namespaceName, "lumigo", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigo-token",
+					Key:  "token",
+				},
+			}, false)
+			newLumigo.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation = &newTrue
+
+			err := k8sClient.Create(ctx, newLumigo)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("inconsistent settings: '.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation' is 'true', but no resource is injected as '.Spec.Tracing.Injection.Enabled' is 'false'"))
+		})
+
+		It("it does not default .Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation if the injection is disabled", func() {
+			newLumigo := newLumigo(namespaceName, "lumigo", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigo-token",
+					Key:  "token",
+				},
+			}, false)
+
+			Expect(k8sClient.Create(ctx, newLumigo)).To(Succeed())
+
+			Expect(newLumigo.Spec.Tracing.Injection.InjectLumigoIntoExistingResourcesOnCreation).To(BeNil())
+			Expect(newLumigo.Spec.Tracing.Injection.RemoveLumigoFromResourcesOnDeletion).To(&beBoolPointer{expectedValue: true})
+		})
+
 	})
 
 	Context("with already one Lumigo instance in the namespace", func() {
@@ -305,6 +493,23 @@ var _ = Context("Lumigo defaulter webhook", func() {
 			})
 		})
 
+		It("should explain how to replace an instance inherited via HNC", func() {
+			lumigoToken := operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigo-credentials",
+					Key:  "token",
+				},
+			}
+
+			inheritedLumigo := newLumigo(namespaceName, "lumigo", lumigoToken, true)
+			inheritedLumigo.Labels["hnc.x-k8s.io/inherited-from"] = "my-parent-namespace"
+			Expect(k8sClient.Create(ctx, inheritedLumigo)).Should(Succeed())
+
+			err := k8sClient.Create(ctx, newLumigo(namespaceName, "my-lumigo", lumigoToken, true))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("inherited from the 'my-parent-namespace' namespace; exclude the '%s' namespace from the propagation", namespaceName))
+		})
+
 	})
 
 })
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Lumigo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The largest workload manifest the injection preview accepts
+const maxInjectionPreviewBodyBytes = 1 << 20
+
+// InjectionPreview tells whether the injector webhook would inject a workload, and how
+type InjectionPreview struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name,omitempty"`
+	// Whether the workload would be injected if it were created as it is
+	Injected bool `json:"injected"`
+	// Whether the workload would be denied, e.g., because the injection is strict and the workload cannot be injected
+	Denied bool `json:"denied,omitempty"`
+	// Why the workload would not be injected, if it would not
+	Reason string `json:"reason,omitempty"`
+	// The JSON patches the injector webhook would apply to the workload, e.g., to add the injector init container
+	// and the Lumigo environment variables
+	Patches json.RawMessage `json:"patches,omitempty"`
+}
+
+// InjectionPreviewHandler answers whether the workload manifest POSTed as JSON would be injected in its namespace,
+// and with what config, without creating it; the namespace of manifests without one comes from the `namespace`
+// query parameter. The manifest goes through the same logic as the admissions of the injector webhook, but no
+// events are recorded nor audit entries written. The handler is meant to be served on the secure metrics endpoint,
+// so that the callers are authenticated and authorized on its path.
+type InjectionPreviewHandler struct {
+	// The injector webhook whose decisions are previewed; it must have been set up with the manager
+	Webhook *LumigoInjectorWebhookHandler
+}
+
+func (p *InjectionPreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if p.Webhook == nil || p.Webhook.lumigoLookup == nil {
+		http.Error(w, "the injector webhook is not set up", http.StatusServiceUnavailable)
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInjectionPreviewBodyBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read the workload: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	workload := &unstructured.Unstructured{}
+	if err := workload.UnmarshalJSON(raw); err != nil {
+		http.Error(w, fmt.Sprintf("cannot parse the workload: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if len(workload.GetNamespace()) < 1 {
+		workload.SetNamespace(r.URL.Query().Get("namespace"))
+		if len(workload.GetNamespace()) < 1 {
+			http.Error(w, "the workload has no namespace, and the 'namespace' query parameter is not set", http.StatusBadRequest)
+			return
+		}
+
+		if raw, err = workload.MarshalJSON(); err != nil {
+			http.Error(w, fmt.Sprintf("cannot marshal the workload: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	gvk := workload.GroupVersionKind()
+	dryRun := true
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{
+				Group:   gvk.Group,
+				Version: gvk.Version,
+				Kind:    gvk.Kind,
+			},
+			Namespace: workload.GetNamespace(),
+			Name:      workload.GetName(),
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+			DryRun:    &dryRun,
+		},
+	}
+
+	// The workload does not exist, so there is nothing to record events about
+	webhook := *p.Webhook
+	webhook.EventRecorder = &record.FakeRecorder{}
+
+	response := webhook.handle(r.Context(), request)
+	// A denial is a decision of the injector webhook to preview, unlike the errors of the webhook itself
+	isDenied := !response.Allowed && response.Result != nil && response.Result.Code == http.StatusForbidden
+	if !response.Allowed && !isDenied {
+		message := responseMessage(response)
+		if len(message) < 1 {
+			message = "the injector webhook failed"
+		}
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	preview := InjectionPreview{
+		Namespace: workload.GetNamespace(),
+		Kind:      gvk.Kind,
+		Name:      workload.GetName(),
+		Injected:  response.Allowed && len(response.Patches) > 0,
+		Denied:    isDenied,
+	}
+
+	if preview.Injected {
+		if preview.Patches, err = json.Marshal(response.Patches); err != nil {
+			http.Error(w, fmt.Sprintf("cannot marshal the patches: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+	} else if message := responseMessage(response); len(message) > 0 {
+		preview.Reason = message
+	} else {
+		preview.Reason = "The workload would be admitted unchanged"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(preview); err != nil {
+		p.Webhook.Log.Error(err, "Cannot write the injection preview", "namespace", preview.Namespace, "kind", preview.Kind, "name", preview.Name)
+	}
+}
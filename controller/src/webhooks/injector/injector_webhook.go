@@ -22,13 +22,17 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -38,7 +42,16 @@ import (
 
 	"github.com/go-logr/logr"
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/audit"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/deferredinjections"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/imageverification"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectionskips"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/injectionspec"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/platform"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/selftelemetry"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxydedicated"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/controllers/telemetryproxyshards"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
 )
 
@@ -54,10 +67,41 @@ type LumigoInjectorWebhookHandler struct {
 	LumigoInjectorImage              string
 	TelemetryProxyOtlpServiceUrl     string
 	TelemetryProxyOtlpLogsServiceUrl string
-	Log                              logr.Logger
+	// Optional: if nil, the telemetry-proxy is not sharded, and all the instrumented workloads send telemetry to the same one
+	TelemetryProxyShardsConfig *telemetryproxyshards.ShardsConfig
+	// Optional: if nil, the Lumigo instances cannot request a dedicated telemetry-proxy in their namespace
+	DedicatedTelemetryProxyConfig *telemetryproxydedicated.DedicatedProxyConfig
+	// Optional: if nil, the injector image is injected without verifying its signature
+	InjectorImageVerifier *imageverification.Verifier
+	// Optional: if nil, the settings of the Lumigo distros not set by the Lumigo instances keep the defaults of the distros
+	InjectorDefaults *mutation.InjectorDefaults
+	// Optional: if nil, only the workloads that opt out are not injected; otherwise, the workloads of the operator
+	// itself are never injected, whatever the Lumigo instance of their namespace
+	ProtectedWorkloads *mutation.ProtectedWorkloads
+	// Optional: if nil, the mutations of resources are not audited
+	Auditor *audit.Auditor
+	// How long the Lumigo instance of a namespace, and the mutator built out of it, are reused across admissions
+	// unless the Lumigo instance or the secrets of the namespace change; if zero, they are looked up at every admission
+	LumigoLookupFreshness time.Duration
+	// Optional: if nil, the admissions are not traced
+	SelfTelemetry *selftelemetry.Tracer
+	// The platform the operator runs on; on restricted ones, the injection is adjusted to their constraints
+	Platform platform.Platform
+	// Whether the admissions that the webhook denies or errors on are recorded as Warning events on the admitted
+	// resources, besides being logged and counted
+	RejectionEventsEnabled bool
+	Log                    logr.Logger
+
+	lumigoLookup *lumigoLookup
 }
 
 func (h *LumigoInjectorWebhookHandler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	// The Lumigo instances are read from the informer cache of the manager rather than from the API server
+	h.lumigoLookup = newLumigoLookup(mgr.GetCache(), h.LumigoLookupFreshness)
+	if err := h.lumigoLookup.InvalidateOnChanges(context.Background(), mgr.GetCache()); err != nil {
+		return err
+	}
+
 	webhook := &admission.Webhook{
 		Handler: h,
 	}
@@ -84,6 +128,41 @@ func (h *LumigoInjectorWebhookHandler) InjectDecoder(d *admission.Decoder) error
 }
 
 func (h *LumigoInjectorWebhookHandler) Handle(ctx context.Context, request admission.Request) admission.Response {
+	start := time.Now()
+	ctx, span := h.SelfTelemetry.StartServerSpan(ctx, "Admission",
+		selftelemetry.String("k8s.namespace.name", request.Namespace),
+		selftelemetry.String("k8s.resource.kind", request.Kind.Kind),
+		selftelemetry.String("admission.operation", string(request.Operation)),
+	)
+	defer span.End()
+
+	response := h.handle(ctx, request)
+
+	outcome := outcomeAllowed
+	if !response.Allowed && response.Result != nil && response.Result.Code == http.StatusForbidden {
+		outcome = outcomeDenied
+	} else if !response.Allowed {
+		outcome = outcomeErrored
+	} else if len(response.Patches) > 0 {
+		outcome = outcomeMutated
+	}
+	admissionDurationSeconds.WithLabelValues(request.Kind.Kind, outcome).Observe(time.Since(start).Seconds())
+
+	span.SetAttributes(selftelemetry.String("admission.outcome", outcome))
+	if !response.Allowed {
+		span.RecordError(fmt.Errorf("%s", responseMessage(response)))
+
+		var eventRecorder record.EventRecorder
+		if h.RejectionEventsEnabled {
+			eventRecorder = h.EventRecorder
+		}
+		recordRejection(request, response, outcome, eventRecorder, &h.Log)
+	}
+
+	return response
+}
+
+func (h *LumigoInjectorWebhookHandler) handle(ctx context.Context, request admission.Request) admission.Response {
 	log := logf.Log.WithName("lumigo-injector-webhook").WithValues("resource_gvk", request.Kind)
 
 	if request.Operation == admissionv1.Delete {
@@ -91,9 +170,13 @@ func (h *LumigoInjectorWebhookHandler) Handle(ctx context.Context, request admis
 		return admission.Allowed("Mutating webhooks have nothing to do on deletions")
 	}
 
+	if request.Kind.Group == "" && request.Kind.Kind == "Pod" {
+		return h.handleEphemeralContainers(request, &log)
+	}
+
 	resourceAdaper, err := newResourceAdatper(request.Kind, request.Object.Raw)
 	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("error while parsing the resource: %w", err))
+		return errored(rejectionReasonInvalidResource, fmt.Errorf("error while parsing the resource: %w", err))
 	}
 
 	if resourceAdaper == nil {
@@ -102,60 +185,103 @@ func (h *LumigoInjectorWebhookHandler) Handle(ctx context.Context, request admis
 	}
 
 	namespace := resourceAdaper.GetNamespace()
+	log = log.WithValues("namespace", namespace)
 
 	// Check if we have a Lumigo instance in the object's namespace
-	lumigos := &operatorv1alpha1.LumigoList{}
-	if err := h.Client.List(ctx, lumigos, &client.ListOptions{
-		Namespace: namespace,
-	}); err != nil {
+	lumigo, err := h.lumigoLookup.GetLumigoOfNamespace(ctx, namespace)
+	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// TODO The Lumigo CRD is not register. Catastrophic error?
 			return admission.Allowed(fmt.Sprintf("No Lumigo configuration for the '%s' namespace; resource will not be mutated", namespace))
 		}
 
 		log.Error(err, "failed to retrieve Lumigo instance in namespace", "namespace", namespace)
-		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("cannot retrieve Lumigo instances in namespace %s: %w", namespace, err))
+		return errored(rejectionReasonLumigoLookupFailed, fmt.Errorf("cannot retrieve Lumigo instances in namespace %s: %w", namespace, err))
 	}
 
-	if len(lumigos.Items) < 1 {
+	if lumigo == nil {
 		return admission.Allowed(fmt.Sprintf("No Lumigo configuration for the '%s' namespace; resource will not be mutated", namespace))
 	}
 
-	lumigo := lumigos.Items[0]
-
 	// Check if tracer injection is enabled (so the injection _should_ be performed)
 	enabled := lumigo.Spec.Tracing.Injection.Enabled
 	if enabled != nil && !*enabled {
 		return admission.Allowed(fmt.Sprintf("Tracing injection is disabled in the '%s' namespace; resource will not be mutated", namespace))
 	}
 
-	if !conditions.IsActive(&lumigo) {
-		return admission.Allowed(fmt.Sprintf("The Lumigo object in the '%s' namespace is not active; resource will not be mutated", namespace))
+	if paused := lumigo.Spec.Paused; paused != nil && *paused {
+		return admission.Allowed(fmt.Sprintf("The Lumigo object in the '%s' namespace is paused; resource will not be mutated", namespace))
+	}
+
+	// Injecting a paused or suspended workload would roll out the injection as a surprise when it is resumed, and
+	// it is injected by the update that resumes it instead, which is not denied in strict mode either
+	if resourceAdaper.GetObjectMeta().Labels[mutation.LumigoAutoTraceLabelKey] != mutation.LumigoAutoTraceLabelSkipNextInjectorValue && deferredinjections.IsSuspended(resourceAdaper.GetResource()) {
+		return admission.Allowed(fmt.Sprintf("The %s is paused or suspended; its injection is deferred until it is resumed", strings.ToLower(request.Kind.Kind)))
+	}
+
+	if !conditions.IsActive(lumigo) {
+		return admitWithoutInjection(lumigo, resourceAdaper.GetObjectMeta(), fmt.Sprintf("The Lumigo object in the '%s' namespace is not active; resource will not be mutated", namespace), fmt.Errorf("the '%s/%s' Lumigo resource is not active", lumigo.Namespace, lumigo.Name))
+	}
+
+	// Injecting an endpoint the workloads cannot reach would lose their telemetry, and may slow them down
+	if conditions.IsTelemetryProxyUnreachable(lumigo) {
+		err := fmt.Errorf("the telemetry-proxy is unreachable, see the '%s' condition of the '%s/%s' Lumigo resource", operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable, lumigo.Namespace, lumigo.Name)
+		operatorv1alpha1.RecordSkippedInstrumentationEvent(h.EventRecorder, resourceAdaper.GetResource(), fmt.Sprintf("injector webhook, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name), err)
+		return admitWithoutInjection(lumigo, resourceAdaper.GetObjectMeta(), fmt.Sprintf("Skipping injection: %s; resource will not be mutated", err.Error()), err)
 	}
 
-	mutator, err := mutation.NewMutator(&log, &lumigo.Spec, h.LumigoOperatorVersion, h.LumigoInjectorImage, h.TelemetryProxyOtlpServiceUrl, h.TelemetryProxyOtlpLogsServiceUrl)
+	lumigoInjectorImage := h.LumigoInjectorImage
+	if h.InjectorImageVerifier != nil {
+		if lumigoInjectorImage, err = h.InjectorImageVerifier.Verify(ctx, h.LumigoInjectorImage); err != nil {
+			operatorv1alpha1.RecordCannotAddInstrumentationEvent(h.EventRecorder, resourceAdaper.GetResource(), fmt.Sprintf("injector webhook, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name), fmt.Errorf("the signature of the Lumigo injector image cannot be verified: %w", err))
+			return admitWithoutInjection(lumigo, resourceAdaper.GetObjectMeta(), fmt.Sprintf("The signature of the Lumigo injector image cannot be verified: %s; resource will not be mutated", err.Error()), fmt.Errorf("the signature of the Lumigo injector image cannot be verified: %w", err))
+		}
+	}
+
+	// The mutator is reused across the admissions in the namespace until the Lumigo instance changes
+	mutator, err := h.lumigoLookup.GetMutatorOfNamespace(lumigo, lumigoInjectorImage, func() (mutation.Mutator, error) {
+		telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl := telemetryproxyshards.OtlpServiceUrls(h.TelemetryProxyShardsConfig, namespace, h.TelemetryProxyOtlpServiceUrl, h.TelemetryProxyOtlpLogsServiceUrl)
+		if h.DedicatedTelemetryProxyConfig != nil && telemetryproxydedicated.IsRequested(lumigo) {
+			telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl = telemetryproxydedicated.OtlpServiceUrls(namespace)
+		}
+		return mutation.NewMutator(&h.Log, injectionspec.EffectiveSpec(lumigo), h.LumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, h.Platform.InjectorResources(), h.InjectorDefaults, h.ProtectedWorkloads)
+	})
 	if err != nil {
-		return admission.Allowed(fmt.Errorf("cannot instantiate mutator: %w", err).Error())
+		return admitWithoutInjection(lumigo, resourceAdaper.GetObjectMeta(), fmt.Errorf("cannot instantiate mutator: %w", err).Error(), err)
 	}
 
 	objectMeta := resourceAdaper.GetObjectMeta()
 	hadAlreadyInstrumentation := strings.HasPrefix(objectMeta.Labels[mutation.LumigoAutoTraceLabelKey], mutation.LumigoAutoTraceLabelVersionPrefixValue)
-	injectionOccurred := false
+	var result *mutation.InjectionResult
 	if objectMeta.Labels[mutation.LumigoAutoTraceLabelKey] == mutation.LumigoAutoTraceLabelSkipNextInjectorValue {
 		h.Log.Info(fmt.Sprintf("Skipping injection: '%s' label set to '%s'", mutation.LumigoAutoTraceLabelKey, mutation.LumigoAutoTraceLabelSkipNextInjectorValue))
 		delete(objectMeta.Labels, mutation.LumigoAutoTraceLabelKey)
-	} else if injectionOccurred, err = resourceAdaper.InjectLumigoInto(mutator); err != nil {
+	} else if result, err = resourceAdaper.InjectLumigoInto(mutator); err == nil && result.IsSkipped() {
+		skipErr := &mutation.SkipInjectionError{Code: result.SkipReason, Reason: result.SkipMessage}
+		operatorv1alpha1.RecordSkippedInstrumentationEvent(h.EventRecorder, resourceAdaper.GetResource(), fmt.Sprintf("injector webhook, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name), skipErr)
+		injectionskips.Record(namespace, request.Kind.Kind, injectionskips.SourceWebhook, skipErr)
+		return admission.Allowed(fmt.Sprintf("Skipping injection (%s): %s; resource will not be mutated", result.SkipReason, result.SkipMessage))
+	} else if err != nil {
 		if !hadAlreadyInstrumentation {
 			operatorv1alpha1.RecordCannotAddInstrumentationEvent(h.EventRecorder, resourceAdaper.GetResource(), fmt.Sprintf("injector webhook, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name), err)
 		} else {
 			operatorv1alpha1.RecordCannotUpdateInstrumentationEvent(h.EventRecorder, resourceAdaper.GetResource(), fmt.Sprintf("injector webhook, acting on behalf of the '%s/%s' Lumigo resource", lumigo.Namespace, lumigo.Name), err)
 		}
-		return admission.Allowed(fmt.Errorf("cannot inject Lumigo tracing in the pod spec %w", err).Error())
+		return admitWithoutInjection(lumigo, objectMeta, fmt.Errorf("cannot inject Lumigo tracing in the pod spec %w", err).Error(), err)
+	}
+
+	injectionOccurred := result != nil && result.Mutated
+	if injectionOccurred {
+		log.V(1).Info("Injected Lumigo", "namespace", namespace, "changedFields", result.ChangedFields)
 	}
 
 	marshalled, err := resourceAdaper.Marshal()
 	if err != nil {
-		return admission.Allowed(fmt.Errorf("cannot marshal object %w", err).Error())
+		return admitWithoutInjection(lumigo, objectMeta, fmt.Errorf("cannot marshal object %w", err).Error(), err)
+	}
+
+	if injectionOccurred && h.Auditor != nil && (request.DryRun == nil || !*request.DryRun) {
+		h.recordAuditEntry(ctx, request, lumigo, objectMeta, marshalled, &log)
 	}
 
 	if injectionOccurred {
@@ -169,11 +295,75 @@ func (h *LumigoInjectorWebhookHandler) Handle(ctx context.Context, request admis
 	return admission.PatchResponseFromRaw(request.Object.Raw, marshalled)
 }
 
+// admitWithoutInjection admits the resource without injecting it with the given message, unless the injection
+// of the Lumigo instance is strict, in which case the admission is denied, so that no workload runs untraced
+func admitWithoutInjection(lumigo *operatorv1alpha1.Lumigo, objectMeta *metav1.ObjectMeta, message string, reason error) admission.Response {
+	// The operator labels the resources it removes the injection from to be skipped, which is not denied either
+	isSkipped := objectMeta.Labels[mutation.LumigoAutoTraceLabelKey] == mutation.LumigoAutoTraceLabelSkipNextInjectorValue
+	if strict := lumigo.Spec.Tracing.Injection.Strict; strict != nil && *strict && !isSkipped {
+		return denied(rejectionReasonStrictInjection, fmt.Sprintf("The injection of Lumigo is strict in the '%s' namespace, and the resource cannot be injected: %s", lumigo.Namespace, reason.Error()))
+	}
+
+	return admission.Allowed(message)
+}
+
+// handleEphemeralContainers strips the Lumigo environment variables from the ephemeral containers added
+// to pods, e.g., by `kubectl debug`, if the pods ask for it; pods are otherwise never mutated, as their
+// workloads are injected instead
+func (h *LumigoInjectorWebhookHandler) handleEphemeralContainers(request admission.Request, log *logr.Logger) admission.Response {
+	if request.SubResource != mutation.EphemeralContainersSubresource {
+		return admission.Allowed("The Lumigo Injector webhook does not mutate pods")
+	}
+
+	pod := &corev1.Pod{}
+	if _, _, err := decoder.Decode(request.Object.Raw, nil, pod); err != nil {
+		return errored(rejectionReasonInvalidResource, fmt.Errorf("cannot parse resource into a pod: %w", err))
+	}
+
+	if !mutation.StripLumigoFromEphemeralContainers(pod) {
+		return admission.Allowed("No Lumigo environment variables to strip from the ephemeral containers")
+	}
+
+	marshalled, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Allowed(fmt.Errorf("cannot marshal object %w", err).Error())
+	}
+
+	log.Info("Stripped the Lumigo environment variables from the ephemeral containers of the pod", "namespace", pod.Namespace, "name", pod.Name)
+	return admission.PatchResponseFromRaw(request.Object.Raw, marshalled)
+}
+
+// recordAuditEntry records the mutation of the resource in the request; as the webhook cannot know
+// whether the request will eventually be accepted, the entry is recorded at admission time
+func (h *LumigoInjectorWebhookHandler) recordAuditEntry(ctx context.Context, request admission.Request, lumigo *operatorv1alpha1.Lumigo, objectMeta *metav1.ObjectMeta, marshalled []byte, log *logr.Logger) {
+	name := objectMeta.Name
+	if len(name) < 1 {
+		// The name of resources created with a generated name is not known yet
+		name = objectMeta.GenerateName
+	}
+
+	entry, err := audit.NewEntryFromJson(audit.ActionInject, audit.ActorInjectorWebhook, lumigo, audit.Target{
+		APIVersion: schema.GroupVersion{Group: request.Kind.Group, Version: request.Kind.Version}.String(),
+		Kind:       request.Kind.Kind,
+		Namespace:  lumigo.Namespace,
+		Name:       name,
+	}, request.Object.Raw, marshalled)
+	if err != nil {
+		log.Error(err, "Cannot create the audit entry of the mutation", "name", name)
+		return
+	}
+	entry.RequestedBy = request.UserInfo.Username
+
+	if err := h.Auditor.Record(ctx, entry); err != nil {
+		log.Error(err, "Cannot record the audit entry of the mutation", "name", name)
+	}
+}
+
 type resourceAdapter interface {
 	GetResource() runtime.Object
 	GetObjectMeta() *metav1.ObjectMeta
 	GetNamespace() string
-	InjectLumigoInto(mutation.Mutator) (bool, error)
+	InjectLumigoInto(mutation.Mutator) (*mutation.InjectionResult, error)
 	Marshal() ([]byte, error)
 }
 
@@ -196,8 +386,8 @@ func (r *resourceAdapterImpl) GetObjectMeta() *metav1.ObjectMeta {
 	return r.getObjectMeta()
 }
 
-func (r *resourceAdapterImpl) InjectLumigoInto(mutator mutation.Mutator) (bool, error) {
-	return mutator.InjectLumigoInto(r.resource)
+func (r *resourceAdapterImpl) InjectLumigoInto(mutator mutation.Mutator) (*mutation.InjectionResult, error) {
+	return mutator.InjectLumigoIntoWithResult(r.resource)
 }
 
 func (r *resourceAdapterImpl) Marshal() ([]byte, error) {
@@ -315,6 +505,25 @@ func newResourceAdatper(gvk metav1.GroupVersionKind, raw []byte) (resourceAdapte
 				},
 			}, nil
 		}
+	case "keda.sh/v1alpha1.ScaledJob":
+		{
+			resource := &unstructured.Unstructured{}
+
+			if err := resource.UnmarshalJSON(raw); err != nil {
+				return nil, fmt.Errorf("cannot parse resource into a %s: %w", sGVK, err)
+			}
+
+			return &kedaScaledJobAdapter{
+				resource: resource,
+				objectMeta: &metav1.ObjectMeta{
+					Name:         resource.GetName(),
+					GenerateName: resource.GetGenerateName(),
+					Namespace:    resource.GetNamespace(),
+					Labels:       resource.GetLabels(),
+					Annotations:  resource.GetAnnotations(),
+				},
+			}, nil
+		}
 	default:
 		{
 			return nil, nil
@@ -322,3 +531,42 @@ func newResourceAdatper(gvk metav1.GroupVersionKind, raw []byte) (resourceAdapte
 	}
 
 }
+
+// kedaScaledJobAdapter adapts the ScaledJobs of Keda, which are handled as unstructured objects.
+// The webhook reads and changes the labels of the object metadata the adapter returns, so the
+// labels and annotations are synced between that and the ScaledJob around each mutation.
+type kedaScaledJobAdapter struct {
+	resource   *unstructured.Unstructured
+	objectMeta *metav1.ObjectMeta
+}
+
+func (a *kedaScaledJobAdapter) GetResource() runtime.Object {
+	return a.resource
+}
+
+func (a *kedaScaledJobAdapter) GetNamespace() string {
+	return a.objectMeta.Namespace
+}
+
+func (a *kedaScaledJobAdapter) GetObjectMeta() *metav1.ObjectMeta {
+	return a.objectMeta
+}
+
+func (a *kedaScaledJobAdapter) InjectLumigoInto(mutator mutation.Mutator) (*mutation.InjectionResult, error) {
+	a.resource.SetLabels(a.objectMeta.Labels)
+	a.resource.SetAnnotations(a.objectMeta.Annotations)
+
+	result, err := mutator.InjectLumigoIntoWithResult(a.resource)
+
+	a.objectMeta.Labels = a.resource.GetLabels()
+	a.objectMeta.Annotations = a.resource.GetAnnotations()
+
+	return result, err
+}
+
+func (a *kedaScaledJobAdapter) Marshal() ([]byte, error) {
+	a.resource.SetLabels(a.objectMeta.Labels)
+	a.resource.SetAnnotations(a.objectMeta.Annotations)
+
+	return a.resource.MarshalJSON()
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package injector
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"testing"
 	"time"
@@ -28,8 +32,10 @@ import (
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/injectionannotations"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
 	"github.com/lumigo-io/lumigo-kubernetes-operator/webhooks/defaulter"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
@@ -39,6 +45,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
@@ -58,6 +65,8 @@ var (
 	testEnv   *envtest.Environment
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	injectorWebhookHandler *LumigoInjectorWebhookHandler
 )
 
 var lumigoApiVersion = fmt.Sprintf("%s/%s", operatorv1alpha1.GroupVersion.Group, operatorv1alpha1.GroupVersion.Version)
@@ -118,7 +127,7 @@ var _ = BeforeSuite(func() {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "..", "config", "crd", "bases")},
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "..", "config", "crd", "bases"), filepath.Join("testdata", "crds")},
 		ErrorIfCRDPathMissing: true,
 		WebhookInstallOptions: envtest.WebhookInstallOptions{
 			Paths: []string{filepath.Join("..", "..", "..", "..", "config", "webhooks")},
@@ -174,14 +183,15 @@ var _ = BeforeSuite(func() {
 	}).SetupWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
-	err = (&LumigoInjectorWebhookHandler{
+	injectorWebhookHandler = &LumigoInjectorWebhookHandler{
 		EventRecorder:                    mgr.GetEventRecorderFor(fmt.Sprintf("lumigo-operator.v%s", lumigoOperatorVersion)),
 		LumigoOperatorVersion:            lumigoOperatorVersion,
 		LumigoInjectorImage:              lumigoInjectorImage,
 		TelemetryProxyOtlpServiceUrl:     telemetryProxyOtlpServiceUrl,
 		TelemetryProxyOtlpLogsServiceUrl: telemetryProxyOtlpLogsServiceUrl,
 		Log:                              ctrl.Log.WithName("injector-webhook").WithName("Lumigo"),
-	}).SetupWebhookWithManager(mgr)
+	}
+	err = injectorWebhookHandler.SetupWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
 	err = (&operatorv1alpha1.Lumigo{}).SetupWebhookWithManager(mgr)
@@ -380,6 +390,197 @@ var _ = Context("Lumigo defaulter webhook", func() {
 
 	})
 
+	Context("with one paused Lumigo instance in the namespace", func() {
+
+		It("should not inject", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, true)
+			paused := true
+			lumigo.Spec.Paused = &paused
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter.ObjectMeta.Labels).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Volumes).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Containers).To(HaveLen(1))
+		})
+
+	})
+
+	Context("with one active Lumigo instance in the namespace whose telemetry-proxy is unreachable", func() {
+
+		It("should not inject", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, true)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = *statusActive.DeepCopy()
+			lumigo.Status.Conditions = append(lumigo.Status.Conditions, operatorv1alpha1.LumigoCondition{
+				Type:               operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable,
+				Status:             corev1.ConditionTrue,
+				Message:            "the telemetry-proxy has not been reachable for at least 1m0s",
+				LastUpdateTime:     metav1.NewTime(time.Now()),
+				LastTransitionTime: metav1.NewTime(time.Now()),
+			})
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter.ObjectMeta.Labels).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Volumes).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Containers).To(HaveLen(1))
+		})
+
+		It("should deny the deployment if the injection is strict", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, true)
+			strict := true
+			lumigo.Spec.Tracing.Injection.Strict = &strict
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = *statusActive.DeepCopy()
+			lumigo.Status.Conditions = append(lumigo.Status.Conditions, operatorv1alpha1.LumigoCondition{
+				Type:               operatorv1alpha1.LumigoConditionTypeTelemetryProxyUnreachable,
+				Status:             corev1.ConditionTrue,
+				Message:            "the telemetry-proxy has not been reachable for at least 1m0s",
+				LastUpdateTime:     metav1.NewTime(time.Now()),
+				LastTransitionTime: metav1.NewTime(time.Now()),
+			})
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, deployment)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("The injection of Lumigo is strict in the '%s' namespace", namespaceName))
+
+			Expect(testutil.ToFloat64(admissionRejectionsTotal.WithLabelValues(namespaceName, "Deployment", outcomeDenied, rejectionReasonStrictInjection))).To(Equal(1.0))
+		})
+
+	})
+
 	Context("with one active Lumigo instance in the namespace", func() {
 
 		It("should inject a minimal deployment", func() {
@@ -435,6 +636,16 @@ var _ = Context("Lumigo defaulter webhook", func() {
 			}
 
 			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, true))
+
+			// Both the deployment and its pods carry the injection annotations
+			for _, objectMeta := range []*metav1.ObjectMeta{&deploymentAfter.ObjectMeta, &deploymentAfter.Spec.Template.ObjectMeta} {
+				injectionAnnotations, err := injectionannotations.Get(objectMeta)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(injectionAnnotations).NotTo(BeNil())
+				Expect(injectionAnnotations.Version).To(Equal(lumigoOperatorVersion))
+				Expect(injectionAnnotations.InjectedAt).To(BeTemporally("~", time.Now(), time.Minute))
+				Expect(injectionAnnotations.Containers).To(Equal([]string{"myapp"}))
+			}
 		})
 
 		It("should inject a deployment with containers running not as root", func() {
@@ -568,65 +779,1650 @@ var _ = Context("Lumigo defaulter webhook", func() {
 			Expect(deploymentAfter.Spec.Template.Spec.InitContainers[0].SecurityContext.RunAsGroup).To(Equal(&group))
 		})
 
-	})
-
-	It("should not inject a minimal deployment with the lumigo.auto-trace label set to false", func() {
-		lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
-			SecretRef: operatorv1alpha1.KubernetesSecretRef{
-				Name: "doesnot",
-				Key:  "exist",
-			},
-		}, true, true)
-		Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+		It("should inject a deployment with the injector image pull policy and secrets", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.InjectorImagePullPolicy = corev1.PullAlways
+			lumigo.Spec.Tracing.Injection.InjectorImagePullSecrets = []corev1.LocalObjectReference{
+				{Name: "mirror-credentials"},
+				{Name: "app-credentials"},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
 
-		lumigo.Status = statusActive
-		k8sClient.Status().Update(ctx, lumigo)
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
 
-		name := "test-deployment"
+			name := "test-deployment"
 
-		deployment := &appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: namespaceName,
-				Labels: map[string]string{
-					mutation.LumigoAutoTraceLabelKey: "false",
-				},
-			},
-			Spec: appsv1.DeploymentSpec{
-				Selector: &metav1.LabelSelector{
-					MatchLabels: map[string]string{
-						"deployment": name,
-					},
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
 				},
-				Template: corev1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels: map[string]string{
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
 							"deployment": name,
 						},
 					},
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{
-							{
-								Name:  "myapp",
-								Image: "busybox",
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+							ImagePullSecrets: []corev1.LocalObjectReference{
+								{Name: "app-credentials"},
 							},
 						},
 					},
 				},
-			},
-		}
-		Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
 
-		deploymentAfter := &appsv1.Deployment{}
-		if err := k8sClient.Get(ctx, types.NamespacedName{
-			Namespace: namespaceName,
-			Name:      name,
-		}, deploymentAfter); err != nil {
-			Expect(err).NotTo(HaveOccurred())
-		}
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
 
-		Expect(deploymentAfter.ObjectMeta.Labels).To(BeEquivalentTo(map[string]string{
-			mutation.LumigoAutoTraceLabelKey: "false",
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+			Expect(deploymentAfter.Spec.Template.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{
+				{Name: "app-credentials"},
+				{Name: "mirror-credentials"},
+			}))
+			Expect(deploymentAfter.Spec.Template.ObjectMeta.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedImagePullSecretsAnnotationKey, "mirror-credentials"))
+
+			// Removing the injection removes only the pull secrets it has added
+			mutator, err := mutation.NewMutator(&injectorWebhookHandler.Log, &lumigo.Spec, lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, telemetryProxyOtlpLogsServiceUrl, nil, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			mutationOccurred, err := mutator.RemoveLumigoFromAppsV1Deployment(deploymentAfter)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mutationOccurred).To(BeTrue())
+			Expect(deploymentAfter.Spec.Template.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{
+				{Name: "app-credentials"},
+			}))
+			Expect(deploymentAfter.Spec.Template.ObjectMeta.Annotations).NotTo(HaveKey(mutation.LumigoInjectedImagePullSecretsAnnotationKey))
+		})
+
+		It("should inject a deployment with the extra env vars", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.ExtraEnv = []corev1.EnvVar{
+				{Name: "LUMIGO_DEBUG", Value: "true"},
+				{
+					Name: "OTEL_SERVICE_NAME",
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{
+							FieldPath: "metadata.labels['app']",
+						},
+					},
+				},
+				{Name: "LUMIGO_TRACER_TOKEN", Value: "not-the-token"},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+									Env: []corev1.EnvVar{
+										{Name: "LUMIGO_DEBUG", Value: "false"},
+										{Name: "APP_ENV", Value: "production"},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedExtraEnvAnnotationKey, "LUMIGO_DEBUG,OTEL_SERVICE_NAME,LUMIGO_TRACER_TOKEN"))
+
+			env := deploymentAfter.Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElements(
+				corev1.EnvVar{Name: "LUMIGO_DEBUG", Value: "true"},
+				corev1.EnvVar{Name: "APP_ENV", Value: "production"},
+				lumigo.Spec.Tracing.Injection.ExtraEnv[1],
+			))
+			Expect(env).NotTo(ContainElement(corev1.EnvVar{Name: "LUMIGO_TRACER_TOKEN", Value: "not-the-token"}))
+		})
+
+		It("should inject a deployment with the injector defaults not overridden by the Lumigo instance", func() {
+			debug := false
+			injectorWebhookHandler.InjectorDefaults = &mutation.InjectorDefaults{
+				Debug:           &debug,
+				EndpointTimeout: &metav1.Duration{Duration: 10 * time.Second},
+				Env: []corev1.EnvVar{
+					{Name: "LUMIGO_SWITCH_OFF", Value: "false"},
+				},
+			}
+			DeferCleanup(func() {
+				injectorWebhookHandler.InjectorDefaults = nil
+			})
+
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.ExtraEnv = []corev1.EnvVar{
+				{Name: "LUMIGO_DEBUG", Value: "true"},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+
+			env := deploymentAfter.Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElements(
+				corev1.EnvVar{Name: "LUMIGO_DEBUG", Value: "true"},
+				corev1.EnvVar{Name: "OTEL_EXPORTER_OTLP_TIMEOUT", Value: "10000"},
+				corev1.EnvVar{Name: "LUMIGO_SWITCH_OFF", Value: "false"},
+			))
+			Expect(env).NotTo(ContainElement(corev1.EnvVar{Name: "LUMIGO_DEBUG", Value: "false"}))
+			Expect(deploymentAfter.Spec.Template.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedExtraEnvAnnotationKey, "OTEL_EXPORTER_OTLP_TIMEOUT,LUMIGO_SWITCH_OFF,LUMIGO_DEBUG"))
+		})
+
+		It("should inject a deployment with the resource attributes of the cluster", func() {
+			injectorWebhookHandler.InjectorDefaults = (&mutation.InjectorDefaults{
+				ResourceAttributes: map[string]string{"deployment.environment": "staging"},
+			}).WithClusterResourceAttributes("my-cluster", "production")
+			DeferCleanup(func() {
+				injectorWebhookHandler.InjectorDefaults = nil
+			})
+
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "OTEL_RESOURCE_ATTRIBUTES", Value: "deployment.environment=staging,k8s.cluster.name=my-cluster"},
+			))
+		})
+
+		It("should inject a deployment that exports over gRPC when the telemetry-proxy receives it", func() {
+			injectorWebhookHandler.InjectorDefaults = (&mutation.InjectorDefaults{}).WithOtlpGrpcEndpoint("http://lumigo-telemetry-proxy.lumigo-system.svc.cluster.local:4317")
+			DeferCleanup(func() {
+				injectorWebhookHandler.InjectorDefaults = nil
+			})
+
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: "OTEL_EXPORTER_OTLP_PROTOCOL", Value: "grpc"},
+				corev1.EnvVar{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Value: "http://lumigo-telemetry-proxy.lumigo-system.svc.cluster.local:4317"},
+			))
+		})
+
+		It("should inject a deployment with the sampling of the Lumigo instance layered over the injector defaults", func() {
+			clusterPercentage := int32(10)
+			clusterParentBased := false
+			injectorWebhookHandler.InjectorDefaults = &mutation.InjectorDefaults{
+				Sampling: &operatorv1alpha1.SamplingSpec{
+					Percentage:  &clusterPercentage,
+					ParentBased: &clusterParentBased,
+				},
+			}
+			DeferCleanup(func() {
+				injectorWebhookHandler.InjectorDefaults = nil
+			})
+
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			namespacePercentage := int32(50)
+			lumigo.Spec.Tracing.Sampling.Percentage = &namespacePercentage
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+
+			// The percentage of the Lumigo instance overrides the cluster-wide one, but not whether the sampling is parent-based
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: mutation.OtelTracesSamplerEnvVarName, Value: "traceidratio"},
+				corev1.EnvVar{Name: mutation.OtelTracesSamplerArgEnvVarName, Value: "0.5"},
+			))
+		})
+
+		It("should inject a deployment with the propagators", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Propagators = []operatorv1alpha1.Propagator{
+				operatorv1alpha1.PropagatorTraceContext,
+				operatorv1alpha1.PropagatorBaggage,
+				operatorv1alpha1.PropagatorXRay,
+			}
+			lumigo.Spec.Tracing.Injection.ExtraEnv = []corev1.EnvVar{{Name: "LUMIGO_DEBUG", Value: "true"}}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+									Env: []corev1.EnvVar{
+										{Name: mutation.OtelPropagatorsEnvVarName, Value: "b3"},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedExtraEnvAnnotationKey, "OTEL_PROPAGATORS,LUMIGO_DEBUG"))
+
+			env := deploymentAfter.Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: mutation.OtelPropagatorsEnvVarName, Value: "tracecontext,baggage,xray"}))
+			Expect(env).NotTo(ContainElement(corev1.EnvVar{Name: mutation.OtelPropagatorsEnvVarName, Value: "b3"}))
+		})
+
+		It("should inject a deployment with the payload capture settings", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			captureRequestBodies := false
+			maxEntrySize := int32(512)
+			lumigo.Spec.Tracing.Injection.PayloadCapture = operatorv1alpha1.PayloadCaptureSpec{
+				MaxEntrySize:         &maxEntrySize,
+				CaptureRequestBodies: &captureRequestBodies,
+				AllowedHeaders:       []operatorv1alpha1.HeaderName{"Content-Type", "x-request-id"},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+
+			env := deploymentAfter.Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElements(
+				corev1.EnvVar{Name: mutation.LumigoMaxEntrySizeEnvVarName, Value: "512"},
+				corev1.EnvVar{Name: mutation.LumigoSecretMaskingRequestBodiesEnvVarName, Value: "all"},
+				corev1.EnvVar{Name: mutation.LumigoSecretMaskingRequestHeadersEnvVarName, Value: `["^(?!(content-type|x-request-id)$).*$"]`},
+				corev1.EnvVar{Name: mutation.LumigoSecretMaskingResponseHeadersEnvVarName, Value: `["^(?!(content-type|x-request-id)$).*$"]`},
+			))
+			Expect(env).NotTo(ContainElement(HaveField("Name", mutation.LumigoSecretMaskingResponseBodiesEnvVarName)))
+		})
+
+		It("should inject a deployment with the domains to skip", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.SkipDomains = []operatorv1alpha1.Domain{"vault.internal.example.com", "*.okta.com"}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedExtraEnvAnnotationKey, mutation.LumigoFilterHttpClientEndpointsEnvVarName))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  mutation.LumigoFilterHttpClientEndpointsEnvVarName,
+				Value: `["^[a-z]+://(vault[.]internal[.]example[.]com|[-a-z0-9.]+[.]okta[.]com)(:[0-9]+)?([/?#].*)?$"]`,
+			}))
+		})
+
+		It("should inject a deployment with the service names of the template", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.ServiceNameTemplate = `{{ .WorkloadName }}-{{ .ContainerName }}-{{ index .Labels "tier" }}`
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+								"tier":       "backend",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+								{
+									Name:  "sidecar",
+									Image: "busybox",
+									Env: []corev1.EnvVar{
+										{Name: "OTEL_SERVICE_NAME", Value: "my-sidecar"},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedServiceNameAnnotationKey, "myapp"))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OTEL_SERVICE_NAME", Value: "test-deployment-myapp-backend"}))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[1].Env).To(ContainElement(corev1.EnvVar{Name: "OTEL_SERVICE_NAME", Value: "my-sidecar"}))
+		})
+
+		It("should inject the debug env vars into a deployment with the debug annotation, and remove them when it is dropped", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+					Annotations: map[string]string{
+						mutation.LumigoDebugAnnotationKey: "true",
+					},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter)).To(Succeed())
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Annotations).To(HaveKeyWithValue(mutation.LumigoInjectedDebugAnnotationKey, "myapp"))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				corev1.EnvVar{Name: mutation.LumigoDebugEnvVarName, Value: "true"},
+				corev1.EnvVar{Name: mutation.OtelLogLevelEnvVarName, Value: "debug"},
+			))
+
+			delete(deploymentAfter.Annotations, mutation.LumigoDebugAnnotationKey)
+			Expect(k8sClient.Update(ctx, deploymentAfter)).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter)).To(Succeed())
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Annotations).NotTo(HaveKey(mutation.LumigoInjectedDebugAnnotationKey))
+			for _, envVarName := range []string{mutation.LumigoDebugEnvVarName, mutation.OtelLogLevelEnvVarName} {
+				Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", envVarName)))
+			}
+		})
+
+		It("should inject a deployment with the token projected from the token secret", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.TokenInjectionMode = operatorv1alpha1.TokenInjectionModeProjectedSecret
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+
+			podSpec := deploymentAfter.Spec.Template.Spec
+			Expect(podSpec.Volumes).To(ContainElement(HaveField("Name", mutation.LumigoTracerTokenVolumeName)))
+			Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      mutation.LumigoTracerTokenVolumeName,
+				ReadOnly:  true,
+				MountPath: mutation.LumigoTracerTokenVolumeMountPoint,
+			}))
+			Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: mutation.LumigoTracerTokenFileEnvVarName, Value: mutation.LumigoTracerTokenFilePath}))
+			Expect(podSpec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", mutation.LumigoTracerTokenEnvVarName)))
+		})
+
+		It("should inject the deployments matched by a route with the token of the route", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Routes = []operatorv1alpha1.TracingRoute{
+				{
+					Name: "payments",
+					Selector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "payments"},
+					},
+					LumigoToken: operatorv1alpha1.Credentials{
+						SecretRef: operatorv1alpha1.KubernetesSecretRef{
+							Name: "lumigo-payments",
+							Key:  "token",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			for name, team := range map[string]string{"payments-deployment": "payments", "checkout-deployment": "checkout"} {
+				deployment := &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: namespaceName,
+						Labels: map[string]string{
+							"team": team,
+						},
+					},
+					Spec: appsv1.DeploymentSpec{
+						Selector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{
+									"deployment": name,
+								},
+							},
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{
+									{
+										Name:  "myapp",
+										Image: "busybox",
+									},
+								},
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+			}
+
+			optional := true
+			expectedSecretNames := map[string]string{
+				"payments-deployment": "lumigo-payments",
+				"checkout-deployment": "lumigosecret",
+			}
+			for name, expectedSecretName := range expectedSecretNames {
+				deploymentAfter := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: name}, deploymentAfter)).To(Succeed())
+				Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+				Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+					Name: mutation.LumigoTracerTokenEnvVarName,
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: expectedSecretName,
+							},
+							Key:      "token",
+							Optional: &optional,
+						},
+					},
+				}))
+			}
+		})
+
+		It("should project the token of the route into the deployments matched by a route", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.TokenInjectionMode = operatorv1alpha1.TokenInjectionModeProjectedSecret
+			lumigo.Spec.Tracing.Routes = []operatorv1alpha1.TracingRoute{
+				{
+					Name: "payments",
+					Selector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "payments"},
+					},
+					LumigoToken: operatorv1alpha1.Credentials{
+						SecretRef: operatorv1alpha1.KubernetesSecretRef{
+							Name: "lumigo-payments",
+							Key:  "token",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+					Labels: map[string]string{
+						"team": "payments",
+					},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: name}, deploymentAfter)).To(Succeed())
+
+			podSpec := deploymentAfter.Spec.Template.Spec
+			Expect(podSpec.Volumes).To(ContainElement(SatisfyAll(
+				HaveField("Name", mutation.LumigoTracerTokenVolumeName),
+				HaveField("Projected.Sources", ConsistOf(HaveField("Secret.Items", ConsistOf(corev1.KeyToPath{
+					Key:  mutation.LumigoTracerTokenSecretKeyOfRoute("payments"),
+					Path: mutation.LumigoTracerTokenSecretKey,
+				})))),
+			)))
+			Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: mutation.LumigoTracerTokenFileEnvVarName, Value: mutation.LumigoTracerTokenFilePath}))
+		})
+
+		It("should not inject a deployment that selects nodes with an unsupported architecture", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+							NodeSelector: map[string]string{
+								mutation.KubernetesArchLabelKey: "s390x",
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Volumes).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(BeEmpty())
+		})
+
+		It("should not inject a deployment whose pods run on Windows", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+							OS: &corev1.PodOS{
+								Name: corev1.Windows,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Volumes).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(BeEmpty())
+		})
+
+		It("should defer the injection of a paused deployment until it is resumed", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Paused: true,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter)).Should(Succeed())
+
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(deploymentAfter.ObjectMeta.Labels).NotTo(HaveKey(mutation.LumigoAutoTraceLabelKey))
+
+			// Resuming the deployment injects it in the same update
+			deploymentAfter.Spec.Paused = false
+			Expect(k8sClient.Update(ctx, deploymentAfter)).Should(Succeed())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter)).Should(Succeed())
+
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			Expect(deploymentAfter.ObjectMeta.Labels[mutation.LumigoAutoTraceLabelKey]).To(HavePrefix(mutation.LumigoAutoTraceLabelVersionPrefixValue))
+		})
+
+		It("should not inject a daemonset if daemonsets are not among the workload types", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.WorkloadTypes = []operatorv1alpha1.WorkloadType{
+				operatorv1alpha1.WorkloadTypeDeployment,
+				operatorv1alpha1.WorkloadTypeStatefulSet,
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-daemonset"
+
+			daemonSet := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DaemonSetSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"daemonset": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"daemonset": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "log-shipper",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, daemonSet)).Should(Succeed())
+
+			daemonSetAfter := &appsv1.DaemonSet{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, daemonSetAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(daemonSetAfter.Labels).NotTo(HaveKey(mutation.LumigoAutoTraceLabelKey))
+			Expect(daemonSetAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(daemonSetAfter.Spec.Template.Spec.Containers[0].Env).To(BeEmpty())
+		})
+
+		It("should not inject a deployment created before the cutoff of the workloads to inject", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.OnlyWorkloadsCreatedAfter = &metav1.Time{Time: time.Now().Add(time.Hour)}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter)).Should(Succeed())
+
+			Expect(deploymentAfter.Labels).NotTo(HaveKey(mutation.LumigoAutoTraceLabelKey))
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		})
+
+		It("should inject only the containers of a deployment whose images match the image patterns", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.ImagePatterns = operatorv1alpha1.ImagePatternsSpec{
+				Allow: []string{"internal-registry/payments/*"},
+				Deny:  []string{"regex:.*/(nginx|redis|postgres)(:.*)?"},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "internal-registry/payments/api:1.2.3",
+								},
+								{
+									Name:  "proxy",
+									Image: "internal-registry/payments/nginx:1.25",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter.Labels).To(HaveKey(mutation.LumigoAutoTraceLabelKey))
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(ContainElement(mutation.BeTheLumigoInjectorContainer(lumigoInjectorImage)))
+			Expect(mutation.InjectedContainerNames(&deploymentAfter.Spec.Template.Spec)).To(Equal([]string{"myapp"}))
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[1].Env).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[1].VolumeMounts).To(BeEmpty())
+		})
+
+		It("should not inject a deployment none of whose containers have images matching the image patterns", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.ImagePatterns = operatorv1alpha1.ImagePatternsSpec{
+				Allow: []string{"internal-registry/payments/*"},
+			}
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "cache",
+									Image: "redis:7",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter.Labels).NotTo(HaveKey(mutation.LumigoAutoTraceLabelKey))
+			Expect(deploymentAfter.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(deploymentAfter.Spec.Template.Spec.Containers[0].Env).To(BeEmpty())
+		})
+
+		It("should inject a deployment with a node affinity on the supported architectures", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, false)
+			lumigo.Spec.Tracing.Injection.UnsupportedArchitecturePolicy = operatorv1alpha1.UnsupportedArchitecturePolicyNodeAffinity
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-deployment"
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespaceName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"deployment": name,
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+			deploymentAfter := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, deploymentAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(deploymentAfter).To(mutation.BeInstrumentedWithLumigo(lumigoOperatorVersion, lumigoInjectorImage, telemetryProxyOtlpServiceUrl, false))
+			Expect(deploymentAfter.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(Equal([]corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      mutation.KubernetesArchLabelKey,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   mutation.LumigoInjectorSupportedArchitectures,
+						},
+					},
+				},
+			}))
+		})
+
+		It("should inject the pod template of a Keda ScaledJob and leave its pause annotation alone", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, true)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			name := "test-scaledjob"
+
+			scaledJob := &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      name,
+						"namespace": namespaceName,
+						"annotations": map[string]interface{}{
+							mutation.KedaPausedAnnotationKey: "true",
+						},
+					},
+					"spec": map[string]interface{}{
+						"jobTargetRef": map[string]interface{}{
+							"template": map[string]interface{}{
+								"spec": map[string]interface{}{
+									"containers": []interface{}{
+										map[string]interface{}{
+											"name":  "myjob",
+											"image": "busybox",
+										},
+									},
+									"restartPolicy": "Never",
+								},
+							},
+						},
+						"triggers": []interface{}{
+							map[string]interface{}{
+								"type": "cron",
+							},
+						},
+					},
+				},
+			}
+			scaledJob.SetGroupVersionKind(mutation.KedaScaledJobGroupVersionKind)
+			Expect(k8sClient.Create(ctx, scaledJob)).Should(Succeed())
+
+			scaledJobAfter := &unstructured.Unstructured{}
+			scaledJobAfter.SetGroupVersionKind(mutation.KedaScaledJobGroupVersionKind)
+			if err := k8sClient.Get(ctx, types.NamespacedName{
+				Namespace: namespaceName,
+				Name:      name,
+			}, scaledJobAfter); err != nil {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(scaledJobAfter.GetLabels()).To(HaveKeyWithValue(mutation.LumigoAutoTraceLabelKey, mutation.LumigoAutoTraceLabelVersionPrefixValue+lumigoOperatorVersion[0:7]))
+			Expect(scaledJobAfter.GetAnnotations()).To(HaveKeyWithValue(mutation.KedaPausedAnnotationKey, "true"))
+			// The triggers and the other fields of the ScaledJob are not known to the operator, but are preserved
+			triggers, _, err := unstructured.NestedSlice(scaledJobAfter.Object, "spec", "triggers")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(triggers).To(HaveLen(1))
+
+			podTemplate, found, err := unstructured.NestedMap(scaledJobAfter.Object, "spec", "jobTargetRef", "template")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+
+			podTemplateSpec := &corev1.PodTemplateSpec{}
+			Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(podTemplate, podTemplateSpec)).To(Succeed())
+			Expect(podTemplateSpec.Spec.InitContainers).To(ContainElement(mutation.BeTheLumigoInjectorContainer(lumigoInjectorImage)))
+			Expect(podTemplateSpec.Spec.Containers[0].Env).To(ContainElement(HaveField("Name", mutation.LumigoTracerTokenEnvVarName)))
+		})
+
+	})
+
+	It("should not inject a minimal deployment with the lumigo.auto-trace label set to false", func() {
+		lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+			SecretRef: operatorv1alpha1.KubernetesSecretRef{
+				Name: "doesnot",
+				Key:  "exist",
+			},
+		}, true, true)
+		Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+		lumigo.Status = statusActive
+		k8sClient.Status().Update(ctx, lumigo)
+
+		name := "test-deployment"
+
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespaceName,
+				Labels: map[string]string{
+					mutation.LumigoAutoTraceLabelKey: "false",
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"deployment": name,
+					},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"deployment": name,
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "myapp",
+								Image: "busybox",
+							},
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+
+		deploymentAfter := &appsv1.Deployment{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: namespaceName,
+			Name:      name,
+		}, deploymentAfter); err != nil {
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(deploymentAfter.ObjectMeta.Labels).To(BeEquivalentTo(map[string]string{
+			mutation.LumigoAutoTraceLabelKey: "false",
 		}))
 		Expect(deploymentAfter.Spec.Template.ObjectMeta.Labels).To(BeEquivalentTo(map[string]string{
 			"deployment": name,
@@ -636,6 +2432,196 @@ var _ = Context("Lumigo defaulter webhook", func() {
 		Expect(deploymentAfter.Spec.Template.Spec.Containers).To(HaveLen(1))
 	})
 
+	Context("previewing the injection", func() {
+
+		previewInjection := func(workload interface{}, query string) (int, *InjectionPreview) {
+			body, err := json.Marshal(workload)
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder := httptest.NewRecorder()
+			(&InjectionPreviewHandler{Webhook: injectorWebhookHandler}).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/injection-preview"+query, bytes.NewReader(body)))
+			if recorder.Code != http.StatusOK {
+				return recorder.Code, nil
+			}
+
+			preview := &InjectionPreview{}
+			Expect(json.Unmarshal(recorder.Body.Bytes(), preview)).To(Succeed())
+			return recorder.Code, preview
+		}
+
+		newDeployment := func(namespace string) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-deployment",
+					Namespace: namespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "myapp",
+									Image: "busybox",
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		It("should tell that a deployment would not be injected without a Lumigo instance in the namespace", func() {
+			code, preview := previewInjection(newDeployment(namespaceName), "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(preview.Injected).To(BeFalse())
+			Expect(preview.Reason).To(ContainSubstring("No Lumigo configuration"))
+			Expect(preview.Patches).To(BeEmpty())
+		})
+
+		It("should return the patches of the injection of a deployment without creating it", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, true)
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			lumigo.Status = statusActive
+			k8sClient.Status().Update(ctx, lumigo)
+
+			Eventually(func() bool {
+				_, preview := previewInjection(newDeployment(""), "?namespace="+namespaceName)
+				return preview != nil && preview.Injected
+			}).Should(BeTrue())
+
+			_, preview := previewInjection(newDeployment(""), "?namespace="+namespaceName)
+			Expect(preview.Namespace).To(Equal(namespaceName))
+			Expect(preview.Kind).To(Equal("Deployment"))
+			Expect(preview.Name).To(Equal("test-deployment"))
+			Expect(string(preview.Patches)).To(ContainSubstring(mutation.LumigoTracerTokenEnvVarName))
+			Expect(string(preview.Patches)).To(ContainSubstring(lumigoInjectorImage))
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: "test-deployment"}, &appsv1.Deployment{})).NotTo(Succeed())
+		})
+
+		It("should tell that a deployment would be denied if the injection is strict and it cannot be injected", func() {
+			lumigo := newLumigo(namespaceName, "lumigo1", operatorv1alpha1.Credentials{
+				SecretRef: operatorv1alpha1.KubernetesSecretRef{
+					Name: "lumigosecret",
+					Key:  "token",
+				},
+			}, true, true)
+			strict := true
+			lumigo.Spec.Tracing.Injection.Strict = &strict
+			Expect(k8sClient.Create(ctx, lumigo)).Should(Succeed())
+
+			// Without an active status, the Lumigo instance is not active and the deployment cannot be injected
+			Eventually(func() bool {
+				_, preview := previewInjection(newDeployment(namespaceName), "")
+				return preview != nil && preview.Denied
+			}).Should(BeTrue())
+
+			code, preview := previewInjection(newDeployment(namespaceName), "")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(preview.Injected).To(BeFalse())
+			Expect(preview.Reason).To(ContainSubstring("The injection of Lumigo is strict in the '%s' namespace", namespaceName))
+			Expect(preview.Patches).To(BeEmpty())
+		})
+
+		It("should reject workloads without a namespace", func() {
+			code, _ := previewInjection(newDeployment(""), "")
+			Expect(code).To(Equal(http.StatusBadRequest))
+		})
+
+	})
+
+	It("should strip the Lumigo environment variables from the ephemeral containers of annotated pods", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-pod",
+				Namespace: namespaceName,
+				Annotations: map[string]string{
+					mutation.LumigoStripEphemeralContainersEnvAnnotationKey: "true",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "myapp",
+						Image: "busybox",
+						Env: []corev1.EnvVar{
+							{Name: mutation.LdPreloadEnvVarName, Value: mutation.LdPreloadEnvVarValue},
+							{Name: mutation.LumigoEndpointEnvVarName, Value: telemetryProxyOtlpServiceUrl},
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+
+		pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+			{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Name:  "debugger",
+					Image: "busybox",
+					Env: []corev1.EnvVar{
+						{Name: mutation.LdPreloadEnvVarName, Value: mutation.LdPreloadEnvVarValue},
+						{Name: mutation.LumigoEndpointEnvVarName, Value: telemetryProxyOtlpServiceUrl},
+						{Name: "DEBUG", Value: "true"},
+					},
+				},
+				TargetContainerName: "myapp",
+			},
+		}
+		Expect(k8sClient.SubResource(mutation.EphemeralContainersSubresource).Update(ctx, pod)).Should(Succeed())
+
+		podAfter := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: "test-pod"}, podAfter)).To(Succeed())
+		Expect(podAfter.Spec.EphemeralContainers[0].Env).To(ConsistOf(corev1.EnvVar{Name: "DEBUG", Value: "true"}))
+		// The containers of pods are never mutated
+		Expect(podAfter.Spec.Containers[0].Env).To(HaveLen(2))
+	})
+
+	It("should not strip the environment variables from the ephemeral containers of pods that are not annotated", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-pod",
+				Namespace: namespaceName,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "myapp",
+						Image: "busybox",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+
+		pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+			{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Name:  "debugger",
+					Image: "busybox",
+					Env: []corev1.EnvVar{
+						{Name: mutation.LdPreloadEnvVarName, Value: mutation.LdPreloadEnvVarValue},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.SubResource(mutation.EphemeralContainersSubresource).Update(ctx, pod)).Should(Succeed())
+
+		podAfter := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespaceName, Name: "test-pod"}, podAfter)).To(Succeed())
+		Expect(podAfter.Spec.EphemeralContainers[0].Env).To(HaveLen(1))
+	})
+
 })
 
 func newLumigo(namespace string, name string, lumigoToken operatorv1alpha1.Credentials, injectionEnabled bool, loggingEnabled bool) *operatorv1alpha1.Lumigo {
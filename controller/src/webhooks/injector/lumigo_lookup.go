@@ -0,0 +1,165 @@
+package injector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/mutation"
+)
+
+// The default freshness window of the Lumigo instances looked up by the webhook; the memoized instances
+// are invalidated as soon as the informers notice changes, so this is merely a safety net against missed events
+const DefaultLumigoLookupFreshness = time.Minute
+
+// lumigoLookup retrieves the Lumigo instance of a namespace from the given reader, which is meant to be
+// the informer cache of the manager, and memoizes the result, together with the mutator built out of it,
+// so that bursts of admissions in the same namespace do no per-request work besides the mutation itself.
+type lumigoLookup struct {
+	reader    client.Reader
+	freshness time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*lumigoLookupEntry
+}
+
+type lumigoLookupEntry struct {
+	// nil if the namespace has no Lumigo instance
+	lumigo    *operatorv1alpha1.Lumigo
+	fetchedAt time.Time
+
+	// The mutator is built lazily, and rebuilt if the configuration it is built from changes
+	mutator           mutation.Mutator
+	mutatorConfigHash string
+}
+
+func newLumigoLookup(reader client.Reader, freshness time.Duration) *lumigoLookup {
+	return &lumigoLookup{
+		reader:    reader,
+		freshness: freshness,
+		entries:   map[string]*lumigoLookupEntry{},
+	}
+}
+
+// InvalidateOnChanges drops the memoized entry of a namespace whenever its Lumigo instance, or one of its
+// secrets, e.g., the one with the Lumigo token, is created, updated or deleted.
+func (l *lumigoLookup) InvalidateOnChanges(ctx context.Context, informers cache.Informers) error {
+	handler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			l.invalidateNamespaceOf(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			l.invalidateNamespaceOf(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			l.invalidateNamespaceOf(obj)
+		},
+	}
+
+	for _, obj := range []client.Object{&operatorv1alpha1.Lumigo{}, &corev1.Secret{}} {
+		informer, err := informers.GetInformer(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("cannot retrieve the informer of %T: %w", obj, err)
+		}
+		informer.AddEventHandler(handler)
+	}
+
+	return nil
+}
+
+// GetLumigoOfNamespace returns a copy of the Lumigo instance in the given namespace, or nil if there is none.
+func (l *lumigoLookup) GetLumigoOfNamespace(ctx context.Context, namespace string) (*operatorv1alpha1.Lumigo, error) {
+	entry, err := l.getEntry(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.lumigo.DeepCopy(), nil
+}
+
+// GetMutatorOfNamespace returns the mutator for the Lumigo instance of the given namespace, building it
+// with newMutator only if the Lumigo instance or the injector image have changed since the last admission.
+func (l *lumigoLookup) GetMutatorOfNamespace(lumigo *operatorv1alpha1.Lumigo, lumigoInjectorImage string, newMutator func() (mutation.Mutator, error)) (mutation.Mutator, error) {
+	configHash := fmt.Sprintf("%s/%s/%s", lumigo.UID, lumigo.ResourceVersion, lumigoInjectorImage)
+
+	l.mutex.Lock()
+	entry, ok := l.entries[lumigo.Namespace]
+	if ok && entry.mutator != nil && entry.mutatorConfigHash == configHash {
+		l.mutex.Unlock()
+		return entry.mutator, nil
+	}
+	l.mutex.Unlock()
+
+	mutator, err := newMutator()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mutex.Lock()
+	// The entry may have been invalidated or replaced in the meantime, in which case the mutator is not memoized
+	if current, ok := l.entries[lumigo.Namespace]; ok && current == entry {
+		entry.mutator = mutator
+		entry.mutatorConfigHash = configHash
+	}
+	l.mutex.Unlock()
+
+	return mutator, nil
+}
+
+func (l *lumigoLookup) getEntry(ctx context.Context, namespace string) (*lumigoLookupEntry, error) {
+	now := time.Now()
+
+	l.mutex.Lock()
+	entry, ok := l.entries[namespace]
+	l.mutex.Unlock()
+
+	if ok && now.Sub(entry.fetchedAt) < l.freshness {
+		lumigoLookupsTotal.WithLabelValues(lookupSourceMemoized).Inc()
+		return entry, nil
+	}
+
+	lumigoLookupsTotal.WithLabelValues(lookupSourceReader).Inc()
+
+	lumigos := &operatorv1alpha1.LumigoList{}
+	if err := l.reader.List(ctx, lumigos, &client.ListOptions{
+		Namespace: namespace,
+	}); err != nil {
+		return nil, err
+	}
+
+	entry = &lumigoLookupEntry{
+		fetchedAt: now,
+	}
+	if len(lumigos.Items) > 0 {
+		entry.lumigo = &lumigos.Items[0]
+	}
+
+	if l.freshness > 0 {
+		l.mutex.Lock()
+		l.entries[namespace] = entry
+		l.mutex.Unlock()
+	}
+
+	return entry, nil
+}
+
+func (l *lumigoLookup) invalidateNamespaceOf(obj interface{}) {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+
+	l.mutex.Lock()
+	delete(l.entries, object.GetNamespace())
+	l.mutex.Unlock()
+}
@@ -0,0 +1,52 @@
+package injector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	outcomeAllowed = "allowed"
+	outcomeMutated = "mutated"
+	outcomeDenied  = "denied"
+	outcomeErrored = "errored"
+
+	lookupSourceMemoized = "memoized"
+	lookupSourceReader   = "cache"
+)
+
+var (
+	// The time it takes the webhook to handle an admission request, by kind of the admitted resource and outcome
+	admissionDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "lumigo_injector_webhook_admission_duration_seconds",
+			Help:    "Time taken by the Lumigo injector webhook to handle admission requests",
+			Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+		},
+		[]string{"kind", "outcome"},
+	)
+
+	// The admissions that the webhook denied or errored on, by namespace and kind of the admitted resource,
+	// outcome and reason
+	admissionRejectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "lumigo_injector_webhook_admission_rejections_total",
+			Help: "Admission requests denied or errored on by the Lumigo injector webhook",
+		},
+		[]string{"namespace", "kind", "outcome", "reason"},
+	)
+
+	// The lookups of Lumigo instances, by whether they have been served from the memoized instances
+	// or from the informer cache of the manager
+	lumigoLookupsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "lumigo_injector_webhook_lumigo_lookups_total",
+			Help: "Lookups of Lumigo instances by the Lumigo injector webhook",
+		},
+		[]string{"source"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(admissionDurationSeconds, admissionRejectionsTotal, lumigoLookupsTotal)
+}
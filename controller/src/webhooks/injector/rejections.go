@@ -0,0 +1,81 @@
+package injector
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+)
+
+// The reasons of the admissions that the webhook denies or errors on, which label the rejections metric
+const (
+	rejectionReasonInvalidResource    = "InvalidResource"
+	rejectionReasonLumigoLookupFailed = "LumigoLookupFailed"
+	rejectionReasonStrictInjection    = "StrictInjection"
+	rejectionReasonUnknown            = "Unknown"
+)
+
+// errored is like admission.Errored, with the reason of the error
+func errored(reason string, err error) admission.Response {
+	response := admission.Errored(http.StatusInternalServerError, err)
+	response.Result.Reason = metav1.StatusReason(reason)
+	return response
+}
+
+// denied is like admission.Denied, but it tells the reason of the denial apart from its message, which the API
+// server returns to the client
+func denied(reason string, message string) admission.Response {
+	response := admission.Denied(reason)
+	response.Result.Message = message
+	return response
+}
+
+// responseMessage returns the message of the response; admission.Allowed and admission.Denied put it in the reason
+func responseMessage(response admission.Response) string {
+	if response.Result == nil {
+		return ""
+	}
+
+	if len(response.Result.Message) > 0 {
+		return response.Result.Message
+	}
+
+	return string(response.Result.Reason)
+}
+
+// recordRejection logs and counts the admission that the webhook denied or errored on and, if enabled, records a
+// Warning event on the admitted resource, as the rejections are otherwise invisible to the owners of the resource
+func recordRejection(request admission.Request, response admission.Response, outcome string, eventRecorder record.EventRecorder, log *logr.Logger) {
+	reason := rejectionReasonUnknown
+	if response.Result != nil && len(response.Result.Message) > 0 && len(response.Result.Reason) > 0 {
+		reason = string(response.Result.Reason)
+	}
+	message := responseMessage(response)
+
+	admissionRejectionsTotal.WithLabelValues(request.Namespace, request.Kind.Kind, outcome, reason).Inc()
+
+	logValues := []interface{}{"namespace", request.Namespace, "kind", request.Kind.Kind, "name", request.Name, "operation", request.Operation, "outcome", outcome, "reason", reason}
+	if outcome == outcomeErrored {
+		log.Error(fmt.Errorf("%s", message), "Admission errored", logValues...)
+	} else {
+		log.Info("Admission denied", append(logValues, "message", message)...)
+	}
+
+	// Events need the name of the resource they are about, which resources created with a generated name lack
+	if eventRecorder == nil || len(request.Name) < 1 || (request.DryRun != nil && *request.DryRun) {
+		return
+	}
+
+	operatorv1alpha1.RecordAdmissionRejectedEvent(eventRecorder, &corev1.ObjectReference{
+		APIVersion: metav1.GroupVersion{Group: request.Kind.Group, Version: request.Kind.Version}.String(),
+		Kind:       request.Kind.Kind,
+		Namespace:  request.Namespace,
+		Name:       request.Name,
+	}, outcome, message)
+}
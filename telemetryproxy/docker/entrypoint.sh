@@ -3,10 +3,20 @@
 set -eo pipefail
 
 readonly OTELCOL_CONFIG_FILE_PATH="/lumigo/etc/otelcol/config.yaml"
+# The new configurations are rendered next to the current ones, and swapped in only once validated
+readonly OTELCOL_NEW_CONFIG_FILE_PATH="/lumigo/etc/otelcol/config.yaml.new"
+# The persistent sending queues of the exporters, which survive the reloads of the configurations
+readonly OTELCOL_SENDING_QUEUE_DIRECTORY_PATH="/lumigo/etc/otelcol/sending-queue"
 readonly OTELCOL_CONFIG_TEMPLATE_FILE_PATH="/lumigo/etc/otelcol-config.yaml.tpl"
 readonly GENERATION_CONFIG_FILE_PATH="/lumigo/etc/otelcol/generation-config.json"
 readonly NAMESPACES_FILE_PATH="/lumigo/etc/namespaces/namespaces_to_monitor.json"
-readonly NAMESPACES_FILE_SHA_PATH="${NAMESPACES_FILE_PATH}.sha1"
+# The checksum is not stored next to the namespaces file, which is read-only when mounted from a secret
+readonly NAMESPACES_FILE_SHA_PATH="/lumigo/etc/otelcol/namespaces_to_monitor.json.sha1"
+# Whether the configurations in use collect the cluster-wide telemetry, to detect the changes of leadership
+readonly CLUSTER_COLLECTOR_STATE_PATH="/lumigo/etc/otelcol/cluster_collector"
+
+# Replaces the Lumigo tokens in the debug output
+readonly REDACT_TOKENS_EXPRESSION='s/t_[[:xdigit:]]{21}/t_***/g'
 
 readonly DEFAULT_MEMORY_LIMIT_MIB=4000
 readonly NO_MEMORY_LIMIT=9223372036854771712
@@ -56,15 +66,45 @@ echo "{
 }" > "${GENERATION_CONFIG_FILE_PATH}"
 
 if [ "${debug}" == 'true' ]; then
-    echo "Generation configurations: $(cat ${GENERATION_CONFIG_FILE_PATH})"
+    echo "Generation configurations: $(sed -E -e "${REDACT_TOKENS_EXPRESSION}" ${GENERATION_CONFIG_FILE_PATH})"
 fi
 
-function generate_configs() {
-    gomplate -f "${OTELCOL_CONFIG_TEMPLATE_FILE_PATH}" -d "config=${GENERATION_CONFIG_FILE_PATH}" -d "namespaces=${NAMESPACES_FILE_PATH}" --in "${config}" > "${OTELCOL_CONFIG_FILE_PATH}"
+# The telemetry-proxy next to the controller collects the cluster-wide telemetry only while its controller is the
+# leader of the replicas of the controller manager, which the controller signals by creating the file at
+# `LUMIGO_CLUSTER_COLLECTOR_LEADER_FILE`; without that variable, the telemetry-proxy always collects it
+function is_cluster_collector() {
+    if [ -z "${LUMIGO_CLUSTER_COLLECTOR_LEADER_FILE}" ] || [ -f "${LUMIGO_CLUSTER_COLLECTOR_LEADER_FILE}" ]; then
+        echo 'true'
+    else
+        echo 'false'
+    fi
+}
+
+# The Lumigo tokens and the values of the HTTP headers of the exporters, like the OTLP headers of the namespaces,
+# carry credentials, and are redacted from the debug output of the configurations and of the namespaces file
+function redact_configs() {
+    # The headers are the only entries of the configurations with both names and values quoted
+    sed -E -e 's/^( +"(\\.|[^"\\])*": )"(\\.|[^"\\])*"$/\1"***"/' -e "${REDACT_TOKENS_EXPRESSION}" "${1}"
+}
+
+function redact_namespaces() {
+    sed -E -e 's/"(headers|otlpHeaders)": *\{( *"(\\.|[^"\\])*": *"(\\.|[^"\\])*" *,?)* *\}/"\1":"***"/g' -e "${REDACT_TOKENS_EXPRESSION}" "${1}"
+}
+
+function render_configs() {
+    local cluster_collector="$(is_cluster_collector)"
+    echo -n "${cluster_collector}" > "${CLUSTER_COLLECTOR_STATE_PATH}"
+
+    LUMIGO_CLUSTER_COLLECTOR="${cluster_collector}" gomplate -f "${OTELCOL_CONFIG_TEMPLATE_FILE_PATH}" -d "config=${GENERATION_CONFIG_FILE_PATH}" -d "namespaces=${NAMESPACES_FILE_PATH}" --in "${config}" > "${OTELCOL_NEW_CONFIG_FILE_PATH}"
 
     if [ "${debug}" == 'true' ]; then
-       cat "${OTELCOL_CONFIG_FILE_PATH}"
+       redact_configs "${OTELCOL_NEW_CONFIG_FILE_PATH}"
     fi
+}
+
+function generate_configs() {
+    render_configs
+    mv -f "${OTELCOL_NEW_CONFIG_FILE_PATH}" "${OTELCOL_CONFIG_FILE_PATH}"
 
     sha1sum -b "${NAMESPACES_FILE_PATH}" > "${NAMESPACES_FILE_SHA_PATH}"
 }
@@ -81,6 +121,34 @@ function trigger_config_reload() {
     kill -SIGHUP "${OTELCOL_PID}"
 }
 
+# Applies the changes of the namespaces file to the running OpenTelemetry Collector, which on SIGHUP
+# restarts its pipelines in-process without restarting the pod. Configurations that fail to render or
+# validate are not applied, as the collector would exit on reload; the current ones stay in use.
+function reload_configs() {
+    # The checksum is updated in any case, so that a broken namespaces file is not retried every second
+    sha1sum -b "${NAMESPACES_FILE_PATH}" > "${NAMESPACES_FILE_SHA_PATH}"
+
+    if ! render_configs; then
+        echo "Cannot render the new configurations; keeping the current ones" > /dev/stderr
+        return
+    fi
+
+    if cmp -s "${OTELCOL_NEW_CONFIG_FILE_PATH}" "${OTELCOL_CONFIG_FILE_PATH}"; then
+        # E.g., a change of the namespaces file that does not affect the configurations of this telemetry-proxy
+        rm -f "${OTELCOL_NEW_CONFIG_FILE_PATH}"
+        return
+    fi
+
+    if ! /lumigo/bin/otelcol validate "--config=${OTELCOL_NEW_CONFIG_FILE_PATH}"; then
+        echo "The new configurations are invalid; keeping the current ones" > /dev/stderr
+        return
+    fi
+
+    # The collector reads the configurations file on reload, so it must never see it half-written
+    mv -f "${OTELCOL_NEW_CONFIG_FILE_PATH}" "${OTELCOL_CONFIG_FILE_PATH}"
+    trigger_config_reload
+}
+
 function watch_namespaces_file() {
     while true; do
         sleep 1s
@@ -89,16 +157,19 @@ function watch_namespaces_file() {
             # Config changed
             if [ "${debug}" == 'true' ]; then
                 echo 'Namespace file change detected'
-                cat "${NAMESPACES_FILE_PATH}"
+                redact_namespaces "${NAMESPACES_FILE_PATH}"
                 echo
             fi
-            generate_configs
-            trigger_config_reload
+            reload_configs
+        elif [ "$(is_cluster_collector)" != "$(<${CLUSTER_COLLECTOR_STATE_PATH})" ]; then
+            echo "Leadership changed, collecting cluster-wide telemetry: $(is_cluster_collector)"
+            reload_configs
         fi
     done
 }
 
 mkdir -p "$(dirname "${NAMESPACES_FILE_PATH}")"
+mkdir -p "${OTELCOL_SENDING_QUEUE_DIRECTORY_PATH}"
 
 if [ ! -s "${NAMESPACES_FILE_PATH}" ]; then
     # `NAMESPACES_FILE_PATH` file not existing or empty. Init the `NAMESPACES_FILE_PATH` with an empty JSON object
@@ -2,6 +2,109 @@
 {{- $config := (datasource "config") -}}
 {{- $debug := $config.debug | conv.ToBool -}}
 {{- $clusterName := getenv "KUBERNETES_CLUSTER_NAME" "" }}
+{{- $deploymentEnvironment := getenv "LUMIGO_DEPLOYMENT_ENVIRONMENT" "" }}
+{{- $clusterAttributesEnabled := or $clusterName $deploymentEnvironment }}
+{{- $memoryLimiterCheckInterval := getenv "LUMIGO_MEMORY_LIMITER_CHECK_INTERVAL" "1s" }}
+{{- $memoryLimiterLimitPercentage := getenv "LUMIGO_MEMORY_LIMITER_LIMIT_PERCENTAGE" "80" }}
+{{- $memoryLimiterSpikeLimitPercentage := getenv "LUMIGO_MEMORY_LIMITER_SPIKE_LIMIT_PERCENTAGE" "25" }}
+{{- /* When not set, each batch processor uses the defaults suited to its pipeline */}}
+{{- $batchSendBatchSize := getenv "LUMIGO_BATCH_SEND_BATCH_SIZE" "" }}
+{{- $batchSendBatchMaxSize := getenv "LUMIGO_BATCH_SEND_BATCH_MAX_SIZE" "" }}
+{{- $batchTimeout := getenv "LUMIGO_BATCH_TIMEOUT" "" }}
+{{- /* The compression of the requests sent to Lumigo, either `none`, `gzip` or `zstd` */}}
+{{- $exportCompression := getenv "LUMIGO_EXPORT_COMPRESSION" "gzip" }}
+{{- /* When not set, the exporters to Lumigo use the write buffer size of the collector */}}
+{{- $exportWriteBufferSize := getenv "LUMIGO_EXPORT_WRITE_BUFFER_SIZE" "" }}
+{{- /* How the exporters to Lumigo retry the requests that fail with transient errors, like 5xx responses, and
+  how many requests they queue while retrying */}}
+{{- $exportRetryInitialInterval := getenv "LUMIGO_EXPORT_RETRY_INITIAL_INTERVAL" "5s" }}
+{{- $exportRetryMaxInterval := getenv "LUMIGO_EXPORT_RETRY_MAX_INTERVAL" "30s" }}
+{{- $exportRetryMaxElapsedTime := getenv "LUMIGO_EXPORT_RETRY_MAX_ELAPSED_TIME" "300s" }}
+{{- $exportQueueNumConsumers := getenv "LUMIGO_EXPORT_QUEUE_NUM_CONSUMERS" "10" }}
+{{- $exportQueueSize := getenv "LUMIGO_EXPORT_QUEUE_SIZE" "1000" }}
+{{- /* When not set, the exporters use the minimum TLS version of the collector */}}
+{{- $tlsMinVersion := getenv "LUMIGO_TLS_MIN_VERSION" "" }}
+{{- /* Whether the OTLP receiver also accepts telemetry over gRPC, whose HTTP/2 connections multiplex the concurrent
+  exports of each workload, rather than opening an HTTP/1.1 connection per concurrent export */}}
+{{- $receiverGrpcEnabled := eq (getenv "LUMIGO_RECEIVER_GRPC_ENABLED" "false") "true" }}
+{{- /* When not set, the gRPC receiver uses the defaults of the collector, i.e., no limit on the concurrent streams
+  and on the age and idleness of the connections */}}
+{{- $receiverGrpcMaxConcurrentStreams := getenv "LUMIGO_RECEIVER_GRPC_MAX_CONCURRENT_STREAMS" "" }}
+{{- $receiverKeepaliveTime := getenv "LUMIGO_RECEIVER_KEEPALIVE_TIME" "" }}
+{{- $receiverKeepaliveTimeout := getenv "LUMIGO_RECEIVER_KEEPALIVE_TIMEOUT" "" }}
+{{- $receiverKeepaliveMaxConnectionIdle := getenv "LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_IDLE" "" }}
+{{- $receiverKeepaliveMaxConnectionAge := getenv "LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_AGE" "" }}
+{{- $receiverKeepaliveMaxConnectionAgeGrace := getenv "LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_AGE_GRACE" "" }}
+{{- /* The gRPC servers close with `too_many_pings` the connections of the clients that ping more often than every 5
+  minutes by default, which the exporters of the SDKs then reopen; the receiver tolerates pings every 10 seconds */}}
+{{- $receiverKeepaliveMinTime := getenv "LUMIGO_RECEIVER_KEEPALIVE_MIN_TIME" "10s" }}
+{{- $receiverKeepalivePermitWithoutStream := getenv "LUMIGO_RECEIVER_KEEPALIVE_PERMIT_WITHOUT_STREAM" "true" }}
+{{- /* On each node, the telemetry-proxy DaemonSet receives the telemetry of the workloads on that node, and leaves
+  the collection of cluster-wide telemetry, like Kubernetes events, to the telemetry-proxy next to the controller */}}
+{{- $nodeLocal := eq (getenv "LUMIGO_TELEMETRY_PROXY_MODE" "") "node" }}
+{{- /* The controller manager can run several replicas, each with its own telemetry-proxy, all of which receive the
+  telemetry of the workloads; only the one next to the leader of the replicas collects the cluster-wide telemetry,
+  so that it is collected once, and the entrypoint sets `LUMIGO_CLUSTER_COLLECTOR` to `false` on the others */}}
+{{- $clusterCollector := and (not $nodeLocal) (ne (getenv "LUMIGO_CLUSTER_COLLECTOR" "true") "false") }}
+{{- $spanMetricsEnabled := false }}
+{{- $rateLimitingEnabled := false }}
+{{- /* When debug is enabled, the telemetry of all namespaces is logged anyhow */}}
+{{- $telemetryDebugEnabled := false }}
+{{- $additionalExportersEnabled := false }}
+{{- $nodeLifecycleEnabled := false }}
+{{- $skipHostsEnabled := false }}
+{{- $awsXRayEnabled := false }}
+{{- $namespaceTagsEnabled := false }}
+{{- /* Whether any namespace has its traces or logs pipeline disabled, and whether any counts the node lifecycle events in its metrics */}}
+{{- $tracesDisabled := false }}
+{{- $logsDisabled := false }}
+{{- $nodeLifecycleMetricsEnabled := false }}
+{{- /* Whether any namespace sends its spans or its logs to an endpoint of its own rather than to the Lumigo ones */}}
+{{- $tracesEndpointsEnabled := false }}
+{{- $logsEndpointsEnabled := false }}
+{{- range $i, $namespace := $namespaces }}
+{{- with $namespace.endpoints }}
+{{- if .traces }}
+{{- $tracesEndpointsEnabled = true }}
+{{- end }}
+{{- if .logs }}
+{{- $logsEndpointsEnabled = true }}
+{{- end }}
+{{- end }}
+{{- if $namespace.spanMetrics }}
+{{- $spanMetricsEnabled = true }}
+{{- end }}
+{{- if or $namespace.maxSpansPerSecond $namespace.quota }}
+{{- $rateLimitingEnabled = true }}
+{{- end }}
+{{- if and $namespace.debug (not $debug) }}
+{{- $telemetryDebugEnabled = true }}
+{{- end }}
+{{- if $namespace.skipHostsRegex }}
+{{- $skipHostsEnabled = true }}
+{{- end }}
+{{- if $namespace.awsXRay }}
+{{- $awsXRayEnabled = true }}
+{{- end }}
+{{- if $namespace.tags }}
+{{- $namespaceTagsEnabled = true }}
+{{- end }}
+{{- if $namespace.additionalExporters }}
+{{- $additionalExportersEnabled = true }}
+{{- end }}
+{{- if and $clusterCollector $namespace.nodeLifecycle }}
+{{- $nodeLifecycleEnabled = true }}
+{{- if not $namespace.metricsDisabled }}
+{{- $nodeLifecycleMetricsEnabled = true }}
+{{- end }}
+{{- end }}
+{{- if $namespace.tracesDisabled }}
+{{- $tracesDisabled = true }}
+{{- end }}
+{{- if $namespace.logsDisabled }}
+{{- $logsDisabled = true }}
+{{- end }}
+{{- end }}
 receivers:
   otlp:
     protocols:
@@ -9,11 +112,46 @@ receivers:
         auth:
           authenticator: lumigoauth/server
         include_metadata: true # Needed by `headers_setter/lumigo`
+{{- if $receiverGrpcEnabled }}
+      grpc:
+        endpoint: 0.0.0.0:4317
+        auth:
+          authenticator: lumigoauth/server
+        include_metadata: true # Needed by `headers_setter/lumigo`
+{{- with $receiverGrpcMaxConcurrentStreams }}
+        max_concurrent_streams: {{ . }}
+{{- end }}
+        keepalive:
+{{- if or $receiverKeepaliveTime $receiverKeepaliveTimeout $receiverKeepaliveMaxConnectionIdle $receiverKeepaliveMaxConnectionAge $receiverKeepaliveMaxConnectionAgeGrace }}
+          server_parameters:
+{{- with $receiverKeepaliveTime }}
+            time: {{ . }}
+{{- end }}
+{{- with $receiverKeepaliveTimeout }}
+            timeout: {{ . }}
+{{- end }}
+{{- with $receiverKeepaliveMaxConnectionIdle }}
+            max_connection_idle: {{ . }}
+{{- end }}
+{{- with $receiverKeepaliveMaxConnectionAge }}
+            max_connection_age: {{ . }}
+{{- end }}
+{{- with $receiverKeepaliveMaxConnectionAgeGrace }}
+            max_connection_age_grace: {{ . }}
+{{- end }}
+{{- end }}
+          enforcement_policy:
+            min_time: {{ $receiverKeepaliveMinTime }}
+            permit_without_stream: {{ $receiverKeepalivePermitWithoutStream }}
+{{- end }}
+{{- if $clusterCollector }}
 {{- range $i, $namespace := $namespaces }}
   lumigooperatorheartbeat/ns_{{ $namespace.name }}:
     namespace: {{ $namespace.name }}
 {{- end }}
+{{- end }}
 {{- range $i, $namespace := $namespaces }}
+{{- if and $clusterCollector (not $namespace.kubeEventsDisabled) }}
   k8sobjects/objects_ns_{{ $namespace.name }}:
     auth_type: serviceAccount
     objects:
@@ -54,7 +192,9 @@ receivers:
       namespaces: [ {{ $namespace.name }} ]
 {{- end }}
 {{- end }}
+{{- end }}
 {{- range $i, $namespace := $namespaces }}
+{{- if and $clusterCollector (not $namespace.kubeEventsDisabled) }}
   k8sobjects/events_ns_{{ $namespace.name }}:
     auth_type: serviceAccount
     objects:
@@ -69,9 +209,69 @@ receivers:
 {{- end }}
 {{- end }}
 {{- end }}
+{{- end }}
+{{- if $nodeLifecycleEnabled }}
+  # Shared by the node_lifecycle pipelines of all namespaces that opted in
+  k8sobjects/node_lifecycle:
+    auth_type: serviceAccount
+    objects:
+{{- range $i, $mode := (coll.Slice "watch" "pull") }}
+{{- range $j, $fieldSelector := (coll.Slice "involvedObject.kind=Node" "involvedObject.kind=NodeClaim") }}
+    - name: events
+      mode: {{ $mode }}
+      interval: 10m
+      # The events of cluster-scoped objects, like nodes and Karpenter's node claims, are recorded in the `default` namespace
+      namespaces: [ default ]
+      field_selector: {{ $fieldSelector }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- if and $clusterCollector $namespace.prometheus }}
+  prometheus/ns_{{ $namespace.name }}:
+    config:
+      scrape_configs:
+{{- if $namespace.prometheus.scrapeAnnotatedPods }}
+      - job_name: kubernetes-pods
+        kubernetes_sd_configs:
+        - role: pod
+          namespaces:
+            names: [ {{ $namespace.name }} ]
+        relabel_configs:
+        # Scrape only pods with the `prometheus.io/scrape: "true"` annotation
+        - source_labels: [ __meta_kubernetes_pod_annotation_prometheus_io_scrape ]
+          action: keep
+          regex: "true"
+        - source_labels: [ __meta_kubernetes_pod_annotation_prometheus_io_path ]
+          action: replace
+          target_label: __metrics_path__
+          regex: (.+)
+        - source_labels: [ __address__, __meta_kubernetes_pod_annotation_prometheus_io_port ]
+          action: replace
+          regex: ([^:]+)(?::\d+)?;(\d+)
+          # The `$` signs are escaped, as the collector would otherwise expand them as environment variables
+          replacement: $$1:$$2
+          target_label: __address__
+        - source_labels: [ __meta_kubernetes_pod_name ]
+          action: replace
+          target_label: k8s_pod_name
+{{- end }}
+{{- range $j, $target := $namespace.prometheus.scrapeTargets }}
+      - job_name: {{ $target.jobName }}
+        metrics_path: {{ $target.metricsPath }}
+        scrape_interval: {{ $target.scrapeInterval }}
+        static_configs:
+        - targets: [ {{ join $target.targets ", " }} ]
+{{- end }}
+{{- end }}
+{{- end }}
 
 extensions:
   health_check:
+  # Backs the sending queues of the exporters with static credentials, so that the telemetry they
+  # have queued is not lost when the configurations are reloaded on changes of the Lumigo resources
+  file_storage/sending_queue:
+    directory: /lumigo/etc/otelcol/sending-queue
   headers_setter/lumigo:
     headers:
     # Use the same authorization header as the one accompanying
@@ -89,30 +289,237 @@ extensions:
 {{- end }}
 
 exporters:
+  # The exporters using `headers_setter/lumigo` keep in-memory sending queues, as the persisted
+  # telemetry would lose the authorization header of the request it was received with
   otlphttp/lumigo:
     endpoint: {{ env.Getenv "LUMIGO_ENDPOINT" "https://ga-otlp.lumigo-tracer-edge.golumigo.com" }}
     auth:
       authenticator: headers_setter/lumigo
+    compression: {{ $exportCompression }}
+{{- if $exportWriteBufferSize }}
+    write_buffer_size: {{ $exportWriteBufferSize }}
+{{- end }}
+    retry_on_failure:
+      enabled: true
+      initial_interval: {{ $exportRetryInitialInterval }}
+      max_interval: {{ $exportRetryMaxInterval }}
+      max_elapsed_time: {{ $exportRetryMaxElapsedTime }}
+    sending_queue:
+      enabled: true
+      num_consumers: {{ $exportQueueNumConsumers }}
+      queue_size: {{ $exportQueueSize }}
+{{- if $tlsMinVersion }}
+    tls:
+      min_version: "{{ $tlsMinVersion }}"
+{{- end }}
   otlphttp/lumigo_logs:
     endpoint: {{ env.Getenv "LUMIGO_LOGS_ENDPOINT" "https://ga-otlp.lumigo-tracer-edge.golumigo.com" }}
     auth:
       authenticator: headers_setter/lumigo
+    compression: {{ $exportCompression }}
+{{- if $exportWriteBufferSize }}
+    write_buffer_size: {{ $exportWriteBufferSize }}
+{{- end }}
+    retry_on_failure:
+      enabled: true
+      initial_interval: {{ $exportRetryInitialInterval }}
+      max_interval: {{ $exportRetryMaxInterval }}
+      max_elapsed_time: {{ $exportRetryMaxElapsedTime }}
+    sending_queue:
+      enabled: true
+      num_consumers: {{ $exportQueueNumConsumers }}
+      queue_size: {{ $exportQueueSize }}
+{{- if $tlsMinVersion }}
+    tls:
+      min_version: "{{ $tlsMinVersion }}"
+{{- end }}
 {{- if $debug }}
   logging:
     verbosity: detailed
     sampling_initial: 1
     sampling_thereafter: 1
 {{- end }}
+{{- if $telemetryDebugEnabled }}
+  logging/debug:
+    verbosity: detailed
+    sampling_initial: 1
+    sampling_thereafter: 1
+{{- end }}
 {{- range $i, $namespace := $namespaces }}
   otlphttp/lumigo_ns_{{ $namespace.name }}:
     endpoint: $LUMIGO_ENDPOINT
     auth:
       authenticator: lumigoauth/ns_{{ $namespace.name }}
+    compression: {{ $exportCompression }}
+{{- if $exportWriteBufferSize }}
+    write_buffer_size: {{ $exportWriteBufferSize }}
+{{- end }}
+    retry_on_failure:
+      enabled: true
+      initial_interval: {{ $exportRetryInitialInterval }}
+      max_interval: {{ $exportRetryMaxInterval }}
+      max_elapsed_time: {{ $exportRetryMaxElapsedTime }}
+    sending_queue:
+      enabled: true
+      num_consumers: {{ $exportQueueNumConsumers }}
+      queue_size: {{ $exportQueueSize }}
+      storage: file_storage/sending_queue
+{{- if $tlsMinVersion }}
+    tls:
+      min_version: "{{ $tlsMinVersion }}"
+{{- end }}
+{{- if $namespace.otlpHeaders }}
+    headers:
+{{- range $header, $value := $namespace.otlpHeaders }}
+      {{ printf "%q" $header }}: {{ printf "%q" $value }}
+{{- end }}
+{{- end }}
+{{- with $namespace.endpoints }}
+{{- $endpoints := . }}
+{{- range $j, $signal := (coll.Slice "traces" "logs" "metrics") }}
+{{- with index $endpoints $signal }}
+  # The spans and logs sent to the endpoints of the namespace are authenticated with the token of the
+  # requests they are received with, like those sent to Lumigo, and the metrics with the token of the namespace
+  otlphttp/lumigo_{{ $signal }}_ns_{{ $namespace.name }}:
+{{- if .endpoint }}
+    endpoint: {{ .endpoint }}
+{{- else if eq $signal "logs" }}
+    # The endpoints without URL carry the OTLP headers of the namespace to the Lumigo endpoints
+    endpoint: {{ env.Getenv "LUMIGO_LOGS_ENDPOINT" "https://ga-otlp.lumigo-tracer-edge.golumigo.com" }}
+{{- else }}
+    endpoint: {{ env.Getenv "LUMIGO_ENDPOINT" "https://ga-otlp.lumigo-tracer-edge.golumigo.com" }}
+{{- end }}
+    auth:
+{{- if eq $signal "metrics" }}
+      authenticator: lumigoauth/ns_{{ $namespace.name }}
+{{- else }}
+      authenticator: headers_setter/lumigo
+{{- end }}
+    compression: {{ $exportCompression }}
+{{- if $exportWriteBufferSize }}
+    write_buffer_size: {{ $exportWriteBufferSize }}
+{{- end }}
+    retry_on_failure:
+      enabled: true
+      initial_interval: {{ $exportRetryInitialInterval }}
+      max_interval: {{ $exportRetryMaxInterval }}
+      max_elapsed_time: {{ $exportRetryMaxElapsedTime }}
+    sending_queue:
+      enabled: true
+      num_consumers: {{ $exportQueueNumConsumers }}
+      queue_size: {{ $exportQueueSize }}
+{{- if eq $signal "metrics" }}
+      storage: file_storage/sending_queue
+{{- end }}
+{{- if $tlsMinVersion }}
+    tls:
+      min_version: "{{ $tlsMinVersion }}"
+{{- end }}
+{{- if .headers }}
+    headers:
+{{- range $header, $value := .headers }}
+      {{ printf "%q" $header }}: {{ printf "%q" $value }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- with $namespace.archival }}
+  awss3/archival_ns_{{ $namespace.name }}:
+    s3uploader:
+      region: {{ .region }}
+      s3_bucket: {{ .bucket }}
+      s3_prefix: {{ .prefix }}
+      s3_partition: {{ .partition }}
+{{- if .endpoint }}
+      endpoint: {{ .endpoint }}
+      s3_force_path_style: true
+{{- end }}
+    marshaler: otlp_json
+{{- end }}
+{{- range $j, $exporter := $namespace.additionalExporters }}
+  otlphttp/additional_ns_{{ $namespace.name }}_{{ $exporter.name }}:
+    endpoint: {{ $exporter.endpoint }}
+    sending_queue:
+      storage: file_storage/sending_queue
+{{- if $tlsMinVersion }}
+    tls:
+      min_version: "{{ $tlsMinVersion }}"
+{{- end }}
+{{- if $exporter.headers }}
+    headers:
+{{- range $header, $value := $exporter.headers }}
+      {{ printf "%q" $header }}: {{ printf "%q" $value }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{- if or $spanMetricsEnabled $telemetryDebugEnabled $additionalExportersEnabled $nodeLifecycleMetricsEnabled $tracesEndpointsEnabled }}
+
+connectors:
+{{- if $telemetryDebugEnabled }}
+  forward/debug:
+{{- end }}
+{{- if $additionalExportersEnabled }}
+  forward/additional_exporters:
+{{- end }}
+{{- if $tracesEndpointsEnabled }}
+  # The spans are sent to the endpoint of their namespace, if it has one, and to Lumigo otherwise
+  forward/lumigo_traces:
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.spanMetrics }}
+  spanmetrics/ns_{{ $namespace.name }}:
+    dimensions:
+    - name: k8s.namespace.name
+{{- range $j, $dimension := $namespace.spanMetrics.dimensions }}
+    - name: {{ $dimension }}
+{{- end }}
+    metrics_flush_interval: 15s
+{{- end }}
+{{- if and $clusterCollector $namespace.nodeLifecycle (not $namespace.metricsDisabled) }}
+  count/node_lifecycle_ns_{{ $namespace.name }}:
+    logs:
+      k8s.node.lifecycle.events:
+        description: The amount of events of the nodes of the cluster, by lifecycle signal and reason
+        attributes:
+        - key: lumigo.node_lifecycle.signal
+        - key: k8s.event.reason
+          default_value: unknown
+{{- end }}
+{{- end }}
 {{- end }}
 
 processors:
+  memory_limiter:
+    check_interval: {{ $memoryLimiterCheckInterval }}
+    limit_percentage: {{ $memoryLimiterLimitPercentage }}
+    spike_limit_percentage: {{ $memoryLimiterSpikeLimitPercentage }}
   k8sdataenricherprocessor:
     auth_type: serviceAccount
+  # Counts the telemetry sent to Lumigo for each namespace, which the controller reports as its daily usage
+  namespaceusage:
+{{- if $rateLimitingEnabled }}
+  ratelimiter:
+    limits:
+{{- range $i, $namespace := $namespaces }}
+{{- if or $namespace.maxSpansPerSecond $namespace.quota }}
+    - namespace: {{ $namespace.name }}
+      spans_per_second: {{ conv.ToInt64 $namespace.maxSpansPerSecond }}
+{{- with $namespace.quota }}
+{{- /* The JSON numbers are floats, which would be rendered in the exponent notation if large */}}
+      quota:
+        spans_per_day: {{ conv.ToInt64 .spansPerDay }}
+        bytes_per_day: {{ conv.ToInt64 .bytesPerDay }}
+        sampling_percentage: {{ conv.ToInt64 .samplingPercentage }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if not $nodeLocal }}
+    status_file: /lumigo/etc/namespaces/rate_limiting_status.json
+{{- end }}
+{{- end }}
 {{- range $i, $namespace := $namespaces }}
   transform/add_ns_attributes_ns_{{ $namespace.name }}:
     log_statements:
@@ -120,6 +527,17 @@ processors:
       statements:
       - set(attributes["k8s.namespace.name"], "{{ $namespace.name }}")
       - set(attributes["k8s.namespace.uid"], "{{ $namespace.uid }}")
+{{- range $key, $value := $namespace.tags }}
+      - 'set(attributes["{{ $key }}"], "{{ $value }}")'
+{{- end }}
+    metric_statements:
+    - context: resource
+      statements:
+      - set(attributes["k8s.namespace.name"], "{{ $namespace.name }}")
+      - set(attributes["k8s.namespace.uid"], "{{ $namespace.uid }}")
+{{- range $key, $value := $namespace.tags }}
+      - 'set(attributes["{{ $key }}"], "{{ $value }}")'
+{{- end }}
 {{- end }}
   filter/only_monitored_namespaces:
     error_mode: ignore
@@ -134,20 +552,19 @@ processors:
 {{- end }}
         - key: k8s.namespace.name
           value: "{{ join $namespaceNames "|" }}"
-{{- if $clusterName }}
-  transform/add_cluster_name:
-    trace_statements:
-    - context: resource
-      statements:
-      - set(attributes["k8s.cluster.name"], "{{ $clusterName }}")
-#   metric_statements:
-#   - context: resource
-#     statements:
-#     - set(attributes["k8s.cluster.name"], "{{ $clusterName }}")
-    log_statements:
+{{- if $clusterAttributesEnabled }}
+  transform/add_cluster_attributes:
+{{- range $statements := slice "trace_statements" "metric_statements" "log_statements" }}
+    {{ $statements }}:
     - context: resource
       statements:
+{{- if $clusterName }}
       - set(attributes["k8s.cluster.name"], "{{ $clusterName }}")
+{{- end }}
+{{- if $deploymentEnvironment }}
+      - set(attributes["deployment.environment"], "{{ $deploymentEnvironment }}")
+{{- end }}
+{{- end }}
 {{- end }}
   transform/add_heartbeat_attributes:
     log_statements:
@@ -166,13 +583,210 @@ processors:
       statements:
       - set(name, "lumigo-operator.k8s-events")
       - set(version, "{{ $config.operator.version }}")
+{{- if $nodeLifecycleEnabled }}
+  transform/set_node_lifecycle_scope:
+    log_statements:
+    - context: scope
+      statements:
+      - set(name, "lumigo-operator.node-lifecycle")
+      - set(version, "{{ $config.operator.version }}")
+  # Classifies the events of the nodes by the churn they signal, so that they can be
+  # correlated with latency spikes in the traces
+  transform/classify_node_lifecycle_events:
+    error_mode: ignore
+    log_statements:
+    - context: log
+      statements:
+      # In watch mode, the event is wrapped in the `object` field of the body
+      - set(cache["event"], body["object"]) where body["object"] != nil
+      - set(cache["event"], body) where body["object"] == nil
+      - set(attributes["k8s.event.reason"], cache["event"]["reason"])
+      - set(attributes["k8s.event.source.component"], cache["event"]["source"]["component"])
+      - set(attributes["k8s.node.name"], cache["event"]["involvedObject"]["name"]) where cache["event"]["involvedObject"]["kind"] == "Node"
+      - set(attributes["lumigo.node_lifecycle.signal"], "other")
+      # Nodes added by Karpenter or the cluster-autoscaler
+      - set(attributes["lumigo.node_lifecycle.signal"], "scale_up") where IsMatch(attributes["k8s.event.reason"], "^(RegisteredNode|Launched|Registered|Initialized)$")
+      # Nodes drained and removed by Karpenter or the cluster-autoscaler
+      - set(attributes["lumigo.node_lifecycle.signal"], "scale_down") where IsMatch(attributes["k8s.event.reason"], "^(ScaleDown|ScaleDownEmpty|RemovingNode|DeletingNode|Disrupting|DisruptionTerminating)$")
+      # Spot interruptions surfaced as node events by Karpenter, the AWS Node Termination Handler or GKE
+      - set(attributes["lumigo.node_lifecycle.signal"], "spot_interruption") where IsMatch(attributes["k8s.event.reason"], "^(SpotInterrupted|SpotInterruption|SpotRebalanceRecommendation|RebalanceRecommendation|InstanceStopping|InstanceTerminating|TerminatingOnInterruption|Preempted)$")
+      - set(attributes["lumigo.node_lifecycle.signal"], "not_ready") where IsMatch(attributes["k8s.event.reason"], "^(NodeNotReady|NodeNotSchedulable)$")
+{{- end }}
 {{- range $i, $namespace := $namespaces }}
   batch/k8s_objects_ns_{{ $namespace.name }}:
-    send_batch_size: 100
-    timeout: 1s
+    send_batch_size: {{ $batchSendBatchSize | default "100" }}
+{{- if $batchSendBatchMaxSize }}
+    send_batch_max_size: {{ $batchSendBatchMaxSize }}
+{{- end }}
+    timeout: {{ $batchTimeout | default "1s" }}
   batch/k8s_events_ns_{{ $namespace.name }}:
-    send_batch_size: 100
-    timeout: 1s
+    send_batch_size: {{ $batchSendBatchSize | default "100" }}
+{{- if $batchSendBatchMaxSize }}
+    send_batch_max_size: {{ $batchSendBatchMaxSize }}
+{{- end }}
+    timeout: {{ $batchTimeout | default "1s" }}
+{{- if $namespace.prometheus }}
+  batch/prometheus_ns_{{ $namespace.name }}:
+    send_batch_size: {{ $batchSendBatchSize | default "1000" }}
+{{- if $batchSendBatchMaxSize }}
+    send_batch_max_size: {{ $batchSendBatchMaxSize }}
+{{- end }}
+    timeout: {{ $batchTimeout | default "10s" }}
+{{- end }}
+{{- if $namespace.spanMetrics }}
+  # The spanmetrics connectors receive the spans of all namespaces
+  filter/span_metrics_ns_{{ $namespace.name }}:
+    error_mode: ignore
+    metrics:
+      metric:
+      - 'resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"'
+  batch/span_metrics_ns_{{ $namespace.name }}:
+    send_batch_size: {{ $batchSendBatchSize | default "1000" }}
+{{- if $batchSendBatchMaxSize }}
+    send_batch_max_size: {{ $batchSendBatchMaxSize }}
+{{- end }}
+    timeout: {{ $batchTimeout | default "10s" }}
+{{- end }}
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.archival }}
+  filter/archival_ns_{{ $namespace.name }}:
+    error_mode: ignore
+    traces:
+      span:
+      - 'resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"'
+    logs:
+      log_record:
+      - 'resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"'
+{{- end }}
+{{- if $namespace.additionalExporters }}
+  filter/additional_exporters_ns_{{ $namespace.name }}:
+    error_mode: ignore
+    traces:
+      span:
+      - 'resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- if $tracesEndpointsEnabled }}
+  # Drops the spans of the namespaces that have a traces endpoint of their own
+  filter/default_traces_endpoint:
+    error_mode: ignore
+    traces:
+      span:
+{{- range $i, $namespace := $namespaces }}
+{{- with $namespace.endpoints }}
+{{- if .traces }}
+      - 'resource.attributes["k8s.namespace.name"] == "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $logsEndpointsEnabled }}
+  # Drops the logs of the namespaces that have a logs endpoint of their own
+  filter/default_logs_endpoint:
+    error_mode: ignore
+    logs:
+      log_record:
+{{- range $i, $namespace := $namespaces }}
+{{- with $namespace.endpoints }}
+{{- if .logs }}
+      - 'resource.attributes["k8s.namespace.name"] == "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- with $namespace.endpoints }}
+{{- if .traces }}
+  filter/traces_endpoint_ns_{{ $namespace.name }}:
+    error_mode: ignore
+    traces:
+      span:
+      - 'resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"'
+{{- end }}
+{{- if .logs }}
+  filter/logs_endpoint_ns_{{ $namespace.name }}:
+    error_mode: ignore
+    logs:
+      log_record:
+      - 'resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $tracesDisabled }}
+  # Drops the spans of the namespaces whose traces pipeline is disabled in their Lumigo resource
+  filter/disabled_traces:
+    error_mode: ignore
+    traces:
+      span:
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.tracesDisabled }}
+      - 'resource.attributes["k8s.namespace.name"] == "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $logsDisabled }}
+  # The application logs pipelines receive the logs of all namespaces, so the logs of the namespaces whose
+  # logs pipeline is disabled are dropped before their namespace attributes are overwritten
+  filter/disabled_logs:
+    error_mode: ignore
+    logs:
+      log_record:
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.logsDisabled }}
+      - 'resource.attributes["k8s.namespace.name"] == "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $skipHostsEnabled }}
+  # Drops the spans of the HTTP calls to the `skipDomains` of the namespaces, whatever attributes the
+  # instrumentations set the host or the URL of the calls in
+  filter/skip_hosts:
+    error_mode: ignore
+    traces:
+      span:
+{{- range $i, $namespace := $namespaces }}
+{{- with $namespace.skipHostsRegex }}
+      - 'resource.attributes["k8s.namespace.name"] == "{{ $namespace.name }}" and (IsMatch(attributes["server.address"], "^{{ . }}$") or IsMatch(attributes["net.peer.name"], "^{{ . }}$") or IsMatch(attributes["http.host"], "^{{ . }}(:[0-9]+)?$") or IsMatch(attributes["url.full"], "^[a-z]+://{{ . }}(:[0-9]+)?([/?#].*)?$") or IsMatch(attributes["http.url"], "^[a-z]+://{{ . }}(:[0-9]+)?([/?#].*)?$"))'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $awsXRayEnabled }}
+  # Adds the trace IDs in the AWS X-Ray format, e.g., `1-5759e988-bd862e3fe1be46a994272793`, to the spans
+  # of the namespaces with `.spec.tracing.awsXRay` enabled, to find their traces by the `X-Amzn-Trace-Id`
+  # headers logged by the AWS load balancers
+  transform/add_aws_xray_trace_ids:
+    error_mode: ignore
+    trace_statements:
+    - context: span
+      statements:
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.awsXRay }}
+      - 'set(attributes["aws.xray.trace_id"], Concat(["1", Substring(trace_id.string, 0, 8), Substring(trace_id.string, 8, 24)], "-")) where resource.attributes["k8s.namespace.name"] == "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $namespaceTagsEnabled }}
+  # Adds the tags of the `lumigo.io/tag.<key>` annotations of the namespaces to their traces; the logs and
+  # metrics get them in the 'transform/add_ns_attributes_ns_<$namespace.name>' processors
+  transform/add_namespace_tags:
+    error_mode: ignore
+    trace_statements:
+    - context: resource
+      statements:
+{{- range $i, $namespace := $namespaces }}
+{{- range $key, $value := $namespace.tags }}
+      - 'set(attributes["{{ $key }}"], "{{ $value }}") where attributes["k8s.namespace.name"] == "{{ $namespace.name }}"'
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $telemetryDebugEnabled }}
+  # The traces pipeline is shared by all namespaces, so we log only the spans of those with debug enabled
+  filter/debug_namespaces:
+    error_mode: ignore
+    traces:
+      span:
+      - 'true{{ range $i, $namespace := $namespaces }}{{ if $namespace.debug }} and resource.attributes["k8s.namespace.name"] != "{{ $namespace.name }}"{{ end }}{{ end }}'
 {{- end }}
   transform/inject_operator_details_into_resource:
     trace_statements:
@@ -180,11 +794,11 @@ processors:
       statements:
       - set(attributes["lumigo.k8s_operator.version"], "{{ $config.operator.version }}")
       - set(attributes["lumigo.k8s_operator.deployment_method"], "{{ $config.operator.deployment_method }}")
-#   metric_statements:
-#   - context: resource
-#     statements:
-#     - set(attributes["lumigo.k8s_operator.version"], "{{ $config.operator.version }}")
-#     - set(attributes["lumigo.k8s_operator.deployment_method"], "{{ $config.operator.deployment_method }}")
+    metric_statements:
+    - context: resource
+      statements:
+      - set(attributes["lumigo.k8s_operator.version"], "{{ $config.operator.version }}")
+      - set(attributes["lumigo.k8s_operator.deployment_method"], "{{ $config.operator.deployment_method }}")
     log_statements:
     - context: resource
       statements:
@@ -195,7 +809,12 @@ service:
   telemetry:
     logs:
       level: {{ $debug | ternary "debug" "info" }}
+    metrics:
+      # Scraped by the controller to detect failing exporters, and optionally by Prometheus
+      address: 0.0.0.0:8888
+      level: normal
   extensions:
+  - file_storage/sending_queue
   - headers_setter/lumigo
   - health_check
   - lumigoauth/server
@@ -210,17 +829,133 @@ service:
       receivers:
       - otlp
       processors:
+      - memory_limiter
       - k8sdataenricherprocessor
-{{- if $clusterName }}
-      - transform/add_cluster_name
+{{- if $tracesDisabled }}
+      - filter/disabled_traces
+{{- end }}
+{{- if $skipHostsEnabled }}
+      - filter/skip_hosts
+{{- end }}
+{{- if $awsXRayEnabled }}
+      - transform/add_aws_xray_trace_ids
+{{- end }}
+{{- if $namespaceTagsEnabled }}
+      - transform/add_namespace_tags
+{{- end }}
+{{- if $rateLimitingEnabled }}
+      - ratelimiter
+{{- end }}
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
 {{- end }}
       - transform/inject_operator_details_into_resource
+      - namespaceusage
       exporters:
+{{- if $tracesEndpointsEnabled }}
+      - forward/lumigo_traces
+{{- else }}
       - otlphttp/lumigo
+{{- end }}
 {{- if $debug }}
       - logging
 {{- end }}
 {{- range $i, $namespace := $namespaces }}
+{{- if $namespace.spanMetrics }}
+      - spanmetrics/ns_{{ $namespace.name }}
+{{- end }}
+{{- end }}
+{{- if $additionalExportersEnabled }}
+      - forward/additional_exporters
+{{- end }}
+{{- if $telemetryDebugEnabled }}
+      - forward/debug
+{{- end }}
+{{- if $tracesEndpointsEnabled }}
+    traces/lumigo:
+      receivers:
+      - forward/lumigo_traces
+      processors:
+      - filter/default_traces_endpoint
+      exporters:
+      - otlphttp/lumigo
+{{- range $i, $namespace := $namespaces }}
+{{- with $namespace.endpoints }}
+{{- if .traces }}
+    traces/lumigo_ns_{{ $namespace.name }}:
+      receivers:
+      - forward/lumigo_traces
+      processors:
+      - filter/traces_endpoint_ns_{{ $namespace.name }}
+      exporters:
+      - otlphttp/lumigo_traces_ns_{{ $namespace.name }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if $telemetryDebugEnabled }}
+    traces/debug:
+      receivers:
+      - forward/debug
+      processors:
+      - filter/debug_namespaces
+      exporters:
+      - logging/debug
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.additionalExporters }}
+    traces/additional_exporters_ns_{{ $namespace.name }}:
+      receivers:
+      - forward/additional_exporters
+      processors:
+      - filter/additional_exporters_ns_{{ $namespace.name }}
+      exporters:
+{{- range $j, $exporter := $namespace.additionalExporters }}
+      - otlphttp/additional_ns_{{ $namespace.name }}_{{ $exporter.name }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- if $namespace.archival }}
+    # The archival pipelines receive the telemetry independently of the pipelines that
+    # send it to Lumigo, so that it is archived even if, for example, it is rate-limited
+    traces/archival_ns_{{ $namespace.name }}:
+      receivers:
+      - otlp
+      processors:
+      - memory_limiter
+      - k8sdataenricherprocessor
+      - filter/archival_ns_{{ $namespace.name }}
+{{- if $namespace.skipHostsRegex }}
+      - filter/skip_hosts
+{{- end }}
+{{- if $namespace.awsXRay }}
+      - transform/add_aws_xray_trace_ids
+{{- end }}
+{{- if $namespace.tags }}
+      - transform/add_namespace_tags
+{{- end }}
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
+{{- end }}
+      exporters:
+      - awss3/archival_ns_{{ $namespace.name }}
+    logs/archival_ns_{{ $namespace.name }}:
+      receivers:
+      - otlp
+      processors:
+      - memory_limiter
+      - k8sdataenricherprocessor
+      - filter/archival_ns_{{ $namespace.name }}
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
+{{- end }}
+      exporters:
+      - awss3/archival_ns_{{ $namespace.name }}
+{{- end }}
+{{- end }}
+{{- range $i, $namespace := $namespaces }}
+{{- if $clusterCollector }}
     logs/usage_analytics_ns_{{ $namespace.name }}:
       receivers:
       - lumigooperatorheartbeat/ns_{{ $namespace.name }}
@@ -228,8 +963,8 @@ service:
       - k8sdataenricherprocessor
       - transform/add_heartbeat_attributes
       - transform/add_ns_attributes_ns_{{ $namespace.name }}
-{{- if $clusterName }}
-      - transform/add_cluster_name
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
 {{- end }}
       - transform/inject_operator_details_into_resource
       exporters:
@@ -237,55 +972,167 @@ service:
       - logging
 {{- end }}
       - otlphttp/lumigo_ns_{{ $namespace.name }}
+{{- end }}
+{{- $logsEndpoint := false }}
+{{- $metricsExporter := print "otlphttp/lumigo_ns_" $namespace.name }}
+{{- with $namespace.endpoints }}
+{{- $logsEndpoint = .logs }}
+{{- if .metrics }}
+{{- $metricsExporter = print "otlphttp/lumigo_metrics_ns_" $namespace.name }}
+{{- end }}
+{{- end }}
+{{- if not $namespace.logsDisabled }}
     logs/application_logs_ns_{{ $namespace.name }}:
       receivers:
       - otlp
       processors:
+      - memory_limiter
       - k8sdataenricherprocessor
+{{- if $logsDisabled }}
+      - filter/disabled_logs
+{{- end }}
+{{- if $logsEndpoint }}
+      - filter/logs_endpoint_ns_{{ $namespace.name }}
+{{- else if $logsEndpointsEnabled }}
+      - filter/default_logs_endpoint
+{{- end }}
       - transform/add_ns_attributes_ns_{{ $namespace.name }}
-{{- if $clusterName }}
-      - transform/add_cluster_name
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
 {{- end }}
       - transform/inject_operator_details_into_resource
+      - namespaceusage
       exporters:
 {{- if $config.debug }}
       - logging
 {{- end }}
+{{- if $logsEndpoint }}
+      - otlphttp/lumigo_logs_ns_{{ $namespace.name }}
+{{- else }}
       - otlphttp/lumigo_logs
+{{- end }}
+{{- end }}
+{{- if and $clusterCollector (not $namespace.kubeEventsDisabled) }}
     logs/k8s_objects_ns_{{ $namespace.name }}:
       receivers:
       - k8sobjects/objects_ns_{{ $namespace.name }}
       processors:
+      - memory_limiter
       - transform/set_k8s_objects_scope
       - k8sdataenricherprocessor
       - transform/add_ns_attributes_ns_{{ $namespace.name }}
       - filter/only_monitored_namespaces
-{{- if $clusterName }}
-      - transform/add_cluster_name
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
 {{- end }}
       - transform/inject_operator_details_into_resource
+      - namespaceusage
       - batch/k8s_objects_ns_{{ $namespace.name }}
       exporters:
 {{- if $debug }}
       - logging
+{{- else if $namespace.debug }}
+      - logging/debug
 {{- end }}
       - otlphttp/lumigo_ns_{{ $namespace.name }}
     logs/k8s_events_ns_{{ $namespace.name }}:
       receivers:
       - k8sobjects/events_ns_{{ $namespace.name }}
       processors:
+      - memory_limiter
       - transform/set_k8s_events_scope
       - k8sdataenricherprocessor
       - transform/add_ns_attributes_ns_{{ $namespace.name }}
       - filter/only_monitored_namespaces
       - transform/inject_operator_details_into_resource
-{{- if $clusterName }}
-      - transform/add_cluster_name
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
 {{- end }}
+      - namespaceusage
       - batch/k8s_events_ns_{{ $namespace.name }}
       exporters:
 {{- if $debug }}
       - logging
+{{- else if $namespace.debug }}
+      - logging/debug
 {{- end }}
       - otlphttp/lumigo_ns_{{ $namespace.name }}
+{{- end }}
+{{- if and $clusterCollector $namespace.nodeLifecycle }}
+    logs/node_lifecycle_ns_{{ $namespace.name }}:
+      receivers:
+      - k8sobjects/node_lifecycle
+      processors:
+      - memory_limiter
+      - transform/set_node_lifecycle_scope
+      - transform/classify_node_lifecycle_events
+      - transform/add_ns_attributes_ns_{{ $namespace.name }}
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
+{{- end }}
+      - transform/inject_operator_details_into_resource
+      - namespaceusage
+      - batch/k8s_events_ns_{{ $namespace.name }}
+      exporters:
+{{- if $debug }}
+      - logging
+{{- else if $namespace.debug }}
+      - logging/debug
+{{- end }}
+      - otlphttp/lumigo_ns_{{ $namespace.name }}
+{{- if not $namespace.metricsDisabled }}
+      - count/node_lifecycle_ns_{{ $namespace.name }}
+    metrics/node_lifecycle_ns_{{ $namespace.name }}:
+      receivers:
+      - count/node_lifecycle_ns_{{ $namespace.name }}
+      exporters:
+{{- if $debug }}
+      - logging
+{{- else if $namespace.debug }}
+      - logging/debug
+{{- end }}
+      - {{ $metricsExporter }}
+{{- end }}
+{{- end }}
+{{- if and $clusterCollector $namespace.prometheus }}
+    metrics/prometheus_ns_{{ $namespace.name }}:
+      receivers:
+      - prometheus/ns_{{ $namespace.name }}
+      processors:
+      - memory_limiter
+      - transform/add_ns_attributes_ns_{{ $namespace.name }}
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
+{{- end }}
+      - transform/inject_operator_details_into_resource
+      - namespaceusage
+      - batch/prometheus_ns_{{ $namespace.name }}
+      exporters:
+{{- if $debug }}
+      - logging
+{{- else if $namespace.debug }}
+      - logging/debug
+{{- end }}
+      - {{ $metricsExporter }}
+{{- end }}
+{{- if $namespace.spanMetrics }}
+    metrics/span_metrics_ns_{{ $namespace.name }}:
+      receivers:
+      - spanmetrics/ns_{{ $namespace.name }}
+      processors:
+      - filter/span_metrics_ns_{{ $namespace.name }}
+{{- if $clusterAttributesEnabled }}
+      - transform/add_cluster_attributes
+{{- end }}
+      - transform/inject_operator_details_into_resource
+      - namespaceusage
+      - batch/span_metrics_ns_{{ $namespace.name }}
+      exporters:
+{{- if $debug }}
+      - logging
+{{- else if $namespace.debug }}
+      - logging/debug
+{{- end }}
+      - {{ $metricsExporter }}
+{{- end }}
 {{ end }}
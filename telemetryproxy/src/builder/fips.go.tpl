@@ -0,0 +1,8 @@
+//go:build fips
+
+// Copied next to the sources generated by the OpenTelemetry Collector builder in FIPS builds, so
+// that all the TLS connections of the telemetry-proxy are restricted to FIPS-approved versions,
+// cipher suites and curves.
+package main
+
+import _ "crypto/tls/fipsonly"
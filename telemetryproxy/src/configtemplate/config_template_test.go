@@ -0,0 +1,373 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configtemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const namespacesWithAdditionalExporter = `[{
+	"name": "my-namespace",
+	"uid": "1234",
+	"token": "t_1234",
+	"additionalExporters": [{"name": "my-backend", "endpoint": "https://otlp.example.com"}]
+}]`
+
+// The exporters that send telemetry to Lumigo
+var lumigoExporters = []string{"otlphttp/lumigo", "otlphttp/lumigo_logs", "otlphttp/lumigo_ns_my-namespace"}
+
+func TestExportersToLumigoCompressWithGzipByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	for _, name := range lumigoExporters {
+		exporter := componentOf(t, config, "exporters", name)
+		assert.Equal(t, "gzip", exporter["compression"], name)
+		assert.NotContains(t, exporter, "write_buffer_size", name)
+	}
+
+	// The additional backends keep the settings of the collector
+	assert.NotContains(t, componentOf(t, config, "exporters", "otlphttp/additional_ns_my-namespace_my-backend"), "compression")
+}
+
+func TestExportCompressionOfExportersToLumigo(t *testing.T) {
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+				"LUMIGO_EXPORT_COMPRESSION": compression,
+			})
+
+			for _, name := range lumigoExporters {
+				assert.Equal(t, compression, componentOf(t, config, "exporters", name)["compression"], name)
+			}
+		})
+	}
+}
+
+func TestExportWriteBufferSizeOfExportersToLumigo(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"LUMIGO_EXPORT_WRITE_BUFFER_SIZE": "1048576",
+	})
+
+	for _, name := range lumigoExporters {
+		assert.Equal(t, 1048576, componentOf(t, config, "exporters", name)["write_buffer_size"], name)
+	}
+	assert.NotContains(t, componentOf(t, config, "exporters", "otlphttp/additional_ns_my-namespace_my-backend"), "write_buffer_size")
+}
+
+func TestRendersWithoutNamespaces(t *testing.T) {
+	config := renderConfig(t, `[]`, nil)
+
+	assert.Equal(t, "gzip", componentOf(t, config, "exporters", "otlphttp/lumigo")["compression"])
+}
+
+func TestExportersToLumigoRetryAndQueueWithDefaults(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	for _, name := range lumigoExporters {
+		exporter := componentOf(t, config, "exporters", name)
+		assert.Equal(t, map[string]interface{}{
+			"enabled":          true,
+			"initial_interval": "5s",
+			"max_interval":     "30s",
+			"max_elapsed_time": "300s",
+		}, exporter["retry_on_failure"], name)
+
+		sendingQueue := exporter["sending_queue"].(map[string]interface{})
+		assert.Equal(t, true, sendingQueue["enabled"], name)
+		assert.Equal(t, 10, sendingQueue["num_consumers"], name)
+		assert.Equal(t, 1000, sendingQueue["queue_size"], name)
+	}
+
+	// The exporters of the namespaces persist their queues, unlike the ones using the headers of the received requests
+	assert.Equal(t, "file_storage/sending_queue", componentOf(t, config, "exporters", "otlphttp/lumigo_ns_my-namespace")["sending_queue"].(map[string]interface{})["storage"])
+	assert.NotContains(t, componentOf(t, config, "exporters", "otlphttp/lumigo")["sending_queue"], "storage")
+}
+
+func TestExportRetryAndQueueOfExportersToLumigo(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"LUMIGO_EXPORT_RETRY_INITIAL_INTERVAL": "1s",
+		"LUMIGO_EXPORT_RETRY_MAX_INTERVAL":     "1m",
+		"LUMIGO_EXPORT_RETRY_MAX_ELAPSED_TIME": "15m",
+		"LUMIGO_EXPORT_QUEUE_NUM_CONSUMERS":    "4",
+		"LUMIGO_EXPORT_QUEUE_SIZE":             "5000",
+	})
+
+	for _, name := range lumigoExporters {
+		exporter := componentOf(t, config, "exporters", name)
+		retryOnFailure := exporter["retry_on_failure"].(map[string]interface{})
+		assert.Equal(t, "1s", retryOnFailure["initial_interval"], name)
+		assert.Equal(t, "1m", retryOnFailure["max_interval"], name)
+		assert.Equal(t, "15m", retryOnFailure["max_elapsed_time"], name)
+
+		sendingQueue := exporter["sending_queue"].(map[string]interface{})
+		assert.Equal(t, 4, sendingQueue["num_consumers"], name)
+		assert.Equal(t, 5000, sendingQueue["queue_size"], name)
+	}
+
+	// The additional backends keep the settings of the collector
+	assert.NotContains(t, componentOf(t, config, "exporters", "otlphttp/additional_ns_my-namespace_my-backend"), "retry_on_failure")
+}
+
+func TestNoClusterAttributesByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	assert.NotContains(t, config["processors"], "transform/add_cluster_attributes")
+}
+
+func TestClusterAttributesAreAddedToAllTelemetry(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"KUBERNETES_CLUSTER_NAME":       "my-cluster",
+		"LUMIGO_DEPLOYMENT_ENVIRONMENT": "production",
+	})
+
+	processor := componentOf(t, config, "processors", "transform/add_cluster_attributes")
+	for _, statements := range []string{"trace_statements", "metric_statements", "log_statements"} {
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"context": "resource",
+			"statements": []interface{}{
+				`set(attributes["k8s.cluster.name"], "my-cluster")`,
+				`set(attributes["deployment.environment"], "production")`,
+			},
+		}}, processor[statements], statements)
+	}
+
+	pipelines := componentOf(t, config, "service", "pipelines")
+	for _, name := range []string{"traces", "logs/usage_analytics_ns_my-namespace", "logs/application_logs_ns_my-namespace"} {
+		pipeline := pipelines[name].(map[string]interface{})
+		assert.Contains(t, pipeline["processors"], "transform/add_cluster_attributes", name)
+	}
+}
+
+func TestDeploymentEnvironmentWithoutClusterName(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"LUMIGO_DEPLOYMENT_ENVIRONMENT": "staging",
+	})
+
+	processor := componentOf(t, config, "processors", "transform/add_cluster_attributes")
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"context":    "resource",
+		"statements": []interface{}{`set(attributes["deployment.environment"], "staging")`},
+	}}, processor["log_statements"])
+}
+
+// The receivers of the cluster-wide telemetry of my-namespace
+var clusterReceivers = []string{"lumigooperatorheartbeat/ns_my-namespace", "k8sobjects/objects_ns_my-namespace", "k8sobjects/events_ns_my-namespace"}
+
+func TestClusterCollectorReceivesClusterWideTelemetry(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"LUMIGO_CLUSTER_COLLECTOR": "true",
+	})
+
+	for _, name := range clusterReceivers {
+		componentOf(t, config, "receivers", name)
+	}
+	assert.Contains(t, config["service"].(map[string]interface{})["pipelines"], "logs/k8s_objects_ns_my-namespace")
+}
+
+func TestOnlyTheClusterCollectorReceivesClusterWideTelemetry(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"not the leader": {"LUMIGO_CLUSTER_COLLECTOR": "false"},
+		"node mode":      {"LUMIGO_TELEMETRY_PROXY_MODE": "node"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := renderConfig(t, namespacesWithAdditionalExporter, env)
+
+			for _, receiver := range clusterReceivers {
+				assert.NotContains(t, config["receivers"], receiver)
+			}
+			pipelines := config["service"].(map[string]interface{})["pipelines"].(map[string]interface{})
+			assert.NotContains(t, pipelines, "logs/k8s_objects_ns_my-namespace")
+			assert.NotContains(t, pipelines, "logs/usage_analytics_ns_my-namespace")
+
+			// The traces of the workloads are received by all the telemetry-proxies
+			componentOf(t, config, "receivers", "otlp")
+			assert.Contains(t, pipelines, "traces")
+		})
+	}
+}
+
+const namespacesWithEndpoints = `[{
+	"name": "my-namespace",
+	"uid": "1234",
+	"token": "t_1234",
+	"spanMetrics": {"dimensions": []},
+	"endpoints": {
+		"traces": {"endpoint": "https://traces.example.com", "headers": {"x-gateway-key": "my-key"}},
+		"logs": {"endpoint": "https://logs.example.com"},
+		"metrics": {"endpoint": "https://metrics.example.com"}
+	}
+}, {
+	"name": "other-namespace",
+	"uid": "5678",
+	"token": "t_5678"
+}]`
+
+func TestNoEndpointsOfNamespacesByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	pipelines := config["service"].(map[string]interface{})["pipelines"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"otlphttp/lumigo"}, pipelines["traces"].(map[string]interface{})["exporters"].([]interface{})[:1])
+	assert.NotContains(t, pipelines, "traces/lumigo")
+	assert.NotContains(t, config["processors"], "filter/default_logs_endpoint")
+}
+
+func TestEndpointsOfNamespaceBySignal(t *testing.T) {
+	config := renderConfig(t, namespacesWithEndpoints, nil)
+
+	tracesExporter := componentOf(t, config, "exporters", "otlphttp/lumigo_traces_ns_my-namespace")
+	assert.Equal(t, "https://traces.example.com", tracesExporter["endpoint"])
+	assert.Equal(t, map[string]interface{}{"authenticator": "headers_setter/lumigo"}, tracesExporter["auth"])
+	assert.Equal(t, map[string]interface{}{"x-gateway-key": "my-key"}, tracesExporter["headers"])
+	assert.Equal(t, "https://logs.example.com", componentOf(t, config, "exporters", "otlphttp/lumigo_logs_ns_my-namespace")["endpoint"])
+	metricsExporter := componentOf(t, config, "exporters", "otlphttp/lumigo_metrics_ns_my-namespace")
+	assert.Equal(t, "https://metrics.example.com", metricsExporter["endpoint"])
+	assert.Equal(t, map[string]interface{}{"authenticator": "lumigoauth/ns_my-namespace"}, metricsExporter["auth"])
+
+	pipelines := config["service"].(map[string]interface{})["pipelines"].(map[string]interface{})
+	pipelineOf := func(name string) map[string]interface{} {
+		require.Contains(t, pipelines, name)
+		return pipelines[name].(map[string]interface{})
+	}
+
+	// The spans of the namespace are sent to its endpoint, and those of the other namespaces to Lumigo
+	assert.Contains(t, pipelineOf("traces")["exporters"], "forward/lumigo_traces")
+	assert.NotContains(t, pipelineOf("traces")["exporters"], "otlphttp/lumigo")
+	assert.Equal(t, []interface{}{"filter/default_traces_endpoint"}, pipelineOf("traces/lumigo")["processors"])
+	assert.Equal(t, []interface{}{"otlphttp/lumigo"}, pipelineOf("traces/lumigo")["exporters"])
+	assert.Equal(t, []interface{}{"filter/traces_endpoint_ns_my-namespace"}, pipelineOf("traces/lumigo_ns_my-namespace")["processors"])
+	assert.Equal(t, []interface{}{"otlphttp/lumigo_traces_ns_my-namespace"}, pipelineOf("traces/lumigo_ns_my-namespace")["exporters"])
+
+	assert.Contains(t, pipelineOf("logs/application_logs_ns_my-namespace")["processors"], "filter/logs_endpoint_ns_my-namespace")
+	assert.Equal(t, []interface{}{"otlphttp/lumigo_logs_ns_my-namespace"}, pipelineOf("logs/application_logs_ns_my-namespace")["exporters"])
+	assert.Contains(t, pipelineOf("logs/application_logs_ns_other-namespace")["processors"], "filter/default_logs_endpoint")
+	assert.Equal(t, []interface{}{"otlphttp/lumigo_logs"}, pipelineOf("logs/application_logs_ns_other-namespace")["exporters"])
+
+	assert.Equal(t, []interface{}{"otlphttp/lumigo_metrics_ns_my-namespace"}, pipelineOf("metrics/span_metrics_ns_my-namespace")["exporters"])
+}
+
+func TestOtlpHeadersOfNamespace(t *testing.T) {
+	// The controller merges the OTLP headers into the endpoints of the namespace, creating those without URL
+	config := renderConfig(t, `[{
+	"name": "my-namespace",
+	"uid": "1234",
+	"token": "t_1234",
+	"otlpHeaders": {"X-Scope-OrgID": "my-tenant"},
+	"endpoints": {
+		"traces": {"endpoint": "https://traces.example.com", "headers": {"X-Scope-OrgID": "my-tenant", "x-gateway-key": "my-key"}},
+		"logs": {"headers": {"X-Scope-OrgID": "my-tenant"}},
+		"metrics": {"headers": {"X-Scope-OrgID": "my-tenant"}}
+	}
+}]`, nil)
+
+	assert.Equal(t, map[string]interface{}{"X-Scope-OrgID": "my-tenant"}, componentOf(t, config, "exporters", "otlphttp/lumigo_ns_my-namespace")["headers"])
+
+	tracesExporter := componentOf(t, config, "exporters", "otlphttp/lumigo_traces_ns_my-namespace")
+	assert.Equal(t, "https://traces.example.com", tracesExporter["endpoint"])
+	assert.Equal(t, map[string]interface{}{"X-Scope-OrgID": "my-tenant", "x-gateway-key": "my-key"}, tracesExporter["headers"])
+
+	// The signals without URL are sent to the Lumigo endpoints
+	logsExporter := componentOf(t, config, "exporters", "otlphttp/lumigo_logs_ns_my-namespace")
+	assert.Equal(t, "https://ga-otlp.lumigo-tracer-edge.golumigo.com", logsExporter["endpoint"])
+	assert.Equal(t, map[string]interface{}{"X-Scope-OrgID": "my-tenant"}, logsExporter["headers"])
+	metricsExporter := componentOf(t, config, "exporters", "otlphttp/lumigo_metrics_ns_my-namespace")
+	assert.Equal(t, "https://ga-otlp.lumigo-tracer-edge.golumigo.com", metricsExporter["endpoint"])
+	assert.Equal(t, map[string]interface{}{"X-Scope-OrgID": "my-tenant"}, metricsExporter["headers"])
+}
+
+func TestNoOtlpHeadersOfNamespacesByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	assert.NotContains(t, componentOf(t, config, "exporters", "otlphttp/lumigo_ns_my-namespace"), "headers")
+}
+
+func TestAwsXRayTraceIdsOfNamespace(t *testing.T) {
+	config := renderConfig(t, `[{
+	"name": "my-namespace",
+	"uid": "1234",
+	"token": "t_1234",
+	"awsXRay": true
+}]`, nil)
+
+	statements := componentOf(t, config, "processors", "transform/add_aws_xray_trace_ids")["trace_statements"].([]interface{})[0].(map[string]interface{})["statements"]
+	assert.Equal(t, []interface{}{
+		`set(attributes["aws.xray.trace_id"], Concat(["1", Substring(trace_id.string, 0, 8), Substring(trace_id.string, 8, 24)], "-")) where resource.attributes["k8s.namespace.name"] == "my-namespace"`,
+	}, statements)
+
+	pipelines := config["service"].(map[string]interface{})["pipelines"].(map[string]interface{})
+	assert.Contains(t, pipelines["traces"].(map[string]interface{})["processors"], "transform/add_aws_xray_trace_ids")
+}
+
+func TestNoAwsXRayTraceIdsByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	assert.NotContains(t, config["processors"], "transform/add_aws_xray_trace_ids")
+}
+
+func TestNoGrpcReceiverByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, nil)
+
+	protocols := componentOf(t, config, "receivers", "otlp")["protocols"].(map[string]interface{})
+	assert.Contains(t, protocols, "http")
+	assert.NotContains(t, protocols, "grpc")
+}
+
+func TestGrpcReceiverKeepalive(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"LUMIGO_RECEIVER_GRPC_ENABLED":                       "true",
+		"LUMIGO_RECEIVER_GRPC_MAX_CONCURRENT_STREAMS":        "100",
+		"LUMIGO_RECEIVER_KEEPALIVE_TIME":                     "2h",
+		"LUMIGO_RECEIVER_KEEPALIVE_TIMEOUT":                  "20s",
+		"LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_IDLE":      "5m",
+		"LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_AGE":       "30m",
+		"LUMIGO_RECEIVER_KEEPALIVE_MAX_CONNECTION_AGE_GRACE": "1m",
+	})
+
+	grpc := componentOf(t, config, "receivers", "otlp")["protocols"].(map[string]interface{})["grpc"].(map[string]interface{})
+	assert.Equal(t, "0.0.0.0:4317", grpc["endpoint"])
+	assert.Equal(t, map[string]interface{}{"authenticator": "lumigoauth/server"}, grpc["auth"])
+	assert.Equal(t, true, grpc["include_metadata"])
+	assert.Equal(t, 100, grpc["max_concurrent_streams"])
+	assert.Equal(t, map[string]interface{}{
+		"server_parameters": map[string]interface{}{
+			"time":                     "2h",
+			"timeout":                  "20s",
+			"max_connection_idle":      "5m",
+			"max_connection_age":       "30m",
+			"max_connection_age_grace": "1m",
+		},
+		"enforcement_policy": map[string]interface{}{
+			"min_time":              "10s",
+			"permit_without_stream": true,
+		},
+	}, grpc["keepalive"])
+}
+
+func TestGrpcReceiverToleratesKeepalivePingsByDefault(t *testing.T) {
+	config := renderConfig(t, namespacesWithAdditionalExporter, map[string]string{
+		"LUMIGO_RECEIVER_GRPC_ENABLED": "true",
+	})
+
+	grpc := componentOf(t, config, "receivers", "otlp")["protocols"].(map[string]interface{})["grpc"].(map[string]interface{})
+	assert.NotContains(t, grpc, "max_concurrent_streams")
+	assert.Equal(t, map[string]interface{}{
+		"enforcement_policy": map[string]interface{}{
+			"min_time":              "10s",
+			"permit_without_stream": true,
+		},
+	}, grpc["keepalive"])
+}
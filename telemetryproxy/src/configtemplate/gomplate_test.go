@@ -0,0 +1,193 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configtemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// The template rendered by the entrypoint of the telemetry-proxy container
+var templatePath = filepath.Join("..", "..", "docker", "etc", "config.yaml.tpl")
+
+// The generation config that the entrypoint of the telemetry-proxy container passes as the `config` datasource
+const defaultGenerationConfig = `{"operator": {"version": "1.0.0", "deployment_method": "Helm"}, "debug": false}`
+
+// The gomplate functions used by the template, with the semantics of gomplate, so that the template can be
+// rendered without it
+func gomplateFuncs(datasources map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"coll": func() collFuncs { return collFuncs{} },
+		"conv": func() convFuncs { return convFuncs{} },
+		"env":  func() envFuncs { return envFuncs{} },
+		"datasource": func(alias string) (interface{}, error) {
+			data, ok := datasources[alias]
+			if !ok {
+				return nil, fmt.Errorf("undefined datasource '%s'", alias)
+			}
+
+			var value interface{}
+			if err := json.Unmarshal([]byte(data), &value); err != nil {
+				return nil, fmt.Errorf("cannot parse datasource '%s': %w", alias, err)
+			}
+			return value, nil
+		},
+		"getenv": envFuncs{}.Getenv,
+		"slice":  collFuncs{}.Slice,
+		"append": func(value interface{}, list []interface{}) []interface{} {
+			return append(append([]interface{}{}, list...), value)
+		},
+		"has": func(in map[string]interface{}, key string) bool {
+			_, ok := in[key]
+			return ok
+		},
+		"join": func(in interface{}, separator string) string {
+			items := []string{}
+			for _, item := range toSlice(in) {
+				items = append(items, fmt.Sprint(item))
+			}
+			return strings.Join(items, separator)
+		},
+		"default": func(defaultValue interface{}, value interface{}) interface{} {
+			if value == nil || reflect.ValueOf(value).IsZero() {
+				return defaultValue
+			}
+			return value
+		},
+		"ternary": func(trueValue interface{}, falseValue interface{}, condition interface{}) interface{} {
+			if (convFuncs{}).ToBool(condition) {
+				return trueValue
+			}
+			return falseValue
+		},
+	}
+}
+
+type collFuncs struct{}
+
+func (collFuncs) Slice(items ...interface{}) []interface{} {
+	return items
+}
+
+type convFuncs struct{}
+
+func (convFuncs) ToBool(in interface{}) bool {
+	switch value := in.(type) {
+	case bool:
+		return value
+	case float64:
+		return value == 1
+	case string:
+		switch strings.ToLower(value) {
+		case "1", "t", "true", "y", "yes", "on":
+			return true
+		}
+	}
+	return false
+}
+
+func (convFuncs) ToInt64(in interface{}) int64 {
+	switch value := in.(type) {
+	case float64:
+		return int64(value)
+	case string:
+		i, _ := strconv.ParseInt(value, 10, 64)
+		return i
+	}
+	return 0
+}
+
+type envFuncs struct{}
+
+// Getenv returns the default value if the environment variable is unset or empty, like gomplate does
+func (envFuncs) Getenv(key string, defaultValue ...string) string {
+	if value := os.Getenv(key); len(value) > 0 || len(defaultValue) < 1 {
+		return value
+	}
+	return defaultValue[0]
+}
+
+func toSlice(in interface{}) []interface{} {
+	value := reflect.ValueOf(in)
+	if value.Kind() != reflect.Slice {
+		return []interface{}{in}
+	}
+
+	items := make([]interface{}, value.Len())
+	for i := range items {
+		items[i] = value.Index(i).Interface()
+	}
+	return items
+}
+
+// renderConfig renders the template with the namespaces to monitor and the environment variables, and returns the
+// parsed configuration of the collector
+func renderConfig(t *testing.T, namespaces string, env map[string]string) map[string]interface{} {
+	t.Helper()
+
+	rendered := renderConfigText(t, namespaces, env)
+
+	config := map[string]interface{}{}
+	require.NoError(t, yaml.Unmarshal([]byte(rendered), &config), rendered)
+
+	return config
+}
+
+// renderConfigText renders the configuration as the telemetry-proxy writes it to file
+func renderConfigText(t *testing.T, namespaces string, env map[string]string) string {
+	t.Helper()
+
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	templateBytes, err := os.ReadFile(templatePath)
+	require.NoError(t, err)
+
+	tpl, err := template.New("config").Funcs(gomplateFuncs(map[string]string{
+		"config":     defaultGenerationConfig,
+		"namespaces": namespaces,
+	})).Parse(string(templateBytes))
+	require.NoError(t, err)
+
+	rendered := &bytes.Buffer{}
+	require.NoError(t, tpl.Execute(rendered, nil))
+
+	return rendered.String()
+}
+
+// componentOf returns the configuration of the component, e.g., the `otlphttp/lumigo` exporter
+func componentOf(t *testing.T, config map[string]interface{}, kind string, name string) map[string]interface{} {
+	t.Helper()
+
+	components, ok := config[kind].(map[string]interface{})
+	require.True(t, ok, "no %s in the configuration", kind)
+
+	component, ok := components[name].(map[string]interface{})
+	require.True(t, ok, "no %s '%s' in the configuration", kind, name)
+
+	return component
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configtemplate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var entrypointPath = filepath.Join("..", "..", "docker", "entrypoint.sh")
+
+const namespacesWithCredentials = `[{
+	"name": "my-namespace",
+	"uid": "1234",
+	"token": "t_0123456789abcdef01234",
+	"otlpHeaders": {"X-Scope-OrgID": "my-tenant"},
+	"endpoints": {
+		"traces": {"endpoint": "https://traces.example.com", "headers": {"X-Scope-OrgID": "my-tenant", "x-gateway-key": "my-\"key\""}}
+	},
+	"additionalExporters": [{"name": "my-backend", "endpoint": "https://otlp.example.com", "headers": {"x-api-key": "my-api-key"}}]
+}]`
+
+// redact runs the given redaction function of the entrypoint of the telemetry-proxy on the text, like the
+// entrypoint does on its debug output
+func redact(t *testing.T, function string, text string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "debug-output")
+	require.NoError(t, os.WriteFile(file, []byte(text), 0644))
+
+	// The entrypoint runs the telemetry-proxy when sourced, so only its redaction is loaded
+	script := `eval "$(sed -n -e '/^readonly REDACT_/p' -e '/^function redact_/,/^}/p' "$0")" && ` + function + ` "$1"`
+	output, err := exec.Command("bash", "-c", script, entrypointPath, file).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	return string(output)
+}
+
+func TestRedactedConfigDump(t *testing.T) {
+	rendered := renderConfigText(t, namespacesWithCredentials, nil)
+	require.Contains(t, rendered, "t_0123456789abcdef01234")
+
+	redacted := redact(t, "redact_configs", rendered)
+
+	for _, secret := range []string{"t_0123456789abcdef01234", "my-tenant", `my-\"key`, "my-api-key"} {
+		assert.NotContains(t, redacted, secret)
+	}
+	assert.Contains(t, redacted, "t_***")
+	assert.Contains(t, redacted, `"X-Scope-OrgID": "***"`)
+	assert.Contains(t, redacted, "https://traces.example.com")
+}
+
+func TestRedactedNamespacesDump(t *testing.T) {
+	redacted := redact(t, "redact_namespaces", namespacesWithCredentials)
+
+	for _, secret := range []string{"t_0123456789abcdef01234", "my-tenant", `my-\"key`, "my-api-key"} {
+		assert.NotContains(t, redacted, secret)
+	}
+	assert.Contains(t, redacted, `"token": "t_***"`)
+	assert.Contains(t, redacted, "my-namespace")
+}
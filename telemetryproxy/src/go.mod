@@ -4,4 +4,8 @@ go 1.20
 
 replace "github.com/open-telemetry/opentelemetry-collector-contrib/processor/k8sdataenricherprocessor v0.90.0" => ./processor/k8sdataenricherprocessor
 
+replace "github.com/open-telemetry/opentelemetry-collector-contrib/processor/namespaceusageprocessor v0.90.0" => ./processor/namespaceusageprocessor
+
+replace "github.com/open-telemetry/opentelemetry-collector-contrib/processor/ratelimiterprocessor v0.90.0" => ./processor/ratelimiterprocessor
+
 replace tools => ./internal/tools
@@ -0,0 +1,22 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaceusageprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/namespaceusageprocessor"
+
+// Config has no settings: the processor counts the telemetry of every namespace
+type Config struct{}
+
+func (cfg *Config) Validate() error {
+	return nil
+}
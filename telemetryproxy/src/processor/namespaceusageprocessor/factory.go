@@ -0,0 +1,110 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaceusageprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/namespaceusageprocessor"
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "namespaceusage"
+	// The stability level of the processor.
+	stability = component.StabilityLevelAlpha
+)
+
+var consumerCapabilities = consumer.Capabilities{MutatesData: false}
+
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		typeStr,
+		createDefaultConfig,
+		processor.WithTraces(createTracesProcessor, stability),
+		processor.WithLogs(createLogsProcessor, stability),
+		processor.WithMetrics(createMetricsProcessor, stability),
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
+
+// The processors are synchronous, so the context of the incoming request (and with it the
+// headers that `headers_setter` extensions rely on) is passed along to the next consumer
+
+func createTracesProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	next consumer.Traces,
+) (processor.Traces, error) {
+	up, err := newNamespaceUsageProcessor(set)
+	if err != nil {
+		return nil, err
+	}
+
+	return processorhelper.NewTracesProcessor(
+		ctx,
+		set,
+		cfg,
+		next,
+		up.processTraces,
+		processorhelper.WithCapabilities(consumerCapabilities))
+}
+
+func createLogsProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	next consumer.Logs,
+) (processor.Logs, error) {
+	up, err := newNamespaceUsageProcessor(set)
+	if err != nil {
+		return nil, err
+	}
+
+	return processorhelper.NewLogsProcessor(
+		ctx,
+		set,
+		cfg,
+		next,
+		up.processLogs,
+		processorhelper.WithCapabilities(consumerCapabilities))
+}
+
+func createMetricsProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	next consumer.Metrics,
+) (processor.Metrics, error) {
+	up, err := newNamespaceUsageProcessor(set)
+	if err != nil {
+		return nil, err
+	}
+
+	return processorhelper.NewMetricsProcessor(
+		ctx,
+		set,
+		cfg,
+		next,
+		up.processMetrics,
+		processorhelper.WithCapabilities(consumerCapabilities))
+}
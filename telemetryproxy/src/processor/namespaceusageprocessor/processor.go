@@ -0,0 +1,194 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaceusageprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/namespaceusageprocessor"
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	K8SNamespaceNameKey = "k8s.namespace.name"
+	SignalKey           = "signal"
+
+	signalSpans        = "spans"
+	signalLogRecords   = "log_records"
+	signalMetricPoints = "metric_points"
+
+	scopeName = "github.com/open-telemetry/opentelemetry-collector-contrib/processor/namespaceusageprocessor"
+)
+
+type namespaceUsageProcessor struct {
+	itemsCounter metric.Int64Counter
+	bytesCounter metric.Int64Counter
+
+	tracesMarshaler  ptrace.ProtoMarshaler
+	logsMarshaler    plog.ProtoMarshaler
+	metricsMarshaler pmetric.ProtoMarshaler
+}
+
+func newNamespaceUsageProcessor(set processor.CreateSettings) (*namespaceUsageProcessor, error) {
+	itemsCounter, err := set.MeterProvider.Meter(scopeName).Int64Counter(
+		"processor_namespaceusage_items",
+		metric.WithDescription("Number of spans, log records or metric points that went through the processor, by namespace"),
+		metric.WithUnit("{items}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the items counter: %w", err)
+	}
+
+	bytesCounter, err := set.MeterProvider.Meter(scopeName).Int64Counter(
+		"processor_namespaceusage_bytes",
+		metric.WithDescription("Size, in the OTLP protobuf encoding, of the telemetry that went through the processor, by namespace"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the bytes counter: %w", err)
+	}
+
+	return &namespaceUsageProcessor{
+		itemsCounter: itemsCounter,
+		bytesCounter: bytesCounter,
+	}, nil
+}
+
+func (up *namespaceUsageProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		resourceSpans := td.ResourceSpans().At(i)
+		namespaceName, found := namespaceNameOf(resourceSpans.Resource())
+		if !found {
+			continue
+		}
+
+		var spans int
+		for j := 0; j < resourceSpans.ScopeSpans().Len(); j++ {
+			spans += resourceSpans.ScopeSpans().At(j).Spans().Len()
+		}
+		if spans == 0 {
+			continue
+		}
+
+		// Measuring the size of one resource requires copying it, unless it is the only one
+		measured := td
+		if td.ResourceSpans().Len() > 1 {
+			measured = ptrace.NewTraces()
+			resourceSpans.CopyTo(measured.ResourceSpans().AppendEmpty())
+		}
+
+		up.record(ctx, namespaceName, signalSpans, spans, up.tracesMarshaler.TracesSize(measured))
+	}
+
+	return td, nil
+}
+
+func (up *namespaceUsageProcessor) processLogs(ctx context.Context, ld plog.Logs) (plog.Logs, error) {
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		resourceLogs := ld.ResourceLogs().At(i)
+		namespaceName, found := namespaceNameOf(resourceLogs.Resource())
+		if !found {
+			continue
+		}
+
+		var logRecords int
+		for j := 0; j < resourceLogs.ScopeLogs().Len(); j++ {
+			logRecords += resourceLogs.ScopeLogs().At(j).LogRecords().Len()
+		}
+		if logRecords == 0 {
+			continue
+		}
+
+		measured := ld
+		if ld.ResourceLogs().Len() > 1 {
+			measured = plog.NewLogs()
+			resourceLogs.CopyTo(measured.ResourceLogs().AppendEmpty())
+		}
+
+		up.record(ctx, namespaceName, signalLogRecords, logRecords, up.logsMarshaler.LogsSize(measured))
+	}
+
+	return ld, nil
+}
+
+func (up *namespaceUsageProcessor) processMetrics(ctx context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		resourceMetrics := md.ResourceMetrics().At(i)
+		namespaceName, found := namespaceNameOf(resourceMetrics.Resource())
+		if !found {
+			continue
+		}
+
+		var metricPoints int
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			metrics := resourceMetrics.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metricPoints += dataPointCount(metrics.At(k))
+			}
+		}
+		if metricPoints == 0 {
+			continue
+		}
+
+		measured := md
+		if md.ResourceMetrics().Len() > 1 {
+			measured = pmetric.NewMetrics()
+			resourceMetrics.CopyTo(measured.ResourceMetrics().AppendEmpty())
+		}
+
+		up.record(ctx, namespaceName, signalMetricPoints, metricPoints, up.metricsMarshaler.MetricsSize(measured))
+	}
+
+	return md, nil
+}
+
+func (up *namespaceUsageProcessor) record(ctx context.Context, namespaceName string, signal string, items int, bytes int) {
+	attributes := metric.WithAttributes(attribute.String(K8SNamespaceNameKey, namespaceName), attribute.String(SignalKey, signal))
+
+	up.itemsCounter.Add(ctx, int64(items), attributes)
+	up.bytesCounter.Add(ctx, int64(bytes), attributes)
+}
+
+func namespaceNameOf(resource pcommon.Resource) (string, bool) {
+	namespaceName, found := resource.Attributes().Get(K8SNamespaceNameKey)
+	if !found || len(namespaceName.Str()) < 1 {
+		return "", false
+	}
+
+	return namespaceName.Str(), true
+}
+
+func dataPointCount(m pmetric.Metric) int {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		return m.Gauge().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		return m.Sum().DataPoints().Len()
+	case pmetric.MetricTypeHistogram:
+		return m.Histogram().DataPoints().Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		return m.ExponentialHistogram().DataPoints().Len()
+	case pmetric.MetricTypeSummary:
+		return m.Summary().DataPoints().Len()
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaceusageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// usageKey identifies the data points of the counters of the processor
+type usageKey struct {
+	namespaceName string
+	signal        string
+}
+
+func newTestProcessor(t *testing.T) (*namespaceUsageProcessor, *sdkmetric.ManualReader) {
+	reader := sdkmetric.NewManualReader()
+
+	settings := processortest.NewNopCreateSettings()
+	settings.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	up, err := newNamespaceUsageProcessor(settings)
+	require.NoError(t, err)
+
+	return up, reader
+}
+
+func counterOf(t *testing.T, reader *sdkmetric.ManualReader, name string) map[usageKey]int64 {
+	resourceMetrics := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+
+	values := make(map[usageKey]int64)
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			for _, dataPoint := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				namespaceName, _ := dataPoint.Attributes.Value(K8SNamespaceNameKey)
+				signal, _ := dataPoint.Attributes.Value(SignalKey)
+				values[usageKey{namespaceName: namespaceName.AsString(), signal: signal.AsString()}] += dataPoint.Value
+			}
+		}
+	}
+
+	return values
+}
+
+func newTraces(namespaceName string, spans int) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	appendResourceSpans(traces, namespaceName, spans)
+	return traces
+}
+
+func appendResourceSpans(traces ptrace.Traces, namespaceName string, spans int) {
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	if len(namespaceName) > 0 {
+		resourceSpans.Resource().Attributes().PutStr(K8SNamespaceNameKey, namespaceName)
+	}
+
+	scopeSpans := resourceSpans.ScopeSpans().AppendEmpty()
+	for i := 0; i < spans; i++ {
+		scopeSpans.Spans().AppendEmpty().SetName("span")
+	}
+}
+
+func TestProcessTracesCountsTheSpansOfEachNamespace(t *testing.T) {
+	up, reader := newTestProcessor(t)
+
+	traces := ptrace.NewTraces()
+	appendResourceSpans(traces, "my-namespace", 3)
+	appendResourceSpans(traces, "my-other-namespace", 2)
+	appendResourceSpans(traces, "", 5)
+
+	processed, err := up.processTraces(context.Background(), traces)
+	require.NoError(t, err)
+	assert.Equal(t, 10, processed.SpanCount())
+
+	_, err = up.processTraces(context.Background(), newTraces("my-namespace", 4))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[usageKey]int64{
+		{namespaceName: "my-namespace", signal: signalSpans}:       7,
+		{namespaceName: "my-other-namespace", signal: signalSpans}: 2,
+	}, counterOf(t, reader, "processor_namespaceusage_items"))
+}
+
+func TestProcessTracesMeasuresTheSizeOfEachNamespace(t *testing.T) {
+	up, reader := newTestProcessor(t)
+
+	traces := ptrace.NewTraces()
+	appendResourceSpans(traces, "my-namespace", 3)
+	appendResourceSpans(traces, "my-other-namespace", 2)
+
+	_, err := up.processTraces(context.Background(), traces)
+	require.NoError(t, err)
+
+	// The size of each namespace is the size of its resource alone
+	marshaler := ptrace.ProtoMarshaler{}
+	assert.Equal(t, map[usageKey]int64{
+		{namespaceName: "my-namespace", signal: signalSpans}:       int64(marshaler.TracesSize(newTraces("my-namespace", 3))),
+		{namespaceName: "my-other-namespace", signal: signalSpans}: int64(marshaler.TracesSize(newTraces("my-other-namespace", 2))),
+	}, counterOf(t, reader, "processor_namespaceusage_bytes"))
+}
+
+func TestProcessLogsCountsTheLogRecordsOfEachNamespace(t *testing.T) {
+	up, reader := newTestProcessor(t)
+
+	logs := plog.NewLogs()
+	for namespaceName, logRecords := range map[string]int{"my-namespace": 2, "": 1} {
+		resourceLogs := logs.ResourceLogs().AppendEmpty()
+		if len(namespaceName) > 0 {
+			resourceLogs.Resource().Attributes().PutStr(K8SNamespaceNameKey, namespaceName)
+		}
+
+		scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+		for i := 0; i < logRecords; i++ {
+			scopeLogs.LogRecords().AppendEmpty().Body().SetStr("log")
+		}
+	}
+
+	_, err := up.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[usageKey]int64{
+		{namespaceName: "my-namespace", signal: signalLogRecords}: 2,
+	}, counterOf(t, reader, "processor_namespaceusage_items"))
+}
+
+func TestProcessMetricsCountsTheMetricPointsOfEachNamespace(t *testing.T) {
+	up, reader := newTestProcessor(t)
+
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+	resourceMetrics.Resource().Attributes().PutStr(K8SNamespaceNameKey, "my-namespace")
+
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	gauge := scopeMetrics.Metrics().AppendEmpty().SetEmptyGauge()
+	gauge.DataPoints().AppendEmpty().SetIntValue(1)
+	gauge.DataPoints().AppendEmpty().SetIntValue(2)
+	scopeMetrics.Metrics().AppendEmpty().SetEmptySum().DataPoints().AppendEmpty().SetIntValue(3)
+	scopeMetrics.Metrics().AppendEmpty().SetEmptyHistogram().DataPoints().AppendEmpty().SetCount(4)
+
+	_, err := up.processMetrics(context.Background(), metrics)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[usageKey]int64{
+		{namespaceName: "my-namespace", signal: signalMetricPoints}: 4,
+	}, counterOf(t, reader, "processor_namespaceusage_items"))
+}
+
+func TestNamespacesWithoutTelemetryAreNotCounted(t *testing.T) {
+	up, reader := newTestProcessor(t)
+
+	_, err := up.processTraces(context.Background(), newTraces("my-namespace", 0))
+	require.NoError(t, err)
+
+	assert.Empty(t, counterOf(t, reader, "processor_namespaceusage_items"))
+	assert.Empty(t, counterOf(t, reader, "processor_namespaceusage_bytes"))
+}
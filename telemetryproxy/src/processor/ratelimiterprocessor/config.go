@@ -0,0 +1,91 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiterprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/ratelimiterprocessor"
+
+import (
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	// The maximum amount of spans per second, and per day, accepted for each
+	// namespace; spans of namespaces without a limit are never dropped
+	Limits []NamespaceLimit `mapstructure:"limits"`
+	// Path of the file to which the processor periodically writes how many
+	// spans have been dropped, and how much of its daily quota has been used,
+	// for each namespace; optional
+	StatusFile string `mapstructure:"status_file"`
+	// How often the status file is written
+	StatusInterval time.Duration `mapstructure:"status_interval"`
+}
+
+type NamespaceLimit struct {
+	Namespace      string `mapstructure:"namespace"`
+	SpansPerSecond int    `mapstructure:"spans_per_second"`
+	// The daily quota of the namespace, which resets at midnight UTC; optional
+	Quota *NamespaceQuota `mapstructure:"quota"`
+}
+
+type NamespaceQuota struct {
+	// The maximum amount of spans per day; zero means no limit
+	SpansPerDay int64 `mapstructure:"spans_per_day"`
+	// The maximum amount of bytes of spans, in their OTLP protobuf encoding, per day;
+	// zero means no limit
+	BytesPerDay int64 `mapstructure:"bytes_per_day"`
+	// The percentage of traces whose spans are still accepted once the quota is exceeded
+	SamplingPercentage int `mapstructure:"sampling_percentage"`
+}
+
+func (cfg *Config) Validate() error {
+	namespaces := make(map[string]bool)
+	for _, limit := range cfg.Limits {
+		if len(limit.Namespace) < 1 {
+			return fmt.Errorf("the 'namespace' field of a limit cannot be empty")
+		}
+
+		if limit.SpansPerSecond < 0 {
+			return fmt.Errorf("the 'spans_per_second' of the limit of namespace '%s' cannot be negative; found: %d", limit.Namespace, limit.SpansPerSecond)
+		}
+
+		if limit.SpansPerSecond == 0 && limit.Quota == nil {
+			return fmt.Errorf("the limit of namespace '%s' must have either 'spans_per_second' or a 'quota'", limit.Namespace)
+		}
+
+		if quota := limit.Quota; quota != nil {
+			if quota.SpansPerDay < 0 || quota.BytesPerDay < 0 {
+				return fmt.Errorf("the 'spans_per_day' and 'bytes_per_day' of the quota of namespace '%s' cannot be negative", limit.Namespace)
+			}
+
+			if quota.SpansPerDay == 0 && quota.BytesPerDay == 0 {
+				return fmt.Errorf("the quota of namespace '%s' must have either 'spans_per_day' or 'bytes_per_day'", limit.Namespace)
+			}
+
+			if quota.SamplingPercentage < 0 || quota.SamplingPercentage > 100 {
+				return fmt.Errorf("the 'sampling_percentage' of the quota of namespace '%s' must be between 0 and 100; found: %d", limit.Namespace, quota.SamplingPercentage)
+			}
+		}
+
+		if namespaces[limit.Namespace] {
+			return fmt.Errorf("multiple limits found for namespace '%s'", limit.Namespace)
+		}
+		namespaces[limit.Namespace] = true
+	}
+
+	if cfg.StatusInterval <= 0 {
+		return fmt.Errorf("the 'status_interval' must be greater than zero; found: %s", cfg.StatusInterval)
+	}
+
+	return nil
+}
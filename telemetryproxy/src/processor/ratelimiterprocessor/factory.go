@@ -0,0 +1,74 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiterprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/ratelimiterprocessor"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "ratelimiter"
+	// The stability level of the processor.
+	stability = component.StabilityLevelAlpha
+
+	defaultStatusInterval = 10 * time.Second
+)
+
+var consumerCapabilities = consumer.Capabilities{MutatesData: true}
+
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		typeStr,
+		createDefaultConfig,
+		processor.WithTraces(createTracesProcessor, stability),
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		StatusInterval: defaultStatusInterval,
+	}
+}
+
+func createTracesProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	next consumer.Traces,
+) (processor.Traces, error) {
+	rp, err := newRateLimiterProcessor(set, cfg.(*Config))
+	if err != nil {
+		return nil, err
+	}
+
+	// The processor is synchronous, so the context of the incoming request (and with it the
+	// headers that `headers_setter` extensions rely on) is passed along to the next consumer
+	return processorhelper.NewTracesProcessor(
+		ctx,
+		set,
+		cfg,
+		next,
+		rp.processTraces,
+		processorhelper.WithCapabilities(consumerCapabilities),
+		processorhelper.WithStart(rp.Start),
+		processorhelper.WithShutdown(rp.Shutdown))
+}
@@ -0,0 +1,381 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiterprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/ratelimiterprocessor"
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	K8SNamespaceNameKey = "k8s.namespace.name"
+
+	scopeName = "github.com/open-telemetry/opentelemetry-collector-contrib/processor/ratelimiterprocessor"
+
+	quotaDayFormat = "2006-01-02"
+)
+
+// NamespaceStatus is what the processor reports in the status file about the spans
+// it dropped for one namespace
+type NamespaceStatus struct {
+	DroppedSpans  int64     `json:"droppedSpans"`
+	LastDroppedAt time.Time `json:"lastDroppedAt"`
+	// The usage of the daily quota of the namespace, if it has one
+	Quota *QuotaStatus `json:"quota,omitempty"`
+}
+
+// QuotaStatus is the usage of the daily quota of one namespace
+type QuotaStatus struct {
+	// The day, in UTC and in the `2006-01-02` format, the usage refers to
+	Day           string `json:"day"`
+	AcceptedSpans int64  `json:"acceptedSpans"`
+	AcceptedBytes int64  `json:"acceptedBytes"`
+	// The spans dropped during the day because the quota was exceeded
+	DroppedSpans int64 `json:"droppedSpans"`
+	// When the quota was exceeded during the day, if it was
+	ExceededAt *time.Time `json:"exceededAt,omitempty"`
+}
+
+type rateLimiterProcessor struct {
+	logger                   *zap.Logger
+	config                   *Config
+	limiters                 map[string]*rate.Limiter
+	quotas                   map[string]*NamespaceQuota
+	marshaler                ptrace.ProtoMarshaler
+	droppedSpansCounter      metric.Int64Counter
+	quotaDroppedSpansCounter metric.Int64Counter
+	acceptedSpansCounter     metric.Int64Counter
+	acceptedBytesCounter     metric.Int64Counter
+
+	statusMutex   sync.Mutex
+	status        map[string]*NamespaceStatus
+	statusChanged bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newRateLimiterProcessor(set processor.CreateSettings, config *Config) (*rateLimiterProcessor, error) {
+	droppedSpansCounter, err := set.MeterProvider.Meter(scopeName).Int64Counter(
+		"processor_ratelimiter_dropped_spans",
+		metric.WithDescription("Number of spans dropped because their namespace exceeded its maximum amount of spans per second"),
+		metric.WithUnit("{spans}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the dropped spans counter: %w", err)
+	}
+
+	quotaDroppedSpansCounter, err := set.MeterProvider.Meter(scopeName).Int64Counter(
+		"processor_ratelimiter_quota_dropped_spans",
+		metric.WithDescription("Number of spans dropped because their namespace exceeded its daily quota"),
+		metric.WithUnit("{spans}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the quota dropped spans counter: %w", err)
+	}
+
+	// The accepted spans and bytes are counted only for the namespaces with a quota
+	acceptedSpansCounter, err := set.MeterProvider.Meter(scopeName).Int64Counter(
+		"processor_ratelimiter_quota_accepted_spans",
+		metric.WithDescription("Number of spans accepted for namespaces with a daily quota"),
+		metric.WithUnit("{spans}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the accepted spans counter: %w", err)
+	}
+
+	acceptedBytesCounter, err := set.MeterProvider.Meter(scopeName).Int64Counter(
+		"processor_ratelimiter_quota_accepted_bytes",
+		metric.WithDescription("Size, in the OTLP protobuf encoding, of the spans accepted for namespaces with a daily quota"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the accepted bytes counter: %w", err)
+	}
+
+	limiters := make(map[string]*rate.Limiter)
+	quotas := make(map[string]*NamespaceQuota)
+	for _, limit := range config.Limits {
+		if limit.SpansPerSecond > 0 {
+			// The burst allows up to one second worth of spans to arrive at once
+			limiters[limit.Namespace] = rate.NewLimiter(rate.Limit(limit.SpansPerSecond), limit.SpansPerSecond)
+		}
+
+		if limit.Quota != nil {
+			quotas[limit.Namespace] = limit.Quota
+		}
+	}
+
+	return &rateLimiterProcessor{
+		logger:                   set.Logger,
+		config:                   config,
+		limiters:                 limiters,
+		quotas:                   quotas,
+		droppedSpansCounter:      droppedSpansCounter,
+		quotaDroppedSpansCounter: quotaDroppedSpansCounter,
+		acceptedSpansCounter:     acceptedSpansCounter,
+		acceptedBytesCounter:     acceptedBytesCounter,
+		status:                   make(map[string]*NamespaceStatus),
+		stopCh:                   make(chan struct{}),
+	}, nil
+}
+
+func (rp *rateLimiterProcessor) Start(_ context.Context, _ component.Host) error {
+	if len(rp.config.StatusFile) < 1 {
+		return nil
+	}
+
+	// The processor is re-created when the configurations are reloaded, and we do not
+	// want to forget about the spans dropped, nor the quotas used, until then
+	if statusBytes, err := os.ReadFile(rp.config.StatusFile); err == nil {
+		if err := json.Unmarshal(statusBytes, &rp.status); err != nil {
+			rp.logger.Warn("Cannot parse the existing status file, it will be overwritten", zap.String("path", rp.config.StatusFile), zap.Error(err))
+			rp.status = make(map[string]*NamespaceStatus)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read the status file '%s': %w", rp.config.StatusFile, err)
+	}
+
+	rp.wg.Add(1)
+	go rp.writeStatusPeriodically()
+
+	return nil
+}
+
+func (rp *rateLimiterProcessor) Shutdown(_ context.Context) error {
+	close(rp.stopCh)
+	rp.wg.Wait()
+	return nil
+}
+
+func (rp *rateLimiterProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	now := time.Now()
+
+	td.ResourceSpans().RemoveIf(func(resourceSpans ptrace.ResourceSpans) bool {
+		namespaceName, found := resourceSpans.Resource().Attributes().Get(K8SNamespaceNameKey)
+		if !found {
+			return false
+		}
+
+		limiter := rp.limiters[namespaceName.Str()]
+		quota := rp.quotas[namespaceName.Str()]
+		if limiter == nil && quota == nil {
+			return false
+		}
+
+		isQuotaExceeded := quota != nil && rp.isQuotaExceeded(namespaceName.Str(), now)
+
+		var droppedSpans, quotaDroppedSpans int64
+		resourceSpans.ScopeSpans().RemoveIf(func(scopeSpans ptrace.ScopeSpans) bool {
+			scopeSpans.Spans().RemoveIf(func(span ptrace.Span) bool {
+				// The spans dropped by the quota do not use up the spans per second of the namespace
+				if isQuotaExceeded && !isSampled(span.TraceID(), quota.SamplingPercentage) {
+					quotaDroppedSpans++
+					return true
+				}
+
+				if limiter == nil || limiter.AllowN(now, 1) {
+					return false
+				}
+
+				droppedSpans++
+				return true
+			})
+
+			return scopeSpans.Spans().Len() == 0
+		})
+
+		if droppedSpans > 0 {
+			rp.recordDroppedSpans(ctx, namespaceName.Str(), droppedSpans, now)
+		}
+
+		if quota != nil {
+			rp.recordQuotaUsage(ctx, namespaceName.Str(), quota, resourceSpans, quotaDroppedSpans, now)
+		}
+
+		return resourceSpans.ScopeSpans().Len() == 0
+	})
+
+	if td.ResourceSpans().Len() == 0 {
+		return td, processorhelper.ErrSkipProcessingData
+	}
+
+	return td, nil
+}
+
+func (rp *rateLimiterProcessor) recordDroppedSpans(ctx context.Context, namespaceName string, droppedSpans int64, now time.Time) {
+	rp.droppedSpansCounter.Add(ctx, droppedSpans, metric.WithAttributes(attribute.String(K8SNamespaceNameKey, namespaceName)))
+
+	rp.logger.Debug("Dropped spans exceeding the namespace limit", zap.String("namespace", namespaceName), zap.Int64("dropped_spans", droppedSpans))
+
+	rp.statusMutex.Lock()
+	defer rp.statusMutex.Unlock()
+
+	namespaceStatus := rp.namespaceStatusOf(namespaceName)
+	namespaceStatus.DroppedSpans += droppedSpans
+	namespaceStatus.LastDroppedAt = now
+	rp.statusChanged = true
+}
+
+func (rp *rateLimiterProcessor) isQuotaExceeded(namespaceName string, now time.Time) bool {
+	rp.statusMutex.Lock()
+	defer rp.statusMutex.Unlock()
+
+	return rp.quotaStatusOf(namespaceName, now).ExceededAt != nil
+}
+
+func (rp *rateLimiterProcessor) recordQuotaUsage(ctx context.Context, namespaceName string, quota *NamespaceQuota, resourceSpans ptrace.ResourceSpans, droppedSpans int64, now time.Time) {
+	namespaceAttribute := metric.WithAttributes(attribute.String(K8SNamespaceNameKey, namespaceName))
+
+	var acceptedSpans, acceptedBytes int64
+	for i := 0; i < resourceSpans.ScopeSpans().Len(); i++ {
+		acceptedSpans += int64(resourceSpans.ScopeSpans().At(i).Spans().Len())
+	}
+	if acceptedSpans > 0 {
+		traces := ptrace.NewTraces()
+		resourceSpans.CopyTo(traces.ResourceSpans().AppendEmpty())
+		acceptedBytes = int64(rp.marshaler.TracesSize(traces))
+
+		rp.acceptedSpansCounter.Add(ctx, acceptedSpans, namespaceAttribute)
+		rp.acceptedBytesCounter.Add(ctx, acceptedBytes, namespaceAttribute)
+	}
+
+	if droppedSpans > 0 {
+		rp.quotaDroppedSpansCounter.Add(ctx, droppedSpans, namespaceAttribute)
+		rp.logger.Debug("Dropped spans exceeding the namespace quota", zap.String("namespace", namespaceName), zap.Int64("dropped_spans", droppedSpans))
+	}
+
+	if acceptedSpans == 0 && droppedSpans == 0 {
+		return
+	}
+
+	rp.statusMutex.Lock()
+	defer rp.statusMutex.Unlock()
+
+	quotaStatus := rp.quotaStatusOf(namespaceName, now)
+	quotaStatus.AcceptedSpans += acceptedSpans
+	quotaStatus.AcceptedBytes += acceptedBytes
+	quotaStatus.DroppedSpans += droppedSpans
+	rp.statusChanged = true
+
+	if quotaStatus.ExceededAt != nil {
+		return
+	}
+
+	if (quota.SpansPerDay > 0 && quotaStatus.AcceptedSpans >= quota.SpansPerDay) || (quota.BytesPerDay > 0 && quotaStatus.AcceptedBytes >= quota.BytesPerDay) {
+		exceededAt := now
+		quotaStatus.ExceededAt = &exceededAt
+
+		rp.logger.Info(
+			"The namespace exceeded its daily quota; its spans are sampled until the end of the day",
+			zap.String("namespace", namespaceName),
+			zap.Int64("accepted_spans", quotaStatus.AcceptedSpans),
+			zap.Int64("accepted_bytes", quotaStatus.AcceptedBytes),
+			zap.Int("sampling_percentage", quota.SamplingPercentage),
+		)
+	}
+}
+
+// The caller must hold the status mutex
+func (rp *rateLimiterProcessor) namespaceStatusOf(namespaceName string) *NamespaceStatus {
+	namespaceStatus, found := rp.status[namespaceName]
+	if !found {
+		namespaceStatus = &NamespaceStatus{}
+		rp.status[namespaceName] = namespaceStatus
+	}
+
+	return namespaceStatus
+}
+
+// The caller must hold the status mutex
+func (rp *rateLimiterProcessor) quotaStatusOf(namespaceName string, now time.Time) *QuotaStatus {
+	namespaceStatus := rp.namespaceStatusOf(namespaceName)
+
+	// The quotas reset at midnight UTC
+	day := now.UTC().Format(quotaDayFormat)
+	if namespaceStatus.Quota == nil || namespaceStatus.Quota.Day != day {
+		namespaceStatus.Quota = &QuotaStatus{Day: day}
+		rp.statusChanged = true
+	}
+
+	return namespaceStatus.Quota
+}
+
+// Trace IDs are random, so sampling on them keeps or drops all the spans of a trace together,
+// also across telemetry-proxies
+func isSampled(traceID pcommon.TraceID, percentage int) bool {
+	return binary.BigEndian.Uint64(traceID[8:])%100 < uint64(percentage)
+}
+
+func (rp *rateLimiterProcessor) writeStatusPeriodically() {
+	defer rp.wg.Done()
+
+	ticker := time.NewTicker(rp.config.StatusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rp.stopCh:
+			rp.writeStatus()
+			return
+		case <-ticker.C:
+			rp.writeStatus()
+		}
+	}
+}
+
+func (rp *rateLimiterProcessor) writeStatus() {
+	rp.statusMutex.Lock()
+	if !rp.statusChanged {
+		rp.statusMutex.Unlock()
+		return
+	}
+
+	statusBytes, err := json.Marshal(rp.status)
+	rp.statusChanged = false
+	rp.statusMutex.Unlock()
+
+	if err != nil {
+		rp.logger.Error("Cannot marshal the rate-limiting status", zap.Error(err))
+		return
+	}
+
+	// Write to a temporary file and rename it, so that readers never see a partially-written file
+	tempFile := filepath.Join(filepath.Dir(rp.config.StatusFile), fmt.Sprintf(".%s.tmp", filepath.Base(rp.config.StatusFile)))
+	if err := os.WriteFile(tempFile, statusBytes, 0644); err != nil {
+		rp.logger.Error("Cannot write the rate-limiting status", zap.String("path", tempFile), zap.Error(err))
+		return
+	}
+
+	if err := os.Rename(tempFile, rp.config.StatusFile); err != nil {
+		rp.logger.Error("Cannot write the rate-limiting status", zap.String("path", rp.config.StatusFile), zap.Error(err))
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright 2023 Lumigo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiterprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/collector/processor/processortest"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newTraces returns traces with the given amount of spans for each namespace; the spans
+// under the empty namespace have no `k8s.namespace.name` resource attribute
+func newTraces(spansPerNamespace map[string]int) ptrace.Traces {
+	traces := ptrace.NewTraces()
+
+	for namespaceName, spans := range spansPerNamespace {
+		resourceSpans := traces.ResourceSpans().AppendEmpty()
+		if len(namespaceName) > 0 {
+			resourceSpans.Resource().Attributes().PutStr(K8SNamespaceNameKey, namespaceName)
+		}
+
+		scopeSpans := resourceSpans.ScopeSpans().AppendEmpty()
+		for i := 0; i < spans; i++ {
+			scopeSpans.Spans().AppendEmpty().SetName("span")
+		}
+	}
+
+	return traces
+}
+
+func spansPerNamespaceOf(traces ptrace.Traces) map[string]int {
+	spansPerNamespace := make(map[string]int)
+
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		resourceSpans := traces.ResourceSpans().At(i)
+
+		var namespaceName string
+		if namespace, found := resourceSpans.Resource().Attributes().Get(K8SNamespaceNameKey); found {
+			namespaceName = namespace.Str()
+		}
+
+		for j := 0; j < resourceSpans.ScopeSpans().Len(); j++ {
+			spansPerNamespace[namespaceName] += resourceSpans.ScopeSpans().At(j).Spans().Len()
+		}
+	}
+
+	return spansPerNamespace
+}
+
+func newTestProcessor(t *testing.T, config *Config) (*rateLimiterProcessor, *sdkmetric.ManualReader) {
+	reader := sdkmetric.NewManualReader()
+
+	settings := processortest.NewNopCreateSettings()
+	settings.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	rp, err := newRateLimiterProcessor(settings, config)
+	require.NoError(t, err)
+
+	return rp, reader
+}
+
+func droppedSpansMetricOf(t *testing.T, reader *sdkmetric.ManualReader) map[string]int64 {
+	resourceMetrics := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+
+	droppedSpans := make(map[string]int64)
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			if m.Name != "processor_ratelimiter_dropped_spans" {
+				continue
+			}
+
+			for _, dataPoint := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				namespaceName, _ := dataPoint.Attributes.Value(K8SNamespaceNameKey)
+				droppedSpans[namespaceName.AsString()] += dataPoint.Value
+			}
+		}
+	}
+
+	return droppedSpans
+}
+
+func TestProcessTracesAcceptsSpansWithinTheLimits(t *testing.T) {
+	rp, reader := newTestProcessor(t, &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "limited", SpansPerSecond: 10},
+		},
+		StatusInterval: defaultStatusInterval,
+	})
+
+	traces, err := rp.processTraces(context.Background(), newTraces(map[string]int{
+		"limited":   10,
+		"unlimited": 100,
+		"":          100,
+	}))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"limited":   10,
+		"unlimited": 100,
+		"":          100,
+	}, spansPerNamespaceOf(traces))
+	assert.Empty(t, droppedSpansMetricOf(t, reader))
+}
+
+func TestProcessTracesDropsSpansExceedingTheLimits(t *testing.T) {
+	rp, reader := newTestProcessor(t, &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "limited", SpansPerSecond: 10},
+			{Namespace: "other-limited", SpansPerSecond: 5},
+		},
+		StatusInterval: defaultStatusInterval,
+	})
+
+	traces, err := rp.processTraces(context.Background(), newTraces(map[string]int{
+		"limited":       15,
+		"other-limited": 5,
+		"unlimited":     100,
+	}))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"limited":       10,
+		"other-limited": 5,
+		"unlimited":     100,
+	}, spansPerNamespaceOf(traces))
+	assert.Equal(t, map[string]int64{"limited": 5}, droppedSpansMetricOf(t, reader))
+
+	// The burst of the other namespace is used up, and it does not refill within the test
+	traces, err = rp.processTraces(context.Background(), newTraces(map[string]int{
+		"other-limited": 3,
+		"unlimited":     1,
+	}))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"unlimited": 1}, spansPerNamespaceOf(traces))
+	assert.Equal(t, map[string]int64{"limited": 5, "other-limited": 3}, droppedSpansMetricOf(t, reader))
+}
+
+func TestProcessTracesSkipsBatchesWhoseSpansAreAllDropped(t *testing.T) {
+	rp, _ := newTestProcessor(t, &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "limited", SpansPerSecond: 1},
+		},
+		StatusInterval: defaultStatusInterval,
+	})
+
+	_, err := rp.processTraces(context.Background(), newTraces(map[string]int{"limited": 1}))
+	require.NoError(t, err)
+
+	traces, err := rp.processTraces(context.Background(), newTraces(map[string]int{"limited": 2}))
+
+	assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	assert.Equal(t, 0, traces.ResourceSpans().Len())
+}
+
+func TestDroppedSpansAreWrittenToTheStatusFile(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), "rate_limiting_status.json")
+	config := &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "limited", SpansPerSecond: 1},
+		},
+		StatusFile:     statusFile,
+		StatusInterval: time.Hour,
+	}
+
+	rp, _ := newTestProcessor(t, config)
+	require.NoError(t, rp.Start(context.Background(), componenttest.NewNopHost()))
+
+	_, err := rp.processTraces(context.Background(), newTraces(map[string]int{"limited": 3}))
+	require.NoError(t, err)
+
+	// The status is written one last time on shutdown
+	require.NoError(t, rp.Shutdown(context.Background()))
+
+	statusBytes, err := os.ReadFile(statusFile)
+	require.NoError(t, err)
+
+	status := make(map[string]*NamespaceStatus)
+	require.NoError(t, json.Unmarshal(statusBytes, &status))
+	require.Contains(t, status, "limited")
+	assert.Equal(t, int64(2), status["limited"].DroppedSpans)
+	assert.WithinDuration(t, time.Now(), status["limited"].LastDroppedAt, time.Minute)
+
+	// The dropped spans are not forgotten when the processor is re-created, e.g., on reloads
+	rp, _ = newTestProcessor(t, config)
+	require.NoError(t, rp.Start(context.Background(), componenttest.NewNopHost()))
+
+	_, err = rp.processTraces(context.Background(), newTraces(map[string]int{"limited": 2}))
+	require.NoError(t, err)
+	require.NoError(t, rp.Shutdown(context.Background()))
+
+	statusBytes, err = os.ReadFile(statusFile)
+	require.NoError(t, err)
+
+	status = make(map[string]*NamespaceStatus)
+	require.NoError(t, json.Unmarshal(statusBytes, &status))
+	assert.Equal(t, int64(3), status["limited"].DroppedSpans)
+}
+
+// newTracesOfNamespace returns traces with the given amount of spans of the namespace, each in its
+// own trace, whose ID makes it sampled by the sampling percentages greater than the index of the span
+func newTracesOfNamespace(namespaceName string, spans int) ptrace.Traces {
+	traces := ptrace.NewTraces()
+
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().PutStr(K8SNamespaceNameKey, namespaceName)
+
+	scopeSpans := resourceSpans.ScopeSpans().AppendEmpty()
+	for i := 0; i < spans; i++ {
+		span := scopeSpans.Spans().AppendEmpty()
+		span.SetName("span")
+		span.SetTraceID(pcommon.TraceID{15: byte(i)})
+	}
+
+	return traces
+}
+
+func TestProcessTracesSamplesSpansOnceTheQuotaIsExceeded(t *testing.T) {
+	rp, _ := newTestProcessor(t, &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "with-quota", Quota: &NamespaceQuota{SpansPerDay: 10, SamplingPercentage: 50}},
+		},
+		StatusInterval: defaultStatusInterval,
+	})
+
+	// The batch that exceeds the quota is accepted as a whole
+	traces, err := rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 10))
+	require.NoError(t, err)
+	assert.Equal(t, 10, traces.SpanCount())
+
+	traces, err = rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 100))
+	require.NoError(t, err)
+	assert.Equal(t, 50, traces.SpanCount())
+
+	quotaStatus := rp.status["with-quota"].Quota
+	require.NotNil(t, quotaStatus)
+	assert.Equal(t, time.Now().UTC().Format(quotaDayFormat), quotaStatus.Day)
+	assert.Equal(t, int64(60), quotaStatus.AcceptedSpans)
+	assert.Equal(t, int64(50), quotaStatus.DroppedSpans)
+	assert.NotNil(t, quotaStatus.ExceededAt)
+}
+
+func TestProcessTracesDropsAllSpansOnceTheQuotaIsExceededWithoutSampling(t *testing.T) {
+	rp, _ := newTestProcessor(t, &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "with-quota", Quota: &NamespaceQuota{BytesPerDay: 1}},
+		},
+		StatusInterval: defaultStatusInterval,
+	})
+
+	_, err := rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 1))
+	require.NoError(t, err)
+
+	_, err = rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 5))
+	assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	assert.Equal(t, int64(5), rp.status["with-quota"].Quota.DroppedSpans)
+}
+
+func TestTheQuotaUsageIsRestoredFromTheStatusFile(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), "rate_limiting_status.json")
+	config := &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "with-quota", Quota: &NamespaceQuota{SpansPerDay: 10}},
+		},
+		StatusFile:     statusFile,
+		StatusInterval: time.Hour,
+	}
+
+	rp, _ := newTestProcessor(t, config)
+	require.NoError(t, rp.Start(context.Background(), componenttest.NewNopHost()))
+
+	_, err := rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 10))
+	require.NoError(t, err)
+	require.NoError(t, rp.Shutdown(context.Background()))
+
+	// The quota stays exceeded after the processor is re-created, e.g., on reloads
+	rp, _ = newTestProcessor(t, config)
+	require.NoError(t, rp.Start(context.Background(), componenttest.NewNopHost()))
+
+	_, err = rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 3))
+	assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	require.NoError(t, rp.Shutdown(context.Background()))
+
+	statusBytes, err := os.ReadFile(statusFile)
+	require.NoError(t, err)
+
+	status := make(map[string]*NamespaceStatus)
+	require.NoError(t, json.Unmarshal(statusBytes, &status))
+	require.NotNil(t, status["with-quota"].Quota)
+	assert.Equal(t, int64(10), status["with-quota"].Quota.AcceptedSpans)
+	assert.Equal(t, int64(3), status["with-quota"].Quota.DroppedSpans)
+	assert.NotNil(t, status["with-quota"].Quota.ExceededAt)
+}
+
+func TestTheQuotaUsageOfPreviousDaysIsReset(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), "rate_limiting_status.json")
+	exceededAt := time.Now().Add(-48 * time.Hour)
+	statusBytes, err := json.Marshal(map[string]*NamespaceStatus{
+		"with-quota": {
+			Quota: &QuotaStatus{
+				Day:           exceededAt.UTC().Format(quotaDayFormat),
+				AcceptedSpans: 10,
+				ExceededAt:    &exceededAt,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(statusFile, statusBytes, 0644))
+
+	rp, _ := newTestProcessor(t, &Config{
+		Limits: []NamespaceLimit{
+			{Namespace: "with-quota", Quota: &NamespaceQuota{SpansPerDay: 10}},
+		},
+		StatusFile:     statusFile,
+		StatusInterval: time.Hour,
+	})
+	require.NoError(t, rp.Start(context.Background(), componenttest.NewNopHost()))
+	defer rp.Shutdown(context.Background())
+
+	traces, err := rp.processTraces(context.Background(), newTracesOfNamespace("with-quota", 3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, traces.SpanCount())
+}
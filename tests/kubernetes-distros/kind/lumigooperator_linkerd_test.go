@@ -0,0 +1,213 @@
+package kind
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachinerywait "k8s.io/apimachinery/pkg/util/wait"
+
+	operatorv1alpha1 "github.com/lumigo-io/lumigo-kubernetes-operator/api/v1alpha1"
+	operatorv1alpha1conditions "github.com/lumigo-io/lumigo-kubernetes-operator/controllers/conditions"
+	"github.com/lumigo-io/lumigo-kubernetes-operator/tests/kubernetes-distros/kind/internal"
+)
+
+// The pods of Linkerd are emulated with the containers that `linkerd inject --manual` adds, running the test
+// server image, so that the test does not depend on a Linkerd installation
+func TestLumigoOperatorLinkerd(t *testing.T) {
+	logger := testr.New(t)
+
+	deploymentName := "linkerd-app"
+	namespaceName := envconf.RandomName("test-linkerd-ns", 20)
+
+	var replicas int32 = 1
+	deploymentLabels := map[string]string{
+		"app":  deploymentName,
+		"type": "deployment",
+	}
+
+	testLinkerdFeature := features.New("TestLinkerd").
+		Setup(func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+			testJsAppServerImage := ctx.Value(internal.ContextTestAppJsServerImageName).(string)
+
+			client := config.Client()
+
+			if err := client.Resources().Create(ctx, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: namespaceName,
+					Annotations: map[string]string{
+						"linkerd.io/inject": "enabled",
+					},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			lumigoToken := ctx.Value(internal.ContextKeyLumigoToken).(string)
+
+			lumigoTokenName := "lumigo-credentials"
+			lumigoTokenKey := "token"
+
+			if err := client.Resources().Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      lumigoTokenName,
+				},
+				StringData: map[string]string{
+					lumigoTokenKey: lumigoToken,
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			lumigo := internal.NewLumigo(namespaceName, "lumigo", lumigoTokenName, lumigoTokenKey, true, false)
+
+			r, err := resources.New(client.RESTConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			operatorv1alpha1.AddToScheme(r.GetScheme())
+			r.Create(ctx, lumigo)
+
+			if err := apimachinerywait.PollImmediateUntilWithContext(ctx, time.Second*1, func(context.Context) (bool, error) {
+				currentLumigo := &operatorv1alpha1.Lumigo{}
+
+				if err := r.Get(ctx, lumigo.Name, lumigo.Namespace, currentLumigo); err != nil {
+					return false, err
+				}
+
+				return operatorv1alpha1conditions.IsActive(currentLumigo), err
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			deploymentPort := 8080
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      deploymentName,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{
+						MatchLabels: deploymentLabels,
+					},
+					Replicas: &replicas,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: deploymentLabels,
+							Annotations: map[string]string{
+								"linkerd.io/inject": "enabled",
+							},
+						},
+						Spec: corev1.PodSpec{
+							InitContainers: []corev1.Container{
+								{
+									Name:    "linkerd-init",
+									Image:   testJsAppServerImage,
+									Command: []string{"node", "-e", "process.exit(0)"},
+								},
+							},
+							Containers: []corev1.Container{
+								{
+									Name:  "server",
+									Image: testJsAppServerImage,
+									Env: []corev1.EnvVar{
+										{
+											Name:  "SERVER_PORT",
+											Value: fmt.Sprintf("%d", deploymentPort),
+										},
+									},
+								},
+								{
+									Name:  "linkerd-proxy",
+									Image: testJsAppServerImage,
+									Env: []corev1.EnvVar{
+										{
+											Name:  "SERVER_PORT",
+											Value: "4143",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			if err := client.Resources().Create(ctx, deployment); err != nil {
+				t.Fatal(err)
+			}
+
+			logger.Info("Deployment with the Linkerd containers is created")
+
+			return ctx
+		}).
+		Assess("The lumigo-injector init container runs after linkerd-init", func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+			deployment := &appsv1.Deployment{}
+			if err := c.Client().Resources().Get(ctx, deploymentName, namespaceName, deployment); err != nil {
+				t.Fatal(err)
+			}
+
+			initContainers := deployment.Spec.Template.Spec.InitContainers
+			if len(initContainers) != 2 || initContainers[0].Name != "linkerd-init" || initContainers[1].Name != "lumigo-injector" {
+				t.Fatalf("unexpected init containers: %+v", initContainers)
+			}
+
+			return ctx
+		}).
+		Assess("The linkerd-proxy container is not instrumented", func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+			deployment := &appsv1.Deployment{}
+			if err := c.Client().Resources().Get(ctx, deploymentName, namespaceName, deployment); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, container := range deployment.Spec.Template.Spec.Containers {
+				isInstrumented := false
+				for _, envVar := range container.Env {
+					if envVar.Name == "LD_PRELOAD" {
+						isInstrumented = true
+					}
+				}
+
+				if container.Name == "linkerd-proxy" && isInstrumented {
+					t.Fatalf("the 'linkerd-proxy' container is instrumented: %+v", container)
+				} else if container.Name == "server" && !isInstrumented {
+					t.Fatalf("the 'server' container is not instrumented: %+v", container)
+				}
+			}
+
+			return ctx
+		}).
+		Assess("The pods with the Linkerd containers become ready", func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespaceName,
+					Name:      deploymentName,
+				},
+			}
+
+			if err := wait.For(conditions.New(c.Client().Resources()).ResourceMatch(deployment, func(object k8s.Object) bool {
+				d := object.(*appsv1.Deployment)
+				return d.Status.AvailableReplicas == replicas && d.Status.ReadyReplicas == replicas
+			}), wait.WithTimeout(time.Minute*5)); err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Feature()
+
+	testEnv.Test(t, testLinkerdFeature)
+}